@@ -0,0 +1,251 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accelerators
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/stats"
+
+	"k8s.io/klog/v2"
+)
+
+type amdManager struct {
+	// amdDevices is a map from minor number of DRM render node to sysfs directory of the device
+	amdDevices map[int]string
+}
+
+var sysFsDRMPath = "/sys/class/drm/"
+
+const (
+	amdVendorID = "0x1002"
+
+	// DRM devices are character devices with major number 226, render nodes use minor numbers from 128.
+	// https://github.com/torvalds/linux/blob/v4.13/Documentation/admin-guide/devices.txt#L3207
+	drmMajorNumber        = "226"
+	drmRenderMinorNumbers = 128
+)
+
+// NewAMDManager returns a manager of AMD GPU metrics read from sysfs attributes exposed
+// by the amdgpu driver, ROCm user space libraries are not required.
+func NewAMDManager(includedMetrics container.MetricSet) stats.Manager {
+	if !includedMetrics.Has(container.AcceleratorUsageMetrics) {
+		klog.V(2).Info("AMD GPU metrics disabled")
+		return &stats.NoopManager{}
+	}
+
+	manager := &amdManager{}
+	err := manager.setup()
+	if err != nil {
+		klog.Warningf("AMD GPU metrics will not be available: %s", err)
+		return &stats.NoopManager{}
+	}
+	return manager
+}
+
+// setup looks for render nodes of AMD devices if AMD devices are present on the node.
+func (am *amdManager) setup() error {
+	if !detectDevices(amdVendorID) {
+		return fmt.Errorf("no AMD devices found")
+	}
+
+	devices, err := getAMDDevices()
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no render nodes of AMD devices found in %q", sysFsDRMPath)
+	}
+	klog.V(1).Infof("Number of AMD devices: %v", len(devices))
+	am.amdDevices = devices
+	return nil
+}
+
+// getAMDDevices returns a map from minor number of DRM render node to sysfs directory
+// of AMD device for all AMD render nodes present on the node.
+func getAMDDevices() (map[int]string, error) {
+	renderNodes, err := filepath.Glob(filepath.Join(sysFsDRMPath, "renderD*"))
+	if err != nil {
+		return nil, err
+	}
+	devices := make(map[int]string, len(renderNodes))
+	for _, renderNode := range renderNodes {
+		devicePath := filepath.Join(renderNode, "device")
+		vendor, err := ioutil.ReadFile(filepath.Join(devicePath, "vendor"))
+		if err != nil {
+			klog.V(4).Infof("Error while reading vendor of %q: %v", renderNode, err)
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(string(vendor)), amdVendorID) {
+			continue
+		}
+		dev, err := ioutil.ReadFile(filepath.Join(renderNode, "dev"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read device number of %q: %v", renderNode, err)
+		}
+		majorMinor := strings.Split(strings.TrimSpace(string(dev)), ":")
+		if len(majorMinor) != 2 {
+			return nil, fmt.Errorf("invalid device number of %q: %q", renderNode, dev)
+		}
+		minorNumber, err := strconv.Atoi(majorMinor[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid device number of %q: %q", renderNode, dev)
+		}
+		devices[minorNumber] = devicePath
+	}
+	return devices, nil
+}
+
+// Destroy is a no-op, sysfs attributes don't need any cleanup.
+func (am *amdManager) Destroy() {}
+
+// GetCollector returns a collector that can fetch AMD gpu metrics for AMD devices
+// present in the devices.list file in the given devicesCgroupPath.
+func (am *amdManager) GetCollector(devicesCgroupPath string) (stats.Collector, error) {
+	if len(am.amdDevices) == 0 {
+		return &stats.NoopCollector{}, nil
+	}
+	renderMinorNumbers, err := parseAMDDevicesCgroup(devicesCgroupPath)
+	if err != nil {
+		return &stats.NoopCollector{}, err
+	}
+
+	ac := &amdCollector{}
+	for _, minor := range renderMinorNumbers {
+		devicePath, ok := am.amdDevices[minor]
+		if !ok {
+			// Render node of other vendor's device.
+			continue
+		}
+		ac.devices = append(ac.devices, devicePath)
+	}
+	if len(ac.devices) == 0 {
+		return &stats.NoopCollector{}, nil
+	}
+	return ac, nil
+}
+
+// parseAMDDevicesCgroup returns a list of minor numbers of DRM render nodes that the
+// container is allowed to access.
+// This is defined as a variable to help in testing.
+var parseAMDDevicesCgroup = func(devicesCgroupPath string) ([]int, error) {
+	return readDevicesCgroup(devicesCgroupPath, drmMajorNumber, func(minor int) bool {
+		return minor >= drmRenderMinorNumbers
+	})
+}
+
+type amdCollector struct {
+	// sysfs directories of AMD devices attached to the container, exposed for testing
+	devices []string
+
+	stats.NoopDestroy
+}
+
+// UpdateStats updates the stats for AMD GPUs (if any) attached to the container.
+func (ac *amdCollector) UpdateStats(stats *info.ContainerStats) error {
+	for _, devicePath := range ac.devices {
+		id, err := getAMDDeviceID(devicePath)
+		if err != nil {
+			return fmt.Errorf("error while getting gpu id: %v", err)
+		}
+		memoryTotal, err := readUint64(filepath.Join(devicePath, "mem_info_vram_total"))
+		if err != nil {
+			return fmt.Errorf("error while getting gpu memory info: %v", err)
+		}
+		memoryUsed, err := readUint64(filepath.Join(devicePath, "mem_info_vram_used"))
+		if err != nil {
+			return fmt.Errorf("error while getting gpu memory info: %v", err)
+		}
+		utilizationGPU, err := readUint64(filepath.Join(devicePath, "gpu_busy_percent"))
+		if err != nil {
+			return fmt.Errorf("error while getting gpu utilization: %v", err)
+		}
+		powerUsage, err := getAMDPowerUsage(devicePath)
+		if err != nil {
+			klog.V(4).Infof("Cannot get power usage of gpu %s: %v", id, err)
+			powerUsage = 0
+		}
+
+		stats.Accelerators = append(stats.Accelerators, info.AcceleratorStats{
+			Make:        "amd",
+			Model:       getAMDDeviceModel(devicePath),
+			ID:          id,
+			MemoryTotal: memoryTotal,
+			MemoryUsed:  memoryUsed,
+			DutyCycle:   utilizationGPU,
+			PowerUsage:  powerUsage,
+		})
+	}
+	return nil
+}
+
+// getAMDDeviceID returns unique id of the GPU if the driver supports it, PCI address otherwise.
+func getAMDDeviceID(devicePath string) (string, error) {
+	uniqueID, err := ioutil.ReadFile(filepath.Join(devicePath, "unique_id"))
+	if err == nil {
+		return strings.TrimSpace(string(uniqueID)), nil
+	}
+	pciDevice, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(pciDevice), nil
+}
+
+// getAMDDeviceModel returns product name of the GPU if available, PCI device id otherwise.
+func getAMDDeviceModel(devicePath string) string {
+	for _, attribute := range []string{"product_name", "device"} {
+		model, err := ioutil.ReadFile(filepath.Join(devicePath, attribute))
+		if err == nil && len(strings.TrimSpace(string(model))) > 0 {
+			return strings.TrimSpace(string(model))
+		}
+	}
+	return ""
+}
+
+// getAMDPowerUsage returns average power usage of the GPU in milliwatts read from hwmon interface of amdgpu driver.
+func getAMDPowerUsage(devicePath string) (uint64, error) {
+	powerFiles, err := filepath.Glob(filepath.Join(devicePath, "hwmon", "hwmon*", "power1_average"))
+	if err != nil {
+		return 0, err
+	}
+	if len(powerFiles) == 0 {
+		return 0, fmt.Errorf("power1_average not found in %q", devicePath)
+	}
+	// Power is reported in microwatts.
+	power, err := readUint64(powerFiles[0])
+	if err != nil {
+		return 0, err
+	}
+	return power / 1000, nil
+}
+
+func readUint64(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %v", path, err)
+	}
+	return value, nil
+}
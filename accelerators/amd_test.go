@@ -0,0 +1,159 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accelerators
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/stats"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createAMDRenderNode(t *testing.T, drmPath string, renderNode string, dev string, vendor string) string {
+	devicePath := filepath.Join(drmPath, renderNode, "device")
+	if err := os.MkdirAll(filepath.Join(devicePath, "hwmon", "hwmon3"), 0777); err != nil {
+		t.Fatalf("Error creating temporary directory for testing: %v", err)
+	}
+	updateFile(t, filepath.Join(drmPath, renderNode, "dev"), []byte(dev+"\n"))
+	updateFile(t, filepath.Join(devicePath, "vendor"), []byte(vendor+"\n"))
+	return devicePath
+}
+
+func TestGetAMDDevices(t *testing.T) {
+	var err error
+	originalDRMPath := sysFsDRMPath
+	if sysFsDRMPath, err = ioutil.TempDir("", "sys-class-drm"); err != nil {
+		t.Fatalf("Error creating temporary directory for testing: %v", err)
+	}
+	defer func() {
+		os.RemoveAll(sysFsDRMPath)
+		sysFsDRMPath = originalDRMPath
+	}()
+
+	devices, err := getAMDDevices()
+	assert.Nil(t, err)
+	assert.Empty(t, devices)
+
+	device0 := createAMDRenderNode(t, sysFsDRMPath, "renderD128", "226:128", "0x1002")
+	createAMDRenderNode(t, sysFsDRMPath, "renderD129", "226:129", "0x8086")
+	device2 := createAMDRenderNode(t, sysFsDRMPath, "renderD130", "226:130", "0x1002")
+
+	devices, err = getAMDDevices()
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{128: device0, 130: device2}, devices)
+
+	updateFile(t, filepath.Join(sysFsDRMPath, "renderD130", "dev"), []byte("invalid\n"))
+	_, err = getAMDDevices()
+	assert.NotNil(t, err)
+}
+
+func TestAMDGetCollector(t *testing.T) {
+	originalParser := parseAMDDevicesCgroup
+	parseAMDDevicesCgroup = func(_ string) ([]int, error) {
+		return []int{128, 129}, nil
+	}
+	defer func() {
+		parseAMDDevicesCgroup = originalParser
+	}()
+
+	am := &amdManager{}
+
+	// When there are no AMD devices, empty collector should be returned.
+	ac, err := am.GetCollector("does-not-matter")
+	assert.Nil(t, err)
+	_, ok := ac.(*stats.NoopCollector)
+	assert.True(t, ok)
+
+	// Render nodes of the container belong to other devices.
+	am.amdDevices = map[int]string{130: "/sys/class/drm/renderD130/device"}
+	ac, err = am.GetCollector("does-not-matter")
+	assert.Nil(t, err)
+	_, ok = ac.(*stats.NoopCollector)
+	assert.True(t, ok)
+
+	am.amdDevices[128] = "/sys/class/drm/renderD128/device"
+	ac, err = am.GetCollector("does-not-matter")
+	assert.Nil(t, err)
+	collector, ok := ac.(*amdCollector)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"/sys/class/drm/renderD128/device"}, collector.devices)
+}
+
+func TestParseAMDDevicesCgroup(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "devices-cgroup")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpfn := filepath.Join(tmpDir, "devices.list")
+
+	// card0 (226:0) and nvidia0 (195:0) are not supposed to be returned.
+	updateFile(t, tmpfn, []byte("c 226:0 rwm\nc 226:128 rwm\nc 195:0 rwm\nc 226:129 rw"))
+	renderMinorNumbers, err := parseAMDDevicesCgroup(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{128, 129}, renderMinorNumbers)
+
+	updateFile(t, tmpfn, []byte("c 226:* rwm\n"))
+	renderMinorNumbers, err = parseAMDDevicesCgroup(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{}, renderMinorNumbers)
+}
+
+func TestAMDUpdateStats(t *testing.T) {
+	drmPath, err := ioutil.TempDir("", "sys-class-drm")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(drmPath)
+
+	devicePath := createAMDRenderNode(t, drmPath, "renderD128", "226:128", "0x1002")
+	updateFile(t, filepath.Join(devicePath, "unique_id"), []byte("2f8d7a3a5e1c7b43\n"))
+	updateFile(t, filepath.Join(devicePath, "product_name"), []byte("Instinct MI100\n"))
+	updateFile(t, filepath.Join(devicePath, "mem_info_vram_total"), []byte("34342961152\n"))
+	updateFile(t, filepath.Join(devicePath, "mem_info_vram_used"), []byte("12582912\n"))
+	updateFile(t, filepath.Join(devicePath, "gpu_busy_percent"), []byte("37\n"))
+	updateFile(t, filepath.Join(devicePath, "hwmon", "hwmon3", "power1_average"), []byte("142000000\n"))
+
+	collector := &amdCollector{devices: []string{devicePath}}
+	var containerStats info.ContainerStats
+	err = collector.UpdateStats(&containerStats)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.AcceleratorStats{{
+		Make:        "amd",
+		Model:       "Instinct MI100",
+		ID:          "2f8d7a3a5e1c7b43",
+		MemoryTotal: 34342961152,
+		MemoryUsed:  12582912,
+		DutyCycle:   37,
+		PowerUsage:  142000,
+	}}, containerStats.Accelerators)
+
+	// Power usage is optional.
+	os.Remove(filepath.Join(devicePath, "hwmon", "hwmon3", "power1_average"))
+	containerStats = info.ContainerStats{}
+	err = collector.UpdateStats(&containerStats)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), containerStats.Accelerators[0].PowerUsage)
+
+	// Utilization is required.
+	os.Remove(filepath.Join(devicePath, "gpu_busy_percent"))
+	err = collector.UpdateStats(&info.ContainerStats{})
+	assert.NotNil(t, err)
+}
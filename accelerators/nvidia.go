@@ -185,13 +185,24 @@ func (nm *nvidiaManager) GetCollector(devicesCgroupPath string) (stats.Collector
 // the devices.list file, we return an empty list.
 // This is defined as a variable to help in testing.
 var parseDevicesCgroup = func(devicesCgroupPath string) ([]int, error) {
+	// NVIDIA graphics devices are character devices with major number 195.
+	// https://github.com/torvalds/linux/blob/v4.13/Documentation/admin-guide/devices.txt#L2583
+	// We don't want devices like nvidiactl (195:255) and nvidia-modeset (195:254)
+	return readDevicesCgroup(devicesCgroupPath, "195", func(minor int) bool {
+		return minor < 128
+	})
+}
+
+// readDevicesCgroup returns minor numbers of character devices with given major number
+// listed in devices.list file of the devices cgroup for which include returns true.
+func readDevicesCgroup(devicesCgroupPath string, major string, include func(minor int) bool) ([]int, error) {
 	// Always return a non-nil slice
-	nvidiaMinorNumbers := []int{}
+	minorNumbers := []int{}
 
 	devicesList := filepath.Join(devicesCgroupPath, "devices.list")
 	f, err := os.Open(devicesList)
 	if err != nil {
-		return nvidiaMinorNumbers, fmt.Errorf("error while opening devices cgroup file %q: %v", devicesList, err)
+		return minorNumbers, fmt.Errorf("error while opening devices cgroup file %q: %v", devicesList, err)
 	}
 	defer f.Close()
 
@@ -203,33 +214,33 @@ var parseDevicesCgroup = func(devicesCgroupPath string) ([]int, error) {
 
 		fields := strings.Fields(text)
 		if len(fields) != 3 {
-			return nvidiaMinorNumbers, fmt.Errorf("invalid devices cgroup entry %q: must contain three whitespace-separated fields", text)
+			return minorNumbers, fmt.Errorf("invalid devices cgroup entry %q: must contain three whitespace-separated fields", text)
 		}
 
 		// Split the second field to find out major:minor numbers
 		majorMinor := strings.Split(fields[1], ":")
 		if len(majorMinor) != 2 {
-			return nvidiaMinorNumbers, fmt.Errorf("invalid devices cgroup entry %q: second field should have one colon", text)
+			return minorNumbers, fmt.Errorf("invalid devices cgroup entry %q: second field should have one colon", text)
 		}
 
-		// NVIDIA graphics devices are character devices with major number 195.
-		// https://github.com/torvalds/linux/blob/v4.13/Documentation/admin-guide/devices.txt#L2583
-		if fields[0] == "c" && majorMinor[0] == "195" {
+		if fields[0] == "c" && majorMinor[0] == major {
+			// We are ignoring the "<major>:*" case
+			// where the container has access to all devices of given type on the machine.
+			if majorMinor[1] == "*" {
+				continue
+			}
 			minorNumber, err := strconv.Atoi(majorMinor[1])
 			if err != nil {
-				return nvidiaMinorNumbers, fmt.Errorf("invalid devices cgroup entry %q: minor number is not integer", text)
+				return minorNumbers, fmt.Errorf("invalid devices cgroup entry %q: minor number is not integer", text)
 			}
-			// We don't want devices like nvidiactl (195:255) and nvidia-modeset (195:254)
-			if minorNumber < 128 {
-				nvidiaMinorNumbers = append(nvidiaMinorNumbers, minorNumber)
+			if include(minorNumber) {
+				minorNumbers = append(minorNumbers, minorNumber)
 			}
-			// We are ignoring the "195:*" case
-			// where the container has access to all NVIDIA devices on the machine.
 		}
 		// We are ignoring the "*:*" case
 		// where the container has access to all devices on the machine.
 	}
-	return nvidiaMinorNumbers, nil
+	return minorNumbers, nil
 }
 
 type nvidiaCollector struct {
@@ -263,6 +274,12 @@ func (nc *nvidiaCollector) UpdateStats(stats *info.ContainerStats) error {
 		if err != nil {
 			return fmt.Errorf("error while getting gpu utilization: %v", err)
 		}
+		// Power readings are not supported by all GPUs (e.g. some GeForce models).
+		powerUsage, err := device.AveragePowerUsage(10 * time.Second)
+		if err != nil {
+			klog.V(4).Infof("Cannot get power usage of gpu %s: %v", uuid, err)
+			powerUsage = 0
+		}
 
 		stats.Accelerators = append(stats.Accelerators, info.AcceleratorStats{
 			Make:        "nvidia",
@@ -271,6 +288,7 @@ func (nc *nvidiaCollector) UpdateStats(stats *info.ContainerStats) error {
 			MemoryTotal: memoryTotal,
 			MemoryUsed:  memoryUsed,
 			DutyCycle:   uint64(utilizationGPU),
+			PowerUsage:  uint64(powerUsage),
 		})
 	}
 	return nil
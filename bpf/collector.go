@@ -0,0 +1,128 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"fmt"
+	"os"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+// direction is a pair of accounting program and map of its counters attached to cgroup
+// for either ingress or egress traffic.
+type direction struct {
+	attachType ebpf.AttachType
+	counters   *ebpf.Map
+	program    *ebpf.Program
+}
+
+type collector struct {
+	cgroupPath string
+	cgroupDir  *os.File
+	ingress    *direction
+	egress     *direction
+}
+
+func newCollector(cgroupPath string) (*collector, error) {
+	cgroupDir, err := os.Open(cgroupPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open cgroup %s: %v", cgroupPath, err)
+	}
+	c := &collector{
+		cgroupPath: cgroupPath,
+		cgroupDir:  cgroupDir,
+	}
+
+	c.ingress, err = c.attach(ebpf.AttachCGroupInetIngress)
+	if err != nil {
+		c.Destroy()
+		return nil, err
+	}
+	c.egress, err = c.attach(ebpf.AttachCGroupInetEgress)
+	if err != nil {
+		c.Destroy()
+		return nil, err
+	}
+	return c, nil
+}
+
+// attach loads accounting program and attaches it to the cgroup. Programs attached with
+// BPF_F_ALLOW_MULTI run also for descendant cgroups, so traffic of children is included
+// in the counters of their parents.
+func (c *collector) attach(attachType ebpf.AttachType) (*direction, error) {
+	counters, err := newCountersMap()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create counters map for cgroup %s: %v", c.cgroupPath, err)
+	}
+	program, err := newAccountingProgram(counters.FD())
+	if err != nil {
+		counters.Close()
+		return nil, fmt.Errorf("unable to load accounting program for cgroup %s: %v", c.cgroupPath, err)
+	}
+	err = program.Attach(int(c.cgroupDir.Fd()), attachType, unix.BPF_F_ALLOW_MULTI)
+	if err != nil {
+		program.Close()
+		counters.Close()
+		return nil, fmt.Errorf("unable to attach accounting program to cgroup %s: %v", c.cgroupPath, err)
+	}
+	return &direction{
+		attachType: attachType,
+		counters:   counters,
+		program:    program,
+	}, nil
+}
+
+func (c *collector) UpdateStats(stats *info.ContainerStats) error {
+	var rx, tx trafficCounter
+	err := c.ingress.counters.Lookup(uint32(0), &rx)
+	if err != nil {
+		return fmt.Errorf("unable to read ingress counters of cgroup %s: %v", c.cgroupPath, err)
+	}
+	err = c.egress.counters.Lookup(uint32(0), &tx)
+	if err != nil {
+		return fmt.Errorf("unable to read egress counters of cgroup %s: %v", c.cgroupPath, err)
+	}
+	stats.Network.Cgroup = newCgroupNetworkStats(rx, tx)
+	return nil
+}
+
+func newCgroupNetworkStats(rx, tx trafficCounter) *info.CgroupNetworkStats {
+	return &info.CgroupNetworkStats{
+		RxBytes:   rx.Bytes,
+		RxPackets: rx.Packets,
+		TxBytes:   tx.Bytes,
+		TxPackets: tx.Packets,
+	}
+}
+
+func (c *collector) Destroy() {
+	for _, d := range []*direction{c.ingress, c.egress} {
+		if d == nil {
+			continue
+		}
+		err := d.program.Detach(int(c.cgroupDir.Fd()), d.attachType, unix.BPF_F_ALLOW_MULTI)
+		if err != nil {
+			klog.V(4).Infof("Unable to detach accounting program from cgroup %s: %v", c.cgroupPath, err)
+		}
+		d.program.Close()
+		d.counters.Close()
+	}
+	c.cgroupDir.Close()
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Managers of eBPF programs accounting network traffic and scheduling of cgroups.
+package bpf
+
+import (
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/stats"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+type manager struct {
+	stats.NoopDestroy
+}
+
+// NewManager returns manager which attaches cgroup/skb programs counting packets and bytes
+// sent and received by processes of a cgroup. Programs can be attached only to cgroups of
+// unified hierarchy (cgroup v2).
+func NewManager(includedMetrics container.MetricSet) stats.Manager {
+	if !includedMetrics.Has(container.CgroupNetworkMetrics) {
+		return &stats.NoopManager{}
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		klog.Warning("cgroup network metrics require unified cgroup hierarchy (cgroup v2) and will not be available")
+		return &stats.NoopManager{}
+	}
+	raiseMemlockLimit()
+	return &manager{}
+}
+
+// raiseMemlockLimit removes memlock limit of cAdvisor, maps and programs are accounted
+// against it on kernels older than 5.11.
+func raiseMemlockLimit() {
+	err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &unix.Rlimit{
+		Cur: unix.RLIM_INFINITY,
+		Max: unix.RLIM_INFINITY,
+	})
+	if err != nil {
+		klog.V(4).Infof("Unable to raise memlock limit for eBPF programs: %v", err)
+	}
+}
+
+func (m *manager) GetCollector(cgroupPath string) (stats.Collector, error) {
+	collector, err := newCollector(cgroupPath)
+	if err != nil {
+		return &stats.NoopCollector{}, err
+	}
+	return collector, nil
+}
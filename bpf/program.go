@@ -0,0 +1,73 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+const license = "Apache"
+
+// trafficCounter is value of counters map, updated by accounting program.
+type trafficCounter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// newCountersMap creates array with single trafficCounter.
+func newCountersMap() (*ebpf.Map, error) {
+	return ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Array,
+		KeySize:    4,
+		ValueSize:  16,
+		MaxEntries: 1,
+	})
+}
+
+// accountingInstructions returns cgroup/skb program which adds packet and its length
+// to the trafficCounter stored in map with given file descriptor. Packet is always allowed.
+func accountingInstructions(countersFD int) asm.Instructions {
+	return asm.Instructions{
+		// r6 = skb
+		asm.Mov.Reg(asm.R6, asm.R1),
+		// key = 0
+		asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+		// r0 = map_lookup_elem(counters, &key)
+		asm.LoadMapPtr(asm.R1, countersFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "allow"),
+		// counter.Packets += 1
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		// counter.Bytes += skb->len
+		asm.LoadMem(asm.R1, asm.R6, 0, asm.Word),
+		asm.Add.Imm(asm.R0, 8),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		// return 1
+		asm.Mov.Imm(asm.R0, 1).Sym("allow"),
+		asm.Return(),
+	}
+}
+
+func newAccountingProgram(countersFD int) (*ebpf.Program, error) {
+	return ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.CGroupSKB,
+		Instructions: accountingInstructions(countersFD),
+		License:      license,
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountingInstructionsMarshal(t *testing.T) {
+	insts := accountingInstructions(3)
+	buf := &bytes.Buffer{}
+	// Marshal fails when jump target is not defined.
+	err := insts.Marshal(buf, binary.LittleEndian)
+	assert.Nil(t, err)
+	assert.NotZero(t, buf.Len())
+}
+
+func TestNewCgroupNetworkStats(t *testing.T) {
+	stats := newCgroupNetworkStats(trafficCounter{Packets: 10, Bytes: 1500}, trafficCounter{Packets: 4, Bytes: 400})
+	assert.Equal(t, &info.CgroupNetworkStats{
+		RxBytes:   1500,
+		RxPackets: 10,
+		TxBytes:   400,
+		TxPackets: 4,
+	}, stats)
+}
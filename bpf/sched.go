@@ -0,0 +1,382 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/stats"
+	"github.com/google/cadvisor/utils/sysfs"
+	"github.com/google/cadvisor/utils/sysinfo"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+var bpfSyscalls = flag.String("bpf_syscalls", "read,write,openat,close,futex,epoll_pwait,connect,accept4,sendto,recvfrom,sendmsg,recvmsg,clone,execve", "Comma-separated list of system calls counted per container when 'bpf_sched' metrics are enabled, system calls are counted only on hosts with unified cgroup hierarchy (cgroup v2).")
+
+// syscallNumbers maps names of system calls which can be counted to their numbers on architecture of cAdvisor.
+var syscallNumbers = map[string]uint64{
+	"accept4":     unix.SYS_ACCEPT4,
+	"clone":       unix.SYS_CLONE,
+	"close":       unix.SYS_CLOSE,
+	"connect":     unix.SYS_CONNECT,
+	"epoll_pwait": unix.SYS_EPOLL_PWAIT,
+	"execve":      unix.SYS_EXECVE,
+	"futex":       unix.SYS_FUTEX,
+	"openat":      unix.SYS_OPENAT,
+	"read":        unix.SYS_READ,
+	"recvfrom":    unix.SYS_RECVFROM,
+	"recvmsg":     unix.SYS_RECVMSG,
+	"sendmsg":     unix.SYS_SENDMSG,
+	"sendto":      unix.SYS_SENDTO,
+	"write":       unix.SYS_WRITE,
+}
+
+const (
+	// Latency map is drained at most once per drainInterval, regardless of number of containers.
+	drainInterval = time.Second
+	// Latencies of threads which don't belong to any monitored container are dropped after pendingTimeout.
+	pendingTimeout = 5 * time.Minute
+)
+
+// latencyHistogram is cumulative run queue latency, bucket latencyBuckets counts latencies
+// exceeding upper bound of the last bucket.
+type latencyHistogram struct {
+	buckets [latencyBuckets + 1]uint64
+	sum     uint64
+}
+
+func (h *latencyHistogram) add(other *latencyHistogram) {
+	for i := range h.buckets {
+		h.buckets[i] += other.buckets[i]
+	}
+	h.sum += other.sum
+}
+
+func (h *latencyHistogram) toInfo() *info.LatencyHistogram {
+	histogram := &info.LatencyHistogram{
+		Buckets: make([]uint64, latencyBuckets),
+		Sum:     h.sum,
+	}
+	copy(histogram.Buckets, h.buckets[:latencyBuckets])
+	for _, count := range h.buckets {
+		histogram.Count += count
+	}
+	return histogram
+}
+
+// pendingLatency is latency of thread drained from latency map and not yet claimed by a container.
+type pendingLatency struct {
+	latency latencyHistogram
+	updated time.Time
+}
+
+type schedManager struct {
+	maps     *schedMaps
+	programs []*ebpf.Program
+	// File descriptors of perf events through which programs are attached to tracepoints.
+	perfFDs []int
+	// CPUs on which programs are attached, CPUs brought online later are not covered.
+	cpus []int
+	// Names of counted system calls by their numbers.
+	syscalls map[uint64]string
+
+	lock      sync.Mutex
+	pending   map[uint32]*pendingLatency
+	lastDrain time.Time
+}
+
+// NewSchedManager returns manager which attaches programs to scheduler tracepoints measuring
+// how long threads wait in run queue and to raw_syscalls:sys_enter tracepoint counting
+// selected system calls.
+func NewSchedManager(includedMetrics container.MetricSet) stats.Manager {
+	if !includedMetrics.Has(container.BPFSchedMetrics) {
+		return &stats.NoopManager{}
+	}
+	syscalls, err := parseSyscalls(*bpfSyscalls)
+	if err != nil {
+		klog.Warningf("Invalid list of system calls, run queue latency and system call metrics will not be available: %v", err)
+		return &stats.NoopManager{}
+	}
+	if len(syscalls) > 0 && !cgroups.IsCgroup2UnifiedMode() {
+		klog.Warning("system call metrics require unified cgroup hierarchy (cgroup v2) and will not be available")
+		syscalls = map[uint64]string{}
+	}
+	raiseMemlockLimit()
+	m, err := newSchedManager(syscalls)
+	if err != nil {
+		klog.Warningf("Unable to load eBPF programs, run queue latency and system call metrics will not be available: %v", err)
+		return &stats.NoopManager{}
+	}
+	return m
+}
+
+// parseSyscalls returns names of system calls listed in comma-separated string by their numbers.
+func parseSyscalls(list string) (map[uint64]string, error) {
+	syscalls := map[uint64]string{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		nr, ok := syscallNumbers[name]
+		if !ok {
+			return nil, fmt.Errorf("system call %q is not supported", name)
+		}
+		syscalls[nr] = name
+	}
+	return syscalls, nil
+}
+
+func newSchedManager(syscalls map[uint64]string) (*schedManager, error) {
+	cpus, _, err := sysinfo.GetOnlineAndOfflineCPUs(sysfs.NewRealSysFs())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get online CPUs: %v", err)
+	}
+	maps, err := newSchedMaps()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create maps: %v", err)
+	}
+	m := &schedManager{
+		maps:     maps,
+		cpus:     cpus,
+		syscalls: syscalls,
+		pending:  map[uint32]*pendingLatency{},
+	}
+
+	wakeup := func(fields map[string]tracepointField) asm.Instructions {
+		return wakeupInstructions(maps.start.FD(), fields["pid"])
+	}
+	err = m.attach("sched", "sched_wakeup", wakeup, "pid")
+	if err == nil {
+		err = m.attach("sched", "sched_wakeup_new", wakeup, "pid")
+	}
+	if err == nil {
+		err = m.attach("sched", "sched_switch", func(fields map[string]tracepointField) asm.Instructions {
+			return switchInstructions(maps.start.FD(), maps.latency.FD(), fields)
+		}, "prev_pid", "prev_state", "next_pid")
+	}
+	if err == nil && len(syscalls) > 0 {
+		for nr := range syscalls {
+			err = maps.selected.Put(uint32(nr), uint32(1))
+			if err != nil {
+				err = fmt.Errorf("unable to select system call %s: %v", syscalls[nr], err)
+				break
+			}
+		}
+		if err == nil {
+			err = m.attach("raw_syscalls", "sys_enter", func(fields map[string]tracepointField) asm.Instructions {
+				return syscallInstructions(maps.selected.FD(), maps.syscalls.FD(), fields["id"])
+			}, "id")
+		}
+	}
+	if err != nil {
+		m.Destroy()
+		return nil, err
+	}
+	return m, nil
+}
+
+// attach loads program built for record of given tracepoint and attaches it to the tracepoint.
+func (m *schedManager) attach(category, name string, instructions func(map[string]tracepointField) asm.Instructions, fieldNames ...string) error {
+	tpDir, err := tracepointDir(category, name)
+	if err != nil {
+		return err
+	}
+	fields, err := readTracepointFields(path.Join(tpDir, "format"), fieldNames...)
+	if err != nil {
+		return err
+	}
+	program, err := newTracepointProgram(instructions(fields))
+	if err != nil {
+		return fmt.Errorf("unable to load program for tracepoint %s:%s: %v", category, name, err)
+	}
+	m.programs = append(m.programs, program)
+	fds, err := attachTracepoint(tpDir, program, m.cpus)
+	m.perfFDs = append(m.perfFDs, fds...)
+	return err
+}
+
+func (m *schedManager) GetCollector(cgroupPath string) (stats.Collector, error) {
+	c := &schedCollector{
+		manager:    m,
+		cgroupPath: cgroupPath,
+	}
+	if len(m.syscalls) > 0 {
+		// Id returned by bpf_get_current_cgroup_id() is inode number of cgroup directory.
+		var stat unix.Stat_t
+		err := unix.Stat(cgroupPath, &stat)
+		if err != nil {
+			return &stats.NoopCollector{}, fmt.Errorf("unable to get id of cgroup %s: %v", cgroupPath, err)
+		}
+		c.cgroupID = stat.Ino
+	}
+	return c, nil
+}
+
+// claim moves latencies of given threads, accounted since previous claim, to the histogram.
+func (m *schedManager) claim(tids []uint32, histogram *latencyHistogram) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	err := m.drain()
+	if err != nil {
+		return err
+	}
+	for _, tid := range tids {
+		if pending, ok := m.pending[tid]; ok {
+			histogram.add(&pending.latency)
+			delete(m.pending, tid)
+		}
+	}
+	return nil
+}
+
+// drain moves latencies from latency map to pending latencies of threads. Latencies accounted
+// between reading and deleting of an entry are lost, which is negligible for the distribution.
+func (m *schedManager) drain() error {
+	now := time.Now()
+	if now.Sub(m.lastDrain) < drainInterval {
+		return nil
+	}
+	m.lastDrain = now
+
+	var key latencyKey
+	var value latencyValue
+	var keys []latencyKey
+	entries := m.maps.latency.Iterate()
+	for entries.Next(&key, &value) {
+		keys = append(keys, key)
+		if key.Bucket > latencyBuckets {
+			continue
+		}
+		pending, ok := m.pending[key.Tid]
+		if !ok {
+			pending = &pendingLatency{}
+			m.pending[key.Tid] = pending
+		}
+		pending.latency.buckets[key.Bucket] += value.Count
+		pending.latency.sum += value.Sum
+		pending.updated = now
+	}
+	if err := entries.Err(); err != nil {
+		return fmt.Errorf("unable to read run queue latencies: %v", err)
+	}
+	for _, key := range keys {
+		err := m.maps.latency.Delete(key)
+		if err != nil {
+			klog.V(5).Infof("Unable to delete run queue latency of thread %d: %v", key.Tid, err)
+		}
+	}
+	for tid, pending := range m.pending {
+		if now.Sub(pending.updated) > pendingTimeout {
+			delete(m.pending, tid)
+		}
+	}
+	return nil
+}
+
+func (m *schedManager) Destroy() {
+	for _, fd := range m.perfFDs {
+		unix.Close(fd)
+	}
+	for _, program := range m.programs {
+		program.Close()
+	}
+	m.maps.Close()
+}
+
+type schedCollector struct {
+	manager    *schedManager
+	cgroupPath string
+	// Id of cgroup used as key of syscalls map, zero when system calls aren't counted.
+	cgroupID uint64
+	latency  latencyHistogram
+}
+
+func (c *schedCollector) UpdateStats(stats *info.ContainerStats) error {
+	tids, err := readThreadIDs(c.cgroupPath)
+	if err != nil {
+		return fmt.Errorf("unable to list threads of cgroup %s: %v", c.cgroupPath, err)
+	}
+	err = c.manager.claim(tids, &c.latency)
+	if err != nil {
+		return err
+	}
+	stats.Cpu.RunqueueLatency = c.latency.toInfo()
+
+	if c.cgroupID == 0 {
+		return nil
+	}
+	stats.Syscalls = make(map[string]uint64, len(c.manager.syscalls))
+	for nr, name := range c.manager.syscalls {
+		var count uint64
+		// Entry doesn't exist until a thread of the cgroup enters the system call.
+		err := c.manager.maps.syscalls.Lookup(syscallKey{CgroupID: c.cgroupID, Nr: nr}, &count)
+		if err != nil {
+			count = 0
+		}
+		stats.Syscalls[name] = count
+	}
+	return nil
+}
+
+func (c *schedCollector) Destroy() {
+	for nr := range c.manager.syscalls {
+		// Most system calls are never entered by threads of the cgroup, errors are expected.
+		_ = c.manager.maps.syscalls.Delete(syscallKey{CgroupID: c.cgroupID, Nr: nr})
+	}
+}
+
+// readThreadIDs lists threads which are direct members of the cgroup, cgroup.threads file
+// of unified hierarchy or tasks file of cgroup v1 is read.
+func readThreadIDs(cgroupPath string) ([]uint32, error) {
+	file, err := os.Open(path.Join(cgroupPath, "cgroup.threads"))
+	if os.IsNotExist(err) {
+		file, err = os.Open(path.Join(cgroupPath, "tasks"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tids []uint32
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		tid, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid thread id %q in %s: %v", scanner.Text(), file.Name(), err)
+		}
+		tids = append(tids, uint32(tid))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tids, nil
+}
@@ -0,0 +1,284 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+const (
+	// Number of latency buckets, bucket i holds latencies in [2^i, 2^(i+1)) microseconds,
+	// latencies not shorter than 2^latencyBuckets microseconds go to overflow bucket.
+	latencyBuckets = 26
+	// Syscall numbers are lower than 512 on all supported architectures.
+	maxSyscalls = 512
+	// BPF_NOEXIST flag of map_update_elem, element is created only if it doesn't exist.
+	noExist = 1
+)
+
+// latencyKey is key of latency map, latencies are accounted per thread because cgroup
+// of thread being switched in is not available to the sched_switch program.
+type latencyKey struct {
+	Tid    uint32
+	Bucket uint32
+}
+
+// latencyValue is value of latency map.
+type latencyValue struct {
+	Count uint64
+	Sum   uint64
+}
+
+// syscallKey is key of syscalls map, system calls are accounted per cgroup of the caller.
+type syscallKey struct {
+	CgroupID uint64
+	Nr       uint64
+}
+
+// schedMaps holds maps shared by programs attached to scheduler and syscall tracepoints.
+type schedMaps struct {
+	// Time at which thread was woken up or preempted, by thread id.
+	start *ebpf.Map
+	// Run queue latencies by latencyKey.
+	latency *ebpf.Map
+	// Non zero value for numbers of system calls which are counted.
+	selected *ebpf.Map
+	// Number of system calls by syscallKey.
+	syscalls *ebpf.Map
+}
+
+func newSchedMaps() (*schedMaps, error) {
+	maps := &schedMaps{}
+	var err error
+	maps.start, err = ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Hash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: 65536,
+	})
+	if err != nil {
+		maps.Close()
+		return nil, err
+	}
+	// LRU maps make room for new entries when userspace doesn't drain them in time.
+	maps.latency, err = ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.LRUHash,
+		KeySize:    8,
+		ValueSize:  16,
+		MaxEntries: 65536,
+	})
+	if err != nil {
+		maps.Close()
+		return nil, err
+	}
+	maps.selected, err = ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Array,
+		KeySize:    4,
+		ValueSize:  4,
+		MaxEntries: maxSyscalls,
+	})
+	if err != nil {
+		maps.Close()
+		return nil, err
+	}
+	maps.syscalls, err = ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.LRUHash,
+		KeySize:    16,
+		ValueSize:  8,
+		MaxEntries: 65536,
+	})
+	if err != nil {
+		maps.Close()
+		return nil, err
+	}
+	return maps, nil
+}
+
+func (m *schedMaps) Close() {
+	for _, bpfMap := range []*ebpf.Map{m.start, m.latency, m.selected, m.syscalls} {
+		if bpfMap != nil {
+			bpfMap.Close()
+		}
+	}
+}
+
+// storeTimestampInstructions store current time in start map under thread id loaded
+// from given field of tracepoint record (r6). Uses stack at fp-16..fp-1.
+func storeTimestampInstructions(startFD int, tid tracepointField) asm.Instructions {
+	return asm.Instructions{
+		// tid = ctx->pid
+		asm.LoadMem(asm.R1, asm.R6, tid.offset, tid.size),
+		asm.StoreMem(asm.RFP, -4, asm.R1, asm.Word),
+		// ts = ktime_get_ns()
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.RFP, -16, asm.R0, asm.DWord),
+		// map_update_elem(start, &tid, &ts, BPF_ANY)
+		asm.LoadMapPtr(asm.R1, startFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -16),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnMapUpdateElem.Call(),
+	}
+}
+
+// wakeupInstructions returns program for sched_wakeup and sched_wakeup_new tracepoints
+// which records time at which thread became runnable.
+func wakeupInstructions(startFD int, pid tracepointField) asm.Instructions {
+	instructions := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+	}
+	instructions = append(instructions, storeTimestampInstructions(startFD, pid)...)
+	return append(instructions,
+		asm.Mov.Imm(asm.R0, 0),
+		asm.Return(),
+	)
+}
+
+// switchInstructions returns program for sched_switch tracepoint. Preempted thread, which
+// stays runnable, starts waiting in run queue again. Time thread being switched in spent
+// in run queue is added to its bucket of the latency map.
+func switchInstructions(startFD, latencyFD int, fields map[string]tracepointField) asm.Instructions {
+	prevState, nextPid := fields["prev_state"], fields["next_pid"]
+	instructions := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		// if prev_state == TASK_RUNNING
+		asm.LoadMem(asm.R1, asm.R6, prevState.offset, prevState.size),
+		asm.JNE.Imm(asm.R1, 0, "next"),
+	}
+	instructions = append(instructions, storeTimestampInstructions(startFD, fields["prev_pid"])...)
+	return append(instructions,
+		// tid = ctx->next_pid
+		asm.LoadMem(asm.R1, asm.R6, nextPid.offset, nextPid.size).Sym("next"),
+		asm.StoreMem(asm.RFP, -4, asm.R1, asm.Word),
+		// r0 = map_lookup_elem(start, &tid)
+		asm.LoadMapPtr(asm.R1, startFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "exit"),
+		// r7 = ktime_get_ns() - *ts
+		asm.LoadMem(asm.R7, asm.R0, 0, asm.DWord),
+		asm.FnKtimeGetNs.Call(),
+		asm.Sub.Reg(asm.R0, asm.R7),
+		asm.Mov.Reg(asm.R7, asm.R0),
+		// map_delete_elem(start, &tid)
+		asm.LoadMapPtr(asm.R1, startFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapDeleteElem.Call(),
+		// r2 = log2(r7 / 1000) computed by binary search over bits of microseconds
+		asm.Mov.Reg(asm.R1, asm.R7),
+		asm.Div.Imm(asm.R1, 1000),
+		asm.Mov.Imm(asm.R2, 0),
+		asm.JLT.Imm(asm.R1, 1<<16, "log8"),
+		asm.RSh.Imm(asm.R1, 16),
+		asm.Add.Imm(asm.R2, 16),
+		asm.JLT.Imm(asm.R1, 1<<8, "log4").Sym("log8"),
+		asm.RSh.Imm(asm.R1, 8),
+		asm.Add.Imm(asm.R2, 8),
+		asm.JLT.Imm(asm.R1, 1<<4, "log2").Sym("log4"),
+		asm.RSh.Imm(asm.R1, 4),
+		asm.Add.Imm(asm.R2, 4),
+		asm.JLT.Imm(asm.R1, 1<<2, "log1").Sym("log2"),
+		asm.RSh.Imm(asm.R1, 2),
+		asm.Add.Imm(asm.R2, 2),
+		asm.JLT.Imm(asm.R1, 1<<1, "cap").Sym("log1"),
+		asm.Add.Imm(asm.R2, 1),
+		asm.JLT.Imm(asm.R2, latencyBuckets, "key").Sym("cap"),
+		asm.Mov.Imm(asm.R2, latencyBuckets),
+		// key = {tid, bucket}
+		asm.StoreMem(asm.RFP, -20, asm.R2, asm.Word).Sym("key"),
+		asm.LoadMem(asm.R1, asm.RFP, -4, asm.Word),
+		asm.StoreMem(asm.RFP, -24, asm.R1, asm.Word),
+		// r0 = map_lookup_elem(latency, &key)
+		asm.LoadMapPtr(asm.R1, latencyFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "insert"),
+		// value.Count += 1, value.Sum += r7
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		asm.Add.Imm(asm.R0, 8),
+		asm.StoreXAdd(asm.R0, asm.R7, asm.DWord),
+		asm.Ja.Label("exit"),
+		// map_update_elem(latency, &key, &{1, r7}, BPF_NOEXIST)
+		asm.StoreImm(asm.RFP, -40, 1, asm.DWord).Sym("insert"),
+		asm.StoreMem(asm.RFP, -32, asm.R7, asm.DWord),
+		asm.LoadMapPtr(asm.R1, latencyFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -40),
+		asm.Mov.Imm(asm.R4, noExist),
+		asm.FnMapUpdateElem.Call(),
+		asm.Mov.Imm(asm.R0, 0).Sym("exit"),
+		asm.Return(),
+	)
+}
+
+// syscallInstructions returns program for raw_syscalls:sys_enter tracepoint which counts
+// selected system calls per cgroup (of unified hierarchy) of calling thread.
+func syscallInstructions(selectedFD, syscallsFD int, id tracepointField) asm.Instructions {
+	return asm.Instructions{
+		// nr = ctx->id
+		asm.LoadMem(asm.R1, asm.R1, id.offset, id.size),
+		asm.JGE.Imm(asm.R1, maxSyscalls, "exit"),
+		asm.StoreMem(asm.RFP, -4, asm.R1, asm.Word),
+		asm.StoreMem(asm.RFP, -16, asm.R1, asm.DWord),
+		// if !selected[nr] return
+		asm.LoadMapPtr(asm.R1, selectedFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "exit"),
+		asm.LoadMem(asm.R1, asm.R0, 0, asm.Word),
+		asm.JEq.Imm(asm.R1, 0, "exit"),
+		// key = {bpf_get_current_cgroup_id(), nr}
+		asm.FnGetCurrentCgroupId.Call(),
+		asm.StoreMem(asm.RFP, -24, asm.R0, asm.DWord),
+		// r0 = map_lookup_elem(syscalls, &key)
+		asm.LoadMapPtr(asm.R1, syscallsFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "insert"),
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		asm.Ja.Label("exit"),
+		// map_update_elem(syscalls, &key, &1, BPF_NOEXIST)
+		asm.StoreImm(asm.RFP, -32, 1, asm.DWord).Sym("insert"),
+		asm.LoadMapPtr(asm.R1, syscallsFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -32),
+		asm.Mov.Imm(asm.R4, noExist),
+		asm.FnMapUpdateElem.Call(),
+		asm.Mov.Imm(asm.R0, 0).Sym("exit"),
+		asm.Return(),
+	}
+}
+
+func newTracepointProgram(instructions asm.Instructions) (*ebpf.Program, error) {
+	return ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.TracePoint,
+		Instructions: instructions,
+		License:      license,
+	})
+}
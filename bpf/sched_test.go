@@ -0,0 +1,93 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/cilium/ebpf/asm"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestSchedInstructionsMarshal(t *testing.T) {
+	pid := tracepointField{offset: 8, size: asm.Word}
+	fields := map[string]tracepointField{
+		"prev_pid":   {offset: 24, size: asm.Word},
+		"prev_state": {offset: 32, size: asm.DWord},
+		"next_pid":   {offset: 56, size: asm.Word},
+	}
+	for name, insts := range map[string]asm.Instructions{
+		"wakeup":  wakeupInstructions(3, pid),
+		"switch":  switchInstructions(3, 4, fields),
+		"syscall": syscallInstructions(3, 4, tracepointField{offset: 8, size: asm.DWord}),
+	} {
+		buf := &bytes.Buffer{}
+		// Marshal fails when jump target is not defined.
+		err := insts.Marshal(buf, binary.LittleEndian)
+		assert.Nil(t, err, name)
+		assert.NotZero(t, buf.Len(), name)
+	}
+}
+
+func TestParseSyscalls(t *testing.T) {
+	syscalls, err := parseSyscalls("read, write,,futex")
+	assert.Nil(t, err)
+	assert.Equal(t, map[uint64]string{
+		unix.SYS_READ:  "read",
+		unix.SYS_WRITE: "write",
+		unix.SYS_FUTEX: "futex",
+	}, syscalls)
+
+	syscalls, err = parseSyscalls("")
+	assert.Nil(t, err)
+	assert.Empty(t, syscalls)
+
+	_, err = parseSyscalls("read,no_such_syscall")
+	assert.NotNil(t, err)
+}
+
+func TestLatencyHistogramToInfo(t *testing.T) {
+	h := latencyHistogram{}
+	h.buckets[0] = 3
+	h.buckets[4] = 1
+	other := latencyHistogram{sum: 150000}
+	other.buckets[4] = 2
+	other.buckets[latencyBuckets] = 1
+	h.add(&other)
+	h.sum += 2500
+
+	expectedBuckets := make([]uint64, latencyBuckets)
+	expectedBuckets[0] = 3
+	expectedBuckets[4] = 3
+	assert.Equal(t, &info.LatencyHistogram{
+		Buckets: expectedBuckets,
+		Count:   7,
+		Sum:     152500,
+	}, h.toInfo())
+}
+
+func TestReadThreadIDs(t *testing.T) {
+	tids, err := readThreadIDs("testdata/cgroup")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1200, 1201, 1305}, tids)
+
+	_, err = readThreadIDs("testdata/missing")
+	assert.NotNil(t, err)
+}
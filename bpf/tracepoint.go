@@ -0,0 +1,169 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"golang.org/x/sys/unix"
+)
+
+// Tracing file system is mounted in /sys/kernel/tracing on newer kernels, older ones expose it in debugfs only.
+var tracingDirs = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+func tracepointDir(category, name string) (string, error) {
+	for _, dir := range tracingDirs {
+		tpDir := path.Join(dir, "events", category, name)
+		if _, err := os.Stat(tpDir); err == nil {
+			return tpDir, nil
+		}
+	}
+	return "", fmt.Errorf("tracepoint %s:%s not found in %v", category, name, tracingDirs)
+}
+
+// tracepointField describes location of a field in record passed to tracepoint programs.
+type tracepointField struct {
+	offset int16
+	size   asm.Size
+}
+
+var fieldSizes = map[int]asm.Size{
+	1: asm.Byte,
+	2: asm.Half,
+	4: asm.Word,
+	8: asm.DWord,
+}
+
+// readTracepointFields returns offsets and sizes of given fields of tracepoint record, read from its format file, e.g.
+//
+//	field:pid_t next_pid;	offset:56;	size:4;	signed:1;
+//
+// Layout of records differs between kernel versions, so it can't be hardcoded.
+func readTracepointFields(formatFile string, names ...string) (map[string]tracepointField, error) {
+	file, err := os.Open(formatFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	fields := make(map[string]tracepointField, len(names))
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var name string
+		offset, size := -1, 0
+		for _, part := range strings.Split(strings.TrimSpace(scanner.Text()), ";") {
+			part = strings.TrimSpace(part)
+			switch {
+			case strings.HasPrefix(part, "field:"):
+				declaration := strings.Fields(strings.TrimPrefix(part, "field:"))
+				if len(declaration) > 0 {
+					name = declaration[len(declaration)-1]
+				}
+			case strings.HasPrefix(part, "offset:"):
+				offset, err = strconv.Atoi(strings.TrimPrefix(part, "offset:"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid offset of field %q in %s: %v", name, formatFile, err)
+				}
+			case strings.HasPrefix(part, "size:"):
+				size, err = strconv.Atoi(strings.TrimPrefix(part, "size:"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid size of field %q in %s: %v", name, formatFile, err)
+				}
+			}
+		}
+		if !wanted[name] || offset < 0 {
+			continue
+		}
+		fieldSize, ok := fieldSizes[size]
+		if !ok {
+			return nil, fmt.Errorf("unsupported size %d of field %q in %s", size, name, formatFile)
+		}
+		fields[name] = tracepointField{
+			offset: int16(offset),
+			size:   fieldSize,
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("field %q not found in %s", name, formatFile)
+		}
+	}
+	return fields, nil
+}
+
+// attachTracepoint attaches program to tracepoint through perf events opened on given CPUs.
+// Returned file descriptors of perf events have to be closed to detach the program, they are
+// returned also when attaching fails on some CPU.
+func attachTracepoint(tpDir string, program *ebpf.Program, cpus []int) ([]int, error) {
+	idFile := path.Join(tpDir, "id")
+	out, err := ioutil.ReadFile(idFile)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracepoint id in %s: %v", idFile, err)
+	}
+
+	fds := make([]int, 0, len(cpus))
+	for _, cpu := range cpus {
+		fd, err := attachPerfEvent(id, cpu, program)
+		if err != nil {
+			return fds, fmt.Errorf("unable to attach program to tracepoint %s on CPU %d: %v", tpDir, cpu, err)
+		}
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
+
+func attachPerfEvent(id uint64, cpu int, program *ebpf.Program) (int, error) {
+	attr := &unix.PerfEventAttr{
+		Type:        unix.PERF_TYPE_TRACEPOINT,
+		Config:      id,
+		Sample_type: unix.PERF_SAMPLE_RAW,
+		Sample:      1,
+		Wakeup:      1,
+	}
+	fd, err := unix.PerfEventOpen(attr, -1, cpu, -1, unix.PERF_FLAG_FD_CLOEXEC)
+	if err != nil {
+		return -1, fmt.Errorf("unable to open perf event: %v", err)
+	}
+	err = unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_SET_BPF, program.FD())
+	if err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("unable to set program of perf event: %v", err)
+	}
+	err = unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_ENABLE, 0)
+	if err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("unable to enable perf event: %v", err)
+	}
+	return fd, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+const switchFormat = "testdata/tracing/events/sched/sched_switch/format"
+
+func TestReadTracepointFields(t *testing.T) {
+	fields, err := readTracepointFields(switchFormat, "prev_pid", "prev_state", "next_pid")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]tracepointField{
+		"prev_pid":   {offset: 24, size: asm.Word},
+		"prev_state": {offset: 32, size: asm.DWord},
+		"next_pid":   {offset: 56, size: asm.Word},
+	}, fields)
+}
+
+func TestReadTracepointFieldsMissing(t *testing.T) {
+	_, err := readTracepointFields(switchFormat, "next_pid", "pid")
+	assert.NotNil(t, err)
+}
+
+func TestReadTracepointFieldsUnsupportedSize(t *testing.T) {
+	_, err := readTracepointFields(switchFormat, "prev_comm")
+	assert.NotNil(t, err)
+}
+
+func TestTracepointDir(t *testing.T) {
+	tracingDirs = []string{"testdata/missing", "testdata/tracing"}
+	defer func() {
+		tracingDirs = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+	}()
+
+	tpDir, err := tracepointDir("sched", "sched_switch")
+	assert.Nil(t, err)
+	assert.Equal(t, "testdata/tracing/events/sched/sched_switch", tpDir)
+
+	_, err = tracepointDir("sched", "sched_wakeup")
+	assert.NotNil(t, err)
+}
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	cadvisorhttp "github.com/google/cadvisor/cmd/internal/http"
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/metrics"
@@ -41,6 +42,7 @@ import (
 	_ "github.com/google/cadvisor/utils/cloudinfo/aws"
 	_ "github.com/google/cadvisor/utils/cloudinfo/azure"
 	_ "github.com/google/cadvisor/utils/cloudinfo/gce"
+	_ "github.com/google/cadvisor/utils/cloudinfo/openstack"
 
 	"k8s.io/klog/v2"
 )
@@ -71,6 +73,15 @@ var collectorKey = flag.String("collector_key", "", "Key for the collector's cer
 var storeContainerLabels = flag.Bool("store_container_labels", true, "convert container labels and environment variables into labels on prometheus metrics for each container. If flag set to false, then only metrics exported are container name, first alias, and image name")
 var whitelistedContainerLabels = flag.String("whitelisted_container_labels", "", "comma separated list of container labels to be converted to labels on prometheus metrics for each container. store_container_labels must be set to false for this to take effect.")
 
+var prometheusRelabelConfig = flag.String("prometheus_relabel_config", "", "Path to a JSON file containing configuration of metric families and labels exported to Prometheus, see documentation for the format.")
+var prometheusDropMetrics = flag.String("prometheus_drop_metrics", "", "comma separated list of regular expressions of metric families not exported to Prometheus, e.g. container_tasks_state,container_memory_failures_total. Merged with drop_metrics of prometheus_relabel_config.")
+var prometheusCacheDuration = flag.Duration("prometheus_cache_duration", 0, "Duration for which metrics gathered for a scrape are served to following scrapes with the same request parameters, e.g. when several Prometheus servers scrape cAdvisor. 0 disables caching, concurrent scrapes share gathering in progress regardless.")
+var prometheusScrapeTimeoutOffset = flag.Duration("prometheus_scrape_timeout_offset", 500*time.Millisecond, "Time subtracted from the scrape timeout sent by Prometheus to leave time for writing the response. Metrics which are not gathered before are served from the last scrape, if it was recent, or omitted.")
+var prometheusNativeHistograms = flag.Bool("prometheus_native_histograms", false, "Export latency histograms, e.g. container_schedstat_runqueue_latency_seconds, also as Prometheus native histograms. They are exposed only when Prometheus negotiates the protobuf format.")
+var prometheusExemplarLabel = flag.String("prometheus_exemplar_label", "", "Name of a container label, e.g. trace_id, whose value is attached to latency histograms of the container as an exemplar. Exemplars are exposed in the protobuf format. Disabled if empty.")
+var prometheusOpenMetrics = flag.Bool("prometheus_openmetrics", false, "Serve the OpenMetrics format when requested by the scraper, with _created samples of counters, histograms and summaries holding the time they started counting from zero.")
+var prometheusDropLabels = flag.String("prometheus_drop_labels", "", "comma separated list of regular expressions of labels of containers removed from Prometheus metrics, e.g. id,container_env_.*. Merged with drop_labels of prometheus_relabel_config.")
+
 var urlBasePrefix = flag.String("url_base_prefix", "", "prefix path that will be prepended to all paths to support some reverse proxies")
 
 var rawCgroupPrefixWhiteList = flag.String("raw_cgroup_prefix_whitelist", "", "A comma-separated list of cgroup path prefix that needs to be collected even when -docker_only is specified")
@@ -87,10 +98,15 @@ var (
 		container.NetworkAdvancedTcpUsageMetrics: struct{}{},
 		container.ProcessSchedulerMetrics:        struct{}{},
 		container.ProcessMetrics:                 struct{}{},
+		container.ProcessListMetrics:             struct{}{},
 		container.HugetlbUsageMetrics:            struct{}{},
 		container.ReferencedMemoryMetrics:        struct{}{},
 		container.CPUTopologyMetrics:             struct{}{},
 		container.ResctrlMetrics:                 struct{}{},
+		container.GPUEngineMetrics:               struct{}{},
+		container.ConntrackMetrics:               struct{}{},
+		container.CgroupNetworkMetrics:           struct{}{},
+		container.BPFSchedMetrics:                struct{}{},
 	}}
 
 	// List of metrics that can be ignored.
@@ -106,10 +122,17 @@ var (
 		container.PerCpuUsageMetrics:             struct{}{},
 		container.ProcessSchedulerMetrics:        struct{}{},
 		container.ProcessMetrics:                 struct{}{},
+		container.ProcessListMetrics:             struct{}{},
 		container.HugetlbUsageMetrics:            struct{}{},
 		container.ReferencedMemoryMetrics:        struct{}{},
 		container.CPUTopologyMetrics:             struct{}{},
 		container.ResctrlMetrics:                 struct{}{},
+		container.GPUEngineMetrics:               struct{}{},
+		container.PressureMetrics:                struct{}{},
+		container.OOMMetrics:                     struct{}{},
+		container.ConntrackMetrics:               struct{}{},
+		container.CgroupNetworkMetrics:           struct{}{},
+		container.BPFSchedMetrics:                struct{}{},
 	}
 )
 
@@ -141,7 +164,7 @@ func (ml *metricSetValue) Set(value string) error {
 }
 
 func init() {
-	flag.Var(&ignoreMetrics, "disable_metrics", "comma-separated list of `metrics` to be disabled. Options are 'accelerator', 'cpu_topology','disk', 'diskIO', 'memory_numa', 'network', 'tcp', 'udp', 'percpu', 'sched', 'process', 'hugetlb', 'referenced_memory', 'resctrl'.")
+	flag.Var(&ignoreMetrics, "disable_metrics", "comma-separated list of `metrics` to be disabled. Options are 'accelerator', 'cpu_topology','disk', 'diskIO', 'memory_numa', 'network', 'tcp', 'udp', 'percpu', 'sched', 'process', 'process_list', 'hugetlb', 'referenced_memory', 'resctrl', 'gpu_engine', 'pressure', 'oom_event', 'conntrack', 'cgroup_network', 'bpf_sched'.")
 
 	// Default logging verbosity to V(2)
 	flag.Set("v", "2")
@@ -196,8 +219,24 @@ func main() {
 		containerLabelFunc = metrics.BaseContainerLabels(whitelistedLabels)
 	}
 
+	relabeler, err := newPrometheusRelabeler()
+	if err != nil {
+		klog.Fatalf("Failed to configure Prometheus relabeling: %v", err)
+	}
+
 	// Register Prometheus collector to gather information about containers, Go runtime, processes, and machine
-	cadvisorhttp.RegisterPrometheusHandler(mux, resourceManager, *prometheusEndpoint, containerLabelFunc, includedMetrics)
+	promOpts := cadvisorhttp.PrometheusOptions{
+		Relabeler:           relabeler,
+		CacheDuration:       *prometheusCacheDuration,
+		ScrapeTimeoutOffset: *prometheusScrapeTimeoutOffset,
+		NativeHistograms:    *prometheusNativeHistograms,
+		ExemplarLabel:       *prometheusExemplarLabel,
+		OpenMetrics:         *prometheusOpenMetrics,
+	}
+	err = cadvisorhttp.RegisterPrometheusHandler(mux, resourceManager, *prometheusEndpoint, containerLabelFunc, includedMetrics, promOpts, buffer.NewCollector(bufferedStorages))
+	if err != nil {
+		klog.Fatalf("Failed to register Prometheus handler: %v", err)
+	}
 
 	// Start the manager.
 	if err := resourceManager.Start(); err != nil {
@@ -244,6 +283,12 @@ func installSignalHandler(containerManager manager.Manager) {
 		if err := containerManager.Stop(); err != nil {
 			klog.Errorf("Failed to stop container manager: %v", err)
 		}
+		// Push or spill stats left in buffers of storage drivers.
+		for _, s := range bufferedStorages {
+			if err := s.Close(); err != nil {
+				klog.Errorf("Failed to close storage driver: %v", err)
+			}
+		}
 		klog.Infof("Exiting given signal: %v", sig)
 		os.Exit(0)
 	}()
@@ -278,3 +323,30 @@ func createCollectorHttpClient(collectorCert, collectorKey string) http.Client {
 func toIncludedMetrics(ignoreMetrics container.MetricSet) container.MetricSet {
 	return container.AllMetrics.Difference(ignoreMetrics)
 }
+
+// newPrometheusRelabeler returns the Relabeler of Prometheus metrics
+// configured by flags, nil if there is no configuration.
+func newPrometheusRelabeler() (*metrics.Relabeler, error) {
+	if *prometheusRelabelConfig == "" && *prometheusDropMetrics == "" && *prometheusDropLabels == "" {
+		return nil, nil
+	}
+	config := &metrics.RelabelConfig{}
+	if *prometheusRelabelConfig != "" {
+		file, err := os.Open(*prometheusRelabelConfig)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		config, err = metrics.ParseRelabelConfig(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if *prometheusDropMetrics != "" {
+		config.DropMetrics = append(config.DropMetrics, strings.Split(*prometheusDropMetrics, ",")...)
+	}
+	if *prometheusDropLabels != "" {
+		config.DropLabels = append(config.DropLabels, strings.Split(*prometheusDropLabels, ",")...)
+	}
+	return metrics.NewRelabeler(config)
+}
@@ -58,6 +58,36 @@ func TestMemoryNumaMetricsAreDisabledByDefault(t *testing.T) {
 	assert.True(t, ignoreMetrics.Has(container.MemoryNumaMetrics))
 }
 
+func TestGPUEngineMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.GPUEngineMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.GPUEngineMetrics))
+}
+
+func TestProcessListMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.ProcessListMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.ProcessListMetrics))
+}
+
+func TestConntrackMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.ConntrackMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.ConntrackMetrics))
+}
+
+func TestCgroupNetworkMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.CgroupNetworkMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.CgroupNetworkMetrics))
+}
+
+func TestBPFSchedMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.BPFSchedMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.BPFSchedMetrics))
+}
+
 func TestIgnoreMetrics(t *testing.T) {
 	tests := []struct {
 		value    string
@@ -102,12 +132,19 @@ func TestToIncludedMetrics(t *testing.T) {
 			container.NetworkAdvancedTcpUsageMetrics: struct{}{},
 			container.NetworkUdpUsageMetrics:         struct{}{},
 			container.ProcessMetrics:                 struct{}{},
+			container.ProcessListMetrics:             struct{}{},
 			container.AppMetrics:                     struct{}{},
 			container.HugetlbUsageMetrics:            struct{}{},
 			container.PerfMetrics:                    struct{}{},
 			container.ReferencedMemoryMetrics:        struct{}{},
 			container.CPUTopologyMetrics:             struct{}{},
 			container.ResctrlMetrics:                 struct{}{},
+			container.GPUEngineMetrics:               struct{}{},
+			container.PressureMetrics:                struct{}{},
+			container.OOMMetrics:                     struct{}{},
+			container.ConntrackMetrics:               struct{}{},
+			container.CgroupNetworkMetrics:           struct{}{},
+			container.BPFSchedMetrics:                struct{}{},
 		},
 		container.AllMetrics,
 		{},
@@ -179,7 +179,7 @@ func getContainerInfoRequest(body io.ReadCloser) (*info.ContainerInfoRequest, er
 // with any twice defined arguments being assigned the first value.
 // If the value type for the argument is wrong the field will be assumed to be
 // unassigned
-// bools: stream, subcontainers, oom_events, creation_events, deletion_events
+// bools: stream, subcontainers, oom_events, creation_events, deletion_events, machine_changed_events
 // ints: max_events, start_time (unix timestamp), end_time (unix timestamp)
 // example r.URL: http://localhost:8080/api/v1.3/events?oom_events=true&stream=true
 func getEventRequest(r *http.Request) (*events.Request, bool, error) {
@@ -201,10 +201,14 @@ func getEventRequest(r *http.Request) (*events.Request, bool, error) {
 		}
 	}
 	eventTypes := map[string]info.EventType{
-		"oom_events":      info.EventOom,
-		"oom_kill_events": info.EventOomKill,
-		"creation_events": info.EventContainerCreation,
-		"deletion_events": info.EventContainerDeletion,
+		"oom_events":                info.EventOom,
+		"oom_kill_events":           info.EventOomKill,
+		"creation_events":           info.EventContainerCreation,
+		"deletion_events":           info.EventContainerDeletion,
+		"machine_changed_events":    info.EventMachineChanged,
+		"pids_limit_events":         info.EventPidsLimit,
+		"thin_pool_metadata_events": info.EventThinPoolMetadata,
+		"conntrack_limit_events":    info.EventConntrackLimit,
 	}
 	allEventTypes := false
 	if val, ok := urlMap["all_events"]; ok {
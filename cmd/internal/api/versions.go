@@ -15,9 +15,12 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"path"
+	"sort"
 	"strconv"
 	"time"
 
@@ -43,6 +46,15 @@ const (
 	versionApi       = "version"
 	psApi            = "ps"
 	customMetricsApi = "appmetrics"
+	wssApi           = "wss"
+	perfApi          = "perf"
+
+	// Argument of machine request which forces update of machine info.
+	machineRefresh = "refresh"
+
+	// Arguments of perf request which replace or only validate configuration of perf events.
+	perfReload   = "reload"
+	perfValidate = "validate"
 )
 
 // Interface for a cAdvisor API version
@@ -89,7 +101,7 @@ func (api *version1_0) HandleRequest(requestType string, request []string, m man
 		klog.V(4).Infof("Api - Machine")
 
 		// Get the MachineInfo
-		machineInfo, err := m.GetMachineInfo()
+		machineInfo, err := m.GetMachineCounters()
 		if err != nil {
 			return err
 		}
@@ -343,7 +355,7 @@ func (api *version2_0) HandleRequest(requestType string, request []string, m man
 		klog.V(4).Info("Api - Machine")
 
 		// TODO(rjnagal): Move machineInfo from v1.
-		machineInfo, err := m.GetMachineInfo()
+		machineInfo, err := m.GetMachineCounters()
 		if err != nil {
 			return err
 		}
@@ -450,13 +462,20 @@ func (api *version2_0) HandleRequest(requestType string, request []string, m man
 	case psApi:
 		// reuse container type from request.
 		// ignore recursive.
-		// TODO(rjnagal): consider count to limit ps output.
+		// "top" limits ps output to processes using the most CPU.
 		name := getContainerName(request)
 		klog.V(4).Infof("Api - Spec for container %q, options %+v", name, opt)
 		ps, err := m.GetProcessList(name, opt)
 		if err != nil {
 			return fmt.Errorf("process listing failed: %v", err)
 		}
+		if top := r.URL.Query().Get("top"); len(top) != 0 {
+			n, err := strconv.ParseUint(top, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse 'top' option: %v", top)
+			}
+			ps = topProcesses(ps, int(n))
+		}
 		return writeResult(ps, w)
 	default:
 		return fmt.Errorf("unknown request type %q", requestType)
@@ -478,7 +497,7 @@ func (api *version2_1) Version() string {
 }
 
 func (api *version2_1) SupportedRequestTypes() []string {
-	return append([]string{machineStatsApi}, api.baseVersion.SupportedRequestTypes()...)
+	return append([]string{machineStatsApi, wssApi, perfApi}, api.baseVersion.SupportedRequestTypes()...)
 }
 
 func (api *version2_1) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
@@ -521,11 +540,81 @@ func (api *version2_1) HandleRequest(requestType string, request []string, m man
 			}
 		}
 		return writeResult(contStats, w)
+	case wssApi:
+		name := getContainerName(request)
+		// Measure referenced memory immediately unless max_age is specified.
+		if opt.MaxAge == nil {
+			maxAge := time.Duration(0)
+			opt.MaxAge = &maxAge
+		}
+		opt.Count = 1
+		klog.V(4).Infof("Api - Working set: Measuring referenced memory of container %q, options %+v", name, opt)
+		conts, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		contStats := make(map[string]*v2.ReferencedMemoryStats, len(conts))
+		for name, cont := range conts {
+			contStats[name] = v2.ReferencedMemoryStatsFromV1(cont)
+		}
+		return writeResult(contStats, w)
+	case machineApi:
+		if len(request) == 0 || request[0] != machineRefresh {
+			return api.baseVersion.HandleRequest(requestType, request, m, w, r)
+		}
+		if r.Method != http.MethodPost {
+			return fmt.Errorf("machine info can be refreshed only with POST request, got %s", r.Method)
+		}
+		klog.V(4).Info("Api - Machine refresh")
+		machineInfo, err := m.RefreshMachineInfo()
+		if err != nil {
+			return err
+		}
+		return writeResult(machineInfo, w)
+	case perfApi:
+		if len(request) == 0 {
+			klog.V(4).Info("Api - Perf events configuration")
+			return writeResult(json.RawMessage(m.GetPerfEventsConfig()), w)
+		}
+		if request[0] != perfReload && request[0] != perfValidate {
+			return fmt.Errorf("unknown perf request %q", request[0])
+		}
+		if r.Method != http.MethodPost {
+			return fmt.Errorf("perf events configuration can be changed only with POST request, got %s", r.Method)
+		}
+		config, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		dryRun := request[0] == perfValidate
+		klog.V(4).Infof("Api - Perf events configuration %s", request[0])
+		err = m.ReloadPerfEvents(config, dryRun)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return writeResult(json.RawMessage(config), w)
+		}
+		return writeResult(json.RawMessage(m.GetPerfEventsConfig()), w)
 	default:
 		return api.baseVersion.HandleRequest(requestType, request, m, w, r)
 	}
 }
 
+// topProcesses returns at most n processes with the highest CPU usage, sorted in descending order.
+func topProcesses(ps []v2.ProcessInfo, n int) []v2.ProcessInfo {
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].PercentCpu > ps[j].PercentCpu
+	})
+	if len(ps) > n {
+		ps = ps[:n]
+	}
+	return ps
+}
+
 // GetRequestOptions returns the metrics request options from a HTTP request.
 func GetRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 	supportedTypes := map[string]bool{
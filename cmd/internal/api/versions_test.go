@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +80,18 @@ func TestGetEventRequestDoubleArgument(t *testing.T) {
 	assert.True(t, stream)
 	assert.Nil(t, err)
 }
+
+func TestTopProcesses(t *testing.T) {
+	ps := []v2.ProcessInfo{
+		{Pid: 1, PercentCpu: 0.5},
+		{Pid: 2, PercentCpu: 12},
+		{Pid: 3, PercentCpu: 3},
+	}
+
+	top := topProcesses(ps, 2)
+	assert.Equal(t, []v2.ProcessInfo{
+		{Pid: 2, PercentCpu: 12},
+		{Pid: 3, PercentCpu: 3},
+	}, top)
+	assert.Len(t, topProcesses(ps, 5), 3)
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/klog/v2"
+)
+
+// scrapeTimeoutHeader is sent by Prometheus with the timeout of the scrape in seconds.
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// staleLimit is the maximal age of metric families served when gathering
+// doesn't finish before the scrape deadline.
+const staleLimit = 5 * time.Minute
+
+// gatherCache gathers metric families of collectors, shares gathering in
+// progress between concurrent scrapes and keeps the result for the cache
+// duration. Families gathered before are served when gathering doesn't finish
+// before the scrape deadline.
+type gatherCache struct {
+	duration time.Duration
+	lock     sync.Mutex
+	entries  map[string]*gatherEntry
+	timeouts *prometheus.CounterVec
+}
+
+type gatherEntry struct {
+	families []*dto.MetricFamily
+	err      error
+	// gathered is zero until the first gathering finishes.
+	gathered time.Time
+	// done is closed when gathering in progress finishes, nil if there is none.
+	done chan struct{}
+}
+
+// namedCollector is a collector gathered under the key, name identifies it in
+// metrics and logs.
+type namedCollector struct {
+	name      string
+	key       string
+	collector prometheus.Collector
+}
+
+func newGatherCache(duration time.Duration) *gatherCache {
+	return &gatherCache{
+		duration: duration,
+		entries:  map[string]*gatherEntry{},
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cadvisor_prometheus_gather_timeouts_total",
+			Help: "Number of scrapes in which gathering metrics of the collector didn't finish before the scrape timeout.",
+		}, []string{"collector"}),
+	}
+}
+
+// gather returns metric families of the collector, waiting at most until the
+// deadline, if it isn't zero.
+func (c *gatherCache) gather(nc namedCollector, deadline time.Time) ([]*dto.MetricFamily, error) {
+	now := time.Now()
+	c.lock.Lock()
+	c.prune(now)
+	e, ok := c.entries[nc.key]
+	if !ok {
+		e = &gatherEntry{}
+		c.entries[nc.key] = e
+	}
+	if !e.gathered.IsZero() && now.Sub(e.gathered) < c.duration {
+		families, err := e.families, e.err
+		c.lock.Unlock()
+		return families, err
+	}
+	done := e.done
+	if done == nil {
+		done = make(chan struct{})
+		e.done = done
+		go c.run(e, nc.collector, done)
+	}
+	c.lock.Unlock()
+
+	if deadline.IsZero() {
+		<-done
+	} else {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			c.timeouts.WithLabelValues(nc.name).Inc()
+			klog.V(2).Infof("Gathering %s metrics didn't finish before the scrape timeout", nc.name)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e.gathered.IsZero() || time.Since(e.gathered) > staleLimit {
+		return nil, fmt.Errorf("gathering %s metrics didn't finish before the scrape timeout", nc.name)
+	}
+	return e.families, e.err
+}
+
+// run gathers metric families of the collector into the entry.
+func (c *gatherCache) run(e *gatherEntry, collector prometheus.Collector, done chan struct{}) {
+	defer close(done)
+	r := prometheus.NewRegistry()
+	err := r.Register(collector)
+	var families []*dto.MetricFamily
+	if err == nil {
+		families, err = r.Gather()
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e.families = families
+	e.err = err
+	e.gathered = time.Now()
+	e.done = nil
+}
+
+// prune removes entries which can't be served anymore, e.g. of request
+// options not scraped recently.
+func (c *gatherCache) prune(now time.Time) {
+	for key, e := range c.entries {
+		if e.done == nil && now.Sub(e.gathered) > staleLimit && now.Sub(e.gathered) > c.duration {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// gatherer returns a Gatherer of metric families of the collectors, which
+// are gathered concurrently.
+func (c *gatherCache) gatherer(collectors []namedCollector, deadline time.Time) prometheus.Gatherer {
+	gatherers := make(prometheus.Gatherers, len(collectors))
+	var wg sync.WaitGroup
+	for i := range collectors {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			families, err := c.gather(collectors[i], deadline)
+			gatherers[i] = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return families, err
+			})
+		}(i)
+	}
+	wg.Wait()
+	return gatherers
+}
+
+// scrapeDeadline returns the time by which metrics have to be gathered to
+// respond before Prometheus times out the scrape, less the offset. It is zero
+// if the scrape timeout is unknown.
+func scrapeDeadline(req *http.Request, offset time.Duration) time.Time {
+	value := req.Header.Get(scrapeTimeoutHeader)
+	if value == "" {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		klog.V(4).Infof("Invalid %s header %q", scrapeTimeoutHeader, value)
+		return time.Time{}
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if offset < timeout {
+		timeout -= offset
+	} else {
+		timeout /= 2
+	}
+	return time.Now().Add(timeout)
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDesc = prometheus.NewDesc("test_collections", "Number of collections.", nil, nil)
+
+// slowCollector counts its collections, which block while it's blocked.
+type slowCollector struct {
+	lock        sync.Mutex
+	collections int
+	block       chan struct{}
+}
+
+func (c *slowCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- testDesc
+}
+
+func (c *slowCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.block != nil {
+		<-c.block
+	}
+	c.lock.Lock()
+	c.collections++
+	collections := c.collections
+	c.lock.Unlock()
+	ch <- prometheus.MustNewConstMetric(testDesc, prometheus.CounterValue, float64(collections))
+}
+
+func gatheredValue(t *testing.T, c *gatherCache, collector prometheus.Collector, deadline time.Time) float64 {
+	families, err := c.gather(namedCollector{name: "test", key: "test", collector: collector}, deadline)
+	require.Nil(t, err)
+	require.Len(t, families, 1)
+	return families[0].GetMetric()[0].GetCounter().GetValue()
+}
+
+func TestGatherCache(t *testing.T) {
+	collector := &slowCollector{}
+	c := newGatherCache(time.Hour)
+	assert.Equal(t, float64(1), gatheredValue(t, c, collector, time.Time{}))
+	assert.Equal(t, float64(1), gatheredValue(t, c, collector, time.Time{}))
+
+	c = newGatherCache(0)
+	assert.Equal(t, float64(2), gatheredValue(t, c, collector, time.Time{}))
+	assert.Equal(t, float64(3), gatheredValue(t, c, collector, time.Time{}))
+}
+
+func TestGatherTimeout(t *testing.T) {
+	collector := &slowCollector{}
+	c := newGatherCache(0)
+	assert.Equal(t, float64(1), gatheredValue(t, c, collector, time.Time{}))
+
+	// Families gathered before are served when gathering times out.
+	collector.block = make(chan struct{})
+	assert.Equal(t, float64(1), gatheredValue(t, c, collector, time.Now().Add(10*time.Millisecond)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.timeouts.WithLabelValues("test")))
+
+	// Gathering in progress is shared by following scrapes.
+	values := make(chan float64, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			values <- gatheredValue(t, c, collector, time.Time{})
+		}()
+	}
+	close(collector.block)
+	assert.Equal(t, float64(2), <-values)
+	assert.Equal(t, float64(2), <-values)
+	assert.Equal(t, float64(3), gatheredValue(t, c, collector, time.Time{}))
+
+	// There is nothing to serve if metrics were never gathered.
+	collector.block = make(chan struct{})
+	defer close(collector.block)
+	_, err := c.gather(namedCollector{name: "other", key: "other", collector: collector}, time.Now().Add(10*time.Millisecond))
+	assert.NotNil(t, err)
+}
+
+func TestGatherer(t *testing.T) {
+	c := newGatherCache(0)
+	gatherer := c.gatherer([]namedCollector{
+		{name: "test", key: "test", collector: &slowCollector{}},
+		{name: "go", key: "go", collector: prometheus.NewGoCollector()},
+	}, time.Time{})
+	families, err := gatherer.Gather()
+	require.Nil(t, err)
+	names := []string{}
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "test_collections")
+	assert.Contains(t, names, "go_goroutines")
+}
+
+func TestScrapeDeadline(t *testing.T) {
+	req, err := http.NewRequest("GET", "/metrics", nil)
+	require.Nil(t, err)
+	assert.True(t, scrapeDeadline(req, time.Second).IsZero())
+
+	req.Header.Set(scrapeTimeoutHeader, "10")
+	assert.WithinDuration(t, time.Now().Add(9*time.Second), scrapeDeadline(req, time.Second), time.Second/2)
+	// The offset is ignored if the timeout is shorter.
+	assert.WithinDuration(t, time.Now().Add(5*time.Second), scrapeDeadline(req, time.Minute), time.Second/2)
+
+	req.Header.Set(scrapeTimeoutHeader, "soon")
+	assert.True(t, scrapeDeadline(req, time.Second).IsZero())
+}
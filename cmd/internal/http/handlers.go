@@ -15,8 +15,10 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/cadvisor/cmd/internal/api"
 	"github.com/google/cadvisor/cmd/internal/healthz"
@@ -24,6 +26,7 @@ import (
 	"github.com/google/cadvisor/cmd/internal/pages"
 	"github.com/google/cadvisor/cmd/internal/pages/static"
 	"github.com/google/cadvisor/container"
+	v2 "github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/metrics"
 	"github.com/google/cadvisor/validate"
@@ -31,6 +34,7 @@ import (
 	auth "github.com/abbot/go-http-auth"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
@@ -92,13 +96,48 @@ func RegisterHandlers(mux httpmux.Mux, containerManager manager.Manager, httpAut
 	return nil
 }
 
+// PrometheusOptions configure the Prometheus endpoint.
+type PrometheusOptions struct {
+	// Relabeler applied to container metrics, may be nil.
+	Relabeler *metrics.Relabeler
+	// CacheDuration is how long gathered metrics are served to following
+	// scrapes, zero disables caching.
+	CacheDuration time.Duration
+	// ScrapeTimeoutOffset is subtracted from the scrape timeout sent by
+	// Prometheus to leave time for writing the response.
+	ScrapeTimeoutOffset time.Duration
+	// NativeHistograms enables exporting latency histograms also as native
+	// histograms, exposed when the protobuf format is negotiated.
+	NativeHistograms bool
+	// ExemplarLabel is the name of the container label attached to histograms
+	// as an exemplar, e.g. a trace ID. Exemplars are disabled if it is empty.
+	ExemplarLabel string
+	// OpenMetrics enables serving the OpenMetrics format, with created
+	// timestamps of cumulative metrics, when it is negotiated.
+	OpenMetrics bool
+}
+
 // RegisterPrometheusHandler creates a new PrometheusCollector and configures
 // the provided HTTP mux to handle the given Prometheus endpoint.
 func RegisterPrometheusHandler(mux httpmux.Mux, resourceManager manager.Manager, prometheusEndpoint string,
-	f metrics.ContainerLabelsFunc, includedMetrics container.MetricSet) {
-	goCollector := prometheus.NewGoCollector()
-	processCollector := prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})
-	machineCollector := metrics.NewPrometheusMachineCollector(resourceManager, includedMetrics)
+	f metrics.ContainerLabelsFunc, includedMetrics container.MetricSet, promOpts PrometheusOptions, collectors ...prometheus.Collector) error {
+	// Metrics of collectors depend only on included metrics, the relabeler
+	// can be validated once.
+	if err := metrics.NewPrometheusCollector(resourceManager, f, includedMetrics, clock.RealClock{}, v2.RequestOptions{}).SetRelabeler(promOpts.Relabeler); err != nil {
+		return err
+	}
+	cache := newGatherCache(promOpts.CacheDuration)
+	staticCollectors := []namedCollector{
+		{name: "machine", key: "machine", collector: metrics.NewPrometheusMachineCollector(resourceManager, includedMetrics)},
+		{name: "go", key: "go", collector: prometheus.NewGoCollector()},
+		{name: "process", key: "process", collector: prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})},
+	}
+	for i, c := range collectors {
+		name := fmt.Sprintf("collector%d", i)
+		staticCollectors = append(staticCollectors, namedCollector{name: name, key: name, collector: c})
+	}
+	timeouts := prometheus.NewRegistry()
+	timeouts.MustRegister(cache.timeouts)
 
 	mux.Handle(prometheusEndpoint, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		opts, err := api.GetRequestOptions(req)
@@ -109,15 +148,28 @@ func RegisterPrometheusHandler(mux httpmux.Mux, resourceManager manager.Manager,
 		opts.Count = 1        // we only want the latest datapoint
 		opts.Recursive = true // get all child containers
 
-		r := prometheus.NewRegistry()
-		r.MustRegister(
-			metrics.NewPrometheusCollector(resourceManager, f, includedMetrics, clock.RealClock{}, opts),
-			machineCollector,
-			goCollector,
-			processCollector,
-		)
-		promhttp.HandlerFor(r, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, req)
+		collector := metrics.NewPrometheusCollector(resourceManager, f, includedMetrics, clock.RealClock{}, opts)
+		if err := collector.SetRelabeler(promOpts.Relabeler); err != nil {
+			http.Error(w, "No metrics gathered, last error:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		collector.SetNativeHistograms(promOpts.NativeHistograms)
+		collector.SetExemplarLabel(promOpts.ExemplarLabel)
+		// Containers collected depend on request options.
+		key, err := json.Marshal(opts)
+		if err != nil {
+			http.Error(w, "No metrics gathered, last error:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		named := append([]namedCollector{{name: "container", key: "container" + string(key), collector: collector}}, staticCollectors...)
+		gatherer := prometheus.Gatherers{cache.gatherer(named, scrapeDeadline(req, promOpts.ScrapeTimeoutOffset)), timeouts}
+		if promOpts.OpenMetrics && expfmt.NegotiateIncludingOpenMetrics(req.Header) == expfmt.FmtOpenMetrics {
+			serveOpenMetrics(w, req, gatherer)
+			return
+		}
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, req)
 	}))
+	return nil
 }
 
 func staticHandlerNoAuth(w http.ResponseWriter, r *http.Request) {
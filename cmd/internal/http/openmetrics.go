@@ -0,0 +1,154 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/klog/v2"
+)
+
+// started is the time cAdvisor started, since which its own counters count.
+var started = time.Now()
+
+// serveOpenMetrics writes metrics of the gatherer in the OpenMetrics format,
+// with created timestamps of cumulative metrics.
+func serveOpenMetrics(w http.ResponseWriter, req *http.Request, gatherer prometheus.Gatherer) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		// Metrics which were gathered are served, as in other formats.
+		klog.V(4).Infof("Error gathering metrics: %v", err)
+	}
+
+	w.Header().Set("Content-Type", string(expfmt.FmtOpenMetrics))
+	out := io.Writer(w)
+	if gzipAccepted(req.Header) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	buffered := bufio.NewWriter(out)
+	if err := writeOpenMetrics(buffered, families, metrics.CreatedTimestamps(families, started)); err != nil {
+		klog.V(4).Infof("Error writing metrics: %v", err)
+		return
+	}
+	if err := buffered.Flush(); err != nil {
+		klog.V(4).Infof("Error writing metrics: %v", err)
+	}
+}
+
+// gzipAccepted returns true if the client accepts gzip-encoded responses.
+func gzipAccepted(header http.Header) bool {
+	for _, part := range strings.Split(header.Get("Accept-Encoding"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "gzip" || strings.HasPrefix(part, "gzip;") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOpenMetrics writes the families in the OpenMetrics text format, where
+// samples of metrics are followed by a _created sample of their created
+// timestamp, if it is known.
+func writeOpenMetrics(w io.Writer, families []*dto.MetricFamily, created map[*dto.Metric]time.Time) error {
+	var buf bytes.Buffer
+	for _, family := range families {
+		if !hasCreated(family, created) {
+			if _, err := expfmt.MetricFamilyToOpenMetrics(w, family); err != nil {
+				return err
+			}
+			continue
+		}
+		header := &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+		if _, err := expfmt.MetricFamilyToOpenMetrics(w, header); err != nil {
+			return err
+		}
+		createdName := strings.TrimSuffix(family.GetName(), "_total") + "_created"
+		gaugeType := dto.MetricType_GAUGE
+		for _, metric := range family.Metric {
+			// Samples of a metric are written as a family of their own, of which
+			// only sample lines are kept.
+			buf.Reset()
+			single := &dto.MetricFamily{Name: family.Name, Type: family.Type, Metric: []*dto.Metric{metric}}
+			if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, single); err != nil {
+				return err
+			}
+			if t, ok := created[metric]; ok {
+				value := float64(t.UnixNano()) / float64(time.Second)
+				createdMetric := &dto.Metric{Label: metric.Label, Gauge: &dto.Gauge{Value: &value}, TimestampMs: metric.TimestampMs}
+				createdFamily := &dto.MetricFamily{Name: &createdName, Type: &gaugeType, Metric: []*dto.Metric{createdMetric}}
+				if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, createdFamily); err != nil {
+					return err
+				}
+			}
+			if err := writeSampleLines(w, buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+// hasCreated returns true if any metric of the family has a created timestamp
+// which can be written. Counters without the _total suffix are written as
+// unknown metrics, which have no _created samples.
+func hasCreated(family *dto.MetricFamily, created map[*dto.Metric]time.Time) bool {
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		if !strings.HasSuffix(family.GetName(), "_total") {
+			return false
+		}
+	case dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY:
+	default:
+		return false
+	}
+	for _, metric := range family.Metric {
+		if _, ok := created[metric]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSampleLines writes lines of the text which are not comments.
+func writeSampleLines(w io.Writer, text []byte) error {
+	for len(text) > 0 {
+		end := bytes.IndexByte(text, '\n') + 1
+		if end == 0 {
+			end = len(text)
+		}
+		if text[0] != '#' {
+			if _, err := w.Write(text[:end]); err != nil {
+				return err
+			}
+		}
+		text = text[end:]
+	}
+	return nil
+}
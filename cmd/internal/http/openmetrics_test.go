@@ -0,0 +1,67 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total", Help: "Number of requests."}, []string{"code"})
+	requests.WithLabelValues("200").Add(3)
+	requests.WithLabelValues("500").Inc()
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_latency_seconds", Help: "Latency of requests.", Buckets: []float64{1}})
+	latency.Observe(0.5)
+	reg.MustRegister(requests, latency, prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_temperature", Help: "Temperature."}))
+	families, err := reg.Gather()
+	require.Nil(t, err)
+
+	created := map[*dto.Metric]time.Time{}
+	for _, family := range families {
+		for i, metric := range family.GetMetric() {
+			// The second request counter has no created timestamp.
+			if family.GetName() != "test_requests_total" || i == 0 {
+				created[metric] = time.Unix(1500000000, 500000000)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	require.Nil(t, writeOpenMetrics(&buf, families, created))
+	assert.Equal(t, `# HELP test_latency_seconds Latency of requests.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="1.0"} 1
+test_latency_seconds_bucket{le="+Inf"} 1
+test_latency_seconds_sum 0.5
+test_latency_seconds_count 1
+test_latency_seconds_created 1.5000000005e+09
+# HELP test_requests Number of requests.
+# TYPE test_requests counter
+test_requests_total{code="200"} 3.0
+test_requests_created{code="200"} 1.5000000005e+09
+test_requests_total{code="500"} 1.0
+# HELP test_temperature Temperature.
+# TYPE test_temperature gauge
+test_temperature 0.0
+# EOF
+`, buf.String())
+}
@@ -0,0 +1,327 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buffer implements a storage driver buffering stats in front of
+// another storage driver, so stats are not lost while its backend is down.
+package buffer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+
+	"k8s.io/klog/v2"
+)
+
+// initialRetryInterval is the interval before the first retry of a failed push.
+const initialRetryInterval = 100 * time.Millisecond
+
+// Config configures the buffer.
+type Config struct {
+	// Capacity is the maximum number of stats buffered in memory.
+	Capacity int
+	// Dir is the directory stats are spilled to when the memory buffer is full,
+	// empty disables spilling.
+	Dir string
+	// MaxDiskSize is the maximum size in bytes of stats spilled to disk.
+	MaxDiskSize int64
+	// MaxRetryInterval is the maximum interval between retries of a failed push.
+	MaxRetryInterval time.Duration
+	// MaxRetries is the maximum number of retries of a failed push, the stats
+	// are dropped when exceeded. 0 means stats are retried until pushed.
+	MaxRetries int
+	// BatchSize is the maximum number of stats pushed at once to storage
+	// drivers implementing BatchDriver.
+	BatchSize int
+}
+
+// Entry holds stats of a container pushed to the storage driver.
+type Entry struct {
+	ContainerInfo  *info.ContainerInfo  `json:"container_info"`
+	ContainerStats *info.ContainerStats `json:"container_stats"`
+}
+
+// BatchDriver is implemented by storage drivers which write stats in batches.
+// Such drivers lose the whole batch when writing it fails, so the buffer
+// pushes its own batches to them by WriteBatch instead of AddStats and keeps
+// the stats of a failed batch to retry it.
+type BatchDriver interface {
+	// WriteBatch writes the stats synchronously, nothing is kept by the
+	// driver when it fails.
+	WriteBatch(entries []*Entry) error
+}
+
+// Storage pushes stats to the storage driver in the background, retrying
+// failed pushes up to MaxRetries times. Stats are buffered in memory while the
+// storage driver fails, when the buffer is full the oldest stats are spilled to
+// disk, or dropped if spilling is disabled.
+type Storage struct {
+	name   string
+	driver storage.StorageDriver
+	config Config
+
+	lock sync.Mutex
+	// memory holds the newest stats, the oldest first.
+	memory []*Entry
+	// disk holds the oldest stats, it is nil when spilling is disabled.
+	disk *diskQueue
+	// pushing is the number of stats being pushed.
+	pushing int
+	dropped uint64
+	retries uint64
+
+	// wake is signalled when stats are added.
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Stats describes the state of the buffer.
+type Stats struct {
+	// MemoryEntries is the number of stats buffered in memory, including stats being pushed.
+	MemoryEntries int
+	// DiskEntries is the number of stats spilled to disk.
+	DiskEntries int
+	// DiskBytes is the size of stats spilled to disk.
+	DiskBytes int64
+	// Dropped is the number of stats dropped because the buffer was full or
+	// pushing them failed more than MaxRetries times.
+	Dropped uint64
+	// Retries is the number of failed pushes which were retried.
+	Retries uint64
+}
+
+// New returns a storage driver buffering stats pushed to the storage driver
+// with the name. Stats spilled to disk by a previous run are pushed first.
+func New(name string, driver storage.StorageDriver, config Config) (*Storage, error) {
+	s := &Storage{
+		name:   name,
+		driver: driver,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if config.Dir != "" {
+		var err error
+		s.disk, err = openDiskQueue(config.Dir, config.MaxDiskSize)
+		if err != nil {
+			return nil, err
+		}
+		if n := s.disk.len(); n > 0 {
+			klog.Infof("Pushing %d stats spilled to %q to storage driver %q", n, config.Dir, name)
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *Storage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	err := func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.memory = append(s.memory, &Entry{ContainerInfo: cInfo, ContainerStats: stats})
+		if len(s.memory) <= s.config.Capacity {
+			return nil
+		}
+		oldest := s.memory[0]
+		s.memory[0] = nil
+		s.memory = s.memory[1:]
+		if s.disk == nil {
+			s.dropped++
+			return nil
+		}
+		if err := s.disk.push(oldest); err != nil {
+			s.dropped++
+			return fmt.Errorf("failed to spill stats of storage driver %q to disk: %v", s.name, err)
+		}
+		return nil
+	}()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// Stats returns the state of the buffer.
+func (s *Storage) Stats() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := Stats{
+		MemoryEntries: len(s.memory) + s.pushing,
+		Dropped:       s.dropped,
+		Retries:       s.retries,
+	}
+	if s.disk != nil {
+		stats.DiskEntries = s.disk.len()
+		stats.DiskBytes = s.disk.size
+		stats.Dropped += s.disk.dropped
+	}
+	return stats
+}
+
+func (s *Storage) run() {
+	defer close(s.done)
+	for {
+		batch := s.next()
+		if len(batch) == 0 {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+		pushed := s.push(batch)
+		s.lock.Lock()
+		s.pushing = 0
+		if !pushed {
+			// Keep the stats to be persisted on Close.
+			s.memory = append(batch, s.memory...)
+		}
+		s.lock.Unlock()
+		if !pushed {
+			return
+		}
+	}
+}
+
+// batchSize returns the maximum number of stats pushed at once.
+func (s *Storage) batchSize() int {
+	if _, ok := s.driver.(BatchDriver); ok && s.config.BatchSize > 1 {
+		return s.config.BatchSize
+	}
+	return 1
+}
+
+// next removes the oldest stats from the buffer, up to the batch size, it
+// returns nothing if the buffer is empty.
+func (s *Storage) next() []*Entry {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	size := s.batchSize()
+	var batch []*Entry
+	for s.disk != nil && s.disk.len() > 0 && len(batch) < size {
+		e, err := s.disk.pop()
+		if err != nil {
+			klog.Errorf("Failed to read stats of storage driver %q spilled to disk: %v", s.name, err)
+		}
+		if e != nil {
+			batch = append(batch, e)
+		}
+	}
+	n := size - len(batch)
+	if n > len(s.memory) {
+		n = len(s.memory)
+	}
+	batch = append(batch, s.memory[:n]...)
+	for i := 0; i < n; i++ {
+		s.memory[i] = nil
+	}
+	s.memory = s.memory[n:]
+	s.pushing = len(batch)
+	return batch
+}
+
+// write pushes the stats to the storage driver once.
+func (s *Storage) write(batch []*Entry) error {
+	if driver, ok := s.driver.(BatchDriver); ok {
+		return driver.WriteBatch(batch)
+	}
+	// Batches of other drivers hold single stats.
+	for _, e := range batch {
+		if err := s.driver.AddStats(e.ContainerInfo, e.ContainerStats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// push pushes the stats to the storage driver until it succeeds or fails more
+// than MaxRetries times, it returns false if the buffer was closed before.
+func (s *Storage) push(batch []*Entry) bool {
+	interval := initialRetryInterval
+	for retries := 0; ; retries++ {
+		err := s.write(batch)
+		if err == nil {
+			return true
+		}
+		if s.config.MaxRetries > 0 && retries >= s.config.MaxRetries {
+			klog.Errorf("Dropping %d stats which storage driver %q failed to push %d times: %v", len(batch), s.name, retries+1, err)
+			s.lock.Lock()
+			s.dropped += uint64(len(batch))
+			s.lock.Unlock()
+			return true
+		}
+		s.lock.Lock()
+		s.retries++
+		s.lock.Unlock()
+		klog.Warningf("Failed to push %d stats to storage driver %q, retrying in %v: %v", len(batch), s.name, interval, err)
+		select {
+		case <-time.After(interval):
+		case <-s.stop:
+			return false
+		}
+		interval *= 2
+		if interval > s.config.MaxRetryInterval {
+			interval = s.config.MaxRetryInterval
+		}
+	}
+}
+
+// Close stops pushing stats and closes the storage driver. Stats left in
+// memory are spilled to disk to be pushed by the next run, if spilling is
+// disabled they are pushed once without retries.
+func (s *Storage) Close() error {
+	close(s.stop)
+	<-s.done
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.disk != nil {
+		for i, e := range s.memory {
+			if err := s.disk.push(e); err != nil {
+				klog.Errorf("Failed to spill %d stats of storage driver %q to disk: %v", len(s.memory)-i, s.name, err)
+				s.dropped += uint64(len(s.memory) - i)
+				break
+			}
+		}
+		s.memory = nil
+		if err := s.disk.close(); err != nil {
+			klog.Errorf("Failed to close stats of storage driver %q spilled to disk: %v", s.name, err)
+		}
+	} else {
+		size := s.batchSize()
+		for i := 0; i < len(s.memory); i += size {
+			end := i + size
+			if end > len(s.memory) {
+				end = len(s.memory)
+			}
+			if err := s.write(s.memory[i:end]); err != nil {
+				klog.Errorf("Dropping %d stats buffered for storage driver %q: %v", len(s.memory)-i, s.name, err)
+				s.dropped += uint64(len(s.memory) - i)
+				break
+			}
+		}
+		s.memory = nil
+	}
+	return s.driver.Close()
+}
@@ -0,0 +1,292 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver fails pushes while it is down, stats with the rejected value
+// always fail.
+type fakeDriver struct {
+	lock     sync.Mutex
+	down     bool
+	rejected *uint64
+	pushed   []uint64
+	closed   bool
+}
+
+func (d *fakeDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.down {
+		return errors.New("backend is down")
+	}
+	if d.rejected != nil && stats.Memory.Usage == *d.rejected {
+		return errors.New("stats are rejected")
+	}
+	d.pushed = append(d.pushed, stats.Memory.Usage)
+	return nil
+}
+
+func (d *fakeDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+func (d *fakeDriver) setDown(down bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.down = down
+}
+
+func (d *fakeDriver) pushedStats() []uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]uint64{}, d.pushed...)
+}
+
+// fakeBatchDriver writes batches of stats, a failed batch is not written at all.
+type fakeBatchDriver struct {
+	fakeDriver
+	batches []int
+}
+
+func (d *fakeBatchDriver) WriteBatch(entries []*Entry) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.down {
+		return errors.New("backend is down")
+	}
+	for _, e := range entries {
+		d.pushed = append(d.pushed, e.ContainerStats.Memory.Usage)
+	}
+	d.batches = append(d.batches, len(entries))
+	return nil
+}
+
+func addStats(t *testing.T, s *Storage, from, to uint64) {
+	for i := from; i < to; i++ {
+		cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/a"}}
+		require.Nil(t, s.AddStats(cInfo, &info.ContainerStats{Memory: info.MemoryStats{Usage: i}}))
+	}
+}
+
+func sequence(from, to uint64) []uint64 {
+	var values []uint64
+	for i := from; i < to; i++ {
+		values = append(values, i)
+	}
+	return values
+}
+
+// waitForStats waits until the buffer is empty.
+func waitForStats(t *testing.T, s *Storage) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		stats := s.Stats()
+		if stats.MemoryEntries == 0 && stats.DiskEntries == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("buffer was not emptied: %+v", s.Stats())
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cadvisor-storage-buffer")
+	require.Nil(t, err)
+	return dir
+}
+
+func TestRetriesWhileDown(t *testing.T) {
+	driver := &fakeDriver{down: true}
+	s, err := New("fake", driver, Config{Capacity: 10, MaxRetryInterval: 10 * time.Millisecond})
+	require.Nil(t, err)
+	addStats(t, s, 0, 5)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, driver.pushedStats())
+	assert.Equal(t, 5, s.Stats().MemoryEntries)
+
+	driver.setDown(false)
+	waitForStats(t, s)
+	assert.Equal(t, sequence(0, 5), driver.pushedStats())
+	stats := s.Stats()
+	assert.NotZero(t, stats.Retries)
+	assert.Zero(t, stats.Dropped)
+	require.Nil(t, s.Close())
+	assert.True(t, driver.closed)
+}
+
+func TestDropsStatsFailingTooManyTimes(t *testing.T) {
+	rejected := uint64(1)
+	driver := &fakeDriver{rejected: &rejected}
+	s, err := New("fake", driver, Config{Capacity: 10, MaxRetryInterval: 10 * time.Millisecond, MaxRetries: 2})
+	require.Nil(t, err)
+	addStats(t, s, 0, 4)
+	waitForStats(t, s)
+	// Rejected stats do not block stats behind them.
+	assert.Equal(t, []uint64{0, 2, 3}, driver.pushedStats())
+	stats := s.Stats()
+	assert.Equal(t, uint64(2), stats.Retries)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	require.Nil(t, s.Close())
+}
+
+func TestPushesBatchesWhileDown(t *testing.T) {
+	driver := &fakeBatchDriver{fakeDriver: fakeDriver{down: true}}
+	s, err := New("fake", driver, Config{Capacity: 10, MaxRetryInterval: 10 * time.Millisecond, BatchSize: 3})
+	require.Nil(t, err)
+	addStats(t, s, 0, 1)
+	for s.Stats().Retries == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	addStats(t, s, 1, 8)
+	assert.Equal(t, 8, s.Stats().MemoryEntries)
+
+	driver.setDown(false)
+	waitForStats(t, s)
+	// Stats of the failed batch are not lost.
+	assert.Equal(t, sequence(0, 8), driver.pushedStats())
+	driver.lock.Lock()
+	assert.Equal(t, []int{1, 3, 3, 1}, driver.batches)
+	driver.lock.Unlock()
+	require.Nil(t, s.Close())
+}
+
+func TestDropsOldestWhenFull(t *testing.T) {
+	driver := &fakeDriver{down: true}
+	s, err := New("fake", driver, Config{Capacity: 3, MaxRetryInterval: 10 * time.Millisecond})
+	require.Nil(t, err)
+	addStats(t, s, 0, 1)
+	// Wait for the first stats to be pushed, they are kept while retried.
+	for s.Stats().Retries == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	addStats(t, s, 1, 10)
+	assert.Equal(t, uint64(6), s.Stats().Dropped)
+
+	driver.setDown(false)
+	waitForStats(t, s)
+	assert.Equal(t, []uint64{0, 7, 8, 9}, driver.pushedStats())
+	require.Nil(t, s.Close())
+}
+
+func TestSpillsToDisk(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	driver := &fakeDriver{down: true}
+	s, err := New("fake", driver, Config{Capacity: 2, Dir: dir, MaxDiskSize: 1024 * 1024, MaxRetryInterval: 10 * time.Millisecond})
+	require.Nil(t, err)
+	addStats(t, s, 0, 10)
+	stats := s.Stats()
+	assert.NotZero(t, stats.DiskEntries)
+	assert.NotZero(t, stats.DiskBytes)
+	assert.Zero(t, stats.Dropped)
+
+	driver.setDown(false)
+	waitForStats(t, s)
+	assert.Equal(t, sequence(0, 10), driver.pushedStats())
+	require.Nil(t, s.Close())
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Empty(t, files)
+}
+
+func TestPushesSpilledStatsAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	config := Config{Capacity: 2, Dir: dir, MaxDiskSize: 1024 * 1024, MaxRetryInterval: 10 * time.Millisecond}
+	driver := &fakeDriver{down: true}
+	s, err := New("fake", driver, config)
+	require.Nil(t, err)
+	addStats(t, s, 0, 10)
+	require.Nil(t, s.Close())
+	assert.Empty(t, driver.pushedStats())
+
+	driver = &fakeDriver{}
+	s, err = New("fake", driver, config)
+	require.Nil(t, err)
+	addStats(t, s, 10, 12)
+	waitForStats(t, s)
+	pushed := driver.pushedStats()
+	require.Len(t, pushed, 12)
+	// Stats spilled before the restart are pushed first.
+	assert.ElementsMatch(t, sequence(0, 10), pushed[:10])
+	assert.Equal(t, sequence(10, 12), pushed[10:])
+	require.Nil(t, s.Close())
+}
+
+func TestDropsOldestSegmentsWhenDiskIsFull(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	line, err := json.Marshal(&Entry{
+		ContainerInfo:  &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/a"}},
+		ContainerStats: &info.ContainerStats{Memory: info.MemoryStats{Usage: 99}},
+	})
+	require.Nil(t, err)
+	maxDiskSize := int64(10 * (len(line) + 1))
+	driver := &fakeDriver{down: true}
+	s, err := New("fake", driver, Config{Capacity: 1, Dir: dir, MaxDiskSize: maxDiskSize, MaxRetryInterval: 10 * time.Millisecond})
+	require.Nil(t, err)
+	addStats(t, s, 0, 100)
+	stats := s.Stats()
+	assert.True(t, stats.DiskBytes <= maxDiskSize, "disk size %d exceeds the maximum", stats.DiskBytes)
+	assert.NotZero(t, stats.Dropped)
+	// Stats were pushed, dropped or are buffered.
+	buffered := uint64(stats.MemoryEntries + stats.DiskEntries)
+	assert.Equal(t, uint64(100), stats.Dropped+buffered)
+
+	driver.setDown(false)
+	waitForStats(t, s)
+	assert.Equal(t, buffered, uint64(len(driver.pushedStats())))
+	require.Nil(t, s.Close())
+}
+
+func TestCollector(t *testing.T) {
+	driver := &fakeDriver{down: true}
+	s, err := New("fake", driver, Config{Capacity: 2, MaxRetryInterval: 10 * time.Millisecond})
+	require.Nil(t, err)
+	defer s.Close()
+	addStats(t, s, 0, 1)
+	for s.Stats().Retries == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	addStats(t, s, 1, 5)
+
+	expected := `
+# HELP cadvisor_storage_buffer_dropped_total Number of container stats dropped because the buffer of the storage driver was full or pushing them failed too many times.
+# TYPE cadvisor_storage_buffer_dropped_total counter
+cadvisor_storage_buffer_dropped_total{driver="fake"} 2
+# HELP cadvisor_storage_buffer_entries Number of container stats buffered for the storage driver.
+# TYPE cadvisor_storage_buffer_entries gauge
+cadvisor_storage_buffer_entries{driver="fake",location="memory"} 3
+`
+	assert.Nil(t, testutil.CollectAndCompare(NewCollector([]*Storage{s}), strings.NewReader(expected),
+		"cadvisor_storage_buffer_dropped_total", "cadvisor_storage_buffer_entries"))
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	entriesDesc = prometheus.NewDesc(
+		"cadvisor_storage_buffer_entries",
+		"Number of container stats buffered for the storage driver.",
+		[]string{"driver", "location"}, nil)
+	diskBytesDesc = prometheus.NewDesc(
+		"cadvisor_storage_buffer_disk_bytes",
+		"Size in bytes of container stats of the storage driver spilled to disk.",
+		[]string{"driver"}, nil)
+	droppedDesc = prometheus.NewDesc(
+		"cadvisor_storage_buffer_dropped_total",
+		"Number of container stats dropped because the buffer of the storage driver was full or pushing them failed too many times.",
+		[]string{"driver"}, nil)
+	retriesDesc = prometheus.NewDesc(
+		"cadvisor_storage_buffer_retries_total",
+		"Number of failed pushes of container stats to the storage driver which were retried.",
+		[]string{"driver"}, nil)
+)
+
+// Collector exports the state of buffers as Prometheus metrics.
+type Collector struct {
+	storages []*Storage
+}
+
+// NewCollector returns a collector of the buffers.
+func NewCollector(storages []*Storage) *Collector {
+	return &Collector{storages: storages}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- entriesDesc
+	ch <- diskBytesDesc
+	ch <- droppedDesc
+	ch <- retriesDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.storages {
+		stats := s.Stats()
+		ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(stats.MemoryEntries), s.name, "memory")
+		if s.disk != nil {
+			ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(stats.DiskEntries), s.name, "disk")
+			ch <- prometheus.MustNewConstMetric(diskBytesDesc, prometheus.GaugeValue, float64(stats.DiskBytes), s.name)
+		}
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(stats.Dropped), s.name)
+		ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(stats.Retries), s.name)
+	}
+}
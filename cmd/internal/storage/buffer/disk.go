@@ -0,0 +1,294 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	segmentSuffix = ".jsonl"
+	// maxSegmentSize is the maximum size of a segment, segments are smaller when
+	// the maximum size of the queue is small.
+	maxSegmentSize = 16 * 1024 * 1024
+)
+
+// segment is a file holding stats as JSON lines, segments are named by
+// sequence numbers in the order they were written.
+type segment struct {
+	seq   uint64
+	size  int64
+	count int
+}
+
+// diskQueue is a queue of stats stored in segment files in a directory. The
+// oldest segment is removed when the queue exceeds its maximum size. Stats of
+// a segment are read at once and the segment is removed once they are all
+// popped, so stats may be pushed twice if cAdvisor crashes in between.
+type diskQueue struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	// segments are the oldest first, the last one is written to.
+	segments []*segment
+	writer   *os.File
+	nextSeq  uint64
+	// reading holds stats left of the oldest segment when it was read.
+	reading []*Entry
+	loaded  bool
+
+	// size is the total size of segments.
+	size int64
+	// count is the number of stats in the queue.
+	count   int
+	dropped uint64
+}
+
+func openDiskQueue(dir string, maxSize int64) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	q := &diskQueue{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: maxSize / 8,
+	}
+	if q.segmentSize > maxSegmentSize {
+		q.segmentSize = maxSegmentSize
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), segmentSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(file.Name(), segmentSuffix) {
+			continue
+		}
+		count, err := countLines(q.path(seq))
+		if err != nil {
+			return nil, err
+		}
+		q.segments = append(q.segments, &segment{seq: seq, size: file.Size(), count: count})
+		q.size += file.Size()
+		q.count += count
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+	if len(q.segments) > 0 {
+		q.nextSeq = q.segments[len(q.segments)-1].seq + 1
+	}
+	return q, nil
+}
+
+func countLines(name string) (int, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.Count(b, []byte{'\n'}), nil
+}
+
+func (q *diskQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+}
+
+func (q *diskQueue) len() int {
+	return q.count
+}
+
+// push appends the stats to the queue, removing the oldest segments if the
+// queue exceeds its maximum size.
+func (q *diskQueue) push(e *Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if q.writer == nil || (q.last().size > 0 && q.last().size+int64(len(line)) > q.segmentSize) {
+		if err := q.startSegment(); err != nil {
+			return err
+		}
+	}
+	n, err := q.writer.Write(line)
+	q.last().size += int64(n)
+	q.size += int64(n)
+	if err != nil {
+		return err
+	}
+	q.last().count++
+	q.count++
+
+	for q.size > q.maxSize && len(q.segments) > 1 {
+		oldest := q.segments[0]
+		dropped := oldest.count
+		if q.loaded {
+			dropped = len(q.reading)
+			q.reading = nil
+			q.loaded = false
+		}
+		if dropped > 0 {
+			klog.Warningf("Dropping %d stats spilled to %q, the maximum size was exceeded", dropped, q.dir)
+			q.dropped += uint64(dropped)
+		}
+		q.removeOldest(dropped)
+	}
+	return nil
+}
+
+func (q *diskQueue) last() *segment {
+	return q.segments[len(q.segments)-1]
+}
+
+// startSegment closes the segment being written and creates a new one.
+func (q *diskQueue) startSegment() error {
+	if err := q.closeWriter(); err != nil {
+		return err
+	}
+	writer, err := os.OpenFile(q.path(q.nextSeq), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	q.writer = writer
+	q.segments = append(q.segments, &segment{seq: q.nextSeq})
+	q.nextSeq++
+	return nil
+}
+
+func (q *diskQueue) closeWriter() error {
+	if q.writer == nil {
+		return nil
+	}
+	err := q.writer.Close()
+	q.writer = nil
+	return err
+}
+
+// removeOldest removes the oldest segment with the number of stats left in it.
+func (q *diskQueue) removeOldest(left int) {
+	oldest := q.segments[0]
+	if len(q.segments) == 1 {
+		q.closeWriter()
+	}
+	if err := os.Remove(q.path(oldest.seq)); err != nil && !os.IsNotExist(err) {
+		klog.Errorf("Failed to remove %q: %v", q.path(oldest.seq), err)
+	}
+	q.segments[0] = nil
+	q.segments = q.segments[1:]
+	q.size -= oldest.size
+	q.count -= left
+}
+
+// pop removes the oldest stats from the queue, it returns nil if the queue is
+// empty. Unreadable stats are dropped.
+func (q *diskQueue) pop() (*Entry, error) {
+	var loadErr error
+	for !q.loaded || len(q.reading) == 0 {
+		if q.loaded {
+			q.removeOldest(0)
+			q.loaded = false
+		}
+		if len(q.segments) == 0 {
+			return nil, loadErr
+		}
+		if err := q.load(); err != nil {
+			loadErr = err
+		}
+	}
+	e := q.reading[0]
+	q.reading[0] = nil
+	q.reading = q.reading[1:]
+	q.count--
+	if len(q.reading) == 0 {
+		q.removeOldest(0)
+		q.loaded = false
+	}
+	return e, loadErr
+}
+
+// load reads stats of the oldest segment.
+func (q *diskQueue) load() error {
+	oldest := q.segments[0]
+	if len(q.segments) == 1 {
+		// Stats pushed from now on go to a new segment.
+		if err := q.closeWriter(); err != nil {
+			return err
+		}
+	}
+	b, err := ioutil.ReadFile(q.path(oldest.seq))
+	if err != nil {
+		q.dropped += uint64(oldest.count)
+		q.removeOldest(oldest.count)
+		return err
+	}
+	var entries []*Entry
+	var invalid int
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+	for scanner.Scan() {
+		e := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil || e.ContainerInfo == nil || e.ContainerStats == nil {
+			invalid++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	// Invalid lines, e.g. a partial line left by a crash, are not in the queue.
+	q.count -= oldest.count - len(entries)
+	oldest.count = len(entries)
+	q.reading = entries
+	q.loaded = true
+	if invalid > 0 {
+		q.dropped += uint64(invalid)
+		return fmt.Errorf("dropped %d invalid stats in %q", invalid, q.path(oldest.seq))
+	}
+	return nil
+}
+
+// close closes the segment being written and rewrites the segment being read
+// with stats left in it, so popped stats are not read again.
+func (q *diskQueue) close() error {
+	if err := q.closeWriter(); err != nil {
+		return err
+	}
+	if !q.loaded {
+		return nil
+	}
+	var b []byte
+	for _, e := range q.reading {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b = append(append(b, line...), '\n')
+	}
+	name := q.path(q.segments[0].seq)
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -15,42 +15,96 @@
 package elasticsearch
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
 	info "github.com/google/cadvisor/info/v1"
 	storage "github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/version"
 
-	"gopkg.in/olivere/elastic.v2"
+	"k8s.io/klog/v2"
 )
 
 func init() {
 	storage.RegisterStorageDriver("elasticsearch", new)
 }
 
+const (
+	requestTimeout = 30 * time.Second
+	minBackoff     = 100 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
 type elasticStorage struct {
-	client      *elastic.Client
+	config      config
+	client      *http.Client
 	machineName string
-	indexName   string
-	typeName    string
-	lock        sync.Mutex
+
+	lock sync.Mutex
+	// Index of the host requests are sent to, the next host is used after connection errors.
+	host    int
+	queue   []bulkItem
+	dropped int
+
+	// flush is signalled when a full batch is queued.
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type config struct {
+	hosts         []string
+	indexName     string
+	dataStream    bool
+	dateSuffix    string
+	apiKey        string
+	username      string
+	password      string
+	flushInterval time.Duration
+	batchSize     int
+	queueCapacity int
 }
 
 type detailSpec struct {
+	// Timestamp in RFC 3339 format, required by data streams.
+	Time           time.Time            `json:"@timestamp"`
 	Timestamp      int64                `json:"timestamp"`
 	MachineName    string               `json:"machine_name,omitempty"`
 	ContainerName  string               `json:"container_Name,omitempty"`
 	ContainerStats *info.ContainerStats `json:"container_stats,omitempty"`
 }
 
+// bulkItem is a document waiting to be indexed.
+type bulkItem struct {
+	index    string
+	document []byte
+}
+
 var (
-	argElasticHost   = flag.String("storage_driver_es_host", "http://localhost:9200", "ElasticSearch host:port")
-	argIndexName     = flag.String("storage_driver_es_index", "cadvisor", "ElasticSearch index name")
-	argTypeName      = flag.String("storage_driver_es_type", "stats", "ElasticSearch type name")
-	argEnableSniffer = flag.Bool("storage_driver_es_enable_sniffer", false, "ElasticSearch uses a sniffing process to find all nodes of your cluster by default, automatically")
+	argElasticHost     = flag.String("storage_driver_es_host", "http://localhost:9200", "ElasticSearch or OpenSearch URL, multiple comma-separated URLs of nodes of the cluster are tried in order when a node is not reachable")
+	argIndexName       = flag.String("storage_driver_es_index", "cadvisor", "ElasticSearch index, index alias or data stream name")
+	argDataStream      = flag.Bool("storage_driver_es_data_stream", false, "write stats to the data stream given by -storage_driver_es_index, requires ElasticSearch 7.9+ or OpenSearch 1.0+ and a matching index template")
+	argIndexDateSuffix = flag.String("storage_driver_es_index_date_suffix", "", "optional date suffix appended to the index name, e.g. '2006.01.02' for daily indices, formatted as time.Format layout of the stats timestamp in UTC")
+	argAPIKey          = flag.String("storage_driver_es_api_key", "", "optional ElasticSearch API key, base64 encoded 'id:api_key'")
+	argUsername        = flag.String("storage_driver_es_username", "", "optional username for basic authentication")
+	argPassword        = flag.String("storage_driver_es_password", "", "optional password for basic authentication")
+	argCaFile          = flag.String("storage_driver_es_ca", "", "optional certificate authority file used to verify ElasticSearch nodes")
+	argBatchSize       = flag.Int("storage_driver_es_batch_size", 1000, "maximum number of documents indexed in a single bulk request")
+	argQueueCapacity   = flag.Int("storage_driver_es_queue_capacity", 10000, "maximum number of documents buffered in memory, the oldest documents are dropped when ElasticSearch can not keep up")
+	argTypeName        = flag.String("storage_driver_es_type", "stats", "Deprecated: mapping types are not supported since ElasticSearch 7, the value is ignored")
+	argEnableSniffer   = flag.Bool("storage_driver_es_enable_sniffer", false, "Deprecated: use multiple URLs in -storage_driver_es_host instead, the value is ignored")
 )
 
 func new() (storage.StorageDriver, error) {
@@ -58,13 +112,37 @@ func new() (storage.StorageDriver, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newStorage(
-		hostname,
-		*argIndexName,
-		*argTypeName,
-		*argElasticHost,
-		*argEnableSniffer,
-	)
+	if *argEnableSniffer {
+		klog.Warning("-storage_driver_es_enable_sniffer is deprecated and ignored")
+	}
+	if *argTypeName != "stats" {
+		klog.Warning("-storage_driver_es_type is deprecated and ignored")
+	}
+	client := &http.Client{Timeout: requestTimeout}
+	if *argCaFile != "" {
+		caCert, err := ioutil.ReadFile(*argCaFile)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: caCertPool},
+		}
+	}
+	return newStorage(hostname, client, config{
+		hosts:         strings.Split(*argElasticHost, ","),
+		indexName:     *argIndexName,
+		dataStream:    *argDataStream,
+		dateSuffix:    *argIndexDateSuffix,
+		apiKey:        *argAPIKey,
+		username:      *argUsername,
+		password:      *argPassword,
+		flushInterval: *storage.ArgDbBufferDuration,
+		batchSize:     *argBatchSize,
+		queueCapacity: *argQueueCapacity,
+	})
 }
 
 func (s *elasticStorage) containerStatsAndDefaultValues(
@@ -77,6 +155,7 @@ func (s *elasticStorage) containerStatsAndDefaultValues(
 		containerName = cInfo.ContainerReference.Name
 	}
 	detail := &detailSpec{
+		Time:           stats.Timestamp.UTC(),
 		Timestamp:      timestamp,
 		MachineName:    s.machineName,
 		ContainerName:  containerName,
@@ -85,74 +164,261 @@ func (s *elasticStorage) containerStatsAndDefaultValues(
 	return detail
 }
 
+// indexFor returns the index documents with the timestamp are written to.
+func (s *elasticStorage) indexFor(timestamp time.Time) string {
+	if s.config.dateSuffix == "" {
+		return s.config.indexName
+	}
+	return s.config.indexName + "-" + timestamp.UTC().Format(s.config.dateSuffix)
+}
+
 func (s *elasticStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
 	if stats == nil {
 		return nil
 	}
-	func() {
-		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
-		s.lock.Lock()
-		defer s.lock.Unlock()
-		// Add some default params based on ContainerStats
-		detail := s.containerStatsAndDefaultValues(cInfo, stats)
-		// Index a cadvisor (using JSON serialization)
-		_, err := s.client.Index().
-			Index(s.indexName).
-			Type(s.typeName).
-			BodyJson(detail).
-			Do()
-		if err != nil {
-			// Handle error
-			fmt.Printf("failed to write stats to ElasticSearch - %s", err)
-			return
+	// Add some default params based on ContainerStats
+	detail := s.containerStatsAndDefaultValues(cInfo, stats)
+	document, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queue = append(s.queue, bulkItem{index: s.indexFor(stats.Timestamp), document: document})
+	if overflow := len(s.queue) - s.config.queueCapacity; overflow > 0 {
+		s.queue = s.queue[overflow:]
+		s.dropped += overflow
+	}
+	if len(s.queue) >= s.config.batchSize {
+		select {
+		case s.flush <- struct{}{}:
+		default:
 		}
-	}()
+	}
 	return nil
 }
 
+// WriteBatch indexes the stats with a single bulk request, it is used by the storage buffer instead of AddStats.
+// Documents indexed by a partially rejected request are indexed again when the buffer retries it.
+func (s *elasticStorage) WriteBatch(entries []*buffer.Entry) error {
+	batch := make([]bulkItem, 0, len(entries))
+	for _, e := range entries {
+		document, err := json.Marshal(s.containerStatsAndDefaultValues(e.ContainerInfo, e.ContainerStats))
+		if err != nil {
+			return err
+		}
+		batch = append(batch, bulkItem{index: s.indexFor(e.ContainerStats.Timestamp), document: document})
+	}
+	retry, err := s.bulk(batch)
+	if len(retry) == 0 && err != nil {
+		// Documents which can not be indexed are not retried.
+		klog.Errorf("Failed to write stats to ElasticSearch: %v", err)
+		return nil
+	}
+	return err
+}
+
 func (s *elasticStorage) Close() error {
-	s.client = nil
+	close(s.stop)
+	<-s.done
 	return nil
 }
 
-// machineName: A unique identifier to identify the host that current cAdvisor
-// instance is running on.
-// ElasticHost: The host which runs ElasticSearch.
-func newStorage(
-	machineName,
-	indexName,
-	typeName,
-	elasticHost string,
-	enableSniffer bool,
-) (storage.StorageDriver, error) {
-	// Obtain a client and connect to the default Elasticsearch installation
-	// on 127.0.0.1:9200. Of course you can configure your client to connect
-	// to other hosts and configure it in various other ways.
-	client, err := elastic.NewClient(
-		elastic.SetHealthcheck(true),
-		elastic.SetSniff(enableSniffer),
-		elastic.SetHealthcheckInterval(30*time.Second),
-		elastic.SetURL(elasticHost),
-	)
-	if err != nil {
-		// Handle error
-		return nil, fmt.Errorf("failed to create the elasticsearch client - %s", err)
+func newStorage(machineName string, client *http.Client, config config) (*elasticStorage, error) {
+	if config.batchSize <= 0 {
+		return nil, fmt.Errorf("elasticsearch batch size must be positive, got %d", config.batchSize)
 	}
-
-	// Ping the Elasticsearch server to get e.g. the version number
-	info, code, err := client.Ping().URL(elasticHost).Do()
-	if err != nil {
-		// Handle error
-		return nil, fmt.Errorf("failed to ping the elasticsearch - %s", err)
-
+	if config.queueCapacity < config.batchSize {
+		return nil, fmt.Errorf("elasticsearch queue capacity (%d) must not be lower than batch size (%d)", config.queueCapacity, config.batchSize)
+	}
+	for i, host := range config.hosts {
+		config.hosts[i] = strings.TrimSuffix(strings.TrimSpace(host), "/")
 	}
-	fmt.Printf("Elasticsearch returned with code %d and version %s", code, info.Version.Number)
-
 	ret := &elasticStorage{
+		config:      config,
 		client:      client,
 		machineName: machineName,
-		indexName:   indexName,
-		typeName:    typeName,
+		flush:       make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
 	}
+	go ret.run()
 	return ret, nil
 }
+
+// run indexes queued documents every flush interval or as soon as a full
+// batch is queued, until the storage is closed.
+func (s *elasticStorage) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.flushInterval)
+	defer ticker.Stop()
+	for {
+		stopped := false
+		select {
+		case <-s.stop:
+			stopped = true
+		case <-ticker.C:
+		case <-s.flush:
+		}
+		for batch := s.nextBatch(); len(batch) > 0; batch = s.nextBatch() {
+			if !s.indexWithRetries(batch, stopped) {
+				return
+			}
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// nextBatch removes up to batch size of the oldest documents from the queue.
+func (s *elasticStorage) nextBatch() []bulkItem {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.dropped > 0 {
+		klog.Warningf("ElasticSearch queue is full, dropped %d oldest documents", s.dropped)
+		s.dropped = 0
+	}
+	n := len(s.queue)
+	if n > s.config.batchSize {
+		n = s.config.batchSize
+	}
+	batch := s.queue[:n:n]
+	s.queue = s.queue[n:]
+	return batch
+}
+
+// indexWithRetries indexes the batch. Documents rejected because the cluster
+// is overloaded, or not sent at all, are retried with exponential backoff. It
+// returns false if the storage was closed meanwhile, or when retries are not
+// allowed because the storage is being closed.
+func (s *elasticStorage) indexWithRetries(batch []bulkItem, stopped bool) bool {
+	backoff := minBackoff
+	for {
+		retry, err := s.bulk(batch)
+		if len(retry) == 0 {
+			if err != nil {
+				klog.Errorf("Failed to write stats to ElasticSearch: %v", err)
+			}
+			return true
+		}
+		if stopped {
+			klog.Warningf("Dropping %d documents on shutdown: %v", len(retry), err)
+			return false
+		}
+		klog.V(2).Infof("Retrying %d documents in %v: %v", len(retry), backoff, err)
+		select {
+		case <-s.stop:
+			klog.Warningf("Dropping %d documents on shutdown: %v", len(retry), err)
+			return false
+		case <-time.After(backoff):
+		}
+		batch = retry
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+type bulkResponse struct {
+	Errors bool                                `json:"errors"`
+	Items  []map[string]bulkResponseItemStatus `json:"items"`
+}
+
+type bulkResponseItemStatus struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// retryable returns true for statuses of requests which can succeed later.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// bulk indexes the batch with the bulk API and returns documents which should
+// be retried, with an error describing the failure.
+func (s *elasticStorage) bulk(batch []bulkItem) ([]bulkItem, error) {
+	op := "index"
+	if s.config.dataStream {
+		// Data streams are append-only.
+		op = "create"
+	}
+	var body bytes.Buffer
+	for _, item := range batch {
+		action, err := json.Marshal(map[string]map[string]string{op: {"_index": item.index}})
+		if err != nil {
+			return nil, err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(item.document)
+		body.WriteByte('\n')
+	}
+
+	s.lock.Lock()
+	host := s.config.hosts[s.host]
+	s.lock.Unlock()
+	req, err := http.NewRequest(http.MethodPost, host+"/_bulk", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("User-Agent", fmt.Sprintf("%v/%v", "cAdvisor", version.Info["version"]))
+	if s.config.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.config.apiKey)
+	} else if s.config.username != "" {
+		req.SetBasicAuth(s.config.username, s.config.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Try the next node.
+		s.lock.Lock()
+		s.host = (s.host + 1) % len(s.config.hosts)
+		s.lock.Unlock()
+		return batch, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = fmt.Errorf("bulk request to %s failed with %s: %s", host, resp.Status, bytes.TrimSpace(message))
+		if retryable(resp.StatusCode) {
+			return batch, err
+		}
+		return nil, err
+	}
+
+	var response bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk response from %s: %v", host, err)
+	}
+	if !response.Errors {
+		return nil, nil
+	}
+	var retry []bulkItem
+	failed := 0
+	var lastError json.RawMessage
+	for i, item := range response.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, status := range item {
+			if status.Status < 300 {
+				continue
+			}
+			if retryable(status.Status) {
+				retry = append(retry, batch[i])
+			} else {
+				failed++
+				lastError = status.Error
+			}
+		}
+	}
+	err = fmt.Errorf("%d documents rejected by %s", len(retry), host)
+	if failed > 0 {
+		err = fmt.Errorf("%d documents failed to be indexed in %s, %d rejected, last error: %s", failed, host, len(retry), lastError)
+	}
+	return retry, err
+}
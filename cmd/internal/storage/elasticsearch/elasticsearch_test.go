@@ -0,0 +1,186 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkRequest is a parsed bulk request, with actions and documents.
+type bulkRequest struct {
+	actions   []map[string]map[string]string
+	documents []map[string]interface{}
+}
+
+// fakeCluster records bulk requests and responds with statuses of the items
+// from responses, all items succeed when there are no responses left.
+type fakeCluster struct {
+	t         *testing.T
+	responses [][]int
+	requests  chan *bulkRequest
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(c.t, "/_bulk", r.URL.Path)
+	assert.Equal(c.t, "application/x-ndjson", r.Header.Get("Content-Type"))
+	assert.Equal(c.t, "ApiKey a2V5", r.Header.Get("Authorization"))
+
+	request := &bulkRequest{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		action := map[string]map[string]string{}
+		require.Nil(c.t, json.Unmarshal(scanner.Bytes(), &action))
+		require.True(c.t, scanner.Scan())
+		document := map[string]interface{}{}
+		require.Nil(c.t, json.Unmarshal(scanner.Bytes(), &document))
+		request.actions = append(request.actions, action)
+		request.documents = append(request.documents, document)
+	}
+
+	var statuses []int
+	if len(c.responses) > 0 {
+		statuses, c.responses = c.responses[0], c.responses[1:]
+	}
+	items := make([]string, len(request.actions))
+	errors := false
+	for i := range items {
+		status := http.StatusCreated
+		if i < len(statuses) {
+			status = statuses[i]
+		}
+		errors = errors || status >= 300
+		items[i] = fmt.Sprintf(`{"create": {"status": %d}}`, status)
+	}
+	fmt.Fprintf(w, `{"errors": %t, "items": [%s]}`, errors, strings.Join(items, ","))
+	c.requests <- request
+}
+
+func (c *fakeCluster) waitForRequest() *bulkRequest {
+	select {
+	case request := <-c.requests:
+		return request
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timeout waiting for bulk request")
+		return nil
+	}
+}
+
+func newTestStorage(t *testing.T, cluster *fakeCluster, dataStream bool) (*elasticStorage, *httptest.Server) {
+	cluster.t = t
+	cluster.requests = make(chan *bulkRequest, 10)
+	server := httptest.NewServer(cluster)
+	s, err := newStorage("machine-1", server.Client(), config{
+		// The first host is not reachable.
+		hosts:         []string{"http://127.0.0.1:1", server.URL + "/"},
+		indexName:     "metrics-cadvisor-default",
+		dataStream:    dataStream,
+		apiKey:        "a2V5",
+		flushInterval: time.Hour,
+		batchSize:     2,
+		queueCapacity: 4,
+	})
+	require.Nil(t, err)
+	return s, server
+}
+
+func testStats(sec int64) (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Aliases: []string{"app"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(sec, 0),
+		Memory:    info.MemoryStats{Usage: 200},
+	}
+	return cInfo, stats
+}
+
+func TestBulkDataStream(t *testing.T) {
+	cluster := &fakeCluster{}
+	s, server := newTestStorage(t, cluster, true)
+	defer server.Close()
+
+	require.Nil(t, s.AddStats(testStats(1395066363)))
+	require.Nil(t, s.AddStats(testStats(1395066364)))
+	request := cluster.waitForRequest()
+	require.Nil(t, s.Close())
+
+	require.Len(t, request.actions, 2)
+	assert.Equal(t, map[string]map[string]string{"create": {"_index": "metrics-cadvisor-default"}}, request.actions[0])
+	document := request.documents[0]
+	assert.Equal(t, "2014-03-17T14:26:03Z", document["@timestamp"])
+	assert.Equal(t, float64(1395066363000000), document["timestamp"])
+	assert.Equal(t, "machine-1", document["machine_name"])
+	assert.Equal(t, "app", document["container_Name"])
+}
+
+func TestBulkRetriesRejectedDocuments(t *testing.T) {
+	cluster := &fakeCluster{responses: [][]int{{http.StatusCreated, http.StatusTooManyRequests}}}
+	s, server := newTestStorage(t, cluster, true)
+	defer server.Close()
+
+	require.Nil(t, s.AddStats(testStats(1395066363)))
+	require.Nil(t, s.AddStats(testStats(1395066364)))
+	assert.Len(t, cluster.waitForRequest().documents, 2)
+	retried := cluster.waitForRequest()
+	require.Nil(t, s.Close())
+
+	require.Len(t, retried.documents, 1)
+	assert.Equal(t, "2014-03-17T14:26:04Z", retried.documents[0]["@timestamp"])
+}
+
+func TestBulkDropsFailedDocuments(t *testing.T) {
+	cluster := &fakeCluster{responses: [][]int{{http.StatusBadRequest, http.StatusCreated}}}
+	s, server := newTestStorage(t, cluster, false)
+	defer server.Close()
+
+	require.Nil(t, s.AddStats(testStats(1395066363)))
+	require.Nil(t, s.AddStats(testStats(1395066364)))
+	request := cluster.waitForRequest()
+	require.Nil(t, s.Close())
+
+	assert.Equal(t, map[string]map[string]string{"index": {"_index": "metrics-cadvisor-default"}}, request.actions[0])
+	select {
+	case <-cluster.requests:
+		t.Fatal("failed document must not be retried")
+	default:
+	}
+}
+
+func TestQueueDropsOldestDocuments(t *testing.T) {
+	s := &elasticStorage{config: config{indexName: "cadvisor", batchSize: 10, queueCapacity: 2}, flush: make(chan struct{}, 1)}
+	for i := int64(0); i < 3; i++ {
+		require.Nil(t, s.AddStats(testStats(i)))
+	}
+	require.Len(t, s.queue, 2)
+	assert.Equal(t, 1, s.dropped)
+	assert.Contains(t, string(s.queue[0].document), `"@timestamp":"1970-01-01T00:00:01Z"`)
+}
+
+func TestIndexDateSuffix(t *testing.T) {
+	s := &elasticStorage{config: config{indexName: "cadvisor", dateSuffix: "2006.01.02"}}
+	assert.Equal(t, "cadvisor-2014.03.17", s.indexFor(time.Unix(1395066363, 0)))
+}
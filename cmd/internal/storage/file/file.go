@@ -0,0 +1,304 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/utils/container"
+
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	storage.RegisterStorageDriver("file", new)
+}
+
+// backupTimeFormat is the format of the time of rotation in names of backups,
+// backups sort by name in the order they were rotated.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+var (
+	argPath       = flag.String("storage_driver_file_path", "-", "file stats are written to as JSON lines, - means standard output")
+	argMaxSize    = flag.Int64("storage_driver_file_max_size", 100*1024*1024, "size in bytes the file is rotated at, 0 disables size based rotation")
+	argMaxAge     = flag.Duration("storage_driver_file_max_age", 0, "age the file is rotated at, 0 disables time based rotation")
+	argMaxBackups = flag.Int("storage_driver_file_max_backups", 5, "number of rotated files kept, 0 keeps all of them")
+	argCompress   = flag.Bool("storage_driver_file_compress", false, "compress rotated files with gzip")
+)
+
+type config struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+}
+
+type fileStorage struct {
+	config      config
+	machineName string
+	now         func() time.Time
+
+	lock sync.Mutex
+	out  io.Writer
+	// file is nil when writing to standard output.
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// cleanupLock serializes removal of old backups.
+	cleanupLock sync.Mutex
+	// compressions tracks backups being compressed.
+	compressions sync.WaitGroup
+}
+
+type detailSpec struct {
+	Timestamp       time.Time            `json:"timestamp"`
+	MachineName     string               `json:"machine_name,omitempty"`
+	ContainerName   string               `json:"container_Name,omitempty"`
+	ContainerID     string               `json:"container_Id,omitempty"`
+	ContainerLabels map[string]string    `json:"container_labels,omitempty"`
+	ContainerStats  *info.ContainerStats `json:"container_stats,omitempty"`
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(hostname, config{
+		path:       *argPath,
+		maxSize:    *argMaxSize,
+		maxAge:     *argMaxAge,
+		maxBackups: *argMaxBackups,
+		compress:   *argCompress,
+	}, time.Now)
+}
+
+func newStorage(machineName string, config config, now func() time.Time) (*fileStorage, error) {
+	s := &fileStorage{
+		config:      config,
+		machineName: machineName,
+		now:         now,
+	}
+	if config.path == "-" {
+		s.out = os.Stdout
+		return s, nil
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the file for appending.
+func (s *fileStorage) open() error {
+	file, err := os.OpenFile(s.config.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.out = file
+	s.size = fileInfo.Size()
+	s.openedAt = s.now()
+	return nil
+}
+
+func (s *fileStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	line, err := json.Marshal(&detailSpec{
+		Timestamp:       stats.Timestamp,
+		MachineName:     s.machineName,
+		ContainerName:   container.GetPreferredName(cInfo.ContainerReference),
+		ContainerID:     cInfo.ContainerReference.Id,
+		ContainerLabels: cInfo.Spec.Labels,
+		ContainerStats:  stats,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file != nil && s.shouldRotate(len(line)) {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate %q: %v", s.config.path, err)
+		}
+	}
+	// Lines are written at once, so readers tailing the file never see partial lines.
+	n, err := s.out.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *fileStorage) shouldRotate(n int) bool {
+	if s.config.maxSize > 0 && s.size > 0 && s.size+int64(n) > s.config.maxSize {
+		return true
+	}
+	return s.config.maxAge > 0 && s.now().Sub(s.openedAt) >= s.config.maxAge
+}
+
+// backupPattern returns the glob pattern matching uncompressed backups.
+func (s *fileStorage) backupPattern() string {
+	ext := filepath.Ext(s.config.path)
+	return strings.TrimSuffix(s.config.path, ext) + "-*" + ext
+}
+
+// rotate renames the file to a backup named after the time of the rotation
+// and opens a new file.
+func (s *fileStorage) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+	ext := filepath.Ext(s.config.path)
+	backup := strings.TrimSuffix(s.config.path, ext) + "-" + s.now().UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(s.config.path, backup); err != nil {
+		// Keep appending to the file.
+		if openErr := s.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if s.config.compress {
+		s.compressions.Add(1)
+		go func() {
+			defer s.compressions.Done()
+			if err := compressFile(backup); err != nil {
+				klog.Errorf("Failed to compress %q: %v", backup, err)
+			}
+			s.removeOldBackups()
+		}()
+	} else {
+		s.removeOldBackups()
+	}
+	return nil
+}
+
+// compressFile replaces the file with a gzip compressed one.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	// Write to a temporary file first, so only complete files have the .gz suffix.
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(dst)
+	if _, err := io.Copy(w, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, name+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// removeOldBackups removes the oldest backups when there are more than the maximum.
+func (s *fileStorage) removeOldBackups() {
+	if s.config.maxBackups <= 0 {
+		return
+	}
+	s.cleanupLock.Lock()
+	defer s.cleanupLock.Unlock()
+	pattern := s.backupPattern()
+	uncompressed, err := filepath.Glob(pattern)
+	if err != nil {
+		klog.Errorf("Failed to list backups of %q: %v", s.config.path, err)
+		return
+	}
+	compressed, err := filepath.Glob(pattern + ".gz")
+	if err != nil {
+		klog.Errorf("Failed to list backups of %q: %v", s.config.path, err)
+		return
+	}
+	// A backup being compressed has both files.
+	files := map[string][]string{}
+	for _, name := range uncompressed {
+		// Without an extension of the file, the pattern matches compressed backups too.
+		if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".gz.tmp") {
+			continue
+		}
+		files[name] = append(files[name], name)
+	}
+	for _, name := range compressed {
+		backup := strings.TrimSuffix(name, ".gz")
+		files[backup] = append(files[backup], name)
+	}
+	backups := make([]string, 0, len(files))
+	for backup := range files {
+		backups = append(backups, backup)
+	}
+	sort.Strings(backups)
+	for i := 0; i < len(backups)-s.config.maxBackups; i++ {
+		for _, name := range files[backups[i]] {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				klog.Errorf("Failed to remove backup %q: %v", name, err)
+			}
+		}
+	}
+}
+
+func (s *fileStorage) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.compressions.Wait()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	s.out = nil
+	return err
+}
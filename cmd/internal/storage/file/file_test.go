@@ -0,0 +1,174 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns the time, advanced by a second on every call.
+type fakeClock struct {
+	time time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.time = c.time.Add(time.Second)
+	return c.time
+}
+
+func testStats() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Id: "abcd", Aliases: []string{"app"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0).UTC(),
+		Memory:    info.MemoryStats{Usage: 200},
+	}
+	return cInfo, stats
+}
+
+func newTestStorage(t *testing.T, config config) (*fileStorage, string) {
+	dir, err := ioutil.TempDir("", "cadvisor-file-storage")
+	require.Nil(t, err)
+	config.path = filepath.Join(dir, "stats.jsonl")
+	clock := &fakeClock{time: time.Date(2020, 10, 16, 12, 0, 0, 0, time.UTC)}
+	s, err := newStorage("machine-1", config, clock.now)
+	require.Nil(t, err)
+	return s, dir
+}
+
+func readLines(t *testing.T, name string) []detailSpec {
+	f, err := os.Open(name)
+	require.Nil(t, err)
+	defer f.Close()
+	var details []detailSpec
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var detail detailSpec
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &detail))
+		details = append(details, detail)
+	}
+	return details
+}
+
+func lineSize(t *testing.T) int64 {
+	s, dir := newTestStorage(t, config{})
+	defer os.RemoveAll(dir)
+	require.Nil(t, s.AddStats(testStats()))
+	require.Nil(t, s.Close())
+	return s.size
+}
+
+func TestAddStats(t *testing.T) {
+	s, dir := newTestStorage(t, config{})
+	defer os.RemoveAll(dir)
+	require.Nil(t, s.AddStats(testStats()))
+	require.Nil(t, s.AddStats(testStats()))
+	require.Nil(t, s.Close())
+
+	details := readLines(t, s.config.path)
+	require.Len(t, details, 2)
+	assert.Equal(t, "machine-1", details[0].MachineName)
+	assert.Equal(t, "app", details[0].ContainerName)
+	assert.Equal(t, "abcd", details[0].ContainerID)
+	assert.Equal(t, time.Unix(1395066363, 0).UTC(), details[0].Timestamp)
+	assert.Equal(t, uint64(200), details[0].ContainerStats.Memory.Usage)
+}
+
+func TestRotateBySize(t *testing.T) {
+	size := lineSize(t)
+	s, dir := newTestStorage(t, config{maxSize: 2 * size, maxBackups: 2})
+	defer os.RemoveAll(dir)
+	for i := 0; i < 7; i++ {
+		require.Nil(t, s.AddStats(testStats()))
+	}
+	require.Nil(t, s.Close())
+
+	assert.Len(t, readLines(t, s.config.path), 1)
+	backups, err := filepath.Glob(filepath.Join(dir, "stats-*.jsonl"))
+	require.Nil(t, err)
+	// 3 files were rotated, the oldest was removed.
+	require.Len(t, backups, 2)
+	assert.Equal(t, filepath.Join(dir, "stats-2020-10-16T12-00-04.000.jsonl"), backups[0])
+	for _, backup := range backups {
+		assert.Len(t, readLines(t, backup), 2)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	s, dir := newTestStorage(t, config{maxAge: 3 * time.Second})
+	defer os.RemoveAll(dir)
+	for i := 0; i < 4; i++ {
+		require.Nil(t, s.AddStats(testStats()))
+	}
+	require.Nil(t, s.Close())
+
+	backups, err := filepath.Glob(filepath.Join(dir, "stats-*.jsonl"))
+	require.Nil(t, err)
+	require.Len(t, backups, 1)
+	assert.Len(t, readLines(t, backups[0]), 2)
+	assert.Len(t, readLines(t, s.config.path), 2)
+}
+
+func TestRotateWithCompression(t *testing.T) {
+	size := lineSize(t)
+	s, dir := newTestStorage(t, config{maxSize: size, compress: true})
+	defer os.RemoveAll(dir)
+	for i := 0; i < 3; i++ {
+		require.Nil(t, s.AddStats(testStats()))
+	}
+	require.Nil(t, s.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "stats-*"))
+	require.Nil(t, err)
+	require.Len(t, files, 2)
+	for _, name := range files {
+		assert.Equal(t, ".gz", filepath.Ext(name))
+		f, err := os.Open(name)
+		require.Nil(t, err)
+		r, err := gzip.NewReader(f)
+		require.Nil(t, err)
+		b, err := ioutil.ReadAll(r)
+		require.Nil(t, err)
+		assert.Equal(t, size, int64(len(b)))
+		f.Close()
+	}
+}
+
+func TestAppendsToExistingFile(t *testing.T) {
+	s, dir := newTestStorage(t, config{})
+	defer os.RemoveAll(dir)
+	require.Nil(t, s.AddStats(testStats()))
+	require.Nil(t, s.Close())
+
+	s, err := newStorage("machine-1", s.config, time.Now)
+	require.Nil(t, err)
+	assert.NotZero(t, s.size)
+	require.Nil(t, s.AddStats(testStats()))
+	require.Nil(t, s.Close())
+	assert.Len(t, readLines(t, s.config.path), 2)
+}
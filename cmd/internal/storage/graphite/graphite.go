@@ -0,0 +1,397 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
+	"github.com/google/cadvisor/cmd/internal/storage/samples"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/utils/container"
+)
+
+func init() {
+	storage.RegisterStorageDriver("graphite", new)
+}
+
+const (
+	protocolPlaintext = "plaintext"
+	protocolPickle    = "pickle"
+
+	// unknownValue replaces values of placeholders which the container doesn't have.
+	unknownValue = "unknown"
+)
+
+var (
+	argAddress      = flag.String("storage_driver_graphite_address", "localhost:2003", "host:port of Graphite carbon receiver, by default 2003 for plaintext and 2004 for pickle protocol")
+	argProtocol     = flag.String("storage_driver_graphite_protocol", protocolPlaintext, "protocol used to send metrics, one of: plaintext, pickle")
+	argTemplate     = flag.String("storage_driver_graphite_template", "cadvisor.{machine}.{container}.{metric}", "template of metric paths, e.g. {cluster}.{namespace}.{container}.{metric}, see documentation for placeholders")
+	argTemplateVars = flag.String("storage_driver_graphite_template_vars", "", "comma-separated list of name=value pairs of custom placeholders of the template, e.g. cluster=prod")
+	argBatchSize    = flag.Int("storage_driver_graphite_batch_size", 500, "maximum number of datapoints sent in a single pickle message")
+	argTimeout      = flag.Duration("storage_driver_graphite_timeout", 10*time.Second, "timeout of connecting and sending metrics to Graphite")
+)
+
+// Labels of containers in Kubernetes pods.
+const (
+	podNamespaceLabel = "io.kubernetes.pod.namespace"
+	podNameLabel      = "io.kubernetes.pod.name"
+)
+
+type graphiteStorage struct {
+	address     string
+	protocol    string
+	template    *template
+	batchSize   int
+	timeout     time.Duration
+	machineName string
+
+	bufferDuration time.Duration
+	lastWrite      time.Time
+	datapoints     []datapoint
+	lock           sync.Mutex
+	readyToFlush   func() bool
+
+	// connLock serializes writes to the connection.
+	connLock sync.Mutex
+	conn     net.Conn
+}
+
+// datapoint is a value of a metric path at a time.
+type datapoint struct {
+	path      string
+	timestamp int64
+	value     float64
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	vars, err := parseVars(*argTemplateVars)
+	if err != nil {
+		return nil, err
+	}
+	template, err := parseTemplate(*argTemplate, vars)
+	if err != nil {
+		return nil, err
+	}
+	if *argProtocol != protocolPlaintext && *argProtocol != protocolPickle {
+		return nil, fmt.Errorf("unknown graphite protocol %q, must be one of: %s, %s", *argProtocol, protocolPlaintext, protocolPickle)
+	}
+	if *argBatchSize <= 0 {
+		return nil, fmt.Errorf("graphite batch size must be positive, got %d", *argBatchSize)
+	}
+	return newStorage(hostname, *argAddress, *argProtocol, template, *argBatchSize, *argTimeout, *storage.ArgDbBufferDuration), nil
+}
+
+func newStorage(machineName, address, protocol string, template *template, batchSize int, timeout, bufferDuration time.Duration) *graphiteStorage {
+	ret := &graphiteStorage{
+		address:        address,
+		protocol:       protocol,
+		template:       template,
+		batchSize:      batchSize,
+		timeout:        timeout,
+		machineName:    machineName,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret
+}
+
+func parseVars(list string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, pair := range strings.Split(list, ",") {
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid graphite template variable %q, must be name=value", pair)
+		}
+		vars[parts[0]] = parts[1]
+	}
+	return vars, nil
+}
+
+// template renders metric paths. Placeholders are:
+// {machine}, {container} (preferred name), {container_name} (full name),
+// {id}, {image}, {runtime} (e.g. docker), {namespace} and {pod} (Kubernetes),
+// {label:<name>}, custom variables and {metric}, which is appended to the
+// path if the template doesn't have it.
+type template struct {
+	// parts are literal strings, or placeholders if the index is odd.
+	parts []string
+	vars  map[string]string
+}
+
+func parseTemplate(text string, vars map[string]string) (*template, error) {
+	t := &template{vars: vars}
+	hasMetric := false
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in graphite template %q", text)
+		}
+		name := text[start+1 : start+end]
+		switch {
+		case name == "metric":
+			hasMetric = true
+		case name == "machine", name == "container", name == "container_name", name == "id", name == "image",
+			name == "runtime", name == "namespace", name == "pod", strings.HasPrefix(name, "label:"):
+		default:
+			if _, ok := vars[name]; !ok {
+				return nil, fmt.Errorf("unknown placeholder {%s} in graphite template", name)
+			}
+		}
+		t.parts = append(t.parts, text[:start], name)
+		text = text[start+end+1:]
+	}
+	if !hasMetric {
+		if text != "" && !strings.HasSuffix(text, ".") {
+			text += "."
+		}
+		if len(t.parts) > 0 && text == "" {
+			text = "."
+		}
+		t.parts = append(t.parts, text, "metric")
+		text = ""
+	}
+	t.parts = append(t.parts, text)
+	return t, nil
+}
+
+// render returns the metric path prefix and suffix of the container, around
+// the {metric} placeholder.
+func (t *template) render(machineName string, cInfo *info.ContainerInfo) (string, string) {
+	var prefix, suffix strings.Builder
+	out := &prefix
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			out.WriteString(part)
+			continue
+		}
+		if part == "metric" {
+			out = &suffix
+			continue
+		}
+		out.WriteString(sanitize(t.value(part, machineName, cInfo)))
+	}
+	return prefix.String(), suffix.String()
+}
+
+func (t *template) value(name, machineName string, cInfo *info.ContainerInfo) string {
+	var value string
+	switch name {
+	case "machine":
+		value = machineName
+	case "container":
+		value = container.GetPreferredName(cInfo.ContainerReference)
+	case "container_name":
+		value = cInfo.Name
+	case "id":
+		value = cInfo.Id
+	case "image":
+		value = cInfo.Spec.Image
+	case "runtime":
+		value = cInfo.Namespace
+	case "namespace":
+		value = cInfo.Spec.Labels[podNamespaceLabel]
+	case "pod":
+		value = cInfo.Spec.Labels[podNameLabel]
+	default:
+		if strings.HasPrefix(name, "label:") {
+			value = cInfo.Spec.Labels[strings.TrimPrefix(name, "label:")]
+		} else {
+			value = t.vars[name]
+		}
+	}
+	// Container names start with a slash, the root container is "/".
+	value = strings.Trim(value, "/")
+	if value == "" {
+		if name == "container" || name == "container_name" {
+			return "root"
+		}
+		return unknownValue
+	}
+	return value
+}
+
+// sanitize replaces characters which separate path components or are not
+// allowed in paths by Graphite.
+func sanitize(component string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, component)
+}
+
+// metricPaths returns paths of values of the sample relative to the container,
+// e.g. network.eth0.rx_bytes.
+func metricPaths(sample *samples.Sample) map[string]float64 {
+	prefix := sample.Group
+	attributes := make([]string, 0, len(sample.Attributes))
+	for name := range sample.Attributes {
+		attributes = append(attributes, name)
+	}
+	sort.Strings(attributes)
+	for _, name := range attributes {
+		prefix += "." + sanitize(sample.Attributes[name])
+	}
+	paths := make(map[string]float64, len(sample.Values))
+	for name, value := range sample.Values {
+		paths[prefix+"."+name] = value
+	}
+	return paths
+}
+
+func (s *graphiteStorage) containerDatapoints(cInfo *info.ContainerInfo, stats *info.ContainerStats) []datapoint {
+	prefix, suffix := s.template.render(s.machineName, cInfo)
+	timestamp := stats.Timestamp.Unix()
+	var datapoints []datapoint
+	for _, sample := range samples.ContainerSamples(s.machineName, cInfo, stats) {
+		for path, value := range metricPaths(sample) {
+			datapoints = append(datapoints, datapoint{
+				path:      prefix + path + suffix,
+				timestamp: timestamp,
+				value:     value,
+			})
+		}
+	}
+	return datapoints
+}
+
+func (s *graphiteStorage) OverrideReadyToFlush(readyToFlush func() bool) {
+	s.readyToFlush = readyToFlush
+}
+
+func (s *graphiteStorage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
+}
+
+func (s *graphiteStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	datapoints := s.containerDatapoints(cInfo, stats)
+	var datapointsToFlush []datapoint
+	func() {
+		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.datapoints = append(s.datapoints, datapoints...)
+		if s.readyToFlush() {
+			datapointsToFlush = s.datapoints
+			s.datapoints = nil
+			s.lastWrite = time.Now()
+		}
+	}()
+	if len(datapointsToFlush) > 0 {
+		if err := s.send(datapointsToFlush); err != nil {
+			return fmt.Errorf("failed to write stats to graphite - %s", err)
+		}
+	}
+	return nil
+}
+
+// WriteBatch sends the stats at once, it is used by the storage buffer instead of AddStats.
+func (s *graphiteStorage) WriteBatch(entries []*buffer.Entry) error {
+	var datapoints []datapoint
+	for _, e := range entries {
+		datapoints = append(datapoints, s.containerDatapoints(e.ContainerInfo, e.ContainerStats)...)
+	}
+	if err := s.send(datapoints); err != nil {
+		return fmt.Errorf("failed to write stats to graphite - %s", err)
+	}
+	return nil
+}
+
+// send writes the datapoints to the connection, which is reopened if writing
+// failed before.
+func (s *graphiteStorage) send(datapoints []datapoint) error {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	var buf bytes.Buffer
+	if s.protocol == protocolPickle {
+		for start := 0; start < len(datapoints); start += s.batchSize {
+			end := start + s.batchSize
+			if end > len(datapoints) {
+				end = len(datapoints)
+			}
+			writePickleMessage(&buf, datapoints[start:end])
+		}
+	} else {
+		for _, d := range datapoints {
+			buf.WriteString(d.path)
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatFloat(d.value, 'f', -1, 64))
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatInt(d.timestamp, 10))
+			buf.WriteByte('\n')
+		}
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *graphiteStorage) Close() error {
+	s.lock.Lock()
+	datapoints := s.datapoints
+	s.datapoints = nil
+	s.lock.Unlock()
+	var err error
+	if len(datapoints) > 0 {
+		err = s.send(datapoints)
+	}
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
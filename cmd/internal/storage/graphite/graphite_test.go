@@ -0,0 +1,175 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStats() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/kubepods/pod1/abcd", Id: "abcd", Aliases: []string{"k8s_app.v1"}, Namespace: "docker"},
+		Spec: info.ContainerSpec{
+			Image:  "nginx:1.19",
+			Labels: map[string]string{podNamespaceLabel: "default", podNameLabel: "web-1", "team": "web/infra"},
+		},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100}},
+		Memory:    info.MemoryStats{Usage: 200},
+		Network: info.NetworkStats{Interfaces: []info.InterfaceStats{
+			{Name: "eth0", RxBytes: 300},
+		}},
+	}
+	return cInfo, stats
+}
+
+func render(t *testing.T, text string, vars map[string]string) string {
+	template, err := parseTemplate(text, vars)
+	require.Nil(t, err)
+	cInfo, _ := testStats()
+	prefix, suffix := template.render("machine.1", cInfo)
+	return prefix + "cpu.usage_total" + suffix
+}
+
+func TestTemplate(t *testing.T) {
+	assert.Equal(t, "cadvisor.machine_1.k8s_app_v1.cpu.usage_total", render(t, "cadvisor.{machine}.{container}.{metric}", nil))
+	assert.Equal(t, "prod.default.web-1.k8s_app_v1.cpu.usage_total", render(t, "{cluster}.{namespace}.{pod}.{container}.{metric}", map[string]string{"cluster": "prod"}))
+	assert.Equal(t, "kubepods_pod1_abcd.abcd.nginx:1_19.docker.web_infra.unknown.cpu.usage_total", render(t, "{container_name}.{id}.{image}.{runtime}.{label:team}.{label:missing}", nil))
+	assert.Equal(t, "stats.cpu.usage_total", render(t, "stats.", nil))
+	assert.Equal(t, "cpu.usage_total", render(t, "", nil))
+	assert.Equal(t, "cpu.usage_total.abcd", render(t, "{metric}.{id}", nil))
+
+	_, err := parseTemplate("{cluster}.{metric}", nil)
+	assert.NotNil(t, err)
+	_, err = parseTemplate("{container.{metric}", nil)
+	assert.NotNil(t, err)
+	_, err = parseTemplate("{container", nil)
+	assert.NotNil(t, err)
+}
+
+func TestRootContainer(t *testing.T) {
+	template, err := parseTemplate("{container}.{metric}", nil)
+	require.Nil(t, err)
+	prefix, _ := template.render("machine", &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}})
+	assert.Equal(t, "root.", prefix)
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := parseVars("cluster=prod,region=eu=west")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"cluster": "prod", "region": "eu=west"}, vars)
+	_, err = parseVars("cluster")
+	assert.NotNil(t, err)
+}
+
+// listen returns an address accepting a connection and data received on it.
+func listen(t *testing.T) (string, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	received := make(chan []byte, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		received <- b
+	}()
+	return listener.Addr().String(), received
+}
+
+func newTestStorage(t *testing.T, address, protocol string) *graphiteStorage {
+	template, err := parseTemplate("cadvisor.{container}.{metric}", nil)
+	require.Nil(t, err)
+	s := newStorage("machine", address, protocol, template, 2, 5*time.Second, time.Minute)
+	flush := false
+	s.OverrideReadyToFlush(func() bool { return flush })
+	cInfo, stats := testStats()
+	require.Nil(t, s.AddStats(cInfo, stats))
+	flush = true
+	require.Nil(t, s.AddStats(cInfo, stats))
+	return s
+}
+
+func TestPlaintext(t *testing.T) {
+	address, received := listen(t)
+	s := newTestStorage(t, address, protocolPlaintext)
+	require.Nil(t, s.Close())
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(<-received))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Contains(t, lines, "cadvisor.k8s_app_v1.cpu.usage_total 100 1395066363")
+	assert.Contains(t, lines, "cadvisor.k8s_app_v1.memory.usage 200 1395066363")
+	assert.Contains(t, lines, "cadvisor.k8s_app_v1.network.eth0.rx_bytes 300 1395066363")
+	// Both stats were flushed at once.
+	cInfo, stats := testStats()
+	assert.Len(t, lines, 2*len(s.containerDatapoints(cInfo, stats)))
+}
+
+func TestPickle(t *testing.T) {
+	address, received := listen(t)
+	s := newTestStorage(t, address, protocolPickle)
+	require.Nil(t, s.Close())
+
+	b := bytes.NewReader(<-received)
+	messages := 0
+	for b.Len() > 0 {
+		var length uint32
+		require.Nil(t, binary.Read(b, binary.BigEndian, &length))
+		payload := make([]byte, length)
+		_, err := io.ReadFull(b, payload)
+		require.Nil(t, err)
+		assert.Equal(t, []byte{pickleProto, 2, pickleEmptyList, pickleMark}, payload[:4])
+		assert.Equal(t, []byte{pickleAppends, pickleStop}, payload[len(payload)-2:])
+		messages++
+	}
+	// Messages have at most 2 datapoints.
+	cInfo, stats := testStats()
+	assert.Equal(t, len(s.containerDatapoints(cInfo, stats)), messages)
+}
+
+func TestPickleMessage(t *testing.T) {
+	var buf bytes.Buffer
+	writePickleMessage(&buf, []datapoint{{path: "a.b", timestamp: 1, value: 0.5}})
+	payload := []byte{
+		pickleProto, 2, pickleEmptyList, pickleMark,
+		pickleBinUnicode, 3, 0, 0, 0, 'a', '.', 'b',
+		pickleBinInt, 1, 0, 0, 0,
+		pickleBinFloat, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+		pickleTuple2, pickleTuple2,
+		pickleAppends, pickleStop,
+	}
+	assert.Equal(t, append([]byte{0, 0, 0, byte(len(payload))}, payload...), buf.Bytes())
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Opcodes of pickle protocol 2 used in messages.
+const (
+	pickleProto      = 0x80
+	pickleEmptyList  = ']'
+	pickleMark       = '('
+	pickleBinUnicode = 'X'
+	pickleBinInt     = 'J'
+	pickleBinFloat   = 'G'
+	pickleTuple2     = 0x86
+	pickleAppends    = 'e'
+	pickleStop       = '.'
+)
+
+// writePickleMessage writes datapoints as a message of the carbon pickle
+// protocol: the length of the payload as 4 bytes big endian, followed by the
+// payload, a pickled list of (path, (timestamp, value)) tuples.
+func writePickleMessage(buf *bytes.Buffer, datapoints []datapoint) {
+	var payload bytes.Buffer
+	payload.WriteByte(pickleProto)
+	payload.WriteByte(2)
+	payload.WriteByte(pickleEmptyList)
+	payload.WriteByte(pickleMark)
+	for _, d := range datapoints {
+		payload.WriteByte(pickleBinUnicode)
+		binary.Write(&payload, binary.LittleEndian, uint32(len(d.path)))
+		payload.WriteString(d.path)
+		if d.timestamp >= math.MinInt32 && d.timestamp <= math.MaxInt32 {
+			payload.WriteByte(pickleBinInt)
+			binary.Write(&payload, binary.LittleEndian, int32(d.timestamp))
+		} else {
+			payload.WriteByte(pickleBinFloat)
+			binary.Write(&payload, binary.BigEndian, float64(d.timestamp))
+		}
+		payload.WriteByte(pickleBinFloat)
+		binary.Write(&payload, binary.BigEndian, d.value)
+		payload.WriteByte(pickleTuple2)
+		payload.WriteByte(pickleTuple2)
+	}
+	payload.WriteByte(pickleAppends)
+	payload.WriteByte(pickleStop)
+
+	binary.Write(buf, binary.BigEndian, uint32(payload.Len()))
+	buf.Write(payload.Bytes())
+}
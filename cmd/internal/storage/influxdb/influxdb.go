@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/storage"
 	"github.com/google/cadvisor/version"
@@ -393,12 +394,7 @@ func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Contai
 		s.lock.Lock()
 		defer s.lock.Unlock()
 
-		s.points = append(s.points, s.containerStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.memoryStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.hugetlbStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.perfStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.resctrlStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.containerFilesystemStatsToPoints(cInfo, stats)...)
+		s.points = append(s.points, s.statsToPoints(cInfo, stats)...)
 		if s.readyToFlush() {
 			pointsToFlush = s.points
 			s.points = make([]*influxdb.Point, 0)
@@ -406,23 +402,51 @@ func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Contai
 		}
 	}()
 	if len(pointsToFlush) > 0 {
-		points := make([]influxdb.Point, len(pointsToFlush))
-		for i, p := range pointsToFlush {
-			points[i] = *p
-		}
+		return s.writePoints(pointsToFlush, stats.Timestamp)
+	}
+	return nil
+}
 
-		batchTags := map[string]string{tagMachineName: s.machineName}
-		bp := influxdb.BatchPoints{
-			Points:          points,
-			Database:        s.database,
-			RetentionPolicy: s.retentionPolicy,
-			Tags:            batchTags,
-			Time:            stats.Timestamp,
-		}
-		response, err := s.client.Write(bp)
-		if err != nil || checkResponseForErrors(response) != nil {
-			return fmt.Errorf("failed to write stats to influxDb - %s", err)
-		}
+// WriteBatch writes the stats in a single request, it is used by the storage buffer instead of AddStats.
+func (s *influxdbStorage) WriteBatch(entries []*buffer.Entry) error {
+	var points []*influxdb.Point
+	for _, e := range entries {
+		points = append(points, s.statsToPoints(e.ContainerInfo, e.ContainerStats)...)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return s.writePoints(points, entries[len(entries)-1].ContainerStats.Timestamp)
+}
+
+func (s *influxdbStorage) statsToPoints(cInfo *info.ContainerInfo, stats *info.ContainerStats) []*influxdb.Point {
+	var points []*influxdb.Point
+	points = append(points, s.containerStatsToPoints(cInfo, stats)...)
+	points = append(points, s.memoryStatsToPoints(cInfo, stats)...)
+	points = append(points, s.hugetlbStatsToPoints(cInfo, stats)...)
+	points = append(points, s.perfStatsToPoints(cInfo, stats)...)
+	points = append(points, s.resctrlStatsToPoints(cInfo, stats)...)
+	points = append(points, s.containerFilesystemStatsToPoints(cInfo, stats)...)
+	return points
+}
+
+func (s *influxdbStorage) writePoints(pointsToFlush []*influxdb.Point, timestamp time.Time) error {
+	points := make([]influxdb.Point, len(pointsToFlush))
+	for i, p := range pointsToFlush {
+		points[i] = *p
+	}
+
+	batchTags := map[string]string{tagMachineName: s.machineName}
+	bp := influxdb.BatchPoints{
+		Points:          points,
+		Database:        s.database,
+		RetentionPolicy: s.retentionPolicy,
+		Tags:            batchTags,
+		Time:            timestamp,
+	}
+	response, err := s.client.Write(bp)
+	if err != nil || checkResponseForErrors(response) != nil {
+		return fmt.Errorf("failed to write stats to influxDb - %s", err)
 	}
 	return nil
 }
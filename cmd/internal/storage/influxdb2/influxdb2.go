@@ -0,0 +1,388 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package influxdb2 implements a storage driver writing stats to InfluxDB 2.x
+// through its HTTP write API.
+package influxdb2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/version"
+)
+
+func init() {
+	storage.RegisterStorageDriver("influxdb2", new)
+}
+
+var (
+	argURL       = flag.String("storage_driver_influxdb2_url", "http://localhost:8086", "URL of the InfluxDB 2.x server")
+	argOrg       = flag.String("storage_driver_influxdb2_org", "", "InfluxDB 2.x organization name")
+	argBucket    = flag.String("storage_driver_influxdb2_bucket", "cadvisor", "InfluxDB 2.x bucket name")
+	argToken     = flag.String("storage_driver_influxdb2_token", "", "InfluxDB 2.x authentication token")
+	argLabelTags = flag.String("storage_driver_influxdb2_label_tags", "", "comma-separated list of container labels exported as tags, in form 'label' or 'label=tag' to rename the tag. Empty means all container labels are exported under their names")
+)
+
+// Measurement names
+const (
+	measurementCpu        = "cpu"
+	measurementCpuPerCpu  = "cpu_per_cpu"
+	measurementMemory     = "memory"
+	measurementNetwork    = "network"
+	measurementFilesystem = "filesystem"
+	measurementHugetlb    = "hugetlb"
+	measurementPerf       = "perf"
+	measurementResctrl    = "resctrl"
+)
+
+// Tag names
+const (
+	tagMachineName   = "machine"
+	tagContainerName = "container_name"
+)
+
+type influxdb2Storage struct {
+	client       *http.Client
+	writeURL     string
+	token        string
+	machineName  string
+	labelTags    map[string]string
+	lastWrite    time.Time
+	lines        bytes.Buffer
+	lock         sync.Mutex
+	readyToFlush func() bool
+	// bufferDuration is how long the lines are buffered before they are written.
+	bufferDuration time.Duration
+}
+
+// point is a single line of the InfluxDB line protocol. Field values are
+// int64 or float64.
+type point struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]interface{}
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	labelTags, err := parseLabelTags(*argLabelTags)
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(
+		hostname,
+		*argURL,
+		*argOrg,
+		*argBucket,
+		*argToken,
+		labelTags,
+		*storage.ArgDbBufferDuration,
+	)
+}
+
+// parseLabelTags parses the mapping of container labels to tags. A nil map
+// means that all labels are exported as tags with the same names.
+func parseLabelTags(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	labelTags := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		label, tag := item, item
+		if i := strings.Index(item, "="); i >= 0 {
+			label, tag = item[:i], item[i+1:]
+		}
+		if label == "" || tag == "" {
+			return nil, fmt.Errorf("invalid container label to tag mapping %q", item)
+		}
+		labelTags[label] = tag
+	}
+	return labelTags, nil
+}
+
+func newStorage(
+	machineName,
+	serverURL,
+	org,
+	bucket,
+	token string,
+	labelTags map[string]string,
+	bufferDuration time.Duration,
+) (*influxdb2Storage, error) {
+	if org == "" {
+		return nil, fmt.Errorf("InfluxDB 2.x organization is required")
+	}
+	writeURL, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid InfluxDB 2.x URL %q: %v", serverURL, err)
+	}
+	writeURL.Path = strings.TrimSuffix(writeURL.Path, "/") + "/api/v2/write"
+	writeURL.RawQuery = url.Values{
+		"org":       {org},
+		"bucket":    {bucket},
+		"precision": {"ns"},
+	}.Encode()
+
+	ret := &influxdb2Storage{
+		client:         &http.Client{Timeout: 30 * time.Second},
+		writeURL:       writeURL.String(),
+		token:          token,
+		machineName:    machineName,
+		labelTags:      labelTags,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret, nil
+}
+
+func (s *influxdb2Storage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
+}
+
+// containerTags returns tags identifying the container, common to all its points.
+func (s *influxdb2Storage) containerTags(cInfo *info.ContainerInfo) map[string]string {
+	tags := map[string]string{}
+	for label, value := range cInfo.Spec.Labels {
+		if s.labelTags == nil {
+			tags[label] = value
+		} else if tag, ok := s.labelTags[label]; ok {
+			tags[tag] = value
+		}
+	}
+	// Use container alias if possible
+	containerName := cInfo.Name
+	if len(cInfo.Aliases) > 0 {
+		containerName = cInfo.Aliases[0]
+	}
+	tags[tagMachineName] = s.machineName
+	tags[tagContainerName] = containerName
+	return tags
+}
+
+func containerStatsToPoints(stats *info.ContainerStats) []point {
+	points := []point{
+		{
+			measurement: measurementCpu,
+			fields: map[string]interface{}{
+				"usage_total":  int64(stats.Cpu.Usage.Total),
+				"usage_system": int64(stats.Cpu.Usage.System),
+				"usage_user":   int64(stats.Cpu.Usage.User),
+				"load_average": int64(stats.Cpu.LoadAverage),
+			},
+		},
+		{
+			measurement: measurementMemory,
+			fields: map[string]interface{}{
+				"usage":                   int64(stats.Memory.Usage),
+				"max_usage":               int64(stats.Memory.MaxUsage),
+				"cache":                   int64(stats.Memory.Cache),
+				"rss":                     int64(stats.Memory.RSS),
+				"swap":                    int64(stats.Memory.Swap),
+				"mapped_file":             int64(stats.Memory.MappedFile),
+				"working_set":             int64(stats.Memory.WorkingSet),
+				"failcnt":                 int64(stats.Memory.Failcnt),
+				"pgfault":                 int64(stats.Memory.ContainerData.Pgfault),
+				"pgmajfault":              int64(stats.Memory.ContainerData.Pgmajfault),
+				"hierarchical_pgfault":    int64(stats.Memory.HierarchicalData.Pgfault),
+				"hierarchical_pgmajfault": int64(stats.Memory.HierarchicalData.Pgmajfault),
+				"referenced":              int64(stats.ReferencedMemory),
+			},
+		},
+	}
+
+	for i, usage := range stats.Cpu.Usage.PerCpu {
+		points = append(points, point{
+			measurement: measurementCpuPerCpu,
+			tags:        map[string]string{"cpu": strconv.Itoa(i)},
+			fields:      map[string]interface{}{"usage": int64(usage)},
+		})
+	}
+
+	for _, iface := range stats.Network.Interfaces {
+		points = append(points, point{
+			measurement: measurementNetwork,
+			tags:        map[string]string{"interface": iface.Name},
+			fields: map[string]interface{}{
+				"rx_bytes":  int64(iface.RxBytes),
+				"rx_errors": int64(iface.RxErrors),
+				"tx_bytes":  int64(iface.TxBytes),
+				"tx_errors": int64(iface.TxErrors),
+			},
+		})
+	}
+
+	for _, fsStat := range stats.Filesystem {
+		points = append(points, point{
+			measurement: measurementFilesystem,
+			tags:        map[string]string{"device": fsStat.Device},
+			fields: map[string]interface{}{
+				"usage": int64(fsStat.Usage),
+				"limit": int64(fsStat.Limit),
+			},
+		})
+	}
+
+	for pageSize, hugetlbStat := range stats.Hugetlb {
+		points = append(points, point{
+			measurement: measurementHugetlb,
+			tags:        map[string]string{"page_size": pageSize},
+			fields: map[string]interface{}{
+				"usage":     int64(hugetlbStat.Usage),
+				"max_usage": int64(hugetlbStat.MaxUsage),
+				"failcnt":   int64(hugetlbStat.Failcnt),
+			},
+		})
+	}
+
+	for _, perfStat := range stats.PerfStats {
+		points = append(points, point{
+			measurement: measurementPerf,
+			tags:        map[string]string{"cpu": strconv.Itoa(perfStat.Cpu), "event": perfStat.Name},
+			fields: map[string]interface{}{
+				"value":         int64(perfStat.Value),
+				"scaling_ratio": perfStat.ScalingRatio,
+			},
+		})
+	}
+
+	nodes := len(stats.Resctrl.MemoryBandwidth)
+	if len(stats.Resctrl.Cache) > nodes {
+		nodes = len(stats.Resctrl.Cache)
+	}
+	for nodeID := 0; nodeID < nodes; nodeID++ {
+		fields := map[string]interface{}{}
+		if nodeID < len(stats.Resctrl.MemoryBandwidth) {
+			fields["memory_bandwidth_total"] = int64(stats.Resctrl.MemoryBandwidth[nodeID].TotalBytes)
+			fields["memory_bandwidth_local"] = int64(stats.Resctrl.MemoryBandwidth[nodeID].LocalBytes)
+		}
+		if nodeID < len(stats.Resctrl.Cache) {
+			fields["llc_occupancy"] = int64(stats.Resctrl.Cache[nodeID].LLCOccupancy)
+		}
+		points = append(points, point{
+			measurement: measurementResctrl,
+			tags:        map[string]string{"node_id": strconv.Itoa(nodeID)},
+			fields:      fields,
+		})
+	}
+
+	return points
+}
+
+func (s *influxdb2Storage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	containerTags := s.containerTags(cInfo)
+	points := containerStatsToPoints(stats)
+
+	var linesToFlush []byte
+	func() {
+		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		for _, p := range points {
+			writeLine(&s.lines, p, containerTags, stats.Timestamp)
+		}
+		if s.readyToFlush() {
+			linesToFlush = append([]byte(nil), s.lines.Bytes()...)
+			s.lines.Reset()
+			s.lastWrite = time.Now()
+		}
+	}()
+	if len(linesToFlush) > 0 {
+		return s.write(linesToFlush)
+	}
+	return nil
+}
+
+// WriteBatch writes the stats in a single request, it is used by the storage buffer instead of AddStats.
+func (s *influxdb2Storage) WriteBatch(entries []*buffer.Entry) error {
+	var lines bytes.Buffer
+	for _, e := range entries {
+		containerTags := s.containerTags(e.ContainerInfo)
+		for _, p := range containerStatsToPoints(e.ContainerStats) {
+			writeLine(&lines, p, containerTags, e.ContainerStats.Timestamp)
+		}
+	}
+	return s.write(lines.Bytes())
+}
+
+// write sends gzip compressed lines to the write API.
+func (s *influxdb2Storage) write(lines []byte) error {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if _, err := gz.Write(lines); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("User-Agent", fmt.Sprintf("%v/%v", "cAdvisor", version.Info["version"]))
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write stats to InfluxDB - %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to write stats to InfluxDB - %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+func (s *influxdb2Storage) Close() error {
+	s.lock.Lock()
+	lines := append([]byte(nil), s.lines.Bytes()...)
+	s.lines.Reset()
+	s.lock.Unlock()
+
+	if len(lines) > 0 {
+		return s.write(lines)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelTags(t *testing.T) {
+	labelTags, err := parseLabelTags("")
+	assert.Nil(t, err)
+	assert.Nil(t, labelTags)
+
+	labelTags, err = parseLabelTags("app, io.kubernetes.pod.name=pod,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"app": "app", "io.kubernetes.pod.name": "pod"}, labelTags)
+
+	_, err = parseLabelTags("app=")
+	assert.NotNil(t, err)
+}
+
+func TestWriteLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeLine(buf, point{
+		measurement: "cpu usage",
+		tags:        map[string]string{"cpu": "1", "empty": ""},
+		fields:      map[string]interface{}{"usage": int64(10), "ratio": 0.5},
+	}, map[string]string{"container_name": "a b,c=d", "cpu": "overridden"}, time.Unix(1, 5))
+	assert.Equal(t, "cpu\\ usage,container_name=a\\ b\\,c\\=d,cpu=1 ratio=0.5,usage=10i 1000000005\n", buf.String())
+
+	buf.Reset()
+	writeLine(buf, point{measurement: "empty"}, nil, time.Unix(1, 0))
+	assert.Empty(t, buf.String())
+}
+
+func TestContainerTags(t *testing.T) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Aliases: []string{"app"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"io.kubernetes.pod.name": "pod", "other": "value"}},
+	}
+	s := &influxdb2Storage{machineName: "machine-1"}
+	assert.Equal(t, map[string]string{
+		"machine":                "machine-1",
+		"container_name":         "app",
+		"io.kubernetes.pod.name": "pod",
+		"other":                  "value",
+	}, s.containerTags(cInfo))
+
+	s.labelTags = map[string]string{"io.kubernetes.pod.name": "pod"}
+	assert.Equal(t, map[string]string{
+		"machine":        "machine-1",
+		"container_name": "app",
+		"pod":            "pod",
+	}, s.containerTags(cInfo))
+}
+
+func TestAddStats(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/write", r.URL.Path)
+		assert.Equal(t, "my-org", r.URL.Query().Get("org"))
+		assert.Equal(t, "my-bucket", r.URL.Query().Get("bucket"))
+		assert.Equal(t, "ns", r.URL.Query().Get("precision"))
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.Nil(t, err)
+		data, err := ioutil.ReadAll(gz)
+		require.Nil(t, err)
+		body = string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s, err := newStorage("machine-1", server.URL, "my-org", "my-bucket", "secret", nil, time.Hour)
+	require.Nil(t, err)
+
+	cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100}},
+		Network:   info.NetworkStats{Interfaces: []info.InterfaceStats{{Name: "eth0", RxBytes: 10}}},
+	}
+	// Lines are buffered until the buffer duration elapses.
+	require.Nil(t, s.AddStats(cInfo, stats))
+	assert.Empty(t, body)
+
+	require.Nil(t, s.Close())
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "cpu,container_name=/,machine=machine-1 load_average=0i,usage_system=0i,usage_total=100i,usage_user=0i 1395066363000000000", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "memory,container_name=/,machine=machine-1 "))
+	assert.Equal(t, "network,container_name=/,interface=eth0,machine=machine-1 rx_bytes=10i,rx_errors=0i,tx_bytes=0i,tx_errors=0i 1395066363000000000", lines[2])
+}
+
+func TestWriteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"unauthorized","message":"unauthorized access"}`))
+	}))
+	defer server.Close()
+
+	s, err := newStorage("machine-1", server.URL, "my-org", "my-bucket", "", nil, 0)
+	require.Nil(t, err)
+	err = s.AddStats(&info.ContainerInfo{}, &info.ContainerStats{})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unauthorized access")
+}
+
+func TestBufferedStatsOfFailedWritesAreNotLost(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		requests int
+		lines    []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gz, err := gzip.NewReader(r.Body)
+		require.Nil(t, err)
+		data, err := ioutil.ReadAll(gz)
+		require.Nil(t, err)
+		lines = append(lines, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	driver, err := newStorage("machine-1", server.URL, "my-org", "my-bucket", "", nil, time.Hour)
+	require.Nil(t, err)
+	s, err := buffer.New("influxdb2", driver, buffer.Config{Capacity: 10, MaxRetryInterval: 10 * time.Millisecond, BatchSize: 3})
+	require.Nil(t, err)
+
+	cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	for i := 0; i < 5; i++ {
+		stats := &info.ContainerStats{
+			Timestamp: time.Unix(1395066363, 0),
+			Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: uint64(i)}},
+		}
+		require.Nil(t, s.AddStats(cInfo, stats))
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for s.Stats().MemoryEntries > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Nil(t, s.Close())
+
+	lock.Lock()
+	defer lock.Unlock()
+	var cpuLines []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "cpu,") {
+			cpuLines = append(cpuLines, line)
+		}
+	}
+	require.Len(t, cpuLines, 5)
+	for i, line := range cpuLines {
+		assert.Contains(t, line, fmt.Sprintf("usage_total=%di,", i))
+	}
+	assert.NotZero(t, s.Stats().Retries)
+	assert.Zero(t, s.Stats().Dropped)
+}
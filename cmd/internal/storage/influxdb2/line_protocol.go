@@ -0,0 +1,91 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb2
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// writeLine appends the point in the line protocol to the buffer. Tags of the
+// point override the common tags. Tags and fields are sorted by key, as
+// recommended for the best write performance.
+func writeLine(buf *bytes.Buffer, p point, commonTags map[string]string, timestamp time.Time) {
+	if len(p.fields) == 0 {
+		return
+	}
+	tags := make(map[string]string, len(commonTags)+len(p.tags))
+	for k, v := range commonTags {
+		tags[k] = v
+	}
+	for k, v := range p.tags {
+		tags[k] = v
+	}
+
+	buf.WriteString(measurementEscaper.Replace(p.measurement))
+	for _, k := range sortedKeys(tags) {
+		// Empty tag values are not allowed.
+		if tags[k] == "" {
+			continue
+		}
+		buf.WriteByte(',')
+		buf.WriteString(tagEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(tagEscaper.Replace(tags[k]))
+	}
+
+	fieldKeys := make([]string, 0, len(p.fields))
+	for k := range p.fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for i, k := range fieldKeys {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(tagEscaper.Replace(k))
+		buf.WriteByte('=')
+		switch v := p.fields[k].(type) {
+		case int64:
+			buf.WriteString(strconv.FormatInt(v, 10))
+			buf.WriteByte('i')
+		case float64:
+			buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(timestamp.UnixNano(), 10))
+	buf.WriteByte('\n')
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -19,17 +19,20 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/google/cadvisor/cmd/internal/storage/samples"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/storage"
 	"github.com/google/cadvisor/utils/container"
 
 	kafka "github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
 	"k8s.io/klog/v2"
 )
 
@@ -39,17 +42,26 @@ func init() {
 }
 
 var (
-	brokers   = flag.String("storage_driver_kafka_broker_list", "localhost:9092", "kafka broker(s) csv")
-	topic     = flag.String("storage_driver_kafka_topic", "stats", "kafka topic")
-	certFile  = flag.String("storage_driver_kafka_ssl_cert", "", "optional certificate file for TLS client authentication")
-	keyFile   = flag.String("storage_driver_kafka_ssl_key", "", "optional key file for TLS client authentication")
-	caFile    = flag.String("storage_driver_kafka_ssl_ca", "", "optional certificate authority file for TLS client authentication")
-	verifySSL = flag.Bool("storage_driver_kafka_ssl_verify", true, "verify ssl certificate chain")
+	brokers       = flag.String("storage_driver_kafka_broker_list", "localhost:9092", "kafka broker(s) csv")
+	topic         = flag.String("storage_driver_kafka_topic", "stats", "kafka topic")
+	enableTLS     = flag.Bool("storage_driver_kafka_tls", false, "connect to kafka using TLS, implied when any of -storage_driver_kafka_ssl_* files is set")
+	certFile      = flag.String("storage_driver_kafka_ssl_cert", "", "optional certificate file for TLS client authentication")
+	keyFile       = flag.String("storage_driver_kafka_ssl_key", "", "optional key file for TLS client authentication")
+	caFile        = flag.String("storage_driver_kafka_ssl_ca", "", "optional certificate authority file used to verify kafka brokers")
+	verifySSL     = flag.Bool("storage_driver_kafka_ssl_verify", true, "verify ssl certificate chain")
+	saslMechanism = flag.String("storage_driver_kafka_sasl_mechanism", "", "optional SASL mechanism used to authenticate to kafka, one of: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512")
+	saslUser      = flag.String("storage_driver_kafka_sasl_user", "", "SASL user name")
+	saslPassword  = flag.String("storage_driver_kafka_sasl_password", "", "SASL password")
+	topicRoutes   = flag.String("storage_driver_kafka_topic_routes", "", fmt.Sprintf("comma-separated list of group=topic pairs routing stats of a metric group to a separate topic, other stats are sent to -storage_driver_kafka_topic. Groups are: %s", strings.Join(samples.MetricGroupNames(), ", ")))
+	serialization = flag.String("storage_driver_kafka_serialization", serializationJSON, "serialization of messages, one of: json, avro, protobuf")
+	registryURL   = flag.String("storage_driver_kafka_schema_registry_url", "", "optional URL of a schema registry, avro and protobuf schemas are registered there and messages are prefixed with the schema ID")
 )
 
 type kafkaStorage struct {
 	producer    kafka.AsyncProducer
 	topic       string
+	routes      map[string]string
+	encoder     encoder
 	machineName string
 }
 
@@ -80,15 +92,61 @@ func (s *kafkaStorage) infoToDetailSpec(cInfo *info.ContainerInfo, stats *info.C
 }
 
 func (s *kafkaStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
-	detail := s.infoToDetailSpec(cInfo, stats)
-	b, err := json.Marshal(detail)
+	if s.encoder != nil {
+		return s.addSamples(cInfo, stats)
+	}
 
-	s.producer.Input() <- &kafka.ProducerMessage{
-		Topic: s.topic,
-		Value: kafka.StringEncoder(b),
+	// Stats of routed metric groups are sent to their topics, the rest to the default topic.
+	messages := map[string]*info.ContainerStats{}
+	if len(s.routes) > 0 && stats != nil {
+		rest := *stats
+		for _, group := range samples.MetricGroups {
+			if topic, ok := s.routes[group.Name]; ok {
+				groupStats := &info.ContainerStats{Timestamp: stats.Timestamp}
+				group.CopyStats(groupStats, stats)
+				group.CopyStats(&rest, &info.ContainerStats{})
+				messages[topic] = groupStats
+			}
+		}
+		messages[s.topic] = &rest
+	} else {
+		messages[s.topic] = stats
 	}
 
-	return err
+	for topic, stats := range messages {
+		detail := s.infoToDetailSpec(cInfo, stats)
+		b, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		s.producer.Input() <- &kafka.ProducerMessage{
+			Topic: topic,
+			Value: kafka.StringEncoder(b),
+		}
+	}
+	return nil
+}
+
+// addSamples sends stats of the container as samples serialized by the encoder.
+func (s *kafkaStorage) addSamples(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	for _, sample := range samples.ContainerSamples(s.machineName, cInfo, stats) {
+		topic, ok := s.routes[sample.Group]
+		if !ok {
+			topic = s.topic
+		}
+		b, err := s.encoder.encode(topic, sample)
+		if err != nil {
+			return err
+		}
+		s.producer.Input() <- &kafka.ProducerMessage{
+			Topic: topic,
+			Value: kafka.ByteEncoder(b),
+		}
+	}
+	return nil
 }
 
 func (s *kafkaStorage) Close() error {
@@ -104,27 +162,75 @@ func new() (storage.StorageDriver, error) {
 }
 
 func generateTLSConfig() (*tls.Config, error) {
-	if *certFile != "" && *keyFile != "" && *caFile != "" {
+	if !*enableTLS && *certFile == "" && *keyFile == "" && *caFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !*verifySSL,
+	}
+
+	if *certFile != "" || *keyFile != "" {
 		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
 		if err != nil {
 			return nil, err
 		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
 
+	if *caFile != "" {
 		caCert, err := ioutil.ReadFile(*caFile)
 		if err != nil {
 			return nil, err
 		}
 		caCertPool := x509.NewCertPool()
 		caCertPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = caCertPool
+	}
 
-		return &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            caCertPool,
-			InsecureSkipVerify: *verifySSL,
-		}, nil
+	return tlsConfig, nil
+}
+
+func configureSASL(config *kafka.Config, mechanism, user, password string) error {
+	if mechanism == "" {
+		return nil
 	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = user
+	config.Net.SASL.Password = password
+	config.Net.SASL.Mechanism = kafka.SASLMechanism(mechanism)
+	switch config.Net.SASL.Mechanism {
+	case kafka.SASLTypePlaintext:
+	case kafka.SASLTypeSCRAMSHA256:
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() kafka.SCRAMClient {
+			return &scramClient{hash: scram.SHA256}
+		}
+	case kafka.SASLTypeSCRAMSHA512:
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() kafka.SCRAMClient {
+			return &scramClient{hash: scramSHA512}
+		}
+	default:
+		return fmt.Errorf("unsupported SASL mechanism %q", mechanism)
+	}
+	return nil
+}
 
-	return nil, nil
+func parseTopicRoutes(value string) (map[string]string, error) {
+	routes := map[string]string{}
+	for _, route := range strings.Split(value, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+		parts := strings.SplitN(route, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid kafka topic route %q, expected group=topic", route)
+		}
+		if samples.FindMetricGroup(parts[0]) == nil {
+			return nil, fmt.Errorf("unknown metric group %q in kafka topic route, known groups are: %s", parts[0], strings.Join(samples.MetricGroupNames(), ", "))
+		}
+		routes[parts[0]] = parts[1]
+	}
+	return routes, nil
 }
 
 func newStorage(machineName string) (storage.StorageDriver, error) {
@@ -140,6 +246,28 @@ func newStorage(machineName string) (storage.StorageDriver, error) {
 		config.Net.TLS.Config = tlsConfig
 	}
 
+	err = configureSASL(config, *saslMechanism, *saslUser, *saslPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := parseTopicRoutes(*topicRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	var enc encoder
+	if *serialization != serializationJSON {
+		topics := []string{*topic}
+		for _, t := range routes {
+			topics = append(topics, t)
+		}
+		enc, err = newEncoder(*serialization, *registryURL, topics)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	config.Producer.RequiredAcks = kafka.WaitForAll
 
 	brokerList := strings.Split(*brokers, ",")
@@ -152,6 +280,8 @@ func newStorage(machineName string) (storage.StorageDriver, error) {
 	ret := &kafkaStorage{
 		producer:    producer,
 		topic:       *topic,
+		routes:      routes,
+		encoder:     enc,
 		machineName: machineName,
 	}
 	return ret, nil
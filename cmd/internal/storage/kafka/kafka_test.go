@@ -0,0 +1,161 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/samples"
+	info "github.com/google/cadvisor/info/v1"
+
+	kafka "github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xdg-go/scram"
+)
+
+// Test vector from RFC 7677.
+func TestSCRAMClient(t *testing.T) {
+	c := &scramClient{hash: scram.SHA256, nonce: "rOprNGfwEbeRWgbNEkqO"}
+	require.Nil(t, c.Begin("user", "pencil", ""))
+
+	msg, err := c.Step("")
+	assert.Nil(t, err)
+	assert.Equal(t, "n,,n=user,r=rOprNGfwEbeRWgbNEkqO", msg)
+	assert.False(t, c.Done())
+
+	msg, err = c.Step("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	assert.Nil(t, err)
+	assert.Equal(t, "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=", msg)
+	assert.False(t, c.Done())
+
+	msg, err = c.Step("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")
+	assert.Nil(t, err)
+	assert.Empty(t, msg)
+	assert.True(t, c.Done())
+}
+
+func TestSCRAMClientInvalidServerSignature(t *testing.T) {
+	c := &scramClient{hash: scram.SHA256, nonce: "rOprNGfwEbeRWgbNEkqO"}
+	require.Nil(t, c.Begin("user", "pencil", ""))
+	_, err := c.Step("")
+	require.Nil(t, err)
+	_, err = c.Step("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	require.Nil(t, err)
+	_, err = c.Step("v=AAAA")
+	assert.NotNil(t, err)
+}
+
+func TestParseTopicRoutes(t *testing.T) {
+	routes, err := parseTopicRoutes("cpu=cadvisor-cpu, network=cadvisor-network")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"cpu": "cadvisor-cpu", "network": "cadvisor-network"}, routes)
+
+	_, err = parseTopicRoutes("cpu")
+	assert.NotNil(t, err)
+	_, err = parseTopicRoutes("unknown=topic")
+	assert.NotNil(t, err)
+}
+
+func TestConfigureSASL(t *testing.T) {
+	config := kafka.NewConfig()
+	assert.Nil(t, configureSASL(config, "SCRAM-SHA-512", "user", "password"))
+	assert.True(t, config.Net.SASL.Enable)
+	assert.NotNil(t, config.Net.SASL.SCRAMClientGeneratorFunc)
+
+	assert.NotNil(t, configureSASL(kafka.NewConfig(), "GSSAPI", "user", "password"))
+}
+
+// fakeProducer collects produced messages.
+type fakeProducer struct {
+	input chan *kafka.ProducerMessage
+}
+
+func (p *fakeProducer) AsyncClose()                              {}
+func (p *fakeProducer) Close() error                             { return nil }
+func (p *fakeProducer) Input() chan<- *kafka.ProducerMessage     { return p.input }
+func (p *fakeProducer) Successes() <-chan *kafka.ProducerMessage { return nil }
+func (p *fakeProducer) Errors() <-chan *kafka.ProducerError      { return nil }
+
+func (p *fakeProducer) messages() map[string]*kafka.ProducerMessage {
+	close(p.input)
+	messages := map[string]*kafka.ProducerMessage{}
+	for message := range p.input {
+		messages[message.Topic] = message
+	}
+	return messages
+}
+
+func testStats() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Id: "abcd", Aliases: []string{"app"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100}},
+		Memory:    info.MemoryStats{Usage: 200},
+	}
+	return cInfo, stats
+}
+
+func TestAddStatsRoutesMetricGroups(t *testing.T) {
+	producer := &fakeProducer{input: make(chan *kafka.ProducerMessage, 10)}
+	s := &kafkaStorage{producer: producer, topic: "stats", routes: map[string]string{"cpu": "stats-cpu"}}
+	require.Nil(t, s.AddStats(testStats()))
+
+	messages := producer.messages()
+	require.Len(t, messages, 2)
+	decode := func(topic string) *detailSpec {
+		b, err := messages[topic].Value.Encode()
+		require.Nil(t, err)
+		detail := &detailSpec{}
+		require.Nil(t, json.Unmarshal(b, detail))
+		return detail
+	}
+	cpu := decode("stats-cpu")
+	assert.Equal(t, uint64(100), cpu.ContainerStats.Cpu.Usage.Total)
+	assert.Equal(t, uint64(0), cpu.ContainerStats.Memory.Usage)
+	rest := decode("stats")
+	assert.Equal(t, uint64(0), rest.ContainerStats.Cpu.Usage.Total)
+	assert.Equal(t, uint64(200), rest.ContainerStats.Memory.Usage)
+}
+
+func TestEncoderWithSchemaRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/subjects/stats-value/versions", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		request := map[string]string{}
+		require.Nil(t, json.Unmarshal(body, &request))
+		assert.Equal(t, "PROTOBUF", request["schemaType"])
+		assert.Equal(t, samples.ProtobufSchema, request["schema"])
+		w.Write([]byte(`{"id": 258}`))
+	}))
+	defer server.Close()
+
+	enc, err := newEncoder(serializationProtobuf, server.URL, []string{"stats"})
+	require.Nil(t, err)
+	s := &samples.Sample{Group: "cpu"}
+	b, err := enc.encode("stats", s)
+	assert.Nil(t, err)
+	assert.Equal(t, append([]byte{0, 0, 0, 1, 2, 0}, samples.MarshalProtobuf(s)...), b)
+
+	_, err = enc.encode("other", s)
+	assert.NotNil(t, err)
+}
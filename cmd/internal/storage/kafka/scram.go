@@ -0,0 +1,55 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"crypto/sha512"
+
+	"github.com/xdg-go/scram"
+)
+
+// scramSHA512 returns SHA-512 hasher, xdg-go/scram provides only SHA-1 and SHA-256 ones.
+var scramSHA512 scram.HashGeneratorFcn = sha512.New
+
+// scramClient adapts SCRAM conversation of xdg-go/scram (RFC 5802) to the
+// client interface sarama uses for SCRAM authentication.
+type scramClient struct {
+	hash scram.HashGeneratorFcn
+	// nonce overrides the randomly generated client nonce, used in tests.
+	nonce string
+
+	conversation *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hash.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	if c.nonce != "" {
+		nonce := c.nonce
+		client = client.WithNonceGenerator(func() string { return nonce })
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
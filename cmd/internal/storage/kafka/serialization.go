@@ -0,0 +1,129 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/samples"
+)
+
+const (
+	serializationJSON     = "json"
+	serializationAvro     = "avro"
+	serializationProtobuf = "protobuf"
+)
+
+// encoder serializes samples sent to a topic.
+type encoder interface {
+	encode(topic string, s *samples.Sample) ([]byte, error)
+}
+
+type sampleEncoder struct {
+	marshal func(s *samples.Sample) []byte
+	// Schema IDs by topic, nil if schema registry is not used.
+	schemaIDs map[string]int
+	// Prefix of protobuf messages after the schema ID, indexes of the message type in the schema.
+	messageIndexes []byte
+}
+
+func newEncoder(serialization, registryURL string, topics []string) (encoder, error) {
+	var enc *sampleEncoder
+	var schema, schemaType string
+	switch serialization {
+	case serializationAvro:
+		enc = &sampleEncoder{marshal: samples.MarshalAvro}
+		schema, schemaType = samples.AvroSchema, "AVRO"
+	case serializationProtobuf:
+		// The first message in the schema is encoded as a single zero.
+		enc = &sampleEncoder{marshal: samples.MarshalProtobuf, messageIndexes: []byte{0}}
+		schema, schemaType = samples.ProtobufSchema, "PROTOBUF"
+	default:
+		return nil, fmt.Errorf("unsupported kafka serialization %q", serialization)
+	}
+	if registryURL == "" {
+		return enc, nil
+	}
+
+	registry := &schemaRegistry{url: strings.TrimSuffix(registryURL, "/"), client: &http.Client{Timeout: 30 * time.Second}}
+	enc.schemaIDs = map[string]int{}
+	for _, topic := range topics {
+		// Subjects follow the default topic name strategy of Confluent serializers.
+		id, err := registry.register(topic+"-value", schema, schemaType)
+		if err != nil {
+			return nil, err
+		}
+		enc.schemaIDs[topic] = id
+	}
+	return enc, nil
+}
+
+// encode serializes the sample, in the Confluent wire format when schema registry is used.
+func (e *sampleEncoder) encode(topic string, s *samples.Sample) ([]byte, error) {
+	payload := e.marshal(s)
+	if e.schemaIDs == nil {
+		return payload, nil
+	}
+	id, ok := e.schemaIDs[topic]
+	if !ok {
+		return nil, fmt.Errorf("no schema registered for kafka topic %q", topic)
+	}
+	b := make([]byte, 5, 5+len(e.messageIndexes)+len(payload))
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	b = append(b, e.messageIndexes...)
+	return append(b, payload...), nil
+}
+
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+}
+
+// register registers the schema under the subject, or looks up its ID if it
+// is already registered, and returns its ID.
+func (r *schemaRegistry) register(subject, schema, schemaType string) (int, error) {
+	request := map[string]string{"schema": schema}
+	if schemaType != "AVRO" {
+		request["schemaType"] = schemaType
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Post(r.url+"/subjects/"+subject+"/versions", "application/vnd.schemaregistry.v1+json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to register schema of %q: %v", subject, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, fmt.Errorf("failed to register schema of %q: %s: %s", subject, resp.Status, bytes.TrimSpace(message))
+	}
+	var response struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to register schema of %q: %v", subject, err)
+	}
+	return response.ID, nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The subset of the OTLP metrics data model used by cAdvisor. Messages are
+// encoded by hand, following opentelemetry/proto/metrics/v1/metrics.proto,
+// to avoid depending on the generated OpenTelemetry protocol packages.
+
+type keyValue struct {
+	key   string
+	value string
+}
+
+type dataPoint struct {
+	attributes []keyValue
+	value      float64
+}
+
+type metric struct {
+	name        string
+	description string
+	unit        string
+	// monotonic metrics are exported as cumulative sums, the others as gauges.
+	monotonic bool
+	start     time.Time
+	time      time.Time
+	points    []dataPoint
+}
+
+type resourceMetrics struct {
+	attributes []keyValue
+	metrics    []metric
+}
+
+// Field numbers of the OTLP messages.
+const (
+	exportRequestResourceMetrics protowire.Number = 1
+
+	resourceMetricsResource     protowire.Number = 1
+	resourceMetricsScopeMetrics protowire.Number = 2
+	resourceAttributes          protowire.Number = 1
+
+	scopeMetricsScope   protowire.Number = 1
+	scopeMetricsMetrics protowire.Number = 2
+	scopeName           protowire.Number = 1
+	scopeVersion        protowire.Number = 2
+
+	metricName        protowire.Number = 1
+	metricDescription protowire.Number = 2
+	metricUnit        protowire.Number = 3
+	metricGauge       protowire.Number = 5
+	metricSum         protowire.Number = 7
+
+	gaugeDataPoints           protowire.Number = 1
+	sumDataPoints             protowire.Number = 1
+	sumAggregationTemporality protowire.Number = 2
+	sumIsMonotonic            protowire.Number = 3
+
+	dataPointStartTimeUnixNano protowire.Number = 2
+	dataPointTimeUnixNano      protowire.Number = 3
+	dataPointAsDouble          protowire.Number = 4
+	dataPointAttributes        protowire.Number = 7
+
+	keyValueKey    protowire.Number = 1
+	keyValueValue  protowire.Number = 2
+	anyValueString protowire.Number = 1
+)
+
+const (
+	scopeNameCAdvisor = "github.com/google/cadvisor"
+	// AGGREGATION_TEMPORALITY_CUMULATIVE of the AggregationTemporality enum.
+	aggregationTemporalityCumulative = 2
+)
+
+// marshalExportRequest serializes an ExportMetricsServiceRequest.
+func marshalExportRequest(resources []resourceMetrics, cadvisorVersion string) []byte {
+	var b []byte
+	for _, resource := range resources {
+		b = appendMessage(b, exportRequestResourceMetrics, marshalResourceMetrics(resource, cadvisorVersion))
+	}
+	return b
+}
+
+func marshalResourceMetrics(resource resourceMetrics, cadvisorVersion string) []byte {
+	var r []byte
+	for _, attribute := range resource.attributes {
+		r = appendMessage(r, resourceAttributes, marshalKeyValue(attribute))
+	}
+
+	var scope []byte
+	scope = appendString(scope, scopeName, scopeNameCAdvisor)
+	scope = appendString(scope, scopeVersion, cadvisorVersion)
+	var sm []byte
+	sm = appendMessage(sm, scopeMetricsScope, scope)
+	for _, m := range resource.metrics {
+		sm = appendMessage(sm, scopeMetricsMetrics, marshalMetric(m))
+	}
+
+	var b []byte
+	b = appendMessage(b, resourceMetricsResource, r)
+	b = appendMessage(b, resourceMetricsScopeMetrics, sm)
+	return b
+}
+
+func marshalMetric(m metric) []byte {
+	var b []byte
+	b = appendString(b, metricName, m.name)
+	b = appendString(b, metricDescription, m.description)
+	b = appendString(b, metricUnit, m.unit)
+
+	var data []byte
+	if m.monotonic {
+		for _, point := range m.points {
+			data = appendMessage(data, sumDataPoints, marshalDataPoint(point, m.start, m.time))
+		}
+		data = protowire.AppendTag(data, sumAggregationTemporality, protowire.VarintType)
+		data = protowire.AppendVarint(data, aggregationTemporalityCumulative)
+		data = protowire.AppendTag(data, sumIsMonotonic, protowire.VarintType)
+		data = protowire.AppendVarint(data, protowire.EncodeBool(true))
+		return appendMessage(b, metricSum, data)
+	}
+	for _, point := range m.points {
+		data = appendMessage(data, gaugeDataPoints, marshalDataPoint(point, time.Time{}, m.time))
+	}
+	return appendMessage(b, metricGauge, data)
+}
+
+func marshalDataPoint(point dataPoint, start, timestamp time.Time) []byte {
+	var b []byte
+	if !start.IsZero() {
+		b = protowire.AppendTag(b, dataPointStartTimeUnixNano, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, uint64(start.UnixNano()))
+	}
+	b = protowire.AppendTag(b, dataPointTimeUnixNano, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(timestamp.UnixNano()))
+	b = protowire.AppendTag(b, dataPointAsDouble, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(point.value))
+	for _, attribute := range point.attributes {
+		b = appendMessage(b, dataPointAttributes, marshalKeyValue(attribute))
+	}
+	return b
+}
+
+func marshalKeyValue(kv keyValue) []byte {
+	var value []byte
+	value = appendString(value, anyValueString, kv.value)
+
+	var b []byte
+	b = appendString(b, keyValueKey, kv.key)
+	return appendMessage(b, keyValueValue, value)
+}
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
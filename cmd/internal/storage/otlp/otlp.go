@@ -0,0 +1,353 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp implements a storage driver which pushes container and machine
+// metrics to an OpenTelemetry collector using OTLP over gRPC.
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/utils/container"
+	"github.com/google/cadvisor/version"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func init() {
+	storage.RegisterStorageDriver("otlp", new)
+}
+
+var (
+	argEndpoint = flag.String("storage_driver_otlp_endpoint", "localhost:4317", "OTLP/gRPC endpoint (host:port) of the OpenTelemetry collector")
+	argTimeout  = flag.Duration("storage_driver_otlp_timeout", 10*time.Second, "timeout of a single OTLP export request")
+	argCaFile   = flag.String("storage_driver_otlp_ca", "", "optional certificate authority file used to verify the OpenTelemetry collector, implies -storage_driver_secure")
+)
+
+// Full name of the Export method of the OTLP MetricsService.
+const exportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+// Resource attribute names, following OpenTelemetry semantic conventions.
+const (
+	attrHostName           = "host.name"
+	attrContainerID        = "container.id"
+	attrContainerName      = "container.name"
+	attrContainerImageName = "container.image.name"
+	attrContainerRuntime   = "container.runtime"
+	attrCgroupPath         = "cadvisor.container.path"
+	attrK8sPodName         = "k8s.pod.name"
+	attrK8sPodUID          = "k8s.pod.uid"
+	attrK8sNamespaceName   = "k8s.namespace.name"
+	attrK8sContainerName   = "k8s.container.name"
+	attrLabelPrefix        = "container.label."
+)
+
+// Container labels set by kubelet, translated into Kubernetes resource attributes.
+var kubernetesLabels = map[string]string{
+	"io.kubernetes.pod.name":       attrK8sPodName,
+	"io.kubernetes.pod.uid":        attrK8sPodUID,
+	"io.kubernetes.pod.namespace":  attrK8sNamespaceName,
+	"io.kubernetes.container.name": attrK8sContainerName,
+}
+
+type otlpStorage struct {
+	conn           *grpc.ClientConn
+	machineName    string
+	timeout        time.Duration
+	bufferDuration time.Duration
+	lastWrite      time.Time
+	resources      []resourceMetrics
+	lock           sync.Mutex
+	readyToFlush   func() bool
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(
+		hostname,
+		*argEndpoint,
+		*storage.ArgDbIsSecure,
+		*argCaFile,
+		*argTimeout,
+		*storage.ArgDbBufferDuration,
+	)
+}
+
+func newStorage(
+	machineName,
+	endpoint string,
+	isSecure bool,
+	caFile string,
+	timeout time.Duration,
+	bufferDuration time.Duration,
+) (*otlpStorage, error) {
+	dialOption := grpc.WithInsecure()
+	if isSecure || caFile != "" {
+		tlsConfig := &tls.Config{}
+		if caFile != "" {
+			caCert, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			caCertPool := x509.NewCertPool()
+			if !caCertPool.AppendCertsFromPEM(caCert) {
+				return nil, fmt.Errorf("no certificates found in %q", caFile)
+			}
+			tlsConfig.RootCAs = caCertPool
+		}
+		dialOption = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	// Dialing is not blocking, the connection is established in the background
+	// and re-established by gRPC whenever it breaks.
+	conn, err := grpc.Dial(endpoint, dialOption, grpc.WithUserAgent("cAdvisor/"+version.Info["version"]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial OTLP endpoint %q: %v", endpoint, err)
+	}
+
+	ret := &otlpStorage{
+		conn:           conn,
+		machineName:    machineName,
+		timeout:        timeout,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret, nil
+}
+
+func (s *otlpStorage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
+}
+
+// resourceAttributes describes the entity the stats belong to. Stats of the
+// root container carry only the host name and are the machine metrics.
+func (s *otlpStorage) resourceAttributes(cInfo *info.ContainerInfo) []keyValue {
+	attributes := []keyValue{{key: attrHostName, value: s.machineName}}
+	if cInfo.Name == "/" {
+		return attributes
+	}
+	attributes = append(attributes, keyValue{key: attrCgroupPath, value: cInfo.Name})
+	if cInfo.Id != "" {
+		attributes = append(attributes, keyValue{key: attrContainerID, value: cInfo.Id})
+	}
+	attributes = append(attributes, keyValue{key: attrContainerName, value: container.GetPreferredName(cInfo.ContainerReference)})
+	if cInfo.Spec.Image != "" {
+		attributes = append(attributes, keyValue{key: attrContainerImageName, value: cInfo.Spec.Image})
+	}
+	if cInfo.Namespace != "" {
+		attributes = append(attributes, keyValue{key: attrContainerRuntime, value: cInfo.Namespace})
+	}
+	for _, name := range sortedKeys(cInfo.Spec.Labels) {
+		value := cInfo.Spec.Labels[name]
+		if attr, ok := kubernetesLabels[name]; ok {
+			attributes = append(attributes, keyValue{key: attr, value: value})
+			continue
+		}
+		attributes = append(attributes, keyValue{key: attrLabelPrefix + name, value: value})
+	}
+	return attributes
+}
+
+func (s *otlpStorage) containerStatsToMetrics(cInfo *info.ContainerInfo, stats *info.ContainerStats) []metric {
+	start := cInfo.Spec.CreationTime
+	if start.IsZero() || start.After(stats.Timestamp) {
+		start = stats.Timestamp
+	}
+	newGauge := func(name, description, unit string, points ...dataPoint) metric {
+		return metric{name: name, description: description, unit: unit, points: points}
+	}
+	newSum := func(name, description, unit string, points ...dataPoint) metric {
+		return metric{name: name, description: description, unit: unit, points: points, monotonic: true, start: start}
+	}
+	point := func(value float64, attributes ...keyValue) dataPoint {
+		return dataPoint{value: value, attributes: attributes}
+	}
+
+	var metrics []metric
+	if cInfo.Spec.HasCpu {
+		metrics = append(metrics,
+			newSum("container_cpu_usage_seconds_total", "Cumulative cpu time consumed", "s",
+				point(float64(stats.Cpu.Usage.Total)/float64(time.Second))),
+			newSum("container_cpu_user_seconds_total", "Cumulative user cpu time consumed", "s",
+				point(float64(stats.Cpu.Usage.User)/float64(time.Second))),
+			newSum("container_cpu_system_seconds_total", "Cumulative system cpu time consumed", "s",
+				point(float64(stats.Cpu.Usage.System)/float64(time.Second))),
+		)
+	}
+	if cInfo.Spec.HasMemory {
+		metrics = append(metrics,
+			newGauge("container_memory_usage_bytes", "Current memory usage, including all memory regardless of when it was accessed", "By",
+				point(float64(stats.Memory.Usage))),
+			newGauge("container_memory_working_set_bytes", "Current working set", "By",
+				point(float64(stats.Memory.WorkingSet))),
+			newGauge("container_memory_rss", "Size of RSS", "By",
+				point(float64(stats.Memory.RSS))),
+			newGauge("container_memory_cache", "Number of bytes of page cache memory", "By",
+				point(float64(stats.Memory.Cache))),
+			newGauge("container_memory_swap", "Container swap usage", "By",
+				point(float64(stats.Memory.Swap))),
+			newSum("container_memory_failcnt", "Number of memory usage hits limits", "1",
+				point(float64(stats.Memory.Failcnt))),
+		)
+	}
+	if cInfo.Spec.HasNetwork && len(stats.Network.Interfaces) > 0 {
+		var rxBytes, rxErrors, txBytes, txErrors []dataPoint
+		for _, iface := range stats.Network.Interfaces {
+			attr := keyValue{key: "interface", value: iface.Name}
+			rxBytes = append(rxBytes, point(float64(iface.RxBytes), attr))
+			rxErrors = append(rxErrors, point(float64(iface.RxErrors), attr))
+			txBytes = append(txBytes, point(float64(iface.TxBytes), attr))
+			txErrors = append(txErrors, point(float64(iface.TxErrors), attr))
+		}
+		metrics = append(metrics,
+			newSum("container_network_receive_bytes_total", "Cumulative count of bytes received", "By", rxBytes...),
+			newSum("container_network_receive_errors_total", "Cumulative count of errors encountered while receiving", "1", rxErrors...),
+			newSum("container_network_transmit_bytes_total", "Cumulative count of bytes transmitted", "By", txBytes...),
+			newSum("container_network_transmit_errors_total", "Cumulative count of errors encountered while transmitting", "1", txErrors...),
+		)
+	}
+	if cInfo.Spec.HasFilesystem && len(stats.Filesystem) > 0 {
+		var usage, limit []dataPoint
+		for _, fs := range stats.Filesystem {
+			attr := keyValue{key: "device", value: fs.Device}
+			usage = append(usage, point(float64(fs.Usage), attr))
+			limit = append(limit, point(float64(fs.Limit), attr))
+		}
+		metrics = append(metrics,
+			newGauge("container_fs_usage_bytes", "Number of bytes that are consumed by the container on this filesystem", "By", usage...),
+			newGauge("container_fs_limit_bytes", "Number of bytes that can be consumed by the container on this filesystem", "By", limit...),
+		)
+	}
+	if cInfo.Spec.HasProcesses {
+		metrics = append(metrics,
+			newGauge("container_processes", "Number of processes running inside the container", "1",
+				point(float64(stats.Processes.ProcessCount))),
+			newGauge("container_threads", "Number of threads running inside the container", "1",
+				point(float64(stats.Processes.ThreadsCurrent))),
+		)
+	}
+
+	for i := range metrics {
+		metrics[i].time = stats.Timestamp
+	}
+	return metrics
+}
+
+func (s *otlpStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	var resourcesToFlush []resourceMetrics
+	func() {
+		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.resources = append(s.resources, resourceMetrics{
+			attributes: s.resourceAttributes(cInfo),
+			metrics:    s.containerStatsToMetrics(cInfo, stats),
+		})
+		if s.readyToFlush() {
+			resourcesToFlush = s.resources
+			s.resources = nil
+			s.lastWrite = time.Now()
+		}
+	}()
+	if len(resourcesToFlush) > 0 {
+		return s.export(resourcesToFlush)
+	}
+	return nil
+}
+
+// WriteBatch exports the stats in a single request, it is used by the storage buffer instead of AddStats.
+func (s *otlpStorage) WriteBatch(entries []*buffer.Entry) error {
+	resources := make([]resourceMetrics, 0, len(entries))
+	for _, e := range entries {
+		resources = append(resources, resourceMetrics{
+			attributes: s.resourceAttributes(e.ContainerInfo),
+			metrics:    s.containerStatsToMetrics(e.ContainerInfo, e.ContainerStats),
+		})
+	}
+	return s.export(resources)
+}
+
+func (s *otlpStorage) export(resources []resourceMetrics) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	request := marshalExportRequest(resources, version.Info["version"])
+	var response []byte
+	err := s.conn.Invoke(ctx, exportMethod, request, &response, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return fmt.Errorf("failed to export stats to OTLP endpoint %q: %v", s.conn.Target(), err)
+	}
+	return nil
+}
+
+func (s *otlpStorage) Close() error {
+	s.lock.Lock()
+	resources := s.resources
+	s.resources = nil
+	s.lock.Unlock()
+
+	var err error
+	if len(resources) > 0 {
+		err = s.export(resources)
+	}
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// rawCodec passes already serialized protocol buffers through gRPC. It is
+// named "proto" so the content-type matches what OTLP receivers expect.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestResourceAttributes(t *testing.T) {
+	s := &otlpStorage{machineName: "machine-1"}
+
+	root := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	assert.Equal(t, []keyValue{{key: attrHostName, value: "machine-1"}}, s.resourceAttributes(root))
+
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{
+			Name:      "/docker/abcd",
+			Id:        "abcd",
+			Aliases:   []string{"k8s_app_pod", "abcd"},
+			Namespace: "docker",
+		},
+		Spec: info.ContainerSpec{
+			Image: "nginx:latest",
+			Labels: map[string]string{
+				"io.kubernetes.pod.name":      "pod",
+				"io.kubernetes.pod.namespace": "default",
+				"app":                         "web",
+			},
+		},
+	}
+	assert.Equal(t, []keyValue{
+		{key: attrHostName, value: "machine-1"},
+		{key: attrCgroupPath, value: "/docker/abcd"},
+		{key: attrContainerID, value: "abcd"},
+		{key: attrContainerName, value: "k8s_app_pod"},
+		{key: attrContainerImageName, value: "nginx:latest"},
+		{key: attrContainerRuntime, value: "docker"},
+		{key: attrLabelPrefix + "app", value: "web"},
+		{key: attrK8sPodName, value: "pod"},
+		{key: attrK8sNamespaceName, value: "default"},
+	}, s.resourceAttributes(cInfo))
+}
+
+func TestContainerStatsToMetrics(t *testing.T) {
+	s := &otlpStorage{machineName: "machine-1"}
+	created := time.Unix(1000, 0)
+	now := time.Unix(2000, 0)
+	cInfo := &info.ContainerInfo{
+		Spec: info.ContainerSpec{CreationTime: created, HasCpu: true, HasNetwork: true},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: now,
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 1500000000, User: 1000000000, System: 500000000}},
+		Network: info.NetworkStats{Interfaces: []info.InterfaceStats{
+			{Name: "eth0", RxBytes: 10, TxBytes: 20},
+			{Name: "eth1", RxBytes: 30, TxBytes: 40},
+		}},
+	}
+
+	metrics := s.containerStatsToMetrics(cInfo, stats)
+	byName := map[string]metric{}
+	for _, m := range metrics {
+		byName[m.name] = m
+	}
+	assert.Len(t, byName, 7)
+
+	cpu := byName["container_cpu_usage_seconds_total"]
+	assert.True(t, cpu.monotonic)
+	assert.Equal(t, created, cpu.start)
+	assert.Equal(t, now, cpu.time)
+	assert.Equal(t, []dataPoint{{value: 1.5}}, cpu.points)
+
+	assert.Equal(t, []dataPoint{
+		{value: 20, attributes: []keyValue{{key: "interface", value: "eth0"}}},
+		{value: 40, attributes: []keyValue{{key: "interface", value: "eth1"}}},
+	}, byName["container_network_transmit_bytes_total"].points)
+	assert.NotContains(t, byName, "container_memory_usage_bytes")
+}
+
+// consumeFields splits a serialized message into its fields.
+func consumeFields(t *testing.T, b []byte) map[protowire.Number][][]byte {
+	fields := map[protowire.Number][][]byte{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0)
+		b = b[n:]
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(b)
+			value = protowire.AppendFixed64(nil, v)
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			value = protowire.AppendVarint(nil, v)
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+		require.True(t, n > 0)
+		b = b[n:]
+		fields[num] = append(fields[num], value)
+	}
+	return fields
+}
+
+func TestMarshalExportRequest(t *testing.T) {
+	now := time.Unix(2000, 0)
+	request := marshalExportRequest([]resourceMetrics{{
+		attributes: []keyValue{{key: attrHostName, value: "machine-1"}},
+		metrics: []metric{
+			{name: "container_memory_usage_bytes", unit: "By", time: now, points: []dataPoint{{value: 42}}},
+			{name: "container_cpu_usage_seconds_total", unit: "s", monotonic: true, start: now, time: now, points: []dataPoint{{value: 1.5}}},
+		},
+	}}, "v0.37.0")
+
+	fields := consumeFields(t, request)
+	require.Len(t, fields[exportRequestResourceMetrics], 1)
+	resource := consumeFields(t, fields[exportRequestResourceMetrics][0])
+
+	attributes := consumeFields(t, resource[resourceMetricsResource][0])
+	attribute := consumeFields(t, attributes[resourceAttributes][0])
+	assert.Equal(t, attrHostName, string(attribute[keyValueKey][0]))
+	anyValue := consumeFields(t, attribute[keyValueValue][0])
+	assert.Equal(t, "machine-1", string(anyValue[anyValueString][0]))
+
+	scopeMetrics := consumeFields(t, resource[resourceMetricsScopeMetrics][0])
+	scope := consumeFields(t, scopeMetrics[scopeMetricsScope][0])
+	assert.Equal(t, scopeNameCAdvisor, string(scope[scopeName][0]))
+	assert.Equal(t, "v0.37.0", string(scope[scopeVersion][0]))
+	require.Len(t, scopeMetrics[scopeMetricsMetrics], 2)
+
+	gauge := consumeFields(t, scopeMetrics[scopeMetricsMetrics][0])
+	assert.Equal(t, "container_memory_usage_bytes", string(gauge[metricName][0]))
+	assert.Equal(t, "By", string(gauge[metricUnit][0]))
+	assert.NotContains(t, gauge, metricDescription)
+	gaugeData := consumeFields(t, gauge[metricGauge][0])
+	point := consumeFields(t, gaugeData[gaugeDataPoints][0])
+	assert.NotContains(t, point, dataPointStartTimeUnixNano)
+	timestamp, _ := protowire.ConsumeFixed64(point[dataPointTimeUnixNano][0])
+	assert.Equal(t, uint64(now.UnixNano()), timestamp)
+	value, _ := protowire.ConsumeFixed64(point[dataPointAsDouble][0])
+	assert.Equal(t, 42.0, math.Float64frombits(value))
+
+	sum := consumeFields(t, scopeMetrics[scopeMetricsMetrics][1])
+	assert.Equal(t, "container_cpu_usage_seconds_total", string(sum[metricName][0]))
+	sumData := consumeFields(t, sum[metricSum][0])
+	temporality, _ := protowire.ConsumeVarint(sumData[sumAggregationTemporality][0])
+	assert.Equal(t, uint64(aggregationTemporalityCumulative), temporality)
+	monotonic, _ := protowire.ConsumeVarint(sumData[sumIsMonotonic][0])
+	assert.True(t, protowire.DecodeBool(monotonic))
+	point = consumeFields(t, sumData[sumDataPoints][0])
+	assert.Contains(t, point, dataPointStartTimeUnixNano)
+}
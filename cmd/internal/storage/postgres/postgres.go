@@ -0,0 +1,334 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
+	info "github.com/google/cadvisor/info/v1"
+	storage "github.com/google/cadvisor/storage"
+
+	"github.com/lib/pq"
+)
+
+func init() {
+	storage.RegisterStorageDriver("postgres", new)
+}
+
+var (
+	argURL          = flag.String("storage_driver_postgres_url", "postgres://localhost:5432/cadvisor?sslmode=disable", "PostgreSQL connection string, as URL or space-separated key=value parameters")
+	argTable        = flag.String("storage_driver_postgres_table", "container_stats", "table stats are written to")
+	argTimescaleDB  = flag.Bool("storage_driver_postgres_timescaledb", false, "create the table as TimescaleDB hypertable, requires the timescaledb extension in the database")
+	argCreateSchema = flag.Bool("storage_driver_postgres_create_schema", true, "create the table and its index on startup if they don't exist")
+)
+
+type postgresStorage struct {
+	db             *sql.DB
+	machineName    string
+	table          string
+	bufferDuration time.Duration
+	lastWrite      time.Time
+	rows           [][]interface{}
+	lock           sync.Mutex
+	readyToFlush   func() bool
+}
+
+// column is a column of the stats table.
+type column struct {
+	name string
+	typ  string
+}
+
+// columns of the stats table, in the order of values of rows returned by
+// containerStatsToRow. Cumulative counters are stored as they are reported,
+// rates have to be computed in queries.
+var columns = []column{
+	{"time", "TIMESTAMPTZ NOT NULL"},
+	{"machine_name", "TEXT NOT NULL"},
+	{"container_name", "TEXT NOT NULL"},
+	{"container_id", "TEXT"},
+	{"container_image", "TEXT"},
+	{"labels", "JSONB"},
+	// Cumulative CPU time in nanoseconds.
+	{"cpu_usage_total", "BIGINT"},
+	{"cpu_usage_user", "BIGINT"},
+	{"cpu_usage_system", "BIGINT"},
+	// Smoothed average of number of runnable threads x 1000.
+	{"cpu_load_average", "INTEGER"},
+	{"cpu_throttled_periods", "BIGINT"},
+	{"cpu_throttled_time", "BIGINT"},
+	// Memory in bytes.
+	{"memory_usage", "BIGINT"},
+	{"memory_max_usage", "BIGINT"},
+	{"memory_working_set", "BIGINT"},
+	{"memory_rss", "BIGINT"},
+	{"memory_cache", "BIGINT"},
+	{"memory_swap", "BIGINT"},
+	{"memory_mapped_file", "BIGINT"},
+	{"memory_failcnt", "BIGINT"},
+	// Cumulative network counters summed over all interfaces.
+	{"network_rx_bytes", "BIGINT"},
+	{"network_rx_packets", "BIGINT"},
+	{"network_rx_errors", "BIGINT"},
+	{"network_rx_dropped", "BIGINT"},
+	{"network_tx_bytes", "BIGINT"},
+	{"network_tx_packets", "BIGINT"},
+	{"network_tx_errors", "BIGINT"},
+	{"network_tx_dropped", "BIGINT"},
+	// Filesystem usage and limits in bytes summed over all devices.
+	{"fs_usage", "BIGINT"},
+	{"fs_limit", "BIGINT"},
+	// Cumulative bytes read from and written to block devices.
+	{"disk_read_bytes", "BIGINT"},
+	{"disk_write_bytes", "BIGINT"},
+	{"processes", "BIGINT"},
+	{"threads", "BIGINT"},
+	{"file_descriptors", "BIGINT"},
+}
+
+func columnNames() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// schemaStatements returns statements creating the stats table.
+func schemaStatements(table string, timescaleDB bool) []string {
+	definitions := make([]string, len(columns))
+	for i, c := range columns {
+		definitions[i] = c.name + " " + c.typ
+	}
+	quoted := pq.QuoteIdentifier(table)
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoted, strings.Join(definitions, ", ")),
+	}
+	if timescaleDB {
+		statements = append(statements, fmt.Sprintf("SELECT create_hypertable(%s, 'time', if_not_exists => TRUE)", pq.QuoteLiteral(quoted)))
+	}
+	statements = append(statements, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (container_name, time DESC)", pq.QuoteIdentifier(table+"_container_name_time_idx"), quoted))
+	return statements
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", *argURL)
+	if err != nil {
+		return nil, err
+	}
+	if *argCreateSchema {
+		for _, statement := range schemaStatements(*argTable, *argTimescaleDB) {
+			if _, err := db.Exec(statement); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("failed to create postgres schema: %v", err)
+			}
+		}
+	}
+	return newStorage(db, hostname, *argTable, *storage.ArgDbBufferDuration), nil
+}
+
+func toInt64(v uint64) int64 {
+	if v > 1<<63-1 {
+		return 1<<63 - 1
+	}
+	return int64(v)
+}
+
+func (s *postgresStorage) containerStatsToRow(cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]interface{}, error) {
+	containerName := cInfo.ContainerReference.Name
+	if len(cInfo.ContainerReference.Aliases) > 0 {
+		containerName = cInfo.ContainerReference.Aliases[0]
+	}
+	var labels interface{}
+	if len(cInfo.Spec.Labels) > 0 {
+		b, err := json.Marshal(cInfo.Spec.Labels)
+		if err != nil {
+			return nil, err
+		}
+		labels = string(b)
+	}
+
+	var network info.InterfaceStats
+	for _, iface := range stats.Network.Interfaces {
+		network.RxBytes += iface.RxBytes
+		network.RxPackets += iface.RxPackets
+		network.RxErrors += iface.RxErrors
+		network.RxDropped += iface.RxDropped
+		network.TxBytes += iface.TxBytes
+		network.TxPackets += iface.TxPackets
+		network.TxErrors += iface.TxErrors
+		network.TxDropped += iface.TxDropped
+	}
+	var fsUsage, fsLimit uint64
+	for _, fs := range stats.Filesystem {
+		fsUsage += fs.Usage
+		fsLimit += fs.Limit
+	}
+	var diskRead, diskWrite uint64
+	for _, perDisk := range stats.DiskIo.IoServiceBytes {
+		diskRead += perDisk.Stats["Read"]
+		diskWrite += perDisk.Stats["Write"]
+	}
+
+	return []interface{}{
+		stats.Timestamp,
+		s.machineName,
+		containerName,
+		cInfo.ContainerReference.Id,
+		cInfo.Spec.Image,
+		labels,
+		toInt64(stats.Cpu.Usage.Total),
+		toInt64(stats.Cpu.Usage.User),
+		toInt64(stats.Cpu.Usage.System),
+		stats.Cpu.LoadAverage,
+		toInt64(stats.Cpu.CFS.ThrottledPeriods),
+		toInt64(stats.Cpu.CFS.ThrottledTime),
+		toInt64(stats.Memory.Usage),
+		toInt64(stats.Memory.MaxUsage),
+		toInt64(stats.Memory.WorkingSet),
+		toInt64(stats.Memory.RSS),
+		toInt64(stats.Memory.Cache),
+		toInt64(stats.Memory.Swap),
+		toInt64(stats.Memory.MappedFile),
+		toInt64(stats.Memory.Failcnt),
+		toInt64(network.RxBytes),
+		toInt64(network.RxPackets),
+		toInt64(network.RxErrors),
+		toInt64(network.RxDropped),
+		toInt64(network.TxBytes),
+		toInt64(network.TxPackets),
+		toInt64(network.TxErrors),
+		toInt64(network.TxDropped),
+		toInt64(fsUsage),
+		toInt64(fsLimit),
+		toInt64(diskRead),
+		toInt64(diskWrite),
+		toInt64(stats.Processes.ProcessCount),
+		toInt64(stats.Processes.ThreadsCurrent),
+		toInt64(stats.Processes.FdCount),
+	}, nil
+}
+
+func (s *postgresStorage) OverrideReadyToFlush(readyToFlush func() bool) {
+	s.readyToFlush = readyToFlush
+}
+
+func (s *postgresStorage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
+}
+
+func (s *postgresStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	row, err := s.containerStatsToRow(cInfo, stats)
+	if err != nil {
+		return err
+	}
+	var rowsToFlush [][]interface{}
+	func() {
+		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.rows = append(s.rows, row)
+		if s.readyToFlush() {
+			rowsToFlush = s.rows
+			s.rows = nil
+			s.lastWrite = time.Now()
+		}
+	}()
+	if len(rowsToFlush) > 0 {
+		if err := s.copyRows(rowsToFlush); err != nil {
+			return fmt.Errorf("failed to write stats to postgres - %s", err)
+		}
+	}
+	return nil
+}
+
+// WriteBatch inserts the stats in a single transaction, it is used by the storage buffer instead of AddStats.
+func (s *postgresStorage) WriteBatch(entries []*buffer.Entry) error {
+	rows := make([][]interface{}, 0, len(entries))
+	for _, e := range entries {
+		row, err := s.containerStatsToRow(e.ContainerInfo, e.ContainerStats)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	if err := s.copyRows(rows); err != nil {
+		return fmt.Errorf("failed to write stats to postgres - %s", err)
+	}
+	return nil
+}
+
+// copyRows inserts rows in a single transaction with COPY.
+func (s *postgresStorage) copyRows(rows [][]interface{}) error {
+	txn, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := txn.Prepare(pq.CopyIn(s.table, columnNames()...))
+	if err != nil {
+		txn.Rollback()
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			txn.Rollback()
+			return err
+		}
+	}
+	// Exec without arguments flushes buffered rows.
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		txn.Rollback()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		txn.Rollback()
+		return err
+	}
+	return txn.Commit()
+}
+
+func (s *postgresStorage) Close() error {
+	return s.db.Close()
+}
+
+func newStorage(db *sql.DB, machineName, table string, bufferDuration time.Duration) *postgresStorage {
+	ret := &postgresStorage{
+		db:             db,
+		machineName:    machineName,
+		table:          table,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret
+}
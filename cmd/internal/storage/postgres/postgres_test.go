@@ -0,0 +1,90 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaStatements(t *testing.T) {
+	statements := schemaStatements("stats", true)
+	require.Len(t, statements, 3)
+	assert.Contains(t, statements[0], `CREATE TABLE IF NOT EXISTS "stats" (time TIMESTAMPTZ NOT NULL, machine_name TEXT NOT NULL,`)
+	assert.Equal(t, `SELECT create_hypertable('"stats"', 'time', if_not_exists => TRUE)`, statements[1])
+	assert.Equal(t, `CREATE INDEX IF NOT EXISTS "stats_container_name_time_idx" ON "stats" (container_name, time DESC)`, statements[2])
+
+	assert.Len(t, schemaStatements("stats", false), 2)
+}
+
+func TestContainerStatsToRow(t *testing.T) {
+	s := &postgresStorage{machineName: "machine-1"}
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Id: "abcd", Aliases: []string{"app"}},
+		Spec: info.ContainerSpec{
+			Image:  "nginx",
+			Labels: map[string]string{"app": "web"},
+		},
+	}
+	timestamp := time.Unix(1395066363, 0)
+	stats := &info.ContainerStats{
+		Timestamp: timestamp,
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100}},
+		Network: info.NetworkStats{Interfaces: []info.InterfaceStats{
+			{Name: "eth0", RxBytes: 1},
+			{Name: "eth1", RxBytes: 2},
+		}},
+		DiskIo: info.DiskIoStats{IoServiceBytes: []info.PerDiskStats{
+			{Stats: map[string]uint64{"Read": 3, "Write": 4}},
+			{Stats: map[string]uint64{"Read": 5}},
+		}},
+	}
+
+	row, err := s.containerStatsToRow(cInfo, stats)
+	require.Nil(t, err)
+	require.Len(t, row, len(columns))
+	values := map[string]interface{}{}
+	for i, c := range columns {
+		values[c.name] = row[i]
+	}
+	assert.Equal(t, timestamp, values["time"])
+	assert.Equal(t, "machine-1", values["machine_name"])
+	assert.Equal(t, "app", values["container_name"])
+	assert.Equal(t, "abcd", values["container_id"])
+	assert.Equal(t, "nginx", values["container_image"])
+	assert.Equal(t, `{"app":"web"}`, values["labels"])
+	assert.Equal(t, int64(100), values["cpu_usage_total"])
+	assert.Equal(t, int64(3), values["network_rx_bytes"])
+	assert.Equal(t, int64(8), values["disk_read_bytes"])
+	assert.Equal(t, int64(4), values["disk_write_bytes"])
+}
+
+func TestContainerStatsToRowWithoutLabels(t *testing.T) {
+	s := &postgresStorage{machineName: "machine-1"}
+	row, err := s.containerStatsToRow(&info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}, &info.ContainerStats{})
+	require.Nil(t, err)
+	assert.Equal(t, "/", row[2])
+	assert.Nil(t, row[5])
+}
+
+func TestToInt64(t *testing.T) {
+	assert.Equal(t, int64(1), toInt64(1))
+	assert.Equal(t, int64(1<<63-1), toInt64(1<<64-1))
+}
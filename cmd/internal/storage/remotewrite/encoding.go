@@ -0,0 +1,94 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Messages of the remote write protocol, see prompb/remote.proto and
+// prompb/types.proto in the Prometheus repository. They are encoded by hand
+// to avoid depending on the Prometheus server module.
+
+// Label holding the name of the metric.
+const labelMetricName = "__name__"
+
+type label struct {
+	name  string
+	value string
+}
+
+type timeSeries struct {
+	// labels sorted by name, as required by the protocol.
+	labels []label
+	value  float64
+	// timestamp in milliseconds since epoch.
+	timestamp int64
+}
+
+func newTimeSeries(labels []label, value float64, timestamp int64) timeSeries {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return timeSeries{labels: labels, value: value, timestamp: timestamp}
+}
+
+// Field numbers of the remote write messages.
+const (
+	writeRequestTimeseries protowire.Number = 1
+
+	timeSeriesLabels  protowire.Number = 1
+	timeSeriesSamples protowire.Number = 2
+
+	labelName  protowire.Number = 1
+	labelValue protowire.Number = 2
+
+	sampleValue     protowire.Number = 1
+	sampleTimestamp protowire.Number = 2
+)
+
+// marshalWriteRequest serializes a WriteRequest.
+func marshalWriteRequest(series []timeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		b = appendMessage(b, writeRequestTimeseries, marshalTimeSeries(ts))
+	}
+	return b
+}
+
+func marshalTimeSeries(ts timeSeries) []byte {
+	var b []byte
+	for _, l := range ts.labels {
+		var m []byte
+		m = appendMessage(m, labelName, []byte(l.name))
+		m = appendMessage(m, labelValue, []byte(l.value))
+		b = appendMessage(b, timeSeriesLabels, m)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(ts.value))
+	sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(ts.timestamp))
+	return appendMessage(b, timeSeriesSamples, sample)
+}
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
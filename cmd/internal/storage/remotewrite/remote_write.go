@@ -0,0 +1,327 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remotewrite implements a storage driver which pushes samples to an
+// endpoint implementing the Prometheus remote write protocol.
+package remotewrite
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/metrics"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/version"
+
+	"github.com/golang/snappy"
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	storage.RegisterStorageDriver("remote_write", new)
+}
+
+var (
+	argURL           = flag.String("storage_driver_remote_write_url", "http://localhost:9090/api/v1/write", "URL of the Prometheus remote write endpoint")
+	argUsername      = flag.String("storage_driver_remote_write_username", "", "optional username for basic authentication at the remote write endpoint")
+	argPassword      = flag.String("storage_driver_remote_write_password", "", "optional password for basic authentication at the remote write endpoint")
+	argTimeout       = flag.Duration("storage_driver_remote_write_timeout", 30*time.Second, "timeout of a single remote write request")
+	argBatchSize     = flag.Int("storage_driver_remote_write_batch_size", 2000, "maximum number of series sent in a single remote write request")
+	argQueueCapacity = flag.Int("storage_driver_remote_write_queue_capacity", 100000, "maximum number of series buffered in memory, the oldest series are dropped when the remote write endpoint can not keep up")
+	argMinBackoff    = flag.Duration("storage_driver_remote_write_min_backoff", 100*time.Millisecond, "initial delay before a failed remote write request is retried, doubled on each retry")
+	argMaxBackoff    = flag.Duration("storage_driver_remote_write_max_backoff", 30*time.Second, "maximum delay between retries of a failed remote write request")
+)
+
+// Label added to all series to identify the host they come from.
+const labelInstance = "instance"
+
+var invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type config struct {
+	url           string
+	username      string
+	password      string
+	timeout       time.Duration
+	flushInterval time.Duration
+	batchSize     int
+	queueCapacity int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+}
+
+type remoteWriteStorage struct {
+	config      config
+	client      *http.Client
+	machineName string
+
+	lock    sync.Mutex
+	queue   []timeSeries
+	dropped int
+
+	// flush is signalled when a full batch is queued.
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(hostname, config{
+		url:           *argURL,
+		username:      *argUsername,
+		password:      *argPassword,
+		timeout:       *argTimeout,
+		flushInterval: *storage.ArgDbBufferDuration,
+		batchSize:     *argBatchSize,
+		queueCapacity: *argQueueCapacity,
+		minBackoff:    *argMinBackoff,
+		maxBackoff:    *argMaxBackoff,
+	})
+}
+
+func newStorage(machineName string, config config) (*remoteWriteStorage, error) {
+	if config.batchSize <= 0 {
+		return nil, fmt.Errorf("remote write batch size must be positive, got %d", config.batchSize)
+	}
+	if config.queueCapacity < config.batchSize {
+		return nil, fmt.Errorf("remote write queue capacity (%d) must not be lower than batch size (%d)", config.queueCapacity, config.batchSize)
+	}
+	s := &remoteWriteStorage{
+		config:      config,
+		client:      &http.Client{Timeout: config.timeout},
+		machineName: machineName,
+		flush:       make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *remoteWriteStorage) containerLabels(cInfo *info.ContainerInfo) []label {
+	labels := []label{{name: labelInstance, value: s.machineName}}
+	for name, value := range metrics.DefaultContainerLabels(cInfo) {
+		labels = append(labels, label{name: invalidNameCharRE.ReplaceAllString(name, "_"), value: value})
+	}
+	return labels
+}
+
+func (s *remoteWriteStorage) containerStatsToSeries(cInfo *info.ContainerInfo, stats *info.ContainerStats) []timeSeries {
+	containerLabels := s.containerLabels(cInfo)
+	timestamp := stats.Timestamp.UnixNano() / int64(time.Millisecond)
+
+	var series []timeSeries
+	add := func(name string, value float64, labels ...label) {
+		all := make([]label, 0, len(containerLabels)+len(labels)+1)
+		all = append(all, label{name: labelMetricName, value: name})
+		all = append(all, containerLabels...)
+		all = append(all, labels...)
+		series = append(series, newTimeSeries(all, value, timestamp))
+	}
+
+	if cInfo.Spec.HasCpu {
+		add("container_cpu_usage_seconds_total", float64(stats.Cpu.Usage.Total)/float64(time.Second))
+		add("container_cpu_user_seconds_total", float64(stats.Cpu.Usage.User)/float64(time.Second))
+		add("container_cpu_system_seconds_total", float64(stats.Cpu.Usage.System)/float64(time.Second))
+	}
+	if cInfo.Spec.HasMemory {
+		add("container_memory_usage_bytes", float64(stats.Memory.Usage))
+		add("container_memory_working_set_bytes", float64(stats.Memory.WorkingSet))
+		add("container_memory_rss", float64(stats.Memory.RSS))
+		add("container_memory_cache", float64(stats.Memory.Cache))
+		add("container_memory_swap", float64(stats.Memory.Swap))
+		add("container_memory_failcnt", float64(stats.Memory.Failcnt))
+	}
+	if cInfo.Spec.HasNetwork {
+		for _, iface := range stats.Network.Interfaces {
+			interfaceLabel := label{name: "interface", value: iface.Name}
+			add("container_network_receive_bytes_total", float64(iface.RxBytes), interfaceLabel)
+			add("container_network_receive_errors_total", float64(iface.RxErrors), interfaceLabel)
+			add("container_network_transmit_bytes_total", float64(iface.TxBytes), interfaceLabel)
+			add("container_network_transmit_errors_total", float64(iface.TxErrors), interfaceLabel)
+		}
+	}
+	if cInfo.Spec.HasFilesystem {
+		for _, fs := range stats.Filesystem {
+			deviceLabel := label{name: "device", value: fs.Device}
+			add("container_fs_usage_bytes", float64(fs.Usage), deviceLabel)
+			add("container_fs_limit_bytes", float64(fs.Limit), deviceLabel)
+		}
+	}
+	if cInfo.Spec.HasProcesses {
+		add("container_processes", float64(stats.Processes.ProcessCount))
+		add("container_threads", float64(stats.Processes.ThreadsCurrent))
+	}
+	return series
+}
+
+func (s *remoteWriteStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	series := s.containerStatsToSeries(cInfo, stats)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queue = append(s.queue, series...)
+	if overflow := len(s.queue) - s.config.queueCapacity; overflow > 0 {
+		s.queue = s.queue[overflow:]
+		s.dropped += overflow
+	}
+	if len(s.queue) >= s.config.batchSize {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// WriteBatch sends the stats in a single request, it is used by the storage buffer instead of AddStats.
+func (s *remoteWriteStorage) WriteBatch(entries []*buffer.Entry) error {
+	var batch []timeSeries
+	for _, e := range entries {
+		batch = append(batch, s.containerStatsToSeries(e.ContainerInfo, e.ContainerStats)...)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	recoverable, err := s.send(batch)
+	if err != nil && !recoverable {
+		klog.Errorf("Dropping %d series rejected by remote write endpoint: %v", len(batch), err)
+		return nil
+	}
+	return err
+}
+
+func (s *remoteWriteStorage) Close() error {
+	close(s.stop)
+	<-s.done
+	return nil
+}
+
+// run sends queued series every flush interval or as soon as a full batch is
+// queued, until the storage is closed.
+func (s *remoteWriteStorage) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			// Last attempt to send what is left, without retries.
+			for batch := s.nextBatch(); len(batch) > 0; batch = s.nextBatch() {
+				if _, err := s.send(batch); err != nil {
+					klog.Warningf("Dropping %d series on shutdown: %v", len(batch), err)
+					return
+				}
+			}
+			return
+		case <-ticker.C:
+		case <-s.flush:
+		}
+		for batch := s.nextBatch(); len(batch) > 0; batch = s.nextBatch() {
+			if !s.sendWithRetries(batch) {
+				return
+			}
+		}
+	}
+}
+
+// nextBatch removes up to batch size of the oldest series from the queue.
+func (s *remoteWriteStorage) nextBatch() []timeSeries {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.dropped > 0 {
+		klog.Warningf("Remote write queue is full, dropped %d oldest series", s.dropped)
+		s.dropped = 0
+	}
+	n := len(s.queue)
+	if n > s.config.batchSize {
+		n = s.config.batchSize
+	}
+	batch := s.queue[:n:n]
+	s.queue = s.queue[n:]
+	return batch
+}
+
+// sendWithRetries sends the batch, retrying recoverable errors with
+// exponential backoff. It returns false if the storage was closed meanwhile.
+func (s *remoteWriteStorage) sendWithRetries(batch []timeSeries) bool {
+	backoff := s.config.minBackoff
+	for {
+		recoverable, err := s.send(batch)
+		if err == nil {
+			return true
+		}
+		if !recoverable {
+			klog.Errorf("Dropping %d series rejected by remote write endpoint: %v", len(batch), err)
+			return true
+		}
+		klog.V(2).Infof("Remote write failed, retrying in %v: %v", backoff, err)
+		select {
+		case <-s.stop:
+			klog.Warningf("Dropping %d series on shutdown: %v", len(batch), err)
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.config.maxBackoff {
+			backoff = s.config.maxBackoff
+		}
+	}
+}
+
+// send writes the batch to the remote write endpoint. Network errors, server
+// errors and throttling are recoverable, other failures are not.
+func (s *remoteWriteStorage) send(batch []timeSeries) (bool, error) {
+	data := snappy.Encode(nil, marshalWriteRequest(batch))
+	req, err := http.NewRequest(http.MethodPost, s.config.url, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Add("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "cAdvisor/"+version.Info["version"])
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if s.config.username != "" {
+		req.SetBasicAuth(s.config.username, s.config.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(body))
+	return resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests, err
+}
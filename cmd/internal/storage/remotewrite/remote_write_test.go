@@ -0,0 +1,246 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remotewrite
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fakeEndpoint records requests and fails the first failures of them with given status.
+type fakeEndpoint struct {
+	lock     sync.Mutex
+	failures int
+	status   int
+	requests [][]byte
+	received chan struct{}
+}
+
+func (e *fakeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.failures > 0 {
+		e.failures--
+		w.WriteHeader(e.status)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	data, err := snappy.Decode(nil, body)
+	if err != nil || r.Header.Get("Content-Encoding") != "snappy" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	e.requests = append(e.requests, data)
+	e.received <- struct{}{}
+}
+
+func newTestStorage(t *testing.T, endpoint *fakeEndpoint) (*remoteWriteStorage, *httptest.Server) {
+	endpoint.received = make(chan struct{}, 10)
+	server := httptest.NewServer(endpoint)
+	s, err := newStorage("machine-1", config{
+		url:           server.URL,
+		timeout:       time.Second,
+		flushInterval: time.Hour,
+		batchSize:     2,
+		queueCapacity: 4,
+		minBackoff:    time.Millisecond,
+		maxBackoff:    10 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	return s, server
+}
+
+func waitForRequest(t *testing.T, endpoint *fakeEndpoint) {
+	select {
+	case <-endpoint.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for remote write request")
+	}
+}
+
+var (
+	testInfo = &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Aliases: []string{"app"}},
+		Spec: info.ContainerSpec{
+			HasCpu: true,
+			Labels: map[string]string{"io.kubernetes.pod.name": "pod"},
+		},
+	}
+	testStats = &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 1500000000}},
+	}
+)
+
+func TestContainerStatsToSeries(t *testing.T) {
+	s := &remoteWriteStorage{machineName: "machine-1"}
+	series := s.containerStatsToSeries(testInfo, testStats)
+	require.Len(t, series, 3)
+	assert.Equal(t, timeSeries{
+		labels: []label{
+			{name: labelMetricName, value: "container_cpu_usage_seconds_total"},
+			{name: "container_label_io_kubernetes_pod_name", value: "pod"},
+			{name: "id", value: "/docker/abcd"},
+			{name: "instance", value: "machine-1"},
+			{name: "name", value: "app"},
+		},
+		value:     1.5,
+		timestamp: 1395066363000,
+	}, series[0])
+}
+
+func TestMarshalWriteRequest(t *testing.T) {
+	b := marshalWriteRequest([]timeSeries{newTimeSeries([]label{{name: "b", value: "2"}, {name: "a", value: "1"}}, 1.5, 1000)})
+
+	num, typ, n := protowire.ConsumeTag(b)
+	assert.Equal(t, writeRequestTimeseries, num)
+	assert.Equal(t, protowire.BytesType, typ)
+	ts, m := protowire.ConsumeBytes(b[n:])
+	assert.Equal(t, len(b), n+m)
+
+	var labels []string
+	for len(ts) > 0 {
+		num, _, n := protowire.ConsumeTag(ts)
+		value, m := protowire.ConsumeBytes(ts[n:])
+		ts = ts[n+m:]
+		switch num {
+		case timeSeriesLabels:
+			_, _, n := protowire.ConsumeTag(value)
+			name, _ := protowire.ConsumeBytes(value[n:])
+			labels = append(labels, string(name))
+		case timeSeriesSamples:
+			_, _, n := protowire.ConsumeTag(value)
+			v, m := protowire.ConsumeFixed64(value[n:])
+			assert.Equal(t, 1.5, math.Float64frombits(v))
+			_, _, k := protowire.ConsumeTag(value[n+m:])
+			timestamp, _ := protowire.ConsumeVarint(value[n+m+k:])
+			assert.Equal(t, uint64(1000), timestamp)
+		}
+	}
+	assert.Equal(t, []string{"a", "b"}, labels)
+}
+
+func TestSend(t *testing.T) {
+	var headers http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	s := &remoteWriteStorage{
+		config:      config{url: server.URL, username: "user", password: "secret"},
+		machineName: "machine-1",
+		client:      server.Client(),
+	}
+	batch := s.containerStatsToSeries(testInfo, testStats)
+
+	recoverable, err := s.send(batch)
+	require.Nil(t, err)
+	assert.False(t, recoverable)
+	assert.Equal(t, "snappy", headers.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", headers.Get("X-Prometheus-Remote-Write-Version"))
+	user, password, ok := (&http.Request{Header: headers}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", password)
+	data, err := snappy.Decode(nil, body)
+	require.Nil(t, err)
+	assert.Equal(t, marshalWriteRequest(batch), data)
+}
+
+func TestSendRecoverableErrors(t *testing.T) {
+	for _, tc := range []struct {
+		status      int
+		recoverable bool
+	}{
+		{http.StatusServiceUnavailable, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadRequest, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		}))
+		s := &remoteWriteStorage{config: config{url: server.URL}, client: server.Client()}
+		recoverable, err := s.send(s.containerStatsToSeries(testInfo, testStats))
+		server.Close()
+		assert.NotNil(t, err)
+		assert.Equal(t, tc.recoverable, recoverable, "status %d", tc.status)
+	}
+}
+
+func TestAddStatsFlushesFullBatches(t *testing.T) {
+	endpoint := &fakeEndpoint{}
+	s, server := newTestStorage(t, endpoint)
+	defer server.Close()
+
+	// Three series fill a batch, so the queue is flushed in two requests
+	// without waiting for the flush interval.
+	require.Nil(t, s.AddStats(testInfo, testStats))
+	waitForRequest(t, endpoint)
+	waitForRequest(t, endpoint)
+
+	require.Nil(t, s.Close())
+	assert.Len(t, endpoint.requests, 2)
+	assert.Empty(t, s.queue)
+}
+
+func TestRetryRecoverableErrors(t *testing.T) {
+	endpoint := &fakeEndpoint{failures: 3, status: http.StatusServiceUnavailable}
+	s, server := newTestStorage(t, endpoint)
+	defer server.Close()
+	defer s.Close()
+
+	require.Nil(t, s.AddStats(testInfo, testStats))
+	waitForRequest(t, endpoint)
+	waitForRequest(t, endpoint)
+
+	endpoint.lock.Lock()
+	defer endpoint.lock.Unlock()
+	assert.Equal(t, 0, endpoint.failures)
+	assert.Len(t, endpoint.requests, 2)
+}
+
+func TestDropRejectedBatches(t *testing.T) {
+	s := &remoteWriteStorage{config: config{batchSize: 2, queueCapacity: 4}, machineName: "machine-1", flush: make(chan struct{}, 1)}
+	for i := 0; i < 2; i++ {
+		require.Nil(t, s.AddStats(testInfo, testStats))
+	}
+	// Six series were added, the two oldest were dropped.
+	assert.Len(t, s.queue, 4)
+	assert.Equal(t, 2, s.dropped)
+
+	endpoint := &fakeEndpoint{failures: 1, status: http.StatusBadRequest}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+	s.config.url = server.URL
+	s.client = server.Client()
+	assert.True(t, s.sendWithRetries(s.nextBatch()))
+	assert.Len(t, s.queue, 2)
+	assert.Empty(t, endpoint.requests)
+}
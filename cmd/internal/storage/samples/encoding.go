@@ -0,0 +1,177 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// AvroSchema describes samples in avro serialization.
+const AvroSchema = `{
+  "type": "record",
+  "name": "ContainerSample",
+  "namespace": "com.github.google.cadvisor",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "machine_name", "type": "string"},
+    {"name": "container_name", "type": "string"},
+    {"name": "container_id", "type": "string"},
+    {"name": "labels", "type": {"type": "map", "values": "string"}},
+    {"name": "group", "type": "string"},
+    {"name": "attributes", "type": {"type": "map", "values": "string"}},
+    {"name": "values", "type": {"type": "map", "values": "double"}}
+  ]
+}`
+
+// ProtobufSchema describes samples in protobuf serialization, timestamp is
+// in milliseconds since epoch.
+const ProtobufSchema = `syntax = "proto3";
+package cadvisor;
+
+message ContainerSample {
+  int64 timestamp = 1;
+  string machine_name = 2;
+  string container_name = 3;
+  string container_id = 4;
+  map<string, string> labels = 5;
+  string group = 6;
+  map<string, string> attributes = 7;
+  map<string, double> values = 8;
+}
+`
+
+func timestampMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// MarshalAvro serializes the sample in the avro binary encoding of AvroSchema.
+func MarshalAvro(s *Sample) []byte {
+	var b []byte
+	appendLong := func(v int64) {
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+	}
+	appendString := func(v string) {
+		appendLong(int64(len(v)))
+		b = append(b, v...)
+	}
+	appendStringMap := func(m map[string]string) {
+		if len(m) > 0 {
+			appendLong(int64(len(m)))
+			for _, k := range sortedKeys(m) {
+				appendString(k)
+				appendString(m[k])
+			}
+		}
+		appendLong(0)
+	}
+
+	appendLong(timestampMillis(s.Timestamp))
+	appendString(s.MachineName)
+	appendString(s.ContainerName)
+	appendString(s.ContainerID)
+	appendStringMap(s.Labels)
+	appendString(s.Group)
+	appendStringMap(s.Attributes)
+	if len(s.Values) > 0 {
+		appendLong(int64(len(s.Values)))
+		for _, k := range sortedValueKeys(s.Values) {
+			appendString(k)
+			b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint64(b[len(b)-8:], math.Float64bits(s.Values[k]))
+		}
+	}
+	appendLong(0)
+	return b
+}
+
+// MarshalProtobuf serializes the sample as the ContainerSample message of ProtobufSchema.
+func MarshalProtobuf(s *Sample) []byte {
+	var b []byte
+	appendString := func(b []byte, num protowire.Number, v string) []byte {
+		if v == "" {
+			return b
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, v)
+	}
+	appendStringMap := func(num protowire.Number, m map[string]string) {
+		for _, k := range sortedKeys(m) {
+			var entry []byte
+			entry = appendString(entry, 1, k)
+			entry = appendString(entry, 2, m[k])
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, entry)
+		}
+	}
+
+	if timestamp := timestampMillis(s.Timestamp); timestamp != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(timestamp))
+	}
+	b = appendString(b, 2, s.MachineName)
+	b = appendString(b, 3, s.ContainerName)
+	b = appendString(b, 4, s.ContainerID)
+	appendStringMap(5, s.Labels)
+	b = appendString(b, 6, s.Group)
+	appendStringMap(7, s.Attributes)
+	for _, k := range sortedValueKeys(s.Values) {
+		var entry []byte
+		entry = appendString(entry, 1, k)
+		entry = protowire.AppendTag(entry, 2, protowire.Fixed64Type)
+		entry = protowire.AppendFixed64(entry, math.Float64bits(s.Values[k]))
+		b = protowire.AppendTag(b, 8, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedValueKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MarshalProtobufList serializes the samples as the message
+//
+//	message ContainerSamples {
+//	  repeated ContainerSample samples = 1;
+//	}
+//
+// in the package of ProtobufSchema.
+func MarshalProtobufList(samples []*Sample) []byte {
+	var b []byte
+	for _, s := range samples {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, MarshalProtobuf(s))
+	}
+	return b
+}
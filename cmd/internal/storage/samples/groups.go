@@ -0,0 +1,241 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"strconv"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/container"
+)
+
+// Sample holds values of a metric group of a container, e.g. of a single
+// network interface, as they are sent in avro and protobuf serialization.
+type Sample struct {
+	Timestamp     time.Time
+	MachineName   string
+	ContainerName string
+	ContainerID   string
+	Labels        map[string]string
+	Group         string
+	Attributes    map[string]string
+	Values        map[string]float64
+}
+
+// MetricGroup is a part of container stats which storage drivers can handle separately.
+type MetricGroup struct {
+	Name string
+	// CopyStats copies stats of the group from src to dst.
+	CopyStats func(dst, src *info.ContainerStats)
+	// Samples returns values of the group, only group specific fields of samples are set.
+	Samples func(stats *info.ContainerStats) []*Sample
+}
+
+var MetricGroups = []MetricGroup{
+	{
+		Name: "cpu",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Cpu = src.Cpu
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			return []*Sample{{Values: map[string]float64{
+				"usage_total":  float64(stats.Cpu.Usage.Total),
+				"usage_user":   float64(stats.Cpu.Usage.User),
+				"usage_system": float64(stats.Cpu.Usage.System),
+				"load_average": float64(stats.Cpu.LoadAverage),
+			}}}
+		},
+	},
+	{
+		Name: "memory",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Memory = src.Memory
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			return []*Sample{{Values: map[string]float64{
+				"usage":       float64(stats.Memory.Usage),
+				"max_usage":   float64(stats.Memory.MaxUsage),
+				"cache":       float64(stats.Memory.Cache),
+				"rss":         float64(stats.Memory.RSS),
+				"swap":        float64(stats.Memory.Swap),
+				"mapped_file": float64(stats.Memory.MappedFile),
+				"working_set": float64(stats.Memory.WorkingSet),
+				"failcnt":     float64(stats.Memory.Failcnt),
+			}}}
+		},
+	},
+	{
+		Name: "network",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Network = src.Network
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			var samples []*Sample
+			for _, iface := range stats.Network.Interfaces {
+				samples = append(samples, &Sample{
+					Attributes: map[string]string{"interface": iface.Name},
+					Values: map[string]float64{
+						"rx_bytes":   float64(iface.RxBytes),
+						"rx_packets": float64(iface.RxPackets),
+						"rx_errors":  float64(iface.RxErrors),
+						"rx_dropped": float64(iface.RxDropped),
+						"tx_bytes":   float64(iface.TxBytes),
+						"tx_packets": float64(iface.TxPackets),
+						"tx_errors":  float64(iface.TxErrors),
+						"tx_dropped": float64(iface.TxDropped),
+					},
+				})
+			}
+			return samples
+		},
+	},
+	{
+		Name: "filesystem",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Filesystem = src.Filesystem
+			dst.DiskIo = src.DiskIo
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			var samples []*Sample
+			for _, fs := range stats.Filesystem {
+				samples = append(samples, &Sample{
+					Attributes: map[string]string{"device": fs.Device},
+					Values: map[string]float64{
+						"usage": float64(fs.Usage),
+						"limit": float64(fs.Limit),
+					},
+				})
+			}
+			return samples
+		},
+	},
+	{
+		Name: "processes",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Processes = src.Processes
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			return []*Sample{{Values: map[string]float64{
+				"process_count":   float64(stats.Processes.ProcessCount),
+				"fd_count":        float64(stats.Processes.FdCount),
+				"socket_count":    float64(stats.Processes.SocketCount),
+				"threads_current": float64(stats.Processes.ThreadsCurrent),
+			}}}
+		},
+	},
+	{
+		Name: "hugetlb",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Hugetlb = src.Hugetlb
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			var samples []*Sample
+			for pageSize, hugetlb := range stats.Hugetlb {
+				samples = append(samples, &Sample{
+					Attributes: map[string]string{"page_size": pageSize},
+					Values: map[string]float64{
+						"usage":     float64(hugetlb.Usage),
+						"max_usage": float64(hugetlb.MaxUsage),
+						"failcnt":   float64(hugetlb.Failcnt),
+					},
+				})
+			}
+			return samples
+		},
+	},
+	{
+		Name: "perf",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.PerfStats = src.PerfStats
+			dst.PerfUncoreStats = src.PerfUncoreStats
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			var samples []*Sample
+			for _, stat := range stats.PerfStats {
+				samples = append(samples, &Sample{
+					Attributes: map[string]string{"event": stat.Name, "cpu": strconv.Itoa(stat.Cpu)},
+					Values: map[string]float64{
+						"value":         float64(stat.Value),
+						"scaling_ratio": stat.ScalingRatio,
+					},
+				})
+			}
+			return samples
+		},
+	},
+	{
+		Name: "resctrl",
+		CopyStats: func(dst, src *info.ContainerStats) {
+			dst.Resctrl = src.Resctrl
+		},
+		Samples: func(stats *info.ContainerStats) []*Sample {
+			var samples []*Sample
+			for nodeID, bandwidth := range stats.Resctrl.MemoryBandwidth {
+				samples = append(samples, &Sample{
+					Attributes: map[string]string{"node_id": strconv.Itoa(nodeID)},
+					Values: map[string]float64{
+						"memory_bandwidth_total": float64(bandwidth.TotalBytes),
+						"memory_bandwidth_local": float64(bandwidth.LocalBytes),
+					},
+				})
+			}
+			for nodeID, cache := range stats.Resctrl.Cache {
+				samples = append(samples, &Sample{
+					Attributes: map[string]string{"node_id": strconv.Itoa(nodeID)},
+					Values:     map[string]float64{"llc_occupancy": float64(cache.LLCOccupancy)},
+				})
+			}
+			return samples
+		},
+	},
+}
+
+// FindMetricGroup returns the metric group with the name, or nil if there is none.
+func FindMetricGroup(name string) *MetricGroup {
+	for i := range MetricGroups {
+		if MetricGroups[i].Name == name {
+			return &MetricGroups[i]
+		}
+	}
+	return nil
+}
+
+// MetricGroupNames returns names of all metric groups.
+func MetricGroupNames() []string {
+	names := make([]string, 0, len(MetricGroups))
+	for _, group := range MetricGroups {
+		names = append(names, group.Name)
+	}
+	return names
+}
+
+// ContainerSamples returns samples of all metric groups of the container stats.
+func ContainerSamples(machineName string, cInfo *info.ContainerInfo, stats *info.ContainerStats) []*Sample {
+	var samples []*Sample
+	containerName := container.GetPreferredName(cInfo.ContainerReference)
+	for _, group := range MetricGroups {
+		for _, sample := range group.Samples(stats) {
+			sample.Timestamp = stats.Timestamp
+			sample.MachineName = machineName
+			sample.ContainerName = containerName
+			sample.ContainerID = cInfo.Id
+			sample.Labels = cInfo.Spec.Labels
+			sample.Group = group.Name
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
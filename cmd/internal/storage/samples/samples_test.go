@@ -0,0 +1,91 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestContainerSamples(t *testing.T) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Id: "abcd", Aliases: []string{"app"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"a": "b"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Network:   info.NetworkStats{Interfaces: []info.InterfaceStats{{Name: "eth0", RxBytes: 10}}},
+	}
+
+	samples := map[string]*Sample{}
+	for _, s := range ContainerSamples("machine-1", cInfo, stats) {
+		samples[s.Group] = s
+	}
+	require.Contains(t, samples, "network")
+	network := samples["network"]
+	assert.Equal(t, stats.Timestamp, network.Timestamp)
+	assert.Equal(t, "machine-1", network.MachineName)
+	assert.Equal(t, "app", network.ContainerName)
+	assert.Equal(t, "abcd", network.ContainerID)
+	assert.Equal(t, cInfo.Spec.Labels, network.Labels)
+	assert.Equal(t, map[string]string{"interface": "eth0"}, network.Attributes)
+	assert.Equal(t, float64(10), network.Values["rx_bytes"])
+	assert.Contains(t, samples, "cpu")
+	assert.NotContains(t, samples, "hugetlb")
+}
+
+func TestFindMetricGroup(t *testing.T) {
+	assert.Equal(t, "memory", FindMetricGroup("memory").Name)
+	assert.Nil(t, FindMetricGroup("unknown"))
+}
+
+func TestMarshalAvro(t *testing.T) {
+	b := MarshalAvro(&Sample{
+		Timestamp:   time.Unix(1, 0),
+		MachineName: "m",
+		Group:       "cpu",
+		Labels:      map[string]string{"a": "b"},
+		Values:      map[string]float64{"v": 1},
+	})
+	assert.Equal(t, []byte{
+		0xd0, 0x0f, // timestamp 1000 zigzag encoded
+		0x02, 'm', // machine_name
+		0x00,                             // container_name
+		0x00,                             // container_id
+		0x02, 0x02, 'a', 0x02, 'b', 0x00, // labels
+		0x06, 'c', 'p', 'u', // group
+		0x00,                                                // attributes
+		0x02, 0x02, 'v', 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x00, // values
+	}, b)
+}
+
+func TestMarshalProtobuf(t *testing.T) {
+	b := MarshalProtobuf(&Sample{Timestamp: time.Unix(1, 0), Group: "cpu"})
+	num, typ, n := protowire.ConsumeTag(b)
+	assert.Equal(t, protowire.Number(1), num)
+	assert.Equal(t, protowire.VarintType, typ)
+	timestamp, m := protowire.ConsumeVarint(b[n:])
+	assert.Equal(t, uint64(1000), timestamp)
+	num, _, l := protowire.ConsumeTag(b[n+m:])
+	assert.Equal(t, protowire.Number(6), num)
+	group, _ := protowire.ConsumeString(b[n+m+l:])
+	assert.Equal(t, "cpu", group)
+}
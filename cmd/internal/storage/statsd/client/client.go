@@ -16,6 +16,7 @@ package client
 
 import (
 	"fmt"
+	"io"
 	"net"
 
 	"k8s.io/klog/v2"
@@ -46,10 +47,14 @@ func (c *Client) Close() error {
 // Simple send to statsd daemon without sampling.
 func (c *Client) Send(namespace, containerName, key string, value uint64) error {
 	// only send counter value
-	formatted := fmt.Sprintf("%s.%s.%s:%d|g", namespace, containerName, key, value)
-	_, err := fmt.Fprintf(c.conn, formatted)
+	return c.SendLine(fmt.Sprintf("%s.%s.%s:%d|g", namespace, containerName, key, value))
+}
+
+// SendLine sends a formatted metric, e.g. with a sample rate or tags.
+func (c *Client) SendLine(line string) error {
+	_, err := io.WriteString(c.conn, line)
 	if err != nil {
-		return fmt.Errorf("failed to send data %q: %v", formatted, err)
+		return fmt.Errorf("failed to send data %q: %v", line, err)
 	}
 	return nil
 }
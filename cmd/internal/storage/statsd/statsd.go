@@ -15,20 +15,81 @@
 package statsd
 
 import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"path"
+	"sort"
 	"strconv"
+	"strings"
 
 	client "github.com/google/cadvisor/cmd/internal/storage/statsd/client"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/utils/container"
 )
 
 func init() {
 	storage.RegisterStorageDriver("statsd", new)
 }
 
+const (
+	formatStatsd    = "statsd"
+	formatDogStatsd = "dogstatsd"
+)
+
+var (
+	argFormat     = flag.String("storage_driver_statsd_format", formatStatsd, "format of metrics, one of: statsd, dogstatsd. dogstatsd sends the container name, ID, image and labels as tags instead of in metric names")
+	argSampleRate = flag.Float64("storage_driver_statsd_sample_rate", 1, "fraction of container stats sent, between 0 and 1, metrics are sent with the sample rate")
+	argMetrics    = flag.String("storage_driver_statsd_metrics", "", "comma-separated list of metric names sent, which may contain wildcards, e.g. cpu_usage_*. Names are without the namespace, container and tags. Empty means all")
+	argTags       = flag.String("storage_driver_statsd_tags", "", "comma-separated list of tags added to all metrics in dogstatsd format, e.g. env:prod")
+	argLabelTags  = flag.String("storage_driver_statsd_label_tags", "", "comma-separated list of container labels sent as tags in dogstatsd format, * means all labels")
+)
+
+type config struct {
+	format     string
+	sampleRate float64
+	// metrics are patterns of metric names sent, all are sent if empty.
+	metrics []string
+	tags    []string
+	// labelTags are labels sent as tags, all are sent if it contains "*".
+	labelTags []string
+}
+
 type statsdStorage struct {
 	client    *client.Client
 	Namespace string
+	config    config
+	// random returns a number in [0.0,1.0) to sample stats.
+	random func() float64
+}
+
+// metric is a value of container stats.
+type metric struct {
+	// name is the name of the metric in dogstatsd format.
+	name string
+	// statsdName is the name of the metric in statsd format, which has values
+	// of tags in it.
+	statsdName string
+	tags       []string
+	value      uint64
+}
+
+type series []metric
+
+// add adds a metric without tags.
+func (s *series) add(name string, value uint64) {
+	*s = append(*s, metric{name: name, statsdName: name, value: value})
+}
+
+// addTagged adds a metric with the tags, its statsd name is the name with values
+// of the tags appended.
+func (s *series) addTagged(name string, value uint64, tags ...string) {
+	statsdName := name
+	for _, tag := range tags {
+		statsdName += "." + tag[strings.Index(tag, ":")+1:]
+	}
+	*s = append(*s, metric{name: name, statsdName: statsdName, tags: tags, value: value})
 }
 
 const (
@@ -94,119 +155,233 @@ const (
 )
 
 func new() (storage.StorageDriver, error) {
-	return newStorage(*storage.ArgDbName, *storage.ArgDbHost)
+	config, err := newConfig(*argFormat, *argSampleRate, *argMetrics, *argTags, *argLabelTags)
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(*storage.ArgDbName, *storage.ArgDbHost, config)
 }
 
-func (s *statsdStorage) containerStatsToValues(stats *info.ContainerStats) (series map[string]uint64) {
-	series = make(map[string]uint64)
+func newConfig(format string, sampleRate float64, metrics, tags, labelTags string) (config, error) {
+	c := config{
+		format:     format,
+		sampleRate: sampleRate,
+		metrics:    splitList(metrics),
+		labelTags:  splitList(labelTags),
+	}
+	if format != formatStatsd && format != formatDogStatsd {
+		return c, fmt.Errorf("unknown statsd format %q, must be one of: %s, %s", format, formatStatsd, formatDogStatsd)
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		return c, fmt.Errorf("statsd sample rate must be in (0, 1], got %v", sampleRate)
+	}
+	for _, pattern := range c.metrics {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return c, fmt.Errorf("invalid statsd metric pattern %q: %v", pattern, err)
+		}
+	}
+	for _, tag := range splitList(tags) {
+		c.tags = append(c.tags, sanitizeTag(tag))
+	}
+	return c, nil
+}
 
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// sanitizeTag replaces characters which separate tags and fields in dogstatsd format.
+func sanitizeTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, tag)
+}
+
+func (s *statsdStorage) containerStatsToValues(series *series, stats *info.ContainerStats) {
 	// Total usage in nanoseconds
-	series[serCpuUsageTotal] = stats.Cpu.Usage.Total
+	series.add(serCpuUsageTotal, stats.Cpu.Usage.Total)
 
 	// To be deprecated in 0.39
-	series[colCpuCumulativeUsage] = series[serCpuUsageTotal]
+	series.add(colCpuCumulativeUsage, stats.Cpu.Usage.Total)
 
 	// CPU usage: Time spend in system space (in nanoseconds)
-	series[serCpuUsageSystem] = stats.Cpu.Usage.System
+	series.add(serCpuUsageSystem, stats.Cpu.Usage.System)
 
 	// CPU usage: Time spent in user space (in nanoseconds)
-	series[serCpuUsageUser] = stats.Cpu.Usage.User
+	series.add(serCpuUsageUser, stats.Cpu.Usage.User)
 
 	// CPU usage per CPU
 	for i := 0; i < len(stats.Cpu.Usage.PerCpu); i++ {
-		series[serCpuUsagePerCpu+"."+strconv.Itoa(i)] = stats.Cpu.Usage.PerCpu[i]
+		series.addTagged(serCpuUsagePerCpu, stats.Cpu.Usage.PerCpu[i], "cpu:"+strconv.Itoa(i))
 	}
 
 	// Load Average
-	series[serLoadAverage] = uint64(stats.Cpu.LoadAverage)
+	series.add(serLoadAverage, uint64(stats.Cpu.LoadAverage))
 
 	// Network stats.
-	series[serRxBytes] = stats.Network.RxBytes
-	series[serRxErrors] = stats.Network.RxErrors
-	series[serTxBytes] = stats.Network.TxBytes
-	series[serTxErrors] = stats.Network.TxErrors
+	series.add(serRxBytes, stats.Network.RxBytes)
+	series.add(serRxErrors, stats.Network.RxErrors)
+	series.add(serTxBytes, stats.Network.TxBytes)
+	series.add(serTxErrors, stats.Network.TxErrors)
 
 	// Referenced Memory
-	series[serReferencedMemory] = stats.ReferencedMemory
-
-	return series
+	series.add(serReferencedMemory, stats.ReferencedMemory)
 }
 
-func (s *statsdStorage) containerFsStatsToValues(series *map[string]uint64, stats *info.ContainerStats) {
+func (s *statsdStorage) containerFsStatsToValues(series *series, stats *info.ContainerStats) {
+	var limit, usage uint64
 	for _, fsStat := range stats.Filesystem {
+		// Per device stats, the device is a prefix of statsd names.
+		*series = append(*series,
+			metric{name: serFsLimit, statsdName: fsStat.Device + "." + serFsLimit, tags: []string{"device:" + fsStat.Device}, value: fsStat.Limit},
+			metric{name: serFsUsage, statsdName: fsStat.Device + "." + serFsUsage, tags: []string{"device:" + fsStat.Device}, value: fsStat.Usage},
+		)
+		limit += fsStat.Limit
+		usage += fsStat.Usage
+	}
+	if len(stats.Filesystem) > 0 {
 		// Summary stats.
-		(*series)[serFsSummary+"."+serFsLimit] += fsStat.Limit
-		(*series)[serFsSummary+"."+serFsUsage] += fsStat.Usage
-
-		// Per device stats.
-		(*series)[fsStat.Device+"."+serFsLimit] = fsStat.Limit
-		(*series)[fsStat.Device+"."+serFsUsage] = fsStat.Usage
+		series.add(serFsSummary+"."+serFsLimit, limit)
+		series.add(serFsSummary+"."+serFsUsage, usage)
 	}
 }
 
-func (s *statsdStorage) memoryStatsToValues(series *map[string]uint64, stats *info.ContainerStats) {
+func (s *statsdStorage) memoryStatsToValues(series *series, stats *info.ContainerStats) {
 	// Memory Usage
-	(*series)[serMemoryUsage] = stats.Memory.Usage
+	series.add(serMemoryUsage, stats.Memory.Usage)
 	// Maximum memory usage recorded
-	(*series)[serMemoryMaxUsage] = stats.Memory.MaxUsage
+	series.add(serMemoryMaxUsage, stats.Memory.MaxUsage)
 	//Number of bytes of page cache memory
-	(*series)[serMemoryCache] = stats.Memory.Cache
+	series.add(serMemoryCache, stats.Memory.Cache)
 	// Size of RSS
-	(*series)[serMemoryRss] = stats.Memory.RSS
+	series.add(serMemoryRss, stats.Memory.RSS)
 	// Container swap usage
-	(*series)[serMemorySwap] = stats.Memory.Swap
+	series.add(serMemorySwap, stats.Memory.Swap)
 	// Size of memory mapped files in bytes
-	(*series)[serMemoryMappedFile] = stats.Memory.MappedFile
+	series.add(serMemoryMappedFile, stats.Memory.MappedFile)
 	// Working Set Size
-	(*series)[serMemoryWorkingSet] = stats.Memory.WorkingSet
+	series.add(serMemoryWorkingSet, stats.Memory.WorkingSet)
 	// Number of memory usage hits limits
-	(*series)[serMemoryFailcnt] = stats.Memory.Failcnt
+	series.add(serMemoryFailcnt, stats.Memory.Failcnt)
 
 	// Cumulative count of memory allocation failures
-	(*series)[serMemoryFailure+".container.pgfault"] = stats.Memory.ContainerData.Pgfault
-	(*series)[serMemoryFailure+".container.pgmajfault"] = stats.Memory.ContainerData.Pgmajfault
-	(*series)[serMemoryFailure+".hierarchical.pgfault"] = stats.Memory.HierarchicalData.Pgfault
-	(*series)[serMemoryFailure+".hierarchical.pgmajfault"] = stats.Memory.HierarchicalData.Pgmajfault
+	series.add(serMemoryFailure+".container.pgfault", stats.Memory.ContainerData.Pgfault)
+	series.add(serMemoryFailure+".container.pgmajfault", stats.Memory.ContainerData.Pgmajfault)
+	series.add(serMemoryFailure+".hierarchical.pgfault", stats.Memory.HierarchicalData.Pgfault)
+	series.add(serMemoryFailure+".hierarchical.pgmajfault", stats.Memory.HierarchicalData.Pgmajfault)
 }
 
-func (s *statsdStorage) hugetlbStatsToValues(series *map[string]uint64, stats *info.ContainerStats) {
+func (s *statsdStorage) hugetlbStatsToValues(series *series, stats *info.ContainerStats) {
 	for pageSize, hugetlbStat := range stats.Hugetlb {
-		(*series)[setHugetlbUsage+"."+pageSize] = hugetlbStat.Usage
-		(*series)[setHugetlbMaxUsage+"."+pageSize] = hugetlbStat.MaxUsage
-		(*series)[setHugetlbFailcnt+"."+pageSize] = hugetlbStat.Failcnt
+		series.addTagged(setHugetlbUsage, hugetlbStat.Usage, "page_size:"+pageSize)
+		series.addTagged(setHugetlbMaxUsage, hugetlbStat.MaxUsage, "page_size:"+pageSize)
+		series.addTagged(setHugetlbFailcnt, hugetlbStat.Failcnt, "page_size:"+pageSize)
 	}
 }
 
-func (s *statsdStorage) perfStatsToValues(series *map[string]uint64, stats *info.ContainerStats) {
+func (s *statsdStorage) perfStatsToValues(series *series, stats *info.ContainerStats) {
 	for _, perfStat := range stats.PerfStats {
-		(*series)[serPerfStat+"."+perfStat.Name+"."+strconv.Itoa(perfStat.Cpu)] = perfStat.Value
+		series.addTagged(serPerfStat, perfStat.Value, "event:"+perfStat.Name, "cpu:"+strconv.Itoa(perfStat.Cpu))
 	}
 }
 
-func (s *statsdStorage) resctrlStatsToValues(series *map[string]uint64, stats *info.ContainerStats) {
+func (s *statsdStorage) resctrlStatsToValues(series *series, stats *info.ContainerStats) {
 	for nodeID, rdtMemoryBandwidth := range stats.Resctrl.MemoryBandwidth {
-		(*series)[serResctrlMemoryBandwidthTotal+"."+strconv.Itoa(nodeID)] = rdtMemoryBandwidth.TotalBytes
-		(*series)[serResctrlMemoryBandwidthLocal+"."+strconv.Itoa(nodeID)] = rdtMemoryBandwidth.LocalBytes
+		series.addTagged(serResctrlMemoryBandwidthTotal, rdtMemoryBandwidth.TotalBytes, "node_id:"+strconv.Itoa(nodeID))
+		series.addTagged(serResctrlMemoryBandwidthLocal, rdtMemoryBandwidth.LocalBytes, "node_id:"+strconv.Itoa(nodeID))
 	}
 	for nodeID, rdtCache := range stats.Resctrl.Cache {
-		(*series)[serResctrlLLCOccupancy+"."+strconv.Itoa(nodeID)] = rdtCache.LLCOccupancy
+		series.addTagged(serResctrlLLCOccupancy, rdtCache.LLCOccupancy, "node_id:"+strconv.Itoa(nodeID))
 	}
 
 }
 
-// Push the data into redis
+// allowed returns true if the metric with the name is sent.
+func (s *statsdStorage) allowed(name string) bool {
+	if len(s.config.metrics) == 0 {
+		return true
+	}
+	for _, pattern := range s.config.metrics {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// containerTags returns tags of the container in dogstatsd format.
+func (s *statsdStorage) containerTags(cInfo *info.ContainerInfo) []string {
+	tags := append([]string{}, s.config.tags...)
+	tags = append(tags, "container_name:"+sanitizeTag(container.GetPreferredName(cInfo.ContainerReference)))
+	if cInfo.Id != "" {
+		tags = append(tags, "container_id:"+sanitizeTag(cInfo.Id))
+	}
+	if cInfo.Spec.Image != "" {
+		tags = append(tags, "image:"+sanitizeTag(cInfo.Spec.Image))
+	}
+	var labelTags []string
+	for _, label := range s.config.labelTags {
+		if label == "*" {
+			labelTags = labelTags[:0]
+			for name, value := range cInfo.Spec.Labels {
+				labelTags = append(labelTags, sanitizeTag(name+":"+value))
+			}
+			break
+		}
+		if value, ok := cInfo.Spec.Labels[label]; ok {
+			labelTags = append(labelTags, sanitizeTag(label+":"+value))
+		}
+	}
+	sort.Strings(labelTags)
+	return append(tags, labelTags...)
+}
+
+// Push the data into statsd
 func (s *statsdStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
 	if stats == nil {
 		return nil
 	}
+	if s.config.sampleRate < 1 && s.random() >= s.config.sampleRate {
+		return nil
+	}
 
-	series := s.containerStatsToValues(stats)
+	var series series
+	s.containerStatsToValues(&series, stats)
 	s.containerFsStatsToValues(&series, stats)
 	s.memoryStatsToValues(&series, stats)
 	s.hugetlbStatsToValues(&series, stats)
 	s.perfStatsToValues(&series, stats)
 	s.resctrlStatsToValues(&series, stats)
 
+	var sampleRate string
+	if s.config.sampleRate < 1 {
+		sampleRate = "|@" + strconv.FormatFloat(s.config.sampleRate, 'f', -1, 64)
+	}
+	if s.config.format == formatDogStatsd {
+		containerTags := s.containerTags(cInfo)
+		for _, metric := range series {
+			if !s.allowed(metric.name) {
+				continue
+			}
+			tags := append(append([]string{}, containerTags...), metric.tags...)
+			line := fmt.Sprintf("%s.%s:%d|g%s|#%s", s.Namespace, metric.name, metric.value, sampleRate, strings.Join(tags, ","))
+			if err := s.client.SendLine(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	var containerName string
 	if len(cInfo.ContainerReference.Aliases) > 0 {
 		containerName = cInfo.ContainerReference.Aliases[0]
@@ -214,9 +389,12 @@ func (s *statsdStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Containe
 		containerName = cInfo.ContainerReference.Name
 	}
 
-	for key, value := range series {
-		err := s.client.Send(s.Namespace, containerName, key, value)
-		if err != nil {
+	for _, metric := range series {
+		if !s.allowed(metric.name) {
+			continue
+		}
+		line := fmt.Sprintf("%s.%s.%s:%d|g%s", s.Namespace, containerName, metric.statsdName, metric.value, sampleRate)
+		if err := s.client.SendLine(line); err != nil {
 			return err
 		}
 	}
@@ -229,7 +407,7 @@ func (s *statsdStorage) Close() error {
 	return nil
 }
 
-func newStorage(namespace, hostPort string) (*statsdStorage, error) {
+func newStorage(namespace, hostPort string, config config) (*statsdStorage, error) {
 	statsdClient, err := client.New(hostPort)
 	if err != nil {
 		return nil, err
@@ -237,6 +415,8 @@ func newStorage(namespace, hostPort string) (*statsdStorage, error) {
 	statsdStorage := &statsdStorage{
 		client:    statsdClient,
 		Namespace: namespace,
+		config:    config,
+		random:    rand.Float64,
 	}
 	return statsdStorage, nil
 }
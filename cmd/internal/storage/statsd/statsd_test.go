@@ -0,0 +1,146 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStats() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Id: "abcd", Aliases: []string{"app"}},
+		Spec: info.ContainerSpec{
+			Image:  "nginx:1.19",
+			Labels: map[string]string{"team": "web", "env": "prod", "note": "a,b"},
+		},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100, PerCpu: []uint64{60, 40}}},
+		Memory:    info.MemoryStats{Usage: 200},
+		Filesystem: []info.FsStats{
+			{Device: "sda1", Limit: 1000, Usage: 300},
+		},
+	}
+	return cInfo, stats
+}
+
+// addStats returns lines received by statsd for the stats.
+func addStats(t *testing.T, config config, random float64) []string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer conn.Close()
+	s, err := newStorage("cadvisor", conn.LocalAddr().String(), config)
+	require.Nil(t, err)
+	defer s.Close()
+	s.random = func() float64 { return random }
+
+	require.Nil(t, s.AddStats(testStats()))
+	// Every stats are followed by a marker, UDP packets are not reordered on loopback.
+	require.Nil(t, s.client.SendLine("end"))
+
+	var lines []string
+	buf := make([]byte, 65536)
+	for {
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.Nil(t, err)
+		line := string(buf[:n])
+		if line == "end" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStatsdFormat(t *testing.T) {
+	config, err := newConfig(formatStatsd, 1, "", "", "")
+	require.Nil(t, err)
+	lines := addStats(t, config, 0)
+	assert.Contains(t, lines, "cadvisor.app.cpu_usage_total:100|g")
+	assert.Contains(t, lines, "cadvisor.app.cpu_usage_per_cpu.1:40|g")
+	assert.Contains(t, lines, "cadvisor.app.memory_usage:200|g")
+	assert.Contains(t, lines, "cadvisor.app.sda1.fs_limit:1000|g")
+	assert.Contains(t, lines, "cadvisor.app.fs_summary.fs_usage:300|g")
+}
+
+func TestDogStatsdFormat(t *testing.T) {
+	config, err := newConfig(formatDogStatsd, 1, "", "region:eu", "team,note,missing")
+	require.Nil(t, err)
+	lines := addStats(t, config, 0)
+	tags := "region:eu,container_name:app,container_id:abcd,image:nginx:1.19,note:a_b,team:web"
+	assert.Contains(t, lines, "cadvisor.cpu_usage_total:100|g|#"+tags)
+	assert.Contains(t, lines, "cadvisor.cpu_usage_per_cpu:40|g|#"+tags+",cpu:1")
+	assert.Contains(t, lines, "cadvisor.fs_limit:1000|g|#"+tags+",device:sda1")
+	assert.Contains(t, lines, "cadvisor.fs_summary.fs_usage:300|g|#"+tags)
+}
+
+func TestDogStatsdAllLabels(t *testing.T) {
+	config, err := newConfig(formatDogStatsd, 1, "memory_usage", "", "*")
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		"cadvisor.memory_usage:200|g|#container_name:app,container_id:abcd,image:nginx:1.19,env:prod,note:a_b,team:web",
+	}, addStats(t, config, 0))
+}
+
+func TestMetricsAllowList(t *testing.T) {
+	config, err := newConfig(formatStatsd, 1, "cpu_usage_*,fs_limit", "", "")
+	require.Nil(t, err)
+	lines := addStats(t, config, 0)
+	assert.ElementsMatch(t, []string{
+		"cadvisor.app.cpu_usage_total:100|g",
+		"cadvisor.app.cpu_usage_system:0|g",
+		"cadvisor.app.cpu_usage_user:0|g",
+		"cadvisor.app.cpu_usage_per_cpu.0:60|g",
+		"cadvisor.app.cpu_usage_per_cpu.1:40|g",
+		"cadvisor.app.sda1.fs_limit:1000|g",
+	}, lines)
+}
+
+func TestSampling(t *testing.T) {
+	config, err := newConfig(formatStatsd, 0.25, "memory_usage", "", "")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"cadvisor.app.memory_usage:200|g|@0.25"}, addStats(t, config, 0.1))
+	assert.Empty(t, addStats(t, config, 0.5))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, args := range []struct {
+		format     string
+		sampleRate float64
+		metrics    string
+	}{
+		{"graphite", 1, ""},
+		{formatStatsd, 0, ""},
+		{formatStatsd, 1.5, ""},
+		{formatStatsd, 1, "cpu_["},
+	} {
+		_, err := newConfig(args.format, args.sampleRate, args.metrics, "", "")
+		assert.NotNil(t, err, "%+v", args)
+	}
+}
+
+func TestSanitizeTag(t *testing.T) {
+	assert.Equal(t, "a:b_c_d_e", sanitizeTag("a:b,c|d#e"))
+	assert.False(t, strings.ContainsAny(sanitizeTag("a\nb"), "\n"))
+}
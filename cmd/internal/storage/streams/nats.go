@@ -0,0 +1,122 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streams
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+
+	"github.com/nats-io/nats.go"
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	storage.RegisterStorageDriver("nats", newNATS)
+}
+
+var (
+	argNATSURL           = flag.String("storage_driver_nats_url", "nats://localhost:4222", "NATS server URL, with optional user:password, tls:// URLs connect with TLS")
+	argNATSSubject       = flag.String("storage_driver_nats_subject", "cadvisor.stats", "NATS subject stats are published to")
+	argNATSJetStream     = flag.Bool("storage_driver_nats_jetstream", false, "wait for the acknowledgement of the JetStream stream capturing the subject for every message")
+	argNATSToken         = flag.String("storage_driver_nats_token", "", "optional NATS authentication token")
+	argNATSCaFile        = flag.String("storage_driver_nats_ca", "", "optional certificate authority file used to verify the NATS server, implies TLS")
+	argNATSTimeout       = flag.Duration("storage_driver_nats_timeout", 5*time.Second, "timeout of connecting to NATS and of JetStream acknowledgements")
+	argNATSSerialization = flag.String("storage_driver_nats_serialization", serializationJSON, "serialization of messages, one of: json, protobuf")
+)
+
+type natsStorage struct {
+	conn *nats.Conn
+	// jetStream is nil unless messages are published to JetStream.
+	jetStream   nats.JetStreamContext
+	machineName string
+	subject     string
+	marshal     marshalFunc
+}
+
+func newNATS() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	marshal, err := newMarshalFunc(*argNATSSerialization)
+	if err != nil {
+		return nil, err
+	}
+	options := []nats.Option{
+		nats.Name("cadvisor"),
+		nats.Timeout(*argNATSTimeout),
+		// Never give up reconnecting, stats are buffered in the meantime.
+		nats.MaxReconnects(-1),
+	}
+	if *argNATSToken != "" {
+		options = append(options, nats.Token(*argNATSToken))
+	}
+	if *argNATSCaFile != "" {
+		options = append(options, nats.RootCAs(*argNATSCaFile))
+	}
+	// Fail early when NATS is not reachable, later failures are handled by
+	// reconnecting in background.
+	conn, err := nats.Connect(*argNATSURL, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+	s := &natsStorage{
+		conn:        conn,
+		machineName: hostname,
+		subject:     *argNATSSubject,
+		marshal:     marshal,
+	}
+	if *argNATSJetStream {
+		s.jetStream, err = conn.JetStream(nats.MaxWait(*argNATSTimeout))
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to use NATS JetStream: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *natsStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	payload, err := s.marshal(s.machineName, cInfo, stats)
+	if err != nil {
+		return err
+	}
+	if s.jetStream == nil {
+		err = s.conn.Publish(s.subject, payload)
+	} else {
+		var ack *nats.PubAck
+		ack, err = s.jetStream.Publish(s.subject, payload)
+		if err == nil {
+			klog.V(5).Infof("Stats stored in JetStream stream %s with sequence %d", ack.Stream, ack.Sequence)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish stats to NATS: %v", err)
+	}
+	return nil
+}
+
+func (s *natsStorage) Close() error {
+	s.conn.Close()
+	return nil
+}
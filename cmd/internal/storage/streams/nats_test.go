@@ -0,0 +1,220 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streams
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// natsMessage is a message published to the fake NATS server.
+type natsMessage struct {
+	subject string
+	reply   string
+	payload []byte
+}
+
+// fakeNATSServer accepts a single connection and records published
+// messages, replying to them with ack if it is not empty. Requests to
+// JetStream API are replied with jsAPIResponse.
+type fakeNATSServer struct {
+	listener      net.Listener
+	ack           string
+	jsAPIResponse string
+	connect       chan map[string]interface{}
+	messages      chan natsMessage
+}
+
+// jsAccountInfo is the response to JetStream account info request of the
+// client, sent when JetStream is enabled.
+const jsAccountInfo = `{"type":"io.nats.jetstream.api.v1.account_info_response"}`
+
+func newFakeNATSServer(t *testing.T, ack, jsAPIResponse string) *fakeNATSServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	s := &fakeNATSServer{
+		listener:      listener,
+		ack:           ack,
+		jsAPIResponse: jsAPIResponse,
+		connect:       make(chan map[string]interface{}, 1),
+		messages:      make(chan natsMessage, 10),
+	}
+	go s.serve()
+	return s
+}
+
+func readNATSLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (s *fakeNATSServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+	reader := bufio.NewReader(conn)
+	// Sids of subscriptions by subject, replies are sent to the inbox
+	// subscription of the client.
+	sids := map[string]string{}
+	for {
+		line, err := readNATSLine(reader)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			options := map[string]interface{}{}
+			json.Unmarshal([]byte(line[len("CONNECT "):]), &options)
+			s.connect <- options
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			sids[fields[1]] = fields[len(fields)-1]
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			message := natsMessage{subject: fields[1], payload: payload[:size]}
+			if len(fields) == 4 {
+				message.reply = fields[2]
+			}
+			if strings.HasPrefix(message.subject, "$JS.API.") {
+				s.reply(conn, sids, message.reply, s.jsAPIResponse)
+				continue
+			}
+			if message.reply != "" {
+				s.reply(conn, sids, message.reply, s.ack)
+			}
+			s.messages <- message
+		}
+	}
+}
+
+func (s *fakeNATSServer) reply(conn net.Conn, sids map[string]string, reply, payload string) {
+	for subject, sid := range sids {
+		if subject == reply || (strings.HasSuffix(subject, ".*") && strings.HasPrefix(reply, strings.TrimSuffix(subject, "*"))) {
+			fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(payload), payload)
+			return
+		}
+	}
+}
+
+func (s *fakeNATSServer) waitForMessage(t *testing.T) natsMessage {
+	select {
+	case message := <-s.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for NATS message")
+		return natsMessage{}
+	}
+}
+
+func newTestNATSStorage(t *testing.T, server *fakeNATSServer, jetStream bool) (*natsStorage, error) {
+	conn, err := nats.Connect("nats://user:secret@"+server.listener.Addr().String(), nats.Timeout(time.Second))
+	require.Nil(t, err)
+	s := &natsStorage{
+		conn:        conn,
+		machineName: "machine-1",
+		subject:     "cadvisor.stats",
+		marshal:     marshalJSON,
+	}
+	if jetStream {
+		s.jetStream, err = conn.JetStream(nats.MaxWait(time.Second))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func TestNATSPublish(t *testing.T) {
+	server := newFakeNATSServer(t, "", jsAccountInfo)
+	defer server.listener.Close()
+	s, err := newTestNATSStorage(t, server, false)
+	require.Nil(t, err)
+	defer s.Close()
+
+	require.Nil(t, s.AddStats(testStats()))
+	connect := <-server.connect
+	assert.Equal(t, "user", connect["user"])
+	assert.Equal(t, "secret", connect["pass"])
+	assert.Equal(t, false, connect["verbose"])
+
+	message := server.waitForMessage(t)
+	assert.Equal(t, "cadvisor.stats", message.subject)
+	assert.Empty(t, message.reply)
+	detail := &detailSpec{}
+	require.Nil(t, json.Unmarshal(message.payload, detail))
+	assert.Equal(t, "app", detail.ContainerName)
+}
+
+func TestNATSPublishToJetStream(t *testing.T) {
+	server := newFakeNATSServer(t, `{"stream":"CADVISOR","seq":1}`, jsAccountInfo)
+	defer server.listener.Close()
+	s, err := newTestNATSStorage(t, server, true)
+	require.Nil(t, err)
+	defer s.Close()
+
+	require.Nil(t, s.AddStats(testStats()))
+	message := server.waitForMessage(t)
+	assert.True(t, strings.HasPrefix(message.reply, "_INBOX."))
+}
+
+func TestNATSPublishToJetStreamError(t *testing.T) {
+	server := newFakeNATSServer(t, `{"error":{"code":503,"description":"storage full"}}`, jsAccountInfo)
+	defer server.listener.Close()
+	s, err := newTestNATSStorage(t, server, true)
+	require.Nil(t, err)
+	defer s.Close()
+
+	err = s.AddStats(testStats())
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "storage full")
+	// The connection is still usable.
+	assert.True(t, s.conn.IsConnected())
+}
+
+func TestNATSJetStreamNotEnabled(t *testing.T) {
+	server := newFakeNATSServer(t, "", `{"error":{"code":503,"description":"jetstream not enabled"}}`)
+	defer server.listener.Close()
+
+	_, err := newTestNATSStorage(t, server, true)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "jetstream not enabled")
+}
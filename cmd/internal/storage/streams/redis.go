@@ -0,0 +1,125 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streams
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/utils/container"
+
+	redis "github.com/garyburd/redigo/redis"
+)
+
+func init() {
+	storage.RegisterStorageDriver("redis_streams", newRedisStreams)
+}
+
+var (
+	argRedisAddress       = flag.String("storage_driver_redis_streams_address", "localhost:6379", "Redis host:port")
+	argRedisPassword      = flag.String("storage_driver_redis_streams_password", "", "optional Redis password")
+	argRedisStream        = flag.String("storage_driver_redis_streams_stream", "cadvisor:stats", "key of the Redis stream stats are added to")
+	argRedisMaxLength     = flag.Int("storage_driver_redis_streams_max_length", 100000, "approximate maximum number of entries kept in the stream, older entries are trimmed, 0 means unlimited")
+	argRedisTimeout       = flag.Duration("storage_driver_redis_streams_timeout", 5*time.Second, "timeout of connecting to Redis, reads and writes")
+	argRedisSerialization = flag.String("storage_driver_redis_streams_serialization", serializationJSON, "serialization of the data field of entries, one of: json, protobuf")
+)
+
+type redisStreamStorage struct {
+	pool        *redis.Pool
+	machineName string
+	stream      string
+	maxLength   int
+	marshal     marshalFunc
+}
+
+func newRedisStreams() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	marshal, err := newMarshalFunc(*argRedisSerialization)
+	if err != nil {
+		return nil, err
+	}
+	address, password, timeout := *argRedisAddress, *argRedisPassword, *argRedisTimeout
+	pool := &redis.Pool{
+		MaxIdle:     2,
+		IdleTimeout: 4 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			conn, err := redis.DialTimeout("tcp", address, timeout, timeout, timeout)
+			if err != nil {
+				return nil, err
+			}
+			if password != "" {
+				if _, err := conn.Do("AUTH", password); err != nil {
+					conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		},
+	}
+	// Fail early when Redis is not reachable.
+	conn := pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	}
+	return &redisStreamStorage{
+		pool:        pool,
+		machineName: hostname,
+		stream:      *argRedisStream,
+		maxLength:   *argRedisMaxLength,
+		marshal:     marshal,
+	}, nil
+}
+
+// xaddArgs returns arguments of the XADD command adding the payload to the stream.
+func (s *redisStreamStorage) xaddArgs(cInfo *info.ContainerInfo, payload []byte) []interface{} {
+	args := []interface{}{s.stream}
+	if s.maxLength > 0 {
+		args = append(args, "MAXLEN", "~", s.maxLength)
+	}
+	return append(args, "*",
+		"machine_name", s.machineName,
+		"container_name", container.GetPreferredName(cInfo.ContainerReference),
+		"data", payload,
+	)
+}
+
+func (s *redisStreamStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	payload, err := s.marshal(s.machineName, cInfo, stats)
+	if err != nil {
+		return err
+	}
+	conn := s.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("XADD", s.xaddArgs(cInfo, payload)...); err != nil {
+		return fmt.Errorf("failed to add stats to Redis stream %q: %v", s.stream, err)
+	}
+	return nil
+}
+
+func (s *redisStreamStorage) Close() error {
+	return s.pool.Close()
+}
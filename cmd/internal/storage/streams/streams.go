@@ -0,0 +1,70 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package streams implements storage drivers publishing stats of every
+// housekeeping of a container as a message to NATS or to a Redis stream.
+package streams
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/storage/samples"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/container"
+)
+
+const (
+	serializationJSON     = "json"
+	serializationProtobuf = "protobuf"
+)
+
+type detailSpec struct {
+	Timestamp       time.Time            `json:"timestamp"`
+	MachineName     string               `json:"machine_name,omitempty"`
+	ContainerName   string               `json:"container_Name,omitempty"`
+	ContainerID     string               `json:"container_Id,omitempty"`
+	ContainerLabels map[string]string    `json:"container_labels,omitempty"`
+	ContainerStats  *info.ContainerStats `json:"container_stats,omitempty"`
+}
+
+// marshalFunc serializes stats of a container into a message.
+type marshalFunc func(machineName string, cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error)
+
+func newMarshalFunc(serialization string) (marshalFunc, error) {
+	switch serialization {
+	case serializationJSON:
+		return marshalJSON, nil
+	case serializationProtobuf:
+		return marshalProtobuf, nil
+	}
+	return nil, fmt.Errorf("unsupported serialization %q, must be one of: json, protobuf", serialization)
+}
+
+func marshalJSON(machineName string, cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error) {
+	return json.Marshal(&detailSpec{
+		Timestamp:       stats.Timestamp,
+		MachineName:     machineName,
+		ContainerName:   container.GetPreferredName(cInfo.ContainerReference),
+		ContainerID:     cInfo.ContainerReference.Id,
+		ContainerLabels: cInfo.Spec.Labels,
+		ContainerStats:  stats,
+	})
+}
+
+// marshalProtobuf serializes samples of all metric groups in a single message.
+func marshalProtobuf(machineName string, cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error) {
+	return samples.MarshalProtobufList(samples.ContainerSamples(machineName, cInfo, stats)), nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streams
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testStats() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abcd", Id: "abcd", Aliases: []string{"app"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"a": "b"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0).UTC(),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100}},
+		Memory:    info.MemoryStats{Usage: 200},
+	}
+	return cInfo, stats
+}
+
+func TestMarshalJSON(t *testing.T) {
+	cInfo, stats := testStats()
+	b, err := marshalJSON("machine-1", cInfo, stats)
+	require.Nil(t, err)
+	detail := &detailSpec{}
+	require.Nil(t, json.Unmarshal(b, detail))
+	assert.Equal(t, stats.Timestamp, detail.Timestamp)
+	assert.Equal(t, "machine-1", detail.MachineName)
+	assert.Equal(t, "app", detail.ContainerName)
+	assert.Equal(t, "abcd", detail.ContainerID)
+	assert.Equal(t, map[string]string{"a": "b"}, detail.ContainerLabels)
+	assert.Equal(t, uint64(200), detail.ContainerStats.Memory.Usage)
+}
+
+func TestMarshalProtobuf(t *testing.T) {
+	cInfo, stats := testStats()
+	b, err := marshalProtobuf("machine-1", cInfo, stats)
+	require.Nil(t, err)
+	// Every sample is a ContainerSamples.samples field.
+	count := 0
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0)
+		assert.Equal(t, protowire.Number(1), num)
+		assert.Equal(t, protowire.BytesType, typ)
+		_, m := protowire.ConsumeBytes(b[n:])
+		require.True(t, m > 0)
+		b = b[n+m:]
+		count++
+	}
+	// cpu, memory and processes samples.
+	assert.Equal(t, 3, count)
+}
+
+func TestNewMarshalFunc(t *testing.T) {
+	_, err := newMarshalFunc("avro")
+	assert.NotNil(t, err)
+}
+
+func TestXAddArgs(t *testing.T) {
+	s := &redisStreamStorage{machineName: "machine-1", stream: "cadvisor:stats", maxLength: 1000}
+	cInfo, _ := testStats()
+	assert.Equal(t, []interface{}{
+		"cadvisor:stats", "MAXLEN", "~", 1000, "*",
+		"machine_name", "machine-1",
+		"container_name", "app",
+		"data", []byte("{}"),
+	}, s.xaddArgs(cInfo, []byte("{}")))
+
+	s.maxLength = 0
+	assert.Equal(t, []interface{}{"cadvisor:stats", "*"}, s.xaddArgs(cInfo, nil)[:2])
+}
@@ -17,17 +17,30 @@ package main
 import (
 	"flag"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/cadvisor/cache/memory"
 	_ "github.com/google/cadvisor/cmd/internal/storage/bigquery"
+	"github.com/google/cadvisor/cmd/internal/storage/buffer"
 	_ "github.com/google/cadvisor/cmd/internal/storage/elasticsearch"
+	_ "github.com/google/cadvisor/cmd/internal/storage/file"
+	_ "github.com/google/cadvisor/cmd/internal/storage/graphite"
 	_ "github.com/google/cadvisor/cmd/internal/storage/influxdb"
+	_ "github.com/google/cadvisor/cmd/internal/storage/influxdb2"
 	_ "github.com/google/cadvisor/cmd/internal/storage/kafka"
+	_ "github.com/google/cadvisor/cmd/internal/storage/otlp"
+	_ "github.com/google/cadvisor/cmd/internal/storage/postgres"
 	_ "github.com/google/cadvisor/cmd/internal/storage/redis"
+	_ "github.com/google/cadvisor/cmd/internal/storage/remotewrite"
+	"github.com/google/cadvisor/cmd/internal/storage/samples"
 	_ "github.com/google/cadvisor/cmd/internal/storage/statsd"
 	_ "github.com/google/cadvisor/cmd/internal/storage/stdout"
+	_ "github.com/google/cadvisor/cmd/internal/storage/streams"
+	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/storage"
 
 	"k8s.io/klog/v2"
@@ -38,20 +51,186 @@ var (
 	storageDuration = flag.Duration("storage_duration", 2*time.Minute, "How long to keep data stored (Default: 2min).")
 )
 
+var (
+	bufferCapacity         = flag.Int("storage_driver_buffer_capacity", 0, "Number of container stats buffered in memory per storage driver while pushing them fails, they are pushed in the background and retried. 0 disables buffering, stats are pushed synchronously and dropped if pushing fails")
+	bufferDir              = flag.String("storage_driver_buffer_dir", "", "Directory the oldest buffered container stats are spilled to when the buffer of a storage driver is full, in a subdirectory per driver, they are pushed after restart too. Empty means they are dropped")
+	bufferMaxDiskSize      = flag.Int64("storage_driver_buffer_max_disk_size", 1024*1024*1024, "Maximum size in bytes of container stats spilled to disk per storage driver, the oldest are dropped when exceeded")
+	bufferMaxRetryInterval = flag.Duration("storage_driver_buffer_max_retry_interval", 30*time.Second, "Maximum interval between retries of pushing buffered container stats to a storage driver")
+	bufferMaxRetries       = flag.Int("storage_driver_buffer_max_retries", 10, "Maximum number of retries of pushing buffered container stats to a storage driver, the stats are dropped when exceeded so that stats rejected by the storage do not block the buffer. 0 means stats are retried until pushed")
+	bufferBatchSize        = flag.Int("storage_driver_buffer_batch_size", 100, "Maximum number of buffered container stats pushed at once to a storage driver which writes stats in batches, failed batches are kept in the buffer and retried")
+)
+
+// bufferedStorages are buffers of storage drivers, set by NewMemoryStorage.
+var bufferedStorages []*buffer.Storage
+
+var (
+	storageDriverIntervals = driverOptions{}
+	storageDriverMetrics   = driverOptions{}
+)
+
+func init() {
+	flag.Var(storageDriverIntervals, "storage_driver_interval", "`driver=interval` minimum interval between stats of a container pushed to the storage driver, stats collected in between are not pushed to it. Can be repeated for multiple drivers")
+	flag.Var(storageDriverMetrics, "storage_driver_metrics", fmt.Sprintf("`driver=groups` comma-separated list of metric groups pushed to the storage driver, other stats are omitted. Can be repeated for multiple drivers. Groups are: %s", strings.Join(samples.MetricGroupNames(), ", ")))
+}
+
+// driverOptions holds values of an option of storage drivers by driver name,
+// set as driver=value.
+type driverOptions map[string]string
+
+func (o driverOptions) String() string {
+	values := make([]string, 0, len(o))
+	for driver, value := range o {
+		values = append(values, driver+"="+value)
+	}
+	sort.Strings(values)
+	return strings.Join(values, " ")
+}
+
+func (o driverOptions) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid storage driver option %q, must be driver=value", value)
+	}
+	o[parts[0]] = parts[1]
+	return nil
+}
+
 // NewMemoryStorage creates a memory storage with an optional backend storage option.
 func NewMemoryStorage() (*memory.InMemoryCache, error) {
+	drivers := map[string]bool{}
 	backendStorages := []storage.StorageDriver{}
 	for _, driver := range strings.Split(*storageDriver, ",") {
 		if driver == "" {
 			continue
 		}
+		drivers[driver] = true
 		storage, err := storage.New(driver)
 		if err != nil {
 			return nil, err
 		}
+		if *bufferCapacity > 0 {
+			storage, err = newBufferedStorage(driver, storage)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create buffer of storage driver %q: %v", driver, err)
+			}
+		}
+		storage, err = newFilteredStorage(storage, storageDriverIntervals[driver], storageDriverMetrics[driver])
+		if err != nil {
+			return nil, fmt.Errorf("invalid options of storage driver %q: %v", driver, err)
+		}
 		backendStorages = append(backendStorages, storage)
 		klog.V(1).Infof("Using backend storage type %q", driver)
 	}
+	for _, options := range []driverOptions{storageDriverIntervals, storageDriverMetrics} {
+		for driver := range options {
+			if !drivers[driver] {
+				return nil, fmt.Errorf("options set for storage driver %q which is not used", driver)
+			}
+		}
+	}
 	klog.V(1).Infof("Caching stats in memory for %v", *storageDuration)
 	return memory.New(*storageDuration, backendStorages), nil
 }
+
+// newBufferedStorage returns a buffer in front of the storage driver.
+func newBufferedStorage(driver string, backend storage.StorageDriver) (storage.StorageDriver, error) {
+	config := buffer.Config{
+		Capacity:         *bufferCapacity,
+		MaxDiskSize:      *bufferMaxDiskSize,
+		MaxRetryInterval: *bufferMaxRetryInterval,
+		MaxRetries:       *bufferMaxRetries,
+		BatchSize:        *bufferBatchSize,
+	}
+	if *bufferDir != "" {
+		if config.MaxDiskSize <= 0 {
+			return nil, fmt.Errorf("maximum disk size must be positive, got %d", config.MaxDiskSize)
+		}
+		config.Dir = filepath.Join(*bufferDir, driver)
+	}
+	s, err := buffer.New(driver, backend, config)
+	if err != nil {
+		return nil, err
+	}
+	bufferedStorages = append(bufferedStorages, s)
+	return s, nil
+}
+
+// filteredStorage pushes stats to the storage driver at most once per interval
+// per container, limited to the metric groups if any are set.
+type filteredStorage struct {
+	storage.StorageDriver
+	interval time.Duration
+	groups   []*samples.MetricGroup
+
+	lock sync.Mutex
+	// lastPushed holds timestamps of the last stats pushed by container name.
+	lastPushed map[string]time.Time
+	lastPruned time.Time
+}
+
+// newFilteredStorage returns the driver itself if no options are set.
+func newFilteredStorage(driver storage.StorageDriver, interval, metrics string) (storage.StorageDriver, error) {
+	if interval == "" && metrics == "" {
+		return driver, nil
+	}
+	s := &filteredStorage{
+		StorageDriver: driver,
+		lastPushed:    map[string]time.Time{},
+	}
+	if interval != "" {
+		var err error
+		s.interval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if metrics != "" {
+		for _, name := range strings.Split(metrics, ",") {
+			group := samples.FindMetricGroup(name)
+			if group == nil {
+				return nil, fmt.Errorf("unknown metric group %q, known groups are: %s", name, strings.Join(samples.MetricGroupNames(), ", "))
+			}
+			s.groups = append(s.groups, group)
+		}
+	}
+	return s, nil
+}
+
+func (s *filteredStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return s.StorageDriver.AddStats(cInfo, stats)
+	}
+	if s.interval > 0 && !s.due(cInfo.Name, stats.Timestamp) {
+		return nil
+	}
+	if len(s.groups) > 0 {
+		filtered := &info.ContainerStats{Timestamp: stats.Timestamp}
+		for _, group := range s.groups {
+			group.CopyStats(filtered, stats)
+		}
+		stats = filtered
+	}
+	return s.StorageDriver.AddStats(cInfo, stats)
+}
+
+// due returns true if stats of the container with the timestamp should be
+// pushed, and records them as pushed.
+func (s *filteredStorage) due(containerName string, timestamp time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if last, ok := s.lastPushed[containerName]; ok && timestamp.Sub(last) < s.interval {
+		return false
+	}
+	s.lastPushed[containerName] = timestamp
+
+	// Forget containers which were not pushed recently, most likely they were removed.
+	if timestamp.Sub(s.lastPruned) >= 10*s.interval {
+		for name, last := range s.lastPushed {
+			if timestamp.Sub(last) >= 2*s.interval {
+				delete(s.lastPushed, name)
+			}
+		}
+		s.lastPruned = timestamp
+	}
+	return true
+}
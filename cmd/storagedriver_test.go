@@ -0,0 +1,124 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorageDriver struct {
+	stats []*info.ContainerStats
+}
+
+func (d *fakeStorageDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	d.stats = append(d.stats, stats)
+	return nil
+}
+
+func (d *fakeStorageDriver) Close() error {
+	return nil
+}
+
+func TestDriverOptions(t *testing.T) {
+	options := driverOptions{}
+	assert.Nil(t, options.Set("kafka=cpu,memory"))
+	assert.Nil(t, options.Set("influxdb=1m"))
+	assert.Equal(t, "cpu,memory", options["kafka"])
+	assert.Equal(t, "influxdb=1m kafka=cpu,memory", options.String())
+	assert.NotNil(t, options.Set("kafka"))
+	assert.NotNil(t, options.Set("=1m"))
+}
+
+func TestFilteredStorageWithoutOptions(t *testing.T) {
+	driver := &fakeStorageDriver{}
+	s, err := newFilteredStorage(driver, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, driver, s)
+}
+
+func TestFilteredStorageInterval(t *testing.T) {
+	driver := &fakeStorageDriver{}
+	s, err := newFilteredStorage(driver, "10s", "")
+	assert.Nil(t, err)
+
+	start := time.Unix(1395066363, 0)
+	for _, container := range []string{"/a", "/b"} {
+		cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: container}}
+		for i := 0; i < 25; i += 5 {
+			stats := &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}
+			assert.Nil(t, s.AddStats(cInfo, stats))
+		}
+	}
+	// Stats at 0s, 10s and 20s of both containers.
+	assert.Len(t, driver.stats, 6)
+	assert.Equal(t, start.Add(10*time.Second), driver.stats[1].Timestamp)
+}
+
+func TestFilteredStorageForgetsContainers(t *testing.T) {
+	driver := &fakeStorageDriver{}
+	s, err := newFilteredStorage(driver, "1s", "")
+	assert.Nil(t, err)
+
+	start := time.Unix(1395066363, 0)
+	for i := 0; i < 20; i++ {
+		cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/removed"}}
+		if i > 0 {
+			cInfo.Name = "/running"
+		}
+		assert.Nil(t, s.AddStats(cInfo, &info.ContainerStats{Timestamp: start.Add(time.Duration(i) * time.Second)}))
+	}
+	assert.Len(t, driver.stats, 20)
+	assert.Equal(t, []string{"/running"}, lastPushedNames(s.(*filteredStorage)))
+}
+
+func lastPushedNames(s *filteredStorage) []string {
+	names := []string{}
+	for name := range s.lastPushed {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestFilteredStorageMetrics(t *testing.T) {
+	driver := &fakeStorageDriver{}
+	s, err := newFilteredStorage(driver, "", "cpu,memory")
+	assert.Nil(t, err)
+
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1395066363, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 100}},
+		Memory:    info.MemoryStats{Usage: 200},
+		Network:   info.NetworkStats{InterfaceStats: info.InterfaceStats{RxBytes: 300}},
+	}
+	assert.Nil(t, s.AddStats(&info.ContainerInfo{}, stats))
+	assert.Len(t, driver.stats, 1)
+	assert.Equal(t, stats.Timestamp, driver.stats[0].Timestamp)
+	assert.Equal(t, uint64(100), driver.stats[0].Cpu.Usage.Total)
+	assert.Equal(t, uint64(200), driver.stats[0].Memory.Usage)
+	assert.Zero(t, driver.stats[0].Network.RxBytes)
+	// Stats of the other drivers are not modified.
+	assert.Equal(t, uint64(300), stats.Network.RxBytes)
+}
+
+func TestFilteredStorageInvalidOptions(t *testing.T) {
+	_, err := newFilteredStorage(&fakeStorageDriver{}, "1x", "")
+	assert.NotNil(t, err)
+	_, err = newFilteredStorage(&fakeStorageDriver{}, "", "cpu,unknown")
+	assert.NotNil(t, err)
+}
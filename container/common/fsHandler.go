@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/fs"
 
 	"k8s.io/klog/v2"
@@ -35,6 +36,9 @@ type FsUsage struct {
 	BaseUsageBytes  uint64
 	TotalUsageBytes uint64
 	InodeUsage      uint64
+
+	// Usage of the writable layer of the container if the storage driver provides it.
+	WritableLayerBytes uint64
 }
 
 type realFsHandler struct {
@@ -56,6 +60,15 @@ const (
 
 const DefaultPeriod = time.Minute
 
+// DiskUsagePeriod returns the period of measuring disk usage, the interval of
+// disk metrics if it is configured.
+func DiskUsagePeriod() time.Duration {
+	if interval := container.ArgMetricIntervals.Interval(container.DiskUsageMetrics); interval > 0 {
+		return interval
+	}
+	return DefaultPeriod
+}
+
 var _ FsHandler = &realFsHandler{}
 
 func NewFsHandler(period time.Duration, rootfs, extraDir string, fsInfo fs.FsInfo) FsHandler {
@@ -94,6 +107,8 @@ func (fh *realFsHandler) update() error {
 		fh.usage.InodeUsage = rootUsage.Inodes
 		fh.usage.BaseUsageBytes = rootUsage.Bytes
 		fh.usage.TotalUsageBytes = rootUsage.Bytes
+		// Data written to a btrfs snapshot of an image is not shared with the image.
+		fh.usage.WritableLayerBytes = rootUsage.ExclusiveBytes
 	}
 	if fh.extraDir != "" && extraErr == nil {
 		fh.usage.TotalUsageBytes += extraUsage.Bytes
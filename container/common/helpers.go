@@ -102,16 +102,31 @@ func GetSpec(cgroupPaths map[string]string, machineInfoFactory info.MachineInfoF
 		if utils.FileExists(cpuRoot) {
 			spec.HasCpu = true
 			spec.Cpu.Limit = readUInt64(cpuRoot, "cpu.shares")
-			spec.Cpu.Period = readUInt64(cpuRoot, "cpu.cfs_period_us")
-			quota := readString(cpuRoot, "cpu.cfs_quota_us")
-
-			if quota != "" && quota != "-1" {
-				val, err := strconv.ParseUint(quota, 10, 64)
-				if err != nil {
-					klog.Errorf("GetSpec: Failed to parse CPUQuota from %q: %s", path.Join(cpuRoot, "cpu.cfs_quota_us"), err)
-				} else {
-					spec.Cpu.Quota = val
+			if cgroups.IsCgroup2UnifiedMode() {
+				cpuMax := readString(cpuRoot, "cpu.max")
+				if cpuMax != "" {
+					quota, period, err := parseCPUMax(cpuMax)
+					if err != nil {
+						klog.Errorf("GetSpec: Failed to parse CPU quota from %q: %s", path.Join(cpuRoot, "cpu.max"), err)
+					} else {
+						spec.Cpu.Quota = quota
+						spec.Cpu.Period = period
+					}
+				}
+				spec.Cpu.Burst = readUInt64(cpuRoot, "cpu.max.burst")
+			} else {
+				spec.Cpu.Period = readUInt64(cpuRoot, "cpu.cfs_period_us")
+				quota := readString(cpuRoot, "cpu.cfs_quota_us")
+
+				if quota != "" && quota != "-1" {
+					val, err := strconv.ParseUint(quota, 10, 64)
+					if err != nil {
+						klog.Errorf("GetSpec: Failed to parse CPUQuota from %q: %s", path.Join(cpuRoot, "cpu.cfs_quota_us"), err)
+					} else {
+						spec.Cpu.Quota = val
+					}
 				}
+				spec.Cpu.Burst = readUInt64(cpuRoot, "cpu.cfs_burst_us")
 			}
 		}
 	}
@@ -187,6 +202,26 @@ func GetSpec(cgroupPaths map[string]string, machineInfoFactory info.MachineInfoF
 	return spec, nil
 }
 
+// parseCPUMax parses quota and period in microseconds from cpu.max of cgroup v2,
+// e.g. "50000 100000" or "max 100000" when quota is not set.
+func parseCPUMax(cpuMax string) (quota uint64, period uint64, err error) {
+	fields := strings.Fields(cpuMax)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected format of cpu.max: %q", cpuMax)
+	}
+	period, err = strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if fields[0] != "max" {
+		quota, err = strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return quota, period, nil
+}
+
 func readString(dirpath string, file string) string {
 	cgroupFile := path.Join(dirpath, file)
 
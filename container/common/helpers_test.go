@@ -16,6 +16,8 @@ package common
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func BenchmarkListDirectories(b *testing.B) {
@@ -26,3 +28,21 @@ func BenchmarkListDirectories(b *testing.B) {
 		}
 	}
 }
+
+func TestParseCPUMax(t *testing.T) {
+	quota, period, err := parseCPUMax("50000 100000")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(50000), quota)
+	assert.Equal(t, uint64(100000), period)
+
+	// Quota is not set.
+	quota, period, err = parseCPUMax("max 100000")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), quota)
+	assert.Equal(t, uint64(100000), period)
+
+	_, _, err = parseCPUMax("max")
+	assert.NotNil(t, err)
+	_, _, err = parseCPUMax("50000 invalid")
+	assert.NotNil(t, err)
+}
@@ -35,6 +35,10 @@ const (
 	ContainerTypeCrio
 	ContainerTypeContainerd
 	ContainerTypeMesos
+	ContainerTypeCri
+	ContainerTypePodman
+	ContainerTypeSystemd
+	ContainerTypeNomad
 )
 
 // Interface for container operation handlers.
@@ -18,18 +18,27 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
 )
 
 type containerdClientMock struct {
 	cntrs     map[string]*containers.Container
 	returnErr error
+	// Namespace of containers, containers are found in any namespace if it is empty.
+	namespace string
+	mounts    map[string][]*types.Mount
 }
 
 func (c *containerdClientMock) LoadContainer(ctx context.Context, id string) (*containers.Container, error) {
 	if c.returnErr != nil {
 		return nil, c.returnErr
 	}
+	if namespace, _ := namespaces.Namespace(ctx); c.namespace != "" && namespace != c.namespace {
+		return nil, errdefs.ErrNotFound
+	}
 	cntr, ok := c.cntrs[id]
 	if !ok {
 		return nil, fmt.Errorf("unable to find container %q", id)
@@ -45,6 +54,14 @@ func (c *containerdClientMock) TaskPid(ctx context.Context, id string) (uint32,
 	return 2389, nil
 }
 
+func (c *containerdClientMock) SnapshotMounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error) {
+	mounts, ok := c.mounts[key]
+	if !ok {
+		return nil, errdefs.ErrNotFound
+	}
+	return mounts, nil
+}
+
 func mockcontainerdClient(cntrs map[string]*containers.Container, returnErr error) ContainerdClient {
 	return &containerdClientMock{
 		cntrs:     cntrs,
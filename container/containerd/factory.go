@@ -21,6 +21,9 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
 	"golang.org/x/net/context"
 	"k8s.io/klog/v2"
 
@@ -32,7 +35,7 @@ import (
 )
 
 var ArgContainerdEndpoint = flag.String("containerd", "/run/containerd/containerd.sock", "containerd endpoint")
-var ArgContainerdNamespace = flag.String("containerd-namespace", "k8s.io", "containerd namespace")
+var ArgContainerdNamespace = flag.String("containerd-namespace", "k8s.io", "Comma separated list of containerd namespaces in which containers are looked up, e.g. 'k8s.io,moby' (containers of 'moby' namespace are handled by docker factory when docker is available)")
 
 // The namespace under which containerd aliases are unique.
 const k8sContainerdNamespace = "containerd"
@@ -45,6 +48,8 @@ type containerdFactory struct {
 	machineInfoFactory info.MachineInfoFactory
 	client             ContainerdClient
 	version            string
+	// containerd namespaces in which containers are looked up.
+	namespaces []string
 	// Information about the mounted cgroup subsystems.
 	cgroupSubsystems libcontainer.CgroupSubsystems
 	// Information about mounted filesystems.
@@ -57,7 +62,7 @@ func (f *containerdFactory) String() string {
 }
 
 func (f *containerdFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
-	client, err := Client(*ArgContainerdEndpoint, *ArgContainerdNamespace)
+	client, err := Client(*ArgContainerdEndpoint, f.namespaces[0])
 	if err != nil {
 		return
 	}
@@ -65,6 +70,7 @@ func (f *containerdFactory) NewContainerHandler(name string, inHostNamespace boo
 	metadataEnvs := []string{}
 	return newContainerdContainerHandler(
 		client,
+		f.namespaces,
 		name,
 		f.machineInfoFactory,
 		f.fsInfo,
@@ -84,6 +90,33 @@ func ContainerNameToContainerdID(name string) string {
 	return id
 }
 
+// containerdNamespaces returns containerd namespaces configured by --containerd-namespace.
+func containerdNamespaces() []string {
+	result := []string{}
+	for _, namespace := range strings.Split(*ArgContainerdNamespace, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			result = append(result, namespace)
+		}
+	}
+	return result
+}
+
+// loadContainer looks up the container in the given containerd namespaces and returns it with
+// context of the namespace it was found in, which is used for further requests about the container.
+func loadContainer(ctx context.Context, client ContainerdClient, containerNamespaces []string, id string) (context.Context, *containers.Container, error) {
+	for _, namespace := range containerNamespaces {
+		namespaceCtx := namespaces.WithNamespace(ctx, namespace)
+		cntr, err := client.LoadContainer(namespaceCtx, id)
+		if err == nil {
+			return namespaceCtx, cntr, nil
+		}
+		if !errdefs.IsNotFound(err) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, fmt.Errorf("container %q not found in containerd namespaces %v", id, containerNamespaces)
+}
+
 // isContainerName returns true if the cgroup with associated name
 // corresponds to a containerd container.
 func isContainerName(name string) bool {
@@ -105,7 +138,7 @@ func (f *containerdFactory) CanHandleAndAccept(name string) (bool, bool, error)
 	// If container and task lookup in containerd fails then we assume
 	// that the container state is not known to containerd
 	ctx := context.Background()
-	_, err := f.client.LoadContainer(ctx, id)
+	_, _, err := loadContainer(ctx, f.client, f.namespaces, id)
 	if err != nil {
 		return false, false, fmt.Errorf("failed to load container: %v", err)
 	}
@@ -119,7 +152,11 @@ func (f *containerdFactory) DebugInfo() map[string][]string {
 
 // Register root container before running this function!
 func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
-	client, err := Client(*ArgContainerdEndpoint, *ArgContainerdNamespace)
+	containerNamespaces := containerdNamespaces()
+	if len(containerNamespaces) == 0 {
+		return fmt.Errorf("no containerd namespace configured")
+	}
+	client, err := Client(*ArgContainerdEndpoint, containerNamespaces[0])
 	if err != nil {
 		return fmt.Errorf("unable to create containerd client: %v", err)
 	}
@@ -134,13 +171,14 @@ func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics
 		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
 	}
 
-	klog.V(1).Infof("Registering containerd factory")
+	klog.V(1).Infof("Registering containerd factory for namespaces %v", containerNamespaces)
 	f := &containerdFactory{
 		cgroupSubsystems:   cgroupSubsystems,
 		client:             client,
 		fsInfo:             fsInfo,
 		machineInfoFactory: factory,
 		version:            containerdVersion,
+		namespaces:         containerNamespaces,
 		includedMetrics:    includedMetrics,
 	}
 
@@ -15,9 +15,11 @@
 package containerd
 
 import (
+	"context"
 	"testing"
 
 	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/typeurl"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/stretchr/testify/assert"
@@ -59,6 +61,7 @@ func TestCanHandleAndAccept(t *testing.T) {
 
 	f := &containerdFactory{
 		client:             mockcontainerdClient(testContainers, nil),
+		namespaces:         []string{"k8s.io"},
 		cgroupSubsystems:   containerlibcontainer.CgroupSubsystems{},
 		fsInfo:             nil,
 		machineInfoFactory: nil,
@@ -74,3 +77,28 @@ func TestCanHandleAndAccept(t *testing.T) {
 		as.Equal(b2, v)
 	}
 }
+
+func TestLoadContainerFromNamespaces(t *testing.T) {
+	testContainer := &containers.Container{ID: "40af7cdcbe507acad47a5a62025743ad3ddc6ab93b77b21363aa1c1d641047c9"}
+	client := &containerdClientMock{
+		cntrs:     map[string]*containers.Container{testContainer.ID: testContainer},
+		namespace: "moby",
+	}
+
+	ctx, cntr, err := loadContainer(context.Background(), client, []string{"k8s.io", "moby"}, testContainer.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, testContainer, cntr)
+	namespace, ok := namespaces.Namespace(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "moby", namespace)
+
+	_, _, err = loadContainer(context.Background(), client, []string{"k8s.io"}, testContainer.ID)
+	assert.NotNil(t, err)
+}
+
+func TestContainerdNamespaces(t *testing.T) {
+	defer func(namespace string) { *ArgContainerdNamespace = namespace }(*ArgContainerdNamespace)
+
+	*ArgContainerdNamespace = "k8s.io, moby,,custom"
+	assert.Equal(t, []string{"k8s.io", "moby", "custom"}, containerdNamespaces())
+}
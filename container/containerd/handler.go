@@ -21,17 +21,29 @@ import (
 	"strings"
 	"time"
 
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
 	"golang.org/x/net/context"
+	"k8s.io/klog/v2"
 
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/container/gvisor"
 	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 )
 
+const (
+	// Label with containerd namespace of the container added to its labels.
+	containerdNamespaceLabel = "io.containerd.namespace"
+	// Annotation with name of the image the container was created from set by CRI plugin,
+	// image of the container is the image ID then.
+	criImageNameAnnotation = "io.kubernetes.cri.image-name"
+)
+
 type containerdContainerHandler struct {
 	machineInfoFactory info.MachineInfoFactory
 	// Absolute path to the cgroup hierarchies of this container.
@@ -44,8 +56,15 @@ type containerdContainerHandler struct {
 	labels    map[string]string
 	// Image name used for this container.
 	image string
+	// Whether the container has its own network namespace.
+	ownNetwork bool
+	// Writable directory of the container's root filesystem snapshot, empty if it is unknown.
+	rootfsDir string
 	// Filesystem handler.
+	fsHandler       common.FsHandler
 	includedMetrics container.MetricSet
+	// Root of runsc state if the container runs in gVisor sandbox, empty otherwise.
+	runscRoot string
 
 	libcontainerHandler *containerlibcontainer.Handler
 }
@@ -55,6 +74,7 @@ var _ container.ContainerHandler = &containerdContainerHandler{}
 // newContainerdContainerHandler returns a new container.ContainerHandler
 func newContainerdContainerHandler(
 	client ContainerdClient,
+	containerNamespaces []string,
 	name string,
 	machineInfoFactory info.MachineInfoFactory,
 	fsInfo fs.FsInfo,
@@ -74,8 +94,7 @@ func newContainerdContainerHandler(
 
 	id := ContainerNameToContainerdID(name)
 	// We assume that if load fails then the container is not known to containerd.
-	ctx := context.Background()
-	cntr, err := client.LoadContainer(ctx, id)
+	ctx, cntr, err := loadContainer(context.Background(), client, containerNamespaces, id)
 	if err != nil {
 		return nil, err
 	}
@@ -121,18 +140,34 @@ func newContainerdContainerHandler(
 
 	libcontainerHandler := containerlibcontainer.NewHandler(cgroupManager, rootfs, int(taskPid), includedMetrics)
 
+	labels := make(map[string]string, len(cntr.Labels)+1)
+	for k, v := range cntr.Labels {
+		labels[k] = v
+	}
+	namespace, _ := namespaces.Namespace(ctx)
+	if namespace != "" {
+		labels[containerdNamespaceLabel] = namespace
+	}
+
 	handler := &containerdContainerHandler{
 		machineInfoFactory:  machineInfoFactory,
 		cgroupPaths:         cgroupPaths,
 		fsInfo:              fsInfo,
 		envs:                make(map[string]string),
-		labels:              cntr.Labels,
+		labels:              labels,
+		ownNetwork:          hasOwnNetworkNamespace(&spec),
 		includedMetrics:     includedMetrics,
 		reference:           containerReference,
 		libcontainerHandler: libcontainerHandler,
 	}
+	if gvisor.IsRuntime(cntr.Runtime.Name) {
+		handler.runscRoot = gvisor.ContainerdRoot(namespace)
+	}
 	// Add the name and bare ID as aliases of the container.
 	handler.image = cntr.Image
+	if imageName, ok := spec.Annotations[criImageNameAnnotation]; ok && imageName != "" {
+		handler.image = imageName
+	}
 	for _, envVar := range spec.Process.Env {
 		if envVar != "" {
 			splits := strings.SplitN(envVar, "=", 2)
@@ -142,9 +177,57 @@ func newContainerdContainerHandler(
 		}
 	}
 
+	if includedMetrics.Has(container.DiskUsageMetrics) && cntr.SnapshotKey != "" {
+		mounts, err := client.SnapshotMounts(ctx, cntr.Snapshotter, cntr.SnapshotKey)
+		if err != nil {
+			klog.V(4).Infof("Unable to get mounts of root filesystem of container %q: %v", id, err)
+		} else {
+			handler.rootfsDir = snapshotWritableDir(mounts)
+		}
+	}
+	if handler.rootfsDir != "" {
+		handler.fsHandler = common.NewFsHandler(common.DiskUsagePeriod(), handler.rootfsDir, "", fsInfo)
+	}
+
 	return handler, nil
 }
 
+// hasOwnNetworkNamespace returns true if a new network namespace is created for the container,
+// containers joining network namespace of another container (e.g. of pod sandbox) or of the host
+// don't have one.
+func hasOwnNetworkNamespace(spec *specs.Spec) bool {
+	if spec.Linux == nil {
+		return false
+	}
+	for _, namespace := range spec.Linux.Namespaces {
+		if namespace.Type == specs.NetworkNamespace {
+			return namespace.Path == ""
+		}
+	}
+	return false
+}
+
+// snapshotWritableDir returns directory to which writes to root filesystem of the container go,
+// i.e. upper directory of overlay mount or source of bind mount created by snapshotters which
+// don't use overlayfs (e.g. native or btrfs).
+func snapshotWritableDir(mounts []*types.Mount) string {
+	if len(mounts) != 1 {
+		return ""
+	}
+	mount := mounts[0]
+	switch mount.Type {
+	case "overlay":
+		for _, option := range mount.Options {
+			if strings.HasPrefix(option, "upperdir=") {
+				return strings.TrimPrefix(option, "upperdir=")
+			}
+		}
+	case "bind":
+		return mount.Source
+	}
+	return ""
+}
+
 func (h *containerdContainerHandler) ContainerReference() (info.ContainerReference, error) {
 	return h.reference, nil
 }
@@ -155,15 +238,18 @@ func (h *containerdContainerHandler) needNet() bool {
 	// label
 	if h.includedMetrics.Has(container.NetworkUsageMetrics) {
 		//TODO change it to exported cri-containerd constants
-		return h.labels["io.cri-containerd.kind"] == "sandbox"
+		if kind, ok := h.labels["io.cri-containerd.kind"]; ok {
+			return kind == "sandbox"
+		}
+		// Containers which are not managed by CRI plugin, e.g. of moby or custom namespaces.
+		return h.ownNetwork
 	}
 	return false
 }
 
 func (h *containerdContainerHandler) GetSpec() (info.ContainerSpec, error) {
-	// TODO: Since we dont collect disk usage stats for containerd, we set hasFilesystem
-	// to false. Revisit when we support disk usage stats for containerd
-	hasFilesystem := false
+	// Disk usage is collected only when the writable directory of root filesystem snapshot is known.
+	hasFilesystem := h.fsHandler != nil
 	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), hasFilesystem)
 	spec.Labels = h.labels
 	spec.Envs = h.envs
@@ -181,6 +267,39 @@ func (h *containerdContainerHandler) getFsStats(stats *info.ContainerStats) erro
 	if h.includedMetrics.Has(container.DiskIOMetrics) {
 		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
 	}
+
+	if h.fsHandler == nil {
+		return nil
+	}
+	deviceInfo, err := h.fsInfo.GetDirFsDevice(h.rootfsDir)
+	if err != nil {
+		return fmt.Errorf("unable to determine device info for dir: %v: %v", h.rootfsDir, err)
+	}
+	var (
+		limit  uint64
+		fsType string
+	)
+	// containerd does not impose any filesystem limits for containers. So use capacity as limit.
+	for _, fs := range mi.Filesystems {
+		if fs.Device == deviceInfo.Device {
+			limit = fs.Capacity
+			fsType = fs.Type
+			break
+		}
+	}
+	if fsType == "" {
+		return fmt.Errorf("unable to determine fs type for device: %v", deviceInfo.Device)
+	}
+	usage := h.fsHandler.Usage()
+	stats.Filesystem = append(stats.Filesystem, info.FsStats{
+		Device:             deviceInfo.Device,
+		Type:               fsType,
+		Limit:              limit,
+		BaseUsage:          usage.BaseUsageBytes,
+		Usage:              usage.TotalUsageBytes,
+		Inodes:             usage.InodeUsage,
+		WritableLayerUsage: usage.BaseUsageBytes,
+	})
 	return nil
 }
 
@@ -197,6 +316,17 @@ func (h *containerdContainerHandler) GetStats() (*info.ContainerStats, error) {
 		stats.Network = info.NetworkStats{}
 	}
 
+	// Host cgroup of containers running in gVisor sandbox does not account
+	// for the application, take its CPU and memory usage from runsc.
+	if h.runscRoot != "" {
+		runscStats, err := gvisor.GetStats(h.runscRoot, h.reference.Id)
+		if err != nil {
+			klog.V(4).Infof("Unable to get stats of gVisor container %q: %v", h.reference.Id, err)
+		} else {
+			gvisor.SetStats(stats, runscStats)
+		}
+	}
+
 	// Get filesystem stats.
 	err = h.getFsStats(stats)
 	return stats, err
@@ -231,9 +361,15 @@ func (h *containerdContainerHandler) Type() container.ContainerType {
 }
 
 func (h *containerdContainerHandler) Start() {
+	if h.fsHandler != nil {
+		h.fsHandler.Start()
+	}
 }
 
 func (h *containerdContainerHandler) Cleanup() {
+	if h.fsHandler != nil {
+		h.fsHandler.Stop()
+	}
 }
 
 func (h *containerdContainerHandler) GetContainerIPAddress() string {
@@ -18,6 +18,7 @@ package containerd
 import (
 	"testing"
 
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/containers"
 	"github.com/containerd/typeurl"
 	"github.com/google/cadvisor/container"
@@ -89,7 +90,7 @@ func TestHandler(t *testing.T) {
 			},
 		},
 	} {
-		handler, err := newContainerdContainerHandler(ts.client, ts.name, ts.machineInfoFactory, ts.fsInfo, ts.cgroupSubsystems, ts.inHostNamespace, ts.metadataEnvs, ts.includedMetrics)
+		handler, err := newContainerdContainerHandler(ts.client, []string{"k8s.io"}, ts.name, ts.machineInfoFactory, ts.fsInfo, ts.cgroupSubsystems, ts.inHostNamespace, ts.metadataEnvs, ts.includedMetrics)
 		if ts.hasErr {
 			as.NotNil(err)
 			if ts.errContains != "" {
@@ -103,3 +104,55 @@ func TestHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestHandlerOfCustomNamespace(t *testing.T) {
+	testContainer := &containers.Container{
+		ID:          "40af7cdcbe507acad47a5a62025743ad3ddc6ab93b77b21363aa1c1d641047c9",
+		Labels:      map[string]string{"app": "test"},
+		Image:       "sha256:4e42a7f2bd0b1d4a5e3f1d2c0c8e7e0a1b5e4f9c6d3a2b1c0d9e8f7a6b5c4d3e",
+		Snapshotter: "overlayfs",
+		SnapshotKey: "40af7cdcbe507acad47a5a62025743ad3ddc6ab93b77b21363aa1c1d641047c9",
+	}
+	spec := &specs.Spec{
+		Root:        &specs.Root{Path: "/test/"},
+		Process:     &specs.Process{},
+		Annotations: map[string]string{"io.kubernetes.cri.image-name": "docker.io/library/nginx:1.19"},
+		Linux: &specs.Linux{
+			Namespaces: []specs.LinuxNamespace{{Type: specs.PIDNamespace}, {Type: specs.NetworkNamespace}},
+		},
+	}
+	testContainer.Spec, _ = typeurl.MarshalAny(spec)
+	client := &containerdClientMock{
+		cntrs:     map[string]*containers.Container{testContainer.ID: testContainer},
+		namespace: "custom",
+		mounts: map[string][]*types.Mount{
+			testContainer.SnapshotKey: {{
+				Type:    "overlay",
+				Source:  "overlay",
+				Options: []string{"index=off", "workdir=/var/lib/containerd/snapshots/12/work", "upperdir=/var/lib/containerd/snapshots/12/fs", "lowerdir=/var/lib/containerd/snapshots/11/fs"},
+			}},
+		},
+	}
+
+	handler, err := newContainerdContainerHandler(client, []string{"k8s.io", "custom"}, "/custom/"+testContainer.ID, nil, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil, container.AllMetrics)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"app": "test", "io.containerd.namespace": "custom"}, handler.GetContainerLabels())
+	h := handler.(*containerdContainerHandler)
+	assert.Equal(t, "docker.io/library/nginx:1.19", h.image)
+	assert.Equal(t, "/var/lib/containerd/snapshots/12/fs", h.rootfsDir)
+	// Containers which are not managed by CRI plugin have network stats if they have own network namespace.
+	assert.True(t, h.needNet())
+}
+
+func TestSnapshotWritableDir(t *testing.T) {
+	assert.Equal(t, "/var/lib/containerd/snapshots/12/fs", snapshotWritableDir([]*types.Mount{
+		{Type: "overlay", Options: []string{"workdir=/var/lib/containerd/snapshots/12/work", "upperdir=/var/lib/containerd/snapshots/12/fs"}},
+	}))
+	assert.Equal(t, "/var/lib/containerd/snapshots/3", snapshotWritableDir([]*types.Mount{
+		{Type: "bind", Source: "/var/lib/containerd/snapshots/3", Options: []string{"rbind", "rw"}},
+	}))
+	// Overlay mount without upper directory is read only, e.g. of view snapshot.
+	assert.Equal(t, "", snapshotWritableDir([]*types.Mount{
+		{Type: "overlay", Options: []string{"lowerdir=/var/lib/containerd/snapshots/11/fs"}},
+	}))
+}
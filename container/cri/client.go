@@ -0,0 +1,302 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/pkg/dialer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	runtimeapiV1alpha2 "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	"k8s.io/klog/v2"
+)
+
+const (
+	maxBackoffDelay   = 3 * time.Second
+	baseBackoffDelay  = 100 * time.Millisecond
+	connectionTimeout = 2 * time.Second
+	requestTimeout    = 10 * time.Second
+)
+
+// ContainerInfo is container or pod sandbox as reported by the CRI runtime.
+type ContainerInfo struct {
+	Id string
+	// Name of the container within the pod, empty for pod sandboxes.
+	Name         string
+	PodName      string
+	PodNamespace string
+	PodUid       string
+	Image        string
+	Labels       map[string]string
+	Annotations  map[string]string
+	LogPath      string
+	IP           string
+	// Pid of the container process, zero when the runtime does not report it.
+	Pid int
+	// Sandbox is true for the infrastructure container of the pod.
+	Sandbox bool
+}
+
+type CriClient interface {
+	// Version returns name and version of the CRI runtime.
+	Version(ctx context.Context) (string, string, error)
+	// ContainerInfo returns container or pod sandbox with given id, an error
+	// satisfying IsNotFound is returned when the runtime does not know it.
+	ContainerInfo(ctx context.Context, id string) (*ContainerInfo, error)
+}
+
+type client struct {
+	runtimeClient runtimeapi.RuntimeServiceClient
+	// runtimeClientV1alpha2 is set instead of runtimeClient when the runtime
+	// does not serve runtime.v1 API yet.
+	runtimeClientV1alpha2 runtimeapiV1alpha2.RuntimeServiceClient
+}
+
+// NewClient creates a client of the CRI runtime service listening at endpoint,
+// runtime.v1 API is used when the runtime serves it, runtime.v1alpha2 otherwise.
+func NewClient(endpoint string) (CriClient, error) {
+	address := strings.TrimPrefix(endpoint, "unix://")
+	tryConn, err := net.DialTimeout("unix", address, connectionTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cri: cannot unix dial CRI runtime service: %v", err)
+	}
+	tryConn.Close()
+
+	connParams := grpc.ConnectParams{
+		Backoff: backoff.Config{
+			BaseDelay: baseBackoffDelay,
+			MaxDelay:  maxBackoffDelay,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, dialer.DialAddress(address),
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(dialer.ContextDialer),
+		grpc.WithBlock(),
+		grpc.WithConnectParams(connParams),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	runtimeClient := runtimeapi.NewRuntimeServiceClient(conn)
+	_, err = runtimeClient.Version(ctx, &runtimeapi.VersionRequest{})
+	if err == nil {
+		return &client{runtimeClient: runtimeClient}, nil
+	}
+	if status.Code(err) != codes.Unimplemented {
+		conn.Close()
+		return nil, err
+	}
+	klog.V(2).Infof("CRI runtime at %s does not serve runtime.v1 API, falling back to runtime.v1alpha2", endpoint)
+	return &client{runtimeClientV1alpha2: runtimeapiV1alpha2.NewRuntimeServiceClient(conn)}, nil
+}
+
+// IsNotFound returns true when err reports that the CRI runtime does not know the container.
+func IsNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+func (c *client) Version(ctx context.Context) (string, string, error) {
+	if c.runtimeClientV1alpha2 != nil {
+		response, err := c.runtimeClientV1alpha2.Version(ctx, &runtimeapiV1alpha2.VersionRequest{})
+		if err != nil {
+			return "", "", err
+		}
+		return response.RuntimeName, response.RuntimeVersion, nil
+	}
+	response, err := c.runtimeClient.Version(ctx, &runtimeapi.VersionRequest{})
+	if err != nil {
+		return "", "", err
+	}
+	return response.RuntimeName, response.RuntimeVersion, nil
+}
+
+func (c *client) ContainerInfo(ctx context.Context, id string) (*ContainerInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	// Cgroups of both containers and pod sandboxes are named after their id,
+	// the runtime is asked for the container first as there are more of them.
+	containerStatus, verboseInfo, err := c.containerStatus(ctx, id)
+	if err == nil && containerStatus != nil {
+		return containerInfoFromStatus(containerStatus, verboseInfo), nil
+	}
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	sandboxStatus, verboseInfo, err := c.podSandboxStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sandboxStatus == nil {
+		return nil, status.Errorf(codes.NotFound, "container %q not found", id)
+	}
+	return sandboxInfoFromStatus(sandboxStatus, verboseInfo), nil
+}
+
+func (c *client) containerStatus(ctx context.Context, id string) (*runtimeapi.ContainerStatus, map[string]string, error) {
+	if c.runtimeClientV1alpha2 != nil {
+		response, err := c.runtimeClientV1alpha2.ContainerStatus(ctx, &runtimeapiV1alpha2.ContainerStatusRequest{ContainerId: id, Verbose: true})
+		if err != nil {
+			return nil, nil, err
+		}
+		return containerStatusFromV1alpha2(response.Status), response.Info, nil
+	}
+	response, err := c.runtimeClient.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id, Verbose: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	return response.Status, response.Info, nil
+}
+
+func (c *client) podSandboxStatus(ctx context.Context, id string) (*runtimeapi.PodSandboxStatus, map[string]string, error) {
+	if c.runtimeClientV1alpha2 != nil {
+		response, err := c.runtimeClientV1alpha2.PodSandboxStatus(ctx, &runtimeapiV1alpha2.PodSandboxStatusRequest{PodSandboxId: id, Verbose: true})
+		if err != nil {
+			return nil, nil, err
+		}
+		return podSandboxStatusFromV1alpha2(response.Status), response.Info, nil
+	}
+	response, err := c.runtimeClient.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{PodSandboxId: id, Verbose: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	return response.Status, response.Info, nil
+}
+
+// containerStatusFromV1alpha2 converts the fields of v1alpha2 container status
+// used by cAdvisor, both API versions share the same messages.
+func containerStatusFromV1alpha2(s *runtimeapiV1alpha2.ContainerStatus) *runtimeapi.ContainerStatus {
+	if s == nil {
+		return nil
+	}
+	converted := &runtimeapi.ContainerStatus{
+		Id:          s.Id,
+		CreatedAt:   s.CreatedAt,
+		ImageRef:    s.ImageRef,
+		Labels:      s.Labels,
+		Annotations: s.Annotations,
+		LogPath:     s.LogPath,
+	}
+	if s.Metadata != nil {
+		converted.Metadata = &runtimeapi.ContainerMetadata{Name: s.Metadata.Name, Attempt: s.Metadata.Attempt}
+	}
+	if s.Image != nil {
+		converted.Image = &runtimeapi.ImageSpec{Image: s.Image.Image}
+	}
+	return converted
+}
+
+// podSandboxStatusFromV1alpha2 converts the fields of v1alpha2 pod sandbox
+// status used by cAdvisor.
+func podSandboxStatusFromV1alpha2(s *runtimeapiV1alpha2.PodSandboxStatus) *runtimeapi.PodSandboxStatus {
+	if s == nil {
+		return nil
+	}
+	converted := &runtimeapi.PodSandboxStatus{
+		Id:          s.Id,
+		CreatedAt:   s.CreatedAt,
+		Labels:      s.Labels,
+		Annotations: s.Annotations,
+	}
+	if s.Metadata != nil {
+		converted.Metadata = &runtimeapi.PodSandboxMetadata{
+			Name:      s.Metadata.Name,
+			Uid:       s.Metadata.Uid,
+			Namespace: s.Metadata.Namespace,
+			Attempt:   s.Metadata.Attempt,
+		}
+	}
+	if s.Network != nil {
+		converted.Network = &runtimeapi.PodSandboxNetworkStatus{Ip: s.Network.Ip}
+	}
+	return converted
+}
+
+// Labels set by kubelet on containers and pod sandboxes.
+const (
+	podNameLabel       = "io.kubernetes.pod.name"
+	podNamespaceLabel  = "io.kubernetes.pod.namespace"
+	podUidLabel        = "io.kubernetes.pod.uid"
+	containerNameLabel = "io.kubernetes.container.name"
+)
+
+func containerInfoFromStatus(s *runtimeapi.ContainerStatus, verboseInfo map[string]string) *ContainerInfo {
+	cInfo := &ContainerInfo{
+		Id:           s.Id,
+		PodName:      s.Labels[podNameLabel],
+		PodNamespace: s.Labels[podNamespaceLabel],
+		PodUid:       s.Labels[podUidLabel],
+		Labels:       s.Labels,
+		Annotations:  s.Annotations,
+		LogPath:      s.LogPath,
+		Pid:          pidFromVerboseInfo(verboseInfo),
+	}
+	if s.Metadata != nil {
+		cInfo.Name = s.Metadata.Name
+	}
+	if s.Image != nil {
+		cInfo.Image = s.Image.Image
+	}
+	if cInfo.Image == "" {
+		cInfo.Image = s.ImageRef
+	}
+	return cInfo
+}
+
+func sandboxInfoFromStatus(s *runtimeapi.PodSandboxStatus, verboseInfo map[string]string) *ContainerInfo {
+	cInfo := &ContainerInfo{
+		Id:          s.Id,
+		Labels:      s.Labels,
+		Annotations: s.Annotations,
+		Pid:         pidFromVerboseInfo(verboseInfo),
+		Sandbox:     true,
+	}
+	if s.Metadata != nil {
+		cInfo.PodName = s.Metadata.Name
+		cInfo.PodNamespace = s.Metadata.Namespace
+		cInfo.PodUid = s.Metadata.Uid
+	}
+	if s.Network != nil {
+		cInfo.IP = s.Network.Ip
+	}
+	return cInfo
+}
+
+// pidFromVerboseInfo returns pid from the "info" entry of verbose status,
+// both containerd and CRI-O report it as {"pid": <pid>, ...}.
+func pidFromVerboseInfo(verboseInfo map[string]string) int {
+	var runtimeInfo struct {
+		Pid int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(verboseInfo["info"]), &runtimeInfo); err != nil {
+		return 0
+	}
+	return runtimeInfo.Pid
+}
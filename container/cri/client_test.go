@@ -0,0 +1,128 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	runtimeapiV1alpha2 "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+type criClientMock struct {
+	containers map[string]*ContainerInfo
+	err        error
+}
+
+func (c *criClientMock) Version(ctx context.Context) (string, string, error) {
+	return "mock", "0.1", c.err
+}
+
+func (c *criClientMock) ContainerInfo(ctx context.Context, id string) (*ContainerInfo, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	cInfo, ok := c.containers[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no container with id %s", id)
+	}
+	return cInfo, nil
+}
+
+func mockCriClient(containers map[string]*ContainerInfo, err error) CriClient {
+	return &criClientMock{
+		containers: containers,
+		err:        err,
+	}
+}
+
+func TestPidFromVerboseInfo(t *testing.T) {
+	for _, tc := range []struct {
+		info        map[string]string
+		expectedPid int
+	}{
+		{map[string]string{"info": `{"sandboxID":"abc","pid":1234,"removing":false}`}, 1234},
+		{map[string]string{"info": "not json"}, 0},
+		{nil, 0},
+	} {
+		assert.Equal(t, tc.expectedPid, pidFromVerboseInfo(tc.info))
+	}
+}
+
+func TestContainerInfoFromStatus(t *testing.T) {
+	cInfo := containerInfoFromStatus(&runtimeapi.ContainerStatus{
+		Id:       "81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f",
+		Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"},
+		Image:    &runtimeapi.ImageSpec{Image: "docker.io/library/nginx:latest"},
+		Labels: map[string]string{
+			"io.kubernetes.pod.name":      "web",
+			"io.kubernetes.pod.namespace": "default",
+			"io.kubernetes.pod.uid":       "068e8fa0-9213-11e7-a01f-507b9d4141fa",
+		},
+		LogPath: "/var/log/pods/default_web_068e8fa0-9213-11e7-a01f-507b9d4141fa/nginx/0.log",
+	}, map[string]string{"info": `{"pid":42}`})
+	assert.Equal(t, "nginx", cInfo.Name)
+	assert.Equal(t, "web", cInfo.PodName)
+	assert.Equal(t, "default", cInfo.PodNamespace)
+	assert.Equal(t, "068e8fa0-9213-11e7-a01f-507b9d4141fa", cInfo.PodUid)
+	assert.Equal(t, "docker.io/library/nginx:latest", cInfo.Image)
+	assert.Equal(t, 42, cInfo.Pid)
+	assert.False(t, cInfo.Sandbox)
+
+	sInfo := sandboxInfoFromStatus(&runtimeapi.PodSandboxStatus{
+		Id:       "990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f81e5c2",
+		Metadata: &runtimeapi.PodSandboxMetadata{Name: "web", Namespace: "default", Uid: "068e8fa0-9213-11e7-a01f-507b9d4141fa"},
+		Network:  &runtimeapi.PodSandboxNetworkStatus{Ip: "10.0.0.5"},
+	}, nil)
+	assert.Equal(t, "web", sInfo.PodName)
+	assert.Equal(t, "default", sInfo.PodNamespace)
+	assert.Equal(t, "10.0.0.5", sInfo.IP)
+	assert.True(t, sInfo.Sandbox)
+}
+
+func TestStatusFromV1alpha2(t *testing.T) {
+	containerStatus := containerStatusFromV1alpha2(&runtimeapiV1alpha2.ContainerStatus{
+		Id:       "81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f",
+		Metadata: &runtimeapiV1alpha2.ContainerMetadata{Name: "nginx", Attempt: 1},
+		Image:    &runtimeapiV1alpha2.ImageSpec{Image: "docker.io/library/nginx:latest"},
+		Labels:   map[string]string{"io.kubernetes.pod.name": "web"},
+		LogPath:  "/var/log/pods/default_web_068e8fa0-9213-11e7-a01f-507b9d4141fa/nginx/0.log",
+	})
+	assert.Equal(t, &runtimeapi.ContainerStatus{
+		Id:       "81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f",
+		Metadata: &runtimeapi.ContainerMetadata{Name: "nginx", Attempt: 1},
+		Image:    &runtimeapi.ImageSpec{Image: "docker.io/library/nginx:latest"},
+		Labels:   map[string]string{"io.kubernetes.pod.name": "web"},
+		LogPath:  "/var/log/pods/default_web_068e8fa0-9213-11e7-a01f-507b9d4141fa/nginx/0.log",
+	}, containerStatus)
+
+	sandboxStatus := podSandboxStatusFromV1alpha2(&runtimeapiV1alpha2.PodSandboxStatus{
+		Id:       "990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f81e5c2",
+		Metadata: &runtimeapiV1alpha2.PodSandboxMetadata{Name: "web", Namespace: "default", Uid: "068e8fa0-9213-11e7-a01f-507b9d4141fa"},
+		Network:  &runtimeapiV1alpha2.PodSandboxNetworkStatus{Ip: "10.0.0.5"},
+	})
+	assert.Equal(t, &runtimeapi.PodSandboxStatus{
+		Id:       "990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f81e5c2",
+		Metadata: &runtimeapi.PodSandboxMetadata{Name: "web", Namespace: "default", Uid: "068e8fa0-9213-11e7-a01f-507b9d4141fa"},
+		Network:  &runtimeapi.PodSandboxNetworkStatus{Ip: "10.0.0.5"},
+	}, sandboxStatus)
+
+	assert.Nil(t, containerStatusFromV1alpha2(nil))
+	assert.Nil(t, podSandboxStatusFromV1alpha2(nil))
+}
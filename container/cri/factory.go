@@ -0,0 +1,148 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var ArgCriEndpoint = flag.String("cri", "", "CRI runtime service endpoint, e.g. unix:///run/containerd/containerd.sock; when set, containers known to the CRI runtime are handled by the generic CRI handler instead of the runtime specific ones")
+
+// The namespace under which CRI aliases are unique.
+const CriNamespace = "cri"
+
+// Regexp that identifies cgroups of CRI containers and pod sandboxes, e.g.
+// cri-containerd-<id>.scope, crio-<id>.scope, docker-<id>.scope or /kubepods/.../<id>.
+var criCgroupRegexp = regexp.MustCompile(`([a-f0-9]{64})(\.scope)?$`)
+
+type criFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	client CriClient
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+}
+
+func (f *criFactory) String() string {
+	return CriNamespace
+}
+
+func (f *criFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	return newCriContainerHandler(
+		f.client,
+		name,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// ContainerNameToCriId returns the id of CRI container or pod sandbox from
+// the full container name.
+func ContainerNameToCriId(name string) string {
+	id := path.Base(name)
+	if matches := criCgroupRegexp.FindStringSubmatch(id); matches != nil {
+		return matches[1]
+	}
+	return id
+}
+
+// isContainerName returns true if the cgroup with associated name
+// may belong to a CRI container or pod sandbox.
+func isContainerName(name string) bool {
+	// always ignore .mount cgroup and conmon of CRI-O even if associated with
+	// a container and delegate them to systemd and raw handlers.
+	base := path.Base(name)
+	if strings.HasSuffix(base, ".mount") || strings.Contains(base, "conmon") {
+		return false
+	}
+	return criCgroupRegexp.MatchString(base)
+}
+
+// CRI handles all containers and pod sandboxes known to the CRI runtime.
+func (f *criFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !isContainerName(name) {
+		return false, false, nil
+	}
+	_, err := f.client.ContainerInfo(context.Background(), ContainerNameToCriId(name))
+	if err != nil {
+		if IsNotFound(err) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("failed to get container %q from CRI runtime: %v", name, err)
+	}
+	return true, true, nil
+}
+
+func (f *criFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	if *ArgCriEndpoint == "" {
+		return fmt.Errorf("CRI runtime service endpoint is not set")
+	}
+	client, err := NewClient(*ArgCriEndpoint)
+	if err != nil {
+		return fmt.Errorf("unable to create CRI client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	runtimeName, runtimeVersion, err := client.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch CRI runtime version: %v", err)
+	}
+
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering CRI factory for %s %s", runtimeName, runtimeVersion)
+	f := &criFactory{
+		client:             client,
+		cgroupSubsystems:   cgroupSubsystems,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		includedMetrics:    includedMetrics,
+	}
+
+	// Containers known to the CRI runtime are also matched by the runtime
+	// specific factories, so CRI factory has to be asked first.
+	container.RegisterContainerHandlerFactoryFirst(f, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanHandleAndAccept(t *testing.T) {
+	as := assert.New(t)
+	f := &criFactory{
+		client: mockCriClient(map[string]*ContainerInfo{
+			"81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f": {},
+		}, nil),
+	}
+	for k, v := range map[string]bool{
+		"/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f":          true,
+		"/kubepods.slice/kubepods-pod068e8fa0.slice/cri-containerd-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f.scope": true,
+		"/kubepods/besteffort/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f":    true,
+		"/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f.mount":    false,
+		"/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-conmon-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f":   false,
+		"/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f81e5c2":          false,
+		"/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75":                   false,
+		"/system.slice/docker.service": false,
+	} {
+		b1, b2, err := f.CanHandleAndAccept(k)
+		as.Nil(err, k)
+		as.Equal(v, b1, k)
+		as.Equal(v, b2, k)
+	}
+
+	f.client = mockCriClient(nil, fmt.Errorf("connection refused"))
+	b1, b2, err := f.CanHandleAndAccept("/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f")
+	as.Error(err)
+	as.False(b1)
+	as.False(b2)
+}
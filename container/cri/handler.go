@@ -0,0 +1,255 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for containers discovered through the CRI runtime service.
+package cri
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+type criContainerHandler struct {
+	client CriClient
+	name   string
+	id     string
+
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	// Metadata associated with the container.
+	envs   map[string]string
+	labels map[string]string
+
+	// Image name used for this container.
+	image string
+
+	// Whether the container is pod sandbox, which owns network of the pod.
+	sandbox bool
+
+	// The IP address of the container
+	ipAddress string
+
+	includedMetrics container.MetricSet
+
+	reference info.ContainerReference
+
+	libcontainerHandler *containerlibcontainer.Handler
+	cgroupManager       cgroups.Manager
+	rootFs              string
+	pidKnown            bool
+}
+
+var _ container.ContainerHandler = &criContainerHandler{}
+
+// newCriContainerHandler returns a new container.ContainerHandler
+func newCriContainerHandler(
+	client CriClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	id := ContainerNameToCriId(name)
+	cInfo, err := client.ContainerInfo(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pod sandboxes have no name of their own, they are aliased by the pod name.
+	alias := cInfo.Name
+	if cInfo.Sandbox {
+		alias = cInfo.PodName
+	}
+	containerReference := info.ContainerReference{
+		Id:        id,
+		Name:      name,
+		Aliases:   []string{alias, id},
+		Namespace: CriNamespace,
+	}
+
+	handler := &criContainerHandler{
+		client:              client,
+		name:                name,
+		id:                  id,
+		machineInfoFactory:  machineInfoFactory,
+		cgroupPaths:         cgroupPaths,
+		envs:                make(map[string]string),
+		labels:              criLabels(cInfo),
+		image:               cInfo.Image,
+		sandbox:             cInfo.Sandbox,
+		ipAddress:           cInfo.IP,
+		includedMetrics:     includedMetrics,
+		reference:           containerReference,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, cInfo.Pid, includedMetrics),
+		cgroupManager:       cgroupManager,
+		rootFs:              rootFs,
+		// If pid is not reported, network stats can not be retrieved and
+		// GetStats() will ask the runtime for it again.
+		pidKnown: cInfo.Pid != 0,
+	}
+	return handler, nil
+}
+
+// criLabels returns labels of the container with pod and container metadata
+// reported by the CRI runtime, so they are set the same way for all runtimes.
+func criLabels(cInfo *ContainerInfo) map[string]string {
+	labels := make(map[string]string, len(cInfo.Labels)+4)
+	for k, v := range cInfo.Labels {
+		labels[k] = v
+	}
+	if cInfo.PodName != "" {
+		labels[podNameLabel] = cInfo.PodName
+	}
+	if cInfo.PodNamespace != "" {
+		labels[podNamespaceLabel] = cInfo.PodNamespace
+	}
+	if cInfo.PodUid != "" {
+		labels[podUidLabel] = cInfo.PodUid
+	}
+	if cInfo.Sandbox {
+		labels[containerNameLabel] = "POD"
+	} else if cInfo.Name != "" {
+		labels[containerNameLabel] = cInfo.Name
+	}
+
+	// ignore err and get zero as default, sandboxes have no restart count.
+	restartCount, _ := strconv.Atoi(cInfo.Annotations["io.kubernetes.container.restartCount"])
+	// Only adds restartcount label if it's greater than 0
+	if restartCount > 0 {
+		labels["restartcount"] = strconv.Itoa(restartCount)
+	}
+	return labels
+}
+
+func (h *criContainerHandler) Start() {}
+
+func (h *criContainerHandler) Cleanup() {}
+
+func (h *criContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+// needNet returns true for pod sandboxes only, other containers of the pod
+// share their network namespace.
+func (h *criContainerHandler) needNet() bool {
+	return h.sandbox && h.includedMetrics.Has(container.NetworkUsageMetrics)
+}
+
+func (h *criContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	// Usage of the root filesystem is not exposed by CRI in a runtime
+	// independent way, so only disk I/O is reported.
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), false)
+
+	spec.Labels = h.labels
+	spec.Envs = h.envs
+	spec.Image = h.image
+
+	return spec, err
+}
+
+func (h *criContainerHandler) getLibcontainerHandler() *containerlibcontainer.Handler {
+	if h.pidKnown {
+		return h.libcontainerHandler
+	}
+
+	cInfo, err := h.client.ContainerInfo(context.Background(), h.id)
+	if err != nil || cInfo.Pid == 0 {
+		return h.libcontainerHandler
+	}
+
+	h.pidKnown = true
+	h.libcontainerHandler = containerlibcontainer.NewHandler(h.cgroupManager, h.rootFs, cInfo.Pid, h.includedMetrics)
+
+	return h.libcontainerHandler
+}
+
+func (h *criContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.getLibcontainerHandler().GetStats()
+	if err != nil {
+		return stats, err
+	}
+	// Clean up stats for containers that don't have their own network, they
+	// are reported by the pod sandbox.
+	if !h.needNet() {
+		stats.Network = info.NetworkStats{}
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *criContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	// No-op for CRI driver.
+	return []info.ContainerReference{}, nil
+}
+
+func (h *criContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *criContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *criContainerHandler) GetContainerIPAddress() string {
+	return h.ipAddress
+}
+
+func (h *criContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *criContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *criContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeCri
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"fmt"
+	"testing"
+
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testContainerId = "81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f"
+	testSandboxId   = "990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f81e5c2"
+)
+
+func TestHandler(t *testing.T) {
+	as := assert.New(t)
+	client := mockCriClient(map[string]*ContainerInfo{
+		testContainerId: {
+			Id:           testContainerId,
+			Name:         "nginx",
+			PodName:      "web",
+			PodNamespace: "default",
+			PodUid:       "068e8fa0-9213-11e7-a01f-507b9d4141fa",
+			Image:        "docker.io/library/nginx:latest",
+			Labels:       map[string]string{"app": "web"},
+			Annotations:  map[string]string{"io.kubernetes.container.restartCount": "2"},
+		},
+		testSandboxId: {
+			Id:           testSandboxId,
+			PodName:      "web",
+			PodNamespace: "default",
+			PodUid:       "068e8fa0-9213-11e7-a01f-507b9d4141fa",
+			IP:           "10.0.0.5",
+			Sandbox:      true,
+		},
+	}, nil)
+
+	for _, ts := range []struct {
+		client         CriClient
+		name           string
+		hasErr         bool
+		errContains    string
+		checkReference *info.ContainerReference
+		checkLabels    map[string]string
+	}{
+		{
+			client:      mockCriClient(nil, fmt.Errorf("no client returned")),
+			name:        "/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-" + testContainerId,
+			hasErr:      true,
+			errContains: "no client returned",
+		},
+		{
+			client:      mockCriClient(nil, nil),
+			name:        "/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/crio-" + testContainerId,
+			hasErr:      true,
+			errContains: "no container with id " + testContainerId,
+		},
+		{
+			client: client,
+			name:   "/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/cri-containerd-" + testContainerId + ".scope",
+			checkReference: &info.ContainerReference{
+				Id:        testContainerId,
+				Name:      "/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/cri-containerd-" + testContainerId + ".scope",
+				Aliases:   []string{"nginx", testContainerId},
+				Namespace: CriNamespace,
+			},
+			checkLabels: map[string]string{
+				"app":                          "web",
+				"io.kubernetes.pod.name":       "web",
+				"io.kubernetes.pod.namespace":  "default",
+				"io.kubernetes.pod.uid":        "068e8fa0-9213-11e7-a01f-507b9d4141fa",
+				"io.kubernetes.container.name": "nginx",
+				"restartcount":                 "2",
+			},
+		},
+		{
+			client: client,
+			name:   "/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/" + testSandboxId,
+			checkReference: &info.ContainerReference{
+				Id:        testSandboxId,
+				Name:      "/kubepods/pod068e8fa0-9213-11e7-a01f-507b9d4141fa/" + testSandboxId,
+				Aliases:   []string{"web", testSandboxId},
+				Namespace: CriNamespace,
+			},
+			checkLabels: map[string]string{
+				"io.kubernetes.pod.name":       "web",
+				"io.kubernetes.pod.namespace":  "default",
+				"io.kubernetes.pod.uid":        "068e8fa0-9213-11e7-a01f-507b9d4141fa",
+				"io.kubernetes.container.name": "POD",
+			},
+		},
+	} {
+		handler, err := newCriContainerHandler(ts.client, ts.name, nil, &containerlibcontainer.CgroupSubsystems{}, false, nil)
+		if ts.hasErr {
+			as.NotNil(err)
+			if ts.errContains != "" {
+				as.Contains(err.Error(), ts.errContains)
+			}
+			continue
+		}
+		as.Nil(err)
+		cr, err := handler.ContainerReference()
+		as.Nil(err)
+		as.Equal(*ts.checkReference, cr)
+		as.Equal(ts.checkLabels, handler.GetContainerLabels())
+	}
+}
@@ -104,6 +104,7 @@ const (
 	overlay2StorageDriver     storageDriver = "overlay2"
 	zfsStorageDriver          storageDriver = "zfs"
 	vfsStorageDriver          storageDriver = "vfs"
+	btrfsStorageDriver        storageDriver = "btrfs"
 )
 
 type dockerFactory struct {
@@ -26,6 +26,7 @@ import (
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/common"
 	dockerutil "github.com/google/cadvisor/container/docker/utils"
+	"github.com/google/cadvisor/container/gvisor"
 	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/devicemapper"
 	"github.com/google/cadvisor/fs"
@@ -44,6 +45,9 @@ const (
 	overlayRWLayer  = "upper"
 	overlay2RWLayer = "diff"
 
+	// Snapshots of images used as root filesystems of containers by btrfs storage driver are stored here.
+	btrfsSubvolumesDir = "subvolumes"
+
 	// Path to the directory where docker stores log files if the json logging driver is enabled.
 	pathToContainersDir = "containers"
 )
@@ -88,6 +92,9 @@ type dockerContainerHandler struct {
 	// zfsParent is the parent for docker zfs
 	zfsParent string
 
+	// Root of runsc state if the container runs in gVisor sandbox, empty otherwise.
+	runscRoot string
+
 	// Reference to the container
 	reference info.ContainerReference
 
@@ -172,6 +179,8 @@ func newDockerContainerHandler(
 		rootfsStorageDir = path.Join(storageDir, string(storageDriver), rwLayerID, overlay2RWLayer)
 	case vfsStorageDriver:
 		rootfsStorageDir = path.Join(storageDir)
+	case btrfsStorageDriver:
+		rootfsStorageDir = path.Join(storageDir, string(storageDriver), btrfsSubvolumesDir, rwLayerID)
 	case zfsStorageDriver:
 		status, err := Status()
 		if err != nil {
@@ -217,6 +226,9 @@ func newDockerContainerHandler(
 	}
 	handler.image = ctnr.Config.Image
 	handler.networkMode = ctnr.HostConfig.NetworkMode
+	if gvisor.IsRuntime(ctnr.HostConfig.Runtime) {
+		handler.runscRoot = gvisor.DockerRoot()
+	}
 	// Only adds restartcount label if it's greater than 0
 	if ctnr.RestartCount > 0 {
 		handler.labels["restartcount"] = strconv.Itoa(ctnr.RestartCount)
@@ -239,13 +251,22 @@ func newDockerContainerHandler(
 	handler.ipAddress = ipAddress
 
 	if includedMetrics.Has(container.DiskUsageMetrics) {
-		handler.fsHandler = &dockerFsHandler{
-			fsHandler:       common.NewFsHandler(common.DefaultPeriod, rootfsStorageDir, otherStorageDir, fsInfo),
+		fsHandler := &dockerFsHandler{
+			fsHandler:       common.NewFsHandler(common.DiskUsagePeriod(), rootfsStorageDir, otherStorageDir, fsInfo),
 			thinPoolWatcher: thinPoolWatcher,
 			zfsWatcher:      zfsWatcher,
 			deviceID:        ctnr.GraphDriver.Data["DeviceId"],
 			zfsFilesystem:   zfsFilesystem,
 		}
+		if storageDriver == overlay2StorageDriver {
+			layer, err := newOverlay2Layer(storageDir, rwLayerID)
+			if err != nil {
+				klog.V(4).Infof("Usage of writable layer of container %q will not be available: %v", id, err)
+			} else {
+				fsHandler.workDirHandler = common.NewFsHandler(common.DiskUsagePeriod(), layer.workDir, "", fsInfo)
+			}
+		}
+		handler.fsHandler = fsHandler
 	}
 
 	// split env vars to get metadata map.
@@ -282,16 +303,25 @@ type dockerFsHandler struct {
 	zfsWatcher *zfs.ZfsWatcher
 	// zfsFilesystem is the docker zfs filesystem
 	zfsFilesystem string
+	// workDirHandler tracks usage of overlayfs work directory of the writable layer on overlay2,
+	// usage of the upper directory is the base usage tracked by fsHandler.
+	workDirHandler common.FsHandler
 }
 
 var _ common.FsHandler = &dockerFsHandler{}
 
 func (h *dockerFsHandler) Start() {
 	h.fsHandler.Start()
+	if h.workDirHandler != nil {
+		h.workDirHandler.Start()
+	}
 }
 
 func (h *dockerFsHandler) Stop() {
 	h.fsHandler.Stop()
+	if h.workDirHandler != nil {
+		h.workDirHandler.Stop()
+	}
 }
 
 func (h *dockerFsHandler) Usage() common.FsUsage {
@@ -324,6 +354,10 @@ func (h *dockerFsHandler) Usage() common.FsUsage {
 			usage.TotalUsageBytes += zfsUsage
 		}
 	}
+
+	if h.workDirHandler != nil {
+		usage.WritableLayerBytes = usage.BaseUsageBytes + h.workDirHandler.Usage().BaseUsageBytes
+	}
 	return usage
 }
 
@@ -381,7 +415,7 @@ func (h *dockerContainerHandler) getFsStats(stats *info.ContainerStats) error {
 		// Device has to be the pool name to correlate with the device name as
 		// set in the machine info filesystems.
 		device = h.poolName
-	case aufsStorageDriver, overlayStorageDriver, overlay2StorageDriver, vfsStorageDriver:
+	case aufsStorageDriver, overlayStorageDriver, overlay2StorageDriver, vfsStorageDriver, btrfsStorageDriver:
 		deviceInfo, err := h.fsInfo.GetDirFsDevice(h.rootfsStorageDir)
 		if err != nil {
 			return fmt.Errorf("unable to determine device info for dir: %v: %v", h.rootfsStorageDir, err)
@@ -410,6 +444,7 @@ func (h *dockerContainerHandler) getFsStats(stats *info.ContainerStats) error {
 	fsStat := info.FsStats{Device: device, Type: fsType, Limit: limit}
 	usage := h.fsHandler.Usage()
 	fsStat.BaseUsage = usage.BaseUsageBytes
+	fsStat.WritableLayerUsage = usage.WritableLayerBytes
 	fsStat.Usage = usage.TotalUsageBytes
 	fsStat.Inodes = usage.InodeUsage
 
@@ -432,6 +467,17 @@ func (h *dockerContainerHandler) GetStats() (*info.ContainerStats, error) {
 		stats.Network = info.NetworkStats{}
 	}
 
+	// Host cgroup of containers running in gVisor sandbox does not account
+	// for the application, take its CPU and memory usage from runsc.
+	if h.runscRoot != "" {
+		runscStats, err := gvisor.GetStats(h.runscRoot, h.reference.Id)
+		if err != nil {
+			klog.V(4).Infof("Unable to get stats of gVisor container %q: %v", h.reference.Id, err)
+		} else {
+			gvisor.SetStats(stats, runscStats)
+		}
+	}
+
 	// Get filesystem stats.
 	err = h.getFsStats(stats)
 	if err != nil {
@@ -0,0 +1,48 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// Work directory of overlayfs, used to prepare files before they are moved to the upper directory.
+const overlay2WorkDir = "work"
+
+// overlay2Layer is the writable layer of a container stored by overlay2 storage driver in
+// <storage dir>/overlay2/<layer id>. The layer consists of overlayfs upper directory ("diff")
+// and work directory ("work"). Image layers below it are listed in "lower" file of the layer
+// and are shared between containers, volumes are stored outside of the storage driver, so
+// neither of them is a part of the writable layer.
+type overlay2Layer struct {
+	upperDir string
+	workDir  string
+}
+
+func newOverlay2Layer(storageDir string, rwLayerID string) (*overlay2Layer, error) {
+	layerDir := path.Join(storageDir, string(overlay2StorageDriver), rwLayerID)
+	layer := &overlay2Layer{
+		upperDir: path.Join(layerDir, overlay2RWLayer),
+		workDir:  path.Join(layerDir, overlay2WorkDir),
+	}
+	for _, dir := range []string{layer.upperDir, layer.workDir} {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("unexpected layout of overlay2 layer %q: %v", layerDir, err)
+		}
+	}
+	return layer, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/cadvisor/container/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeFsHandler struct {
+	usage common.FsUsage
+}
+
+func (h *fakeFsHandler) Start()                {}
+func (h *fakeFsHandler) Stop()                 {}
+func (h *fakeFsHandler) Usage() common.FsUsage { return h.usage }
+
+func TestNewOverlay2Layer(t *testing.T) {
+	storageDir, err := ioutil.TempDir("", "docker")
+	assert.Nil(t, err)
+	defer os.RemoveAll(storageDir)
+
+	layerDir := path.Join(storageDir, "overlay2", "f3b0d3a9")
+	assert.Nil(t, os.MkdirAll(path.Join(layerDir, "diff"), 0755))
+
+	// Work directory is missing.
+	_, err = newOverlay2Layer(storageDir, "f3b0d3a9")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, os.MkdirAll(path.Join(layerDir, "work"), 0755))
+	layer, err := newOverlay2Layer(storageDir, "f3b0d3a9")
+	assert.Nil(t, err)
+	assert.Equal(t, &overlay2Layer{upperDir: path.Join(layerDir, "diff"), workDir: path.Join(layerDir, "work")}, layer)
+}
+
+func TestDockerFsHandlerWritableLayerUsage(t *testing.T) {
+	handler := &dockerFsHandler{
+		fsHandler: &fakeFsHandler{usage: common.FsUsage{BaseUsageBytes: 4096, TotalUsageBytes: 12288, InodeUsage: 3}},
+	}
+	assert.Equal(t, uint64(0), handler.Usage().WritableLayerBytes)
+
+	handler.workDirHandler = &fakeFsHandler{usage: common.FsUsage{BaseUsageBytes: 1024, TotalUsageBytes: 1024}}
+	usage := handler.Usage()
+	assert.Equal(t, uint64(5120), usage.WritableLayerBytes)
+	// Usage of the work directory is reported only as a part of the writable layer.
+	assert.Equal(t, uint64(12288), usage.TotalUsageBytes)
+}
@@ -58,11 +58,18 @@ const (
 	AcceleratorUsageMetrics        MetricKind = "accelerator"
 	AppMetrics                     MetricKind = "app"
 	ProcessMetrics                 MetricKind = "process"
+	ProcessListMetrics             MetricKind = "process_list"
 	HugetlbUsageMetrics            MetricKind = "hugetlb"
 	PerfMetrics                    MetricKind = "perf_event"
 	ReferencedMemoryMetrics        MetricKind = "referenced_memory"
 	CPUTopologyMetrics             MetricKind = "cpu_topology"
 	ResctrlMetrics                 MetricKind = "resctrl"
+	GPUEngineMetrics               MetricKind = "gpu_engine"
+	PressureMetrics                MetricKind = "pressure"
+	OOMMetrics                     MetricKind = "oom_event"
+	ConntrackMetrics               MetricKind = "conntrack"
+	CgroupNetworkMetrics           MetricKind = "cgroup_network"
+	BPFSchedMetrics                MetricKind = "bpf_sched"
 )
 
 // AllMetrics represents all kinds of metrics that cAdvisor supported.
@@ -81,12 +88,19 @@ var AllMetrics = MetricSet{
 	NetworkAdvancedTcpUsageMetrics: struct{}{},
 	NetworkUdpUsageMetrics:         struct{}{},
 	ProcessMetrics:                 struct{}{},
+	ProcessListMetrics:             struct{}{},
 	AppMetrics:                     struct{}{},
 	HugetlbUsageMetrics:            struct{}{},
 	PerfMetrics:                    struct{}{},
 	ReferencedMemoryMetrics:        struct{}{},
 	CPUTopologyMetrics:             struct{}{},
 	ResctrlMetrics:                 struct{}{},
+	GPUEngineMetrics:               struct{}{},
+	PressureMetrics:                struct{}{},
+	OOMMetrics:                     struct{}{},
+	ConntrackMetrics:               struct{}{},
+	CgroupNetworkMetrics:           struct{}{},
+	BPFSchedMetrics:                struct{}{},
 }
 
 func (mk MetricKind) String() string {
@@ -187,6 +201,17 @@ func RegisterContainerHandlerFactory(factory ContainerHandlerFactory, watchTypes
 	}
 }
 
+// Register a ContainerHandlerFactory ahead of all already registered factories, it is used by
+// factories which accept containers matched by more specific factories too.
+func RegisterContainerHandlerFactoryFirst(factory ContainerHandlerFactory, watchTypes []watcher.ContainerWatchSource) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	for _, watchType := range watchTypes {
+		factories[watchType] = append([]ContainerHandlerFactory{factory}, factories[watchType]...)
+	}
+}
+
 // Returns whether there are any container handler factories registered.
 func HasFactories() bool {
 	factoriesLock.Lock()
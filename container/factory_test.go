@@ -112,6 +112,41 @@ func TestNewContainerHandler_SecondMatches(t *testing.T) {
 	}
 }
 
+func TestNewContainerHandler_RegisteredFirstMatches(t *testing.T) {
+	container.ClearContainerHandlerFactories()
+
+	// Register one always yes factory and another one ahead of it.
+	allwaysYes := &mockContainerHandlerFactory{
+		Name:           "yes",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}
+	container.RegisterContainerHandlerFactory(allwaysYes, []watcher.ContainerWatchSource{watcher.Raw})
+	first := &mockContainerHandlerFactory{
+		Name:           "first",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}
+	container.RegisterContainerHandlerFactoryFirst(first, []watcher.ContainerWatchSource{watcher.Raw})
+
+	// The first factory should be asked to create the ContainerHandler.
+	mockContainer, err := mockFactory.NewContainerHandler(testContainerName, true)
+	if err != nil {
+		t.Error(err)
+	}
+	first.On("NewContainerHandler", testContainerName).Return(mockContainer, nil)
+
+	cont, _, err := container.NewContainerHandler(testContainerName, watcher.Raw, true)
+	if err != nil {
+		t.Error(err)
+	}
+	if cont == nil {
+		t.Error("Expected container to not be nil")
+	}
+	first.AssertExpectations(t)
+	allwaysYes.AssertNotCalled(t, "NewContainerHandler", testContainerName)
+}
+
 func TestNewContainerHandler_NoneMatch(t *testing.T) {
 	container.ClearContainerHandlerFactories()
 
@@ -0,0 +1,140 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gvisor collects stats of containers running in gVisor sandboxes.
+//
+// Processes of such containers run in the user space kernel (the sentry) of
+// the sandbox, so the host cgroup of the container accounts for the sandbox
+// processes only and does not reflect CPU and memory used by the application.
+// The stats are taken from `runsc events --stats` instead.
+package gvisor
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+var (
+	runscBinary = flag.String("runsc_binary", "runsc", "Path to runsc binary used to collect stats of containers running in gVisor sandboxes")
+	runscRoot   = flag.String("runsc_root", "", "Root directory of runsc state, by default it is derived from the runtime of the container (/run/containerd/runsc/<namespace> for containerd, /var/run/docker/runtime-runsc/moby for docker)")
+)
+
+const (
+	// Root of runsc state of containers created by containerd runsc shim,
+	// containers of each containerd namespace have their own root.
+	containerdRunscRoot = "/run/containerd/runsc"
+	// Root of runsc state of containers created by docker with runsc runtime.
+	dockerRunscRoot = "/var/run/docker/runtime-runsc/moby"
+)
+
+// IsRuntime returns true if the runtime of the container is runsc, e.g.
+// "io.containerd.runsc.v1" for containerd or "runsc" for docker.
+func IsRuntime(runtime string) bool {
+	return strings.Contains(runtime, "runsc")
+}
+
+// ContainerdRoot returns root directory of runsc state of containerd containers in namespace.
+func ContainerdRoot(namespace string) string {
+	if *runscRoot != "" {
+		return *runscRoot
+	}
+	return containerdRunscRoot + "/" + namespace
+}
+
+// DockerRoot returns root directory of runsc state of docker containers.
+func DockerRoot() string {
+	if *runscRoot != "" {
+		return *runscRoot
+	}
+	return dockerRunscRoot
+}
+
+// event is output of `runsc events --stats`, it follows format of `runc events`.
+type event struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	Data *Stats `json:"data,omitempty"`
+}
+
+// Stats of the container as reported by runsc.
+type Stats struct {
+	Cpu struct {
+		Usage struct {
+			Total  uint64   `json:"total,omitempty"`
+			Percpu []uint64 `json:"percpu,omitempty"`
+			Kernel uint64   `json:"kernel"`
+			User   uint64   `json:"user"`
+		} `json:"usage,omitempty"`
+	} `json:"cpu"`
+	Memory struct {
+		Cache uint64 `json:"cache,omitempty"`
+		Usage struct {
+			Usage uint64 `json:"usage,omitempty"`
+		} `json:"usage,omitempty"`
+		// Usage broken down by kind, e.g. "anonymous", "page-cache" or "mapped".
+		Raw map[string]uint64 `json:"raw,omitempty"`
+	} `json:"memory"`
+	Pids struct {
+		Current uint64 `json:"current,omitempty"`
+	} `json:"pids"`
+}
+
+// GetStats returns stats of the container with id from runsc state in root.
+func GetStats(root, id string) (*Stats, error) {
+	out, err := exec.Command(*runscBinary, "--root", root, "events", "--stats", id).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats of gVisor container %q: %v", id, err)
+	}
+	return parseStats(out)
+}
+
+func parseStats(out []byte) (*Stats, error) {
+	e := event{}
+	if err := json.Unmarshal(out, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse runsc events output %q: %v", string(out), err)
+	}
+	if e.Type != "stats" || e.Data == nil {
+		return nil, fmt.Errorf("unexpected runsc event %q of container %q", e.Type, e.Id)
+	}
+	return e.Data, nil
+}
+
+// SetStats replaces CPU, memory and process stats of the host cgroup of the container
+// with stats of the application running in the sandbox.
+func SetStats(stats *info.ContainerStats, runscStats *Stats) {
+	cpu := runscStats.Cpu.Usage
+	stats.Cpu.Usage.Total = cpu.Total
+	stats.Cpu.Usage.User = cpu.User
+	stats.Cpu.Usage.System = cpu.Kernel
+	if len(cpu.Percpu) > 0 {
+		stats.Cpu.Usage.PerCpu = cpu.Percpu
+	}
+
+	memory := runscStats.Memory
+	stats.Memory.Usage = memory.Usage.Usage
+	stats.Memory.Cache = memory.Cache
+	stats.Memory.RSS = memory.Raw["anonymous"]
+	stats.Memory.MappedFile = memory.Raw["mapped"]
+	// Sentry does not report inactive page cache, so whole usage is the working set.
+	stats.Memory.WorkingSet = memory.Usage.Usage
+
+	if runscStats.Pids.Current > 0 {
+		stats.Processes.ProcessCount = runscStats.Pids.Current
+	}
+}
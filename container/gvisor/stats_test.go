@@ -0,0 +1,65 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gvisor
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+const runscEvent = `{"type":"stats","id":"81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f","data":{"cpu":{"usage":{"total":2500000000,"percpu":[1500000000,1000000000],"kernel":500000000,"user":2000000000}},"memory":{"cache":4096,"usage":{"usage":10485760},"raw":{"anonymous":8388608,"mapped":1048576,"page-cache":4096}},"pids":{"current":3}}}`
+
+func TestParseStats(t *testing.T) {
+	stats, err := parseStats([]byte(runscEvent))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2500000000), stats.Cpu.Usage.Total)
+	assert.Equal(t, []uint64{1500000000, 1000000000}, stats.Cpu.Usage.Percpu)
+	assert.Equal(t, uint64(10485760), stats.Memory.Usage.Usage)
+	assert.Equal(t, uint64(3), stats.Pids.Current)
+
+	_, err = parseStats([]byte(`{"type":"oom","id":"81e5c2990803"}`))
+	assert.Error(t, err)
+	_, err = parseStats([]byte("container not found"))
+	assert.Error(t, err)
+}
+
+func TestSetStats(t *testing.T) {
+	runscStats, err := parseStats([]byte(runscEvent))
+	assert.NoError(t, err)
+
+	// Stats of the host cgroup, which contains the sandbox processes only.
+	stats := &info.ContainerStats{}
+	stats.Cpu.Usage.Total = 1000
+	stats.Memory.Usage = 2048
+	stats.Processes.ProcessCount = 1
+	stats.Network.Interfaces = []info.InterfaceStats{{Name: "eth0", RxBytes: 100}}
+
+	SetStats(stats, runscStats)
+	expected := &info.ContainerStats{}
+	expected.Cpu.Usage = info.CpuUsage{Total: 2500000000, PerCpu: []uint64{1500000000, 1000000000}, User: 2000000000, System: 500000000}
+	expected.Memory = info.MemoryStats{Usage: 10485760, Cache: 4096, RSS: 8388608, MappedFile: 1048576, WorkingSet: 10485760}
+	expected.Processes.ProcessCount = 3
+	expected.Network.Interfaces = []info.InterfaceStats{{Name: "eth0", RxBytes: 100}}
+	assert.Equal(t, expected, stats)
+}
+
+func TestIsRuntime(t *testing.T) {
+	assert.True(t, IsRuntime("io.containerd.runsc.v1"))
+	assert.True(t, IsRuntime("runsc"))
+	assert.False(t, IsRuntime("io.containerd.runc.v2"))
+	assert.False(t, IsRuntime(""))
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntervalMetrics are metric kinds which can be collected at their own
+// intervals, longer than the housekeeping interval.
+var IntervalMetrics = MetricSet{
+	AcceleratorUsageMetrics: struct{}{},
+	BPFSchedMetrics:         struct{}{},
+	CgroupNetworkMetrics:    struct{}{},
+	DiskUsageMetrics:        struct{}{},
+	PerfMetrics:             struct{}{},
+	ProcessMetrics:          struct{}{},
+	ReferencedMemoryMetrics: struct{}{},
+	ResctrlMetrics:          struct{}{},
+}
+
+// ArgMetricIntervals are intervals of metric kinds configured by flags.
+var ArgMetricIntervals = MetricIntervals{}
+
+func init() {
+	flag.Var(&ArgMetricIntervals, "metric_intervals", "comma-separated list of metric=interval pairs, e.g. disk=5m,perf_event=30s. Listed metrics are collected at most once per interval, values are repeated in stats between collections. Options are 'accelerator', 'bpf_sched', 'cgroup_network', 'disk', 'perf_event', 'process', 'referenced_memory', 'resctrl'. Other metrics are collected every housekeeping.")
+}
+
+// MetricIntervals are minimal intervals between collections of metric kinds.
+type MetricIntervals map[MetricKind]time.Duration
+
+// Interval returns the interval of the metric kind, zero if it is collected
+// every housekeeping.
+func (mi MetricIntervals) Interval(mk MetricKind) time.Duration {
+	return mi[mk]
+}
+
+func (mi MetricIntervals) String() string {
+	pairs := make([]string, 0, len(mi))
+	for mk, interval := range mi {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", mk, interval))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (mi MetricIntervals) Set(value string) error {
+	for mk := range mi {
+		delete(mi, mk)
+	}
+	if value == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid metric interval %q, must be metric=interval", pair)
+		}
+		mk := MetricKind(parts[0])
+		if !IntervalMetrics.Has(mk) {
+			return fmt.Errorf("unsupported metric %q specified in metric_intervals", parts[0])
+		}
+		interval, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid interval of metric %q: %v", parts[0], err)
+		}
+		if interval < 0 {
+			return fmt.Errorf("interval of metric %q must not be negative, got %s", parts[0], interval)
+		}
+		mi[mk] = interval
+	}
+	return nil
+}
+
+// MetricSchedule tracks when metric kinds are collected next, for stats of a
+// single container. A nil MetricSchedule collects all metrics every time.
+type MetricSchedule struct {
+	intervals MetricIntervals
+	lock      sync.Mutex
+	next      map[MetricKind]time.Time
+}
+
+// NewMetricSchedule returns a schedule of metric kinds, nil if no metric kind
+// has an interval.
+func NewMetricSchedule(intervals MetricIntervals) *MetricSchedule {
+	if len(intervals) == 0 {
+		return nil
+	}
+	return &MetricSchedule{
+		intervals: intervals,
+		next:      map[MetricKind]time.Time{},
+	}
+}
+
+// Due returns true if the metric kind should be collected at the time, in
+// which case its next collection is scheduled after its interval.
+func (s *MetricSchedule) Due(mk MetricKind, now time.Time) bool {
+	if s == nil {
+		return true
+	}
+	interval := s.intervals.Interval(mk)
+	if interval <= 0 {
+		return true
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if next, ok := s.next[mk]; ok && now.Before(next) {
+		return false
+	}
+	s.next[mk] = now.Add(interval)
+	return true
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricIntervalsSet(t *testing.T) {
+	intervals := MetricIntervals{}
+	assert.Nil(t, intervals.Set("disk=5m,perf_event=30s"))
+	assert.Equal(t, MetricIntervals{DiskUsageMetrics: 5 * time.Minute, PerfMetrics: 30 * time.Second}, intervals)
+	assert.Equal(t, "disk=5m0s,perf_event=30s", intervals.String())
+
+	assert.Nil(t, intervals.Set(""))
+	assert.Empty(t, intervals)
+
+	for _, value := range []string{"disk", "cpu=1m", "disk=often", "disk=-1s"} {
+		assert.NotNil(t, intervals.Set(value), value)
+	}
+}
+
+func TestMetricSchedule(t *testing.T) {
+	assert.Nil(t, NewMetricSchedule(MetricIntervals{}))
+	var schedule *MetricSchedule
+	assert.True(t, schedule.Due(PerfMetrics, time.Now()))
+
+	schedule = NewMetricSchedule(MetricIntervals{PerfMetrics: time.Minute})
+	start := time.Unix(1395066363, 0)
+	assert.True(t, schedule.Due(PerfMetrics, start))
+	assert.False(t, schedule.Due(PerfMetrics, start.Add(30*time.Second)))
+	assert.True(t, schedule.Due(ResctrlMetrics, start.Add(30*time.Second)))
+	assert.True(t, schedule.Due(PerfMetrics, start.Add(time.Minute)))
+	assert.False(t, schedule.Due(PerfMetrics, start.Add(90*time.Second)))
+}
@@ -0,0 +1,252 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// setCgroupV2Stats sets stats read directly from interface files of cgroup v2 unified hierarchy.
+// CPU usage and CFS throttling are read from cpu.stat for all cgroups as runc does not read throttling,
+// swap usage is read from memory.swap.current as runc reports it combined with memory usage.
+// Root cgroup has no interface files of controllers except cpu.stat, io.stat and memory.stat
+// (on recent kernels), so runc does not read its stats at all, IO and memory stats of root cgroup
+// are read from those files and /proc/meminfo.
+func setCgroupV2Stats(cgroupPath string, rootFs string, isRoot bool, includedMetrics container.MetricSet, ret *info.ContainerStats) error {
+	err := setCPUStatsV2(cgroupPath, ret)
+	if err != nil {
+		return err
+	}
+	if includedMetrics.Has(container.DiskIOMetrics) {
+		err = setDiskIoStatsV2(cgroupPath, isRoot, ret)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if !isRoot {
+		swap, err := ioutil.ReadFile(path.Join(cgroupPath, "memory.swap.current"))
+		if err != nil {
+			// Swap accounting may be disabled.
+			return nil
+		}
+		ret.Memory.Swap, err = strconv.ParseUint(strings.TrimSpace(string(swap)), 10, 64)
+		return err
+	}
+	return setRootMemoryStatsV2(cgroupPath, rootFs, ret)
+}
+
+// setCPUStatsV2 sets CPU usage, CFS throttling and burst from cpu.stat which reports time in microseconds.
+func setCPUStatsV2(cgroupPath string, ret *info.ContainerStats) error {
+	stats, err := readFlatKeyedFile(path.Join(cgroupPath, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	ret.Cpu.Usage.Total = stats["usage_usec"] * 1000
+	ret.Cpu.Usage.User = stats["user_usec"] * 1000
+	ret.Cpu.Usage.System = stats["system_usec"] * 1000
+	ret.Cpu.CFS.Periods = stats["nr_periods"]
+	ret.Cpu.CFS.ThrottledPeriods = stats["nr_throttled"]
+	ret.Cpu.CFS.ThrottledTime = stats["throttled_usec"] * 1000
+	ret.Cpu.CFS.BurstPeriods = stats["nr_bursts"]
+	ret.Cpu.CFS.BurstTime = stats["burst_usec"] * 1000
+	return nil
+}
+
+// setDiskIoStatsV2 sets IO stats by device from io.stat, e.g.
+// "8:0 rbytes=90112 wbytes=4096 rios=3 wios=1 dbytes=0 dios=0 cost.usage=1500 cost.wait=1200 cost.indebt=0 cost.indelay=0".
+// Bytes and operations are set only for the root cgroup as runc reads them for other cgroups.
+// Time IOs of the cgroup waited to be issued (cost.wait in microseconds) is reported only by
+// io.cost controller, i.e. when IO cost model based control is enabled for the device.
+func setDiskIoStatsV2(cgroupPath string, isRoot bool, ret *info.ContainerStats) error {
+	file, err := os.Open(path.Join(cgroupPath, "io.stat"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	serviceBytes := []info.PerDiskStats{}
+	serviced := []info.PerDiskStats{}
+	waitTime := []info.PerDiskStats{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var major, minor uint64
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &major, &minor); err != nil {
+			return fmt.Errorf("failed to parse device of io.stat line %q: %v", scanner.Text(), err)
+		}
+		values := make(map[string]uint64, len(fields)-1)
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(keyValue[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse io.stat line %q: %v", scanner.Text(), err)
+			}
+			values[keyValue[0]] = value
+		}
+		serviceBytes = append(serviceBytes, info.PerDiskStats{
+			Major: major,
+			Minor: minor,
+			Stats: map[string]uint64{"Read": values["rbytes"], "Write": values["wbytes"]},
+		})
+		serviced = append(serviced, info.PerDiskStats{
+			Major: major,
+			Minor: minor,
+			Stats: map[string]uint64{"Read": values["rios"], "Write": values["wios"]},
+		})
+		if wait, ok := values["cost.wait"]; ok {
+			waitTime = append(waitTime, info.PerDiskStats{
+				Major: major,
+				Minor: minor,
+				Stats: map[string]uint64{"Total": wait * 1000},
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if isRoot {
+		ret.DiskIo.IoServiceBytes = serviceBytes
+		ret.DiskIo.IoServiced = serviced
+	}
+	if len(waitTime) > 0 {
+		ret.DiskIo.IoWaitTime = waitTime
+	}
+	return nil
+}
+
+// setRootMemoryStatsV2 sets memory stats of root cgroup. There is no memory.current in root cgroup,
+// so usage is derived from /proc/meminfo. Breakdown is read from memory.stat if the kernel provides it
+// for root cgroup, /proc/meminfo is used otherwise.
+func setRootMemoryStatsV2(cgroupPath string, rootFs string, ret *info.ContainerStats) error {
+	meminfo, err := readMeminfo(path.Join(rootFs, "proc", "meminfo"))
+	if err != nil {
+		return err
+	}
+	ret.Memory.Usage = meminfo["MemTotal"] - meminfo["MemFree"]
+	ret.Memory.Swap = meminfo["SwapTotal"] - meminfo["SwapFree"]
+
+	inactiveFile := meminfo["Inactive(file)"]
+	stats, err := readFlatKeyedFile(path.Join(cgroupPath, "memory.stat"))
+	if err == nil {
+		ret.Memory.Cache = stats["file"]
+		ret.Memory.RSS = stats["anon"]
+		ret.Memory.MappedFile = stats["file_mapped"]
+		ret.Memory.ContainerData.Pgfault = stats["pgfault"]
+		ret.Memory.ContainerData.Pgmajfault = stats["pgmajfault"]
+		ret.Memory.HierarchicalData.Pgfault = stats["pgfault"]
+		ret.Memory.HierarchicalData.Pgmajfault = stats["pgmajfault"]
+		setMemoryReclaimStatsV2(stats, ret)
+		inactiveFile = stats["inactive_file"]
+	} else if os.IsNotExist(err) {
+		ret.Memory.Cache = meminfo["Cached"] + meminfo["Buffers"]
+		ret.Memory.RSS = meminfo["AnonPages"]
+		ret.Memory.MappedFile = meminfo["Mapped"]
+	} else {
+		return err
+	}
+
+	ret.Memory.WorkingSet = 0
+	if ret.Memory.Usage > inactiveFile {
+		ret.Memory.WorkingSet = ret.Memory.Usage - inactiveFile
+	}
+	return nil
+}
+
+// setMemoryReclaimStatsV2 sets refault and reclaim counters from memory.stat. Kernels older than 5.9
+// track refaults of file pages only and report them as workingset_refault and workingset_activate.
+func setMemoryReclaimStatsV2(stats map[string]uint64, ret *info.ContainerStats) {
+	reclaim := &ret.Memory.Reclaim
+	reclaim.WorkingsetRefaultAnon = stats["workingset_refault_anon"]
+	reclaim.WorkingsetActivateAnon = stats["workingset_activate_anon"]
+	if v, ok := stats["workingset_refault_file"]; ok {
+		reclaim.WorkingsetRefaultFile = v
+	} else {
+		reclaim.WorkingsetRefaultFile = stats["workingset_refault"]
+	}
+	if v, ok := stats["workingset_activate_file"]; ok {
+		reclaim.WorkingsetActivateFile = v
+	} else {
+		reclaim.WorkingsetActivateFile = stats["workingset_activate"]
+	}
+	reclaim.Pgscan = stats["pgscan"]
+	reclaim.Pgsteal = stats["pgsteal"]
+}
+
+// readFlatKeyedFile reads cgroup v2 interface file with "key value" lines, e.g. cpu.stat or memory.stat.
+func readFlatKeyedFile(filePath string) (map[string]uint64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q of %s: %v", scanner.Text(), filePath, err)
+		}
+		stats[fields[0]] = value
+	}
+	return stats, scanner.Err()
+}
+
+// readMeminfo reads /proc/meminfo, values are converted to bytes.
+func readMeminfo(filePath string) (map[string]uint64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	meminfo := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// e.g. "MemTotal:       32817192 kB" or "HugePages_Total:       0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+		meminfo[strings.TrimSuffix(fields[0], ":")] = value
+	}
+	return meminfo, scanner.Err()
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetCgroupV2Stats(t *testing.T) {
+	stats := &info.ContainerStats{}
+	err := setCgroupV2Stats("testdata/cgroupv2/system.slice/test.service", "testdata/cgroupv2", false, container.AllMetrics, stats)
+	assert.Nil(t, err)
+	assert.Equal(t, info.CpuUsage{Total: 1234567000, User: 1000000000, System: 234567000}, stats.Cpu.Usage)
+	assert.Equal(t, info.CpuCFS{Periods: 100, ThrottledPeriods: 25, ThrottledTime: 500000000, BurstPeriods: 10, BurstTime: 200000000}, stats.Cpu.CFS)
+	assert.Equal(t, uint64(1048576), stats.Memory.Swap)
+	// Memory usage of non-root cgroups is provided by runc.
+	assert.Equal(t, uint64(0), stats.Memory.Usage)
+	// Bytes and operations of non-root cgroups are provided by runc.
+	assert.Nil(t, stats.DiskIo.IoServiceBytes)
+	// Wait time is reported only for devices controlled by io.cost.
+	assert.Equal(t, []info.PerDiskStats{
+		{Major: 8, Minor: 0, Stats: map[string]uint64{"Total": 2500000}},
+	}, stats.DiskIo.IoWaitTime)
+}
+
+func TestSetCgroupV2StatsOfRootCgroup(t *testing.T) {
+	stats := &info.ContainerStats{}
+	err := setCgroupV2Stats("testdata/cgroupv2", "testdata/cgroupv2", true, container.AllMetrics, stats)
+	assert.Nil(t, err)
+	assert.Equal(t, info.CpuUsage{Total: 8765432000, User: 5432100000, System: 3333332000}, stats.Cpu.Usage)
+	assert.Equal(t, info.CpuCFS{}, stats.Cpu.CFS)
+
+	assert.Equal(t, []info.PerDiskStats{
+		{Major: 8, Minor: 0, Stats: map[string]uint64{"Read": 90112, "Write": 4096}},
+		{Major: 259, Minor: 0, Stats: map[string]uint64{"Read": 1048576, "Write": 2097152}},
+	}, stats.DiskIo.IoServiceBytes)
+	assert.Equal(t, []info.PerDiskStats{
+		{Major: 8, Minor: 0, Stats: map[string]uint64{"Read": 3, "Write": 1}},
+		{Major: 259, Minor: 0, Stats: map[string]uint64{"Read": 256, "Write": 512}},
+	}, stats.DiskIo.IoServiced)
+
+	assert.Equal(t, uint64(8192000*1024), stats.Memory.Usage)
+	assert.Equal(t, uint64(1048576*1024), stats.Memory.Swap)
+	assert.Equal(t, uint64(4294967296), stats.Memory.Cache)
+	assert.Equal(t, uint64(2147483648), stats.Memory.RSS)
+	assert.Equal(t, uint64(536870912), stats.Memory.MappedFile)
+	assert.Equal(t, uint64(8192000*1024-1073741824), stats.Memory.WorkingSet)
+	assert.Equal(t, uint64(123456), stats.Memory.ContainerData.Pgfault)
+	assert.Equal(t, uint64(789), stats.Memory.HierarchicalData.Pgmajfault)
+	assert.Equal(t, info.MemoryReclaimStats{
+		WorkingsetRefaultAnon:  1024,
+		WorkingsetRefaultFile:  4096,
+		WorkingsetActivateAnon: 256,
+		WorkingsetActivateFile: 2048,
+		Pgscan:                 65536,
+		Pgsteal:                32768,
+	}, stats.Memory.Reclaim)
+}
+
+func TestSetMemoryReclaimStatsV2BeforeAnonRefaults(t *testing.T) {
+	// Kernels older than 5.9 report refaults of file pages without suffix.
+	stats := &info.ContainerStats{}
+	setMemoryReclaimStatsV2(map[string]uint64{
+		"workingset_refault":  300,
+		"workingset_activate": 100,
+		"pgscan":              5000,
+		"pgsteal":             4000,
+	}, stats)
+	assert.Equal(t, info.MemoryReclaimStats{
+		WorkingsetRefaultFile:  300,
+		WorkingsetActivateFile: 100,
+		Pgscan:                 5000,
+		Pgsteal:                4000,
+	}, stats.Memory.Reclaim)
+}
+
+func TestSetRootMemoryStatsV2WithoutMemoryStat(t *testing.T) {
+	// Older kernels do not provide memory.stat in root cgroup.
+	stats := &info.ContainerStats{}
+	err := setRootMemoryStatsV2("testdata/cgroupv2/system.slice", "testdata/cgroupv2", stats)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(8192000*1024), stats.Memory.Usage)
+	assert.Equal(t, uint64((102400+4096000)*1024), stats.Memory.Cache)
+	assert.Equal(t, uint64(2048000*1024), stats.Memory.RSS)
+	assert.Equal(t, uint64(512000*1024), stats.Memory.MappedFile)
+	assert.Equal(t, uint64((8192000-1024000)*1024), stats.Memory.WorkingSet)
+}
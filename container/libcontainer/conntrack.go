@@ -0,0 +1,83 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// conntrackStatsFromProc reads usage of conntrack table of network namespace of the process.
+// Host network namespace (pid 1) reports number of entries through nf_conntrack_count sysctl,
+// for other namespaces entries listed in /proc/PID/net/nf_conntrack are counted. Maximum number
+// of entries is shared by all network namespaces.
+func conntrackStatsFromProc(rootFs string, pid int) (info.ConntrackStat, error) {
+	var stats info.ConntrackStat
+	var err error
+
+	if pid == 1 {
+		stats.Entries, err = readUint64File(path.Join(rootFs, "proc", "sys", "net", "netfilter", "nf_conntrack_count"))
+	} else {
+		stats.Entries, err = countConntrackEntries(path.Join(rootFs, "proc", strconv.Itoa(pid), "net", "nf_conntrack"))
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Max, err = readUint64File(path.Join(rootFs, "proc", "sys", "net", "netfilter", "nf_conntrack_max"))
+	if err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+func countConntrackEntries(conntrackFile string) (uint64, error) {
+	file, err := os.Open(conntrackFile)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var entries uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			entries++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", conntrackFile, err)
+	}
+	return entries, nil
+}
+
+func readUint64File(filePath string) (uint64, error) {
+	out, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %v", filePath, err)
+	}
+	return value, nil
+}
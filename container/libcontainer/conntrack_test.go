@@ -0,0 +1,40 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConntrackStatsFromProcHost(t *testing.T) {
+	stats, err := conntrackStatsFromProc("testdata/conntrack", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, info.ConntrackStat{Entries: 3, Max: 65536}, stats)
+}
+
+func TestConntrackStatsFromProcContainer(t *testing.T) {
+	stats, err := conntrackStatsFromProc("testdata/conntrack", 42)
+	assert.Nil(t, err)
+	assert.Equal(t, info.ConntrackStat{Entries: 2, Max: 65536}, stats)
+}
+
+func TestConntrackStatsFromProcMissing(t *testing.T) {
+	_, err := conntrackStatsFromProc("testdata/conntrack", 7)
+	assert.NotNil(t, err)
+}
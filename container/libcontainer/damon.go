@@ -0,0 +1,295 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	damonAdminDir = "/sys/kernel/mm/damon/admin"
+
+	// DAMON is configured through single kdamond which measures working sets of all containers in turns.
+	defaultDamonMonitor = &damonMonitor{}
+
+	// damonWorkingSetExpiry is time after which working set which is not requested anymore, e.g. of removed
+	// container, is not measured.
+	damonWorkingSetExpiry = 5 * time.Minute
+	// damonIdleInterval is time kdamond is idle for when there are no processes to measure.
+	damonIdleInterval = time.Second
+)
+
+// damonStat holds working set of container estimated by DAMON.
+type damonStat struct {
+	// Bytes of memory accessed at least once during aggregation interval.
+	bytes uint64
+	// Bytes of memory by number of sampling intervals in which it was accessed during aggregation interval.
+	accessHistogram map[uint64]uint64
+}
+
+// damonWorkingSet estimates working set of container processes using DAMON with its own intervals.
+// Working set is measured asynchronously by damonMonitor, housekeeping of container only reads the
+// latest measurement.
+type damonWorkingSet struct {
+	monitor             *damonMonitor
+	sampleInterval      time.Duration
+	aggregationInterval time.Duration
+
+	lock sync.Mutex
+	// PIDs to measure and time when they were requested.
+	pids      []int
+	requested time.Time
+	// The latest measurement.
+	stat     damonStat
+	err      error
+	measured bool
+}
+
+func newDamonWorkingSet(monitor *damonMonitor, sampleInterval, aggregationInterval time.Duration) *damonWorkingSet {
+	return &damonWorkingSet{
+		monitor:             monitor,
+		sampleInterval:      sampleInterval,
+		aggregationInterval: aggregationInterval,
+	}
+}
+
+// update sets PIDs of container to measure and returns the latest measurement of working set, which may
+// be of PIDs set before. It returns false if working set was not measured yet.
+func (w *damonWorkingSet) update(pids []int) (damonStat, bool, error) {
+	w.lock.Lock()
+	w.pids = pids
+	w.requested = time.Now()
+	stat, err, measured := w.stat, w.err, w.measured
+	w.lock.Unlock()
+
+	w.monitor.register(w)
+	return stat, measured, err
+}
+
+// measure measures working set of PIDs set by the latest update, it returns false if there is nothing
+// to measure or DAMON failed, so that the monitor does not retry immediately.
+func (w *damonWorkingSet) measure() bool {
+	w.lock.Lock()
+	pids := w.pids
+	w.lock.Unlock()
+	if len(pids) == 0 {
+		return false
+	}
+
+	stat, err := damonReferencedBytesStat(pids, w.sampleInterval, w.aggregationInterval)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stat, w.err, w.measured = stat, err, true
+	return err == nil
+}
+
+func (w *damonWorkingSet) expired() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return time.Since(w.requested) > damonWorkingSetExpiry
+}
+
+// damonMonitor measures working sets in turns using single kdamond, while there are any working sets
+// which were requested recently.
+type damonMonitor struct {
+	lock        sync.Mutex
+	workingSets []*damonWorkingSet
+	running     bool
+}
+
+func (m *damonMonitor) register(w *damonWorkingSet) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, registered := range m.workingSets {
+		if registered == w {
+			return
+		}
+	}
+	m.workingSets = append(m.workingSets, w)
+	if !m.running {
+		m.running = true
+		go m.run()
+	}
+}
+
+// next removes expired working sets and returns the rest, it stops the monitor if there is none.
+func (m *damonMonitor) next() []*damonWorkingSet {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	workingSets := m.workingSets[:0]
+	for _, w := range m.workingSets {
+		if !w.expired() {
+			workingSets = append(workingSets, w)
+		}
+	}
+	m.workingSets = workingSets
+	if len(workingSets) == 0 {
+		m.running = false
+		return nil
+	}
+	return append([]*damonWorkingSet(nil), workingSets...)
+}
+
+func (m *damonMonitor) run() {
+	for {
+		workingSets := m.next()
+		if workingSets == nil {
+			return
+		}
+		measured := false
+		for _, w := range workingSets {
+			measured = w.measure() || measured
+		}
+		if !measured {
+			time.Sleep(damonIdleInterval)
+		}
+	}
+}
+
+// damonReferencedBytesStat estimates working set of given PIDs using DAMON (Data Access MONitor).
+// Kernel DAMON is programmed to monitor virtual address spaces of PIDs for single aggregation interval,
+// memory regions which were accessed at least once in that time are accounted as working set.
+// It blocks for duration of aggregation interval, so it is called only by damonMonitor.
+// see: https://www.kernel.org/doc/html/latest/admin-guide/mm/damon/usage.html
+func damonReferencedBytesStat(pids []int, sampleInterval, aggregationInterval time.Duration) (damonStat, error) {
+	if len(pids) == 0 {
+		return damonStat{}, nil
+	}
+
+	kdamondDir := filepath.Join(damonAdminDir, "kdamonds", "0")
+	state, err := readDamonFile(filepath.Join(kdamondDir, "state"))
+	if err == nil && state == "on" {
+		return damonStat{}, fmt.Errorf("kdamond %s is already running, DAMON is used by other user", kdamondDir)
+	}
+	if err != nil {
+		err = writeDamonFile(filepath.Join(damonAdminDir, "kdamonds", "nr_kdamonds"), "1")
+		if err != nil {
+			return damonStat{}, err
+		}
+	}
+
+	schemeDir, err := configureDamon(kdamondDir, pids, sampleInterval, aggregationInterval)
+	if err != nil {
+		return damonStat{}, err
+	}
+
+	err = writeDamonFile(filepath.Join(kdamondDir, "state"), "on")
+	if err != nil {
+		return damonStat{}, err
+	}
+	defer writeDamonFile(filepath.Join(kdamondDir, "state"), "off")
+
+	// Wait until regions are aggregated at least once.
+	time.Sleep(2 * aggregationInterval)
+	err = writeDamonFile(filepath.Join(kdamondDir, "state"), "update_schemes_tried_regions")
+	if err != nil {
+		return damonStat{}, err
+	}
+	return readDamonTriedRegions(filepath.Join(schemeDir, "tried_regions"))
+}
+
+// damonSetting is value written to DAMON sysfs file.
+type damonSetting struct {
+	file  string
+	value string
+}
+
+// configureDamon sets up single monitoring context of kdamond with given PIDs as targets and "stat" scheme
+// matching all regions, it returns directory of the scheme.
+func configureDamon(kdamondDir string, pids []int, sampleInterval, aggregationInterval time.Duration) (string, error) {
+	contextDir := filepath.Join(kdamondDir, "contexts", "0")
+	schemeDir := filepath.Join(contextDir, "schemes", "0")
+	intervalsDir := filepath.Join(contextDir, "monitoring_attrs", "intervals")
+	settings := []damonSetting{
+		{filepath.Join(kdamondDir, "contexts", "nr_contexts"), "1"},
+		{filepath.Join(contextDir, "operations"), "vaddr"},
+		{filepath.Join(intervalsDir, "sample_us"), strconv.FormatInt(sampleInterval.Microseconds(), 10)},
+		{filepath.Join(intervalsDir, "aggr_us"), strconv.FormatInt(aggregationInterval.Microseconds(), 10)},
+		{filepath.Join(intervalsDir, "update_us"), strconv.FormatInt(aggregationInterval.Microseconds(), 10)},
+		{filepath.Join(contextDir, "targets", "nr_targets"), strconv.Itoa(len(pids))},
+	}
+	for i, pid := range pids {
+		settings = append(settings, damonSetting{filepath.Join(contextDir, "targets", strconv.Itoa(i), "pid_target"), strconv.Itoa(pid)})
+	}
+	// Default access pattern of scheme matches all regions.
+	settings = append(settings, []damonSetting{
+		{filepath.Join(contextDir, "schemes", "nr_schemes"), "1"},
+		{filepath.Join(schemeDir, "action"), "stat"},
+	}...)
+
+	for _, setting := range settings {
+		err := writeDamonFile(setting.file, setting.value)
+		if err != nil {
+			return "", err
+		}
+	}
+	return schemeDir, nil
+}
+
+func readDamonTriedRegions(triedRegionsDir string) (damonStat, error) {
+	regions, err := ioutil.ReadDir(triedRegionsDir)
+	if err != nil {
+		return damonStat{}, err
+	}
+	stat := damonStat{accessHistogram: make(map[uint64]uint64)}
+	for _, region := range regions {
+		if !region.IsDir() {
+			continue
+		}
+		regionDir := filepath.Join(triedRegionsDir, region.Name())
+		values := make(map[string]uint64, 3)
+		for _, file := range []string{"start", "end", "nr_accesses"} {
+			value, err := readDamonFile(filepath.Join(regionDir, file))
+			if err != nil {
+				return damonStat{}, err
+			}
+			values[file], err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return damonStat{}, fmt.Errorf("failed to parse %s of region %s: %v", file, regionDir, err)
+			}
+		}
+		if values["end"] < values["start"] {
+			return damonStat{}, fmt.Errorf("unexpected address range of region %s: %d-%d", regionDir, values["start"], values["end"])
+		}
+		size := values["end"] - values["start"]
+		stat.accessHistogram[values["nr_accesses"]] += size
+		if values["nr_accesses"] > 0 {
+			stat.bytes += size
+		}
+	}
+	return stat, nil
+}
+
+func readDamonFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func writeDamonFile(path string, value string) error {
+	err := ioutil.WriteFile(path, []byte(value), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %q to %s: %v", value, path, err)
+	}
+	return nil
+}
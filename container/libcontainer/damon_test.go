@@ -0,0 +1,153 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupDamon creates directories which kernel creates in DAMON sysfs interface
+// along with regions reported by "stat" scheme.
+func setupDamon(t *testing.T, state string) string {
+	dir, err := ioutil.TempDir("", "damon")
+	assert.Nil(t, err)
+
+	//overwrite package variable
+	damonAdminDir = dir
+
+	contextDir := filepath.Join(dir, "kdamonds", "0", "contexts", "0")
+	for _, d := range []string{
+		filepath.Join(contextDir, "monitoring_attrs", "intervals"),
+		filepath.Join(contextDir, "targets", "0"),
+		filepath.Join(contextDir, "targets", "1"),
+	} {
+		assert.Nil(t, os.MkdirAll(d, 0755))
+	}
+
+	regions := []map[string]string{
+		{"start": "4096", "end": "12288", "nr_accesses": "0"},
+		{"start": "12288", "end": "16384", "nr_accesses": "3"},
+		{"start": "65536", "end": "131072", "nr_accesses": "20"},
+		{"start": "131072", "end": "135168", "nr_accesses": "3"},
+	}
+	for i, region := range regions {
+		regionDir := filepath.Join(contextDir, "schemes", "0", "tried_regions", string(rune('0'+i)))
+		assert.Nil(t, os.MkdirAll(regionDir, 0755))
+		for file, value := range region {
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(regionDir, file), []byte(value+"\n"), 0644))
+		}
+	}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "kdamonds", "0", "state"), []byte(state+"\n"), 0644))
+	return dir
+}
+
+func TestDamonReferencedBytesStat(t *testing.T) {
+	dir := setupDamon(t, "off")
+	defer os.RemoveAll(dir)
+
+	stat, err := damonReferencedBytesStat([]int{4, 6}, time.Millisecond, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4096+65536+4096), stat.bytes)
+	assert.Equal(t, map[uint64]uint64{0: 8192, 3: 8192, 20: 65536}, stat.accessHistogram)
+
+	contextDir := filepath.Join(dir, "kdamonds", "0", "contexts", "0")
+	for file, expected := range map[string]string{
+		filepath.Join(contextDir, "operations"):                                 "vaddr",
+		filepath.Join(contextDir, "monitoring_attrs", "intervals", "sample_us"): "1000",
+		filepath.Join(contextDir, "monitoring_attrs", "intervals", "aggr_us"):   "10000",
+		filepath.Join(contextDir, "targets", "nr_targets"):                      "2",
+		filepath.Join(contextDir, "targets", "0", "pid_target"):                 "4",
+		filepath.Join(contextDir, "targets", "1", "pid_target"):                 "6",
+		filepath.Join(contextDir, "schemes", "0", "action"):                     "stat",
+		filepath.Join(dir, "kdamonds", "0", "state"):                            "off",
+	} {
+		value, err := readDamonFile(file)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, value, file)
+	}
+}
+
+func TestDamonReferencedBytesStatWhenKdamondIsRunning(t *testing.T) {
+	dir := setupDamon(t, "on")
+	defer os.RemoveAll(dir)
+
+	_, err := damonReferencedBytesStat([]int{4}, time.Millisecond, 10*time.Millisecond)
+	assert.NotNil(t, err)
+}
+
+func TestDamonWorkingSet(t *testing.T) {
+	dir := setupDamon(t, "off")
+	defer os.RemoveAll(dir)
+	//overwrite package variables
+	originalExpiry, originalIdleInterval := damonWorkingSetExpiry, damonIdleInterval
+	defer func() {
+		damonWorkingSetExpiry, damonIdleInterval = originalExpiry, originalIdleInterval
+	}()
+	damonWorkingSetExpiry, damonIdleInterval = 100*time.Millisecond, time.Millisecond
+
+	monitor := &damonMonitor{}
+	workingSet := newDamonWorkingSet(monitor, time.Millisecond, 10*time.Millisecond)
+	// Working set is measured in background, it is not available yet.
+	_, measured, err := workingSet.update([]int{4, 6})
+	assert.Nil(t, err)
+	assert.False(t, measured)
+
+	var stat damonStat
+	for i := 0; i < 100 && !measured; i++ {
+		time.Sleep(10 * time.Millisecond)
+		stat, measured, err = workingSet.update([]int{4, 6})
+	}
+	assert.Nil(t, err)
+	assert.True(t, measured)
+	assert.Equal(t, uint64(4096+65536+4096), stat.bytes)
+
+	// Monitor stops when working set is not requested anymore.
+	running := true
+	for i := 0; i < 100 && running; i++ {
+		time.Sleep(10 * time.Millisecond)
+		monitor.lock.Lock()
+		running = monitor.running
+		monitor.lock.Unlock()
+	}
+	assert.False(t, running)
+	assert.Empty(t, monitor.workingSets)
+}
+
+func TestDamonWorkingSetWhenDamonFails(t *testing.T) {
+	// DAMON sysfs interface is not available.
+	dir, err := ioutil.TempDir("", "damon")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	originalAdminDir := damonAdminDir
+	defer func() {
+		damonAdminDir = originalAdminDir
+	}()
+	damonAdminDir = dir
+
+	workingSet := newDamonWorkingSet(&damonMonitor{}, time.Millisecond, 10*time.Millisecond)
+	workingSet.pids = []int{4}
+	// Monitor sleeps before next round instead of retrying immediately.
+	assert.False(t, workingSet.measure())
+	workingSet.lock.Lock()
+	defer workingSet.lock.Unlock()
+	assert.True(t, workingSet.measured)
+	assert.Error(t, workingSet.err)
+}
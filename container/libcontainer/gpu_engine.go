@@ -0,0 +1,123 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	drmDevicesPrefix        = "/dev/dri/"
+	drmPdevKey              = "drm-pdev"
+	drmClientIDKey          = "drm-client-id"
+	drmEngineKeyPrefix      = "drm-engine-"
+	drmEngineCapacityPrefix = "drm-engine-capacity-"
+	drmEngineTimeUnit       = " ns"
+)
+
+// gpuEngineStatsFromProcs returns time in nanoseconds during which GPU engines were busy with work
+// submitted through DRM clients opened by given processes, by engine name.
+// Clients are identified by device and client id because file descriptor of a client can be shared
+// by many processes. Busy time of closed clients is kept in drmClientsCache so that it is still accounted.
+// See https://www.kernel.org/doc/html/latest/gpu/drm-usage-stats.html for the description of fdinfo keys.
+func gpuEngineStatsFromProcs(rootFs string, pids []int, drmClientsCache map[string]map[string]uint64) map[string]uint64 {
+	for _, pid := range pids {
+		procPath := path.Join(rootFs, "proc", strconv.Itoa(pid))
+		fds, err := ioutil.ReadDir(path.Join(procPath, "fd"))
+		if err != nil {
+			klog.V(4).Infof("error while listing file descriptors of process %d: %v", pid, err)
+			continue
+		}
+		for _, fd := range fds {
+			linkName, err := os.Readlink(path.Join(procPath, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(linkName, drmDevicesPrefix) {
+				continue
+			}
+			fdinfoPath := path.Join(procPath, "fdinfo", fd.Name())
+			client, engines, err := readDRMFdinfo(fdinfoPath)
+			if err != nil {
+				klog.V(4).Infof("error while reading %q: %v", fdinfoPath, err)
+				continue
+			}
+			// Drivers which do not support usage statistics don't report client id.
+			if client == "" {
+				continue
+			}
+			drmClientsCache[client] = engines
+		}
+	}
+	if len(drmClientsCache) == 0 {
+		return nil
+	}
+	busyTime := make(map[string]uint64)
+	for _, engines := range drmClientsCache {
+		for engine, engineBusyTime := range engines {
+			busyTime[engine] += engineBusyTime
+		}
+	}
+	return busyTime
+}
+
+// readDRMFdinfo returns identifier of DRM client and busy time of its engines read from fdinfo file.
+// Only engine utilization reported as time is used (e.g. by i915 and amdgpu drivers), drivers reporting
+// it in GPU cycles (drm-cycles-<engine> keys of xe driver) are not supported.
+func readDRMFdinfo(fdinfoPath string) (string, map[string]uint64, error) {
+	f, err := os.Open(fdinfoPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	var pdev, clientID string
+	engines := make(map[string]uint64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.SplitN(s.Text(), ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		key, value := fields[0], strings.TrimSpace(fields[1])
+		switch {
+		case key == drmPdevKey:
+			pdev = value
+		case key == drmClientIDKey:
+			clientID = value
+		case strings.HasPrefix(key, drmEngineKeyPrefix) && !strings.HasPrefix(key, drmEngineCapacityPrefix):
+			if !strings.HasSuffix(value, drmEngineTimeUnit) {
+				continue
+			}
+			engineBusyTime, err := strconv.ParseUint(strings.TrimSuffix(value, drmEngineTimeUnit), 10, 64)
+			if err != nil {
+				klog.V(4).Infof("invalid value of %s in %q: %v", key, fdinfoPath, err)
+				continue
+			}
+			engines[strings.TrimPrefix(key, drmEngineKeyPrefix)] = engineBusyTime
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", nil, err
+	}
+	if clientID == "" {
+		return "", nil, nil
+	}
+	return pdev + "/" + clientID, engines, nil
+}
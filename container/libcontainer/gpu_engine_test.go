@@ -0,0 +1,68 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDRMFdinfo(t *testing.T) {
+	client, engines, err := readDRMFdinfo("testdata/gpu_engine/proc/100/fdinfo/3")
+	assert.Nil(t, err)
+	assert.Equal(t, "0000:00:02.0/7", client)
+	assert.Equal(t, map[string]uint64{
+		"render":        9288864723,
+		"copy":          0,
+		"video":         1200000000,
+		"video-enhance": 0,
+	}, engines)
+
+	// File descriptor without usage statistics.
+	client, engines, err = readDRMFdinfo("testdata/gpu_engine/proc/100/fdinfo/4")
+	assert.Nil(t, err)
+	assert.Equal(t, "", client)
+	assert.Nil(t, engines)
+
+	_, _, err = readDRMFdinfo("testdata/gpu_engine/proc/100/fdinfo/9")
+	assert.NotNil(t, err)
+}
+
+func TestGPUEngineStatsFromProcs(t *testing.T) {
+	drmClientsCache := map[string]map[string]uint64{}
+	assert.Nil(t, gpuEngineStatsFromProcs("testdata/gpu_engine", []int{102}, drmClientsCache))
+
+	// Client 7 is shared by processes 100 and 101 and must be accounted once,
+	// process 102 does not exist.
+	busyTime := gpuEngineStatsFromProcs("testdata/gpu_engine", []int{100, 101, 102}, drmClientsCache)
+	assert.Equal(t, map[string]uint64{
+		"render":        9788864723,
+		"copy":          0,
+		"video":         5200000000,
+		"video-enhance": 0,
+	}, busyTime)
+
+	// Busy time of closed clients is still accounted.
+	drmClientsCache["0000:00:02.0/5"] = map[string]uint64{"render": 1000000000, "compute": 10}
+	busyTime = gpuEngineStatsFromProcs("testdata/gpu_engine", []int{101}, drmClientsCache)
+	assert.Equal(t, map[string]uint64{
+		"render":        10788864723,
+		"copy":          0,
+		"video":         5200000000,
+		"video-enhance": 0,
+		"compute":       10,
+	}, busyTime)
+}
@@ -17,6 +17,7 @@ package libcontainer
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -44,12 +45,59 @@ var (
 	referencedResetInterval = flag.Uint64("referenced_reset_interval", 0,
 		"Reset interval for referenced bytes (container_referenced_bytes metric), number of measurement cycles after which referenced bytes are cleared, if set to 0 referenced bytes are never cleared (default: 0)")
 
-	smapsFilePathPattern     = "/proc/%d/smaps"
-	clearRefsFilePathPattern = "/proc/%d/clear_refs"
+	referencedReadConcurrency = flag.Int("referenced_read_concurrency", 1,
+		"Number of processes which smaps files are read concurrently while measuring referenced bytes (container_referenced_bytes metric)")
+	referencedReadBudget = flag.Duration("referenced_read_budget", 0,
+		"Time budget for reading smaps files of container processes in single measurement cycle, referenced bytes are reported as partial when exceeded, if set to 0 there is no budget (default: 0)")
+	referencedMemoryBreakdown = flag.Bool("referenced_memory_breakdown", false,
+		"Whether to split referenced bytes into anonymous and file-backed memory, it requires reading of smaps files instead of smaps_rollup, used when referenced_memory_backend is set to 'smaps'")
+	damonSampleInterval = flag.Duration("damon_sample_interval", 5*time.Millisecond,
+		"Interval between access checks of DAMON, used when referenced_memory_backend is set to 'damon'")
+	damonAggregationInterval = flag.Duration("damon_aggregation_interval", 100*time.Millisecond,
+		"Interval of DAMON during which accesses are counted, working sets of containers are measured in turns for twice that time in background, used when referenced_memory_backend is set to 'damon'")
+	fdCountSampleLimit = flag.Int("fd_count_sample_limit", 0,
+		"Maximum number of processes of container which file descriptors are listed to measure file descriptor and socket counts, counts of containers with more processes are extrapolated from the sample, if set to 0 there is no limit (default: 0)")
+
+	smapsFilePathPattern       = "/proc/%d/smaps"
+	smapsRollupFilePathPattern = "/proc/%d/smaps_rollup"
+	clearRefsFilePathPattern   = "/proc/%d/clear_refs"
 
 	referencedRegexp = regexp.MustCompile(`Referenced:\s*([0-9]+)\s*kB`)
+	// smapsMappingRegexp matches header of mapping in smaps file, e.g.
+	// "55f523c9f000-55f523cc1000 r-xp 00000000 08:02 5505067    /sbin/cgmanager" and captures start address and inode.
+	smapsMappingRegexp = regexp.MustCompile(`^([0-9a-f]+)-[0-9a-f]+\s+\S+\s+[0-9a-f]+\s+[0-9a-f]+:[0-9a-f]+\s+([0-9]+)`)
 )
 
+const (
+	smapsReferencedBackend    = "smaps"
+	idlePageReferencedBackend = "idle_page"
+	damonReferencedBackend    = "damon"
+)
+
+// referencedMemoryBackend is backend used to measure referenced bytes, set by --referenced_memory_backend.
+var referencedMemoryBackend = referencedBackend(smapsReferencedBackend)
+
+func init() {
+	flag.Var(&referencedMemoryBackend, "referenced_memory_backend",
+		"Backend used to measure referenced bytes (container_referenced_bytes metric), 'smaps' uses Referenced field of /proc/PID/smaps and resets it through /proc/PID/clear_refs, 'idle_page' uses idle page tracking (/sys/kernel/mm/page_idle/bitmap) which does not perturb kernel page reclaim but requires CAP_SYS_ADMIN, 'damon' uses kernel Data Access MONitor (/sys/kernel/mm/damon) to estimate memory accessed during aggregation interval")
+}
+
+// referencedBackend is flag value which accepts only known backends of referenced bytes.
+type referencedBackend string
+
+func (b *referencedBackend) String() string {
+	return string(*b)
+}
+
+func (b *referencedBackend) Set(value string) error {
+	switch value {
+	case smapsReferencedBackend, idlePageReferencedBackend, damonReferencedBackend:
+		*b = referencedBackend(value)
+		return nil
+	}
+	return fmt.Errorf("unknown referenced memory backend %q, must be one of: %s, %s, %s", value, smapsReferencedBackend, idlePageReferencedBackend, damonReferencedBackend)
+}
+
 type Handler struct {
 	cgroupManager   cgroups.Manager
 	rootFs          string
@@ -57,6 +105,18 @@ type Handler struct {
 	includedMetrics container.MetricSet
 	pidMetricsCache map[int]*info.CpuSchedstat
 	cycles          uint64
+	// Averages of referenced bytes over time windows
+	referencedWindows *referencedWindows
+	// Maximal hugetlb usage by page size observed by cAdvisor, cgroup v2 does not record it.
+	hugetlbMaxUsage map[string]uint64
+	// Busy time of GPU engines by DRM client, kept for clients which were closed.
+	drmClientsCache map[string]map[string]uint64
+	// metricSchedule tracks when metrics with their own intervals are collected.
+	metricSchedule *container.MetricSchedule
+	// Working set estimated by DAMON, used when referenced_memory_backend is set to 'damon'.
+	damon *damonWorkingSet
+	// Stats last returned, stats of metrics which are not due are copied from them.
+	lastStats *info.ContainerStats
 }
 
 func NewHandler(cgroupManager cgroups.Manager, rootFs string, pid int, includedMetrics container.MetricSet) *Handler {
@@ -66,6 +126,12 @@ func NewHandler(cgroupManager cgroups.Manager, rootFs string, pid int, includedM
 		pid:             pid,
 		includedMetrics: includedMetrics,
 		pidMetricsCache: make(map[int]*info.CpuSchedstat),
+
+		referencedWindows: newReferencedWindows(),
+		hugetlbMaxUsage:   make(map[string]uint64),
+		drmClientsCache:   make(map[string]map[string]uint64),
+		metricSchedule:    container.NewMetricSchedule(container.ArgMetricIntervals),
+		damon:             newDamonWorkingSet(defaultDamonMonitor, *damonSampleInterval, *damonAggregationInterval),
 	}
 }
 
@@ -91,6 +157,43 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		CgroupStats: cgroupStats,
 	}
 	stats := newContainerStats(libcontainerStats, h.includedMetrics)
+	if cgroups.IsCgroup2UnifiedMode() && h.includedMetrics.Has(container.HugetlbUsageMetrics) {
+		trackHugetlbMaxUsage(h.hugetlbMaxUsage, stats.Hugetlb)
+	}
+	// runc does not parse memory.numa_stat on cgroup v2.
+	if cgroups.IsCgroup2UnifiedMode() && readCgroupStats && h.includedMetrics.Has(container.MemoryNumaMetrics) {
+		err = setMemoryNumaStatsV2(h.cgroupManager.Path(""), stats)
+		if err != nil {
+			klog.V(4).Infof("Unable to get memory NUMA stats of %s: %v", h.cgroupManager.Path(""), err)
+		}
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		err = setCgroupV2Stats(h.cgroupManager.Path(""), h.rootFs, !readCgroupStats, h.includedMetrics, stats)
+		if err != nil {
+			klog.V(4).Infof("Unable to get cgroup v2 stats of %s: %v", h.cgroupManager.Path(""), err)
+		}
+	}
+	// The root cgroup has no memory controller interface files on cgroup v2.
+	if readCgroupStats && h.includedMetrics.Has(container.OOMMetrics) {
+		err = setOOMKillStats(h.cgroupManager.Path("memory"), cgroups.IsCgroup2UnifiedMode(), stats)
+		if err != nil {
+			klog.V(4).Infof("Unable to get OOM kill stats of %s: %v", h.cgroupManager.Path("memory"), err)
+		}
+	}
+	// runc reads only IO time stats of CFQ scheduler, which was removed in kernel 5.0.
+	if !cgroups.IsCgroup2UnifiedMode() && h.includedMetrics.Has(container.DiskIOMetrics) {
+		err = setBfqDiskIoStats(h.cgroupManager.Path("blkio"), stats)
+		if err != nil {
+			klog.V(4).Infof("Unable to get BFQ IO stats of %s: %v", h.cgroupManager.Path("blkio"), err)
+		}
+	}
+	// Pressure stall information is available only for cgroups of unified hierarchy.
+	if cgroups.IsCgroup2UnifiedMode() && h.includedMetrics.Has(container.PressureMetrics) {
+		err = setPSIStats(h.cgroupManager.Path(""), h.rootFs, !readCgroupStats, stats)
+		if err != nil {
+			klog.V(4).Infof("Unable to get pressure stall information of %s: %v", h.cgroupManager.Path(""), err)
+		}
+	}
 
 	if h.includedMetrics.Has(container.ProcessSchedulerMetrics) {
 		pids, err := h.cgroupManager.GetAllPids()
@@ -104,16 +207,22 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		}
 	}
 
-	if h.includedMetrics.Has(container.ReferencedMemoryMetrics) {
+	if h.includedMetrics.Has(container.GPUEngineMetrics) {
+		pids, err := h.cgroupManager.GetAllPids()
+		if err != nil {
+			klog.V(4).Infof("Could not get PIDs for container %d: %v", h.pid, err)
+		} else {
+			stats.GpuEngineBusyTime = gpuEngineStatsFromProcs(h.rootFs, pids, h.drmClientsCache)
+		}
+	}
+
+	if h.includedMetrics.Has(container.ReferencedMemoryMetrics) && h.due(container.ReferencedMemoryMetrics, stats) {
 		h.cycles++
 		pids, err := h.cgroupManager.GetPids()
 		if err != nil {
 			klog.V(4).Infof("Could not get PIDs for container %d: %v", h.pid, err)
 		} else {
-			stats.ReferencedMemory, err = referencedBytesStat(pids, h.cycles, *referencedResetInterval)
-			if err != nil {
-				klog.V(4).Infof("Unable to get referenced bytes: %v", err)
-			}
+			h.updateReferencedMemoryStats(pids, cgroupStats, stats)
 		}
 	}
 
@@ -165,23 +274,45 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 			} else {
 				stats.Network.Udp6 = u6
 			}
+
+			ua, err := udpAdvancedStatsFromProc(h.rootFs, h.pid, "net/snmp")
+			if err != nil {
+				klog.V(4).Infof("Unable to get udp protocol stats from pid %d: %v", h.pid, err)
+			} else {
+				stats.Network.UdpAdvanced = ua
+			}
+		}
+		if h.includedMetrics.Has(container.ConntrackMetrics) {
+			c, err := conntrackStatsFromProc(h.rootFs, h.pid)
+			if err != nil {
+				klog.V(4).Infof("Unable to get conntrack stats from pid %d: %v", h.pid, err)
+			} else {
+				stats.Network.Conntrack = c
+			}
 		}
 	}
 	// some process metrics are per container ( number of processes, number of
 	// file descriptors etc.) and not required a proper container's
 	// root PID (systemd services don't have the root PID atm)
-	if h.includedMetrics.Has(container.ProcessMetrics) {
+	if h.includedMetrics.Has(container.ProcessMetrics) && h.due(container.ProcessMetrics, stats) {
 		paths := h.cgroupManager.GetPaths()
-		path, ok := paths["cpu"]
+		path, ok := cgroupProcsPath(paths, cgroups.IsCgroup2UnifiedMode())
 		if !ok {
 			klog.V(4).Infof("Could not find cgroups CPU for container %d", h.pid)
 		} else {
-			stats.Processes, err = processStatsFromProcs(h.rootFs, path, h.pid)
+			stats.Processes, err = processStatsFromProcs(h.rootFs, path, h.pid, *fdCountSampleLimit)
 			if err != nil {
 				klog.V(4).Infof("Unable to get Process Stats: %v", err)
 			}
+			if h.includedMetrics.Has(container.ProcessListMetrics) {
+				stats.Processes.TopProcesses, err = topProcessesFromProcs(h.rootFs, path, *processListTopN)
+				if err != nil {
+					klog.V(4).Infof("Unable to get top processes: %v", err)
+				}
+			}
 		}
-
+	}
+	if h.includedMetrics.Has(container.ProcessMetrics) {
 		// if include processes metrics, just set threads metrics if exist, and has no relationship with cpu path
 		setThreadsStats(cgroupStats, stats)
 	}
@@ -191,9 +322,90 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		stats.Network.InterfaceStats = stats.Network.Interfaces[0]
 	}
 
+	h.lastStats = stats
 	return stats, nil
 }
 
+// due returns true if stats of the metric kind should be collected now, otherwise the stats
+// returned last time are copied to stats.
+func (h *Handler) due(kind container.MetricKind, stats *info.ContainerStats) bool {
+	if h.metricSchedule.Due(kind, time.Now()) || h.lastStats == nil {
+		return true
+	}
+	last := h.lastStats
+	switch kind {
+	case container.ReferencedMemoryMetrics:
+		stats.ReferencedMemory = last.ReferencedMemory
+		stats.ReferencedMemoryApproximate = last.ReferencedMemoryApproximate
+		stats.ReferencedMemoryPartial = last.ReferencedMemoryPartial
+		stats.ReferencedMemoryByNode = last.ReferencedMemoryByNode
+		stats.ReferencedMemoryAccessHistogram = last.ReferencedMemoryAccessHistogram
+		stats.ReferencedMemoryAnon = last.ReferencedMemoryAnon
+		stats.ReferencedMemoryFile = last.ReferencedMemoryFile
+		stats.ReferencedMemoryWindows = last.ReferencedMemoryWindows
+	case container.ProcessMetrics:
+		stats.Processes = last.Processes
+	}
+	return false
+}
+
+// updateReferencedMemoryStats sets referenced bytes and their averages over time windows, averages
+// are not updated until referenced bytes are measured for the first time.
+func (h *Handler) updateReferencedMemoryStats(pids []int, cgroupStats *cgroups.Stats, stats *info.ContainerStats) {
+	measured, err := h.setReferencedMemoryStats(pids, cgroupStats, stats)
+	if err != nil {
+		klog.V(4).Infof("Unable to get referenced bytes: %v", err)
+		return
+	}
+	if measured {
+		windows := h.referencedWindows.update(time.Now(), stats.ReferencedMemory)
+		stats.ReferencedMemoryWindows = &windows
+	}
+}
+
+// setReferencedMemoryStats sets referenced bytes measured by configured backend, it returns false
+// if they were not measured yet, e.g. DAMON measures working sets in background.
+func (h *Handler) setReferencedMemoryStats(pids []int, cgroupStats *cgroups.Stats, stats *info.ContainerStats) (bool, error) {
+	var err error
+	switch referencedMemoryBackend {
+	case idlePageReferencedBackend:
+		stats.ReferencedMemory, err = idlePageReferencedBytesStat(pids, h.cycles, *referencedResetInterval)
+		return err == nil, err
+	case damonReferencedBackend:
+		damon, measured, err := h.damon.update(pids)
+		if err != nil || !measured {
+			return false, err
+		}
+		stats.ReferencedMemory = damon.bytes
+		stats.ReferencedMemoryAccessHistogram = damon.accessHistogram
+		return true, nil
+	}
+
+	details := referencedDetails{
+		perNode:   h.includedMetrics.Has(container.MemoryNumaMetrics),
+		breakdown: *referencedMemoryBreakdown,
+	}
+	referenced, err := referencedBytesStat(pids, h.cycles, *referencedResetInterval, details)
+	if err != nil {
+		return false, err
+	}
+	stats.ReferencedMemory = referenced.bytes
+	stats.ReferencedMemoryPartial = referenced.partial
+	if details.perNode && referenced.found {
+		stats.ReferencedMemoryByNode = referenced.bytesPerNode
+	}
+	if details.breakdown && referenced.found {
+		stats.ReferencedMemoryAnon = referenced.anonBytes
+		stats.ReferencedMemoryFile = referenced.fileBytes
+	}
+	if !referenced.found && len(pids) != 0 {
+		// smaps do not provide Referenced field (e.g. stripped by hardened kernel config),
+		// estimate referenced bytes from cgroup memory statistics instead.
+		stats.ReferencedMemory, stats.ReferencedMemoryApproximate = referencedBytesFromMemoryStats(cgroupStats)
+	}
+	return true, nil
+}
+
 func parseUlimit(value string) (int64, error) {
 	num, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
@@ -263,7 +475,22 @@ func processRootProcUlimits(rootFs string, rootPid int) []info.UlimitSpec {
 	return processLimitsFile(string(out))
 }
 
-func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.ProcessStats, error) {
+// cgroupProcsPath returns path to cgroup which cgroup.procs file lists processes of container.
+// On cgroup v1 cpu hierarchy is used, on cgroup v2 (unified hierarchy) there is single
+// cgroup path shared by all controllers.
+func cgroupProcsPath(paths map[string]string, unified bool) (string, bool) {
+	if unified {
+		path, ok := paths[""]
+		return path, ok
+	}
+	path, ok := paths["cpu"]
+	return path, ok
+}
+
+// processStatsFromProcs counts processes of cgroup and their open file descriptors and sockets.
+// When sampleLimit is greater than zero, file descriptors of at most sampleLimit processes are listed
+// and the counts are scaled to the number of all processes.
+func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int, sampleLimit int) (info.ProcessStats, error) {
 	var fdCount, socketCount uint64
 	filePath := path.Join(cgroupPath, "cgroup.procs")
 	out, err := ioutil.ReadFile(filePath)
@@ -280,7 +507,11 @@ func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.
 		pids = pids[:len(pids)-1]
 	}
 
-	for _, pid := range pids {
+	sampled := pids
+	if sampleLimit > 0 && len(pids) > sampleLimit {
+		sampled = pids[:sampleLimit]
+	}
+	for _, pid := range sampled {
 		dirPath := path.Join(rootFs, "/proc", pid, "fd")
 		fds, err := ioutil.ReadDir(dirPath)
 		if err != nil {
@@ -301,6 +532,11 @@ func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.
 		}
 	}
 
+	if len(sampled) < len(pids) {
+		fdCount = fdCount * uint64(len(pids)) / uint64(len(sampled))
+		socketCount = socketCount * uint64(len(pids)) / uint64(len(sampled))
+	}
+
 	processStats := info.ProcessStats{
 		ProcessCount: uint64(len(pids)),
 		FdCount:      fdCount,
@@ -358,64 +594,324 @@ func schedulerStatsFromProcs(rootFs string, pids []int, pidMetricsCache map[int]
 	return schedstats, nil
 }
 
+// referencedStat holds referenced memory read from smaps files of container processes.
+type referencedStat struct {
+	// Referenced memory in bytes.
+	bytes uint64
+	// Whether information about referenced bytes was found in smaps of any process.
+	found bool
+	// Whether reading of smaps exceeded time budget, only part of processes is accounted then.
+	partial bool
+	// PIDs which smaps were read, referenced bytes of other processes are not cleared.
+	pids []int
+	// Referenced memory in bytes per NUMA node, when requested by referencedDetails.
+	bytesPerNode map[uint8]uint64
+	// Referenced memory in bytes of anonymous and file-backed mappings, when requested by referencedDetails.
+	anonBytes uint64
+	fileBytes uint64
+}
+
+// referencedDetails selects details of referenced memory read together with referenced bytes.
+// They need referenced bytes of each mapping, so smaps are read instead of smaps_rollup.
+type referencedDetails struct {
+	// Whether referenced bytes are split between NUMA nodes.
+	perNode bool
+	// Whether referenced bytes are split into anonymous and file-backed mappings.
+	breakdown bool
+}
+
+func (d referencedDetails) needMappings() bool {
+	return d.perNode || d.breakdown
+}
+
 // referencedBytesStat gets and clears referenced bytes
 // see: https://github.com/brendangregg/wss#wsspl-referenced-page-flag
-func referencedBytesStat(pids []int, cycles uint64, resetInterval uint64) (uint64, error) {
-	referencedKBytes, err := getReferencedKBytes(pids)
+func referencedBytesStat(pids []int, cycles uint64, resetInterval uint64, details referencedDetails) (referencedStat, error) {
+	referenced, err := getReferencedBytes(pids, *referencedReadConcurrency, *referencedReadBudget, details)
 	if err != nil {
-		return uint64(0), err
+		return referencedStat{}, err
 	}
 
-	err = clearReferencedBytes(pids, cycles, resetInterval)
+	err = clearReferencedBytes(referenced.pids, cycles, resetInterval)
 	if err != nil {
-		return uint64(0), err
+		return referencedStat{}, err
 	}
-	return referencedKBytes * 1024, nil
+	return referenced, nil
 }
 
-func getReferencedKBytes(pids []int) (uint64, error) {
-	referencedKBytes := uint64(0)
-	readSmapsContent := false
-	foundMatch := false
-	for _, pid := range pids {
-		smapsFilePath := fmt.Sprintf(smapsFilePathPattern, pid)
-		smapsContent, err := ioutil.ReadFile(smapsFilePath)
-		if err != nil {
-			klog.V(5).Infof("Cannot read %s file, err: %s", smapsFilePath, err)
-			if os.IsNotExist(err) {
-				continue //smaps file does not exists for all PIDs
+// pidReferenced holds referenced memory read from smaps of single process.
+type pidReferenced struct {
+	pid          int
+	kBytes       uint64
+	bytesPerNode map[uint8]uint64
+	anonKBytes   uint64
+	fileKBytes   uint64
+	readSmaps    bool
+	found        bool
+	err          error
+}
+
+// getReferencedBytes reads smaps of given PIDs using up to concurrency workers. When budget is
+// greater than zero, reading is cancelled when budget is exceeded, processes which smaps were not
+// read within budget are not accounted and returned stat is marked as partial. Details of referenced
+// memory are read in the same pass, before referenced bytes are cleared.
+func getReferencedBytes(pids []int, concurrency int, budget time.Duration, details referencedDetails) (referencedStat, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(pids) {
+		concurrency = len(pids)
+	}
+	ctx := context.Background()
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
+	pidsToRead := make(chan int)
+	// Results are buffered, so that workers do not block when reading is cancelled.
+	results := make(chan pidReferenced, len(pids))
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for pid := range pidsToRead {
+				if ctx.Err() != nil {
+					continue
+				}
+				result := getPidReferencedKBytes(pid, details)
+				result.pid = pid
+				results <- result
 			}
-			return 0, err
-		}
-		readSmapsContent = true
+		}()
+	}
 
-		allMatches := referencedRegexp.FindAllSubmatch(smapsContent, -1)
-		if len(allMatches) == 0 {
-			klog.V(5).Infof("Not found any information about referenced bytes in %s file", smapsFilePath)
-			continue // referenced bytes may not exist in smaps file
+	stat := referencedStat{}
+	if details.perNode {
+		stat.bytesPerNode = make(map[uint8]uint64)
+	}
+	dispatched := 0
+dispatch:
+	for _, pid := range pids {
+		// Check budget first, select chooses randomly between channels which are ready.
+		select {
+		case <-ctx.Done():
+			stat.partial = true
+			break dispatch
+		default:
 		}
+		select {
+		case pidsToRead <- pid:
+			dispatched++
+		case <-ctx.Done():
+			stat.partial = true
+			break dispatch
+		}
+	}
+	close(pidsToRead)
 
-		for _, matches := range allMatches {
-			if len(matches) != 2 {
-				return 0, fmt.Errorf("failed to match regexp in output: %s", string(smapsContent))
+	readSmapsContent := false
+	referencedKBytes := uint64(0)
+collect:
+	for i := 0; i < dispatched; i++ {
+		select {
+		case result := <-results:
+			if result.err != nil {
+				return referencedStat{}, result.err
 			}
-			foundMatch = true
-			referenced, err := strconv.ParseUint(string(matches[1]), 10, 64)
-			if err != nil {
-				return 0, err
+			if result.readSmaps {
+				stat.pids = append(stat.pids, result.pid)
+			}
+			readSmapsContent = readSmapsContent || result.readSmaps
+			stat.found = stat.found || result.found
+			referencedKBytes += result.kBytes
+			stat.anonBytes += result.anonKBytes * 1024
+			stat.fileBytes += result.fileKBytes * 1024
+			for node, nodeBytes := range result.bytesPerNode {
+				stat.bytesPerNode[node] += nodeBytes
 			}
-			referencedKBytes += referenced
+		case <-ctx.Done():
+			// Smaps which are still being read are not accounted.
+			stat.partial = true
+			break collect
 		}
 	}
+	stat.bytes = referencedKBytes * 1024
 
-	if len(pids) != 0 {
+	if stat.partial {
+		klog.V(4).Infof("Reading smaps files exceeded time budget of %v, referenced bytes are partial", budget)
+	}
+	if len(pids) != 0 && !stat.partial {
 		if !readSmapsContent {
 			klog.Warningf("Cannot read smaps files for any PID from %s", "CONTAINER")
-		} else if !foundMatch {
+		} else if !stat.found {
 			klog.Warningf("Not found any information about referenced bytes in smaps files for any PID from %s", "CONTAINER")
 		}
 	}
-	return referencedKBytes, nil
+	return stat, nil
+}
+
+func getPidReferencedKBytes(pid int, details referencedDetails) pidReferenced {
+	if details.needMappings() {
+		return getPidMappingsReferencedKBytes(pid, details)
+	}
+	smapsFilePath, smapsContent, err := readSmaps(pid)
+	if err != nil {
+		klog.V(5).Infof("Cannot read %s file, err: %s", smapsFilePath, err)
+		if os.IsNotExist(err) {
+			return pidReferenced{} //smaps file does not exists for all PIDs
+		}
+		return pidReferenced{err: err}
+	}
+	result := pidReferenced{readSmaps: true}
+
+	allMatches := referencedRegexp.FindAllSubmatch(smapsContent, -1)
+	if len(allMatches) == 0 {
+		klog.V(5).Infof("Not found any information about referenced bytes in %s file", smapsFilePath)
+		return result // referenced bytes may not exist in smaps file
+	}
+
+	for _, matches := range allMatches {
+		if len(matches) != 2 {
+			return pidReferenced{err: fmt.Errorf("failed to match regexp in output: %s", string(smapsContent))}
+		}
+		result.found = true
+		referenced, err := strconv.ParseUint(string(matches[1]), 10, 64)
+		if err != nil {
+			return pidReferenced{err: err}
+		}
+		result.kBytes += referenced
+	}
+	return result
+}
+
+// getPidMappingsReferencedKBytes reads referenced kilobytes of each mapping from smaps of given PID and
+// sums them up together with requested details. smaps_rollup sums up all mappings, so it cannot be used.
+func getPidMappingsReferencedKBytes(pid int, details referencedDetails) pidReferenced {
+	smapsFilePath := fmt.Sprintf(smapsFilePathPattern, pid)
+	smapsContent, err := ioutil.ReadFile(smapsFilePath)
+	if err != nil {
+		klog.V(5).Infof("Cannot read %s file, err: %s", smapsFilePath, err)
+		if os.IsNotExist(err) {
+			return pidReferenced{} //smaps file does not exists for all PIDs
+		}
+		return pidReferenced{err: err}
+	}
+	mappings, err := parseSmapsMappings(smapsContent)
+	if err != nil {
+		return pidReferenced{err: err}
+	}
+	result := pidReferenced{readSmaps: true}
+	for _, referenced := range mappings.kBytes {
+		result.found = true
+		result.kBytes += referenced
+	}
+	if !result.found {
+		klog.V(5).Infof("Not found any information about referenced bytes in %s file", smapsFilePath)
+		return result // referenced bytes may not exist in smaps file
+	}
+
+	if details.perNode {
+		result.bytesPerNode, err = getReferencedBytesPerNode(pid, mappings.kBytes)
+		if err != nil {
+			klog.V(4).Infof("Unable to get referenced bytes per NUMA node of PID %d: %v", pid, err)
+		}
+	}
+	if details.breakdown {
+		result.anonKBytes = mappings.anonKBytes
+		result.fileKBytes = mappings.fileKBytes
+	}
+	return result
+}
+
+// smapsMappings holds referenced kilobytes of mappings read from smaps file.
+type smapsMappings struct {
+	// Referenced kilobytes of each mapping identified by start address.
+	kBytes map[string]uint64
+	// Referenced kilobytes of anonymous mappings, e.g. heap and stack, which have inode equal to 0.
+	anonKBytes uint64
+	// Referenced kilobytes of file-backed mappings, i.e. page cache.
+	fileKBytes uint64
+}
+
+func parseSmapsMappings(smapsContent []byte) (smapsMappings, error) {
+	mappings := smapsMappings{kBytes: make(map[string]uint64)}
+	mapping, anon := "", false
+	scanner := bufio.NewScanner(bytes.NewReader(smapsContent))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if matches := smapsMappingRegexp.FindSubmatch(line); len(matches) == 3 {
+			mapping = string(matches[1])
+			anon = string(matches[2]) == "0"
+			continue
+		}
+		matches := referencedRegexp.FindSubmatch(line)
+		if len(matches) != 2 || mapping == "" {
+			continue
+		}
+		referenced, err := strconv.ParseUint(string(matches[1]), 10, 64)
+		if err != nil {
+			return smapsMappings{}, err
+		}
+		mappings.kBytes[mapping] += referenced
+		if anon {
+			mappings.anonKBytes += referenced
+		} else {
+			mappings.fileKBytes += referenced
+		}
+	}
+	return mappings, scanner.Err()
+}
+
+// readSmaps returns path and content of smaps_rollup file for given PID, which sums up all
+// mappings and is much cheaper to generate by kernel than smaps (available since kernel 4.14).
+// Content of smaps file is returned when smaps_rollup is not available.
+func readSmaps(pid int) (string, []byte, error) {
+	smapsRollupFilePath := fmt.Sprintf(smapsRollupFilePathPattern, pid)
+	smapsRollupContent, err := ioutil.ReadFile(smapsRollupFilePath)
+	if err == nil {
+		return smapsRollupFilePath, smapsRollupContent, nil
+	}
+	if !os.IsNotExist(err) {
+		return smapsRollupFilePath, nil, err
+	}
+
+	smapsFilePath := fmt.Sprintf(smapsFilePathPattern, pid)
+	smapsContent, err := ioutil.ReadFile(smapsFilePath)
+	return smapsFilePath, smapsContent, err
+}
+
+// referencedBytesFromMemoryStats estimates referenced bytes from cgroup memory statistics,
+// active part of page cache and anonymous memory is used as an approximation of working set.
+// It returns false if estimation is not possible.
+func referencedBytesFromMemoryStats(cgroupStats *cgroups.Stats) (uint64, bool) {
+	if cgroupStats == nil {
+		return 0, false
+	}
+	memoryStats := cgroupStats.MemoryStats.Stats
+	// total_* fields are provided by cgroup v1 and include whole hierarchy.
+	for _, prefix := range []string{"total_", ""} {
+		activeAnon, anonFound := memoryStats[prefix+"active_anon"]
+		activeFile, fileFound := memoryStats[prefix+"active_file"]
+		if anonFound || fileFound {
+			return activeAnon + activeFile, true
+		}
+	}
+
+	// Fall back to memory usage (memory.current on cgroup v2) without inactive page cache.
+	usage := cgroupStats.MemoryStats.Usage.Usage
+	if usage == 0 {
+		return 0, false
+	}
+	inactiveFile, ok := memoryStats["total_inactive_file"]
+	if !ok {
+		inactiveFile = memoryStats["inactive_file"]
+	}
+	if inactiveFile > usage {
+		// Counters are not read atomically, so they may be inconsistent, e.g. when page cache
+		// is reclaimed meanwhile. Nothing can be estimated from them.
+		return 0, false
+	}
+	return usage - inactiveFile, true
 }
 
 func clearReferencedBytes(pids []int, cycles uint64, resetInterval uint64) error {
@@ -750,6 +1246,70 @@ func scanUDPStats(r io.Reader) (info.UdpStat, error) {
 	return stats, nil
 }
 
+func udpAdvancedStatsFromProc(rootFs string, pid int, file string) (info.UdpAdvancedStat, error) {
+	snmpFile := path.Join(rootFs, "proc", strconv.Itoa(pid), file)
+
+	r, err := os.Open(snmpFile)
+	if err != nil {
+		return info.UdpAdvancedStat{}, fmt.Errorf("failure opening %s: %v", snmpFile, err)
+	}
+	defer r.Close()
+
+	stats, err := scanUDPAdvancedStats(r)
+	if err != nil {
+		return stats, fmt.Errorf("couldn't read udp protocol stats from %s: %v", snmpFile, err)
+	}
+	return stats, nil
+}
+
+// scanUDPAdvancedStats reads counters of Udp protocol from snmp file, it consists of pairs of lines
+// with names and values of counters, e.g.
+// Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors InCsumErrors
+// Udp: 1024 3 0 1000 0 0 0
+func scanUDPAdvancedStats(r io.Reader) (info.UdpAdvancedStat, error) {
+	var stats info.UdpAdvancedStat
+	counters := map[string]*uint64{
+		"InDatagrams":  &stats.InDatagrams,
+		"NoPorts":      &stats.NoPorts,
+		"InErrors":     &stats.InErrors,
+		"OutDatagrams": &stats.OutDatagrams,
+		"RcvbufErrors": &stats.RcvbufErrors,
+		"SndbufErrors": &stats.SndbufErrors,
+		"InCsumErrors": &stats.InCsumErrors,
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		names := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		values := strings.Fields(scanner.Text())
+		if len(names) == 0 || names[0] != "Udp:" {
+			continue
+		}
+		if len(names) != len(values) {
+			return stats, fmt.Errorf("mismatch of Udp field count: %d names, %d values", len(names), len(values))
+		}
+		for i := 1; i < len(names); i++ {
+			counter, ok := counters[names[i]]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseUint(values[i], 10, 64)
+			if err != nil {
+				return stats, fmt.Errorf("decode value of %s: %v", names[i], err)
+			}
+			*counter = value
+		}
+		return stats, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	return stats, fmt.Errorf("no Udp counters found")
+}
+
 func (h *Handler) GetProcesses() ([]int, error) {
 	pids, err := h.cgroupManager.GetPids()
 	if err != nil {
@@ -834,7 +1394,13 @@ func setMemoryStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	ret.Memory.MaxUsage = s.MemoryStats.Usage.MaxUsage
 	ret.Memory.Failcnt = s.MemoryStats.Usage.Failcnt
 
-	if s.MemoryStats.UseHierarchy {
+	if cgroups.IsCgroup2UnifiedMode() {
+		ret.Memory.Cache = s.MemoryStats.Stats["file"]
+		ret.Memory.RSS = s.MemoryStats.Stats["anon"]
+		ret.Memory.MappedFile = s.MemoryStats.Stats["file_mapped"]
+		setMemoryReclaimStatsV2(s.MemoryStats.Stats, ret)
+		// Swap usage is read from memory.swap.current by setCgroupV2Stats.
+	} else if s.MemoryStats.UseHierarchy {
 		ret.Memory.Cache = s.MemoryStats.Stats["total_cache"]
 		ret.Memory.RSS = s.MemoryStats.Stats["total_rss"]
 		ret.Memory.Swap = s.MemoryStats.Stats["total_swap"]
@@ -870,6 +1436,75 @@ func setMemoryStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	ret.Memory.WorkingSet = workingSet
 }
 
+// setOOMKillStats sets number of processes killed by OOM killer read from memory.events on cgroup v2
+// and from memory.oom_control on cgroup v1, which reports oom_kill since kernel 4.13.
+func setOOMKillStats(memoryPath string, cgroup2UnifiedMode bool, ret *info.ContainerStats) error {
+	file := "memory.oom_control"
+	if cgroup2UnifiedMode {
+		file = "memory.events"
+	}
+	stats, err := readFlatKeyedFile(path.Join(memoryPath, file))
+	if err != nil {
+		return err
+	}
+	ret.Memory.OOMKills = stats["oom_kill"]
+	return nil
+}
+
+// setBfqDiskIoStats sets time IO requests spent being serviced and waiting in scheduler queues and
+// number of queued IO requests from blkio.bfq.*_recursive files of BFQ scheduler, unless runc already
+// set them from files of CFQ scheduler. BFQ reports them only if kernel is built with
+// CONFIG_BFQ_CGROUP_DEBUG, files are missing otherwise.
+func setBfqDiskIoStats(blkioPath string, ret *info.ContainerStats) error {
+	for file, stats := range map[string]*[]info.PerDiskStats{
+		"blkio.bfq.io_service_time_recursive": &ret.DiskIo.IoServiceTime,
+		"blkio.bfq.io_wait_time_recursive":    &ret.DiskIo.IoWaitTime,
+		"blkio.bfq.io_queued_recursive":       &ret.DiskIo.IoQueued,
+	} {
+		if len(*stats) > 0 {
+			continue
+		}
+		entries, err := readBlkioStatFile(path.Join(blkioPath, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		*stats = DiskStatsCopy(entries)
+	}
+	return nil
+}
+
+// readBlkioStatFile reads blkio file with values by device and operation, e.g. "8:0 Read 4096",
+// the last line with total value of all devices is skipped.
+func readBlkioStatFile(filePath string) ([]cgroups.BlkioStatEntry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []cgroups.BlkioStatEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		var major, minor uint64
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &major, &minor); err != nil {
+			return nil, fmt.Errorf("failed to parse device of %s line %q: %v", filePath, scanner.Text(), err)
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %q: %v", filePath, scanner.Text(), err)
+		}
+		entries = append(entries, cgroups.BlkioStatEntry{Major: major, Minor: minor, Op: fields[1], Value: value})
+	}
+	return entries, scanner.Err()
+}
+
 func getNumaStats(memoryStats map[uint8]uint64) map[uint8]uint64 {
 	stats := make(map[uint8]uint64, len(memoryStats))
 	for node, usage := range memoryStats {
@@ -888,6 +1523,49 @@ func setMemoryNumaStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	ret.Memory.HierarchicalData.NumaStats.Unevictable = getNumaStats(s.MemoryStats.PageUsageByNUMA.Hierarchical.Unevictable.Nodes)
 }
 
+// setMemoryNumaStatsV2 sets NUMA stats from memory.numa_stat of cgroup v2, which reports bytes
+// per node (e.g. "anon N0=40960000 N1=29118464"), converted to pages for consistency with cgroup v1.
+// Stats of cgroup v2 are hierarchical, so they are reported for both container and hierarchy.
+func setMemoryNumaStatsV2(cgroupPath string, ret *info.ContainerStats) error {
+	file, err := os.Open(path.Join(cgroupPath, "memory.numa_stat"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var stats *map[uint8]uint64
+		var hierarchicalStats *map[uint8]uint64
+		switch fields[0] {
+		case "anon":
+			stats, hierarchicalStats = &ret.Memory.ContainerData.NumaStats.Anon, &ret.Memory.HierarchicalData.NumaStats.Anon
+		case "file":
+			stats, hierarchicalStats = &ret.Memory.ContainerData.NumaStats.File, &ret.Memory.HierarchicalData.NumaStats.File
+		case "unevictable":
+			stats, hierarchicalStats = &ret.Memory.ContainerData.NumaStats.Unevictable, &ret.Memory.HierarchicalData.NumaStats.Unevictable
+		default:
+			continue
+		}
+		*stats = make(map[uint8]uint64, len(fields)-1)
+		for _, nodeStat := range fields[1:] {
+			var node uint8
+			var bytes uint64
+			_, err := fmt.Sscanf(nodeStat, "N%d=%d", &node, &bytes)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q of memory.numa_stat: %v", nodeStat, err)
+			}
+			(*stats)[node] = bytes / pageSize
+		}
+		*hierarchicalStats = getNumaStats(*stats)
+	}
+	return scanner.Err()
+}
+
 func setHugepageStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	ret.Hugetlb = make(map[string]info.HugetlbStats)
 	for k, v := range s.HugetlbStats {
@@ -899,6 +1577,18 @@ func setHugepageStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	}
 }
 
+// trackHugetlbMaxUsage sets maximal hugetlb usage to the highest usage observed so far, as cgroup v2
+// exposes only current usage (hugetlb.<size>.current).
+func trackHugetlbMaxUsage(maxUsage map[string]uint64, hugetlb map[string]info.HugetlbStats) {
+	for pageSize, stats := range hugetlb {
+		if stats.Usage > maxUsage[pageSize] {
+			maxUsage[pageSize] = stats.Usage
+		}
+		stats.MaxUsage = maxUsage[pageSize]
+		hugetlb[pageSize] = stats
+	}
+}
+
 func setNetworkStats(libcontainerStats *libcontainer.Stats, ret *info.ContainerStats) {
 	ret.Network.Interfaces = make([]info.InterfaceStats, len(libcontainerStats.Interfaces))
 	for i := range libcontainerStats.Interfaces {
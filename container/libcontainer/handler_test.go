@@ -17,7 +17,9 @@ package libcontainer
 import (
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -90,6 +92,31 @@ func TestScanUDPStats(t *testing.T) {
 	}
 }
 
+func TestScanUDPAdvancedStats(t *testing.T) {
+	r, err := os.Open("testdata/procnetsnmp")
+	if err != nil {
+		t.Fatalf("failure opening testdata/procnetsnmp: %v", err)
+	}
+	defer r.Close()
+
+	stats, err := scanUDPAdvancedStats(r)
+	assert.Nil(t, err)
+	assert.Equal(t, info.UdpAdvancedStat{
+		InDatagrams:  89345,
+		NoPorts:      112,
+		InErrors:     7,
+		OutDatagrams: 90123,
+		RcvbufErrors: 5,
+		SndbufErrors: 2,
+		InCsumErrors: 0,
+	}, stats)
+}
+
+func TestScanUDPAdvancedStatsMissing(t *testing.T) {
+	_, err := scanUDPAdvancedStats(strings.NewReader("Tcp: RtoAlgorithm RtoMin\nTcp: 1 200\n"))
+	assert.NotNil(t, err)
+}
+
 // https://github.com/docker/libcontainer/blob/v2.2.1/cgroups/fs/cpuacct.go#L19
 const nanosecondsInSeconds = 1000000000
 
@@ -178,6 +205,75 @@ func TestSetProcessesStats(t *testing.T) {
 
 }
 
+func TestTrackHugetlbMaxUsage(t *testing.T) {
+	maxUsage := map[string]uint64{}
+	hugetlb := map[string]info.HugetlbStats{
+		"2MB": {Usage: 4 * 1024 * 1024},
+		"1GB": {Usage: 0},
+	}
+	trackHugetlbMaxUsage(maxUsage, hugetlb)
+	assert.Equal(t, uint64(4*1024*1024), hugetlb["2MB"].MaxUsage)
+	assert.Equal(t, uint64(0), hugetlb["1GB"].MaxUsage)
+
+	hugetlb = map[string]info.HugetlbStats{
+		"2MB": {Usage: 2 * 1024 * 1024},
+		"1GB": {Usage: 1024 * 1024 * 1024},
+	}
+	trackHugetlbMaxUsage(maxUsage, hugetlb)
+	assert.Equal(t, uint64(4*1024*1024), hugetlb["2MB"].MaxUsage)
+	assert.Equal(t, uint64(1024*1024*1024), hugetlb["1GB"].MaxUsage)
+}
+
+func TestSetMemoryNumaStatsV2(t *testing.T) {
+	stats := &info.ContainerStats{}
+	err := setMemoryNumaStatsV2("testdata/cgroupv2/system.slice/test.service", stats)
+	assert.Nil(t, err)
+
+	expected := info.MemoryNumaStats{
+		Anon:        map[uint8]uint64{0: 40960000 / pageSize, 1: 29118464 / pageSize},
+		File:        map[uint8]uint64{0: 68194304 / pageSize, 1: 0},
+		Unevictable: map[uint8]uint64{0: 36454400 / pageSize, 1: 0},
+	}
+	assert.Equal(t, expected, stats.Memory.ContainerData.NumaStats)
+	assert.Equal(t, expected, stats.Memory.HierarchicalData.NumaStats)
+}
+
+func TestSetOOMKillStats(t *testing.T) {
+	stats := &info.ContainerStats{}
+	err := setOOMKillStats("testdata/cgroupv2/system.slice/test.service", true, stats)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), stats.Memory.OOMKills)
+
+	stats = &info.ContainerStats{}
+	err = setOOMKillStats("testdata/oom", false, stats)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), stats.Memory.OOMKills)
+
+	err = setOOMKillStats("testdata/does-not-exist", false, stats)
+	assert.NotNil(t, err)
+}
+
+func TestSetBfqDiskIoStats(t *testing.T) {
+	stats := &info.ContainerStats{}
+	// Service time read by runc from files of CFQ scheduler is kept.
+	stats.DiskIo.IoServiceTime = []info.PerDiskStats{{Major: 8, Minor: 0, Stats: map[string]uint64{"Total": 1}}}
+	err := setBfqDiskIoStats("testdata/blkio_bfq", stats)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.PerDiskStats{{Major: 8, Minor: 0, Stats: map[string]uint64{"Total": 1}}}, stats.DiskIo.IoServiceTime)
+	assert.Equal(t, []info.PerDiskStats{{Major: 8, Minor: 0, Stats: map[string]uint64{
+		"Read": 300000, "Write": 100000, "Sync": 350000, "Async": 50000, "Total": 400000,
+	}}}, stats.DiskIo.IoWaitTime)
+	assert.Equal(t, []info.PerDiskStats{{Major: 8, Minor: 0, Stats: map[string]uint64{
+		"Read": 2, "Write": 1, "Sync": 3, "Async": 0, "Total": 3,
+	}}}, stats.DiskIo.IoQueued)
+
+	// Files are missing when kernel is built without CONFIG_BFQ_CGROUP_DEBUG.
+	stats = &info.ContainerStats{}
+	err = setBfqDiskIoStats("testdata/does-not-exist", stats)
+	assert.Nil(t, err)
+	assert.Nil(t, stats.DiskIo.IoWaitTime)
+}
+
 func TestParseLimitsFile(t *testing.T) {
 	var testData = []struct {
 		limitLine string
@@ -220,12 +316,15 @@ func TestParseLimitsFile(t *testing.T) {
 func TestReferencedBytesStat(t *testing.T) {
 	//overwrite package variables
 	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
 	clearRefsFilePathPattern = "testdata/clear_refs%d"
 
 	pids := []int{4, 6, 8}
-	stat, err := referencedBytesStat(pids, 1, 3)
+	stat, err := referencedBytesStat(pids, 1, 3, referencedDetails{})
 	assert.Nil(t, err)
-	assert.Equal(t, uint64(416*1024), stat)
+	assert.True(t, stat.found)
+	assert.False(t, stat.partial)
+	assert.Equal(t, uint64(416*1024), stat.bytes)
 
 	clearRefsFiles := []string{
 		"testdata/clear_refs4",
@@ -241,12 +340,15 @@ func TestReferencedBytesStat(t *testing.T) {
 func TestReferencedBytesStatWhenNeverCleared(t *testing.T) {
 	//overwrite package variables
 	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
 	clearRefsFilePathPattern = "testdata/clear_refs%d"
 
 	pids := []int{4, 6, 8}
-	stat, err := referencedBytesStat(pids, 1, 0)
+	stat, err := referencedBytesStat(pids, 1, 0, referencedDetails{})
 	assert.Nil(t, err)
-	assert.Equal(t, uint64(416*1024), stat)
+	assert.True(t, stat.found)
+	assert.False(t, stat.partial)
+	assert.Equal(t, uint64(416*1024), stat.bytes)
 
 	clearRefsFiles := []string{
 		"testdata/clear_refs4",
@@ -262,12 +364,15 @@ func TestReferencedBytesStatWhenNeverCleared(t *testing.T) {
 func TestReferencedBytesStatWhenResetIsNeeded(t *testing.T) {
 	//overwrite package variables
 	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
 	clearRefsFilePathPattern = "testdata/clear_refs%d"
 
 	pids := []int{4, 6, 8}
-	stat, err := referencedBytesStat(pids, 1, 1)
+	stat, err := referencedBytesStat(pids, 1, 1, referencedDetails{})
 	assert.Nil(t, err)
-	assert.Equal(t, uint64(416*1024), stat)
+	assert.True(t, stat.found)
+	assert.False(t, stat.partial)
+	assert.Equal(t, uint64(416*1024), stat.bytes)
 
 	clearRefsFiles := []string{
 		"testdata/clear_refs4",
@@ -285,11 +390,196 @@ func TestReferencedBytesStatWhenResetIsNeeded(t *testing.T) {
 func TestGetReferencedKBytesWhenSmapsMissing(t *testing.T) {
 	//overwrite package variable
 	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
 
 	pids := []int{10}
-	referenced, err := getReferencedKBytes(pids)
+	referenced, err := getReferencedBytes(pids, 1, 0, referencedDetails{})
 	assert.Nil(t, err)
-	assert.Equal(t, uint64(0), referenced)
+	assert.False(t, referenced.found)
+	assert.Equal(t, uint64(0), referenced.bytes)
+}
+
+func TestReferencedBytesFallbackWhenSmapsLackReferenced(t *testing.T) {
+	//overwrite package variable
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+
+	pids := []int{12}
+	referenced, err := getReferencedBytes(pids, 1, 0, referencedDetails{})
+	assert.Nil(t, err)
+	assert.False(t, referenced.found)
+	assert.Equal(t, uint64(0), referenced.bytes)
+
+	cgroupStats := &cgroups.Stats{
+		MemoryStats: cgroups.MemoryStats{
+			Usage: cgroups.MemoryData{Usage: 1048576},
+			Stats: map[string]uint64{
+				"total_active_anon":   262144,
+				"total_active_file":   131072,
+				"total_inactive_file": 65536,
+			},
+		},
+	}
+	estimated, ok := referencedBytesFromMemoryStats(cgroupStats)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(262144+131072), estimated)
+
+	// cgroup v2 memory.stat without active lists
+	cgroupStats = &cgroups.Stats{
+		MemoryStats: cgroups.MemoryStats{
+			Usage: cgroups.MemoryData{Usage: 1048576},
+			Stats: map[string]uint64{
+				"inactive_file": 65536,
+			},
+		},
+	}
+	estimated, ok = referencedBytesFromMemoryStats(cgroupStats)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1048576-65536), estimated)
+
+	// Inactive page cache exceeding usage read before it.
+	cgroupStats.MemoryStats.Usage.Usage = 32768
+	_, ok = referencedBytesFromMemoryStats(cgroupStats)
+	assert.False(t, ok)
+
+	_, ok = referencedBytesFromMemoryStats(nil)
+	assert.False(t, ok)
+}
+
+func TestUpdateReferencedMemoryStatsWhenSmapsLackReferenced(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+
+	h := &Handler{referencedWindows: newReferencedWindows()}
+	cgroupStats := &cgroups.Stats{
+		MemoryStats: cgroups.MemoryStats{
+			Usage: cgroups.MemoryData{Usage: 1048576},
+			Stats: map[string]uint64{
+				"active_anon":   262144,
+				"active_file":   131072,
+				"inactive_file": 65536,
+			},
+		},
+	}
+	stats := &info.ContainerStats{}
+	h.updateReferencedMemoryStats([]int{12}, cgroupStats, stats)
+	assert.Equal(t, uint64(262144+131072), stats.ReferencedMemory)
+	assert.True(t, stats.ReferencedMemoryApproximate)
+	if assert.NotNil(t, stats.ReferencedMemoryWindows) {
+		assert.Equal(t, uint64(262144+131072), stats.ReferencedMemoryWindows.Window1m)
+	}
+
+	// Referenced bytes read from smaps are not approximate.
+	stats = &info.ContainerStats{}
+	h.updateReferencedMemoryStats([]int{4}, cgroupStats, stats)
+	assert.NotZero(t, stats.ReferencedMemory)
+	assert.NotEqual(t, uint64(262144+131072), stats.ReferencedMemory)
+	assert.False(t, stats.ReferencedMemoryApproximate)
+}
+
+func TestGetReferencedKBytesFromSmapsRollup(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+
+	// smaps_rollup exists only for PID 14, smaps is used for PID 6
+	pids := []int{14, 6}
+	referenced, err := getReferencedBytes(pids, 1, 0, referencedDetails{})
+	assert.Nil(t, err)
+	assert.True(t, referenced.found)
+	assert.Equal(t, uint64((1024+132)*1024), referenced.bytes)
+}
+
+func TestGetReferencedBytesConcurrently(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+
+	pids := []int{4, 6, 8, 10}
+	referenced, err := getReferencedBytes(pids, 3, time.Minute, referencedDetails{})
+	assert.Nil(t, err)
+	assert.True(t, referenced.found)
+	assert.False(t, referenced.partial)
+	assert.Equal(t, uint64(416*1024), referenced.bytes)
+}
+
+func TestGetReferencedBytesWhenBudgetExceeded(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+
+	pids := []int{4, 6, 8}
+	referenced, err := getReferencedBytes(pids, 1, time.Nanosecond, referencedDetails{})
+	assert.Nil(t, err)
+	assert.True(t, referenced.partial)
+	assert.True(t, referenced.bytes < uint64(416*1024))
+	assert.True(t, len(referenced.pids) < len(pids))
+}
+
+func TestReferencedBytesStatWhenBudgetExceeded(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+	clearRefsFilePathPattern = "testdata/clear_refs%d"
+	originalBudget := *referencedReadBudget
+	defer func() {
+		*referencedReadBudget = originalBudget
+	}()
+	*referencedReadBudget = time.Nanosecond
+
+	pids := []int{4, 6, 8}
+	stat, err := referencedBytesStat(pids, 1, 1, referencedDetails{})
+	assert.Nil(t, err)
+	assert.True(t, stat.partial)
+
+	clearRefsFiles := []string{
+		"testdata/clear_refs4",
+		"testdata/clear_refs6",
+		"testdata/clear_refs8"}
+
+	//check if only clear_refs files of processes which smaps were read are written
+	for i, pid := range pids {
+		expected := "0\n"
+		for _, read := range stat.pids {
+			if read == pid {
+				expected = "1\n"
+			}
+		}
+		assert.Equal(t, expected, getFileContent(t, clearRefsFiles[i]))
+	}
+
+	clearTestData(t, clearRefsFiles)
+}
+
+func TestGetReferencedBytesBreakdown(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+
+	// PID 10 does not exist.
+	referenced, err := getReferencedBytes([]int{4, 6, 10}, 1, 0, referencedDetails{breakdown: true})
+	assert.Nil(t, err)
+	assert.True(t, referenced.found)
+	assert.Equal(t, uint64((152+132)*1024), referenced.bytes)
+	// Only [heap] mapping of PID 4 is anonymous, other mappings are backed by /sbin/cgmanager.
+	assert.Equal(t, uint64(16*1024), referenced.anonBytes)
+	assert.Equal(t, uint64((136+132)*1024), referenced.fileBytes)
+}
+
+func TestParseSmapsMappings(t *testing.T) {
+	smaps := []byte(`7f0e7c000000-7f0e7c021000 rw-p 00000000 00:00 0
+Referenced:            8 kB
+7f0e81a2b000-7f0e81a2c000 r--p 00000000 fd:01 1835012                    /usr/lib/locale/C.UTF-8/LC_IDENTIFICATION
+Referenced:            4 kB
+7ffc4f9c1000-7ffc4f9e2000 rw-p 00000000 00:00 0                          [stack]
+Referenced:           12 kB
+`)
+	mappings, err := parseSmapsMappings(smaps)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]uint64{"7f0e7c000000": 8, "7f0e81a2b000": 4, "7ffc4f9c1000": 12}, mappings.kBytes)
+	assert.Equal(t, uint64(20), mappings.anonKBytes)
+	assert.Equal(t, uint64(4), mappings.fileKBytes)
 }
 
 func TestClearReferencedBytesWhenClearRefsMissing(t *testing.T) {
@@ -300,3 +590,106 @@ func TestClearReferencedBytesWhenClearRefsMissing(t *testing.T) {
 	err := clearReferencedBytes(pids, 0, 1)
 	assert.Nil(t, err)
 }
+
+func TestCgroupProcsPath(t *testing.T) {
+	v1Paths := map[string]string{
+		"cpu":    "/sys/fs/cgroup/cpu,cpuacct/system.slice/test.service",
+		"memory": "/sys/fs/cgroup/memory/system.slice/test.service",
+	}
+	path, ok := cgroupProcsPath(v1Paths, false)
+	assert.True(t, ok)
+	assert.Equal(t, "/sys/fs/cgroup/cpu,cpuacct/system.slice/test.service", path)
+
+	_, ok = cgroupProcsPath(v1Paths, true)
+	assert.False(t, ok)
+
+	v2Paths := map[string]string{
+		"": "/sys/fs/cgroup/system.slice/test.service",
+	}
+	path, ok = cgroupProcsPath(v2Paths, true)
+	assert.True(t, ok)
+	assert.Equal(t, "/sys/fs/cgroup/system.slice/test.service", path)
+}
+
+func TestProcessStatsFromProcsOnUnifiedHierarchy(t *testing.T) {
+	paths := map[string]string{
+		"": "testdata/cgroupv2/system.slice/test.service",
+	}
+	path, ok := cgroupProcsPath(paths, true)
+	assert.True(t, ok)
+
+	stats, err := processStatsFromProcs("testdata/cgroupv2", path, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, info.ProcessStats{
+		ProcessCount: 2,
+		FdCount:      3,
+		SocketCount:  1,
+	}, stats)
+}
+
+func TestProcessStatsFromProcsSampled(t *testing.T) {
+	stats, err := processStatsFromProcs("testdata/cgroupv2", "testdata/cgroupv2/system.slice/test.service", 0, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, info.ProcessStats{
+		ProcessCount: 2,
+		FdCount:      4,
+		SocketCount:  0,
+	}, stats)
+}
+
+func TestUpdateReferencedMemoryStatsBeforeFirstMeasurement(t *testing.T) {
+	dir := setupDamon(t, "off")
+	defer os.RemoveAll(dir)
+	//overwrite package variables
+	originalBackend, originalExpiry := referencedMemoryBackend, damonWorkingSetExpiry
+	defer func() {
+		referencedMemoryBackend, damonWorkingSetExpiry = originalBackend, originalExpiry
+	}()
+	referencedMemoryBackend, damonWorkingSetExpiry = damonReferencedBackend, 100*time.Millisecond
+
+	monitor := &damonMonitor{}
+	h := &Handler{
+		referencedWindows: newReferencedWindows(),
+		damon:             newDamonWorkingSet(monitor, time.Millisecond, 10*time.Millisecond),
+	}
+	// Working set is measured in background, averages are not started with zero.
+	stats := &info.ContainerStats{}
+	h.updateReferencedMemoryStats([]int{4, 6}, nil, stats)
+	assert.Equal(t, uint64(0), stats.ReferencedMemory)
+	assert.Nil(t, stats.ReferencedMemoryWindows)
+	assert.True(t, h.referencedWindows.lastUpdate.IsZero())
+
+	for i := 0; i < 100 && stats.ReferencedMemoryWindows == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		stats = &info.ContainerStats{}
+		h.updateReferencedMemoryStats([]int{4, 6}, nil, stats)
+	}
+	referenced := uint64(4096 + 65536 + 4096)
+	assert.Equal(t, referenced, stats.ReferencedMemory)
+	assert.Equal(t, &info.ReferencedMemoryWindows{
+		Window1m:  referenced,
+		Window5m:  referenced,
+		Window15m: referenced,
+	}, stats.ReferencedMemoryWindows)
+
+	// Wait until monitor stops, so it does not outlive the test.
+	running := true
+	for i := 0; i < 100 && running; i++ {
+		time.Sleep(10 * time.Millisecond)
+		monitor.lock.Lock()
+		running = monitor.running
+		monitor.lock.Unlock()
+	}
+}
+
+func TestReferencedBackendFlag(t *testing.T) {
+	var backend referencedBackend
+	for _, value := range []string{smapsReferencedBackend, idlePageReferencedBackend, damonReferencedBackend} {
+		assert.Nil(t, backend.Set(value))
+		assert.Equal(t, value, backend.String())
+	}
+
+	err := backend.Set("damom")
+	assert.EqualError(t, err, `unknown referenced memory backend "damom", must be one of: smaps, idle_page, damon`)
+	assert.Equal(t, damonReferencedBackend, backend.String())
+}
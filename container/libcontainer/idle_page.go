@@ -0,0 +1,194 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// pagemap entry bits, see: https://www.kernel.org/doc/Documentation/vm/pagemap.txt
+	pagemapEntrySize    = 8
+	pagemapPresentBit   = uint64(1) << 63
+	pagemapPFNMask      = (uint64(1) << 55) - 1
+	pagemapReadBatchLen = 4096
+
+	// page idle bitmap consists of 8-byte words, each bit corresponds to single page frame.
+	pageIdleBitmapWordSize = 8
+	pageIdleBitmapWordBits = 64
+	// maximal number of consecutive words of page idle bitmap read at once.
+	pageIdleBitmapReadBatchLen = 4096
+)
+
+var (
+	mapsFilePathPattern    = "/proc/%d/maps"
+	pagemapFilePathPattern = "/proc/%d/pagemap"
+	pageIdleBitmapFilePath = "/sys/kernel/mm/page_idle/bitmap"
+
+	pageSize = uint64(os.Getpagesize())
+)
+
+// idlePageReferencedBytesStat gets referenced bytes using idle page tracking and marks pages
+// of given PIDs as idle after reset interval. Contrary to clear_refs, marking pages as idle
+// does not affect kernel page reclaim.
+// see: https://www.kernel.org/doc/Documentation/vm/idle_page_tracking.txt
+func idlePageReferencedBytesStat(pids []int, cycles uint64, resetInterval uint64) (uint64, error) {
+	pfns, err := getPageFrames(pids)
+	if err != nil {
+		return 0, err
+	}
+
+	bitmap, err := os.OpenFile(pageIdleBitmapFilePath, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer bitmap.Close()
+
+	wordIndexes := make([]uint64, 0, len(pfns))
+	for wordIndex := range pfns {
+		wordIndexes = append(wordIndexes, wordIndex)
+	}
+	sort.Slice(wordIndexes, func(i, j int) bool { return wordIndexes[i] < wordIndexes[j] })
+
+	reset := resetInterval != 0 && cycles%resetInterval == 0
+	referencedPages := uint64(0)
+	words := make([]byte, pageIdleBitmapReadBatchLen*pageIdleBitmapWordSize)
+	for start := 0; start < len(wordIndexes); {
+		// consecutive words of the bitmap are read and written at once
+		end := start + 1
+		for end < len(wordIndexes) && end-start < pageIdleBitmapReadBatchLen && wordIndexes[end] == wordIndexes[end-1]+1 {
+			end++
+		}
+		batch := words[:(end-start)*pageIdleBitmapWordSize]
+		offset := int64(wordIndexes[start] * pageIdleBitmapWordSize)
+		_, err = bitmap.ReadAt(batch, offset)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s at offset %d: %v", pageIdleBitmapFilePath, offset, err)
+		}
+		for i, wordIndex := range wordIndexes[start:end] {
+			word := batch[i*pageIdleBitmapWordSize : (i+1)*pageIdleBitmapWordSize]
+			mask := pfns[wordIndex]
+			referencedPages += uint64(bits.OnesCount64(mask &^ binary.LittleEndian.Uint64(word)))
+			if reset {
+				binary.LittleEndian.PutUint64(word, mask)
+			}
+		}
+
+		if reset {
+			_, err = bitmap.WriteAt(batch, offset)
+			if err != nil {
+				return 0, fmt.Errorf("failed to write %s at offset %d: %v", pageIdleBitmapFilePath, offset, err)
+			}
+		}
+		start = end
+	}
+	return referencedPages * pageSize, nil
+}
+
+// getPageFrames returns page frames mapped by given PIDs which are present in memory,
+// grouped by word of page idle bitmap they belong to. Page frames shared between PIDs
+// are accounted once.
+func getPageFrames(pids []int) (map[uint64]uint64, error) {
+	pfns := make(map[uint64]uint64)
+	for _, pid := range pids {
+		err := addPageFrames(pid, pfns)
+		if err != nil {
+			if os.IsNotExist(err) {
+				klog.V(5).Infof("Cannot read page frames of PID %d, err: %s", pid, err)
+				continue // process may have exited
+			}
+			return nil, err
+		}
+	}
+	return pfns, nil
+}
+
+func addPageFrames(pid int, pfns map[uint64]uint64) error {
+	maps, err := os.Open(fmt.Sprintf(mapsFilePathPattern, pid))
+	if err != nil {
+		return err
+	}
+	defer maps.Close()
+
+	pagemap, err := os.Open(fmt.Sprintf(pagemapFilePathPattern, pid))
+	if err != nil {
+		return err
+	}
+	defer pagemap.Close()
+
+	entries := make([]byte, pagemapReadBatchLen*pagemapEntrySize)
+	scanner := bufio.NewScanner(maps)
+	for scanner.Scan() {
+		start, end, err := parseMapsAddressRange(scanner.Text())
+		if err != nil {
+			return err
+		}
+		for page := start / pageSize; page < end/pageSize; page += pagemapReadBatchLen {
+			batchLen := end/pageSize - page
+			if batchLen > pagemapReadBatchLen {
+				batchLen = pagemapReadBatchLen
+			}
+			n, err := pagemap.ReadAt(entries[:batchLen*pagemapEntrySize], int64(page*pagemapEntrySize))
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("failed to read pagemap of PID %d: %v", pid, err)
+			}
+			for i := 0; i+pagemapEntrySize <= n; i += pagemapEntrySize {
+				entry := binary.LittleEndian.Uint64(entries[i : i+pagemapEntrySize])
+				if entry&pagemapPresentBit == 0 {
+					continue
+				}
+				// PFN is zeroed when cAdvisor lacks CAP_SYS_ADMIN.
+				pfn := entry & pagemapPFNMask
+				if pfn == 0 {
+					continue
+				}
+				pfns[pfn/pageIdleBitmapWordBits] |= uint64(1) << (pfn % pageIdleBitmapWordBits)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// parseMapsAddressRange parses address range of mapping from line of /proc/<pid>/maps file, e.g.
+// "55f523c9f000-55f523cc1000 r-xp 00000000 08:02 5505067   /sbin/cgmanager"
+func parseMapsAddressRange(line string) (uint64, uint64, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("unexpected format of maps line: %q", line)
+	}
+	addresses := strings.Split(fields[0], "-")
+	if len(addresses) != 2 {
+		return 0, 0, fmt.Errorf("unexpected format of address range: %q", fields[0])
+	}
+	start, err := strconv.ParseUint(addresses[0], 16, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := strconv.ParseUint(addresses[1], 16, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
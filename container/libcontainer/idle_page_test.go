@@ -0,0 +1,119 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePagemap writes pagemap with given PFNs for consecutive pages starting at address 0x1000,
+// PFN equal to 0 marks page which is not present.
+func writePagemap(t *testing.T, path string, pfns []uint64) {
+	startPage := 0x1000 / pageSize
+	content := make([]byte, (startPage+uint64(len(pfns)))*pagemapEntrySize)
+	for i, pfn := range pfns {
+		entry := uint64(0)
+		if pfn != 0 {
+			entry = pagemapPresentBit | pfn
+		}
+		binary.LittleEndian.PutUint64(content[(startPage+uint64(i))*pagemapEntrySize:], entry)
+	}
+	assert.Nil(t, ioutil.WriteFile(path, content, 0644))
+}
+
+func setupIdlePageTracking(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "idle_page")
+	assert.Nil(t, err)
+
+	//overwrite package variables
+	mapsFilePathPattern = filepath.Join(dir, "maps%d")
+	pagemapFilePathPattern = filepath.Join(dir, "pagemap%d")
+	pageIdleBitmapFilePath = filepath.Join(dir, "bitmap")
+
+	maps := fmt.Sprintf("%x-%x rw-p 00000000 00:00 0    [heap]\n", 0x1000, 0x1000+4*pageSize)
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf(mapsFilePathPattern, 4), []byte(maps), 0644))
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf(mapsFilePathPattern, 6), []byte(maps), 0644))
+
+	// PID 4 maps page frames 1, 2 and 65, PID 6 shares page frame 2 and maps page frame 3.
+	writePagemap(t, fmt.Sprintf(pagemapFilePathPattern, 4), []uint64{1, 2, 0, 65})
+	writePagemap(t, fmt.Sprintf(pagemapFilePathPattern, 6), []uint64{2, 3, 0, 0})
+
+	// Page frames 1 and 3 are idle.
+	bitmap := make([]byte, 2*pageIdleBitmapWordSize)
+	binary.LittleEndian.PutUint64(bitmap, 1<<1|1<<3)
+	assert.Nil(t, ioutil.WriteFile(pageIdleBitmapFilePath, bitmap, 0644))
+	return dir
+}
+
+func TestIdlePageReferencedBytesStat(t *testing.T) {
+	dir := setupIdlePageTracking(t)
+	defer os.RemoveAll(dir)
+
+	stat, err := idlePageReferencedBytesStat([]int{4, 6, 10}, 1, 0)
+	assert.Nil(t, err)
+	// Page frames 2 and 65 are not idle.
+	assert.Equal(t, 2*pageSize, stat)
+
+	// Pages are not marked as idle when reset interval is not configured.
+	bitmap, err := ioutil.ReadFile(pageIdleBitmapFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1<<1|1<<3), binary.LittleEndian.Uint64(bitmap[0:8]))
+	assert.Equal(t, uint64(0), binary.LittleEndian.Uint64(bitmap[8:16]))
+}
+
+func TestIdlePageReferencedBytesStatWhenResetIsNeeded(t *testing.T) {
+	dir := setupIdlePageTracking(t)
+	defer os.RemoveAll(dir)
+
+	stat, err := idlePageReferencedBytesStat([]int{4, 6}, 2, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*pageSize, stat)
+
+	// All page frames of PIDs are marked as idle.
+	bitmap, err := ioutil.ReadFile(pageIdleBitmapFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1<<1|1<<2|1<<3), binary.LittleEndian.Uint64(bitmap[0:8]))
+	assert.Equal(t, uint64(1<<1), binary.LittleEndian.Uint64(bitmap[8:16]))
+
+	stat, err = idlePageReferencedBytesStat([]int{4, 6}, 3, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), stat)
+}
+
+func TestIdlePageReferencedBytesStatWhenBitmapMissing(t *testing.T) {
+	dir := setupIdlePageTracking(t)
+	defer os.RemoveAll(dir)
+	pageIdleBitmapFilePath = filepath.Join(dir, "missing")
+
+	_, err := idlePageReferencedBytesStat([]int{4}, 1, 0)
+	assert.NotNil(t, err)
+}
+
+func TestParseMapsAddressRange(t *testing.T) {
+	start, end, err := parseMapsAddressRange("55f523c9f000-55f523cc1000 r-xp 00000000 08:02 5505067    /sbin/cgmanager")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x55f523c9f000), start)
+	assert.Equal(t, uint64(0x55f523cc1000), end)
+
+	_, _, err = parseMapsAddressRange("55f523c9f000 r-xp")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+var processListTopN = flag.Int("process_list_top_n", 10,
+	"Number of processes using the most CPU time which resource usage is reported per container when 'process_list' metrics are enabled")
+
+// userHz is the number of clock ticks per second in which CPU times are reported in /proc/PID/stat,
+// it is 100 on all architectures supported by cAdvisor.
+const userHz = 100
+
+// topProcessesFromProcs returns resource usage of at most n processes of the cgroup which consumed
+// the most CPU time. Processes which exit while being read are skipped.
+func topProcessesFromProcs(rootFs string, cgroupPath string, n int) ([]info.ProcessUsage, error) {
+	filePath := path.Join(cgroupPath, "cgroup.procs")
+	out, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open cgroup procs file %v : %v", filePath, err)
+	}
+
+	processes := []info.ProcessUsage{}
+	for _, pid := range strings.Fields(string(out)) {
+		usage, err := readProcessUsage(rootFs, pid)
+		if err != nil {
+			klog.V(4).Infof("Unable to read usage of process %s: %v", pid, err)
+			continue
+		}
+		processes = append(processes, usage)
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processes[i].CpuTime > processes[j].CpuTime
+	})
+	if n >= 0 && len(processes) > n {
+		processes = processes[:n]
+	}
+	return processes, nil
+}
+
+// readProcessUsage reads resource usage of a process from /proc/PID/stat and counts its file descriptors.
+func readProcessUsage(rootFs string, pid string) (info.ProcessUsage, error) {
+	statPath := path.Join(rootFs, "proc", pid, "stat")
+	out, err := ioutil.ReadFile(statPath)
+	if err != nil {
+		return info.ProcessUsage{}, err
+	}
+	usage, err := parseProcessStat(string(out))
+	if err != nil {
+		return info.ProcessUsage{}, fmt.Errorf("couldn't parse %q: %v", statPath, err)
+	}
+
+	fdPath := path.Join(rootFs, "proc", pid, "fd")
+	fds, err := ioutil.ReadDir(fdPath)
+	if err != nil {
+		klog.V(4).Infof("error while listing directory %q to measure fd count: %v", fdPath, err)
+	} else {
+		usage.FdCount = uint64(len(fds))
+	}
+	return usage, nil
+}
+
+// parseProcessStat parses content of /proc/PID/stat, e.g.
+// 1234 (nginx: worker) S 1 1234 1234 0 -1 4194624 300 0 0 0 150 25 0 0 20 0 4 0 100 1000000 500 ...
+// Command is enclosed in parentheses and may contain spaces and parentheses itself,
+// the remaining fields are located after the last closing parenthesis.
+func parseProcessStat(stat string) (info.ProcessUsage, error) {
+	start := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return info.ProcessUsage{}, fmt.Errorf("command not found")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:start]))
+	if err != nil {
+		return info.ProcessUsage{}, fmt.Errorf("invalid pid: %v", err)
+	}
+
+	// Fields following command, starting with state (field 3 in proc(5)).
+	fields := strings.Fields(stat[end+1:])
+	const (
+		utimeField   = 14 - 3
+		stimeField   = 15 - 3
+		threadsField = 20 - 3
+		rssField     = 24 - 3
+	)
+	if len(fields) <= rssField {
+		return info.ProcessUsage{}, fmt.Errorf("expected at least %d fields, found %d", rssField+3, len(fields)+2)
+	}
+
+	values := map[int]uint64{}
+	for _, field := range []int{utimeField, stimeField, threadsField, rssField} {
+		value, err := strconv.ParseUint(fields[field], 10, 64)
+		if err != nil {
+			return info.ProcessUsage{}, fmt.Errorf("invalid field %d: %v", field+3, err)
+		}
+		values[field] = value
+	}
+
+	return info.ProcessUsage{
+		Pid:     pid,
+		Command: stat[start+1 : end],
+		CpuTime: (values[utimeField] + values[stimeField]) * uint64(time.Second) / userHz,
+		RSS:     values[rssField] * pageSize,
+		Threads: values[threadsField],
+	}, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopProcessesFromProcs(t *testing.T) {
+	processes, err := topProcessesFromProcs("testdata/cgroupv2", "testdata/cgroupv2/system.slice/test.service", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.ProcessUsage{
+		{
+			Pid:     2,
+			Command: "my (app)",
+			CpuTime: 3500000000,
+			RSS:     1000 * pageSize,
+			FdCount: 1,
+			Threads: 4,
+		},
+		{
+			Pid:     1,
+			Command: "sh",
+			CpuTime: 150000000,
+			RSS:     200 * pageSize,
+			FdCount: 2,
+			Threads: 1,
+		},
+	}, processes)
+}
+
+func TestTopProcessesFromProcsLimit(t *testing.T) {
+	processes, err := topProcessesFromProcs("testdata/cgroupv2", "testdata/cgroupv2/system.slice/test.service", 1)
+	assert.Nil(t, err)
+	assert.Len(t, processes, 1)
+	assert.Equal(t, 2, processes[0].Pid)
+}
+
+func TestParseProcessStatInvalid(t *testing.T) {
+	for _, stat := range []string{
+		"",
+		"1 sh S 0",
+		"1 (sh) S 0 1 1",
+		"x (sh) S 0 1 1 0 -1 4194560 500 0 0 0 10 5 0 0 20 0 1 0 100 4579328 200",
+		"1 (sh) S 0 1 1 0 -1 4194560 500 0 0 0 x 5 0 0 20 0 1 0 100 4579328 200",
+	} {
+		_, err := parseProcessStat(stat)
+		assert.NotNil(t, err, "stat: %q", stat)
+	}
+}
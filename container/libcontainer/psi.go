@@ -0,0 +1,105 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// setPSIStats sets pressure stall information of CPU, memory and IO read from <resource>.pressure
+// files of cgroup v2. Root cgroup does not have the files, system-wide pressure from /proc/pressure
+// is reported for it.
+func setPSIStats(cgroupPath string, rootFs string, isRoot bool, ret *info.ContainerStats) error {
+	for resource, psi := range map[string]*info.PSIStats{
+		"cpu":    &ret.Cpu.PSI,
+		"memory": &ret.Memory.PSI,
+		"io":     &ret.DiskIo.PSI,
+	} {
+		pressureFile := path.Join(cgroupPath, resource+".pressure")
+		if isRoot {
+			pressureFile = path.Join(rootFs, "proc", "pressure", resource)
+		}
+		stats, err := readPSIFile(pressureFile)
+		if err != nil {
+			return err
+		}
+		*psi = stats
+	}
+	return nil
+}
+
+// readPSIFile parses pressure file, e.g.
+// some avg10=0.12 avg60=0.30 avg300=0.05 total=3271825
+// full avg10=0.00 avg60=0.13 avg300=0.03 total=1573862
+// Total is reported in microseconds.
+func readPSIFile(pressureFile string) (info.PSIStats, error) {
+	stats := info.PSIStats{}
+	file, err := os.Open(pressureFile)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var data *info.PSIData
+		switch fields[0] {
+		case "some":
+			data = &stats.Some
+		case "full":
+			data = &stats.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			if keyValue[0] == "total" {
+				total, err := strconv.ParseUint(keyValue[1], 10, 64)
+				if err != nil {
+					return stats, fmt.Errorf("failed to parse %q of %s: %v", scanner.Text(), pressureFile, err)
+				}
+				data.Total = total * 1000
+				continue
+			}
+			value, err := strconv.ParseFloat(keyValue[1], 64)
+			if err != nil {
+				return stats, fmt.Errorf("failed to parse %q of %s: %v", scanner.Text(), pressureFile, err)
+			}
+			switch keyValue[0] {
+			case "avg10":
+				data.Avg10 = value
+			case "avg60":
+				data.Avg60 = value
+			case "avg300":
+				data.Avg300 = value
+			}
+		}
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPSIStats(t *testing.T) {
+	stats := &info.ContainerStats{}
+	err := setPSIStats("testdata/cgroupv2/system.slice/test.service", "testdata/cgroupv2", false, stats)
+	assert.Nil(t, err)
+	// CPU full is not reported by kernels older than 5.13.
+	assert.Equal(t, info.PSIStats{
+		Some: info.PSIData{Total: 3271825000, Avg10: 1.5, Avg60: 0.75, Avg300: 0.25},
+	}, stats.Cpu.PSI)
+	assert.Equal(t, info.PSIStats{
+		Some: info.PSIData{Total: 1573862000, Avg10: 0, Avg60: 0.13, Avg300: 0.03},
+		Full: info.PSIData{Total: 1002003000, Avg10: 0, Avg60: 0.1, Avg300: 0.02},
+	}, stats.Memory.PSI)
+	assert.Equal(t, info.PSIStats{
+		Some: info.PSIData{Total: 98765432000, Avg10: 4.2, Avg60: 2.1, Avg300: 0.9},
+		Full: info.PSIData{Total: 87654321000, Avg10: 3.1, Avg60: 1.6, Avg300: 0.7},
+	}, stats.DiskIo.PSI)
+}
+
+func TestSetPSIStatsOfRootCgroup(t *testing.T) {
+	stats := &info.ContainerStats{}
+	err := setPSIStats("testdata/cgroupv2", "testdata/cgroupv2", true, stats)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(123456789000), stats.Cpu.PSI.Some.Total)
+	assert.Equal(t, uint64(0), stats.Cpu.PSI.Full.Total)
+	assert.Equal(t, uint64(2000000), stats.Memory.PSI.Some.Total)
+	assert.Equal(t, uint64(1000000), stats.Memory.PSI.Full.Total)
+	assert.Equal(t, uint64(5000000000), stats.DiskIo.PSI.Some.Total)
+	assert.Equal(t, 0.1, stats.DiskIo.PSI.Full.Avg10)
+}
+
+func TestSetPSIStatsWhenPressureIsNotAvailable(t *testing.T) {
+	// Kernel was built without CONFIG_PSI or booted with psi=0.
+	err := setPSIStats("testdata/cgroupv2/system.slice", "testdata/cgroupv2", false, &info.ContainerStats{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"math"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// referencedWindows averages referenced bytes over 1, 5 and 15 minute windows
+// with exponential decay, similarly to load average.
+type referencedWindows struct {
+	lastUpdate time.Time
+	// Averages for windows of corresponding durations.
+	averages  [3]float64
+	durations [3]time.Duration
+}
+
+func newReferencedWindows() *referencedWindows {
+	return &referencedWindows{
+		durations: [3]time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute},
+	}
+}
+
+// update accounts referenced bytes measured at given time and returns current averages.
+func (w *referencedWindows) update(now time.Time, referenced uint64) info.ReferencedMemoryWindows {
+	value := float64(referenced)
+	if w.lastUpdate.IsZero() {
+		for i := range w.averages {
+			w.averages[i] = value
+		}
+	} else if elapsed := now.Sub(w.lastUpdate); elapsed > 0 {
+		for i, duration := range w.durations {
+			decay := math.Exp(-elapsed.Seconds() / duration.Seconds())
+			w.averages[i] = w.averages[i]*decay + value*(1-decay)
+		}
+	}
+	w.lastUpdate = now
+
+	return info.ReferencedMemoryWindows{
+		Window1m:  uint64(math.Round(w.averages[0])),
+		Window5m:  uint64(math.Round(w.averages[1])),
+		Window15m: uint64(math.Round(w.averages[2])),
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferencedWindows(t *testing.T) {
+	windows := newReferencedWindows()
+	now := time.Unix(1600000000, 0)
+
+	// First measurement initializes all windows.
+	averages := windows.update(now, 1000)
+	assert.Equal(t, info.ReferencedMemoryWindows{Window1m: 1000, Window5m: 1000, Window15m: 1000}, averages)
+
+	// Measurement at the same time does not change averages.
+	averages = windows.update(now, 5000)
+	assert.Equal(t, info.ReferencedMemoryWindows{Window1m: 1000, Window5m: 1000, Window15m: 1000}, averages)
+
+	now = now.Add(time.Minute)
+	averages = windows.update(now, 2000)
+	expected := func(window time.Duration) uint64 {
+		decay := math.Exp(-time.Minute.Seconds() / window.Seconds())
+		return uint64(math.Round(1000*decay + 2000*(1-decay)))
+	}
+	assert.Equal(t, info.ReferencedMemoryWindows{
+		Window1m:  expected(time.Minute),
+		Window5m:  expected(5 * time.Minute),
+		Window15m: expected(15 * time.Minute),
+	}, averages)
+	// Shorter windows follow changes faster.
+	assert.True(t, averages.Window1m > averages.Window5m)
+	assert.True(t, averages.Window5m > averages.Window15m)
+}
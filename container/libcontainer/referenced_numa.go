@@ -0,0 +1,101 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	numaMapsFilePathPattern = "/proc/%d/numa_maps"
+
+	numaMapsNodeRegexp = regexp.MustCompile(`^N([0-9]+)=([0-9]+)$`)
+)
+
+// getReferencedBytesPerNode splits referenced kilobytes of each mapping of given PID between NUMA nodes
+// proportionally to number of pages of the mapping placed on the node according to numa_maps, and returns
+// referenced bytes per NUMA node.
+// see: https://www.kernel.org/doc/Documentation/vm/numa_memory_policy.txt
+func getReferencedBytesPerNode(pid int, referencedKBytes map[string]uint64) (map[uint8]uint64, error) {
+	nodePages, err := getMappingsNodePages(pid)
+	if err != nil {
+		if os.IsNotExist(err) {
+			klog.V(5).Infof("Cannot read numa_maps of PID %d, err: %s", pid, err)
+			return nil, nil // numa_maps is not available on kernels without NUMA support
+		}
+		return nil, err
+	}
+
+	referencedPerNode := make(map[uint8]uint64)
+	for mapping, referenced := range referencedKBytes {
+		pages, ok := nodePages[mapping]
+		if !ok || referenced == 0 {
+			continue
+		}
+		totalPages := uint64(0)
+		for _, n := range pages {
+			totalPages += n
+		}
+		if totalPages == 0 {
+			continue
+		}
+		for node, n := range pages {
+			referencedPerNode[node] += referenced * 1024 * n / totalPages
+		}
+	}
+	return referencedPerNode, nil
+}
+
+// getMappingsNodePages returns number of pages placed on each NUMA node for each mapping of given PID,
+// mappings are identified by start address.
+func getMappingsNodePages(pid int) (map[string]map[uint8]uint64, error) {
+	numaMapsContent, err := ioutil.ReadFile(fmt.Sprintf(numaMapsFilePathPattern, pid))
+	if err != nil {
+		return nil, err
+	}
+
+	nodePages := make(map[string]map[uint8]uint64)
+	for _, line := range strings.Split(string(numaMapsContent), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pages := make(map[uint8]uint64)
+		for _, field := range fields[1:] {
+			matches := numaMapsNodeRegexp.FindStringSubmatch(field)
+			if len(matches) != 3 {
+				continue
+			}
+			node, err := strconv.ParseUint(matches[1], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse NUMA node in %q: %v", field, err)
+			}
+			n, err := strconv.ParseUint(matches[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse number of pages in %q: %v", field, err)
+			}
+			pages[uint8(node)] = n
+		}
+		nodePages[fields[0]] = pages
+	}
+	return nodePages, nil
+}
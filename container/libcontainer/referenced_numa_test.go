@@ -0,0 +1,50 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReferencedBytesPerNode(t *testing.T) {
+	//overwrite package variables
+	smapsFilePathPattern = "testdata/smaps%d"
+	smapsRollupFilePathPattern = "testdata/smaps_rollup%d"
+	numaMapsFilePathPattern = "testdata/numa_maps%d"
+
+	// numa_maps does not exist for PID 6 and PID 10 does not exist at all
+	pids := []int{4, 6, 10}
+	referenced, err := getReferencedBytes(pids, 1, 0, referencedDetails{perNode: true})
+	assert.Nil(t, err)
+	assert.True(t, referenced.found)
+	assert.Equal(t, uint64((152+132)*1024), referenced.bytes)
+	assert.Equal(t, map[uint8]uint64{
+		0: 132*1024*22/33 + 16*1024,
+		1: 132*1024*11/33 + 4*1024,
+	}, referenced.bytesPerNode)
+}
+
+func TestGetMappingsNodePages(t *testing.T) {
+	//overwrite package variable
+	numaMapsFilePathPattern = "testdata/numa_maps%d"
+
+	nodePages, err := getMappingsNodePages(4)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(nodePages))
+	assert.Equal(t, map[uint8]uint64{0: 22, 1: 11}, nodePages["55f523c9f000"])
+	assert.Equal(t, map[uint8]uint64{0: 4}, nodePages["55f52478d000"])
+}
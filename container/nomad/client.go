@@ -0,0 +1,92 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Allocation is subset of allocation returned by the Nomad HTTP API.
+type Allocation struct {
+	ID        string `json:"ID"`
+	Namespace string `json:"Namespace"`
+	JobID     string `json:"JobID"`
+	TaskGroup string `json:"TaskGroup"`
+	Job       *Job   `json:"Job"`
+}
+
+// Job is subset of job of the allocation.
+type Job struct {
+	ID   string `json:"ID"`
+	Name string `json:"Name"`
+}
+
+type NomadClient interface {
+	// Ping returns an error when the Nomad agent does not respond.
+	Ping() error
+	Allocation(id string) (*Allocation, error)
+}
+
+type nomadClientImpl struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+func newClient(address, token string) NomadClient {
+	return &nomadClientImpl{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *nomadClientImpl) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", c.address+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Nomad-Token", c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nomad agent at %s returned status %d for %s", c.address, resp.StatusCode, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Ping checks that the Nomad agent responds, the leader endpoint does not require ACL token.
+func (c *nomadClientImpl) Ping() error {
+	var leader string
+	return c.get("/v1/status/leader", &leader)
+}
+
+// Allocation returns the allocation with given id.
+func (c *nomadClientImpl) Allocation(id string) (*Allocation, error) {
+	alloc := &Allocation{}
+	if err := c.get("/v1/allocation/"+id, alloc); err != nil {
+		return nil, fmt.Errorf("failed to get nomad allocation %q: %v", id, err)
+	}
+	return alloc, nil
+}
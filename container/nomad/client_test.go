@@ -0,0 +1,72 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nomadClientMock struct {
+	allocations map[string]*Allocation
+}
+
+func (c *nomadClientMock) Ping() error {
+	return nil
+}
+
+func (c *nomadClientMock) Allocation(id string) (*Allocation, error) {
+	alloc, ok := c.allocations[id]
+	if !ok {
+		return nil, fmt.Errorf("no allocation with id %s", id)
+	}
+	return alloc, nil
+}
+
+const testAllocID = "7f1c0e3a-5d3b-4a4e-9c0e-2f6c8a1b9d2e"
+
+func TestClientAllocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Nomad-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/status/leader":
+			fmt.Fprint(w, `"10.0.0.1:4647"`)
+		case "/v1/allocation/" + testAllocID:
+			fmt.Fprint(w, `{"ID":"`+testAllocID+`","Namespace":"default","JobID":"cache","TaskGroup":"cache","Job":{"ID":"cache","Name":"cache"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newClient(server.URL+"/", "secret")
+	assert.NoError(t, client.Ping())
+	alloc, err := client.Allocation(testAllocID)
+	assert.NoError(t, err)
+	assert.Equal(t, testAllocID, alloc.ID)
+	assert.Equal(t, "cache", alloc.Job.Name)
+	assert.Equal(t, "cache", alloc.TaskGroup)
+	_, err = client.Allocation("unknown")
+	assert.Error(t, err)
+
+	assert.Error(t, newClient(server.URL, "").Ping())
+}
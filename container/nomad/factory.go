@@ -0,0 +1,122 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	ArgNomadAddress = flag.String("nomad", "http://127.0.0.1:4646", "Nomad agent HTTP API address")
+	ArgNomadToken   = flag.String("nomad_token", "", "Nomad ACL token with read-job capability used to look up allocations of tasks")
+)
+
+// The namespace under which Nomad aliases are unique.
+const NomadNamespace = "nomad"
+
+// Regexp that identifies cgroups of Nomad tasks of exec, raw_exec and java
+// drivers, <alloc id>.<task> under /nomad with cgroupfs or <alloc id>.<task>.scope
+// under nomad.slice with systemd cgroup driver. Tasks of docker driver are handled
+// by the docker factory.
+var nomadCgroupRegexp = regexp.MustCompile(`^([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})\.(.+?)(\.scope)?$`)
+
+type nomadFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	client NomadClient
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	includedMetrics container.MetricSet
+}
+
+func (f *nomadFactory) String() string {
+	return NomadNamespace
+}
+
+func (f *nomadFactory) NewContainerHandler(name string, inHostNamespace bool) (container.ContainerHandler, error) {
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+	return newNomadContainerHandler(f.client, name, f.machineInfoFactory, &f.cgroupSubsystems, rootFs, f.includedMetrics)
+}
+
+// parseContainerName returns allocation ID and task name of the Nomad task from its cgroup.
+func parseContainerName(name string) (string, string, bool) {
+	if !strings.HasPrefix(name, "/nomad/") && !strings.HasPrefix(name, "/nomad.slice/") {
+		return "", "", false
+	}
+	matches := nomadCgroupRegexp.FindStringSubmatch(path.Base(name))
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
+
+// nomad handles cgroups of tasks of the allocations known to the Nomad agent.
+func (f *nomadFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	allocID, _, ok := parseContainerName(name)
+	if !ok {
+		return false, false, nil
+	}
+	if _, err := f.client.Allocation(allocID); err != nil {
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+func (f *nomadFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	if *ArgNomadAddress == "" {
+		return fmt.Errorf("nomad agent address is not set")
+	}
+	client := newClient(*ArgNomadAddress, *ArgNomadToken)
+	if err := client.Ping(); err != nil {
+		return fmt.Errorf("nomad agent is not available: %v", err)
+	}
+
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering Nomad factory")
+	f := &nomadFactory{
+		machineInfoFactory: factory,
+		client:             client,
+		cgroupSubsystems:   cgroupSubsystems,
+		includedMetrics:    includedMetrics,
+	}
+	container.RegisterContainerHandlerFactory(f, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanHandleAndAccept(t *testing.T) {
+	f := &nomadFactory{
+		client: &nomadClientMock{allocations: map[string]*Allocation{testAllocID: {ID: testAllocID}}},
+	}
+	for name, expected := range map[string]bool{
+		"/nomad/" + testAllocID + ".redis":                              true,
+		"/nomad.slice/" + testAllocID + ".redis.scope":                  true,
+		"/nomad.slice/share.slice/" + testAllocID + ".web.server.scope": true,
+		"/nomad/2b6f6e5c-1d1e-4a4e-9c0e-2f6c8a1b9d2e.redis":             false,
+		"/nomad":       false,
+		"/nomad.slice": false,
+		"/system.slice/" + testAllocID + ".redis.scope":                            false,
+		"/docker/81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f": false,
+	} {
+		handle, accept, _ := f.CanHandleAndAccept(name)
+		assert.Equal(t, expected, handle, name)
+		assert.Equal(t, expected, accept, name)
+	}
+
+	allocID, task, ok := parseContainerName("/nomad.slice/share.slice/" + testAllocID + ".web.server.scope")
+	assert.True(t, ok)
+	assert.Equal(t, testAllocID, allocID)
+	assert.Equal(t, "web.server", task)
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for Nomad tasks.
+package nomad
+
+import (
+	"fmt"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Labels with metadata of the task, they are the same as labels which Nomad
+// docker driver sets on containers.
+const (
+	allocIDLabel   = "com.hashicorp.nomad.alloc_id"
+	jobNameLabel   = "com.hashicorp.nomad.job_name"
+	jobIDLabel     = "com.hashicorp.nomad.job_id"
+	taskGroupLabel = "com.hashicorp.nomad.task_group_name"
+	taskNameLabel  = "com.hashicorp.nomad.task_name"
+	namespaceLabel = "com.hashicorp.nomad.namespace"
+)
+
+type nomadContainerHandler struct {
+	// Name of the container for this handler.
+	name string
+
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	// Metadata associated with the task.
+	labels map[string]string
+
+	includedMetrics container.MetricSet
+
+	reference info.ContainerReference
+
+	libcontainerHandler *libcontainer.Handler
+}
+
+var _ container.ContainerHandler = &nomadContainerHandler{}
+
+func newNomadContainerHandler(
+	client NomadClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *libcontainer.CgroupSubsystems,
+	rootFs string,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	allocID, task, ok := parseContainerName(name)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a cgroup of nomad task", name)
+	}
+	alloc, err := client.Allocation(allocID)
+	if err != nil {
+		return nil, err
+	}
+
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	cgroupManager, err := libcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{
+		allocIDLabel:   allocID,
+		jobIDLabel:     alloc.JobID,
+		taskGroupLabel: alloc.TaskGroup,
+		taskNameLabel:  task,
+		namespaceLabel: alloc.Namespace,
+	}
+	if alloc.Job != nil {
+		labels[jobNameLabel] = alloc.Job.Name
+	}
+
+	return &nomadContainerHandler{
+		name:               name,
+		machineInfoFactory: machineInfoFactory,
+		cgroupPaths:        cgroupPaths,
+		labels:             labels,
+		includedMetrics:    includedMetrics,
+		reference: info.ContainerReference{
+			Id:   allocID,
+			Name: name,
+			// Containers of docker driver are named the same way.
+			Aliases:   []string{task + "-" + allocID},
+			Namespace: NomadNamespace,
+		},
+		libcontainerHandler: libcontainer.NewHandler(cgroupManager, rootFs, 0, includedMetrics),
+	}, nil
+}
+
+func (h *nomadContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+// Nothing to start up.
+func (h *nomadContainerHandler) Start() {}
+
+// Nothing to clean up.
+func (h *nomadContainerHandler) Cleanup() {}
+
+func (h *nomadContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	const (
+		hasNetwork    = false
+		hasFilesystem = false
+	)
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, hasNetwork, hasFilesystem)
+	spec.Labels = h.labels
+	return spec, err
+}
+
+func (h *nomadContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *nomadContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.name)
+	}
+	return path, nil
+}
+
+func (h *nomadContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *nomadContainerHandler) GetContainerIPAddress() string {
+	// Tasks of exec and java drivers use network of the host by default.
+	return "127.0.0.1"
+}
+
+func (h *nomadContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	// No-op for Nomad driver.
+	return []info.ContainerReference{}, nil
+}
+
+func (h *nomadContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *nomadContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *nomadContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeNomad
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"testing"
+
+	"github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	alloc := &Allocation{ID: testAllocID, Namespace: "default", JobID: "cache", TaskGroup: "cache", Job: &Job{ID: "cache", Name: "cache"}}
+	client := &nomadClientMock{allocations: map[string]*Allocation{testAllocID: alloc}}
+
+	name := "/nomad.slice/" + testAllocID + ".redis.scope"
+	handler, err := newNomadContainerHandler(client, name, nil, &libcontainer.CgroupSubsystems{}, "/", nil)
+	assert.NoError(t, err)
+	reference, err := handler.ContainerReference()
+	assert.NoError(t, err)
+	assert.Equal(t, info.ContainerReference{
+		Id:        testAllocID,
+		Name:      name,
+		Aliases:   []string{"redis-" + testAllocID},
+		Namespace: NomadNamespace,
+	}, reference)
+	assert.Equal(t, map[string]string{
+		"com.hashicorp.nomad.alloc_id":        testAllocID,
+		"com.hashicorp.nomad.job_id":          "cache",
+		"com.hashicorp.nomad.job_name":        "cache",
+		"com.hashicorp.nomad.task_group_name": "cache",
+		"com.hashicorp.nomad.task_name":       "redis",
+		"com.hashicorp.nomad.namespace":       "default",
+	}, handler.GetContainerLabels())
+
+	_, err = newNomadContainerHandler(client, "/nomad/2b6f6e5c-1d1e-4a4e-9c0e-2f6c8a1b9d2e.redis", nil, &libcontainer.CgroupSubsystems{}, "/", nil)
+	assert.Error(t, err)
+}
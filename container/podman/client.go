@@ -0,0 +1,161 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Socket of the rootful Podman service.
+	PodmanSocket = "/run/podman/podman.sock"
+	// Socket of the rootless Podman service of the user with given uid.
+	rootlessSocketFormat = "/run/user/%s/podman/podman.sock"
+
+	maxUnixSocketPathSize = len(syscall.RawSockaddrUnix{}.Path)
+	// Version of the libpod REST API, it is supported by Podman 2.0 and newer.
+	apiVersion = "v1.0.0"
+)
+
+// ContainerInfo is subset of container inspect data returned by the libpod REST API.
+type ContainerInfo struct {
+	Id        string `json:"Id"`
+	Name      string `json:"Name"`
+	ImageName string `json:"ImageName"`
+	State     struct {
+		Pid int `json:"Pid"`
+	} `json:"State"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+		Env    []string          `json:"Env"`
+	} `json:"Config"`
+	HostConfig struct {
+		NetworkMode string `json:"NetworkMode"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		IPAddress string `json:"IPAddress"`
+	} `json:"NetworkSettings"`
+	GraphDriver struct {
+		Name string            `json:"Name"`
+		Data map[string]string `json:"Data"`
+	} `json:"GraphDriver"`
+	Pod string `json:"Pod"`
+}
+
+type PodmanClient interface {
+	// Ping returns an error when the Podman service does not respond.
+	Ping() error
+	ContainerInfo(id string) (*ContainerInfo, error)
+}
+
+type podmanClientImpl struct {
+	socket string
+	client *http.Client
+}
+
+var (
+	clientsLock sync.Mutex
+	clients     = map[string]PodmanClient{}
+)
+
+// RootlessSocket returns socket of the rootless Podman service of the user with given uid.
+func RootlessSocket(uid int) string {
+	return fmt.Sprintf(rootlessSocketFormat, strconv.Itoa(uid))
+}
+
+// Client returns a client of the Podman service listening at socket, clients
+// are cached so that each service is connected only once.
+func Client(socket string) (PodmanClient, error) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	if c, ok := clients[socket]; ok {
+		return c, nil
+	}
+	if len(socket) > maxUnixSocketPathSize {
+		return nil, fmt.Errorf("Unix socket path %q is too long", socket)
+	}
+	tr := &http.Transport{
+		// No need for compression in local communications.
+		DisableCompression: true,
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.DialTimeout("unix", socket, 32*time.Second)
+		},
+	}
+	c := &podmanClientImpl{
+		socket: socket,
+		client: &http.Client{
+			Transport: tr,
+			Timeout:   30 * time.Second,
+		},
+	}
+	clients[socket] = c
+	return c, nil
+}
+
+func (c *podmanClientImpl) get(path string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// For local communications over a unix socket, it doesn't matter what
+	// the host is. We just need a valid and meaningful host name.
+	req.Host = "podman"
+	req.URL.Host = "podman"
+	req.URL.Scheme = "http"
+	return c.client.Do(req)
+}
+
+// Ping checks that the Podman service responds.
+func (c *podmanClientImpl) Ping() error {
+	resp, err := c.get("/" + apiVersion + "/libpod/_ping")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("podman service at %s returned status %d", c.socket, resp.StatusCode)
+	}
+	return nil
+}
+
+// ContainerInfo returns information about a given container
+func (c *podmanClientImpl) ContainerInfo(id string) (*ContainerInfo, error) {
+	resp, err := c.get("/" + apiVersion + "/libpod/containers/" + id + "/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// golang's http.Do doesn't return an error if non 200 response code is returned
+	// handle this case here, rather than failing to decode the body
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error finding container %s: Status %d returned by podman service at %s", id, resp.StatusCode, c.socket)
+	}
+
+	cInfo := ContainerInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(&cInfo); err != nil {
+		return nil, err
+	}
+	return &cInfo, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var ArgPodmanEndpoint = flag.String("podman", "unix://"+PodmanSocket, "podman endpoint, rootless containers are looked up at /run/user/<uid>/podman/podman.sock of their user")
+
+// The namespace under which podman aliases are unique.
+const PodmanNamespace = "podman"
+
+var (
+	// Regexp that identifies podman cgroups, e.g. libpod-<id>.scope with
+	// systemd cgroup manager or libpod-<id> with cgroupfs one. Cgroups of
+	// conmon (libpod-conmon-<id>.scope) are not matched.
+	podmanCgroupRegexp = regexp.MustCompile(`^libpod-([a-f0-9]{64})(\.scope)?$`)
+	// Regexp that identifies cgroups of rootless containers, they are created
+	// under user.slice/user-<uid>.slice/user@<uid>.service of their user.
+	rootlessCgroupRegexp = regexp.MustCompile(`/user@([0-9]+)\.service/`)
+)
+
+type podmanFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Socket of the rootful Podman service.
+	socket string
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+}
+
+func (f *podmanFactory) String() string {
+	return PodmanNamespace
+}
+
+func (f *podmanFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	client, err := Client(f.socketForContainer(name))
+	if err != nil {
+		return
+	}
+	handler, err = newPodmanContainerHandler(
+		client,
+		name,
+		f.machineInfoFactory,
+		f.fsInfo,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+	return
+}
+
+// Returns the podman ID from the full container name.
+func ContainerNameToPodmanId(name string) string {
+	id := path.Base(name)
+	if matches := podmanCgroupRegexp.FindStringSubmatch(id); matches != nil {
+		return matches[1]
+	}
+	return id
+}
+
+// isContainerName returns true if the cgroup with associated name
+// corresponds to a podman container.
+func isContainerName(name string) bool {
+	return podmanCgroupRegexp.MatchString(path.Base(name))
+}
+
+// rootlessUid returns uid of the user owning the rootless container and true,
+// or false when the container is rootful.
+func rootlessUid(name string) (int, bool) {
+	matches := rootlessCgroupRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return 0, false
+	}
+	uid, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	return uid, true
+}
+
+// socketForContainer returns socket of the Podman service which manages the container.
+func (f *podmanFactory) socketForContainer(name string) string {
+	if uid, ok := rootlessUid(name); ok {
+		return RootlessSocket(uid)
+	}
+	return f.socket
+}
+
+// podman handles all containers under libpod-<id> cgroups which are known to
+// the rootful or rootless Podman service.
+func (f *podmanFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !isContainerName(name) {
+		return false, false, nil
+	}
+	client, err := Client(f.socketForContainer(name))
+	if err != nil {
+		return false, false, err
+	}
+	// If info can not be fetched, the container is not known to podman.
+	if _, err := client.ContainerInfo(ContainerNameToPodmanId(name)); err != nil {
+		return false, false, fmt.Errorf("failed to get podman container %q: %v", name, err)
+	}
+	return true, true, nil
+}
+
+func (f *podmanFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	socket := strings.TrimPrefix(*ArgPodmanEndpoint, "unix://")
+
+	// Podman is daemonless, the factory is registered when either the rootful
+	// service or a rootless service of any user is available.
+	client, err := Client(socket)
+	if err != nil {
+		return err
+	}
+	if err := client.Ping(); err != nil {
+		rootlessSockets, _ := filepath.Glob(fmt.Sprintf(rootlessSocketFormat, "*"))
+		if len(rootlessSockets) == 0 {
+			return fmt.Errorf("podman service is not available: %v", err)
+		}
+		klog.V(4).Infof("Rootful podman service is not available: %v", err)
+	}
+
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering Podman factory")
+	f := &podmanFactory{
+		machineInfoFactory: factory,
+		socket:             socket,
+		cgroupSubsystems:   cgroupSubsystems,
+		fsInfo:             fsInfo,
+		includedMetrics:    includedMetrics,
+	}
+
+	container.RegisterContainerHandlerFactory(f, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testContainerId = "81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f"
+
+func TestSocketForContainer(t *testing.T) {
+	f := &podmanFactory{socket: PodmanSocket}
+	for name, socket := range map[string]string{
+		"/machine.slice/libpod-" + testContainerId + ".scope":                                              PodmanSocket,
+		"/libpod_parent/libpod-" + testContainerId:                                                         PodmanSocket,
+		"/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerId + ".scope":    "/run/user/1000/podman/podman.sock",
+		"/user.slice/user-1001.slice/user@1001.service/app.slice/podman.service/libpod-" + testContainerId: "/run/user/1001/podman/podman.sock",
+	} {
+		assert.Equal(t, socket, f.socketForContainer(name), name)
+		assert.Equal(t, testContainerId, ContainerNameToPodmanId(name), name)
+	}
+}
+
+func TestCanHandleAndAccept(t *testing.T) {
+	as := assert.New(t)
+	const rootlessSocket = "/run/user/1000/podman/podman.sock"
+	clients[PodmanSocket] = mockPodmanClient(map[string]*ContainerInfo{testContainerId: {}}, nil)
+	clients[rootlessSocket] = mockPodmanClient(nil, nil)
+	defer delete(clients, PodmanSocket)
+	defer delete(clients, rootlessSocket)
+
+	f := &podmanFactory{socket: PodmanSocket}
+	for k, v := range map[string]bool{
+		"/machine.slice/libpod-" + testContainerId + ".scope":                                           true,
+		"/libpod_parent/libpod-" + testContainerId:                                                      true,
+		"/machine.slice/libpod-conmon-" + testContainerId + ".scope":                                    false,
+		"/machine.slice/libpod-" + testContainerId + ".scope/container":                                 false,
+		"/system.slice/docker-" + testContainerId + ".scope":                                            false,
+		"/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerId + ".scope": false,
+	} {
+		b1, b2, _ := f.CanHandleAndAccept(k)
+		as.Equal(v, b1, k)
+		as.Equal(v, b2, k)
+	}
+}
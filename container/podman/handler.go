@@ -0,0 +1,317 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for Podman containers.
+package podman
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+const (
+	overlayStorageDriver = "overlay"
+	// Label with pod ID of containers which are members of a Podman pod.
+	podmanPodLabel = "io.podman.pod"
+	// Label with uid of the user owning a rootless container.
+	podmanRootlessUidLabel = "io.podman.rootless.uid"
+)
+
+type podmanContainerHandler struct {
+	client PodmanClient
+	name   string
+	id     string
+
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	fsInfo fs.FsInfo
+	// Writable layer of the container, empty if the storage driver is not supported.
+	rootfsStorageDir string
+
+	// Metadata associated with the container.
+	envs   map[string]string
+	labels map[string]string
+
+	// Image name used for this container.
+	image string
+
+	// Whether the container has its own network namespace.
+	ownNetwork bool
+
+	// Filesystem handler.
+	fsHandler common.FsHandler
+
+	// The IP address of the container
+	ipAddress string
+
+	includedMetrics container.MetricSet
+
+	reference info.ContainerReference
+
+	libcontainerHandler *containerlibcontainer.Handler
+	cgroupManager       cgroups.Manager
+	rootFs              string
+	pidKnown            bool
+}
+
+var _ container.ContainerHandler = &podmanContainerHandler{}
+
+// newPodmanContainerHandler returns a new container.ContainerHandler
+func newPodmanContainerHandler(
+	client PodmanClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	fsInfo fs.FsInfo,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	id := ContainerNameToPodmanId(name)
+	cInfo, err := client.ContainerInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var rootfsStorageDir string
+	if cInfo.GraphDriver.Name == overlayStorageDriver {
+		rootfsStorageDir = cInfo.GraphDriver.Data["UpperDir"]
+	}
+
+	labels := make(map[string]string, len(cInfo.Config.Labels)+2)
+	for k, v := range cInfo.Config.Labels {
+		labels[k] = v
+	}
+	if cInfo.Pod != "" {
+		labels[podmanPodLabel] = cInfo.Pod
+	}
+	if uid, ok := rootlessUid(name); ok {
+		labels[podmanRootlessUidLabel] = strconv.Itoa(uid)
+	}
+
+	containerReference := info.ContainerReference{
+		Id:        id,
+		Name:      name,
+		Aliases:   []string{strings.TrimPrefix(cInfo.Name, "/"), id},
+		Namespace: PodmanNamespace,
+	}
+
+	handler := &podmanContainerHandler{
+		client:              client,
+		name:                name,
+		id:                  id,
+		machineInfoFactory:  machineInfoFactory,
+		cgroupPaths:         cgroupPaths,
+		fsInfo:              fsInfo,
+		rootfsStorageDir:    rootfsStorageDir,
+		envs:                make(map[string]string),
+		labels:              labels,
+		image:               cInfo.ImageName,
+		ownNetwork:          hasOwnNetwork(cInfo.HostConfig.NetworkMode),
+		ipAddress:           cInfo.NetworkSettings.IPAddress,
+		includedMetrics:     includedMetrics,
+		reference:           containerReference,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, cInfo.State.Pid, includedMetrics),
+		cgroupManager:       cgroupManager,
+		rootFs:              rootFs,
+		// If pid is not known yet, network stats can not be retrieved and
+		// GetStats() will ask podman for it again.
+		pidKnown: cInfo.State.Pid != 0,
+	}
+
+	// we optionally collect disk usage metrics
+	if includedMetrics.Has(container.DiskUsageMetrics) && rootfsStorageDir != "" {
+		handler.fsHandler = common.NewFsHandler(common.DiskUsagePeriod(), rootfsStorageDir, "", fsInfo)
+	}
+
+	return handler, nil
+}
+
+// hasOwnNetwork returns false for containers sharing network namespace of
+// the host or of another container, e.g. infra container of their pod.
+func hasOwnNetwork(networkMode string) bool {
+	return networkMode != "host" && !strings.HasPrefix(networkMode, "container:")
+}
+
+func (h *podmanContainerHandler) Start() {
+	if h.fsHandler != nil {
+		h.fsHandler.Start()
+	}
+}
+
+func (h *podmanContainerHandler) Cleanup() {
+	if h.fsHandler != nil {
+		h.fsHandler.Stop()
+	}
+}
+
+func (h *podmanContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *podmanContainerHandler) needNet() bool {
+	return h.ownNetwork && h.includedMetrics.Has(container.NetworkUsageMetrics)
+}
+
+func (h *podmanContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasFilesystem := h.fsHandler != nil
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), hasFilesystem)
+
+	spec.Labels = h.labels
+	spec.Envs = h.envs
+	spec.Image = h.image
+
+	return spec, err
+}
+
+func (h *podmanContainerHandler) getFsStats(stats *info.ContainerStats) error {
+	mi, err := h.machineInfoFactory.GetMachineInfo()
+	if err != nil {
+		return err
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+
+	if h.fsHandler == nil {
+		return nil
+	}
+	deviceInfo, err := h.fsInfo.GetDirFsDevice(h.rootfsStorageDir)
+	if err != nil {
+		return fmt.Errorf("unable to determine device info for dir: %v: %v", h.rootfsStorageDir, err)
+	}
+	device := deviceInfo.Device
+
+	var (
+		limit  uint64
+		fsType string
+	)
+
+	// podman does not impose any filesystem limits for containers. So use capacity as limit.
+	for _, fs := range mi.Filesystems {
+		if fs.Device == device {
+			limit = fs.Capacity
+			fsType = fs.Type
+			break
+		}
+	}
+
+	if fsType == "" {
+		return fmt.Errorf("unable to determine fs type for device: %v", device)
+	}
+	fsStat := info.FsStats{Device: device, Type: fsType, Limit: limit}
+	usage := h.fsHandler.Usage()
+	fsStat.BaseUsage = usage.BaseUsageBytes
+	fsStat.Usage = usage.TotalUsageBytes
+	fsStat.Inodes = usage.InodeUsage
+
+	stats.Filesystem = append(stats.Filesystem, fsStat)
+
+	return nil
+}
+
+func (h *podmanContainerHandler) getLibcontainerHandler() *containerlibcontainer.Handler {
+	if h.pidKnown {
+		return h.libcontainerHandler
+	}
+
+	cInfo, err := h.client.ContainerInfo(h.id)
+	if err != nil || cInfo.State.Pid == 0 {
+		return h.libcontainerHandler
+	}
+
+	h.pidKnown = true
+	h.libcontainerHandler = containerlibcontainer.NewHandler(h.cgroupManager, h.rootFs, cInfo.State.Pid, h.includedMetrics)
+
+	return h.libcontainerHandler
+}
+
+func (h *podmanContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.getLibcontainerHandler().GetStats()
+	if err != nil {
+		return stats, err
+	}
+	// Clean up stats for containers that don't have their own network - this
+	// includes containers of pods which use the network of the infra container.
+	if !h.needNet() {
+		stats.Network = info.NetworkStats{}
+	}
+
+	// Get filesystem stats.
+	err = h.getFsStats(stats)
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+func (h *podmanContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	// No-op for podman driver.
+	return []info.ContainerReference{}, nil
+}
+
+func (h *podmanContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *podmanContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *podmanContainerHandler) GetContainerIPAddress() string {
+	return h.ipAddress
+}
+
+func (h *podmanContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *podmanContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *podmanContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypePodman
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"fmt"
+	"testing"
+
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	as := assert.New(t)
+	cInfo := &ContainerInfo{Id: testContainerId, Name: "web", ImageName: "docker.io/library/nginx:latest", Pod: "f2a3c2"}
+	cInfo.Config.Labels = map[string]string{"app": "web"}
+	cInfo.HostConfig.NetworkMode = "container:990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f81e5c2"
+
+	for _, ts := range []struct {
+		client         PodmanClient
+		name           string
+		hasErr         bool
+		errContains    string
+		checkReference *info.ContainerReference
+		checkLabels    map[string]string
+		ownNetwork     bool
+	}{
+		{
+			client:      mockPodmanClient(nil, fmt.Errorf("no client returned")),
+			name:        "/machine.slice/libpod-" + testContainerId + ".scope",
+			hasErr:      true,
+			errContains: "no client returned",
+		},
+		{
+			client:      mockPodmanClient(nil, nil),
+			name:        "/machine.slice/libpod-" + testContainerId + ".scope",
+			hasErr:      true,
+			errContains: "no container with id " + testContainerId,
+		},
+		{
+			client: mockPodmanClient(map[string]*ContainerInfo{testContainerId: cInfo}, nil),
+			name:   "/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerId + ".scope",
+			checkReference: &info.ContainerReference{
+				Id:        testContainerId,
+				Name:      "/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerId + ".scope",
+				Aliases:   []string{"web", testContainerId},
+				Namespace: PodmanNamespace,
+			},
+			checkLabels: map[string]string{
+				"app":                    "web",
+				"io.podman.pod":          "f2a3c2",
+				"io.podman.rootless.uid": "1000",
+			},
+		},
+		{
+			client: mockPodmanClient(map[string]*ContainerInfo{testContainerId: {Id: testContainerId, Name: "db"}}, nil),
+			name:   "/machine.slice/libpod-" + testContainerId + ".scope",
+			checkReference: &info.ContainerReference{
+				Id:        testContainerId,
+				Name:      "/machine.slice/libpod-" + testContainerId + ".scope",
+				Aliases:   []string{"db", testContainerId},
+				Namespace: PodmanNamespace,
+			},
+			checkLabels: map[string]string{},
+			ownNetwork:  true,
+		},
+	} {
+		handler, err := newPodmanContainerHandler(ts.client, ts.name, nil, nil, &containerlibcontainer.CgroupSubsystems{}, false, nil)
+		if ts.hasErr {
+			as.NotNil(err)
+			if ts.errContains != "" {
+				as.Contains(err.Error(), ts.errContains)
+			}
+			continue
+		}
+		as.Nil(err)
+		cr, err := handler.ContainerReference()
+		as.Nil(err)
+		as.Equal(*ts.checkReference, cr)
+		as.Equal(ts.checkLabels, handler.GetContainerLabels())
+		as.Equal(ts.ownNetwork, handler.(*podmanContainerHandler).ownNetwork)
+	}
+}
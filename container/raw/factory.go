@@ -50,6 +50,9 @@ type rawFactory struct {
 
 	// List of raw container cgroup path prefix whitelist.
 	rawPrefixWhiteList []string
+
+	// Rules which label and alias cgroups.
+	labelRules labelRules
 }
 
 func (f *rawFactory) String() string {
@@ -61,12 +64,12 @@ func (f *rawFactory) NewContainerHandler(name string, inHostNamespace bool) (con
 	if !inHostNamespace {
 		rootFs = "/rootfs"
 	}
-	return newRawContainerHandler(name, f.cgroupSubsystems, f.machineInfoFactory, f.fsInfo, f.watcher, rootFs, f.includedMetrics)
+	return newRawContainerHandler(name, f.cgroupSubsystems, f.machineInfoFactory, f.fsInfo, f.watcher, rootFs, f.includedMetrics, f.labelRules)
 }
 
-// The raw factory can handle any container. If --docker_only is set to true, non-docker containers are ignored except for "/" and those whitelisted by raw_cgroup_prefix_whitelist flag.
+// The raw factory can handle any container. If --docker_only is set to true, non-docker containers are ignored except for "/", those whitelisted by raw_cgroup_prefix_whitelist flag and those matched by raw_cgroup_label_rules.
 func (f *rawFactory) CanHandleAndAccept(name string) (bool, bool, error) {
-	if name == "/" {
+	if name == "/" || f.labelRules.matches(name) {
 		return true, true, nil
 	}
 	if *dockerOnly && f.rawPrefixWhiteList[0] == "" {
@@ -98,6 +101,11 @@ func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, incl
 		return err
 	}
 
+	labelRules, err := loadLabelRules(*labelRulesFile)
+	if err != nil {
+		return err
+	}
+
 	klog.V(1).Infof("Registering Raw factory")
 	factory := &rawFactory{
 		machineInfoFactory: machineInfoFactory,
@@ -106,6 +114,7 @@ func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, incl
 		watcher:            watcher,
 		includedMetrics:    includedMetrics,
 		rawPrefixWhiteList: rawPrefixWhiteList,
+		labelRules:         labelRules,
 	}
 	container.RegisterContainerHandlerFactory(factory, []watch.ContainerWatchSource{watch.Raw})
 	return nil
@@ -42,13 +42,17 @@ type rawContainerHandler struct {
 	includedMetrics container.MetricSet
 
 	libcontainerHandler *libcontainer.Handler
+
+	// Labels and aliases given to the cgroup by raw_cgroup_label_rules.
+	labels  map[string]string
+	aliases []string
 }
 
 func isRootCgroup(name string) bool {
 	return name == "/"
 }
 
-func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSubsystems, machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, watcher *common.InotifyWatcher, rootFs string, includedMetrics container.MetricSet) (container.ContainerHandler, error) {
+func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSubsystems, machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, watcher *common.InotifyWatcher, rootFs string, includedMetrics container.MetricSet, labelRules labelRules) (container.ContainerHandler, error) {
 	cHints, err := common.GetContainerHintsFromFile(*common.ArgContainerHints)
 	if err != nil {
 		return nil, err
@@ -78,6 +82,7 @@ func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSu
 	}
 
 	handler := libcontainer.NewHandler(cgroupManager, rootFs, pid, includedMetrics)
+	labels, aliases := labelRules.apply(name)
 
 	return &rawContainerHandler{
 		name:                name,
@@ -87,13 +92,16 @@ func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSu
 		externalMounts:      externalMounts,
 		includedMetrics:     includedMetrics,
 		libcontainerHandler: handler,
+		labels:              labels,
+		aliases:             aliases,
 	}, nil
 }
 
 func (h *rawContainerHandler) ContainerReference() (info.ContainerReference, error) {
-	// We only know the container by its one name.
+	// We only know the container by its one name and aliases given by label rules.
 	return info.ContainerReference{
-		Name: h.name,
+		Name:    h.name,
+		Aliases: h.aliases,
 	}, nil
 }
 
@@ -122,6 +130,9 @@ func (h *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	if err != nil {
 		return spec, err
 	}
+	if len(h.labels) > 0 {
+		spec.Labels = h.labels
+	}
 
 	if isRootCgroup(h.name) {
 		// Check physical network devices for root container.
@@ -252,7 +263,7 @@ func (h *rawContainerHandler) GetCgroupPath(resource string) (string, error) {
 }
 
 func (h *rawContainerHandler) GetContainerLabels() map[string]string {
-	return map[string]string{}
+	return h.labels
 }
 
 func (h *rawContainerHandler) GetContainerIPAddress() string {
@@ -0,0 +1,94 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+)
+
+var labelRulesFile = flag.String("raw_cgroup_label_rules", "", "location of the JSON file with rules which label and alias raw cgroups matched by regular expressions on their path")
+
+// labelRule labels and aliases raw cgroups whose path matches Pattern.
+// Labels and Alias may refer to submatches of Pattern as ${1} or ${name}.
+type labelRule struct {
+	Pattern string            `json:"pattern"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Alias   string            `json:"alias,omitempty"`
+
+	regexp *regexp.Regexp
+}
+
+type labelRules []labelRule
+
+// loadLabelRules reads and compiles rules from file, no rules are returned when file is empty.
+func loadLabelRules(file string) (labelRules, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw cgroup label rules: %v", err)
+	}
+	return parseLabelRules(data)
+}
+
+func parseLabelRules(data []byte) (labelRules, error) {
+	var rules labelRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse raw cgroup label rules: %v", err)
+	}
+	for i := range rules {
+		re, err := regexp.Compile(rules[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q of raw cgroup label rule: %v", rules[i].Pattern, err)
+		}
+		rules[i].regexp = re
+	}
+	return rules, nil
+}
+
+// matches returns true if any rule matches the cgroup.
+func (r labelRules) matches(name string) bool {
+	for _, rule := range r {
+		if rule.regexp.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns labels and aliases of the cgroup given by all rules which match
+// its path. Labels of later rules override labels of earlier ones.
+func (r labelRules) apply(name string) (map[string]string, []string) {
+	labels := map[string]string{}
+	var aliases []string
+	for _, rule := range r {
+		submatches := rule.regexp.FindStringSubmatchIndex(name)
+		if submatches == nil {
+			continue
+		}
+		for key, template := range rule.Labels {
+			labels[key] = string(rule.regexp.ExpandString(nil, template, name, submatches))
+		}
+		if rule.Alias != "" {
+			aliases = append(aliases, string(rule.regexp.ExpandString(nil, rule.Alias, name, submatches)))
+		}
+	}
+	return labels, aliases
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLabelRules = `[
+	{"pattern": "^/slurm/uid_(\\d+)/job_(?P<job>\\d+)$", "labels": {"slurm_uid": "${1}", "slurm_job": "${job}"}, "alias": "slurm-job-${job}"},
+	{"pattern": "^/nomad/(?P<alloc>[0-9a-f-]+)\\.(?P<task>[^/]+)$", "labels": {"nomad_alloc": "${alloc}", "nomad_task": "${task}"}, "alias": "${task}"},
+	{"pattern": "^/slurm/", "labels": {"scheduler": "slurm"}}
+]`
+
+func TestLabelRules(t *testing.T) {
+	rules, err := parseLabelRules([]byte(testLabelRules))
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name            string
+		expectedMatch   bool
+		expectedLabels  map[string]string
+		expectedAliases []string
+	}{
+		{
+			name:            "/slurm/uid_1000/job_42",
+			expectedMatch:   true,
+			expectedLabels:  map[string]string{"slurm_uid": "1000", "slurm_job": "42", "scheduler": "slurm"},
+			expectedAliases: []string{"slurm-job-42"},
+		},
+		{
+			name:           "/slurm/uid_1000",
+			expectedMatch:  true,
+			expectedLabels: map[string]string{"scheduler": "slurm"},
+		},
+		{
+			name:            "/nomad/7f1c0e3a-5d3b-4a4e-9c0e-2f6c8a1b9d2e.redis",
+			expectedMatch:   true,
+			expectedLabels:  map[string]string{"nomad_alloc": "7f1c0e3a-5d3b-4a4e-9c0e-2f6c8a1b9d2e", "nomad_task": "redis"},
+			expectedAliases: []string{"redis"},
+		},
+		{
+			name:           "/system.slice/docker.service",
+			expectedLabels: map[string]string{},
+		},
+	} {
+		assert.Equal(t, tc.expectedMatch, rules.matches(tc.name), tc.name)
+		labels, aliases := rules.apply(tc.name)
+		assert.Equal(t, tc.expectedLabels, labels, tc.name)
+		assert.Equal(t, tc.expectedAliases, aliases, tc.name)
+	}
+}
+
+func TestLabelRulesInvalid(t *testing.T) {
+	_, err := parseLabelRules([]byte(`[{"pattern": "^/slurm/($"}]`))
+	assert.Error(t, err)
+	_, err = parseLabelRules([]byte(`{"pattern": "^/slurm/"}`))
+	assert.Error(t, err)
+
+	rules, err := loadLabelRules("")
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+}
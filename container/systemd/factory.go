@@ -15,25 +15,66 @@
 package systemd
 
 import (
+	"flag"
 	"fmt"
+	"path"
+	"regexp"
 	"strings"
 
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/watcher"
 
+	"github.com/coreos/go-systemd/v22/dbus"
 	"k8s.io/klog/v2"
 )
 
-type systemdFactory struct{}
+var systemdUnits = flag.Bool("systemd_units", false, "Report cgroups of systemd services, slices and scopes as systemd containers labelled with unit name, slice and active state of the unit")
+
+// The namespace under which systemd aliases are unique.
+const SystemdNamespace = "systemd"
+
+// Regexp that identifies scopes of containers managed by container runtimes,
+// e.g. docker-<id>.scope, they are left to the runtime specific factories.
+var containerScopeRegexp = regexp.MustCompile(`[a-f0-9]{64}`)
+
+type systemdFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Client of systemd D-Bus API, nil when units are not reported.
+	client unitPropertiesGetter
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	includedMetrics container.MetricSet
+}
 
 func (f *systemdFactory) String() string {
 	return "systemd"
 }
 
 func (f *systemdFactory) NewContainerHandler(name string, inHostNamespace bool) (container.ContainerHandler, error) {
-	return nil, fmt.Errorf("Not yet supported")
+	if f.client == nil {
+		return nil, fmt.Errorf("Not yet supported")
+	}
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+	return newSystemdContainerHandler(f.client, name, f.machineInfoFactory, &f.cgroupSubsystems, rootFs, f.includedMetrics)
+}
+
+// isUnitName returns true if the cgroup with associated name corresponds to a
+// systemd service, slice or scope which is not a container.
+func isUnitName(name string) bool {
+	base := path.Base(name)
+	if !strings.HasSuffix(base, ".service") && !strings.HasSuffix(base, ".slice") && !strings.HasSuffix(base, ".scope") {
+		return false
+	}
+	return !containerScopeRegexp.MatchString(base)
 }
 
 func (f *systemdFactory) CanHandleAndAccept(name string) (bool, bool, error) {
@@ -42,6 +83,9 @@ func (f *systemdFactory) CanHandleAndAccept(name string) (bool, bool, error) {
 	if strings.HasSuffix(name, ".mount") {
 		return true, false, nil
 	}
+	if f.client != nil && isUnitName(name) {
+		return true, true, nil
+	}
 	klog.V(5).Infof("%s not handled by systemd handler", name)
 	return false, false, nil
 }
@@ -53,7 +97,22 @@ func (f *systemdFactory) DebugInfo() map[string][]string {
 // Register registers the systemd container factory.
 func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
 	klog.V(1).Infof("Registering systemd factory")
-	factory := &systemdFactory{}
+	factory := &systemdFactory{
+		machineInfoFactory: machineInfoFactory,
+		includedMetrics:    includedMetrics,
+	}
+	if *systemdUnits {
+		conn, err := dbus.NewSystemConnection()
+		if err != nil {
+			return fmt.Errorf("failed to connect to systemd: %v", err)
+		}
+		cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+		if err != nil {
+			return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+		}
+		factory.client = conn
+		factory.cgroupSubsystems = cgroupSubsystems
+	}
 	container.RegisterContainerHandlerFactory(factory, []watcher.ContainerWatchSource{watcher.Raw})
 	return nil
 }
@@ -0,0 +1,50 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanHandleAndAccept(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		reportUnits    bool
+		expectedHandle bool
+		expectedAccept bool
+	}{
+		{"/system.slice/var-lib-docker.mount", false, true, false},
+		{"/system.slice/var-lib-docker.mount", true, true, false},
+		{"/system.slice/docker.service", false, false, false},
+		{"/system.slice/docker.service", true, true, true},
+		{"/system.slice", true, true, true},
+		{"/user.slice/user-1000.slice/session-2.scope", true, true, true},
+		{"/system.slice/docker-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f.scope", true, false, false},
+		{"/system.slice/crio-conmon-81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f.scope", true, false, false},
+		{"/kubepods/besteffort/pod068e8fa0-9213-11e7-a01f-507b9d4141fa", true, false, false},
+		{"/", true, false, false},
+	} {
+		f := &systemdFactory{}
+		if tc.reportUnits {
+			f.client = &fakeUnitPropertiesGetter{}
+		}
+		handle, accept, err := f.CanHandleAndAccept(tc.name)
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expectedHandle, handle, tc.name)
+		assert.Equal(t, tc.expectedAccept, accept, tc.name)
+	}
+}
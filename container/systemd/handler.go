@@ -0,0 +1,193 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for systemd units.
+package systemd
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Labels with metadata of the systemd unit.
+	unitLabel        = "io.systemd.unit"
+	sliceLabel       = "io.systemd.slice"
+	activeStateLabel = "io.systemd.active_state"
+)
+
+// unitPropertiesGetter returns properties of systemd unit, it is implemented by dbus.Conn.
+type unitPropertiesGetter interface {
+	GetUnitProperties(unit string) (map[string]interface{}, error)
+}
+
+type systemdContainerHandler struct {
+	// Name of the container for this handler.
+	name string
+	// Name of the systemd unit, e.g. docker.service.
+	unit string
+
+	machineInfoFactory info.MachineInfoFactory
+	client             unitPropertiesGetter
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	// Metadata of the unit, refreshed with the spec.
+	labelsLock sync.RWMutex
+	labels     map[string]string
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *libcontainer.Handler
+}
+
+var _ container.ContainerHandler = &systemdContainerHandler{}
+
+func newSystemdContainerHandler(
+	client unitPropertiesGetter,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *libcontainer.CgroupSubsystems,
+	rootFs string,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	cgroupManager, err := libcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := &systemdContainerHandler{
+		name:                name,
+		unit:                path.Base(name),
+		machineInfoFactory:  machineInfoFactory,
+		client:              client,
+		cgroupPaths:         cgroupPaths,
+		includedMetrics:     includedMetrics,
+		libcontainerHandler: libcontainer.NewHandler(cgroupManager, rootFs, 0, includedMetrics),
+	}
+	handler.labels = handler.unitLabels()
+	return handler, nil
+}
+
+// unitLabels returns metadata of the unit as labels, only the unit name is
+// returned when properties of the unit can not be fetched from systemd.
+func (h *systemdContainerHandler) unitLabels() map[string]string {
+	labels := map[string]string{unitLabel: h.unit}
+	properties, err := h.client.GetUnitProperties(h.unit)
+	if err != nil {
+		klog.V(4).Infof("Unable to get properties of systemd unit %q: %v", h.unit, err)
+		return labels
+	}
+	if slice, ok := properties["Slice"].(string); ok && slice != "" {
+		labels[sliceLabel] = slice
+	}
+	if activeState, ok := properties["ActiveState"].(string); ok && activeState != "" {
+		labels[activeStateLabel] = activeState
+	}
+	return labels
+}
+
+func (h *systemdContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return info.ContainerReference{
+		Name:      h.name,
+		Aliases:   []string{h.unit},
+		Namespace: SystemdNamespace,
+	}, nil
+}
+
+// Nothing to start up.
+func (h *systemdContainerHandler) Start() {}
+
+// Nothing to clean up.
+func (h *systemdContainerHandler) Cleanup() {}
+
+func (h *systemdContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	const (
+		hasNetwork    = false
+		hasFilesystem = false
+	)
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, hasNetwork, hasFilesystem)
+	if err != nil {
+		return spec, err
+	}
+	// Active state of the unit changes over its life, so labels are refreshed with the spec.
+	labels := h.unitLabels()
+	h.labelsLock.Lock()
+	h.labels = labels
+	h.labelsLock.Unlock()
+	spec.Labels = labels
+	return spec, nil
+}
+
+func (h *systemdContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *systemdContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.name)
+	}
+	return path, nil
+}
+
+func (h *systemdContainerHandler) GetContainerLabels() map[string]string {
+	h.labelsLock.RLock()
+	defer h.labelsLock.RUnlock()
+	return h.labels
+}
+
+func (h *systemdContainerHandler) GetContainerIPAddress() string {
+	// the IP address of systemd units corresponds to the system ip address.
+	return "127.0.0.1"
+}
+
+func (h *systemdContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	return common.ListContainers(h.name, h.cgroupPaths, listType)
+}
+
+func (h *systemdContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *systemdContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *systemdContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeSystemd
+}
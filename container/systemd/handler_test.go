@@ -0,0 +1,84 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUnitPropertiesGetter struct {
+	properties map[string]map[string]interface{}
+}
+
+func (f *fakeUnitPropertiesGetter) GetUnitProperties(unit string) (map[string]interface{}, error) {
+	properties, ok := f.properties[unit]
+	if !ok {
+		return nil, fmt.Errorf("unit %s not found", unit)
+	}
+	return properties, nil
+}
+
+type machineInfoFactory struct{}
+
+func (m machineInfoFactory) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{}, nil
+}
+
+func (m machineInfoFactory) GetVersionInfo() (*info.VersionInfo, error) {
+	return &info.VersionInfo{}, nil
+}
+
+func TestHandler(t *testing.T) {
+	client := &fakeUnitPropertiesGetter{
+		properties: map[string]map[string]interface{}{
+			"docker.service": {
+				"Slice":       "system.slice",
+				"ActiveState": "active",
+				"SubState":    "running",
+			},
+		},
+	}
+
+	handler, err := newSystemdContainerHandler(client, "/system.slice/docker.service", machineInfoFactory{}, &libcontainer.CgroupSubsystems{}, "/", nil)
+	assert.NoError(t, err)
+	reference, err := handler.ContainerReference()
+	assert.NoError(t, err)
+	assert.Equal(t, info.ContainerReference{
+		Name:      "/system.slice/docker.service",
+		Aliases:   []string{"docker.service"},
+		Namespace: SystemdNamespace,
+	}, reference)
+	assert.Equal(t, map[string]string{
+		"io.systemd.unit":         "docker.service",
+		"io.systemd.slice":        "system.slice",
+		"io.systemd.active_state": "active",
+	}, handler.GetContainerLabels())
+
+	// Active state is refreshed with the spec.
+	client.properties["docker.service"]["ActiveState"] = "deactivating"
+	spec, _ := handler.GetSpec()
+	assert.Equal(t, "deactivating", spec.Labels["io.systemd.active_state"])
+	assert.Equal(t, "deactivating", handler.GetContainerLabels()["io.systemd.active_state"])
+
+	// Only the unit name is known when systemd does not report the unit.
+	handler, err = newSystemdContainerHandler(client, "/system.slice/gone.service", machineInfoFactory{}, &libcontainer.CgroupSubsystems{}, "/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"io.systemd.unit": "gone.service"}, handler.GetContainerLabels())
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package devicemapper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Size of sector used by device mapper in bytes.
+	sectorSize = 512
+	// Size of metadata block of thin pool in bytes, it is fixed by the kernel.
+	thinPoolMetadataBlockSize = 4096
+)
+
+// ThinPoolStatus holds usage of devicemapper thin pool in bytes.
+type ThinPoolStatus struct {
+	// Mode of the pool, e.g. rw, ro or out_of_data_space.
+	Mode string
+	// NeedsCheck is true when the pool needs to be repaired with thin_check.
+	NeedsCheck bool
+
+	DataUsage    uint64
+	DataCapacity uint64
+	// Free data space below which device mapper raises an event, configured in the table of the pool.
+	DataLowWaterMark uint64
+
+	MetadataUsage    uint64
+	MetadataCapacity uint64
+	// Free metadata space below which device mapper raises an event, reported by kernel 4.19 and newer.
+	MetadataLowWaterMark uint64
+}
+
+// GetThinPoolStatus returns usage of the thin pool from `dmsetup table` and
+// `dmsetup status` of the pool.
+//
+// Thin pool targets are detailed at
+// https://www.kernel.org/doc/Documentation/device-mapper/thin-provisioning.txt
+func GetThinPoolStatus(dmsetup DmsetupClient, poolName string) (*ThinPoolStatus, error) {
+	table, err := dmsetup.Table(poolName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table of thin pool %q: %v", poolName, err)
+	}
+	dataBlockSize, lowWaterMark, err := parseThinPoolTable(string(table))
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := dmsetup.Status(poolName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of thin pool %q: %v", poolName, err)
+	}
+	poolStatus, err := parseThinPoolStatus(string(status))
+	if err != nil {
+		return nil, err
+	}
+	poolStatus.DataUsage *= dataBlockSize
+	poolStatus.DataCapacity *= dataBlockSize
+	poolStatus.DataLowWaterMark = lowWaterMark * dataBlockSize
+	return poolStatus, nil
+}
+
+// parseThinPoolTable parses `dmsetup table` output of thin pool:
+//
+// <start> <length> thin-pool <metadata dev> <data dev> <data block size> <low water mark> ...
+//
+// and returns data block size in bytes and low water mark in data blocks.
+func parseThinPoolTable(table string) (uint64, uint64, error) {
+	fields := strings.Fields(table)
+	if len(fields) < 7 || fields[2] != "thin-pool" {
+		return 0, 0, fmt.Errorf("invalid dmsetup table output of thin pool: %q", table)
+	}
+	dataBlockSize, err := strconv.ParseUint(fields[5], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid data block size in dmsetup table output of thin pool %q: %v", table, err)
+	}
+	lowWaterMark, err := strconv.ParseUint(fields[6], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid low water mark in dmsetup table output of thin pool %q: %v", table, err)
+	}
+	return dataBlockSize * sectorSize, lowWaterMark, nil
+}
+
+// parseThinPoolStatus parses `dmsetup status` output of thin pool:
+//
+// <start> <length> thin-pool <transaction id> <used metadata blocks>/<total metadata blocks>
+// <used data blocks>/<total data blocks> <held metadata root> ro|rw|out_of_data_space
+// [no_]discard_passdown [error|queue]_if_no_space needs_check|- [metadata low watermark]
+//
+// Metadata usage is returned in bytes, data usage in data blocks.
+func parseThinPoolStatus(status string) (*ThinPoolStatus, error) {
+	fields := strings.Fields(status)
+	if len(fields) < 4 || fields[2] != "thin-pool" {
+		return nil, fmt.Errorf("invalid dmsetup status output of thin pool: %q", status)
+	}
+	if fields[3] == "Fail" || fields[3] == "Error" {
+		return nil, fmt.Errorf("thin pool is in failed state: %q", status)
+	}
+	if len(fields) < 6 {
+		return nil, fmt.Errorf("invalid dmsetup status output of thin pool: %q", status)
+	}
+
+	poolStatus := &ThinPoolStatus{}
+	var err error
+	poolStatus.MetadataUsage, poolStatus.MetadataCapacity, err = parseUsedTotal(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata usage in dmsetup status output of thin pool %q: %v", status, err)
+	}
+	poolStatus.DataUsage, poolStatus.DataCapacity, err = parseUsedTotal(fields[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid data usage in dmsetup status output of thin pool %q: %v", status, err)
+	}
+	// Mode and flags of the pool are not reported by old kernels.
+	if len(fields) > 7 {
+		poolStatus.Mode = fields[7]
+	}
+	if len(fields) > 10 {
+		poolStatus.NeedsCheck = fields[10] == "needs_check"
+	}
+	if len(fields) > 11 {
+		metadataLowWaterMark, err := strconv.ParseUint(fields[11], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata low watermark in dmsetup status output of thin pool %q: %v", status, err)
+		}
+		poolStatus.MetadataLowWaterMark = metadataLowWaterMark * thinPoolMetadataBlockSize
+	}
+	poolStatus.MetadataUsage *= thinPoolMetadataBlockSize
+	poolStatus.MetadataCapacity *= thinPoolMetadataBlockSize
+	return poolStatus, nil
+}
+
+// parseUsedTotal parses "<used>/<total>" pair of dmsetup status output.
+func parseUsedTotal(value string) (uint64, uint64, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected <used>/<total>, got %q", value)
+	}
+	used, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return used, total, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package devicemapper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/google/cadvisor/devicemapper/fake"
+)
+
+func TestGetThinPoolStatus(t *testing.T) {
+	const table = "0 209715200 thin-pool 253:1 253:2 128 32768 1 skip_block_zeroing"
+
+	cases := []struct {
+		name            string
+		dmsetupCommands []fake.DmsetupCommand
+		expectedStatus  *ThinPoolStatus
+		expectedError   bool
+	}{
+		{
+			name: "status with metadata low watermark",
+			dmsetupCommands: []fake.DmsetupCommand{
+				{Name: "table", Result: table},
+				{Name: "status", Result: "0 209715200 thin-pool 1 1233/4161600 52429/1638400 - rw no_discard_passdown queue_if_no_space - 1024"},
+			},
+			expectedStatus: &ThinPoolStatus{
+				Mode:                 "rw",
+				DataUsage:            52429 * 65536,
+				DataCapacity:         1638400 * 65536,
+				DataLowWaterMark:     32768 * 65536,
+				MetadataUsage:        1233 * 4096,
+				MetadataCapacity:     4161600 * 4096,
+				MetadataLowWaterMark: 1024 * 4096,
+			},
+		},
+		{
+			name: "pool out of data space which needs check",
+			dmsetupCommands: []fake.DmsetupCommand{
+				{Name: "table", Result: table},
+				{Name: "status", Result: "0 209715200 thin-pool 1 1233/4161600 1638400/1638400 - out_of_data_space discard_passdown error_if_no_space needs_check"},
+			},
+			expectedStatus: &ThinPoolStatus{
+				Mode:             "out_of_data_space",
+				NeedsCheck:       true,
+				DataUsage:        1638400 * 65536,
+				DataCapacity:     1638400 * 65536,
+				DataLowWaterMark: 32768 * 65536,
+				MetadataUsage:    1233 * 4096,
+				MetadataCapacity: 4161600 * 4096,
+			},
+		},
+		{
+			name: "status of old kernel without mode",
+			dmsetupCommands: []fake.DmsetupCommand{
+				{Name: "table", Result: table},
+				{Name: "status", Result: "0 75497472 thin-pool 65 327/524288 14092/589824 -"},
+			},
+			expectedStatus: &ThinPoolStatus{
+				DataUsage:        14092 * 65536,
+				DataCapacity:     589824 * 65536,
+				DataLowWaterMark: 32768 * 65536,
+				MetadataUsage:    327 * 4096,
+				MetadataCapacity: 524288 * 4096,
+			},
+		},
+		{
+			name: "failed pool",
+			dmsetupCommands: []fake.DmsetupCommand{
+				{Name: "table", Result: table},
+				{Name: "status", Result: "0 209715200 thin-pool Fail"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "not a thin pool",
+			dmsetupCommands: []fake.DmsetupCommand{
+				{Name: "table", Result: "0 209715200 linear 8:16 2048"},
+			},
+			expectedError: true,
+		},
+		{
+			name: "dmsetup status fails",
+			dmsetupCommands: []fake.DmsetupCommand{
+				{Name: "table", Result: table},
+				{Name: "status", Err: fmt.Errorf("not gonna work")},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dmsetup := fake.NewFakeDmsetupClient(t, tc.dmsetupCommands...)
+			status, err := GetThinPoolStatus(dmsetup, "docker-pool")
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, status)
+		})
+	}
+}
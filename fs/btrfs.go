@@ -0,0 +1,127 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package fs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// BTRFS_IOC_INO_LOOKUP, i.e. _IOWR(0x94, 18, struct btrfs_ioctl_ino_lookup_args) in asm-generic ioctl encoding.
+	btrfsIocInoLookup = 0xd0009412
+	// BTRFS_IOC_FS_INFO, i.e. _IOR(0x94, 31, struct btrfs_ioctl_fs_info_args) in asm-generic ioctl encoding.
+	btrfsIocFsInfo = 0x8400941f
+
+	// Inode number of the root directory of every btrfs subvolume (BTRFS_FIRST_FREE_OBJECTID).
+	btrfsSubvolumeRootIno = 256
+)
+
+// Path to sysfs directory of btrfs filesystems which reports usage of qgroups since kernel 5.9,
+// e.g. /sys/fs/btrfs/<fsid>/qgroups/0_<subvolume id>/referenced.
+// This is defined as a variable to help in testing.
+var btrfsSysFsPath = "/sys/fs/btrfs"
+
+// btrfsInoLookupArgs is struct btrfs_ioctl_ino_lookup_args from linux/btrfs.h.
+type btrfsInoLookupArgs struct {
+	treeID   uint64
+	objectID uint64
+	name     [4080]byte
+}
+
+// btrfsFsInfoArgs is struct btrfs_ioctl_fs_info_args from linux/btrfs.h, only leading fields are used.
+type btrfsFsInfoArgs struct {
+	maxID      uint64
+	numDevices uint64
+	fsid       [16]byte
+	reserved   [1024 - 32]byte
+}
+
+// getBtrfsSubvolume returns UUID of btrfs filesystem and ID of subvolume of which dir is the root.
+// This is defined as a variable to help in testing.
+var getBtrfsSubvolume = func(dir string) (string, uint64, error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if sys, ok := stat.Sys().(*syscall.Stat_t); !ok || sys.Ino != btrfsSubvolumeRootIno {
+		return "", 0, fmt.Errorf("%q is not root of btrfs subvolume", dir)
+	}
+
+	lookupArgs := btrfsInoLookupArgs{objectID: btrfsSubvolumeRootIno}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), btrfsIocInoLookup, uintptr(unsafe.Pointer(&lookupArgs)))
+	if errno != 0 {
+		return "", 0, fmt.Errorf("failed to get btrfs subvolume ID of %q: %v", dir, errno)
+	}
+	var fsInfoArgs btrfsFsInfoArgs
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), btrfsIocFsInfo, uintptr(unsafe.Pointer(&fsInfoArgs)))
+	if errno != 0 {
+		return "", 0, fmt.Errorf("failed to get btrfs filesystem info of %q: %v", dir, errno)
+	}
+	fsid := fsInfoArgs.fsid
+	uuid := fmt.Sprintf("%x-%x-%x-%x-%x", fsid[0:4], fsid[4:6], fsid[6:8], fsid[8:10], fsid[10:16])
+	return uuid, lookupArgs.treeID, nil
+}
+
+// getBtrfsQgroupUsage returns usage of dir from level 0 qgroup of btrfs subvolume without walking
+// the directory. Referenced bytes include extents shared with other subvolumes, e.g. the snapshot
+// of an image, exclusive bytes are used only by the subvolume. It is available only if dir is root
+// of a subvolume and quotas are enabled on the filesystem. Qgroups don't account inodes.
+func (i *RealFsInfo) getBtrfsQgroupUsage(dir string) (UsageInfo, error) {
+	device, err := i.GetDirFsDevice(dir)
+	if err != nil {
+		return UsageInfo{}, err
+	}
+	if i.partitions[device.Device].fsType != "btrfs" {
+		return UsageInfo{}, fmt.Errorf("%q is not on btrfs filesystem", dir)
+	}
+
+	fsid, subvolumeID, err := getBtrfsSubvolume(dir)
+	if err != nil {
+		return UsageInfo{}, err
+	}
+	qgroupDir := path.Join(btrfsSysFsPath, fsid, "qgroups", fmt.Sprintf("0_%d", subvolumeID))
+	referenced, err := readUint64File(path.Join(qgroupDir, "referenced"))
+	if err != nil {
+		return UsageInfo{}, fmt.Errorf("usage of qgroup of btrfs subvolume %d is not available: %v", subvolumeID, err)
+	}
+	exclusive, err := readUint64File(path.Join(qgroupDir, "exclusive"))
+	if err != nil {
+		return UsageInfo{}, fmt.Errorf("usage of qgroup of btrfs subvolume %d is not available: %v", subvolumeID, err)
+	}
+	return UsageInfo{Bytes: referenced, ExclusiveBytes: exclusive}, nil
+}
+
+func readUint64File(filePath string) (uint64, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
@@ -633,7 +633,19 @@ func GetDirUsage(dir string) (UsageInfo, error) {
 	return usage, err
 }
 
+// GetDirUsage returns usage of dir from project quota or btrfs qgroup if available, it walks the directory otherwise.
 func (i *RealFsInfo) GetDirUsage(dir string) (UsageInfo, error) {
+	usage, err := i.getProjectQuotaUsage(dir)
+	if err == nil {
+		return usage, nil
+	}
+	klog.V(5).Infof("Usage of %q is not available from project quota: %v", dir, err)
+	usage, err = i.getBtrfsQgroupUsage(dir)
+	if err == nil {
+		return usage, nil
+	}
+	klog.V(5).Infof("Usage of %q is not available from btrfs qgroup, walking the directory: %v", dir, err)
+
 	claimToken()
 	defer releaseToken()
 	return GetDirUsage(dir)
@@ -18,7 +18,9 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -140,6 +142,85 @@ func TestDirInodeUsage(t *testing.T) {
 	as.True(uint64(numFiles+1) == usage.Inodes, "expected inodes in dir to be %d; got inodes: %d", numFiles+1, usage.Inodes)
 }
 
+func TestDirUsageFromProjectQuota(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	buf := new(syscall.Stat_t)
+	require.NoError(t, syscall.Stat(dir, buf))
+
+	fsInfo := &RealFsInfo{
+		partitions: map[string]partition{
+			"/dev/sda1": {major: major(uint64(buf.Dev)), minor: minor(uint64(buf.Dev)), fsType: "xfs"}, // nolint: unconvert
+		},
+	}
+	projectIDs := map[string]uint32{dir: 7, filepath.Dir(dir): 0}
+	originalGetProjectID, originalGetProjectQuota := getProjectID, getProjectQuota
+	getProjectID = func(dir string) (uint32, bool, error) {
+		return projectIDs[dir], true, nil
+	}
+	getProjectQuota = func(device string, projectID uint32) (UsageInfo, error) {
+		assert.Equal(t, "/dev/sda1", device)
+		assert.Equal(t, uint32(7), projectID)
+		return UsageInfo{Bytes: 1 << 30, Inodes: 1000}, nil
+	}
+	defer func() {
+		getProjectID, getProjectQuota = originalGetProjectID, originalGetProjectQuota
+	}()
+
+	usage, err := fsInfo.GetDirUsage(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, UsageInfo{Bytes: 1 << 30, Inodes: 1000}, usage)
+
+	// Project shared with the parent directory is not used, the directory is walked.
+	projectIDs[filepath.Dir(dir)] = 7
+	usage, err = fsInfo.GetDirUsage(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), usage.Inodes)
+
+	// Project quota is not supported.
+	projectIDs[filepath.Dir(dir)] = 0
+	fsInfo.partitions["/dev/sda1"] = partition{major: major(uint64(buf.Dev)), minor: minor(uint64(buf.Dev)), fsType: "btrfs"} // nolint: unconvert
+	usage, err = fsInfo.GetDirUsage(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), usage.Inodes)
+}
+
+func TestDirUsageFromBtrfsQgroup(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	buf := new(syscall.Stat_t)
+	require.NoError(t, syscall.Stat(dir, buf))
+
+	fsInfo := &RealFsInfo{
+		partitions: map[string]partition{
+			"/dev/sdb1": {major: major(uint64(buf.Dev)), minor: minor(uint64(buf.Dev)), fsType: "btrfs"}, // nolint: unconvert
+		},
+	}
+	originalGetBtrfsSubvolume, originalBtrfsSysFsPath := getBtrfsSubvolume, btrfsSysFsPath
+	getBtrfsSubvolume = func(dir string) (string, uint64, error) {
+		return "2a7f6c1e-5b0d-4f3a-9c8e-1d2b3c4d5e6f", 258, nil
+	}
+	btrfsSysFsPath = "test_resources/btrfs"
+	defer func() {
+		getBtrfsSubvolume, btrfsSysFsPath = originalGetBtrfsSubvolume, originalBtrfsSysFsPath
+	}()
+
+	usage, err := fsInfo.GetDirUsage(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, UsageInfo{Bytes: 1073741824, ExclusiveBytes: 16384}, usage)
+
+	// Quotas are not enabled, the directory is walked.
+	getBtrfsSubvolume = func(dir string) (string, uint64, error) {
+		return "2a7f6c1e-5b0d-4f3a-9c8e-1d2b3c4d5e6f", 259, nil
+	}
+	usage, err = fsInfo.GetDirUsage(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), usage.Inodes)
+	assert.Equal(t, uint64(0), usage.ExclusiveBytes)
+}
+
 var dmStatusTests = []struct {
 	dmStatus    string
 	used        uint64
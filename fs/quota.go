@@ -0,0 +1,121 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// FS_IOC_FSGETXATTR, i.e. _IOR('X', 31, struct fsxattr) in asm-generic ioctl encoding.
+	fsIocFsGetXAttr = 0x801c581f
+	// FS_XFLAG_PROJINHERIT, files and directories created in the directory inherit its project ID.
+	fsXFlagProjInherit = 0x00000200
+
+	// QCMD(Q_GETQUOTA, PRJQUOTA)
+	qGetProjectQuota = 0x800007<<8 | 2
+)
+
+// fsxattr is struct fsxattr from linux/fs.h.
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// ifDqblk is struct if_dqblk from linux/quota.h.
+type ifDqblk struct {
+	bhardlimit uint64
+	bsoftlimit uint64
+	curspace   uint64
+	ihardlimit uint64
+	isoftlimit uint64
+	curinodes  uint64
+	btime      uint64
+	itime      uint64
+	valid      uint32
+}
+
+// getProjectID returns project ID of dir and whether it is inherited by files created in dir.
+// This is defined as a variable to help in testing.
+var getProjectID = func(dir string) (uint32, bool, error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	var attr fsxattr
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), fsIocFsGetXAttr, uintptr(unsafe.Pointer(&attr)))
+	if errno != 0 {
+		return 0, false, fmt.Errorf("failed to get project ID of %q: %v", dir, errno)
+	}
+	return attr.projid, attr.xflags&fsXFlagProjInherit != 0, nil
+}
+
+// getProjectQuota returns bytes and inodes accounted to the project on the block device.
+// This is defined as a variable to help in testing.
+var getProjectQuota = func(device string, projectID uint32) (UsageInfo, error) {
+	devicePtr, err := syscall.BytePtrFromString(device)
+	if err != nil {
+		return UsageInfo{}, err
+	}
+	var quota ifDqblk
+	_, _, errno := syscall.Syscall6(syscall.SYS_QUOTACTL, qGetProjectQuota, uintptr(unsafe.Pointer(devicePtr)), uintptr(projectID), uintptr(unsafe.Pointer(&quota)), 0, 0)
+	if errno != 0 {
+		return UsageInfo{}, fmt.Errorf("failed to get quota of project %d on %q: %v", projectID, device, errno)
+	}
+	return UsageInfo{Bytes: quota.curspace, Inodes: quota.curinodes}, nil
+}
+
+// getProjectQuotaUsage returns usage of dir from project quota of XFS or ext4 filesystem without
+// walking the directory. It is available only if dir is the root of a dedicated project, i.e. it has
+// a project ID inherited by its content (e.g. assigned by the container runtime) which differs from
+// project ID of its parent directory. Quota accounting must be enabled for projects on the filesystem.
+func (i *RealFsInfo) getProjectQuotaUsage(dir string) (UsageInfo, error) {
+	device, err := i.GetDirFsDevice(dir)
+	if err != nil {
+		return UsageInfo{}, err
+	}
+	fsType := i.partitions[device.Device].fsType
+	if fsType != "xfs" && fsType != "ext4" {
+		return UsageInfo{}, fmt.Errorf("project quota is not supported on %q filesystem", fsType)
+	}
+
+	projectID, inherited, err := getProjectID(dir)
+	if err != nil {
+		return UsageInfo{}, err
+	}
+	if projectID == 0 || !inherited {
+		return UsageInfo{}, fmt.Errorf("%q has no inherited project ID", dir)
+	}
+	parentProjectID, _, err := getProjectID(filepath.Dir(dir))
+	if err != nil {
+		return UsageInfo{}, err
+	}
+	if parentProjectID == projectID {
+		return UsageInfo{}, fmt.Errorf("project %d of %q is shared with its parent directory", projectID, dir)
+	}
+	return getProjectQuota(device.Device, projectID)
+}
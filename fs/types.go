@@ -82,6 +82,8 @@ type DiskStats struct {
 type UsageInfo struct {
 	Bytes  uint64
 	Inodes uint64
+	// Bytes not shared with other subvolumes, set only if usage is read from btrfs qgroup.
+	ExclusiveBytes uint64
 }
 
 // ErrNoSuchDevice is the error indicating the requested device does not exist.
@@ -3,24 +3,23 @@ module github.com/google/cadvisor
 go 1.13
 
 require (
-	cloud.google.com/go v0.26.0
+	cloud.google.com/go v0.34.0
 	github.com/Azure/go-ansiterm v0.0.0-20170929234023-d6e3b3328b78 // indirect
 	github.com/Microsoft/go-winio v0.4.15-0.20190919025122-fc70bd9a86b5 // indirect
 	github.com/aws/aws-sdk-go v1.6.10
 	github.com/blang/semver v3.1.0+incompatible
-	github.com/cilium/ebpf v0.0.0-20200702112145-1c8d4c9ef775 // indirect
+	github.com/cilium/ebpf v0.0.0-20200702112145-1c8d4c9ef775
 	github.com/containerd/containerd v1.4.0-beta.2
 	github.com/containerd/ttrpc v1.0.1 // indirect
 	github.com/containerd/typeurl v1.0.1
-	github.com/coreos/go-systemd/v22 v22.1.0 // indirect
+	github.com/coreos/go-systemd/v22 v22.1.0
 	github.com/docker/distribution v2.7.1-0.20190205005809-0d3efadf0154+incompatible // indirect
 	github.com/docker/docker v0.7.3-0.20190327010347-be7ac8be2ae0
 	github.com/docker/go-connections v0.4.0
 	github.com/docker/go-units v0.4.0
 	github.com/euank/go-kmsg-parser v2.0.0+incompatible
 	github.com/go-ini/ini v1.9.0 // indirect
-	github.com/gogo/protobuf v1.3.1
-	github.com/google/uuid v1.1.1 // indirect
+	github.com/gogo/protobuf v1.3.2
 	github.com/gorilla/mux v1.7.3 // indirect
 	github.com/jmespath/go-jmespath v0.0.0-20160202185014-0b12d6b521d8 // indirect
 	github.com/karrick/godirwalk v1.7.5
@@ -36,18 +35,17 @@ require (
 	github.com/opencontainers/selinux v1.5.2 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.7.1
-	github.com/prometheus/client_model v0.2.0
+	github.com/prometheus/client_model v0.3.0
 	github.com/prometheus/common v0.10.0
 	github.com/smartystreets/goconvey v1.6.4 // indirect
-	github.com/stretchr/testify v1.4.0
+	github.com/stretchr/testify v1.7.0
 	github.com/vishvananda/netns v0.0.0-20200520041808-52d707b772fe // indirect
-	golang.org/x/net v0.0.0-20191209160850-c0dbc17a3553
-	golang.org/x/sys v0.0.0-20200819035508-9a32b3aa38f5
-	golang.org/x/text v0.3.3 // indirect
+	golang.org/x/net v0.0.0-20210825183410-e898025ed96a
+	golang.org/x/sys v0.0.0-20210831042530-f4d43177bf5e
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
-	google.golang.org/grpc v1.27.0
-	google.golang.org/protobuf v1.24.0 // indirect
+	google.golang.org/grpc v1.40.0
 	gotest.tools v2.2.0+incompatible // indirect
+	k8s.io/cri-api v0.23.0
 	k8s.io/klog/v2 v2.2.0
 	k8s.io/mount-utils v0.20.0-alpha.1
 	k8s.io/utils v0.0.0-20200729134348-d5654de09c73
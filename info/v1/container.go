@@ -25,6 +25,9 @@ type CpuSpec struct {
 	Mask     string `json:"mask,omitempty"`
 	Quota    uint64 `json:"quota,omitempty"`
 	Period   uint64 `json:"period,omitempty"`
+	// Amount of unused quota that can be accumulated and used above quota in later periods.
+	// Units: microseconds.
+	Burst uint64 `json:"burst,omitempty"`
 }
 
 type MemorySpec struct {
@@ -309,6 +312,13 @@ type CpuCFS struct {
 	// Total time duration for which tasks in the cgroup have been throttled.
 	// Unit: nanoseconds.
 	ThrottledTime uint64 `json:"throttled_time"`
+
+	// Total number of periods in which tasks in the cgroup used accumulated burst, only on cgroup v2.
+	BurstPeriods uint64 `json:"burst_periods,omitempty"`
+
+	// Total time duration that tasks in the cgroup ran above quota using burst, only on cgroup v2.
+	// Unit: nanoseconds.
+	BurstTime uint64 `json:"burst_time,omitempty"`
 }
 
 // Cpu Aggregated scheduler statistics
@@ -333,6 +343,44 @@ type CpuStats struct {
 	// Load is smoothed over the last 10 seconds. Instantaneous value can be read
 	// from LoadStats.NrRunning.
 	LoadAverage int32 `json:"load_average"`
+	// Pressure stall information of CPU, "full" is reported by kernels 5.13 and newer.
+	PSI PSIStats `json:"psi"`
+	// Time threads of the container spent runnable in run queue before being scheduled,
+	// measured by eBPF programs attached to scheduler tracepoints.
+	RunqueueLatency *LatencyHistogram `json:"runqueue_latency,omitempty"`
+}
+
+// LatencyHistogram holds distribution of latencies in buckets of exponentially growing width.
+type LatencyHistogram struct {
+	// Number of latencies falling into each bucket. Bucket i counts latencies shorter than
+	// 2^(i+1) microseconds which don't fall into previous buckets. Latencies exceeding
+	// upper bound of the last bucket are included only in Count and Sum.
+	Buckets []uint64 `json:"buckets"`
+	// Number of all measured latencies.
+	Count uint64 `json:"count"`
+	// Sum of all measured latencies.
+	// Unit: nanoseconds.
+	Sum uint64 `json:"sum"`
+}
+
+// PSIData holds pressure stall information of one kind (some or full) as reported in
+// <resource>.pressure files, see https://www.kernel.org/doc/html/latest/accounting/psi.html
+type PSIData struct {
+	// Total time tasks were stalled on the resource.
+	// Unit: nanoseconds.
+	Total uint64 `json:"total"`
+	// Percentage of time tasks were stalled over 10, 60 and 300 second windows.
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+}
+
+// PSIStats holds pressure stall information of a resource.
+type PSIStats struct {
+	// Time at least one task was stalled on the resource.
+	Some PSIData `json:"some,omitempty"`
+	// Time all non-idle tasks were stalled on the resource simultaneously.
+	Full PSIData `json:"full,omitempty"`
 }
 
 type PerDiskStats struct {
@@ -351,6 +399,8 @@ type DiskIoStats struct {
 	IoWaitTime     []PerDiskStats `json:"io_wait_time,omitempty"`
 	IoMerged       []PerDiskStats `json:"io_merged,omitempty"`
 	IoTime         []PerDiskStats `json:"io_time,omitempty"`
+	// Pressure stall information of IO.
+	PSI PSIStats `json:"psi"`
 }
 
 type HugetlbStats struct {
@@ -397,6 +447,32 @@ type MemoryStats struct {
 
 	ContainerData    MemoryStatsMemoryData `json:"container_data,omitempty"`
 	HierarchicalData MemoryStatsMemoryData `json:"hierarchical_data,omitempty"`
+
+	// Pressure stall information of memory.
+	PSI PSIStats `json:"psi"`
+
+	// Page reclaim and refault activity, available only on cgroup v2.
+	Reclaim MemoryReclaimStats `json:"reclaim,omitempty"`
+
+	// Cumulative count of processes killed by OOM killer in the container.
+	OOMKills uint64 `json:"oom_kills"`
+}
+
+// MemoryReclaimStats holds counters from memory.stat of cgroup v2 that help
+// detect memory thrashing: refaults of recently evicted pages and their activations.
+type MemoryReclaimStats struct {
+	// Cumulative count of refaults of previously evicted anonymous pages.
+	WorkingsetRefaultAnon uint64 `json:"workingset_refault_anon"`
+	// Cumulative count of refaults of previously evicted file pages.
+	WorkingsetRefaultFile uint64 `json:"workingset_refault_file"`
+	// Cumulative count of refaulted anonymous pages that were immediately activated.
+	WorkingsetActivateAnon uint64 `json:"workingset_activate_anon"`
+	// Cumulative count of refaulted file pages that were immediately activated.
+	WorkingsetActivateFile uint64 `json:"workingset_activate_file"`
+	// Cumulative count of pages scanned by page reclaim.
+	Pgscan uint64 `json:"pgscan"`
+	// Cumulative count of pages reclaimed.
+	Pgsteal uint64 `json:"pgsteal"`
 }
 
 type MemoryNumaStats struct {
@@ -445,6 +521,33 @@ type NetworkStats struct {
 	Udp6 UdpStat `json:"udp6"`
 	// TCP advanced stats
 	TcpAdvanced TcpAdvancedStat `json:"tcp_advanced"`
+	// UDP protocol stats of network namespace (Udp line of /proc/net/snmp)
+	UdpAdvanced UdpAdvancedStat `json:"udp_advanced"`
+	// Usage of conntrack table of network namespace
+	Conntrack ConntrackStat `json:"conntrack"`
+	// Traffic of processes of the cgroup regardless of their network namespace
+	Cgroup *CgroupNetworkStats `json:"cgroup,omitempty"`
+}
+
+// CgroupNetworkStats holds network traffic of processes of cgroup accounted by eBPF programs
+// attached to the cgroup, including traffic of descendant cgroups.
+type CgroupNetworkStats struct {
+	// Cumulative count of bytes received.
+	RxBytes uint64 `json:"rx_bytes"`
+	// Cumulative count of packets received.
+	RxPackets uint64 `json:"rx_packets"`
+	// Cumulative count of bytes transmitted.
+	TxBytes uint64 `json:"tx_bytes"`
+	// Cumulative count of packets transmitted.
+	TxPackets uint64 `json:"tx_packets"`
+}
+
+// ConntrackStat holds usage of connection tracking table of network namespace.
+type ConntrackStat struct {
+	// Number of entries in conntrack table
+	Entries uint64 `json:"entries"`
+	// Maximum number of entries in conntrack table
+	Max uint64 `json:"max"`
 }
 
 type TcpStat struct {
@@ -711,6 +814,24 @@ type TcpAdvancedStat struct {
 	PAWSEstab uint64
 }
 
+// UdpAdvancedStat holds UDP protocol counters of network namespace.
+type UdpAdvancedStat struct {
+	// The number of datagrams delivered to UDP users
+	InDatagrams uint64
+	// The number of received datagrams for which there was no application at the destination port
+	NoPorts uint64
+	// The number of received datagrams that could not be delivered for reasons other than no port
+	InErrors uint64
+	// The number of datagrams sent
+	OutDatagrams uint64
+	// The number of datagrams dropped because socket receive buffer was full
+	RcvbufErrors uint64
+	// The number of datagrams dropped because socket send buffer was full
+	SndbufErrors uint64
+	// The number of datagrams with checksum errors
+	InCsumErrors uint64
+}
+
 type UdpStat struct {
 	// Count of UDP sockets in state "Listen"
 	Listen uint64
@@ -742,6 +863,12 @@ type FsStats struct {
 	// This field is only applicable for docker container's as of now.
 	BaseUsage uint64 `json:"base_usage"`
 
+	// Number of bytes consumed by the container's writable layer, i.e. upper and work directories
+	// of overlayfs. It does not include image layers, volumes and logs of the container.
+	// This field is only applicable for docker containers using overlay2 storage driver
+	// and btrfs storage driver with quotas enabled.
+	WritableLayerUsage uint64 `json:"writable_layer_usage,omitempty"`
+
 	// Number of bytes available for non-root user.
 	Available uint64 `json:"available"`
 
@@ -830,6 +957,10 @@ type AcceleratorStats struct {
 	// Percent of time over the past sample period during which
 	// the accelerator was actively processing.
 	DutyCycle uint64 `json:"duty_cycle"`
+
+	// Average power usage over the past sample period, 0 when it is not supported by the accelerator.
+	// unit: milliwatts
+	PowerUsage uint64 `json:"power_usage,omitempty"`
 }
 
 // PerfStat represents value of a single monitored perf event.
@@ -920,6 +1051,37 @@ type ProcessStats struct {
 
 	// Ulimits for the top-level container process
 	Ulimits []UlimitSpec `json:"ulimits,omitempty"`
+
+	// Processes of container using the most CPU time, sorted in descending order
+	TopProcesses []ProcessUsage `json:"top_processes,omitempty"`
+}
+
+// ProcessUsage holds resource usage of a single process running in container.
+type ProcessUsage struct {
+	Pid int `json:"pid"`
+
+	// Name of executable of the process (comm)
+	Command string `json:"command"`
+
+	// Cumulative CPU time consumed by the process in user and kernel mode, in nanoseconds
+	CpuTime uint64 `json:"cpu_time"`
+
+	// Resident set size in bytes
+	RSS uint64 `json:"rss"`
+
+	// Number of open file descriptors
+	FdCount uint64 `json:"fd_count"`
+
+	// Number of threads of the process
+	Threads uint64 `json:"threads"`
+}
+
+// ReferencedMemoryWindows holds referenced memory in bytes averaged over
+// time windows with exponential decay, similarly to load average.
+type ReferencedMemoryWindows struct {
+	Window1m  uint64 `json:"window_1m"`
+	Window5m  uint64 `json:"window_5m"`
+	Window15m uint64 `json:"window_15m"`
 }
 
 type ContainerStats struct {
@@ -939,6 +1101,10 @@ type ContainerStats struct {
 	// Metrics for Accelerators. Each Accelerator corresponds to one element in the array.
 	Accelerators []AcceleratorStats `json:"accelerators,omitempty"`
 
+	// Time in nanoseconds during which GPU engines were busy with work submitted by
+	// container processes, by engine name (e.g. render, video), read from DRM fdinfo.
+	GpuEngineBusyTime map[string]uint64 `json:"gpu_engine_busy_time,omitempty"`
+
 	// ProcessStats for Containers
 	Processes ProcessStats `json:"processes,omitempty"`
 
@@ -955,8 +1121,35 @@ type ContainerStats struct {
 	// Referenced memory
 	ReferencedMemory uint64 `json:"referenced_memory,omitempty"`
 
+	// Whether referenced memory is an approximation based on cgroup memory statistics,
+	// used when referenced bytes cannot be read from smaps.
+	ReferencedMemoryApproximate bool `json:"referenced_memory_approximate,omitempty"`
+
+	// Whether referenced memory accounts only part of container processes,
+	// because measurement exceeded its time budget.
+	ReferencedMemoryPartial bool `json:"referenced_memory_partial,omitempty"`
+
+	// Referenced memory per NUMA node
+	ReferencedMemoryByNode map[uint8]uint64 `json:"referenced_memory_by_node,omitempty"`
+
+	// Referenced memory by number of samples in which it was found accessed during
+	// aggregation interval, available when DAMON is used to measure referenced memory.
+	ReferencedMemoryAccessHistogram map[uint64]uint64 `json:"referenced_memory_access_histogram,omitempty"`
+
+	// Referenced memory of anonymous mappings, e.g. heap and stack
+	ReferencedMemoryAnon uint64 `json:"referenced_memory_anon,omitempty"`
+
+	// Referenced memory of file-backed mappings, i.e. page cache
+	ReferencedMemoryFile uint64 `json:"referenced_memory_file,omitempty"`
+
+	// Referenced memory averaged over time windows
+	ReferencedMemoryWindows *ReferencedMemoryWindows `json:"referenced_memory_windows,omitempty"`
+
 	// Resource Control (resctrl) statistics
 	Resctrl ResctrlStats `json:"resctrl,omitempty"`
+
+	// Number of selected system calls entered by threads of the container, by name of system call.
+	Syscalls map[string]uint64 `json:"syscalls,omitempty"`
 }
 
 func timeEq(t1, t2 time.Time, tolerance time.Duration) bool {
@@ -1012,6 +1205,9 @@ func (a *ContainerStats) StatsEq(b *ContainerStats) bool {
 	if !reflect.DeepEqual(a.Accelerators, b.Accelerators) {
 		return false
 	}
+	if !reflect.DeepEqual(a.GpuEngineBusyTime, b.GpuEngineBusyTime) {
+		return false
+	}
 	if !reflect.DeepEqual(a.CustomMetrics, b.CustomMetrics) {
 		return false
 	}
@@ -1045,12 +1241,64 @@ const (
 	EventOomKill           EventType = "oomKill"
 	EventContainerCreation EventType = "containerCreation"
 	EventContainerDeletion EventType = "containerDeletion"
+	// Machine info changed when it was refreshed, e.g. after CPUs or memory were hotplugged, reported for root container.
+	EventMachineChanged EventType = "machineChanged"
+	// Number of tasks of the container approached the limit of pids cgroup.
+	EventPidsLimit EventType = "pidsLimit"
+	// Metadata usage of devicemapper thin pool approached its capacity, reported for root container.
+	EventThinPoolMetadata EventType = "thinPoolMetadata"
+	// Number of entries of conntrack table of network namespace of the container approached the limit.
+	EventConntrackLimit EventType = "conntrackLimit"
 )
 
 // Extra information about an event. Only one type will be set.
 type EventData struct {
 	// Information about an OOM kill event.
 	OomKill *OomKillEventData `json:"oom,omitempty"`
+	// Information about a machine changed event.
+	MachineChanged *MachineChangedEventData `json:"machine_changed,omitempty"`
+	// Information about a pids limit event.
+	PidsLimit *PidsLimitEventData `json:"pids_limit,omitempty"`
+	// Information about a thin pool metadata event.
+	ThinPoolMetadata *ThinPoolMetadataEventData `json:"thin_pool_metadata,omitempty"`
+	// Information about a conntrack limit event.
+	ConntrackLimit *ConntrackLimitEventData `json:"conntrack_limit,omitempty"`
+}
+
+// Information related to number of conntrack entries approaching the limit
+type ConntrackLimitEventData struct {
+	// Number of entries in conntrack table
+	Entries uint64 `json:"entries"`
+
+	// Maximum number of entries in conntrack table
+	Max uint64 `json:"max"`
+}
+
+// Information related to metadata usage of devicemapper thin pool approaching its capacity
+type ThinPoolMetadataEventData struct {
+	// Name of the thin pool
+	Pool string `json:"pool"`
+
+	// Used metadata space of the thin pool in bytes
+	Usage uint64 `json:"usage"`
+
+	// Total metadata space of the thin pool in bytes
+	Capacity uint64 `json:"capacity"`
+}
+
+// Information related to number of tasks approaching the limit of pids cgroup
+type PidsLimitEventData struct {
+	// Number of tasks (processes and threads) in the container
+	Current uint64 `json:"current"`
+
+	// Maximum number of tasks allowed by pids cgroup
+	Limit uint64 `json:"limit"`
+}
+
+// Information related to a change of machine info
+type MachineChangedEventData struct {
+	// JSON names of fields of machine info which changed, e.g. memory_capacity
+	ChangedFields []string `json:"changed_fields"`
 }
 
 // Information related to an OOM kill instance
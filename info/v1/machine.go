@@ -40,10 +40,22 @@ type FsInfo struct {
 type Node struct {
 	Id int `json:"node_id"`
 	// Per-node memory
-	Memory    uint64          `json:"memory"`
-	HugePages []HugePagesInfo `json:"hugepages"`
-	Cores     []Core          `json:"cores"`
-	Caches    []Cache         `json:"caches"`
+	Memory uint64 `json:"memory"`
+	// Per-node memory usage as reported in /sys/devices/system/node/node*/meminfo,
+	// read on each request of machine API or metrics.
+	MemoryFree uint64          `json:"memory_free,omitempty"`
+	MemoryFile uint64          `json:"memory_file,omitempty"`
+	MemoryAnon uint64          `json:"memory_anon,omitempty"`
+	HugePages  []HugePagesInfo `json:"hugepages"`
+	Cores      []Core          `json:"cores"`
+	Caches     []Cache         `json:"caches"`
+	// Caches shared by groups of cores which do not span whole node, e.g. L2 cache of core cluster
+	// or L3 cache of die, or span CPUs of several nodes, e.g. L3 cache of socket with sub-NUMA clustering.
+	// Caches shared with other nodes are listed by each of them.
+	UncoreCaches []UncoreCache `json:"uncore_caches,omitempty"`
+	// Distances to all NUMA nodes, indexed by node id, as reported in
+	// /sys/devices/system/node/node*/distance
+	Distances []uint64 `json:"distances,omitempty"`
 }
 
 type Core struct {
@@ -51,8 +63,29 @@ type Core struct {
 	Threads  []int   `json:"thread_ids"`
 	Caches   []Cache `json:"caches"`
 	SocketID int     `json:"socket_id"`
+	// Die of the core within socket, as reported in topology/die_id.
+	DieID string `json:"die_id,omitempty"`
+	// Cluster of cores sharing L2 cache or other resources, as reported in topology/cluster_id.
+	ClusterID string `json:"cluster_id,omitempty"`
+	// Book and drawer of the core on s390x, as reported in topology/book_id and topology/drawer_id.
+	BookID   string `json:"book_id,omitempty"`
+	DrawerID string `json:"drawer_id,omitempty"`
+	// Type of core on hybrid CPUs, CoreTypePerformance or CoreTypeEfficiency.
+	CoreType string `json:"core_type,omitempty"`
+	// Capacity of core relative to the most performant core in the system scaled to 1024,
+	// as reported in cpu_capacity on arm64.
+	Capacity uint64 `json:"capacity,omitempty"`
+	// Maximal frequency of core in kHz.
+	MaxFrequency uint64 `json:"max_frequency_khz,omitempty"`
 }
 
+const (
+	// CoreTypePerformance is type of performance core of hybrid CPU, e.g. Intel P-core or ARM big core.
+	CoreTypePerformance = "performance"
+	// CoreTypeEfficiency is type of efficiency core of hybrid CPU, e.g. Intel E-core or ARM LITTLE core.
+	CoreTypeEfficiency = "efficiency"
+)
+
 type Cache struct {
 	// Size of memory cache in bytes.
 	Size uint64 `json:"size"`
@@ -60,6 +93,18 @@ type Cache struct {
 	Type string `json:"type"`
 	// Level (distance from cpus) in a multi-level cache hierarchy.
 	Level int `json:"level"`
+	// List of cpus sharing the cache, e.g. "0-7,64-71".
+	SharedCPUList string `json:"shared_cpu_list,omitempty"`
+}
+
+// UncoreCache is a cache shared by cores of a cluster, die or socket.
+type UncoreCache struct {
+	Cache
+	// Socket and die of cores sharing the cache, DieID is empty if die is not reported.
+	SocketID int    `json:"socket_id"`
+	DieID    string `json:"die_id,omitempty"`
+	// Cluster of cores sharing the cache, empty if the cache is shared by whole die or socket.
+	ClusterID string `json:"cluster_id,omitempty"`
 }
 
 func (n *Node) FindCore(id int) (bool, int) {
@@ -117,6 +162,15 @@ type HugePagesInfo struct {
 
 	// number of huge pages
 	NumPages uint64 `json:"num_pages"`
+
+	// number of huge pages which are not allocated
+	FreePages uint64 `json:"free_pages,omitempty"`
+
+	// number of huge pages allocated above num_pages (overcommit)
+	SurplusPages uint64 `json:"surplus_pages,omitempty"`
+
+	// number of huge pages reserved but not yet allocated, reported only for machine
+	ReservedPages uint64 `json:"reserved_pages,omitempty"`
 }
 
 type DiskInfo struct {
@@ -134,6 +188,27 @@ type DiskInfo struct {
 
 	// I/O Scheduler - one of "none", "noop", "cfq", "deadline"
 	Scheduler string `json:"scheduler"`
+
+	// Whether the device is rotational (HDD) or not (SSD, NVMe)
+	Rotational bool `json:"rotational,omitempty"`
+
+	// Maximum number of requests in the block device queue
+	NrRequests uint64 `json:"nr_requests,omitempty"`
+
+	// Discard granularity in bytes, 0 if discard is not supported
+	DiscardGranularity uint64 `json:"discard_granularity,omitempty"`
+
+	// Physical block size in bytes
+	PhysicalBlockSize uint64 `json:"physical_block_size,omitempty"`
+
+	// RAID level of md device, e.g. "raid1", empty for other devices
+	RaidLevel string `json:"raid_level,omitempty"`
+
+	// Name of device-mapper device, e.g. "vg0-root", empty for other devices
+	DMName string `json:"dm_name,omitempty"`
+
+	// Names of devices underlying md or dm device, e.g. ["sda1", "sdb1"]
+	Slaves []string `json:"slaves,omitempty"`
 }
 
 type NetInfo struct {
@@ -148,6 +223,70 @@ type NetInfo struct {
 
 	// Maximum Transmission Unit
 	Mtu int64 `json:"mtu"`
+
+	// Operational state, e.g. "up", "down" or "lowerlayerdown"
+	OperState string `json:"oper_state,omitempty"`
+
+	// Duplex, "full" or "half", empty when link is down
+	Duplex string `json:"duplex,omitempty"`
+
+	// Name of driver, e.g. "ixgbe", empty for virtual devices
+	Driver string `json:"driver,omitempty"`
+
+	// SR-IOV capabilities, reported only for physical functions of SR-IOV capable devices
+	Sriov *SriovInfo `json:"sriov,omitempty"`
+
+	// Driver statistics related to drops and errors by statistic name, e.g. "rx_missed_errors",
+	// collected only when enabled by --ethtool_stats
+	EthtoolStats map[string]uint64 `json:"ethtool_stats,omitempty"`
+}
+
+// NetworkQueueStats holds counters of receive or transmit queue of network device.
+type NetworkQueueStats struct {
+	// Name of network device, e.g. eth0.
+	Device string `json:"device"`
+	// Name of queue, e.g. rx-0 or tx-0.
+	Queue string `json:"queue"`
+	// Number of packets received or transmitted by the queue.
+	Packets uint64 `json:"packets"`
+	// Number of bytes received or transmitted by the queue.
+	Bytes uint64 `json:"bytes"`
+}
+
+// SriovInfo holds information about SR-IOV physical function and its virtual functions.
+type SriovInfo struct {
+	// PCI address of physical function, e.g. 0000:3b:00.0.
+	PCIAddress string `json:"pci_address"`
+	// PCI vendor ID, e.g. 0x8086.
+	VendorID string `json:"vendor_id"`
+	// PCI device ID, e.g. 0x158b.
+	DeviceID string `json:"device_id"`
+	// Maximal number of virtual functions supported by device.
+	TotalVFs int `json:"total_vfs"`
+	// Number of enabled virtual functions.
+	NumVFs int `json:"num_vfs"`
+	// Enabled virtual functions.
+	VirtualFunctions []VirtualFunction `json:"virtual_functions,omitempty"`
+}
+
+// VirtualFunction holds information about SR-IOV virtual function.
+type VirtualFunction struct {
+	// Index of virtual function, e.g. 0 for virtfn0.
+	Index int `json:"index"`
+	// PCI address of virtual function, e.g. 0000:3b:02.0.
+	PCIAddress string `json:"pci_address"`
+}
+
+// NetworkInterface describes relation of network interface to other interfaces.
+type NetworkInterface struct {
+	// Interface name, e.g. bond0.
+	Name string `json:"name"`
+	// Type of interface, e.g. bond, bridge or vlan, empty for physical and veth devices.
+	Type string `json:"type,omitempty"`
+	// Interfaces backing this interface, e.g. slaves of bond or ports of bridge.
+	Lower []string `json:"lower,omitempty"`
+	// Interfaces this interface is part of, e.g. bond it is enslaved to.
+	Upper []string `json:"upper,omitempty"`
 }
 
 type CloudProvider string
@@ -156,6 +295,7 @@ const (
 	GCE             CloudProvider = "GCE"
 	AWS             CloudProvider = "AWS"
 	Azure           CloudProvider = "Azure"
+	OpenStack       CloudProvider = "OpenStack"
 	UnknownProvider CloudProvider = "Unknown"
 )
 
@@ -207,6 +347,15 @@ type MachineInfo struct {
 	// The boot id
 	BootID string `json:"boot_id"`
 
+	// The time the machine booted at, uptime can be derived from it.
+	BootTime time.Time `json:"boot_time"`
+
+	// Kernel version of the machine (uname -r).
+	KernelVersion string `json:"kernel_version,omitempty"`
+
+	// Operating system of the machine (PRETTY_NAME from os-release).
+	OSImage string `json:"os_image,omitempty"`
+
 	// Filesystems on this machine.
 	Filesystems []FsInfo `json:"filesystems"`
 
@@ -228,6 +377,266 @@ type MachineInfo struct {
 
 	// ID of cloud instance (e.g. instance-1) given to it by the cloud provider.
 	InstanceID InstanceID `json:"instance_id"`
+
+	// Region of cloud instance (e.g. us-central1), reported when cloud metadata probing is enabled.
+	Region string `json:"region,omitempty"`
+
+	// Vendor, model, microcode and selected flags of CPUs.
+	CPUInfo *CPUInfo `json:"cpu_info,omitempty"`
+
+	// Frequency scaling state of logical CPUs, read on each request of machine API or metrics.
+	CPUFrequencies []CPUFrequency `json:"cpu_frequencies,omitempty"`
+
+	// Energy counters of RAPL power zones, read on each request of machine API or metrics.
+	PowerZones []PowerZone `json:"power_zones,omitempty"`
+
+	// Readings of temperature sensors, read on each request of machine API or metrics.
+	ThermalSensors []ThermalSensor `json:"thermal_sensors,omitempty"`
+
+	// Mitigation state of CPU vulnerabilities by vulnerability name,
+	// e.g. "spectre_v2": "Mitigation: Enhanced IBRS".
+	CPUVulnerabilities map[string]string `json:"cpu_vulnerabilities,omitempty"`
+
+	// Logical CPUs which are online and offline from kernel hotplug mechanism standpoint.
+	OnlineCPUs  []int `json:"online_cpus,omitempty"`
+	OfflineCPUs []int `json:"offline_cpus,omitempty"`
+
+	// Memory devices (DIMMs) installed on the machine as reported by SMBIOS.
+	MemoryDevices []MemoryDevice `json:"memory_devices,omitempty"`
+
+	// Persistent memory regions discovered through libnvdimm (/sys/bus/nd/devices).
+	PmemRegions []PmemRegion `json:"pmem_regions,omitempty"`
+
+	// NVMe controllers discovered through /sys/class/nvme, temperature is read on each request of machine API or metrics.
+	NVMeDevices []NVMeDevice `json:"nvme_devices,omitempty"`
+
+	// IO statistics of block devices, read on each request of machine API or metrics.
+	BlockDeviceStats []BlockDeviceStats `json:"block_device_stats,omitempty"`
+
+	// Hierarchy of network interfaces, e.g. physical devices backing bonds and bridges.
+	NetworkTopology []NetworkInterface `json:"network_topology,omitempty"`
+
+	// State and counters of InfiniBand (or RoCE) ports, read on each request of machine API or metrics.
+	InfinibandPorts []InfinibandPort `json:"infiniband_ports,omitempty"`
+
+	// Counters of receive and transmit queues of multiqueue network devices, read on each request of machine API or metrics.
+	NetworkQueueStats []NetworkQueueStats `json:"network_queue_stats,omitempty"`
+
+	// PCI devices, e.g. accelerators and NICs with NUMA node they are attached to.
+	PciDevices []PCIDevice `json:"pci_devices,omitempty"`
+
+	// Usage of devicemapper thin pools backing filesystems, read on each request of machine API or metrics.
+	ThinPools []ThinPoolInfo `json:"thin_pools,omitempty"`
+
+	// Perf uncore events (e.g. memory controller CAS counts or UPI traffic) summed per socket
+	// and type of PMU, taken from the latest stats of the root container.
+	PerfUncoreStats []PerfUncoreStat `json:"perf_uncore_stats,omitempty"`
+}
+
+// PmemRegion holds information about persistent memory region and its namespaces.
+type PmemRegion struct {
+	// Name of region, e.g. region0.
+	Name string `json:"name"`
+	// Size of region in bytes.
+	Size uint64 `json:"size"`
+	// NUMA node the region is attached to, -1 when unknown.
+	NumaNode int `json:"numa_node"`
+	// Namespaces configured in the region.
+	Namespaces []PmemNamespace `json:"namespaces,omitempty"`
+}
+
+// PmemNamespace holds information about persistent memory namespace.
+type PmemNamespace struct {
+	// Name of namespace, e.g. namespace0.0.
+	Name string `json:"name"`
+	// Mode of namespace, e.g. fsdax, devdax, sector or raw.
+	Mode string `json:"mode"`
+	// Size of namespace in bytes.
+	Size uint64 `json:"size"`
+	// NUMA node the namespace is attached to, -1 when unknown.
+	NumaNode int `json:"numa_node"`
+}
+
+// BlockDeviceStats holds IO statistics of block device from /sys/block/<dev>/stat, counters are
+// accumulated since boot.
+type BlockDeviceStats struct {
+	// Name of block device, e.g. sda.
+	Name string `json:"name"`
+	// Major number of block device.
+	Major uint64 `json:"major"`
+	// Minor number of block device.
+	Minor uint64 `json:"minor"`
+	// Number of completed read requests.
+	ReadsCompleted uint64 `json:"reads_completed"`
+	// Number of read requests merged with already queued requests.
+	ReadsMerged uint64 `json:"reads_merged"`
+	// Number of read bytes.
+	ReadBytes uint64 `json:"read_bytes"`
+	// Time spent waiting for read requests in milliseconds.
+	ReadTime uint64 `json:"read_time_ms"`
+	// Number of completed write requests.
+	WritesCompleted uint64 `json:"writes_completed"`
+	// Number of write requests merged with already queued requests.
+	WritesMerged uint64 `json:"writes_merged"`
+	// Number of written bytes.
+	WrittenBytes uint64 `json:"written_bytes"`
+	// Time spent waiting for write requests in milliseconds.
+	WriteTime uint64 `json:"write_time_ms"`
+	// Number of requests issued to the device driver but not yet completed.
+	IoInProgress uint64 `json:"io_in_progress"`
+	// Time the device had requests in flight in milliseconds.
+	IoTime uint64 `json:"io_time_ms"`
+	// Time requests spent in queue in milliseconds, weighted by number of requests in flight.
+	WeightedIoTime uint64 `json:"weighted_io_time_ms"`
+}
+
+// PCIDevice holds information about PCI device.
+type PCIDevice struct {
+	// Address of device, e.g. 0000:3b:00.0.
+	Address string `json:"address"`
+	// Vendor ID, e.g. 0x8086.
+	VendorID string `json:"vendor_id"`
+	// Device ID, e.g. 0x158b.
+	DeviceID string `json:"device_id"`
+	// Class code, e.g. 0x020000 for Ethernet controller.
+	Class string `json:"class"`
+	// NUMA node the device is attached to, -1 when unknown.
+	NumaNode int `json:"numa_node"`
+	// IOMMU group, empty when IOMMU is disabled.
+	IOMMUGroup string `json:"iommu_group,omitempty"`
+	// Current and maximal link speed of PCI Express device, e.g. "8.0 GT/s PCIe".
+	CurrentLinkSpeed string `json:"current_link_speed,omitempty"`
+	MaxLinkSpeed     string `json:"max_link_speed,omitempty"`
+	// Current and maximal link width of PCI Express device, e.g. 16.
+	CurrentLinkWidth int `json:"current_link_width,omitempty"`
+	MaxLinkWidth     int `json:"max_link_width,omitempty"`
+}
+
+// InfinibandPort holds state and counters of InfiniBand (or RoCE) port, counters are accumulated
+// since HCA was initialized.
+type InfinibandPort struct {
+	// Name of HCA, e.g. mlx5_0.
+	Device string `json:"device"`
+	// Port number, starting from 1.
+	Port int `json:"port"`
+	// State of port, e.g. ACTIVE or DOWN.
+	State string `json:"state"`
+	// Number of received data bytes.
+	ReceivedBytes uint64 `json:"received_bytes"`
+	// Number of transmitted data bytes.
+	TransmittedBytes uint64 `json:"transmitted_bytes"`
+	// Number of received packets.
+	ReceivedPackets uint64 `json:"received_packets"`
+	// Number of transmitted packets.
+	TransmittedPackets uint64 `json:"transmitted_packets"`
+	// Number of received packets containing an error.
+	ReceiveErrors uint64 `json:"receive_errors"`
+	// Number of outbound packets discarded by the port.
+	TransmitDiscards uint64 `json:"transmit_discards"`
+	// Number of times the link went down.
+	LinkDowned uint64 `json:"link_downed"`
+}
+
+// NVMeDevice holds information about NVMe controller and its namespaces.
+type NVMeDevice struct {
+	// Name of controller, e.g. nvme0.
+	Name string `json:"name"`
+	// Model of controller.
+	Model string `json:"model"`
+	// Firmware revision of controller.
+	Firmware string `json:"firmware"`
+	// Namespaces attached to the controller.
+	Namespaces []NVMeNamespace `json:"namespaces,omitempty"`
+	// Composite temperature in degrees Celsius, reported when kernel exposes hwmon for NVMe (5.5+).
+	Temperature *float64 `json:"temperature_celsius,omitempty"`
+}
+
+// NVMeNamespace holds information about NVMe namespace.
+type NVMeNamespace struct {
+	// Name of namespace, e.g. nvme0n1.
+	Name string `json:"name"`
+	// Size of namespace in bytes.
+	Size uint64 `json:"size"`
+}
+
+// ThinPoolInfo holds usage of devicemapper thin pool reported by `dmsetup status`.
+type ThinPoolInfo struct {
+	// Name of thin pool, e.g. docker-thinpool.
+	Name string `json:"name"`
+	// Mode of thin pool: rw, ro or out_of_data_space, empty when not reported by the kernel.
+	Mode string `json:"mode,omitempty"`
+	// Whether metadata of thin pool needs to be repaired with thin_check.
+	NeedsCheck bool `json:"needs_check"`
+	// Used and total data space in bytes.
+	DataUsage    uint64 `json:"data_usage"`
+	DataCapacity uint64 `json:"data_capacity"`
+	// Free data space in bytes below which device mapper notifies userspace, configured in table of thin pool.
+	DataLowWaterMark uint64 `json:"data_low_water_mark"`
+	// Used and total metadata space in bytes.
+	MetadataUsage    uint64 `json:"metadata_usage"`
+	MetadataCapacity uint64 `json:"metadata_capacity"`
+	// Free metadata space in bytes below which device mapper notifies userspace, reported since kernel 4.19.
+	MetadataLowWaterMark uint64 `json:"metadata_low_water_mark,omitempty"`
+}
+
+// MemoryDevice holds information about memory device (DIMM) from SMBIOS memory device structure (type 17).
+type MemoryDevice struct {
+	// Slot of memory device, e.g. DIMM_A1.
+	Locator string `json:"locator"`
+	// Bank of memory device, e.g. BANK 0.
+	BankLocator string `json:"bank_locator,omitempty"`
+	// Memory type, e.g. DDR4.
+	Type string `json:"type"`
+	// Size of memory device in bytes.
+	Size uint64 `json:"size"`
+	// Maximal speed of memory device in MT/s, 0 when unknown.
+	Speed uint64 `json:"speed,omitempty"`
+	// Manufacturer of memory device, e.g. Samsung.
+	Manufacturer string `json:"manufacturer,omitempty"`
+}
+
+// ThermalSensor holds reading of hwmon temperature sensor.
+type ThermalSensor struct {
+	// Name of hwmon device, e.g. coretemp or nvme.
+	Device string `json:"device"`
+	// Label of sensor, e.g. "Package id 0" or "Composite".
+	Label string `json:"label"`
+	// Temperature in degrees Celsius.
+	Temperature float64 `json:"temperature_celsius"`
+}
+
+// PowerZone holds energy counter of RAPL (Running Average Power Limit) power zone.
+type PowerZone struct {
+	// Id of powercap zone, e.g. intel-rapl:0 or intel-rapl:0:2.
+	Id string `json:"id"`
+	// Power domain of the zone, e.g. package-0, core or dram.
+	Domain string `json:"domain"`
+	// Energy consumed in microjoules, the counter wraps around.
+	EnergyUJ uint64 `json:"energy_uj"`
+}
+
+// CPUInfo holds identification and capabilities of CPUs reported in /proc/cpuinfo.
+type CPUInfo struct {
+	// Vendor of CPU, e.g. GenuineIntel or AuthenticAMD (CPU implementer on ARM).
+	Vendor string `json:"vendor,omitempty"`
+	// Model name of CPU, e.g. Intel(R) Xeon(R) Gold 6248 CPU @ 2.50GHz.
+	Model string `json:"model,omitempty"`
+	// Revision of loaded microcode.
+	Microcode string `json:"microcode,omitempty"`
+	// Selected CPU flags related to vector instructions, cryptography and virtualization, e.g. avx512f or aes.
+	Flags []string `json:"flags,omitempty"`
+}
+
+// CPUFrequency holds frequency scaling state of logical CPU.
+type CPUFrequency struct {
+	// Id of logical CPU.
+	CPU int `json:"cpu"`
+	// Current frequency in kHz.
+	CurrentFrequency uint64 `json:"current_frequency_khz"`
+	// Frequency scaling governor, e.g. performance or powersave.
+	Governor string `json:"governor,omitempty"`
+	// Time in milliseconds spent at each frequency (in kHz) since boot.
+	TimeInState map[uint64]uint64 `json:"time_in_state_ms,omitempty"`
 }
 
 func (m *MachineInfo) Clone() *MachineInfo {
@@ -245,26 +654,54 @@ func (m *MachineInfo) Clone() *MachineInfo {
 			diskMap[k] = info
 		}
 	}
+	cpuVulnerabilities := m.CPUVulnerabilities
+	if len(m.CPUVulnerabilities) > 0 {
+		cpuVulnerabilities = make(map[string]string, len(m.CPUVulnerabilities))
+		for vulnerability, mitigation := range m.CPUVulnerabilities {
+			cpuVulnerabilities[vulnerability] = mitigation
+		}
+	}
 	copy := MachineInfo{
-		Timestamp:        m.Timestamp,
-		NumCores:         m.NumCores,
-		NumPhysicalCores: m.NumPhysicalCores,
-		NumSockets:       m.NumSockets,
-		CpuFrequency:     m.CpuFrequency,
-		MemoryCapacity:   m.MemoryCapacity,
-		MemoryByType:     memoryByType,
-		NVMInfo:          m.NVMInfo,
-		HugePages:        m.HugePages,
-		MachineID:        m.MachineID,
-		SystemUUID:       m.SystemUUID,
-		BootID:           m.BootID,
-		Filesystems:      m.Filesystems,
-		DiskMap:          diskMap,
-		NetworkDevices:   m.NetworkDevices,
-		Topology:         m.Topology,
-		CloudProvider:    m.CloudProvider,
-		InstanceType:     m.InstanceType,
-		InstanceID:       m.InstanceID,
+		Timestamp:          m.Timestamp,
+		NumCores:           m.NumCores,
+		NumPhysicalCores:   m.NumPhysicalCores,
+		NumSockets:         m.NumSockets,
+		CpuFrequency:       m.CpuFrequency,
+		MemoryCapacity:     m.MemoryCapacity,
+		MemoryByType:       memoryByType,
+		NVMInfo:            m.NVMInfo,
+		HugePages:          m.HugePages,
+		MachineID:          m.MachineID,
+		SystemUUID:         m.SystemUUID,
+		BootID:             m.BootID,
+		BootTime:           m.BootTime,
+		KernelVersion:      m.KernelVersion,
+		OSImage:            m.OSImage,
+		Filesystems:        m.Filesystems,
+		DiskMap:            diskMap,
+		NetworkDevices:     m.NetworkDevices,
+		Topology:           m.Topology,
+		CloudProvider:      m.CloudProvider,
+		InstanceType:       m.InstanceType,
+		InstanceID:         m.InstanceID,
+		Region:             m.Region,
+		CPUInfo:            m.CPUInfo,
+		CPUFrequencies:     m.CPUFrequencies,
+		PowerZones:         m.PowerZones,
+		ThermalSensors:     m.ThermalSensors,
+		CPUVulnerabilities: cpuVulnerabilities,
+		OnlineCPUs:         m.OnlineCPUs,
+		OfflineCPUs:        m.OfflineCPUs,
+		MemoryDevices:      m.MemoryDevices,
+		PmemRegions:        m.PmemRegions,
+		NVMeDevices:        m.NVMeDevices,
+		BlockDeviceStats:   m.BlockDeviceStats,
+		NetworkTopology:    m.NetworkTopology,
+		InfinibandPorts:    m.InfinibandPorts,
+		NetworkQueueStats:  m.NetworkQueueStats,
+		PciDevices:         m.PciDevices,
+		ThinPools:          m.ThinPools,
+		PerfUncoreStats:    m.PerfUncoreStats,
 	}
 	return &copy
 }
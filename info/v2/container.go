@@ -144,6 +144,20 @@ type DeprecatedContainerStats struct {
 	PerfUncoreStats []v1.PerfUncoreStat `json:"perf_uncore_stats,omitempty"`
 	// Referenced memory
 	ReferencedMemory uint64 `json:"referenced_memory,omitempty"`
+	// Whether referenced memory is an approximation based on cgroup memory statistics
+	ReferencedMemoryApproximate bool `json:"referenced_memory_approximate,omitempty"`
+	// Whether referenced memory accounts only part of container processes
+	ReferencedMemoryPartial bool `json:"referenced_memory_partial,omitempty"`
+	// Referenced memory per NUMA node
+	ReferencedMemoryByNode map[uint8]uint64 `json:"referenced_memory_by_node,omitempty"`
+	// Referenced memory by number of samples in which it was found accessed
+	ReferencedMemoryAccessHistogram map[uint64]uint64 `json:"referenced_memory_access_histogram,omitempty"`
+	// Referenced memory of anonymous mappings, e.g. heap and stack
+	ReferencedMemoryAnon uint64 `json:"referenced_memory_anon,omitempty"`
+	// Referenced memory of file-backed mappings, i.e. page cache
+	ReferencedMemoryFile uint64 `json:"referenced_memory_file,omitempty"`
+	// Referenced memory averaged over time windows
+	ReferencedMemoryWindows *v1.ReferencedMemoryWindows `json:"referenced_memory_windows,omitempty"`
 	// Resource Control (resctrl) statistics
 	Resctrl v1.ResctrlStats `json:"resctrl,omitempty"`
 }
@@ -181,6 +195,20 @@ type ContainerStats struct {
 	PerfUncoreStats []v1.PerfUncoreStat `json:"perf_uncore_stats,omitempty"`
 	// Referenced memory
 	ReferencedMemory uint64 `json:"referenced_memory,omitempty"`
+	// Whether referenced memory is an approximation based on cgroup memory statistics
+	ReferencedMemoryApproximate bool `json:"referenced_memory_approximate,omitempty"`
+	// Whether referenced memory accounts only part of container processes
+	ReferencedMemoryPartial bool `json:"referenced_memory_partial,omitempty"`
+	// Referenced memory per NUMA node
+	ReferencedMemoryByNode map[uint8]uint64 `json:"referenced_memory_by_node,omitempty"`
+	// Referenced memory by number of samples in which it was found accessed
+	ReferencedMemoryAccessHistogram map[uint64]uint64 `json:"referenced_memory_access_histogram,omitempty"`
+	// Referenced memory of anonymous mappings, e.g. heap and stack
+	ReferencedMemoryAnon uint64 `json:"referenced_memory_anon,omitempty"`
+	// Referenced memory of file-backed mappings, i.e. page cache
+	ReferencedMemoryFile uint64 `json:"referenced_memory_file,omitempty"`
+	// Referenced memory averaged over time windows
+	ReferencedMemoryWindows *v1.ReferencedMemoryWindows `json:"referenced_memory_windows,omitempty"`
 	// Resource Control (resctrl) statistics
 	Resctrl v1.ResctrlStats `json:"resctrl,omitempty"`
 }
@@ -274,6 +302,28 @@ type RequestOptions struct {
 	MaxAge *time.Duration `json:"max_age"`
 }
 
+// ReferencedMemoryStats holds latest measurement of memory referenced by container.
+type ReferencedMemoryStats struct {
+	// The time of measurement.
+	Timestamp time.Time `json:"timestamp"`
+	// Referenced memory in bytes
+	ReferencedMemory uint64 `json:"referenced_memory"`
+	// Referenced memory is approximated from cgroup memory statistics
+	Approximate bool `json:"approximate,omitempty"`
+	// Referenced memory was read only for part of container processes
+	Partial bool `json:"partial,omitempty"`
+	// Referenced memory in bytes by NUMA node
+	ByNode map[uint8]uint64 `json:"by_node,omitempty"`
+	// Bytes of memory by number of sampling intervals in which it was accessed
+	AccessHistogram map[uint64]uint64 `json:"access_histogram,omitempty"`
+	// Referenced memory of anonymous mappings
+	Anon uint64 `json:"anon,omitempty"`
+	// Referenced memory of file-backed mappings
+	File uint64 `json:"file,omitempty"`
+	// Referenced memory averaged over time windows
+	Windows *v1.ReferencedMemoryWindows `json:"windows,omitempty"`
+}
+
 type ProcessInfo struct {
 	User          string  `json:"user"`
 	Pid           int     `json:"pid"`
@@ -289,6 +339,7 @@ type ProcessInfo struct {
 	Cmd           string  `json:"cmd"`
 	FdCount       int     `json:"fd_count"`
 	Psr           int     `json:"psr"`
+	Threads       int     `json:"threads"`
 }
 
 type TcpStat struct {
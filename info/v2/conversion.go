@@ -101,8 +101,15 @@ func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []
 	var last *v1.ContainerStats
 	for _, val := range stats {
 		stat := &ContainerStats{
-			Timestamp:        val.Timestamp,
-			ReferencedMemory: val.ReferencedMemory,
+			Timestamp:                       val.Timestamp,
+			ReferencedMemory:                val.ReferencedMemory,
+			ReferencedMemoryApproximate:     val.ReferencedMemoryApproximate,
+			ReferencedMemoryPartial:         val.ReferencedMemoryPartial,
+			ReferencedMemoryByNode:          val.ReferencedMemoryByNode,
+			ReferencedMemoryAccessHistogram: val.ReferencedMemoryAccessHistogram,
+			ReferencedMemoryAnon:            val.ReferencedMemoryAnon,
+			ReferencedMemoryFile:            val.ReferencedMemoryFile,
+			ReferencedMemoryWindows:         val.ReferencedMemoryWindows,
 		}
 		if spec.HasCpu {
 			stat.Cpu = &val.Cpu
@@ -172,15 +179,22 @@ func DeprecatedStatsFromV1(cont *v1.ContainerInfo) []DeprecatedContainerStats {
 	var last *v1.ContainerStats
 	for _, val := range cont.Stats {
 		stat := DeprecatedContainerStats{
-			Timestamp:        val.Timestamp,
-			HasCpu:           cont.Spec.HasCpu,
-			HasMemory:        cont.Spec.HasMemory,
-			HasHugetlb:       cont.Spec.HasHugetlb,
-			HasNetwork:       cont.Spec.HasNetwork,
-			HasFilesystem:    cont.Spec.HasFilesystem,
-			HasDiskIo:        cont.Spec.HasDiskIo,
-			HasCustomMetrics: cont.Spec.HasCustomMetrics,
-			ReferencedMemory: val.ReferencedMemory,
+			Timestamp:                       val.Timestamp,
+			HasCpu:                          cont.Spec.HasCpu,
+			HasMemory:                       cont.Spec.HasMemory,
+			HasHugetlb:                      cont.Spec.HasHugetlb,
+			HasNetwork:                      cont.Spec.HasNetwork,
+			HasFilesystem:                   cont.Spec.HasFilesystem,
+			HasDiskIo:                       cont.Spec.HasDiskIo,
+			HasCustomMetrics:                cont.Spec.HasCustomMetrics,
+			ReferencedMemory:                val.ReferencedMemory,
+			ReferencedMemoryApproximate:     val.ReferencedMemoryApproximate,
+			ReferencedMemoryPartial:         val.ReferencedMemoryPartial,
+			ReferencedMemoryByNode:          val.ReferencedMemoryByNode,
+			ReferencedMemoryAccessHistogram: val.ReferencedMemoryAccessHistogram,
+			ReferencedMemoryAnon:            val.ReferencedMemoryAnon,
+			ReferencedMemoryFile:            val.ReferencedMemoryFile,
+			ReferencedMemoryWindows:         val.ReferencedMemoryWindows,
 		}
 		if stat.HasCpu {
 			stat.Cpu = val.Cpu
@@ -228,6 +242,26 @@ func DeprecatedStatsFromV1(cont *v1.ContainerInfo) []DeprecatedContainerStats {
 	return stats
 }
 
+// ReferencedMemoryStatsFromV1 returns referenced memory of latest stats of container,
+// nil is returned when container has no stats.
+func ReferencedMemoryStatsFromV1(cont *v1.ContainerInfo) *ReferencedMemoryStats {
+	if len(cont.Stats) == 0 {
+		return nil
+	}
+	latest := cont.Stats[len(cont.Stats)-1]
+	return &ReferencedMemoryStats{
+		Timestamp:        latest.Timestamp,
+		ReferencedMemory: latest.ReferencedMemory,
+		Approximate:      latest.ReferencedMemoryApproximate,
+		Partial:          latest.ReferencedMemoryPartial,
+		ByNode:           latest.ReferencedMemoryByNode,
+		AccessHistogram:  latest.ReferencedMemoryAccessHistogram,
+		Anon:             latest.ReferencedMemoryAnon,
+		File:             latest.ReferencedMemoryFile,
+		Windows:          latest.ReferencedMemoryWindows,
+	}
+}
+
 func InstCpuStats(last, cur *v1.ContainerStats) (*CpuInstStats, error) {
 	if last == nil {
 		return nil, nil
@@ -441,3 +441,35 @@ func TestInstCpuStats(t *testing.T) {
 		assert.Equal(t, c.want, got)
 	}
 }
+
+func TestReferencedMemoryStatsFromV1(t *testing.T) {
+	assert.Nil(t, ReferencedMemoryStatsFromV1(&v1.ContainerInfo{}))
+
+	cont := &v1.ContainerInfo{
+		Stats: []*v1.ContainerStats{
+			{
+				Timestamp:        timestamp,
+				ReferencedMemory: 1024,
+			},
+			{
+				Timestamp:               timestamp.Add(time.Second),
+				ReferencedMemory:        2048,
+				ReferencedMemoryPartial: true,
+				ReferencedMemoryByNode:  map[uint8]uint64{0: 1024, 1: 1024},
+				ReferencedMemoryAnon:    512,
+				ReferencedMemoryFile:    1536,
+				ReferencedMemoryWindows: &v1.ReferencedMemoryWindows{Window1m: 2000, Window5m: 1500, Window15m: 1200},
+			},
+		},
+	}
+	expected := &ReferencedMemoryStats{
+		Timestamp:        timestamp.Add(time.Second),
+		ReferencedMemory: 2048,
+		Partial:          true,
+		ByNode:           map[uint8]uint64{0: 1024, 1: 1024},
+		Anon:             512,
+		File:             1536,
+		Windows:          &v1.ReferencedMemoryWindows{Window1m: 2000, Window5m: 1500, Window15m: 1200},
+	}
+	assert.Equal(t, expected, ReferencedMemoryStatsFromV1(cont))
+}
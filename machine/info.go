@@ -24,6 +24,7 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	"github.com/google/cadvisor/devicemapper"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/nvm"
@@ -36,9 +37,11 @@ import (
 
 const hugepagesDirectory = "/sys/kernel/mm/hugepages/"
 const memoryControllerPath = "/sys/devices/system/edac/mc/"
+const ndDevicesPath = "/sys/bus/nd/devices/"
 
 var machineIDFilePath = flag.String("machine_id_file", "/etc/machine-id,/var/lib/dbus/machine-id", "Comma-separated list of files to check for machine-id. Use the first one that exists.")
 var bootIDFilePath = flag.String("boot_id_file", "/proc/sys/kernel/random/boot_id", "Comma-separated list of files to check for boot-id. Use the first one that exists.")
+var ethtoolStats = flag.Bool("ethtool_stats", false, "Collect driver statistics of network devices related to drops and errors (ethtool -S), e.g. rx_missed_errors or per ring drops.")
 
 func getInfoFromFiles(filePaths string) string {
 	if len(filePaths) == 0 {
@@ -68,6 +71,7 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 	if err != nil {
 		return nil, err
 	}
+	cpuInfo := GetCPUInfo(cpuinfo)
 
 	memoryCapacity, err := GetMachineMemoryCapacity()
 	if err != nil {
@@ -79,11 +83,25 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 		return nil, err
 	}
 
+	memoryDevices, err := sysinfo.GetMemoryDevices(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get memory devices from SMBIOS: %v", err)
+	}
+	// EDAC is not available on all platforms (e.g. without ECC memory), SMBIOS is used instead.
+	if len(memoryByType) == 0 && len(memoryDevices) != 0 {
+		memoryByType = sysinfo.GetMemoryByTypeFromDevices(memoryDevices)
+	}
+
 	nvmInfo, err := nvm.GetInfo()
 	if err != nil {
 		return nil, err
 	}
 
+	pmemRegions, err := GetPmemRegions(ndDevicesPath)
+	if err != nil {
+		klog.Errorf("Failed to get persistent memory regions: %v", err)
+	}
+
 	hugePagesInfo, err := sysinfo.GetHugePagesInfo(sysFs, hugepagesDirectory)
 	if err != nil {
 		return nil, err
@@ -99,45 +117,133 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 		klog.Errorf("Failed to get disk map: %v", err)
 	}
 
+	blockDeviceStats, err := sysinfo.GetBlockDeviceStats(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get block device statistics: %v", err)
+	}
+
 	netDevices, err := sysinfo.GetNetworkDevices(sysFs)
 	if err != nil {
 		klog.Errorf("Failed to get network devices: %v", err)
 	}
+	if *ethtoolStats {
+		sysinfo.SetNetworkEthtoolStats(sysFs, netDevices)
+	}
+
+	networkTopology, err := sysinfo.GetNetworkTopology(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get network topology: %v", err)
+	}
+
+	networkQueueStats, err := sysinfo.GetNetworkQueueStats(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get network queue statistics: %v", err)
+	}
+
+	pciDevices, err := sysinfo.GetPCIDevices(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get PCI devices: %v", err)
+	}
+
+	infinibandPorts, err := sysinfo.GetInfinibandPorts(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get InfiniBand ports: %v", err)
+	}
 
 	topology, numCores, err := GetTopology(sysFs)
 	if err != nil {
 		klog.Errorf("Failed to get topology information: %v", err)
 	}
 
+	cpuFrequencies, err := sysinfo.GetCPUFrequencies(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get CPU frequencies: %v", err)
+	}
+
+	powerZones, err := sysinfo.GetPowerZones(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get RAPL power zones: %v", err)
+	}
+
+	thermalSensors, err := sysinfo.GetThermalSensors(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get thermal sensors: %v", err)
+	}
+
+	nvmeDevices, err := sysinfo.GetNVMeDevices(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get NVMe devices: %v", err)
+	}
+
+	cpuVulnerabilities, err := sysinfo.GetCPUVulnerabilities(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get CPU vulnerabilities: %v", err)
+	}
+
+	onlineCPUs, offlineCPUs, err := sysinfo.GetOnlineAndOfflineCPUs(sysFs)
+	if err != nil {
+		klog.Errorf("Failed to get online CPUs: %v", err)
+	}
+
 	systemUUID, err := sysinfo.GetSystemUUID(sysFs)
 	if err != nil {
 		klog.Errorf("Failed to get system UUID: %v", err)
 	}
 
+	osImage, err := GetOSImage(rootFs)
+	if err != nil {
+		klog.Errorf("Failed to get OS image: %v", err)
+	}
+
+	bootTime, err := GetBootTime(rootFs)
+	if err != nil {
+		klog.Errorf("Failed to get boot time: %v", err)
+	}
+
 	realCloudInfo := cloudinfo.NewRealCloudInfo()
 	cloudProvider := realCloudInfo.GetCloudProvider()
 	instanceType := realCloudInfo.GetInstanceType()
 	instanceID := realCloudInfo.GetInstanceID()
+	region := realCloudInfo.GetRegion()
 
 	machineInfo := &info.MachineInfo{
-		Timestamp:        time.Now(),
-		NumCores:         numCores,
-		NumPhysicalCores: GetPhysicalCores(cpuinfo),
-		NumSockets:       GetSockets(cpuinfo),
-		CpuFrequency:     clockSpeed,
-		MemoryCapacity:   memoryCapacity,
-		MemoryByType:     memoryByType,
-		NVMInfo:          nvmInfo,
-		HugePages:        hugePagesInfo,
-		DiskMap:          diskMap,
-		NetworkDevices:   netDevices,
-		Topology:         topology,
-		MachineID:        getInfoFromFiles(filepath.Join(rootFs, *machineIDFilePath)),
-		SystemUUID:       systemUUID,
-		BootID:           getInfoFromFiles(filepath.Join(rootFs, *bootIDFilePath)),
-		CloudProvider:    cloudProvider,
-		InstanceType:     instanceType,
-		InstanceID:       instanceID,
+		Timestamp:          time.Now(),
+		NumCores:           numCores,
+		NumPhysicalCores:   GetPhysicalCores(cpuinfo),
+		NumSockets:         GetSockets(cpuinfo),
+		CpuFrequency:       clockSpeed,
+		MemoryCapacity:     memoryCapacity,
+		MemoryByType:       memoryByType,
+		NVMInfo:            nvmInfo,
+		HugePages:          hugePagesInfo,
+		DiskMap:            diskMap,
+		NetworkDevices:     netDevices,
+		Topology:           topology,
+		MachineID:          getInfoFromFiles(filepath.Join(rootFs, *machineIDFilePath)),
+		SystemUUID:         systemUUID,
+		BootID:             getInfoFromFiles(filepath.Join(rootFs, *bootIDFilePath)),
+		BootTime:           bootTime,
+		KernelVersion:      KernelVersion(),
+		OSImage:            osImage,
+		CloudProvider:      cloudProvider,
+		InstanceType:       instanceType,
+		InstanceID:         instanceID,
+		Region:             region,
+		CPUInfo:            cpuInfo,
+		CPUFrequencies:     cpuFrequencies,
+		PowerZones:         powerZones,
+		ThermalSensors:     thermalSensors,
+		CPUVulnerabilities: cpuVulnerabilities,
+		OnlineCPUs:         onlineCPUs,
+		OfflineCPUs:        offlineCPUs,
+		MemoryDevices:      memoryDevices,
+		PmemRegions:        pmemRegions,
+		NVMeDevices:        nvmeDevices,
+		BlockDeviceStats:   blockDeviceStats,
+		NetworkTopology:    networkTopology,
+		InfinibandPorts:    infinibandPorts,
+		NetworkQueueStats:  networkQueueStats,
+		PciDevices:         pciDevices,
 	}
 
 	for i := range filesystems {
@@ -148,10 +254,151 @@ func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.Mach
 		}
 		machineInfo.Filesystems = append(machineInfo.Filesystems, info.FsInfo{Device: fs.Device, DeviceMajor: uint64(fs.Major), DeviceMinor: uint64(fs.Minor), Type: fs.Type.String(), Capacity: fs.Capacity, Inodes: inodes, HasInodes: fs.Inodes != nil})
 	}
+	machineInfo.ThinPools = getThinPools(devicemapper.NewDmsetupClient(), filesystems)
 
 	return machineInfo, nil
 }
 
+// UpdateCounters reads current values of counters in machine info returned by Info, e.g. energy of
+// RAPL power zones or IO statistics of block devices, which are otherwise read only when machine info
+// is updated. Only counters reported in machine info are read and counters which cannot be read keep
+// their previous values. Slices holding counters are replaced rather than modified, so machine info
+// may share them with other copies.
+func UpdateCounters(sysFs sysfs.SysFs, machineInfo *info.MachineInfo) {
+	if len(machineInfo.HugePages) != 0 {
+		if hugePagesInfo, err := sysinfo.GetHugePagesInfo(sysFs, hugepagesDirectory); err != nil {
+			klog.V(4).Infof("Failed to get hugepages info: %v", err)
+		} else {
+			machineInfo.HugePages = hugePagesInfo
+		}
+	}
+
+	if len(machineInfo.Topology) != 0 {
+		topology := make([]info.Node, len(machineInfo.Topology))
+		copy(topology, machineInfo.Topology)
+		if err := sysinfo.SetNodesCounters(sysFs, topology); err != nil {
+			klog.V(4).Infof("Failed to get memory usage of NUMA nodes: %v", err)
+		} else {
+			machineInfo.Topology = topology
+		}
+	}
+
+	if len(machineInfo.BlockDeviceStats) != 0 {
+		if blockDeviceStats, err := sysinfo.GetBlockDeviceStats(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get block device statistics: %v", err)
+		} else {
+			machineInfo.BlockDeviceStats = blockDeviceStats
+		}
+	}
+
+	if *ethtoolStats && len(machineInfo.NetworkDevices) != 0 {
+		netDevices := make([]info.NetInfo, len(machineInfo.NetworkDevices))
+		copy(netDevices, machineInfo.NetworkDevices)
+		sysinfo.SetNetworkEthtoolStats(sysFs, netDevices)
+		machineInfo.NetworkDevices = netDevices
+	}
+
+	if len(machineInfo.NetworkQueueStats) != 0 {
+		if networkQueueStats, err := sysinfo.GetNetworkQueueStats(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get network queue statistics: %v", err)
+		} else {
+			machineInfo.NetworkQueueStats = networkQueueStats
+		}
+	}
+
+	if len(machineInfo.InfinibandPorts) != 0 {
+		if infinibandPorts, err := sysinfo.GetInfinibandPorts(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get InfiniBand ports: %v", err)
+		} else {
+			machineInfo.InfinibandPorts = infinibandPorts
+		}
+	}
+
+	if len(machineInfo.CPUFrequencies) != 0 {
+		if cpuFrequencies, err := sysinfo.GetCPUFrequencies(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get CPU frequencies: %v", err)
+		} else {
+			machineInfo.CPUFrequencies = cpuFrequencies
+		}
+	}
+
+	if len(machineInfo.PowerZones) != 0 {
+		if powerZones, err := sysinfo.GetPowerZones(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get RAPL power zones: %v", err)
+		} else {
+			machineInfo.PowerZones = powerZones
+		}
+	}
+
+	if len(machineInfo.ThermalSensors) != 0 {
+		if thermalSensors, err := sysinfo.GetThermalSensors(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get thermal sensors: %v", err)
+		} else {
+			machineInfo.ThermalSensors = thermalSensors
+		}
+	}
+
+	if len(machineInfo.NVMeDevices) != 0 {
+		if nvmeDevices, err := sysinfo.GetNVMeDevices(sysFs); err != nil {
+			klog.V(4).Infof("Failed to get NVMe devices: %v", err)
+		} else {
+			machineInfo.NVMeDevices = nvmeDevices
+		}
+	}
+
+	if len(machineInfo.ThinPools) != 0 {
+		machineInfo.ThinPools = updateThinPools(devicemapper.NewDmsetupClient(), machineInfo.ThinPools)
+	}
+}
+
+// getThinPools returns usage of devicemapper thin pools backing filesystems, device of
+// devicemapper filesystem is the name of its thin pool.
+func getThinPools(dmsetup devicemapper.DmsetupClient, filesystems []fs.Fs) []info.ThinPoolInfo {
+	var thinPools []info.ThinPoolInfo
+	for _, filesystem := range filesystems {
+		if filesystem.Type != fs.DeviceMapper {
+			continue
+		}
+		status, err := devicemapper.GetThinPoolStatus(dmsetup, filesystem.Device)
+		if err != nil {
+			klog.Errorf("Failed to get status of thin pool %q: %v", filesystem.Device, err)
+			continue
+		}
+		thinPools = append(thinPools, thinPoolInfo(filesystem.Device, status))
+	}
+	return thinPools
+}
+
+// updateThinPools returns current usage of thin pools, thin pools whose status cannot be read
+// keep their previous usage.
+func updateThinPools(dmsetup devicemapper.DmsetupClient, thinPools []info.ThinPoolInfo) []info.ThinPoolInfo {
+	updated := make([]info.ThinPoolInfo, 0, len(thinPools))
+	for _, thinPool := range thinPools {
+		status, err := devicemapper.GetThinPoolStatus(dmsetup, thinPool.Name)
+		if err != nil {
+			klog.V(4).Infof("Failed to get status of thin pool %q: %v", thinPool.Name, err)
+			updated = append(updated, thinPool)
+			continue
+		}
+		updated = append(updated, thinPoolInfo(thinPool.Name, status))
+	}
+	return updated
+}
+
+func thinPoolInfo(name string, status *devicemapper.ThinPoolStatus) info.ThinPoolInfo {
+	return info.ThinPoolInfo{
+		Name:                 name,
+		Mode:                 status.Mode,
+		NeedsCheck:           status.NeedsCheck,
+		DataUsage:            status.DataUsage,
+		DataCapacity:         status.DataCapacity,
+		DataLowWaterMark:     status.DataLowWaterMark,
+		MetadataUsage:        status.MetadataUsage,
+		MetadataCapacity:     status.MetadataCapacity,
+		MetadataLowWaterMark: status.MetadataLowWaterMark,
+	}
+}
+
 func ContainerOsVersion() string {
 	os, err := getOperatingSystem()
 	if err != nil {
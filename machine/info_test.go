@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/google/cadvisor/devicemapper/fake"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetThinPools(t *testing.T) {
+	filesystems := []fs.Fs{
+		{DeviceInfo: fs.DeviceInfo{Device: "/dev/sda1"}, Type: fs.VFS},
+		{DeviceInfo: fs.DeviceInfo{Device: "docker-thinpool"}, Type: fs.DeviceMapper},
+		{DeviceInfo: fs.DeviceInfo{Device: "broken-thinpool"}, Type: fs.DeviceMapper},
+	}
+	dmsetup := fake.NewFakeDmsetupClient(t,
+		fake.DmsetupCommand{Name: "table", Result: "0 209715200 thin-pool 253:1 253:2 128 32768 1 skip_block_zeroing"},
+		fake.DmsetupCommand{Name: "status", Result: "0 209715200 thin-pool 1 1233/4161600 52429/1638400 - rw no_discard_passdown queue_if_no_space - 1024"},
+		fake.DmsetupCommand{Name: "table", Result: "0 209715200 thin-pool 253:3 253:4 128 32768 1 skip_block_zeroing"},
+		fake.DmsetupCommand{Name: "status", Result: "0 209715200 thin-pool Fail"},
+	)
+
+	thinPools := getThinPools(dmsetup, filesystems)
+	assert.Equal(t, []info.ThinPoolInfo{
+		{
+			Name:                 "docker-thinpool",
+			Mode:                 "rw",
+			DataUsage:            3435986944,
+			DataCapacity:         107374182400,
+			DataLowWaterMark:     2147483648,
+			MetadataUsage:        5050368,
+			MetadataCapacity:     17045913600,
+			MetadataLowWaterMark: 4194304,
+		},
+	}, thinPools)
+}
+
+func TestUpdateThinPools(t *testing.T) {
+	thinPools := []info.ThinPoolInfo{
+		{Name: "docker-thinpool", Mode: "rw", DataUsage: 1024, DataCapacity: 107374182400},
+		{Name: "broken-thinpool", Mode: "rw", DataUsage: 2048, DataCapacity: 107374182400},
+	}
+	dmsetup := fake.NewFakeDmsetupClient(t,
+		fake.DmsetupCommand{Name: "table", Result: "0 209715200 thin-pool 253:1 253:2 128 32768 1 skip_block_zeroing"},
+		fake.DmsetupCommand{Name: "status", Result: "0 209715200 thin-pool 1 1233/4161600 52429/1638400 - rw no_discard_passdown queue_if_no_space - 1024"},
+		fake.DmsetupCommand{Name: "table", Result: "0 209715200 thin-pool 253:3 253:4 128 32768 1 skip_block_zeroing"},
+		fake.DmsetupCommand{Name: "status", Result: "0 209715200 thin-pool Fail"},
+	)
+
+	updated := updateThinPools(dmsetup, thinPools)
+	assert.Equal(t, []info.ThinPoolInfo{
+		{
+			Name:                 "docker-thinpool",
+			Mode:                 "rw",
+			DataUsage:            3435986944,
+			DataCapacity:         107374182400,
+			DataLowWaterMark:     2147483648,
+			MetadataUsage:        5050368,
+			MetadataCapacity:     17045913600,
+			MetadataLowWaterMark: 4194304,
+		},
+		// Usage is kept when status cannot be read.
+		{Name: "broken-thinpool", Mode: "rw", DataUsage: 2048, DataCapacity: 107374182400},
+	}, updated)
+	assert.Equal(t, uint64(1024), thinPools[0].DataUsage)
+}
@@ -23,8 +23,10 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	// s390/s390x changes
 	"runtime"
@@ -46,19 +48,53 @@ var (
 	cpuClockSpeedMHz     = regexp.MustCompile(`(?:cpu MHz|CPU MHz|clock)\s*:\s*([0-9]+\.[0-9]+)(?:MHz)?`)
 	memoryCapacityRegexp = regexp.MustCompile(`MemTotal:\s*([0-9]+) kB`)
 	swapCapacityRegexp   = regexp.MustCompile(`SwapTotal:\s*([0-9]+) kB`)
+	cpuVendorRegExp      = regexp.MustCompile(`(?m)^(?:vendor_id|CPU implementer)[ \t]*:[ \t]*(.+)$`)
+	cpuModelNameRegExp   = regexp.MustCompile(`(?m)^model name[ \t]*:[ \t]*(.+)$`)
+	cpuMicrocodeRegExp   = regexp.MustCompile(`(?m)^microcode[ \t]*:[ \t]*(.+)$`)
+	// CPU flags are named "Features" on ARM
+	cpuFlagsRegExp = regexp.MustCompile(`(?m)^(?:flags|Features)[ \t]*:[ \t]*(.+)$`)
+	// Flags related to vector instructions, cryptography and virtualization which are reported in machine info
+	selectedCPUFlagRegExp = regexp.MustCompile(`^(?:sse4_1|sse4_2|avx|avx2|avx512.*|amx_.*|fma|aes|sha_ni|vmx|svm|hypervisor|asimd|sve|sve2|sha2|sha512)$`)
 
 	cpuBusPath         = "/sys/bus/cpu/devices/"
 	isMemoryController = regexp.MustCompile("mc[0-9]+")
 	isDimm             = regexp.MustCompile("dimm[0-9]+")
+	isNdRegion         = regexp.MustCompile(`^region([0-9]+)$`)
+	isNdNamespace      = regexp.MustCompile(`^namespace([0-9]+)\.[0-9]+$`)
 	machineArch        = getMachineArch()
 	maxFreqFile        = "/sys/devices/system/cpu/cpu0/cpufreq/cpuinfo_max_freq"
+	loadAvgFile        = "/proc/loadavg"
+
+	// Nominal frequency of CPU provided by ACPI CPPC, available on arm64 servers without cpufreq driver.
+	cppcNominalFreqFile = "/sys/devices/system/cpu/cpu0/acpi_cppc/nominal_freq"
+
+	osReleasePrettyNameRegexp = regexp.MustCompile(`(?m)^PRETTY_NAME=(.*)$`)
+	bootTimeRegexp            = regexp.MustCompile(`(?m)^btime\s+([0-9]+)$`)
 )
 
+const memInfoFile = "/proc/meminfo"
 const sysFsCPUCoreID = "core_id"
 const sysFsCPUPhysicalPackageID = "physical_package_id"
 const sysFsCPUTopology = "topology"
 const memTypeFileName = "dimm_mem_type"
 const sizeFileName = "size"
+const ndModeFileName = "mode"
+const ndNumaNodeFileName = "numa_node"
+
+// fileReader abstracts reading of procfs files, so that their parsing can be tested against fixtures.
+type fileReader interface {
+	ReadFile(filename string) ([]byte, error)
+}
+
+// realFileReader reads files from the filesystem, optionally under given root,
+// e.g. /host when the host filesystem is mounted into cAdvisor's container.
+type realFileReader struct {
+	root string
+}
+
+func (r realFileReader) ReadFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(r.root, filename))
+}
 
 // GetPhysicalCores returns number of CPU cores reading /proc/cpuinfo file or if needed information from sysfs cpu path
 func GetPhysicalCores(procInfo []byte) int {
@@ -88,25 +124,49 @@ func GetSockets(procInfo []byte) int {
 	return numSocket
 }
 
+// GetCPUInfo returns vendor, model name, microcode revision and selected flags of CPU read from
+// /proc/cpuinfo, values of the first processor are reported. Nil is returned when none of them is available.
+func GetCPUInfo(procInfo []byte) *info.CPUInfo {
+	cpuInfo := &info.CPUInfo{
+		Vendor:    getFirstMatch(procInfo, cpuVendorRegExp),
+		Model:     getFirstMatch(procInfo, cpuModelNameRegExp),
+		Microcode: getFirstMatch(procInfo, cpuMicrocodeRegExp),
+	}
+	for _, flag := range strings.Fields(getFirstMatch(procInfo, cpuFlagsRegExp)) {
+		if selectedCPUFlagRegExp.MatchString(flag) {
+			cpuInfo.Flags = append(cpuInfo.Flags, flag)
+		}
+	}
+	if cpuInfo.Vendor == "" && cpuInfo.Model == "" && cpuInfo.Microcode == "" && len(cpuInfo.Flags) == 0 {
+		return nil
+	}
+	sort.Strings(cpuInfo.Flags)
+	return cpuInfo
+}
+
+// getFirstMatch returns trimmed value of the first submatch of r in b, empty string if there is no match.
+func getFirstMatch(b []byte, r *regexp.Regexp) string {
+	matches := r.FindSubmatch(b)
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(string(matches[1]))
+}
+
 // GetClockSpeed returns the CPU clock speed, given a []byte formatted as the /proc/cpuinfo file.
 func GetClockSpeed(procInfo []byte) (uint64, error) {
-	// s390/s390x, mips64, riscv64, aarch64 and arm32 changes
-	if isMips64() || isSystemZ() || isAArch64() || isArm32() || isRiscv64() {
+	// s390/s390x, mips64, riscv64 and arm32 changes
+	if isMips64() || isSystemZ() || isArm32() || isRiscv64() {
 		return 0, nil
 	}
+	// aarch64 does not report clock speed in /proc/cpuinfo, it is read from sysfs if available.
+	if isAArch64() {
+		return getAArch64ClockSpeed()
+	}
 
 	// First look through sys to find a max supported cpu frequency.
 	if utils.FileExists(maxFreqFile) {
-		val, err := ioutil.ReadFile(maxFreqFile)
-		if err != nil {
-			return 0, err
-		}
-		var maxFreq uint64
-		n, err := fmt.Sscanf(string(val), "%d", &maxFreq)
-		if err != nil || n != 1 {
-			return 0, fmt.Errorf("could not parse frequency %q", val)
-		}
-		return maxFreq, nil
+		return readFrequency(maxFreqFile, 1)
 	}
 	// Fall back to /proc/cpuinfo
 	matches := cpuClockSpeedMHz.FindSubmatch(procInfo)
@@ -122,10 +182,47 @@ func GetClockSpeed(procInfo []byte) (uint64, error) {
 	return uint64(speed * 1000), nil
 }
 
+// getAArch64ClockSpeed returns maximal frequency of cpufreq driver or nominal frequency provided
+// by ACPI CPPC on aarch64, 0 is returned when neither is available, e.g. in virtual machines.
+func getAArch64ClockSpeed() (uint64, error) {
+	if utils.FileExists(maxFreqFile) {
+		return readFrequency(maxFreqFile, 1)
+	}
+	if utils.FileExists(cppcNominalFreqFile) {
+		// Nominal frequency is reported in MHz.
+		return readFrequency(cppcNominalFreqFile, 1000)
+	}
+	return 0, nil
+}
+
+// readFrequency reads frequency from the file and converts it to kHz using the multiplier.
+func readFrequency(path string, multiplier uint64) (uint64, error) {
+	val, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var freq uint64
+	n, err := fmt.Sscanf(string(val), "%d", &freq)
+	if err != nil || n != 1 {
+		return 0, fmt.Errorf("could not parse frequency %q", val)
+	}
+	return freq * multiplier, nil
+}
+
 // GetMachineMemoryCapacity returns the machine's total memory from /proc/meminfo.
 // Returns the total memory capacity as an uint64 (number of bytes).
 func GetMachineMemoryCapacity() (uint64, error) {
-	out, err := ioutil.ReadFile("/proc/meminfo")
+	return getMachineMemoryCapacity(realFileReader{})
+}
+
+// GetMachineMemoryCapacityWithRoot returns the machine's total memory from /proc/meminfo located under given root.
+// Returns the total memory capacity as an uint64 (number of bytes).
+func GetMachineMemoryCapacityWithRoot(root string) (uint64, error) {
+	return getMachineMemoryCapacity(realFileReader{root: root})
+}
+
+func getMachineMemoryCapacity(reader fileReader) (uint64, error) {
+	out, err := reader.ReadFile(memInfoFile)
 	if err != nil {
 		return 0, err
 	}
@@ -192,6 +289,93 @@ func GetMachineMemoryByType(edacPath string) (map[string]*info.MemoryInfo, error
 	return memory, nil
 }
 
+// GetPmemRegions returns persistent memory regions and their namespaces discovered through
+// libnvdimm sysfs interface (/sys/bus/nd/devices). Namespaces which are not configured (size 0)
+// are skipped. Documentation can be found at https://www.kernel.org/doc/Documentation/nvdimm/nvdimm.txt
+func GetPmemRegions(ndPath string) ([]info.PmemRegion, error) {
+	devices, err := ioutil.ReadDir(ndPath)
+	if os.IsNotExist(err) {
+		// libnvdimm is not loaded, there is no persistent memory.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	regions := []info.PmemRegion{}
+	regionIndex := map[string]int{}
+	for _, device := range devices {
+		if !isNdRegion.MatchString(device.Name()) {
+			continue
+		}
+		size, err := readUintFile(path.Join(ndPath, device.Name(), sizeFileName))
+		if err != nil {
+			return nil, err
+		}
+		regionIndex[device.Name()] = len(regions)
+		regions = append(regions, info.PmemRegion{
+			Name:     device.Name(),
+			Size:     size,
+			NumaNode: readNumaNode(path.Join(ndPath, device.Name(), ndNumaNodeFileName)),
+		})
+	}
+
+	for _, device := range devices {
+		matches := isNdNamespace.FindStringSubmatch(device.Name())
+		if matches == nil {
+			continue
+		}
+		i, ok := regionIndex["region"+matches[1]]
+		if !ok {
+			continue
+		}
+		namespaceDir := path.Join(ndPath, device.Name())
+		size, err := readUintFile(path.Join(namespaceDir, sizeFileName))
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			continue
+		}
+		mode, err := ioutil.ReadFile(path.Join(namespaceDir, ndModeFileName))
+		if err != nil {
+			return nil, err
+		}
+		regions[i].Namespaces = append(regions[i].Namespaces, info.PmemNamespace{
+			Name:     device.Name(),
+			Mode:     strings.TrimSpace(string(mode)),
+			Size:     size,
+			NumaNode: readNumaNode(path.Join(namespaceDir, ndNumaNodeFileName)),
+		})
+	}
+	return regions, nil
+}
+
+func readUintFile(filePath string) (uint64, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %v", filePath, err)
+	}
+	return value, nil
+}
+
+// readNumaNode returns NUMA node from numa_node file, -1 is returned when NUMA node
+// is unknown, i.e. kernel is built without CONFIG_NUMA.
+func readNumaNode(filePath string) int {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return -1
+	}
+	node, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return -1
+	}
+	return node
+}
+
 func mbToBytes(megabytes int) int {
 	return megabytes * 1024 * 1024
 }
@@ -199,7 +383,11 @@ func mbToBytes(megabytes int) int {
 // GetMachineSwapCapacity returns the machine's total swap from /proc/meminfo.
 // Returns the total swap capacity as an uint64 (number of bytes).
 func GetMachineSwapCapacity() (uint64, error) {
-	out, err := ioutil.ReadFile("/proc/meminfo")
+	return getMachineSwapCapacity(realFileReader{})
+}
+
+func getMachineSwapCapacity(reader fileReader) (uint64, error) {
+	out, err := reader.ReadFile(memInfoFile)
 	if err != nil {
 		return 0, err
 	}
@@ -211,13 +399,108 @@ func GetMachineSwapCapacity() (uint64, error) {
 	return swapCapacity, err
 }
 
-// GetTopology returns CPU topology reading information from sysfs
+// LoadAverage holds the system load averages and task counts reported by /proc/loadavg.
+type LoadAverage struct {
+	// Load averages over the last 1, 5 and 15 minutes.
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	// Number of currently runnable kernel scheduling entities (processes, threads).
+	RunnableTasks uint64
+	// Number of kernel scheduling entities that currently exist on the system.
+	TotalTasks uint64
+}
+
+// GetLoadAverage returns the system load averages read from /proc/loadavg.
+func GetLoadAverage() (LoadAverage, error) {
+	out, err := ioutil.ReadFile(loadAvgFile)
+	if err != nil {
+		return LoadAverage{}, err
+	}
+	return parseLoadAverage(out)
+}
+
+// parseLoadAverage parses content formatted as the /proc/loadavg file, e.g.
+// "0.20 0.18 0.12 1/80 11206".
+func parseLoadAverage(b []byte) (LoadAverage, error) {
+	fields := strings.Fields(string(b))
+	if len(fields) < 4 {
+		return LoadAverage{}, fmt.Errorf("unexpected format of load average: %q", string(b))
+	}
+	var loadAvg LoadAverage
+	var err error
+	loads := []*float64{&loadAvg.Load1, &loadAvg.Load5, &loadAvg.Load15}
+	for i, load := range loads {
+		*load, err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return LoadAverage{}, fmt.Errorf("could not parse load average %q: %v", fields[i], err)
+		}
+	}
+	tasks := strings.Split(fields[3], "/")
+	if len(tasks) != 2 {
+		return LoadAverage{}, fmt.Errorf("unexpected format of task counts: %q", fields[3])
+	}
+	loadAvg.RunnableTasks, err = strconv.ParseUint(tasks[0], 10, 64)
+	if err != nil {
+		return LoadAverage{}, fmt.Errorf("could not parse runnable tasks %q: %v", tasks[0], err)
+	}
+	loadAvg.TotalTasks, err = strconv.ParseUint(tasks[1], 10, 64)
+	if err != nil {
+		return LoadAverage{}, fmt.Errorf("could not parse total tasks %q: %v", tasks[1], err)
+	}
+	return loadAvg, nil
+}
+
+// GetOSImage returns the name of operating system (PRETTY_NAME from os-release) installed in root,
+// which allows to read it from the host file system when cAdvisor runs in a container.
+func GetOSImage(root string) (string, error) {
+	// /usr/lib/os-release is used in stateless systems like Clear Linux
+	for _, osReleaseFile := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		content, err := ioutil.ReadFile(filepath.Join(root, osReleaseFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		matches := osReleasePrettyNameRegexp.FindSubmatch(content)
+		if matches == nil {
+			return "Linux", nil
+		}
+		return strings.Trim(strings.TrimSpace(string(matches[1])), `"'`), nil
+	}
+	return "", fmt.Errorf("os-release file not found in %s", root)
+}
+
+// GetBootTime returns the time the system booted at read from /proc/stat in root,
+// uptime of the system can be derived from it.
+func GetBootTime(root string) (time.Time, error) {
+	content, err := ioutil.ReadFile(filepath.Join(root, "/proc/stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	matches := bootTimeRegexp.FindSubmatch(content)
+	if matches == nil {
+		return time.Time{}, fmt.Errorf("boot time not found in /proc/stat")
+	}
+	bootTime, err := strconv.ParseInt(string(matches[1]), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse boot time %q: %v", matches[1], err)
+	}
+	return time.Unix(bootTime, 0), nil
+}
+
+// GetTopology returns CPU topology reading information from sysfs, sysFs created with
+// sysfs.NewRealSysFsWithRoot() allows to read topology of the host from within a container.
 func GetTopology(sysFs sysfs.SysFs) ([]info.Node, int, error) {
-	// s390/s390x changes
-	if isSystemZ() {
+	nodes, numCores, err := sysinfo.GetNodesInfo(sysFs)
+	// s390/s390x changes, topology may not be available e.g. in z/VM guests on older kernels,
+	// number of cores is provided then.
+	if isSystemZ() && (err != nil || len(nodes) == 0) {
+		klog.Warningf("CPU topology is not available, providing number of CPU cores: %v", err)
 		return nil, getNumCores(), nil
 	}
-	return sysinfo.GetNodesInfo(sysFs)
+	return nodes, numCores, err
 }
 
 // parseCapacity matches a Regexp in a []byte, returning the resulting value in bytes.
@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils/sysfs"
@@ -309,6 +310,55 @@ func TestTopologyWithoutNodes(t *testing.T) {
 	assert.JSONEq(t, expectedTopology2, json2)
 }
 
+func TestTopologyOnSystemZBooksAndDrawers(t *testing.T) {
+	machineArch = "s390x" // overwrite package variable
+	defer func() {
+		machineArch = ""
+	}()
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetNodesPaths([]string{}, nil)
+
+	cpusPaths := map[string][]string{
+		"/sys/devices/system/cpu": {
+			"/sys/devices/system/cpu/cpu0",
+			"/sys/devices/system/cpu/cpu1",
+		},
+	}
+	sysFs.SetCPUsPaths(cpusPaths, nil)
+	sysFs.SetCoreThreads(map[string]string{
+		"/sys/devices/system/cpu/cpu0": "0",
+		"/sys/devices/system/cpu/cpu1": "1",
+	}, nil)
+	sysFs.SetPhysicalPackageIDs(map[string]string{
+		"/sys/devices/system/cpu/cpu0": "0",
+		"/sys/devices/system/cpu/cpu1": "0",
+	}, nil)
+	sysFs.SetBookIDs(map[string]string{
+		"/sys/devices/system/cpu/cpu0": "1",
+		"/sys/devices/system/cpu/cpu1": "1",
+	})
+	sysFs.SetDrawerIDs(map[string]string{
+		"/sys/devices/system/cpu/cpu0": "0",
+		"/sys/devices/system/cpu/cpu1": "0",
+	})
+
+	topology, numCores, err := GetTopology(sysFs)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numCores)
+	assert.Equal(t, 1, len(topology))
+	assert.Equal(t, 2, len(topology[0].Cores))
+	for _, core := range topology[0].Cores {
+		assert.Equal(t, "1", core.BookID)
+		assert.Equal(t, "0", core.DrawerID)
+	}
+
+	// Number of cores is provided when topology is not available.
+	topology, numCores, err = GetTopology(&fakesysfs.FakeSysFs{})
+	assert.Nil(t, err)
+	assert.Nil(t, topology)
+	assert.Equal(t, getNumCores(), numCores)
+}
+
 func TestTopologyWithNodesWithoutCPU(t *testing.T) {
 	machineArch = "" // overwrite package variable
 	sysFs := &fakesysfs.FakeSysFs{}
@@ -409,6 +459,37 @@ func TestMemoryInfoOnArchThatDoNotExposeMemoryController(t *testing.T) {
 	assert.Len(t, memory, 0)
 }
 
+func TestPmemRegions(t *testing.T) {
+	regions, err := GetPmemRegions("./testdata/nd/devices")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []info.PmemRegion{
+		{
+			Name:     "region0",
+			Size:     136365211648,
+			NumaNode: 0,
+			Namespaces: []info.PmemNamespace{
+				{Name: "namespace0.0", Mode: "fsdax", Size: 133175443456, NumaNode: 0},
+			},
+		},
+		{
+			Name:     "region1",
+			Size:     136365211648,
+			NumaNode: 1,
+			Namespaces: []info.PmemNamespace{
+				{Name: "namespace1.0", Mode: "devdax", Size: 135289372672, NumaNode: 1},
+			},
+		},
+	}, regions)
+}
+
+func TestPmemRegionsWithoutLibnvdimm(t *testing.T) {
+	regions, err := GetPmemRegions("./there/is/no/spoon")
+
+	assert.Nil(t, err)
+	assert.Nil(t, regions)
+}
+
 func TestClockSpeedOnCpuUpperCase(t *testing.T) {
 	maxFreqFile = ""                            // do not read the system max frequency
 	machineArch = ""                            // overwrite package variable
@@ -438,3 +519,152 @@ func TestClockSpeedOnCpuLowerCase(t *testing.T) {
 	assert.NotNil(t, clockSpeed)
 	assert.Equal(t, uint64(1450*1000), clockSpeed)
 }
+
+func TestClockSpeedOnAArch64(t *testing.T) {
+	maxFreqFile = "" // do not read the system max frequency
+	machineArch = "aarch64"
+	originalCPPCNominalFreqFile := cppcNominalFreqFile
+	defer func() {
+		machineArch = ""
+		cppcNominalFreqFile = originalCPPCNominalFreqFile
+	}()
+
+	// Clock speed is not reported in /proc/cpuinfo on aarch64.
+	cppcNominalFreqFile = ""
+	clockSpeed, err := GetClockSpeed([]byte("processor\t: 0\nBogoMIPS\t: 243.75\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), clockSpeed)
+
+	cppcNominalFreqFile = "./testdata/acpi_cppc/nominal_freq"
+	clockSpeed, err = GetClockSpeed([]byte("processor\t: 0\nBogoMIPS\t: 243.75\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2600*1000), clockSpeed)
+}
+
+func TestLoadAverage(t *testing.T) {
+	originalLoadAvgFile := loadAvgFile
+	defer func() {
+		loadAvgFile = originalLoadAvgFile
+	}()
+	loadAvgFile = "./testdata/loadavg" // overwriting package variable to mock procfs
+
+	loadAvg, err := GetLoadAverage()
+	assert.Nil(t, err)
+	assert.Equal(t, LoadAverage{
+		Load1:         0.75,
+		Load5:         1.03,
+		Load15:        1.21,
+		RunnableTasks: 3,
+		TotalTasks:    1024,
+	}, loadAvg)
+}
+
+func TestLoadAverageWithMalformedTasks(t *testing.T) {
+	_, err := parseLoadAverage([]byte("0.75 1.03 1.21 1024 123456\n"))
+	assert.NotNil(t, err)
+
+	_, err = parseLoadAverage([]byte("0.75 1.03\n"))
+	assert.NotNil(t, err)
+}
+
+type fakeFileReader map[string]string
+
+func (r fakeFileReader) ReadFile(filename string) ([]byte, error) {
+	content, ok := r[filename]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+const testMemInfo = `MemTotal:       32817192 kB
+MemFree:         8016108 kB
+MemAvailable:   20741316 kB
+Buffers:          893040 kB
+Cached:         11403036 kB
+SwapCached:            0 kB
+SwapTotal:       2097148 kB
+SwapFree:        2097148 kB
+`
+
+func TestMachineMemoryCapacity(t *testing.T) {
+	reader := fakeFileReader{memInfoFile: testMemInfo}
+
+	memoryCapacity, err := getMachineMemoryCapacity(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(32817192*1024), memoryCapacity)
+}
+
+func TestMachineSwapCapacity(t *testing.T) {
+	reader := fakeFileReader{memInfoFile: testMemInfo}
+
+	swapCapacity, err := getMachineSwapCapacity(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2097148*1024), swapCapacity)
+}
+
+func TestMachineSwapCapacityWithoutSwapTotal(t *testing.T) {
+	reader := fakeFileReader{memInfoFile: "MemTotal:       32817192 kB\nMemFree:         8016108 kB\n"}
+
+	swapCapacity, err := getMachineSwapCapacity(reader)
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(0), swapCapacity)
+
+	// Memory capacity is still available.
+	memoryCapacity, err := getMachineMemoryCapacity(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(32817192*1024), memoryCapacity)
+}
+
+func TestMachineMemoryCapacityWithoutMemInfo(t *testing.T) {
+	_, err := getMachineMemoryCapacity(fakeFileReader{})
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = getMachineSwapCapacity(fakeFileReader{})
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMachineMemoryCapacityWithRoot(t *testing.T) {
+	memoryCapacity, err := GetMachineMemoryCapacityWithRoot("./testdata/host")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(16408596*1024), memoryCapacity)
+}
+
+func TestGetOSImage(t *testing.T) {
+	osImage, err := GetOSImage("./testdata/host")
+	assert.Nil(t, err)
+	assert.Equal(t, "Ubuntu 20.04.1 LTS", osImage)
+
+	_, err = GetOSImage("./testdata/non-existing")
+	assert.NotNil(t, err)
+}
+
+func TestGetBootTime(t *testing.T) {
+	bootTime, err := GetBootTime("./testdata/host")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1595234012, 0), bootTime)
+
+	_, err = GetBootTime("./testdata/non-existing")
+	assert.NotNil(t, err)
+}
+
+func TestGetCPUInfo(t *testing.T) {
+	cpuinfo, err := ioutil.ReadFile("./testdata/cpuinfo_flags")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.CPUInfo{
+		Vendor:    "GenuineIntel",
+		Model:     "Intel(R) Xeon(R) Gold 6248 CPU @ 2.50GHz",
+		Microcode: "0x5003006",
+		Flags: []string{"aes", "avx", "avx2", "avx512_vnni", "avx512bw", "avx512cd", "avx512dq", "avx512f", "avx512vl",
+			"fma", "hypervisor", "sse4_1", "sse4_2", "vmx"},
+	}, GetCPUInfo(cpuinfo))
+
+	cpuinfo, err = ioutil.ReadFile("./testdata/cpuinfo_arm")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.CPUInfo{
+		Vendor: "0x41",
+		Model:  "ARMv7 Processor rev 4 (v7l)",
+	}, GetCPUInfo(cpuinfo))
+
+	assert.Nil(t, GetCPUInfo([]byte("processor\t: 0\n")))
+}
@@ -27,11 +27,13 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/cadvisor/cache/memory"
 	"github.com/google/cadvisor/collector"
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	v2 "github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/stats"
@@ -46,6 +48,8 @@ import (
 // Housekeeping interval.
 var enableLoadReader = flag.Bool("enable_load_reader", false, "Whether to enable cpu load reader")
 var HousekeepingInterval = flag.Duration("housekeeping_interval", 1*time.Second, "Interval between container housekeepings")
+var conntrackLimitEventThreshold = flag.Float64("conntrack_limit_event_threshold", 0.9, "Fraction of the conntrack table size at which a conntrackLimit event is added for the container. The event is added again only after number of entries drops below the threshold. Set to 0 to disable.")
+var pidsLimitEventThreshold = flag.Float64("pids_limit_event_threshold", 0.9, "Fraction of the pids cgroup limit at which a pidsLimit event is added for the container. The event is added again only after number of tasks drops below the threshold. Set to 0 to disable.")
 
 // cgroup type chosen to fetch the cgroup path of a process.
 // Memory has been chosen, as it is one of the default cgroups that is enabled for most containers.
@@ -58,6 +62,10 @@ type containerInfo struct {
 }
 
 type containerData struct {
+	// Number of OOM kills of the container's processes reported in kernel log, accessed atomically.
+	// It is the first field to be 64-bit aligned on 32-bit platforms.
+	oomKills uint64
+
 	handler                  container.ContainerHandler
 	info                     containerInfo
 	memoryCache              *memory.InMemoryCache
@@ -92,11 +100,35 @@ type containerData struct {
 	// nvidiaCollector updates stats for Nvidia GPUs attached to the container.
 	nvidiaCollector stats.Collector
 
+	// amdCollector updates stats for AMD GPUs attached to the container.
+	amdCollector stats.Collector
+
 	// perfCollector updates stats for perf_event cgroup controller.
 	perfCollector stats.Collector
 
 	// resctrlCollector updates stats for resctrl controller.
 	resctrlCollector stats.Collector
+
+	// cgroupNetworkCollector updates network traffic of the cgroup accounted by eBPF programs.
+	cgroupNetworkCollector stats.Collector
+
+	// bpfSchedCollector updates run queue latency and system call counts of the cgroup measured by eBPF programs.
+	bpfSchedCollector stats.Collector
+
+	// eventHandler receives events raised while updating stats, e.g. when number of tasks approaches pids limit.
+	eventHandler events.EventManager
+
+	// Whether number of tasks was above pids limit event threshold when stats were last updated.
+	pidsLimitExceeded bool
+
+	// Whether number of conntrack entries was above conntrack limit event threshold when stats were last updated.
+	conntrackLimitExceeded bool
+
+	// metricSchedule tracks when metrics with their own intervals are collected.
+	metricSchedule *container.MetricSchedule
+
+	// lastStats are stats last added to memory cache, stats of metrics which are not due are copied from them.
+	lastStats *info.ContainerStats
 }
 
 // jitter returns a time.Duration between duration and duration + maxFactor * duration,
@@ -122,6 +154,9 @@ func (cd *containerData) Stop() error {
 	}
 	close(cd.stop)
 	cd.perfCollector.Destroy()
+	cd.cgroupNetworkCollector.Destroy()
+	cd.bpfSchedCollector.Destroy()
+	cd.resctrlCollector.Destroy()
 	return nil
 }
 
@@ -289,12 +324,12 @@ func (cd *containerData) GetProcessList(cadvisorContainer string, inHostNamespac
 	if !inHostNamespace {
 		rootfs = "/rootfs"
 	}
-	format := "user,pid,ppid,stime,pcpu,pmem,rss,vsz,stat,time,comm,psr,cgroup"
+	format := "user,pid,ppid,stime,pcpu,pmem,rss,vsz,stat,time,comm,psr,nlwp,cgroup"
 	out, err := cd.getPsOutput(inHostNamespace, format)
 	if err != nil {
 		return nil, err
 	}
-	expectedFields := 13
+	expectedFields := 14
 	processes := []v2.ProcessInfo{}
 	lines := strings.Split(string(out), "\n")
 	for _, line := range lines[1:] {
@@ -338,9 +373,14 @@ func (cd *containerData) GetProcessList(cadvisorContainer string, inHostNamespac
 			return nil, fmt.Errorf("invalid pid %q: %v", fields[1], err)
 		}
 
-		cgroup, err := cd.getCgroupPath(fields[12])
+		threads, err := strconv.Atoi(fields[12])
 		if err != nil {
-			return nil, fmt.Errorf("could not parse cgroup path from %q: %v", fields[11], err)
+			return nil, fmt.Errorf("invalid number of threads %q: %v", fields[12], err)
+		}
+
+		cgroup, err := cd.getCgroupPath(fields[13])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse cgroup path from %q: %v", fields[13], err)
 		}
 		// Remove the ps command we just ran from cadvisor container.
 		// Not necessary, but makes the cadvisor page look cleaner.
@@ -377,6 +417,7 @@ func (cd *containerData) GetProcessList(cadvisorContainer string, inHostNamespac
 				CgroupPath:    cgroupPath,
 				FdCount:       fdCount,
 				Psr:           psr,
+				Threads:       threads,
 			})
 		}
 	}
@@ -409,7 +450,11 @@ func newContainerData(containerName string, memoryCache *memory.InMemoryCache, h
 		clock:                    clock,
 		perfCollector:            &stats.NoopCollector{},
 		nvidiaCollector:          &stats.NoopCollector{},
+		amdCollector:             &stats.NoopCollector{},
 		resctrlCollector:         &stats.NoopCollector{},
+		cgroupNetworkCollector:   &stats.NoopCollector{},
+		bpfSchedCollector:        &stats.NoopCollector{},
+		metricSchedule:           container.NewMetricSchedule(container.ArgMetricIntervals),
 	}
 	cont.info.ContainerReference = ref
 
@@ -643,15 +688,31 @@ func (cd *containerData) updateStats() error {
 		}
 	}
 
-	var nvidiaStatsErr error
-	if cd.nvidiaCollector != nil {
-		// This updates the Accelerators field of the stats struct
-		nvidiaStatsErr = cd.nvidiaCollector.UpdateStats(stats)
+	now := cd.clock.Now()
+	var nvidiaStatsErr, amdStatsErr error
+	if cd.collectorDue(container.AcceleratorUsageMetrics, now, stats) {
+		if cd.nvidiaCollector != nil {
+			// This updates the Accelerators field of the stats struct
+			nvidiaStatsErr = cd.nvidiaCollector.UpdateStats(stats)
+		}
+		if cd.amdCollector != nil {
+			amdStatsErr = cd.amdCollector.UpdateStats(stats)
+		}
 	}
 
-	perfStatsErr := cd.perfCollector.UpdateStats(stats)
-
-	resctrlStatsErr := cd.resctrlCollector.UpdateStats(stats)
+	var perfStatsErr, resctrlStatsErr, cgroupNetworkStatsErr, bpfSchedStatsErr error
+	if cd.collectorDue(container.PerfMetrics, now, stats) {
+		perfStatsErr = cd.perfCollector.UpdateStats(stats)
+	}
+	if cd.collectorDue(container.ResctrlMetrics, now, stats) {
+		resctrlStatsErr = cd.resctrlCollector.UpdateStats(stats)
+	}
+	if cd.collectorDue(container.CgroupNetworkMetrics, now, stats) {
+		cgroupNetworkStatsErr = cd.cgroupNetworkCollector.UpdateStats(stats)
+	}
+	if cd.collectorDue(container.BPFSchedMetrics, now, stats) {
+		bpfSchedStatsErr = cd.bpfSchedCollector.UpdateStats(stats)
+	}
 
 	ref, err := cd.handler.ContainerReference()
 	if err != nil {
@@ -662,14 +723,27 @@ func (cd *containerData) updateStats() error {
 		return err
 	}
 
+	// Kernel log is used when the kernel doesn't count OOM kills in memory cgroup.
+	if oomKills := atomic.LoadUint64(&cd.oomKills); oomKills > stats.Memory.OOMKills {
+		stats.Memory.OOMKills = oomKills
+	}
+
+	// Storage drivers export the spec, e.g. labels and image of the container.
+	cd.lock.Lock()
+	spec := cd.info.Spec
+	cd.lock.Unlock()
 	cInfo := info.ContainerInfo{
 		ContainerReference: ref,
+		Spec:               spec,
 	}
 
 	err = cd.memoryCache.AddStats(&cInfo, stats)
 	if err != nil {
 		return err
 	}
+	cd.lastStats = stats
+	cd.checkPidsLimit(cInfo.Name, stats)
+	cd.checkConntrackLimit(cInfo.Name, stats)
 	if statsErr != nil {
 		return statsErr
 	}
@@ -677,6 +751,10 @@ func (cd *containerData) updateStats() error {
 		klog.Errorf("error occurred while collecting nvidia stats for container %s: %s", cInfo.Name, err)
 		return nvidiaStatsErr
 	}
+	if amdStatsErr != nil {
+		klog.Errorf("error occurred while collecting amd stats for container %s: %s", cInfo.Name, amdStatsErr)
+		return amdStatsErr
+	}
 	if perfStatsErr != nil {
 		klog.Errorf("error occurred while collecting perf stats for container %s: %s", cInfo.Name, err)
 		return perfStatsErr
@@ -685,9 +763,95 @@ func (cd *containerData) updateStats() error {
 		klog.Errorf("error occurred while collecting resctrl stats for container %s: %s", cInfo.Name, err)
 		return resctrlStatsErr
 	}
+	if cgroupNetworkStatsErr != nil {
+		klog.Errorf("error occurred while collecting cgroup network stats for container %s: %s", cInfo.Name, cgroupNetworkStatsErr)
+		return cgroupNetworkStatsErr
+	}
+	if bpfSchedStatsErr != nil {
+		klog.Errorf("error occurred while collecting run queue latency for container %s: %s", cInfo.Name, bpfSchedStatsErr)
+		return bpfSchedStatsErr
+	}
 	return customStatsErr
 }
 
+// collectorDue returns true if stats of the metric kind should be collected now, otherwise the stats
+// collected last time are copied to stats.
+func (cd *containerData) collectorDue(kind container.MetricKind, now time.Time, stats *info.ContainerStats) bool {
+	if cd.metricSchedule.Due(kind, now) || cd.lastStats == nil {
+		return true
+	}
+	last := cd.lastStats
+	switch kind {
+	case container.AcceleratorUsageMetrics:
+		stats.Accelerators = last.Accelerators
+	case container.PerfMetrics:
+		stats.PerfStats = last.PerfStats
+		stats.PerfUncoreStats = last.PerfUncoreStats
+	case container.ResctrlMetrics:
+		stats.Resctrl = last.Resctrl
+	case container.CgroupNetworkMetrics:
+		stats.Network.Cgroup = last.Network.Cgroup
+	case container.BPFSchedMetrics:
+		stats.Cpu.RunqueueLatency = last.Cpu.RunqueueLatency
+		stats.Syscalls = last.Syscalls
+	}
+	return false
+}
+
+// checkPidsLimit adds a pidsLimit event when number of tasks of the container reaches the configured
+// fraction of pids limit. The event is not repeated until number of tasks drops below the threshold.
+func (cd *containerData) checkPidsLimit(containerName string, stats *info.ContainerStats) {
+	if cd.eventHandler == nil || *pidsLimitEventThreshold <= 0 || stats.Processes.ThreadsMax == 0 {
+		return
+	}
+	exceeded := float64(stats.Processes.ThreadsCurrent) >= *pidsLimitEventThreshold*float64(stats.Processes.ThreadsMax)
+	if exceeded && !cd.pidsLimitExceeded {
+		newEvent := &info.Event{
+			ContainerName: containerName,
+			Timestamp:     stats.Timestamp,
+			EventType:     info.EventPidsLimit,
+			EventData: info.EventData{
+				PidsLimit: &info.PidsLimitEventData{
+					Current: stats.Processes.ThreadsCurrent,
+					Limit:   stats.Processes.ThreadsMax,
+				},
+			},
+		}
+		err := cd.eventHandler.AddEvent(newEvent)
+		if err != nil {
+			klog.Errorf("failed to add pids limit event for %q: %v", containerName, err)
+		}
+	}
+	cd.pidsLimitExceeded = exceeded
+}
+
+// checkConntrackLimit adds a conntrackLimit event when number of entries of conntrack table reaches the
+// configured fraction of its size. The event is not repeated until number of entries drops below the threshold.
+func (cd *containerData) checkConntrackLimit(containerName string, stats *info.ContainerStats) {
+	if cd.eventHandler == nil || *conntrackLimitEventThreshold <= 0 || stats.Network.Conntrack.Max == 0 {
+		return
+	}
+	exceeded := float64(stats.Network.Conntrack.Entries) >= *conntrackLimitEventThreshold*float64(stats.Network.Conntrack.Max)
+	if exceeded && !cd.conntrackLimitExceeded {
+		newEvent := &info.Event{
+			ContainerName: containerName,
+			Timestamp:     stats.Timestamp,
+			EventType:     info.EventConntrackLimit,
+			EventData: info.EventData{
+				ConntrackLimit: &info.ConntrackLimitEventData{
+					Entries: stats.Network.Conntrack.Entries,
+					Max:     stats.Network.Conntrack.Max,
+				},
+			},
+		}
+		err := cd.eventHandler.AddEvent(newEvent)
+		if err != nil {
+			klog.Errorf("failed to add conntrack limit event for %q: %v", containerName, err)
+		}
+	}
+	cd.conntrackLimitExceeded = exceeded
+}
+
 func (cd *containerData) updateCustomStats() (map[string][]info.MetricVal, error) {
 	_, customStats, customStatsErr := cd.collectorManager.Collect()
 	if customStatsErr != nil {
@@ -27,6 +27,7 @@ import (
 	"github.com/google/cadvisor/collector"
 	"github.com/google/cadvisor/container"
 	containertest "github.com/google/cadvisor/container/testing"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	itest "github.com/google/cadvisor/info/v1/test"
 
@@ -150,6 +151,94 @@ func TestUpdateStats(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestUpdateStatsWithKernelLogOOMKills(t *testing.T) {
+	stats := itest.GenerateRandomStats(1, 4, 1*time.Second)[0]
+	stats.Memory.OOMKills = 1
+
+	cd, mockHandler, memoryCache, _ := newTestContainerData(t)
+	mockHandler.On("GetStats").Return(
+		stats,
+		nil,
+	)
+	cd.oomKills = 3
+
+	err := cd.updateStats()
+	require.Nil(t, err)
+
+	recent, err := memoryCache.RecentStats(containerName, time.Time{}, time.Time{}, 1)
+	require.Nil(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, uint64(3), recent[0].Memory.OOMKills)
+}
+
+func TestCheckPidsLimit(t *testing.T) {
+	cd, _, _, _ := newTestContainerData(t)
+	cd.eventHandler = events.NewEventManager(events.DefaultStoragePolicy())
+	check := func(current uint64) {
+		cd.checkPidsLimit(containerName, &info.ContainerStats{
+			Timestamp: time.Now(),
+			Processes: info.ProcessStats{ThreadsCurrent: current, ThreadsMax: 100},
+		})
+	}
+	pidsLimitEvents := func() []*info.Event {
+		request := events.NewRequest()
+		request.EventType[info.EventPidsLimit] = true
+		request.ContainerName = containerName
+		evs, err := cd.eventHandler.GetEvents(request)
+		require.Nil(t, err)
+		return evs
+	}
+
+	check(50)
+	assert.Empty(t, pidsLimitEvents())
+
+	// Event is added once while number of tasks stays above the threshold.
+	check(90)
+	check(95)
+	evs := pidsLimitEvents()
+	require.Len(t, evs, 1)
+	assert.Equal(t, &info.PidsLimitEventData{Current: 90, Limit: 100}, evs[0].EventData.PidsLimit)
+
+	// Event is added again after number of tasks dropped below the threshold.
+	check(10)
+	check(99)
+	assert.Len(t, pidsLimitEvents(), 2)
+}
+
+func TestCheckConntrackLimit(t *testing.T) {
+	cd, _, _, _ := newTestContainerData(t)
+	cd.eventHandler = events.NewEventManager(events.DefaultStoragePolicy())
+	check := func(entries uint64) {
+		cd.checkConntrackLimit(containerName, &info.ContainerStats{
+			Timestamp: time.Now(),
+			Network: info.NetworkStats{
+				Conntrack: info.ConntrackStat{Entries: entries, Max: 1000},
+			},
+		})
+	}
+	conntrackLimitEvents := func() []*info.Event {
+		request := events.NewRequest()
+		request.EventType[info.EventConntrackLimit] = true
+		request.ContainerName = containerName
+		evs, err := cd.eventHandler.GetEvents(request)
+		require.Nil(t, err)
+		return evs
+	}
+
+	check(100)
+	assert.Empty(t, conntrackLimitEvents())
+
+	check(950)
+	check(990)
+	evs := conntrackLimitEvents()
+	require.Len(t, evs, 1)
+	assert.Equal(t, &info.ConntrackLimitEventData{Entries: 950, Max: 1000}, evs[0].EventData.ConntrackLimit)
+
+	check(10)
+	check(900)
+	assert.Len(t, conntrackLimitEvents(), 2)
+}
+
 func TestUpdateSpec(t *testing.T) {
 	spec := itest.GenerateRandomContainerSpec(4)
 	cd, mockHandler, _, _ := newTestContainerData(t)
@@ -231,6 +320,42 @@ func TestUpdateNvidiaStats(t *testing.T) {
 	assert.Equal(t, info.ContainerStats{}, stats)
 }
 
+// countingCollector sets the number of its updates as perf stats.
+type countingCollector struct {
+	updates int
+}
+
+func (c *countingCollector) UpdateStats(stats *info.ContainerStats) error {
+	c.updates++
+	stats.PerfStats = []info.PerfStat{{PerfValue: info.PerfValue{Value: uint64(c.updates)}}}
+	return nil
+}
+
+func (c *countingCollector) Destroy() {}
+
+func TestUpdateStatsWithMetricInterval(t *testing.T) {
+	statsList := itest.GenerateRandomStats(3, 4, 1*time.Second)
+	cd, mockHandler, _, fakeClock := newTestContainerData(t)
+	for _, stats := range statsList {
+		mockHandler.On("GetStats").Return(stats, nil).Once()
+	}
+	collector := &countingCollector{}
+	cd.perfCollector = collector
+	cd.metricSchedule = container.NewMetricSchedule(container.MetricIntervals{container.PerfMetrics: time.Minute})
+
+	require.Nil(t, cd.updateStats())
+	fakeClock.Step(30 * time.Second)
+	require.Nil(t, cd.updateStats())
+	// Perf stats are not collected again before the interval elapses.
+	assert.Equal(t, 1, collector.updates)
+	assert.Equal(t, []info.PerfStat{{PerfValue: info.PerfValue{Value: 1}}}, statsList[1].PerfStats)
+
+	fakeClock.Step(30 * time.Second)
+	require.Nil(t, cd.updateStats())
+	assert.Equal(t, 2, collector.updates)
+	assert.Equal(t, []info.PerfStat{{PerfValue: info.PerfValue{Value: 2}}}, statsList[2].PerfStats)
+}
+
 func TestOnDemandHousekeeping(t *testing.T) {
 	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
 	stats := statsList[0]
@@ -21,12 +21,17 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/cadvisor/accelerators"
+	"github.com/google/cadvisor/bpf"
 	"github.com/google/cadvisor/cache/memory"
 	"github.com/google/cadvisor/collector"
 	"github.com/google/cadvisor/container"
@@ -43,12 +48,12 @@ import (
 	"github.com/google/cadvisor/stats"
 	"github.com/google/cadvisor/utils/oomparser"
 	"github.com/google/cadvisor/utils/sysfs"
+	"github.com/google/cadvisor/utils/sysinfo"
 	"github.com/google/cadvisor/version"
 	"github.com/google/cadvisor/watcher"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
-	"github.com/opencontainers/runc/libcontainer/intelrdt"
 
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
@@ -56,9 +61,11 @@ import (
 
 var globalHousekeepingInterval = flag.Duration("global_housekeeping_interval", 1*time.Minute, "Interval between global housekeepings")
 var updateMachineInfoInterval = flag.Duration("update_machine_info_interval", 5*time.Minute, "Interval between machine info updates.")
+var hotplugCheckInterval = flag.Duration("hotplug_check_interval", 10*time.Second, "Interval between checks of online CPUs and memory, machine info (including topology) is updated as soon as CPUs or memory are hotplugged. Set to 0 to disable the check.")
 var logCadvisorUsage = flag.Bool("log_cadvisor_usage", false, "Whether to log the usage of the cAdvisor container")
 var eventStorageAgeLimit = flag.String("event_storage_age_limit", "default=24h", "Max length of time for which to store events (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is a duration. Default is applied to all non-specified event types")
 var eventStorageEventLimit = flag.String("event_storage_event_limit", "default=100000", "Max number of events to store (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is an integer. Default is applied to all non-specified event types")
+var thinPoolMetadataEventThreshold = flag.Float64("thin_pool_metadata_event_threshold", 0.8, "Fraction of metadata space of devicemapper thin pool at which a thinPoolMetadata event is added for the root container. The event is added again only after metadata usage drops below the threshold. Set to 0 to disable.")
 var applicationMetricsCountLimit = flag.Int("application_metrics_count_limit", 100, "Max number of application metrics to store (per container)")
 
 // The Manager interface defines operations for starting a manager and getting
@@ -103,6 +110,20 @@ type Manager interface {
 	// Get information about the machine.
 	GetMachineInfo() (*info.MachineInfo, error)
 
+	// Get information about the machine with current values of its counters, e.g. energy of RAPL
+	// power zones or IO statistics of block devices, which are read on each call.
+	GetMachineCounters() (*info.MachineInfo, error)
+
+	// Update information about the machine immediately instead of waiting for periodic update.
+	RefreshMachineInfo() (*info.MachineInfo, error)
+
+	// Get JSON configuration of measured perf events.
+	GetPerfEventsConfig() []byte
+
+	// Replace measured perf events with events from JSON configuration, configuration is
+	// only validated if dryRun is set.
+	ReloadPerfEvents(config []byte, dryRun bool) error
+
 	// Get version information about different components we depend on.
 	GetVersionInfo() (*info.VersionInfo, error)
 
@@ -202,6 +223,9 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 		eventsChannel:                         eventsChannel,
 		collectorHTTPClient:                   collectorHTTPClient,
 		nvidiaManager:                         accelerators.NewNvidiaManager(includedMetricsSet),
+		amdManager:                            accelerators.NewAMDManager(includedMetricsSet),
+		cgroupNetworkManager:                  bpf.NewManager(includedMetricsSet),
+		bpfSchedManager:                       bpf.NewSchedManager(includedMetricsSet),
 		rawContainerCgroupPathPrefixWhiteList: rawContainerCgroupPathPrefixWhiteList,
 	}
 
@@ -217,7 +241,7 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 		return nil, err
 	}
 
-	newManager.resctrlManager, err = resctrl.NewManager(selfContainer)
+	newManager.resctrlManager, err = resctrl.NewManager()
 	if err != nil {
 		klog.V(4).Infof("Cannot gather resctrl metrics: %v", err)
 	}
@@ -261,8 +285,16 @@ type manager struct {
 	eventsChannel            chan watcher.ContainerEvent
 	collectorHTTPClient      *http.Client
 	nvidiaManager            stats.Manager
-	perfManager              stats.Manager
-	resctrlManager           stats.Manager
+	amdManager               stats.Manager
+	cgroupNetworkManager     stats.Manager
+	bpfSchedManager          stats.Manager
+	perfManager              perf.Manager
+	resctrlManager           resctrl.Manager
+	// Memory state machine info was last refreshed with, accessed only by updateMachineInfo.
+	memoryState memoryHotplugState
+	// Names of thin pools whose metadata usage is above thin_pool_metadata_event_threshold.
+	thinPoolsMu               sync.Mutex // protects thinPoolsMetadataExceeded
+	thinPoolsMetadataExceeded map[string]bool
 	// List of raw container cgroup path prefix whitelist.
 	rawContainerCgroupPathPrefixWhiteList []string
 }
@@ -327,6 +359,9 @@ func (m *manager) Start() error {
 
 func (m *manager) Stop() error {
 	defer m.nvidiaManager.Destroy()
+	defer m.amdManager.Destroy()
+	defer m.cgroupNetworkManager.Destroy()
+	defer m.bpfSchedManager.Destroy()
 	defer m.destroyPerfCollectors()
 	// Stop and wait on all quit channels.
 	for i, c := range m.quitChannels {
@@ -351,20 +386,41 @@ func (m *manager) destroyPerfCollectors() {
 	}
 }
 
+// memoryHotplugState changes when memory is hot-added or hot-removed.
+type memoryHotplugState struct {
+	onlineBlocks int
+	nodes        int
+}
+
 func (m *manager) updateMachineInfo(quit chan error) {
 	ticker := time.NewTicker(*updateMachineInfoInterval)
+	// Hotplug check is disabled when its ticker channel is nil.
+	var hotplugC <-chan time.Time
+	if *hotplugCheckInterval > 0 {
+		hotplugTicker := time.NewTicker(*hotplugCheckInterval)
+		defer hotplugTicker.Stop()
+		hotplugC = hotplugTicker.C
+		// Record memory state which machine info was built with.
+		m.memoryChanged()
+	}
+	if machineInfo, err := m.GetMachineInfo(); err == nil {
+		m.checkThinPoolsMetadata(machineInfo)
+	}
 	for {
 		select {
 		case <-ticker.C:
-			info, err := machine.Info(m.sysFs, m.fsInfo, m.inHostNamespace)
-			if err != nil {
+			if _, err := m.refreshMachineInfo(); err != nil {
 				klog.Errorf("Could not get machine info: %v", err)
-				break
 			}
-			m.machineMu.Lock()
-			m.machineInfo = *info
-			m.machineMu.Unlock()
-			klog.V(5).Infof("Update machine info: %+v", *info)
+		case <-hotplugC:
+			cpusChanged := m.onlineCPUsChanged()
+			memoryChanged := m.memoryChanged()
+			if cpusChanged || memoryChanged {
+				klog.Infof("CPUs or memory were hotplugged (CPUs changed: %t, memory changed: %t), updating machine info", cpusChanged, memoryChanged)
+				if _, err := m.refreshMachineInfo(); err != nil {
+					klog.Errorf("Could not get machine info: %v", err)
+				}
+			}
 		case <-quit:
 			ticker.Stop()
 			quit <- nil
@@ -373,6 +429,190 @@ func (m *manager) updateMachineInfo(quit chan error) {
 	}
 }
 
+// refreshMachineInfo updates machine info and adds machine changed event when
+// hardware or software of the machine differs from the previous snapshot.
+func (m *manager) refreshMachineInfo() (*info.MachineInfo, error) {
+	machineInfo, err := machine.Info(m.sysFs, m.fsInfo, m.inHostNamespace)
+	if err != nil {
+		return nil, err
+	}
+	m.machineMu.Lock()
+	changedFields := machineInfoChanges(&m.machineInfo, machineInfo)
+	m.machineInfo = *machineInfo
+	m.machineMu.Unlock()
+	klog.V(5).Infof("Update machine info: %+v", *machineInfo)
+
+	if len(changedFields) > 0 {
+		klog.Infof("Machine info changed, changed fields: %v", changedFields)
+		m.addMachineChangedEvent(changedFields)
+	}
+	m.checkThinPoolsMetadata(machineInfo)
+	return machineInfo.Clone(), nil
+}
+
+// machineInfoChanges returns JSON names of fields which differ between previous and current
+// machine info, counters are not compared.
+func machineInfoChanges(previous, current *info.MachineInfo) []string {
+	previousValue := reflect.ValueOf(machineInfoWithoutCounters(previous))
+	currentValue := reflect.ValueOf(machineInfoWithoutCounters(current))
+	changedFields := []string{}
+	for i := 0; i < previousValue.NumField(); i++ {
+		if !reflect.DeepEqual(previousValue.Field(i).Interface(), currentValue.Field(i).Interface()) {
+			name := strings.Split(previousValue.Type().Field(i).Tag.Get("json"), ",")[0]
+			changedFields = append(changedFields, name)
+		}
+	}
+	return changedFields
+}
+
+// machineInfoWithoutCounters returns shallow copy of machine info with fields cleared which are read
+// together with machine info but describe current state rather than hardware or software of the machine,
+// e.g. counters or temperatures. All such fields have to be cleared here, otherwise every update of machine
+// info is reported as machine change. Slices are copied before their elements are cleared.
+func machineInfoWithoutCounters(machineInfo *info.MachineInfo) info.MachineInfo {
+	result := *machineInfo
+	result.Timestamp = time.Time{}
+	result.CPUFrequencies = nil
+	result.PowerZones = nil
+	result.ThermalSensors = nil
+	result.BlockDeviceStats = nil
+	result.InfinibandPorts = nil
+	result.NetworkQueueStats = nil
+	result.ThinPools = nil
+	result.PerfUncoreStats = nil
+	result.HugePages = hugePagesWithoutCounters(machineInfo.HugePages)
+	result.Topology = make([]info.Node, len(machineInfo.Topology))
+	for i, node := range machineInfo.Topology {
+		node.MemoryFree = 0
+		node.MemoryFile = 0
+		node.MemoryAnon = 0
+		node.HugePages = hugePagesWithoutCounters(node.HugePages)
+		result.Topology[i] = node
+	}
+	result.NetworkDevices = make([]info.NetInfo, len(machineInfo.NetworkDevices))
+	for i, device := range machineInfo.NetworkDevices {
+		device.EthtoolStats = nil
+		result.NetworkDevices[i] = device
+	}
+	result.NVMeDevices = make([]info.NVMeDevice, len(machineInfo.NVMeDevices))
+	for i, device := range machineInfo.NVMeDevices {
+		device.Temperature = nil
+		result.NVMeDevices[i] = device
+	}
+	return result
+}
+
+// hugePagesWithoutCounters returns copy of huge pages info with free, surplus and reserved pages cleared.
+func hugePagesWithoutCounters(hugePages []info.HugePagesInfo) []info.HugePagesInfo {
+	if hugePages == nil {
+		return nil
+	}
+	result := make([]info.HugePagesInfo, len(hugePages))
+	for i, hugePage := range hugePages {
+		hugePage.FreePages = 0
+		hugePage.SurplusPages = 0
+		hugePage.ReservedPages = 0
+		result[i] = hugePage
+	}
+	return result
+}
+
+// onlineCPUsChanged determines if CPUs were brought online or offline (e.g. VM was resized)
+// since machine info was updated.
+func (m *manager) onlineCPUsChanged() bool {
+	online, _, err := sysinfo.GetOnlineAndOfflineCPUs(m.sysFs)
+	if err != nil {
+		klog.V(4).Infof("Could not get online CPUs: %v", err)
+		return false
+	}
+	m.machineMu.RLock()
+	defer m.machineMu.RUnlock()
+	if len(online) != len(m.machineInfo.OnlineCPUs) {
+		return true
+	}
+	for i := range online {
+		if online[i] != m.machineInfo.OnlineCPUs[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryChanged determines if memory blocks were brought online or offline, or NUMA nodes
+// appeared or disappeared since last check.
+func (m *manager) memoryChanged() bool {
+	onlineBlocks, err := sysinfo.GetOnlineMemoryBlocks(m.sysFs)
+	if err != nil {
+		klog.V(4).Infof("Could not get online memory blocks: %v", err)
+		return false
+	}
+	nodes, err := m.sysFs.GetNodesPaths()
+	if err != nil {
+		klog.V(4).Infof("Could not get NUMA nodes: %v", err)
+		return false
+	}
+	state := memoryHotplugState{onlineBlocks: onlineBlocks, nodes: len(nodes)}
+	changed := state != m.memoryState
+	m.memoryState = state
+	return changed
+}
+
+func (m *manager) addMachineChangedEvent(changedFields []string) {
+	newEvent := &info.Event{
+		ContainerName: "/",
+		Timestamp:     time.Now(),
+		EventType:     info.EventMachineChanged,
+		EventData: info.EventData{
+			MachineChanged: &info.MachineChangedEventData{
+				ChangedFields: changedFields,
+			},
+		},
+	}
+	err := m.eventHandler.AddEvent(newEvent)
+	if err != nil {
+		klog.Errorf("failed to add machine changed event: %v", err)
+	}
+}
+
+// checkThinPoolsMetadata adds a thinPoolMetadata event for the root container when metadata usage of
+// devicemapper thin pool reaches the configured fraction of metadata space. Thin pool fails once its
+// metadata space is exhausted, so the event is added well before. The event is not repeated until
+// metadata usage drops below the threshold.
+func (m *manager) checkThinPoolsMetadata(machineInfo *info.MachineInfo) {
+	if *thinPoolMetadataEventThreshold <= 0 {
+		return
+	}
+	m.thinPoolsMu.Lock()
+	defer m.thinPoolsMu.Unlock()
+	exceeded := make(map[string]bool, len(machineInfo.ThinPools))
+	for _, pool := range machineInfo.ThinPools {
+		if pool.MetadataCapacity == 0 || float64(pool.MetadataUsage) < *thinPoolMetadataEventThreshold*float64(pool.MetadataCapacity) {
+			continue
+		}
+		exceeded[pool.Name] = true
+		if m.thinPoolsMetadataExceeded[pool.Name] {
+			continue
+		}
+		newEvent := &info.Event{
+			ContainerName: "/",
+			Timestamp:     machineInfo.Timestamp,
+			EventType:     info.EventThinPoolMetadata,
+			EventData: info.EventData{
+				ThinPoolMetadata: &info.ThinPoolMetadataEventData{
+					Pool:     pool.Name,
+					Usage:    pool.MetadataUsage,
+					Capacity: pool.MetadataCapacity,
+				},
+			},
+		}
+		err := m.eventHandler.AddEvent(newEvent)
+		if err != nil {
+			klog.Errorf("failed to add thin pool metadata event for %q: %v", pool.Name, err)
+		}
+	}
+	m.thinPoolsMetadataExceeded = exceeded
+}
+
 func (m *manager) globalHousekeeping(quit chan error) {
 	// Long housekeeping is either 100ms or half of the housekeeping interval.
 	longHousekeeping := 100 * time.Millisecond
@@ -812,8 +1052,87 @@ func (m *manager) GetFsInfo(label string) ([]v2.FsInfo, error) {
 
 func (m *manager) GetMachineInfo() (*info.MachineInfo, error) {
 	m.machineMu.RLock()
-	defer m.machineMu.RUnlock()
-	return m.machineInfo.Clone(), nil
+	machineInfo := m.machineInfo.Clone()
+	m.machineMu.RUnlock()
+	if m.includedMetrics.Has(container.PerfMetrics) {
+		machineInfo.PerfUncoreStats = m.machinePerfUncoreStats()
+	}
+	return machineInfo, nil
+}
+
+func (m *manager) GetMachineCounters() (*info.MachineInfo, error) {
+	machineInfo, err := m.GetMachineInfo()
+	if err != nil {
+		return nil, err
+	}
+	// Cached machine info is updated only every update_machine_info_interval.
+	machine.UpdateCounters(m.sysFs, machineInfo)
+	return machineInfo, nil
+}
+
+// machinePerfUncoreStats returns uncore events of the latest stats of the root container
+// summed per socket and type of PMU, e.g. uncore_imc_0 and uncore_imc_1 are reported as uncore_imc.
+func (m *manager) machinePerfUncoreStats() []info.PerfUncoreStat {
+	stats, err := m.memoryCache.RecentStats("/", time.Time{}, time.Time{}, 1)
+	if err != nil || len(stats) == 0 {
+		return nil
+	}
+	return aggregatePerfUncoreStats(stats[0].PerfUncoreStats)
+}
+
+var pmuIndexRegexp = regexp.MustCompile(`_\d+$`)
+
+// aggregatePerfUncoreStats sums values of uncore events measured on the same socket by PMUs
+// of the same type, scaling ratio of the result is the lowest one of summed events.
+func aggregatePerfUncoreStats(stats []info.PerfUncoreStat) []info.PerfUncoreStat {
+	type key struct {
+		socket int
+		pmu    string
+		name   string
+	}
+	aggregated := map[key]*info.PerfUncoreStat{}
+	keys := []key{}
+	for _, stat := range stats {
+		k := key{socket: stat.Socket, pmu: pmuIndexRegexp.ReplaceAllString(stat.PMU, ""), name: stat.Name}
+		if sum, ok := aggregated[k]; ok {
+			sum.Value += stat.Value
+			if stat.ScalingRatio < sum.ScalingRatio {
+				sum.ScalingRatio = stat.ScalingRatio
+			}
+			continue
+		}
+		sum := stat
+		sum.PMU = k.pmu
+		aggregated[k] = &sum
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].socket != keys[j].socket {
+			return keys[i].socket < keys[j].socket
+		}
+		if keys[i].pmu != keys[j].pmu {
+			return keys[i].pmu < keys[j].pmu
+		}
+		return keys[i].name < keys[j].name
+	})
+	result := make([]info.PerfUncoreStat, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, *aggregated[k])
+	}
+	return result
+}
+
+func (m *manager) RefreshMachineInfo() (*info.MachineInfo, error) {
+	return m.refreshMachineInfo()
+}
+
+func (m *manager) GetPerfEventsConfig() []byte {
+	return m.perfManager.Config()
+}
+
+func (m *manager) ReloadPerfEvents(config []byte, dryRun bool) error {
+	return m.perfManager.Reload(config, dryRun)
 }
 
 func (m *manager) GetVersionInfo() (*info.VersionInfo, error) {
@@ -927,6 +1246,7 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 	if err != nil {
 		return err
 	}
+	cont.eventHandler = m.eventHandler
 
 	if cgroups.IsCgroup2UnifiedMode() {
 		perfCgroupPath := path.Join(fs2.UnifiedMountpoint, containerName)
@@ -934,6 +1254,14 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 		if err != nil {
 			klog.V(4).Infof("perf_event metrics will not be available for container %s: %s", containerName, err)
 		}
+		cont.cgroupNetworkCollector, err = m.cgroupNetworkManager.GetCollector(path.Join(fs2.UnifiedMountpoint, containerName))
+		if err != nil {
+			klog.V(4).Infof("cgroup network metrics will not be available for container %s: %s", containerName, err)
+		}
+		cont.bpfSchedCollector, err = m.bpfSchedManager.GetCollector(path.Join(fs2.UnifiedMountpoint, containerName))
+		if err != nil {
+			klog.V(4).Infof("run queue latency metrics will not be available for container %s: %s", containerName, err)
+		}
 	} else {
 		devicesCgroupPath, err := handler.GetCgroupPath("devices")
 		if err != nil {
@@ -943,6 +1271,10 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 			if err != nil {
 				klog.V(4).Infof("GPU metrics may be unavailable/incomplete for container %s: %s", cont.info.Name, err)
 			}
+			cont.amdCollector, err = m.amdManager.GetCollector(devicesCgroupPath)
+			if err != nil {
+				klog.V(4).Infof("AMD GPU metrics may be unavailable/incomplete for container %s: %s", cont.info.Name, err)
+			}
 		}
 		perfCgroupPath, err := handler.GetCgroupPath("perf_event")
 		if err != nil {
@@ -953,17 +1285,26 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 				klog.V(4).Infof("perf_event metrics will not be available for container %s: %s", containerName, err)
 			}
 		}
+		if m.includedMetrics.Has(container.BPFSchedMetrics) {
+			cpuCgroupPath, err := handler.GetCgroupPath("cpu")
+			if err != nil {
+				klog.Warningf("Error getting cpu cgroup path: %q", err)
+			} else {
+				cont.bpfSchedCollector, err = m.bpfSchedManager.GetCollector(cpuCgroupPath)
+				if err != nil {
+					klog.V(4).Infof("run queue latency metrics will not be available for container %s: %s", containerName, err)
+				}
+			}
+		}
 	}
 
 	if m.includedMetrics.Has(container.ResctrlMetrics) {
-		resctrlPath, err := intelrdt.GetIntelRdtPath(containerName)
+		getContainerPids := func() ([]int, error) {
+			return handler.ListProcesses(container.ListSelf)
+		}
+		cont.resctrlCollector, err = m.resctrlManager.GetCollector(containerName, getContainerPids)
 		if err != nil {
-			klog.V(4).Infof("Error getting resctrl path: %q", err)
-		} else {
-			cont.resctrlCollector, err = m.resctrlManager.GetCollector(resctrlPath)
-			if err != nil {
-				klog.V(4).Infof("resctrl metrics will not be available for container %s: %s", cont.info.Name, err)
-			}
+			klog.V(4).Infof("resctrl metrics will not be available for container %s: %s", cont.info.Name, err)
 		}
 	}
 
@@ -1237,6 +1578,10 @@ func (m *manager) watchForNewOoms() error {
 			if err != nil {
 				klog.Errorf("failed to add OOM kill event for %q: %v", oomInstance.ContainerName, err)
 			}
+
+			if cont, err := m.getContainer(oomInstance.VictimContainerName); err == nil {
+				atomic.AddUint64(&cont.oomKills, 1)
+			}
 		}
 	}()
 	return nil
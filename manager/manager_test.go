@@ -17,7 +17,9 @@
 package manager
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
@@ -28,12 +30,16 @@ import (
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/docker"
 	containertest "github.com/google/cadvisor/container/testing"
+	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
 	itest "github.com/google/cadvisor/info/v1/test"
 	"github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/machine"
+	"github.com/google/cadvisor/utils/sysfs"
 	"github.com/google/cadvisor/utils/sysfs/fakesysfs"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	clock "k8s.io/utils/clock/testing"
 
 	// install all the container runtimes included in the library version for testing.
@@ -533,3 +539,270 @@ func TestDockerContainersInfo(t *testing.T) {
 		t.Errorf("expected error %q but received %q", expectedError, err)
 	}
 }
+
+func TestOnlineCPUsChanged(t *testing.T) {
+	sysfs := &fakesysfs.FakeSysFs{}
+	sysfs.SetCPULists("0-3", "", nil)
+	m := &manager{
+		sysFs:       sysfs,
+		machineInfo: info.MachineInfo{OnlineCPUs: []int{0, 1, 2, 3}},
+	}
+	assert.False(t, m.onlineCPUsChanged())
+
+	sysfs.SetCPULists("0-1,3", "2", nil)
+	assert.True(t, m.onlineCPUsChanged())
+
+	sysfs.SetCPULists("0-5", "", nil)
+	assert.True(t, m.onlineCPUsChanged())
+
+	sysfs.SetCPULists("", "", fmt.Errorf("no such file"))
+	assert.False(t, m.onlineCPUsChanged())
+}
+
+// setMachineCounters sets all counters of machine in fake sysfs to value.
+func setMachineCounters(fakeSys *fakesysfs.FakeSysFs, value uint64) {
+	v := fmt.Sprint(value)
+	fakeSys.SetHugePagesCounters(map[string]string{
+		"/sys/kernel/mm/hugepages/hugepages-2048kB/free_hugepages":                 v,
+		"/sys/devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages": v,
+	})
+	fakeSys.SetMemory(fmt.Sprintf("Node 0 MemFree: %s kB", v), nil)
+	fakeSys.SetBlockDeviceStats(map[string]string{
+		"sda": fmt.Sprintf("%s 213 453920 3210 12345 6789 1048576 45678 2 30120 48888", v),
+	})
+	fakeSys.SetNetworkQueueStats(
+		map[string][]string{"eth0": {"rx-0"}},
+		map[string]map[string]uint64{"eth0": {"rx_queue_0_packets": value, "rx_queue_0_bytes": value, "rx_missed_errors": value}},
+	)
+	fakeSys.SetInfinibandPorts([]sysfs.InfinibandPort{{Device: "mlx5_0", Port: 1, State: "ACTIVE", Counters: map[string]uint64{"port_rcv_packets": value}}})
+	fakeSys.SetCPUFrequencyScaling(
+		map[string]string{"/sys/devices/system/cpu/cpu0": "2400000"},
+		nil,
+		map[string]string{"/sys/devices/system/cpu/cpu0": fmt.Sprintf("2400000 %s", v)},
+	)
+	fakeSys.SetPowercapZones(
+		[]string{"/sys/class/powercap/intel-rapl:0"},
+		map[string]string{"/sys/class/powercap/intel-rapl:0": "package-0"},
+		map[string]string{"/sys/class/powercap/intel-rapl:0": v},
+	)
+	fakeSys.SetHwmonSensors([]sysfs.HwmonSensor{{Device: "coretemp", Label: "Package id 0", Temperature: int64(value) * 1000}})
+	fakeSys.SetNVMeControllers([]sysfs.NVMeController{{Name: "nvme0", Sensors: []sysfs.HwmonSensor{{Label: "Composite", Temperature: int64(value) * 1000}}}})
+}
+
+func TestGetMachineCounters(t *testing.T) {
+	require.NoError(t, flag.Set("ethtool_stats", "true"))
+	defer flag.Set("ethtool_stats", "false")
+
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetNodesPaths([]string{"/sys/devices/system/node/node0"}, nil)
+	fakeSys.SetCPUsPaths(map[string][]string{"/sys/devices/system/cpu": {"/sys/devices/system/cpu/cpu0"}}, nil)
+	fakeSys.SetHugePages([]os.FileInfo{&fakesysfs.FileInfo{EntryName: "hugepages-2048kB"}}, nil)
+	fakeSys.SetHugePagesNr(map[string]string{
+		"/sys/kernel/mm/hugepages/hugepages-2048kB/nr_hugepages":                 "512",
+		"/sys/devices/system/node/node0/hugepages/hugepages-2048kB/nr_hugepages": "512",
+	}, nil)
+	fakeSys.SetNetworkDevices([]string{"eth0"})
+	setMachineCounters(fakeSys, 1)
+	// Only counters reported in cached machine info are read.
+	m := &manager{
+		sysFs: fakeSys,
+		machineInfo: info.MachineInfo{
+			HugePages:         []info.HugePagesInfo{{PageSize: 2048}},
+			Topology:          []info.Node{{Id: 0}},
+			BlockDeviceStats:  []info.BlockDeviceStats{{Name: "sda"}},
+			NetworkDevices:    []info.NetInfo{{Name: "eth0"}},
+			NetworkQueueStats: []info.NetworkQueueStats{{Device: "eth0"}},
+			InfinibandPorts:   []info.InfinibandPort{{Device: "mlx5_0"}},
+			CPUFrequencies:    []info.CPUFrequency{{CPU: 0}},
+			PowerZones:        []info.PowerZone{{Id: "intel-rapl:0"}},
+			ThermalSensors:    []info.ThermalSensor{{Device: "coretemp"}},
+			NVMeDevices:       []info.NVMeDevice{{Name: "nvme0"}},
+		},
+	}
+	machine.UpdateCounters(fakeSys, &m.machineInfo)
+	cachedMachineInfo := m.machineInfo.Clone()
+
+	setMachineCounters(fakeSys, 2)
+	// Machine info is served from cache.
+	machineInfo, err := m.GetMachineInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, cachedMachineInfo, machineInfo)
+
+	machineInfo, err = m.GetMachineCounters()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), machineInfo.HugePages[0].FreePages)
+	assert.Equal(t, uint64(2), machineInfo.Topology[0].HugePages[0].FreePages)
+	assert.Equal(t, uint64(2*1024), machineInfo.Topology[0].MemoryFree)
+	assert.Equal(t, uint64(2), machineInfo.BlockDeviceStats[0].ReadsCompleted)
+	assert.Equal(t, uint64(2), machineInfo.NetworkDevices[0].EthtoolStats["rx_missed_errors"])
+	assert.Equal(t, uint64(2), machineInfo.NetworkQueueStats[0].Packets)
+	assert.Equal(t, uint64(2), machineInfo.InfinibandPorts[0].ReceivedPackets)
+	assert.Equal(t, uint64(2*10), machineInfo.CPUFrequencies[0].TimeInState[2400000])
+	assert.Equal(t, uint64(2), machineInfo.PowerZones[0].EnergyUJ)
+	assert.Equal(t, float64(2), machineInfo.ThermalSensors[0].Temperature)
+	assert.Equal(t, float64(2), *machineInfo.NVMeDevices[0].Temperature)
+	// Cached machine info is not modified.
+	assert.Equal(t, cachedMachineInfo, &m.machineInfo)
+	assert.Equal(t, uint64(1), m.machineInfo.Topology[0].HugePages[0].FreePages)
+	assert.Equal(t, uint64(1), m.machineInfo.NetworkDevices[0].EthtoolStats["rx_missed_errors"])
+
+	// Cached values are kept when counters cannot be read.
+	fakeSys.SetBlockDeviceStats(nil)
+	machineInfo, err = m.GetMachineCounters()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), machineInfo.BlockDeviceStats[0].ReadsCompleted)
+}
+
+func TestMemoryChanged(t *testing.T) {
+	sysfs := &fakesysfs.FakeSysFs{}
+	sysfs.SetNodesPaths([]string{"/sys/devices/system/node/node0"}, nil)
+	sysfs.SetMemoryBlocks(
+		[]string{"/sys/devices/system/memory/memory0", "/sys/devices/system/memory/memory1"},
+		map[string]string{
+			"/sys/devices/system/memory/memory0": "online",
+			"/sys/devices/system/memory/memory1": "offline",
+		})
+	m := &manager{
+		sysFs:        sysfs,
+		eventHandler: events.NewEventManager(events.DefaultStoragePolicy()),
+	}
+	// Initial state is recorded.
+	assert.True(t, m.memoryChanged())
+	assert.False(t, m.memoryChanged())
+
+	// Memory block is hot-added.
+	sysfs.SetMemoryBlocks(
+		[]string{"/sys/devices/system/memory/memory0", "/sys/devices/system/memory/memory1"},
+		map[string]string{
+			"/sys/devices/system/memory/memory0": "online",
+			"/sys/devices/system/memory/memory1": "online",
+		})
+	assert.True(t, m.memoryChanged())
+
+	// NUMA node is hot-added.
+	sysfs.SetNodesPaths([]string{"/sys/devices/system/node/node0", "/sys/devices/system/node/node1"}, nil)
+	assert.True(t, m.memoryChanged())
+
+	m.addMachineChangedEvent([]string{"memory_capacity", "topology"})
+	request := events.NewRequest()
+	request.EventType[info.EventMachineChanged] = true
+	request.ContainerName = "/"
+	machineEvents, err := m.GetPastEvents(request)
+	assert.Nil(t, err)
+	assert.Len(t, machineEvents, 1)
+	assert.Equal(t, []string{"memory_capacity", "topology"}, machineEvents[0].EventData.MachineChanged.ChangedFields)
+}
+
+func TestMachineInfoChanges(t *testing.T) {
+	temperature := 40.0
+	newMachineInfo := func() *info.MachineInfo {
+		return &info.MachineInfo{
+			Timestamp:      time.Unix(1000, 0),
+			NumCores:       4,
+			MemoryCapacity: 1024,
+			HugePages:      []info.HugePagesInfo{{PageSize: 2048, NumPages: 512, FreePages: 512, ReservedPages: 2}},
+			Topology: []info.Node{
+				{Id: 0, Memory: 1024, MemoryFree: 512, HugePages: []info.HugePagesInfo{{PageSize: 2048, NumPages: 512, FreePages: 512}}},
+			},
+			NetworkDevices: []info.NetInfo{
+				{Name: "eth0", Mtu: 1500, EthtoolStats: map[string]uint64{"rx_missed_errors": 1}},
+			},
+			ThermalSensors:    []info.ThermalSensor{{Device: "coretemp", Label: "Package id 0", Temperature: 40}},
+			CPUFrequencies:    []info.CPUFrequency{{CPU: 0, CurrentFrequency: 2400000}},
+			PowerZones:        []info.PowerZone{{Id: "intel-rapl:0", Domain: "package-0", EnergyUJ: 1000}},
+			NVMeDevices:       []info.NVMeDevice{{Name: "nvme0", Model: "SSD", Temperature: &temperature}},
+			BlockDeviceStats:  []info.BlockDeviceStats{{Name: "sda", ReadsCompleted: 10}},
+			InfinibandPorts:   []info.InfinibandPort{{Device: "mlx5_0", Port: 1, ReceivedPackets: 10}},
+			NetworkQueueStats: []info.NetworkQueueStats{{Device: "eth0", Queue: "rx-0", Packets: 10}},
+			ThinPools:         []info.ThinPoolInfo{{Name: "docker-thinpool", DataUsage: 10}},
+		}
+	}
+	previous := newMachineInfo()
+
+	// Counters and volatile fields are ignored.
+	current := newMachineInfo()
+	current.Timestamp = time.Unix(2000, 0)
+	current.Topology[0].MemoryFree = 256
+	current.HugePages[0].FreePages = 256
+	current.HugePages[0].SurplusPages = 1
+	current.HugePages[0].ReservedPages = 4
+	current.Topology[0].HugePages[0].FreePages = 256
+	current.Topology[0].HugePages[0].SurplusPages = 1
+	current.NetworkDevices[0].EthtoolStats["rx_missed_errors"] = 2
+	current.ThermalSensors[0].Temperature = 45
+	current.CPUFrequencies[0].CurrentFrequency = 800000
+	current.PowerZones[0].EnergyUJ = 2000
+	currentTemperature := 45.0
+	current.NVMeDevices[0].Temperature = &currentTemperature
+	current.BlockDeviceStats[0].ReadsCompleted = 20
+	current.InfinibandPorts[0].ReceivedPackets = 20
+	current.NetworkQueueStats[0].Packets = 20
+	current.ThinPools[0].DataUsage = 20
+	assert.Empty(t, machineInfoChanges(previous, current))
+
+	current.NumCores = 8
+	current.Topology[0].Memory = 2048
+	current.NetworkDevices[0].Mtu = 9000
+	assert.Equal(t, []string{"num_cores", "network_devices", "topology"}, machineInfoChanges(previous, current))
+	// Counters of compared machine info are not modified.
+	assert.Equal(t, uint64(256), current.Topology[0].HugePages[0].FreePages)
+
+	current = newMachineInfo()
+	current.HugePages[0].NumPages = 1024
+	current.NVMeDevices[0].Model = "NVMe SSD"
+	assert.Equal(t, []string{"hugepages", "nvme_devices"}, machineInfoChanges(previous, current))
+}
+
+func TestAggregatePerfUncoreStats(t *testing.T) {
+	stats := []info.PerfUncoreStat{
+		{PerfValue: info.PerfValue{Name: "cas_count_read", Value: 100, ScalingRatio: 1.0}, Socket: 1, PMU: "uncore_imc_0"},
+		{PerfValue: info.PerfValue{Name: "cas_count_read", Value: 200, ScalingRatio: 1.0}, Socket: 0, PMU: "uncore_imc_0"},
+		{PerfValue: info.PerfValue{Name: "cas_count_read", Value: 300, ScalingRatio: 0.5}, Socket: 0, PMU: "uncore_imc_1"},
+		{PerfValue: info.PerfValue{Name: "txl_flits", Value: 40, ScalingRatio: 1.0}, Socket: 0, PMU: "uncore_upi_0"},
+	}
+
+	assert.Equal(t, []info.PerfUncoreStat{
+		{PerfValue: info.PerfValue{Name: "cas_count_read", Value: 500, ScalingRatio: 0.5}, Socket: 0, PMU: "uncore_imc"},
+		{PerfValue: info.PerfValue{Name: "txl_flits", Value: 40, ScalingRatio: 1.0}, Socket: 0, PMU: "uncore_upi"},
+		{PerfValue: info.PerfValue{Name: "cas_count_read", Value: 100, ScalingRatio: 1.0}, Socket: 1, PMU: "uncore_imc"},
+	}, aggregatePerfUncoreStats(stats))
+	assert.Empty(t, aggregatePerfUncoreStats(nil))
+}
+
+func TestCheckThinPoolsMetadata(t *testing.T) {
+	m := &manager{
+		eventHandler: events.NewEventManager(events.DefaultStoragePolicy()),
+	}
+	check := func(metadataUsage uint64) {
+		m.checkThinPoolsMetadata(&info.MachineInfo{
+			Timestamp: time.Now(),
+			ThinPools: []info.ThinPoolInfo{
+				{Name: "docker-thinpool", MetadataUsage: metadataUsage, MetadataCapacity: 1000},
+				{Name: "empty-thinpool", MetadataUsage: 1, MetadataCapacity: 1000},
+			},
+		})
+	}
+	thinPoolEvents := func() []*info.Event {
+		request := events.NewRequest()
+		request.EventType[info.EventThinPoolMetadata] = true
+		request.ContainerName = "/"
+		evs, err := m.GetPastEvents(request)
+		require.Nil(t, err)
+		return evs
+	}
+
+	check(500)
+	assert.Empty(t, thinPoolEvents())
+
+	// Event is added once while metadata usage stays above the threshold.
+	check(800)
+	check(900)
+	evs := thinPoolEvents()
+	require.Len(t, evs, 1)
+	assert.Equal(t, &info.ThinPoolMetadataEventData{Pool: "docker-thinpool", Usage: 800, Capacity: 1000}, evs[0].EventData.ThinPoolMetadata)
+
+	// Event is added again after metadata usage dropped below the threshold.
+	check(100)
+	check(990)
+	assert.Len(t, thinPoolEvents(), 2)
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// countedByCadvisor are container metric families counted by cAdvisor itself,
+// e.g. by eBPF programs, perf events or resctrl monitoring groups, or summed
+// over processes it finds, which start from zero when cAdvisor restarts.
+var countedByCadvisor = map[string]bool{
+	"container_gpu_engine_busy_seconds_total":         true,
+	"container_memory_bandwidth_bytes_total":          true,
+	"container_memory_bandwidth_local_bytes_total":    true,
+	"container_network_cgroup_receive_bytes_total":    true,
+	"container_network_cgroup_receive_packets_total":  true,
+	"container_network_cgroup_transmit_bytes_total":   true,
+	"container_network_cgroup_transmit_packets_total": true,
+	"container_perf_events_total":                     true,
+	"container_perf_uncore_events_total":              true,
+	"container_processes_cpu_seconds_total":           true,
+	"container_schedstat_runqueue_latency_seconds":    true,
+	"container_syscalls_total":                        true,
+}
+
+// startTimeFamilies are gauges of start times of containers and the machine,
+// whose cumulative metrics are exported in families with the prefix.
+var startTimeFamilies = []struct {
+	prefix string
+	name   string
+}{
+	{prefix: "container_", name: "container_start_time_seconds"},
+	{prefix: "machine_", name: "machine_boot_time_seconds"},
+}
+
+// CreatedTimestamps returns times since which counters, histograms and
+// summaries of the families are counted, they change when counting restarts
+// from zero. Metrics of containers and the machine are counted since the
+// container started or the machine booted, which are looked up by labels in
+// their start time metrics, and have no created timestamp if it isn't
+// exported. Other metrics are counted since cAdvisor started.
+func CreatedTimestamps(families []*dto.MetricFamily, started time.Time) map[*dto.Metric]time.Time {
+	startTimes := make(map[string]*startTimeIndex, len(startTimeFamilies))
+	for _, family := range families {
+		for _, st := range startTimeFamilies {
+			if family.GetName() == st.name {
+				startTimes[st.prefix] = newStartTimeIndex(family)
+			}
+		}
+	}
+
+	created := map[*dto.Metric]time.Time{}
+	for _, family := range families {
+		switch family.GetType() {
+		case dto.MetricType_COUNTER, dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY:
+		default:
+			continue
+		}
+		var index *startTimeIndex
+		prefixed := false
+		for _, st := range startTimeFamilies {
+			if strings.HasPrefix(family.GetName(), st.prefix) {
+				index = startTimes[st.prefix]
+				prefixed = true
+				break
+			}
+		}
+		for _, metric := range family.GetMetric() {
+			if !prefixed {
+				created[metric] = started
+				continue
+			}
+			start, ok := index.startTime(metric)
+			if !ok {
+				continue
+			}
+			if countedByCadvisor[family.GetName()] && start.Before(started) {
+				start = started
+			}
+			created[metric] = start
+		}
+	}
+	return created
+}
+
+// startTimeIndex maps values of labels of a start time metric to the time.
+type startTimeIndex struct {
+	labels []string
+	times  map[string]time.Time
+}
+
+func newStartTimeIndex(family *dto.MetricFamily) *startTimeIndex {
+	index := &startTimeIndex{times: make(map[string]time.Time, len(family.GetMetric()))}
+	for i, metric := range family.GetMetric() {
+		if i == 0 {
+			// Metrics of a family have the same labels.
+			for _, pair := range metric.GetLabel() {
+				index.labels = append(index.labels, pair.GetName())
+			}
+		}
+		key, _ := labelValuesKey(metric, index.labels)
+		seconds, fraction := math.Modf(metric.GetGauge().GetValue())
+		index.times[key] = time.Unix(int64(seconds), int64(fraction*float64(time.Second)))
+	}
+	return index
+}
+
+// startTime returns the start time with the same values of labels of the
+// start time metrics as the metric.
+func (i *startTimeIndex) startTime(metric *dto.Metric) (time.Time, bool) {
+	if i == nil {
+		return time.Time{}, false
+	}
+	key, ok := labelValuesKey(metric, i.labels)
+	if !ok {
+		return time.Time{}, false
+	}
+	start, ok := i.times[key]
+	return start, ok
+}
+
+// labelValuesKey returns values of the labels of the metric joined in a key,
+// false if the metric lacks any of them. Labels of metrics are sorted by name.
+func labelValuesKey(metric *dto.Metric, labels []string) (string, bool) {
+	values := make([]string, 0, len(labels))
+	pairs := metric.GetLabel()
+	for _, label := range labels {
+		for len(pairs) > 0 && pairs[0].GetName() < label {
+			pairs = pairs[1:]
+		}
+		if len(pairs) == 0 || pairs[0].GetName() != label {
+			return "", false
+		}
+		values = append(values, pairs[0].GetValue())
+	}
+	return strings.Join(values, "\xff"), true
+}
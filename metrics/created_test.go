@@ -0,0 +1,70 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatedTimestamps(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewPrometheusCollector(testSubcontainersInfoProvider{}, DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{}))
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "cadvisor_test_total", Help: "Test counter."}))
+	families, err := reg.Gather()
+	require.Nil(t, err)
+	byName := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+
+	containerStart := time.Unix(1257894000, 0)
+	started := time.Unix(1300000000, 0)
+	created := CreatedTimestamps(families, started)
+	for _, metric := range byName["container_cpu_usage_seconds_total"].GetMetric() {
+		assert.Equal(t, containerStart, created[metric])
+	}
+	// Counted by cAdvisor, since it started after the container.
+	for _, metric := range byName["container_perf_events_total"].GetMetric() {
+		assert.Equal(t, started, created[metric])
+	}
+	for _, metric := range byName["container_schedstat_runqueue_latency_seconds"].GetMetric() {
+		assert.Equal(t, started, created[metric])
+	}
+	assert.Equal(t, started, created[byName["cadvisor_test_total"].GetMetric()[0]])
+	for _, metric := range byName["container_memory_usage_bytes"].GetMetric() {
+		assert.NotContains(t, created, metric)
+	}
+
+	// Metrics of containers have no created timestamps without start times.
+	delete(byName, "container_start_time_seconds")
+	families = families[:0]
+	for _, family := range byName {
+		families = append(families, family)
+	}
+	created = CreatedTimestamps(families, started)
+	for _, metric := range byName["container_cpu_usage_seconds_total"].GetMetric() {
+		assert.NotContains(t, created, metric)
+	}
+	assert.Contains(t, created, byName["cadvisor_test_total"].GetMetric()[0])
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// nativeZeroThreshold is the width of the zero bucket of native histograms,
+// the default of Prometheus client libraries.
+var nativeZeroThreshold = math.Ldexp(1, -128)
+
+// maxExemplarRunes is the maximal length of names and values of exemplar
+// labels allowed by OpenMetrics.
+const maxExemplarRunes = 128
+
+// nativeBucketIndex returns the index of the bucket of a native histogram
+// with schema 0 the value falls into, bucket i holds values in (2^(i-1), 2^i].
+func nativeBucketIndex(value float64) int {
+	frac, exp := math.Frexp(value)
+	if frac == 0.5 {
+		// Powers of two are upper bounds of buckets.
+		return exp - 1
+	}
+	return exp
+}
+
+// nativeHistogram adds buckets of a native histogram with schema 0 to a
+// histogram metric, they are exposed only in protobuf format. Buckets are
+// counts by their index.
+type nativeHistogram struct {
+	prometheus.Metric
+	buckets map[int]uint64
+}
+
+func (h *nativeHistogram) Write(m *dto.Metric) error {
+	if err := h.Metric.Write(m); err != nil {
+		return err
+	}
+	indexes := make([]int, 0, len(h.buckets))
+	for index, count := range h.buckets {
+		if count > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+
+	schema := int32(0)
+	zeroThreshold := nativeZeroThreshold
+	zeroCount := uint64(0)
+	m.Histogram.Schema = &schema
+	m.Histogram.ZeroThreshold = &zeroThreshold
+	m.Histogram.ZeroCount = &zeroCount
+	var span *dto.BucketSpan
+	var previousIndex int
+	var previousCount int64
+	for _, index := range indexes {
+		if span == nil || index != previousIndex+1 {
+			// Offset of the first span is the index of its first bucket, offsets
+			// of following spans are numbers of empty buckets before them.
+			offset := int32(index)
+			if span != nil {
+				offset = int32(index - previousIndex - 1)
+			}
+			length := uint32(0)
+			span = &dto.BucketSpan{Offset: &offset, Length: &length}
+			m.Histogram.PositiveSpan = append(m.Histogram.PositiveSpan, span)
+		}
+		*span.Length++
+		// Counts are encoded as differences from the previous bucket.
+		count := int64(h.buckets[index])
+		m.Histogram.PositiveDelta = append(m.Histogram.PositiveDelta, count-previousCount)
+		previousIndex = index
+		previousCount = count
+	}
+	return nil
+}
+
+// exemplarHistogram adds an exemplar with the labels to the highest bucket of
+// a histogram metric which has observations. Its value is the upper bound of
+// the bucket, the distribution of observations within buckets is unknown.
+type exemplarHistogram struct {
+	prometheus.Metric
+	labels []*dto.LabelPair
+}
+
+// newExemplarLabels returns labels of exemplars, nil if they are too long
+// for OpenMetrics.
+func newExemplarLabels(name, value string) []*dto.LabelPair {
+	if utf8.RuneCountInString(name)+utf8.RuneCountInString(value) > maxExemplarRunes {
+		return nil
+	}
+	return []*dto.LabelPair{{Name: &name, Value: &value}}
+}
+
+func (h *exemplarHistogram) Write(m *dto.Metric) error {
+	if err := h.Metric.Write(m); err != nil {
+		return err
+	}
+	var previous uint64
+	var highest *dto.Bucket
+	for _, bucket := range m.Histogram.Bucket {
+		if bucket.GetCumulativeCount() > previous {
+			highest = bucket
+		}
+		previous = bucket.GetCumulativeCount()
+	}
+	if highest != nil {
+		value := highest.GetUpperBound()
+		highest.Exemplar = &dto.Exemplar{Label: h.labels, Value: &value}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/cadvisor/container"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatherRunqueueLatency(t *testing.T, c *PrometheusCollector) *dto.Histogram {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	require.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() == "container_schedstat_runqueue_latency_seconds" {
+			require.Len(t, family.GetMetric(), 1)
+			return family.GetMetric()[0].GetHistogram()
+		}
+	}
+	t.Fatal("container_schedstat_runqueue_latency_seconds not collected")
+	return nil
+}
+
+func TestNativeBucketIndex(t *testing.T) {
+	assert.Equal(t, 0, nativeBucketIndex(1))
+	assert.Equal(t, 1, nativeBucketIndex(1.5))
+	assert.Equal(t, 1, nativeBucketIndex(2))
+	assert.Equal(t, -1, nativeBucketIndex(0.3))
+	assert.Equal(t, -18, nativeBucketIndex(2e-6))
+}
+
+func TestNativeHistograms(t *testing.T) {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{})
+	h := gatherRunqueueLatency(t, c)
+	assert.Nil(t, h.Schema)
+	assert.Empty(t, h.GetPositiveSpan())
+
+	c.SetNativeHistograms(true)
+	h = gatherRunqueueLatency(t, c)
+	assert.Equal(t, uint64(20), h.GetSampleCount())
+	assert.Len(t, h.GetBucket(), 4)
+	assert.Equal(t, int32(0), h.GetSchema())
+	assert.Equal(t, nativeZeroThreshold, h.GetZeroThreshold())
+	// Buckets of 2, 4, 8 and 16 microseconds, the third is empty.
+	spans := [][2]int64{}
+	for _, span := range h.GetPositiveSpan() {
+		spans = append(spans, [2]int64{int64(span.GetOffset()), int64(span.GetLength())})
+	}
+	assert.Equal(t, [][2]int64{{-18, 2}, {1, 1}}, spans)
+	assert.Equal(t, []int64{10, -5, -2}, h.GetPositiveDelta())
+}
+
+func TestExemplars(t *testing.T) {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{})
+	c.SetExemplarLabel("foo.label")
+	h := gatherRunqueueLatency(t, c)
+	for _, bucket := range h.GetBucket() {
+		if bucket.GetUpperBound() != 1.6e-05 {
+			assert.Nil(t, bucket.GetExemplar())
+			continue
+		}
+		require.NotNil(t, bucket.GetExemplar())
+		assert.Equal(t, 1.6e-05, bucket.GetExemplar().GetValue())
+		assert.Equal(t, map[string]string{"foo_label": "bar"}, labelPairs(&dto.Metric{Label: bucket.GetExemplar().GetLabel()}))
+	}
+
+	// Containers without the label have no exemplars.
+	c.SetExemplarLabel("trace_id")
+	for _, bucket := range gatherRunqueueLatency(t, c).GetBucket() {
+		assert.Nil(t, bucket.GetExemplar())
+	}
+
+	assert.Nil(t, newExemplarLabels("trace_id", strings.Repeat("a", maxExemplarRunes)))
+}
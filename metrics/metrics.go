@@ -27,6 +27,19 @@ type metricValue struct {
 	value     float64
 	labels    []string
 	timestamp time.Time
+	// histogram is set for metrics of histogram type, value is not used then.
+	histogram *histogramValue
+}
+
+// histogramValue describes a histogram with cumulative counts of observations
+// by upper bounds of buckets.
+type histogramValue struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+	// native are counts of buckets of a native histogram by their index, nil
+	// if the histogram has no native representation.
+	native map[int]uint64
 }
 
 type metricValues []metricValue
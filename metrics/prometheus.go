@@ -16,8 +16,13 @@ package metrics
 
 import (
 	"fmt"
+	"os"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/cadvisor/container"
@@ -25,10 +30,14 @@ import (
 	v2 "github.com/google/cadvisor/info/v2"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
 
+// pageSize is used to convert memory reported in pages to bytes.
+var pageSize = os.Getpagesize()
+
 // asFloat64 converts a uint64 into a float64.
 func asFloat64(v uint64) float64 { return float64(v) }
 
@@ -72,6 +81,24 @@ func ioValues(ioStats []info.PerDiskStats, ioType string, ioValueFn func(uint64)
 	return values
 }
 
+// ioValuesIfPresent returns values of operation type by device, devices for which the operation type
+// is not reported are skipped, e.g. wait time is split into reads and writes only on cgroup v1.
+func ioValuesIfPresent(ioStats []info.PerDiskStats, ioType string, ioValueFn func(uint64) float64, timestamp time.Time) metricValues {
+	values := make(metricValues, 0, len(ioStats))
+	for _, stat := range ioStats {
+		value, ok := stat.Stats[ioType]
+		if !ok {
+			continue
+		}
+		values = append(values, metricValue{
+			value:     ioValueFn(value),
+			labels:    []string{stat.Device},
+			timestamp: timestamp,
+		})
+	}
+	return values
+}
+
 // containerMetric describes a multi-dimensional metric used for exposing a
 // certain type of container statistic.
 type containerMetric struct {
@@ -99,6 +126,11 @@ type PrometheusCollector struct {
 	containerLabelsFunc ContainerLabelsFunc
 	includedMetrics     container.MetricSet
 	opts                v2.RequestOptions
+	relabeler           *Relabeler
+	// workers is the number of goroutines collecting metrics of containers, GOMAXPROCS if zero.
+	workers          int
+	nativeHistograms bool
+	exemplarLabel    string
 }
 
 // NewPrometheusCollector returns a new PrometheusCollector. The passed
@@ -222,6 +254,30 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 							timestamp: s.Timestamp,
 						}}
 				},
+			}, {
+				name:      "container_cpu_cfs_burst_periods_total",
+				help:      "Number of periods in which the container used accumulated burst, only on cgroup v2.",
+				valueType: prometheus.CounterValue,
+				condition: func(s info.ContainerSpec) bool { return s.Cpu.Quota != 0 },
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Cpu.CFS.BurstPeriods),
+							timestamp: s.Timestamp,
+						}}
+				},
+			}, {
+				name:      "container_cpu_cfs_burst_seconds_total",
+				help:      "Total time duration the container has run above quota using burst, only on cgroup v2.",
+				valueType: prometheus.CounterValue,
+				condition: func(s info.ContainerSpec) bool { return s.Cpu.Quota != 0 },
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Cpu.CFS.BurstTime) / float64(time.Second),
+							timestamp: s.Timestamp,
+						}}
+				},
 			},
 		}...)
 	}
@@ -260,6 +316,36 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 			},
 		}...)
 	}
+	if includedMetrics.Has(container.BPFSchedMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name: "container_schedstat_runqueue_latency_seconds",
+				help: "Distribution of time threads of the container waited in run queue before being scheduled, measured by eBPF programs.",
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Cpu.RunqueueLatency == nil {
+						return metricValues{}
+					}
+					return metricValues{{histogram: latencyHistogramValue(s.Cpu.RunqueueLatency), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:        "container_syscalls_total",
+				help:        "Cumulative count of selected system calls entered by threads of the container, measured by eBPF programs.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"syscall"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Syscalls))
+					for name, count := range s.Syscalls {
+						values = append(values, metricValue{
+							value:     float64(count),
+							labels:    []string{name},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			},
+		}...)
+	}
 	if includedMetrics.Has(container.CpuLoadMetrics) {
 		c.containerMetrics = append(c.containerMetrics, []containerMetric{
 			{
@@ -452,6 +538,58 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 						},
 					}
 				},
+			}, {
+				name:        "container_memory_workingset_refaults_total",
+				help:        "Cumulative count of refaults of previously evicted pages, only on cgroup v2.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Memory.Reclaim.WorkingsetRefaultAnon),
+							labels:    []string{"anon"},
+							timestamp: s.Timestamp,
+						},
+						{
+							value:     float64(s.Memory.Reclaim.WorkingsetRefaultFile),
+							labels:    []string{"file"},
+							timestamp: s.Timestamp,
+						},
+					}
+				},
+			}, {
+				name:        "container_memory_workingset_activations_total",
+				help:        "Cumulative count of refaulted pages that were immediately activated, only on cgroup v2.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Memory.Reclaim.WorkingsetActivateAnon),
+							labels:    []string{"anon"},
+							timestamp: s.Timestamp,
+						},
+						{
+							value:     float64(s.Memory.Reclaim.WorkingsetActivateFile),
+							labels:    []string{"file"},
+							timestamp: s.Timestamp,
+						},
+					}
+				},
+			}, {
+				name:      "container_memory_pages_scanned_total",
+				help:      "Cumulative count of pages scanned by page reclaim, only on cgroup v2.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Reclaim.Pgscan), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_memory_pages_reclaimed_total",
+				help:      "Cumulative count of pages reclaimed, only on cgroup v2.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Memory.Reclaim.Pgsteal), timestamp: s.Timestamp}}
+				},
 			},
 		}...)
 	}
@@ -463,21 +601,16 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 				valueType:   prometheus.GaugeValue,
 				extraLabels: []string{"type", "scope", "node"},
 				getValues: func(s *info.ContainerStats) metricValues {
-					values := make(metricValues, 0)
-					values = append(values, getNumaStatsPerNode(s.Memory.ContainerData.NumaStats.File,
-						[]string{"file", "container"}, s.Timestamp)...)
-					values = append(values, getNumaStatsPerNode(s.Memory.ContainerData.NumaStats.Anon,
-						[]string{"anon", "container"}, s.Timestamp)...)
-					values = append(values, getNumaStatsPerNode(s.Memory.ContainerData.NumaStats.Unevictable,
-						[]string{"unevictable", "container"}, s.Timestamp)...)
-
-					values = append(values, getNumaStatsPerNode(s.Memory.HierarchicalData.NumaStats.File,
-						[]string{"file", "hierarchy"}, s.Timestamp)...)
-					values = append(values, getNumaStatsPerNode(s.Memory.HierarchicalData.NumaStats.Anon,
-						[]string{"anon", "hierarchy"}, s.Timestamp)...)
-					values = append(values, getNumaStatsPerNode(s.Memory.HierarchicalData.NumaStats.Unevictable,
-						[]string{"unevictable", "hierarchy"}, s.Timestamp)...)
-					return values
+					return getMemoryNumaStats(s, 1)
+				},
+			},
+			{
+				name:        "container_memory_numa_bytes",
+				help:        "Memory used per NUMA node in bytes",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"type", "scope", "node"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getMemoryNumaStats(s, float64(pageSize))
 				},
 			},
 		}...)
@@ -532,6 +665,102 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					}
 					return values
 				},
+			}, {
+				name:        "container_accelerator_power_watts",
+				help:        "Average power usage of the accelerator over the past sample period.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"make", "model", "acc_id"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Accelerators))
+					for _, value := range s.Accelerators {
+						// Power usage is not reported by all accelerators.
+						if value.PowerUsage == 0 {
+							continue
+						}
+						values = append(values, metricValue{
+							value:     float64(value.PowerUsage) / 1000,
+							labels:    []string{value.Make, value.Model, value.ID},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.GPUEngineMetrics) {
+		c.containerMetrics = append(c.containerMetrics, containerMetric{
+			name:        "container_gpu_engine_busy_seconds_total",
+			help:        "Cumulative time GPU engines were busy with work submitted by the container in seconds.",
+			valueType:   prometheus.CounterValue,
+			extraLabels: []string{"engine"},
+			getValues: func(s *info.ContainerStats) metricValues {
+				values := make(metricValues, 0, len(s.GpuEngineBusyTime))
+				for engine, busyTime := range s.GpuEngineBusyTime {
+					values = append(values, metricValue{
+						value:     asNanosecondsToSeconds(busyTime),
+						labels:    []string{engine},
+						timestamp: s.Timestamp,
+					})
+				}
+				return values
+			},
+		})
+	}
+	if includedMetrics.Has(container.OOMMetrics) {
+		c.containerMetrics = append(c.containerMetrics, containerMetric{
+			name:      "container_oom_kills_total",
+			help:      "Cumulative count of processes killed by OOM killer in the container.",
+			valueType: prometheus.CounterValue,
+			getValues: func(s *info.ContainerStats) metricValues {
+				return metricValues{{value: float64(s.Memory.OOMKills), timestamp: s.Timestamp}}
+			},
+		})
+	}
+	if includedMetrics.Has(container.PressureMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_pressure_cpu_stalled_seconds_total",
+				help:      "Total time duration no tasks in the container could make progress due to CPU congestion.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: asNanosecondsToSeconds(s.Cpu.PSI.Full.Total), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_pressure_cpu_waiting_seconds_total",
+				help:      "Total time duration tasks in the container have waited due to CPU congestion.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: asNanosecondsToSeconds(s.Cpu.PSI.Some.Total), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_pressure_memory_stalled_seconds_total",
+				help:      "Total time duration no tasks in the container could make progress due to memory congestion.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: asNanosecondsToSeconds(s.Memory.PSI.Full.Total), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_pressure_memory_waiting_seconds_total",
+				help:      "Total time duration tasks in the container have waited due to memory congestion.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: asNanosecondsToSeconds(s.Memory.PSI.Some.Total), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_pressure_io_stalled_seconds_total",
+				help:      "Total time duration no tasks in the container could make progress due to IO congestion.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: asNanosecondsToSeconds(s.DiskIo.PSI.Full.Total), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_pressure_io_waiting_seconds_total",
+				help:      "Total time duration tasks in the container have waited due to IO congestion.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: asNanosecondsToSeconds(s.DiskIo.PSI.Some.Total), timestamp: s.Timestamp}}
+				},
 			},
 		}...)
 	}
@@ -567,6 +796,26 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 						return float64(fs.Limit)
 					}, s.Timestamp)
 				},
+			}, {
+				name:        "container_fs_writable_layer_bytes",
+				help:        "Number of bytes that are consumed by the writable layer of the container on this filesystem.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Filesystem))
+					for _, stat := range s.Filesystem {
+						// Writable layer usage is available only for some storage drivers.
+						if stat.WritableLayerUsage == 0 {
+							continue
+						}
+						values = append(values, metricValue{
+							value:     float64(stat.WritableLayerUsage),
+							labels:    []string{stat.Device},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
 			}, {
 				name:        "container_fs_usage_bytes",
 				help:        "Number of bytes that are consumed by the container on this filesystem.",
@@ -746,6 +995,30 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 						s.Timestamp,
 					)
 				},
+			}, {
+				name:        "container_fs_read_wait_seconds_total",
+				help:        "Cumulative count of seconds reads spent waiting in I/O scheduler queues",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValuesIfPresent(s.DiskIo.IoWaitTime, "Read", asNanosecondsToSeconds, s.Timestamp)
+				},
+			}, {
+				name:        "container_fs_write_wait_seconds_total",
+				help:        "Cumulative count of seconds writes spent waiting in I/O scheduler queues",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValuesIfPresent(s.DiskIo.IoWaitTime, "Write", asNanosecondsToSeconds, s.Timestamp)
+				},
+			}, {
+				name:        "container_fs_io_wait_seconds_total",
+				help:        "Cumulative count of seconds I/Os spent waiting to be issued to the device",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return ioValuesIfPresent(s.DiskIo.IoWaitTime, "Total", asNanosecondsToSeconds, s.Timestamp)
+				},
 			}, {
 				name:        "container_fs_io_time_weighted_seconds_total",
 				help:        "Cumulative weighted I/O time in seconds",
@@ -1438,6 +1711,32 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 				},
 			},
 		}...)
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_network_tcp_retransmits_total",
+				help:      "Cumulative count of TCP segments retransmitted in network namespace of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.TcpAdvanced.RetransSegs), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_tcp_listen_drops_total",
+				help:      "Cumulative count of SYNs to listening TCP sockets dropped in network namespace of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.TcpAdvanced.ListenDrops), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_tcp_listen_overflows_total",
+				help:      "Cumulative count of times accept queue of listening TCP socket overflowed in network namespace of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.TcpAdvanced.ListenOverflows), timestamp: s.Timestamp}}
+				},
+			},
+		}...)
 	}
 	if includedMetrics.Has(container.NetworkUdpUsageMetrics) {
 		c.containerMetrics = append(c.containerMetrics, []containerMetric{
@@ -1503,6 +1802,98 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					}
 				},
 			},
+			{
+				name:      "container_network_udp_receive_errors_total",
+				help:      "Cumulative count of UDP datagrams which could not be delivered in network namespace of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.UdpAdvanced.InErrors), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_udp_receive_buffer_errors_total",
+				help:      "Cumulative count of UDP datagrams dropped because of full socket receive buffer in network namespace of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.UdpAdvanced.RcvbufErrors), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_udp_send_buffer_errors_total",
+				help:      "Cumulative count of UDP datagrams dropped because of full socket send buffer in network namespace of the container",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.UdpAdvanced.SndbufErrors), timestamp: s.Timestamp}}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.CgroupNetworkMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_network_cgroup_receive_bytes_total",
+				help:      "Cumulative count of bytes received by processes of the container regardless of their network namespace",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Network.Cgroup == nil {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(s.Network.Cgroup.RxBytes), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_cgroup_receive_packets_total",
+				help:      "Cumulative count of packets received by processes of the container regardless of their network namespace",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Network.Cgroup == nil {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(s.Network.Cgroup.RxPackets), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_cgroup_transmit_bytes_total",
+				help:      "Cumulative count of bytes transmitted by processes of the container regardless of their network namespace",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Network.Cgroup == nil {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(s.Network.Cgroup.TxBytes), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_cgroup_transmit_packets_total",
+				help:      "Cumulative count of packets transmitted by processes of the container regardless of their network namespace",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Network.Cgroup == nil {
+						return metricValues{}
+					}
+					return metricValues{{value: float64(s.Network.Cgroup.TxPackets), timestamp: s.Timestamp}}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.ConntrackMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_network_conntrack_entries",
+				help:      "Number of entries in conntrack table of network namespace of the container",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.Conntrack.Entries), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_network_conntrack_entries_limit",
+				help:      "Maximum number of entries in conntrack table of network namespace of the container",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Network.Conntrack.Max), timestamp: s.Timestamp}}
+				},
+			},
 		}...)
 	}
 	if includedMetrics.Has(container.ProcessMetrics) {
@@ -1515,6 +1906,14 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					return metricValues{{value: float64(s.Processes.ProcessCount), timestamp: s.Timestamp}}
 				},
 			},
+			{
+				name:      "container_processes_limit",
+				help:      "Maximum number of tasks (processes and threads) allowed inside the container by pids cgroup, infinity if value is zero.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.ThreadsMax), timestamp: s.Timestamp}}
+				},
+			},
 			{
 				name:      "container_file_descriptors",
 				help:      "Number of open file descriptors for the container.",
@@ -1574,6 +1973,71 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					return values
 				},
 			},
+			{
+				name:        "container_ulimits_hard",
+				help:        "Hard ulimit values for the container root process. Unlimited if -1, except priority and nice",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"ulimit"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Processes.Ulimits))
+					for _, ulimit := range s.Processes.Ulimits {
+						values = append(values, metricValue{
+							value:     float64(ulimit.HardLimit),
+							labels:    []string{ulimit.Name},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.ProcessListMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_processes_cpu_seconds_total",
+				help:        "Cumulative CPU time consumed by processes of the container using the most CPU time, summed per command.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"command"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTopProcessesValues(s, func(p *info.ProcessUsage) float64 {
+						return float64(p.CpuTime) / float64(time.Second)
+					})
+				},
+			},
+			{
+				name:        "container_processes_rss_bytes",
+				help:        "Resident set size of processes of the container using the most CPU time, summed per command.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"command"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTopProcessesValues(s, func(p *info.ProcessUsage) float64 {
+						return float64(p.RSS)
+					})
+				},
+			},
+			{
+				name:        "container_processes_file_descriptors",
+				help:        "Number of open file descriptors of processes of the container using the most CPU time, summed per command.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"command"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTopProcessesValues(s, func(p *info.ProcessUsage) float64 {
+						return float64(p.FdCount)
+					})
+				},
+			},
+			{
+				name:        "container_processes_threads",
+				help:        "Number of threads of processes of the container using the most CPU time, summed per command.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"command"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return getTopProcessesValues(s, func(p *info.ProcessUsage) float64 {
+						return float64(p.Threads)
+					})
+				},
+			},
 		}...)
 	}
 	if includedMetrics.Has(container.PerfMetrics) {
@@ -1705,6 +2169,40 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					return metrics
 				},
 			},
+			{
+				name:        "container_memory_bandwidth_bytes_total",
+				help:        "Total memory bandwidth used by container in bytes counted with RDT Memory Bandwidth Monitoring (MBM).",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(s *info.ContainerStats) metricValues {
+					metrics := make(metricValues, len(s.Resctrl.MemoryBandwidth))
+					for numaNode, stats := range s.Resctrl.MemoryBandwidth {
+						metrics[numaNode] = metricValue{
+							value:     float64(stats.TotalBytes),
+							timestamp: s.Timestamp,
+							labels:    []string{strconv.Itoa(numaNode)},
+						}
+					}
+					return metrics
+				},
+			},
+			{
+				name:        "container_memory_bandwidth_local_bytes_total",
+				help:        "Local memory bandwidth used by container in bytes counted with RDT Memory Bandwidth Monitoring (MBM).",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(s *info.ContainerStats) metricValues {
+					metrics := make(metricValues, len(s.Resctrl.MemoryBandwidth))
+					for numaNode, stats := range s.Resctrl.MemoryBandwidth {
+						metrics[numaNode] = metricValue{
+							value:     float64(stats.LocalBytes),
+							timestamp: s.Timestamp,
+							labels:    []string{strconv.Itoa(numaNode)},
+						}
+					}
+					return metrics
+				},
+			},
 			{
 				name:        "container_llc_occupancy_bytes",
 				help:        "Last level cache usage statistics for container counted with RDT Memory Bandwidth Monitoring (MBM).",
@@ -1728,11 +2226,51 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 	return c
 }
 
+// SetRelabeler sets the Relabeler applied to exported metrics. Labels of
+// metrics can't be renamed to names of labels the collector adds itself, e.g.
+// "cpu" or "interface".
+func (c *PrometheusCollector) SetRelabeler(r *Relabeler) error {
+	if r != nil {
+		extraLabels := map[string]bool{}
+		for _, cm := range c.containerMetrics {
+			for _, label := range cm.extraLabels {
+				extraLabels[label] = true
+			}
+		}
+		for from, to := range r.renameLabels {
+			if extraLabels[to] && from != to {
+				return fmt.Errorf("unable to rename label %q to %q, which is a label of container metrics", from, to)
+			}
+		}
+	}
+	c.relabeler = r
+	return nil
+}
+
+// SetNativeHistograms enables exporting latency histograms also as native
+// histograms, which are exposed only in the protobuf format.
+func (c *PrometheusCollector) SetNativeHistograms(enabled bool) {
+	c.nativeHistograms = enabled
+}
+
+// SetExemplarLabel sets the name of the container label whose value, e.g. a
+// trace ID, is attached to histograms of the container as an exemplar with
+// the same name. Exemplars are not attached if the name is empty.
+func (c *PrometheusCollector) SetExemplarLabel(label string) {
+	c.exemplarLabel = label
+}
+
+// newDesc returns a description of the metric, with renamed labels.
+func (c *PrometheusCollector) newDesc(name, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, c.relabeler.labelNames(labels), nil)
+}
+
 var (
 	versionInfoDesc = prometheus.NewDesc("cadvisor_version_info", "A metric with a constant '1' value labeled by kernel version, OS version, docker version, cadvisor version & cadvisor revision.", []string{"kernelVersion", "osVersion", "dockerVersion", "cadvisorVersion", "cadvisorRevision"}, nil)
 	startTimeDesc   = prometheus.NewDesc("container_start_time_seconds", "Start time of the container since unix epoch in seconds.", nil, nil)
 	cpuPeriodDesc   = prometheus.NewDesc("container_spec_cpu_period", "CPU period of the container.", nil, nil)
 	cpuQuotaDesc    = prometheus.NewDesc("container_spec_cpu_quota", "CPU quota of the container.", nil, nil)
+	cpuBurstDesc    = prometheus.NewDesc("container_spec_cpu_burst", "CPU burst of the container.", nil, nil)
 	cpuSharesDesc   = prometheus.NewDesc("container_spec_cpu_shares", "CPU share of the container.", nil, nil)
 )
 
@@ -1741,13 +2279,25 @@ var (
 func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.errors.Describe(ch)
 	for _, cm := range c.containerMetrics {
-		ch <- cm.desc([]string{})
+		if c.relabeler.KeepMetric(cm.name) {
+			ch <- cm.desc([]string{})
+		}
+	}
+	for _, desc := range []struct {
+		name string
+		desc *prometheus.Desc
+	}{
+		{"container_start_time_seconds", startTimeDesc},
+		{"container_spec_cpu_period", cpuPeriodDesc},
+		{"container_spec_cpu_quota", cpuQuotaDesc},
+		{"container_spec_cpu_burst", cpuBurstDesc},
+		{"container_spec_cpu_shares", cpuSharesDesc},
+		{"cadvisor_version_info", versionInfoDesc},
+	} {
+		if c.relabeler.KeepMetric(desc.name) {
+			ch <- desc.desc
+		}
 	}
-	ch <- startTimeDesc
-	ch <- cpuPeriodDesc
-	ch <- cpuQuotaDesc
-	ch <- cpuSharesDesc
-	ch <- versionInfoDesc
 }
 
 // Collect fetches the stats from all containers and delivers them as
@@ -1824,93 +2374,225 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 		klog.Warningf("Couldn't get containers: %s", err)
 		return
 	}
+	conts := make([]*info.ContainerInfo, 0, len(containers))
+	containersLabels := make([]map[string]string, 0, len(containers))
 	rawLabels := map[string]struct{}{}
 	for _, container := range containers {
-		for l := range c.containerLabelsFunc(container) {
+		containerLabels := c.relabeler.containerLabels(c.containerLabelsFunc(container))
+		for l := range containerLabels {
 			rawLabels[l] = struct{}{}
 		}
+		conts = append(conts, container)
+		containersLabels = append(containersLabels, containerLabels)
 	}
 
-	for _, cont := range containers {
-		values := make([]string, 0, len(rawLabels))
-		labels := make([]string, 0, len(rawLabels))
-		containerLabels := c.containerLabelsFunc(cont)
-		for l := range rawLabels {
-			duplicate := false
-			sl := sanitizeLabelName(l)
-			for _, x := range labels {
-				if sl == x {
-					duplicate = true
-					break
-				}
-			}
-			if !duplicate {
-				labels = append(labels, sl)
-				values = append(values, containerLabels[l])
-			}
+	// Labels are the same for all containers. Raw labels are sorted, the
+	// first one is used if several have the same sanitized name.
+	rawNames := make([]string, 0, len(rawLabels))
+	for l := range rawLabels {
+		rawNames = append(rawNames, l)
+	}
+	sort.Strings(rawNames)
+	labels := make([]string, 0, len(rawNames))
+	keys := make([]string, 0, len(rawNames))
+	seen := make(map[string]bool, len(rawNames))
+	for _, l := range rawNames {
+		sl := c.relabeler.labelName(sanitizeLabelName(l))
+		if !seen[sl] {
+			seen[sl] = true
+			labels = append(labels, sl)
+			keys = append(keys, l)
 		}
+	}
 
-		// Container spec
-		desc := prometheus.NewDesc("container_start_time_seconds", "Start time of the container since unix epoch in seconds.", labels, nil)
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.CreationTime.Unix()), values...)
+	// Label values of containers collected, if labels were dropped.
+	var collected map[string]bool
+	if c.relabeler != nil && c.relabeler.dropLabels != nil {
+		collected = make(map[string]bool, len(conts))
+	}
+	work := make([]containerWork, 0, len(conts))
+	for i, cont := range conts {
+		values := make([]string, len(keys))
+		for j, key := range keys {
+			values[j] = containersLabels[i][key]
+		}
+		if collected != nil {
+			key := strings.Join(values, "\xff")
+			if collected[key] {
+				klog.V(4).Infof("Skipping metrics of container %q with the same labels as another container", cont.Name)
+				continue
+			}
+			collected[key] = true
+		}
+		work = append(work, containerWork{cont: cont, values: values})
+	}
 
-		if cont.Spec.HasCpu {
-			desc = prometheus.NewDesc("container_spec_cpu_period", "CPU period of the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.Cpu.Period), values...)
-			if cont.Spec.Cpu.Quota != 0 {
-				desc = prometheus.NewDesc("container_spec_cpu_quota", "CPU quota of the container.", labels, nil)
-				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.Cpu.Quota), values...)
+	descs := c.newContainerDescs(labels)
+	workers := c.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(work) {
+		workers = len(work)
+	}
+	queue := make(chan containerWork, len(work))
+	for _, w := range work {
+		queue <- w
+	}
+	close(queue)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for w := range queue {
+				c.collectContainer(ch, descs, w.cont, w.values)
 			}
-			desc := prometheus.NewDesc("container_spec_cpu_shares", "CPU share of the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.Cpu.Limit), values...)
+		}()
+	}
+	wg.Wait()
+}
+
+// containerWork is a container whose metrics are collected, with values of its labels.
+type containerWork struct {
+	cont   *info.ContainerInfo
+	values []string
+}
 
+// containerDescs are descriptions of metrics of containers with the same labels.
+type containerDescs struct {
+	// spec are descriptions of spec metrics by name, nil if the metric is dropped.
+	spec map[string]*prometheus.Desc
+	// metrics are descriptions of containerMetrics, nil if the metric is dropped.
+	metrics []*prometheus.Desc
+	// labels are names of labels of containers.
+	labels []string
+}
+
+var specMetricsHelp = map[string]string{
+	"container_start_time_seconds":                  "Start time of the container since unix epoch in seconds.",
+	"container_spec_cpu_period":                     "CPU period of the container.",
+	"container_spec_cpu_quota":                      "CPU quota of the container.",
+	"container_spec_cpu_burst":                      "CPU burst of the container.",
+	"container_spec_cpu_shares":                     "CPU share of the container.",
+	"container_spec_memory_limit_bytes":             "Memory limit for the container.",
+	"container_spec_memory_swap_limit_bytes":        "Memory swap limit for the container.",
+	"container_spec_memory_reservation_limit_bytes": "Memory reservation limit for the container.",
+}
+
+func (c *PrometheusCollector) newContainerDescs(labels []string) *containerDescs {
+	descs := &containerDescs{
+		spec:    make(map[string]*prometheus.Desc, len(specMetricsHelp)),
+		metrics: make([]*prometheus.Desc, len(c.containerMetrics)),
+		labels:  labels,
+	}
+	for name, help := range specMetricsHelp {
+		if c.relabeler.KeepMetric(name) {
+			descs.spec[name] = prometheus.NewDesc(name, help, labels, nil)
+		}
+	}
+	for i, cm := range c.containerMetrics {
+		if !c.relabeler.KeepMetric(cm.name) {
+			continue
+		}
+		descLabels := make([]string, 0, len(labels)+len(cm.extraLabels))
+		descLabels = append(descLabels, labels...)
+		descLabels = append(descLabels, cm.extraLabels...)
+		descs.metrics[i] = c.newDesc(cm.name, cm.help, descLabels)
+	}
+	return descs
+}
+
+// collectContainer sends metrics of the container, values are values of its labels.
+func (c *PrometheusCollector) collectContainer(ch chan<- prometheus.Metric, descs *containerDescs, cont *info.ContainerInfo, values []string) {
+	specMetric := func(name string, value float64) {
+		if desc := descs.spec[name]; desc != nil {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, values...)
+		}
+	}
+
+	// Container spec
+	specMetric("container_start_time_seconds", float64(cont.Spec.CreationTime.Unix()))
+	if cont.Spec.HasCpu {
+		specMetric("container_spec_cpu_period", float64(cont.Spec.Cpu.Period))
+		if cont.Spec.Cpu.Quota != 0 {
+			specMetric("container_spec_cpu_quota", float64(cont.Spec.Cpu.Quota))
 		}
-		if cont.Spec.HasMemory {
-			desc := prometheus.NewDesc("container_spec_memory_limit_bytes", "Memory limit for the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Limit), values...)
-			desc = prometheus.NewDesc("container_spec_memory_swap_limit_bytes", "Memory swap limit for the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.SwapLimit), values...)
-			desc = prometheus.NewDesc("container_spec_memory_reservation_limit_bytes", "Memory reservation limit for the container.", labels, nil)
-			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Reservation), values...)
+		if cont.Spec.Cpu.Burst != 0 {
+			specMetric("container_spec_cpu_burst", float64(cont.Spec.Cpu.Burst))
 		}
+		specMetric("container_spec_cpu_shares", float64(cont.Spec.Cpu.Limit))
 
-		// Now for the actual metrics
-		if len(cont.Stats) == 0 {
+	}
+	if cont.Spec.HasMemory {
+		specMetric("container_spec_memory_limit_bytes", specMemoryValue(cont.Spec.Memory.Limit))
+		specMetric("container_spec_memory_swap_limit_bytes", specMemoryValue(cont.Spec.Memory.SwapLimit))
+		specMetric("container_spec_memory_reservation_limit_bytes", specMemoryValue(cont.Spec.Memory.Reservation))
+	}
+
+	// Now for the actual metrics
+	if len(cont.Stats) == 0 {
+		return
+	}
+	stats := cont.Stats[0]
+	var exemplarLabels []*dto.LabelPair
+	if value := cont.Spec.Labels[c.exemplarLabel]; c.exemplarLabel != "" && value != "" {
+		exemplarLabels = newExemplarLabels(sanitizeLabelName(c.exemplarLabel), value)
+	}
+	// Label values of metrics, with room for extra labels.
+	metricValues := make([]string, len(values), len(values)+4)
+	copy(metricValues, values)
+	for i, cm := range c.containerMetrics {
+		desc := descs.metrics[i]
+		if desc == nil {
 			continue
 		}
-		stats := cont.Stats[0]
-		for _, cm := range c.containerMetrics {
-			if cm.condition != nil && !cm.condition(cont.Spec) {
-				continue
-			}
-			desc := cm.desc(labels)
-			for _, metricValue := range cm.getValues(stats) {
-				ch <- prometheus.NewMetricWithTimestamp(
-					metricValue.timestamp,
-					prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), append(values, metricValue.labels...)...),
-				)
+		if cm.condition != nil && !cm.condition(cont.Spec) {
+			continue
+		}
+		for _, metricValue := range cm.getValues(stats) {
+			labelValues := append(metricValues[:len(values)], metricValue.labels...)
+			var metric prometheus.Metric
+			if h := metricValue.histogram; h != nil {
+				metric = prometheus.MustNewConstHistogram(desc, h.count, h.sum, h.buckets, labelValues...)
+				if c.nativeHistograms && h.native != nil {
+					metric = &nativeHistogram{Metric: metric, buckets: h.native}
+				}
+				if exemplarLabels != nil {
+					metric = &exemplarHistogram{Metric: metric, labels: exemplarLabels}
+				}
+			} else {
+				metric = prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), labelValues...)
 			}
+			ch <- prometheus.NewMetricWithTimestamp(metricValue.timestamp, metric)
 		}
-		if c.includedMetrics.Has(container.AppMetrics) {
-			for metricLabel, v := range stats.CustomMetrics {
-				for _, metric := range v {
-					clabels := make([]string, len(rawLabels), len(rawLabels)+len(metric.Labels))
-					cvalues := make([]string, len(rawLabels), len(rawLabels)+len(metric.Labels))
-					copy(clabels, labels)
-					copy(cvalues, values)
-					for label, value := range metric.Labels {
-						clabels = append(clabels, sanitizeLabelName("app_"+label))
-						cvalues = append(cvalues, value)
-					}
-					desc := prometheus.NewDesc(metricLabel, "Custom application metric.", clabels, nil)
-					ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(metric.FloatValue), cvalues...)
+	}
+	if c.includedMetrics.Has(container.AppMetrics) {
+		labels := descs.labels
+		for metricLabel, v := range stats.CustomMetrics {
+			if !c.relabeler.KeepMetric(metricLabel) {
+				continue
+			}
+			for _, metric := range v {
+				clabels := make([]string, len(labels), len(labels)+len(metric.Labels))
+				cvalues := make([]string, len(values), len(values)+len(metric.Labels))
+				copy(clabels, labels)
+				copy(cvalues, values)
+				for label, value := range metric.Labels {
+					clabels = append(clabels, sanitizeLabelName("app_"+label))
+					cvalues = append(cvalues, value)
 				}
+				desc := c.newDesc(metricLabel, "Custom application metric.", clabels)
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(metric.FloatValue), cvalues...)
 			}
 		}
 	}
 }
 
 func (c *PrometheusCollector) collectVersionInfo(ch chan<- prometheus.Metric) {
+	if !c.relabeler.KeepMetric("cadvisor_version_info") {
+		return
+	}
 	versionInfo, err := c.infoProvider.GetVersionInfo()
 	if err != nil {
 		c.errors.Set(1)
@@ -1939,15 +2621,73 @@ func sanitizeLabelName(name string) string {
 	return invalidNameCharRE.ReplaceAllString(name, "_")
 }
 
-func getNumaStatsPerNode(nodeStats map[uint8]uint64, labels []string, timestamp time.Time) metricValues {
+// getMemoryNumaStats returns NUMA stats of container multiplied by given multiplier, e.g. page size.
+func getMemoryNumaStats(s *info.ContainerStats, multiplier float64) metricValues {
+	values := make(metricValues, 0)
+	values = append(values, getNumaStatsPerNode(s.Memory.ContainerData.NumaStats.File,
+		[]string{"file", "container"}, multiplier, s.Timestamp)...)
+	values = append(values, getNumaStatsPerNode(s.Memory.ContainerData.NumaStats.Anon,
+		[]string{"anon", "container"}, multiplier, s.Timestamp)...)
+	values = append(values, getNumaStatsPerNode(s.Memory.ContainerData.NumaStats.Unevictable,
+		[]string{"unevictable", "container"}, multiplier, s.Timestamp)...)
+
+	values = append(values, getNumaStatsPerNode(s.Memory.HierarchicalData.NumaStats.File,
+		[]string{"file", "hierarchy"}, multiplier, s.Timestamp)...)
+	values = append(values, getNumaStatsPerNode(s.Memory.HierarchicalData.NumaStats.Anon,
+		[]string{"anon", "hierarchy"}, multiplier, s.Timestamp)...)
+	values = append(values, getNumaStatsPerNode(s.Memory.HierarchicalData.NumaStats.Unevictable,
+		[]string{"unevictable", "hierarchy"}, multiplier, s.Timestamp)...)
+	return values
+}
+
+func getNumaStatsPerNode(nodeStats map[uint8]uint64, labels []string, multiplier float64, timestamp time.Time) metricValues {
 	mValues := make(metricValues, 0, len(nodeStats))
 	for node, stat := range nodeStats {
 		nodeLabels := append(labels, strconv.FormatUint(uint64(node), 10))
-		mValues = append(mValues, metricValue{value: float64(stat), labels: nodeLabels, timestamp: timestamp})
+		mValues = append(mValues, metricValue{value: float64(stat) * multiplier, labels: nodeLabels, timestamp: timestamp})
 	}
 	return mValues
 }
 
+// getTopProcessesValues returns values of top processes of container aggregated by command,
+// processes are not reported individually to keep cardinality of PIDs out of labels.
+func getTopProcessesValues(s *info.ContainerStats, valueFn func(*info.ProcessUsage) float64) metricValues {
+	perCommand := make(map[string]float64)
+	for i := range s.Processes.TopProcesses {
+		perCommand[s.Processes.TopProcesses[i].Command] += valueFn(&s.Processes.TopProcesses[i])
+	}
+	values := make(metricValues, 0, len(perCommand))
+	for command, value := range perCommand {
+		values = append(values, metricValue{
+			value:     value,
+			labels:    []string{command},
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
+// latencyHistogramValue converts latency histogram to cumulative counts by upper bounds
+// of buckets in seconds, upper bound of bucket i is 2^(i+1) microseconds.
+func latencyHistogramValue(h *info.LatencyHistogram) *histogramValue {
+	value := &histogramValue{
+		count:   h.Count,
+		sum:     float64(h.Sum) / float64(time.Second),
+		buckets: make(map[float64]uint64, len(h.Buckets)),
+		native:  make(map[int]uint64, len(h.Buckets)),
+	}
+	var cumulative uint64
+	for i, count := range h.Buckets {
+		cumulative += count
+		upperBound := float64(uint64(2)<<uint(i)) * float64(time.Microsecond) / float64(time.Second)
+		value.buckets[upperBound] = cumulative
+		// Latencies are counted in the native bucket with the same upper bound,
+		// rounded up to a power of two.
+		value.native[nativeBucketIndex(upperBound)] += count
+	}
+	return value
+}
+
 func getPerCPUCorePerfEvents(s *info.ContainerStats) metricValues {
 	values := make(metricValues, 0, len(s.PerfStats))
 	for _, metric := range s.PerfStats {
@@ -34,7 +34,12 @@ func (p testSubcontainersInfoProvider) GetVersionInfo() (*info.VersionInfo, erro
 	}, nil
 }
 
+func (p testSubcontainersInfoProvider) GetMachineCounters() (*info.MachineInfo, error) {
+	return p.GetMachineInfo()
+}
+
 func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, error) {
+	nvmeTemperature := 38.85
 	return &info.MachineInfo{
 		Timestamp:        time.Unix(1395066363, 0),
 		NumCores:         4,
@@ -49,13 +54,53 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 			MemoryModeCapacity:    429496729600,
 			AppDirectModeCapacity: 1735166787584,
 		},
-		MachineID:  "machine-id-test",
-		SystemUUID: "system-uuid-test",
-		BootID:     "boot-id-test",
+		PmemRegions: []info.PmemRegion{
+			{
+				Name:     "region0",
+				Size:     136365211648,
+				NumaNode: 0,
+				Namespaces: []info.PmemNamespace{
+					{Name: "namespace0.0", Mode: "fsdax", Size: 133175443456, NumaNode: 0},
+				},
+			},
+			{
+				Name:     "region1",
+				Size:     136365211648,
+				NumaNode: 1,
+				Namespaces: []info.PmemNamespace{
+					{Name: "namespace1.0", Mode: "devdax", Size: 135289372672, NumaNode: 1},
+				},
+			},
+		},
+		HugePages: []info.HugePagesInfo{
+			{
+				PageSize:      uint64(2048),
+				NumPages:      uint64(4),
+				FreePages:     uint64(0),
+				SurplusPages:  uint64(1),
+				ReservedPages: uint64(3),
+			},
+		},
+		MachineID:     "machine-id-test",
+		SystemUUID:    "system-uuid-test",
+		BootID:        "boot-id-test",
+		BootTime:      time.Unix(1395000000, 0),
+		KernelVersion: "5.4.0-42-generic",
+		OSImage:       "Ubuntu 20.04.1 LTS",
+		CPUInfo: &info.CPUInfo{
+			Vendor:    "GenuineIntel",
+			Model:     "Intel(R) Xeon(R) Gold 6248 CPU @ 2.50GHz",
+			Microcode: "0x5003006",
+			Flags:     []string{"avx2", "avx512f"},
+		},
 		Topology: []info.Node{
 			{
-				Id:     0,
-				Memory: 33604804608,
+				Id:         0,
+				Memory:     33604804608,
+				MemoryFree: 20594716672,
+				MemoryFile: 8341307392,
+				MemoryAnon: 3286573056,
+				Distances:  []uint64{10, 21},
 				HugePages: []info.HugePagesInfo{
 					{
 						PageSize: uint64(1048576),
@@ -68,8 +113,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 				},
 				Cores: []info.Core{
 					{
-						Id:      0,
-						Threads: []int{0, 1},
+						Id:           0,
+						Threads:      []int{0, 1},
+						CoreType:     info.CoreTypePerformance,
+						MaxFrequency: 4700000,
 						Caches: []info.Cache{
 							{
 								Size:  32768,
@@ -89,8 +136,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 						},
 					},
 					{
-						Id:      1,
-						Threads: []int{2, 3},
+						Id:           1,
+						Threads:      []int{2, 3},
+						CoreType:     info.CoreTypePerformance,
+						MaxFrequency: 4700000,
 						Caches: []info.Cache{
 							{
 								Size:  32764,
@@ -111,8 +160,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 					},
 
 					{
-						Id:      2,
-						Threads: []int{4, 5},
+						Id:           2,
+						Threads:      []int{4, 5},
+						CoreType:     info.CoreTypePerformance,
+						MaxFrequency: 4700000,
 						Caches: []info.Cache{
 							{
 								Size:  32768,
@@ -132,8 +183,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 						},
 					},
 					{
-						Id:      3,
-						Threads: []int{6, 7},
+						Id:           3,
+						Threads:      []int{6, 7},
+						CoreType:     info.CoreTypePerformance,
+						MaxFrequency: 4700000,
 						Caches: []info.Cache{
 							{
 								Size:  32764,
@@ -155,22 +208,31 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 				},
 			},
 			{
-				Id:     1,
-				Memory: 33604804606,
+				Id:         1,
+				Memory:     33604804606,
+				MemoryFree: 31138512896,
+				MemoryFile: 1073741824,
+				MemoryAnon: 536870912,
+				Distances:  []uint64{21, 10},
 				HugePages: []info.HugePagesInfo{
 					{
-						PageSize: uint64(1048576),
-						NumPages: uint64(2),
+						PageSize:  uint64(1048576),
+						NumPages:  uint64(2),
+						FreePages: uint64(1),
 					},
 					{
-						PageSize: uint64(2048),
-						NumPages: uint64(4),
+						PageSize:     uint64(2048),
+						NumPages:     uint64(4),
+						FreePages:    uint64(0),
+						SurplusPages: uint64(1),
 					},
 				},
 				Cores: []info.Core{
 					{
-						Id:      4,
-						Threads: []int{8, 9},
+						Id:           4,
+						Threads:      []int{8, 9},
+						CoreType:     info.CoreTypeEfficiency,
+						MaxFrequency: 3600000,
 						Caches: []info.Cache{
 							{
 								Size:  32768,
@@ -190,8 +252,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 						},
 					},
 					{
-						Id:      5,
-						Threads: []int{10, 11},
+						Id:           5,
+						Threads:      []int{10, 11},
+						CoreType:     info.CoreTypeEfficiency,
+						MaxFrequency: 3600000,
 						Caches: []info.Cache{
 							{
 								Size:  32764,
@@ -211,8 +275,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 						},
 					},
 					{
-						Id:      6,
-						Threads: []int{12, 13},
+						Id:           6,
+						Threads:      []int{12, 13},
+						CoreType:     info.CoreTypeEfficiency,
+						MaxFrequency: 3600000,
 						Caches: []info.Cache{
 							{
 								Size:  32768,
@@ -232,8 +298,10 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 						},
 					},
 					{
-						Id:      7,
-						Threads: []int{14, 15},
+						Id:           7,
+						Threads:      []int{14, 15},
+						CoreType:     info.CoreTypeEfficiency,
+						MaxFrequency: 3600000,
 						Caches: []info.Cache{
 							{
 								Size:  32764,
@@ -262,6 +330,123 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 				},
 			},
 		},
+		CPUFrequencies: []info.CPUFrequency{
+			{
+				CPU:              0,
+				CurrentFrequency: 2400000,
+				Governor:         "powersave",
+				TimeInState:      map[uint64]uint64{800000: 250, 2400000: 1500},
+			},
+			{
+				CPU:              1,
+				CurrentFrequency: 800000,
+				Governor:         "powersave",
+				TimeInState:      map[uint64]uint64{800000: 1020, 2400000: 730},
+			},
+		},
+		PowerZones: []info.PowerZone{
+			{Id: "intel-rapl:0", Domain: "package-0", EnergyUJ: 123456789},
+			{Id: "intel-rapl:0:0", Domain: "dram", EnergyUJ: 4567},
+		},
+		ThermalSensors: []info.ThermalSensor{
+			{Device: "coretemp", Label: "Package id 0", Temperature: 45},
+			{Device: "nvme", Label: "Composite", Temperature: 38.85},
+		},
+		CPUVulnerabilities: map[string]string{
+			"meltdown":   "Mitigation: PTI",
+			"spectre_v2": "Vulnerable",
+		},
+		OnlineCPUs:  []int{0, 1, 2},
+		OfflineCPUs: []int{3},
+		NetworkDevices: []info.NetInfo{
+			{
+				Name:         "eth0",
+				MacAddress:   "42:01:02:03:04:f4",
+				Speed:        1000,
+				Mtu:          1500,
+				OperState:    "up",
+				Duplex:       "full",
+				Driver:       "ixgbe",
+				EthtoolStats: map[string]uint64{"rx_missed_errors": 3, "rx_queue_0_drops": 2},
+			},
+			{Name: "br0", MacAddress: "42:01:02:03:04:f5", Mtu: 1500, OperState: "down"},
+		},
+		NetworkQueueStats: []info.NetworkQueueStats{
+			{Device: "eth0", Queue: "rx-0", Packets: 10, Bytes: 1000},
+			{Device: "eth0", Queue: "tx-0", Packets: 5, Bytes: 500},
+		},
+		BlockDeviceStats: []info.BlockDeviceStats{
+			{
+				Name:            "sda",
+				Major:           8,
+				Minor:           0,
+				ReadsCompleted:  8764,
+				ReadsMerged:     213,
+				ReadBytes:       232407040,
+				ReadTime:        3210,
+				WritesCompleted: 12345,
+				WritesMerged:    6789,
+				WrittenBytes:    536870912,
+				WriteTime:       45678,
+				IoInProgress:    2,
+				IoTime:          30120,
+				WeightedIoTime:  48888,
+			},
+		},
+		InfinibandPorts: []info.InfinibandPort{
+			{
+				Device:             "mlx5_0",
+				Port:               1,
+				State:              "ACTIVE",
+				ReceivedBytes:      4194304,
+				TransmittedBytes:   8388608,
+				ReceivedPackets:    4096,
+				TransmittedPackets: 8192,
+				ReceiveErrors:      0,
+				TransmitDiscards:   3,
+				LinkDowned:         1,
+			},
+		},
+		NVMeDevices: []info.NVMeDevice{
+			{
+				Name:     "nvme0",
+				Model:    "Samsung SSD 970 EVO Plus 1TB",
+				Firmware: "2B2QEXM7",
+				Namespaces: []info.NVMeNamespace{
+					{Name: "nvme0n1", Size: 1000204886016},
+				},
+				Temperature: &nvmeTemperature,
+			},
+		},
+		ThinPools: []info.ThinPoolInfo{
+			{
+				Name:                 "docker-thinpool",
+				Mode:                 "rw",
+				DataUsage:            3435986944,
+				DataCapacity:         107374182400,
+				DataLowWaterMark:     2147483648,
+				MetadataUsage:        5050368,
+				MetadataCapacity:     17045913600,
+				MetadataLowWaterMark: 4194304,
+			},
+		},
+		PerfUncoreStats: []info.PerfUncoreStat{
+			{
+				PerfValue: info.PerfValue{ScalingRatio: 1.0, Value: 1500000, Name: "cas_count_read"},
+				Socket:    0,
+				PMU:       "uncore_imc",
+			},
+			{
+				PerfValue: info.PerfValue{ScalingRatio: 0.5, Value: 420000, Name: "txl_flits_all_data"},
+				Socket:    0,
+				PMU:       "uncore_upi",
+			},
+			{
+				PerfValue: info.PerfValue{ScalingRatio: 1.0, Value: 2100000, Name: "cas_count_read"},
+				Socket:    1,
+				PMU:       "uncore_imc",
+			},
+		},
 	}, nil
 }
 
@@ -279,6 +464,7 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 					Limit:  1000,
 					Period: 100000,
 					Quota:  10000,
+					Burst:  5000,
 				},
 				Memory: info.MemorySpec{
 					Limit:       2048,
@@ -312,6 +498,8 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							Periods:          723,
 							ThrottledPeriods: 18,
 							ThrottledTime:    1724314000,
+							BurstPeriods:     7,
+							BurstTime:        350000000,
 						},
 						Schedstat: info.CpuSchedstat{
 							RunTime:      53643567,
@@ -319,6 +507,15 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							RunPeriods:   984285,
 						},
 						LoadAverage: 2,
+						PSI: info.PSIStats{
+							Full: info.PSIData{Total: 100000000},
+							Some: info.PSIData{Total: 200000000},
+						},
+						RunqueueLatency: &info.LatencyHistogram{
+							Buckets: []uint64{10, 5, 0, 3},
+							Count:   20,
+							Sum:     150000,
+						},
 					},
 					Memory: info.MemoryStats{
 						Usage:      8,
@@ -346,6 +543,29 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						RSS:        15,
 						MappedFile: 16,
 						Swap:       8192,
+						PSI: info.PSIStats{
+							Full: info.PSIData{Total: 300000000},
+							Some: info.PSIData{Total: 400000000},
+						},
+						Reclaim: info.MemoryReclaimStats{
+							WorkingsetRefaultAnon:  17,
+							WorkingsetRefaultFile:  18,
+							WorkingsetActivateAnon: 19,
+							WorkingsetActivateFile: 20,
+							Pgscan:                 21,
+							Pgsteal:                22,
+						},
+						OOMKills: 2,
+					},
+					DiskIo: info.DiskIoStats{
+						IoWaitTime: []info.PerDiskStats{
+							{Device: "sda1", Major: 8, Minor: 1, Stats: map[string]uint64{"Read": 1500000000, "Write": 500000000, "Total": 2000000000}},
+							{Device: "sdb", Major: 8, Minor: 16, Stats: map[string]uint64{"Total": 250000000}},
+						},
+						PSI: info.PSIStats{
+							Full: info.PSIData{Total: 500000000},
+							Some: info.PSIData{Total: 600000000},
+						},
 					},
 					Hugetlb: map[string]info.HugetlbStats{
 						"2Mi": {
@@ -523,25 +743,44 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							RxQueued: 0,
 							TxQueued: 0,
 						},
+						Cgroup: &info.CgroupNetworkStats{
+							RxBytes:   1500000,
+							RxPackets: 1200,
+							TxBytes:   800000,
+							TxPackets: 900,
+						},
+						Conntrack: info.ConntrackStat{
+							Entries: 1500,
+							Max:     262144,
+						},
+						UdpAdvanced: info.UdpAdvancedStat{
+							InDatagrams:  1024,
+							NoPorts:      3,
+							InErrors:     4,
+							OutDatagrams: 1000,
+							RcvbufErrors: 2,
+							SndbufErrors: 1,
+						},
 					},
 					Filesystem: []info.FsStats{
 						{
-							Device:          "sda1",
-							InodesFree:      524288,
-							Inodes:          2097152,
-							Limit:           22,
-							Usage:           23,
-							ReadsCompleted:  24,
-							ReadsMerged:     25,
-							SectorsRead:     26,
-							ReadTime:        27,
-							WritesCompleted: 28,
-							WritesMerged:    39,
-							SectorsWritten:  40,
-							WriteTime:       41,
-							IoInProgress:    42,
-							IoTime:          43,
-							WeightedIoTime:  44,
+							Device:             "sda1",
+							InodesFree:         524288,
+							Inodes:             2097152,
+							Limit:              22,
+							Usage:              23,
+							WritableLayerUsage: 21,
+							ReadsCompleted:     24,
+							ReadsMerged:        25,
+							SectorsRead:        26,
+							ReadTime:           27,
+							WritesCompleted:    28,
+							WritesMerged:       39,
+							SectorsWritten:     40,
+							WriteTime:          41,
+							IoInProgress:       42,
+							IoTime:             43,
+							WeightedIoTime:     44,
 						},
 						{
 							Device:          "sda2",
@@ -570,6 +809,7 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							MemoryTotal: 20304050607,
 							MemoryUsed:  2030405060,
 							DutyCycle:   12,
+							PowerUsage:  150500,
 						},
 						{
 							Make:        "nvidia",
@@ -580,6 +820,10 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							DutyCycle:   6,
 						},
 					},
+					GpuEngineBusyTime: map[string]uint64{
+						"render": 9288864723,
+						"video":  1200000000,
+					},
 					Processes: info.ProcessStats{
 						ProcessCount:   1,
 						FdCount:        5,
@@ -593,6 +837,24 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 								HardLimit: 16384,
 							},
 						},
+						TopProcesses: []info.ProcessUsage{
+							{
+								Pid:     10,
+								Command: "nginx",
+								CpuTime: 2000000000,
+								RSS:     4096,
+								FdCount: 3,
+								Threads: 2,
+							},
+							{
+								Pid:     11,
+								Command: "nginx",
+								CpuTime: 1000000000,
+								RSS:     8192,
+								FdCount: 2,
+								Threads: 1,
+							},
+						},
 					},
 					TaskStats: info.LoadStats{
 						NrSleeping:        50,
@@ -708,6 +970,10 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							},
 						},
 					},
+					Syscalls: map[string]uint64{
+						"futex": 35,
+						"read":  1200,
+					},
 				},
 			},
 		},
@@ -733,6 +999,13 @@ func (p *erroringSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo,
 	return p.successfulProvider.GetMachineInfo()
 }
 
+func (p *erroringSubcontainersInfoProvider) GetMachineCounters() (*info.MachineInfo, error) {
+	if p.shouldFail {
+		return nil, errors.New("Oops 2")
+	}
+	return p.successfulProvider.GetMachineCounters()
+}
+
 func (p *erroringSubcontainersInfoProvider) GetRequestedContainersInfo(
 	a string, opt v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
 	if p.shouldFail {
@@ -15,6 +15,7 @@
 package metrics
 
 import (
+	"sort"
 	"strconv"
 
 	"github.com/google/cadvisor/container"
@@ -27,13 +28,39 @@ import (
 var baseLabelsNames = []string{"machine_id", "system_uuid", "boot_id"}
 
 const (
-	prometheusModeLabelName     = "mode"
-	prometheusTypeLabelName     = "type"
-	prometheusLevelLabelName    = "level"
-	prometheusNodeLabelName     = "node_id"
-	prometheusCoreLabelName     = "core_id"
-	prometheusThreadLabelName   = "thread_id"
-	prometheusPageSizeLabelName = "page_size"
+	prometheusModeLabelName       = "mode"
+	prometheusTypeLabelName       = "type"
+	prometheusLevelLabelName      = "level"
+	prometheusNodeLabelName       = "node_id"
+	prometheusTargetNodeLabelName = "target_node_id"
+	prometheusCoreLabelName       = "core_id"
+	prometheusThreadLabelName     = "thread_id"
+	prometheusPageSizeLabelName   = "page_size"
+	prometheusCoreTypeLabelName   = "core_type"
+	prometheusGovernorLabelName   = "governor"
+	prometheusFrequencyLabelName  = "frequency"
+	prometheusZoneLabelName       = "zone"
+	prometheusDomainLabelName     = "domain"
+	prometheusDeviceLabelName     = "device"
+	prometheusSensorLabelName     = "sensor"
+	prometheusVulnLabelName       = "vulnerability"
+	prometheusMitigationLabelName = "mitigation"
+	prometheusModelLabelName      = "model"
+	prometheusFirmwareLabelName   = "firmware"
+	prometheusOperStateLabelName  = "operstate"
+	prometheusDuplexLabelName     = "duplex"
+	prometheusDriverLabelName     = "driver"
+	prometheusPortLabelName       = "port"
+	prometheusQueueLabelName      = "queue"
+	prometheusStatLabelName       = "stat"
+	prometheusKernelLabelName     = "kernel"
+	prometheusOSLabelName         = "os"
+	prometheusVendorLabelName     = "vendor"
+	prometheusMicrocodeLabelName  = "microcode"
+	prometheusFlagLabelName       = "flag"
+	// NVMe namespace is not named "namespace" to avoid clash with Kubernetes namespace label.
+	prometheusNVMeNamespaceLabelName = "nvme_namespace"
+	prometheusPoolLabelName          = "pool"
 
 	nvmMemoryMode    = "memory_mode"
 	nvmAppDirectMode = "app_direct_mode"
@@ -59,15 +86,21 @@ func (metric *machineMetric) desc(baseLabels []string) *prometheus.Desc {
 	return prometheus.NewDesc(metric.name, metric.help, append(baseLabels, metric.extraLabels...), nil)
 }
 
+// machineInfoProvider will usually be manager.Manager, but can be swapped out for testing.
+type machineInfoProvider interface {
+	// GetMachineCounters provides information about the machine with current values of its counters.
+	GetMachineCounters() (*info.MachineInfo, error)
+}
+
 // PrometheusMachineCollector implements prometheus.Collector.
 type PrometheusMachineCollector struct {
-	infoProvider   infoProvider
+	infoProvider   machineInfoProvider
 	errors         prometheus.Gauge
 	machineMetrics []machineMetric
 }
 
 // NewPrometheusMachineCollector returns a new PrometheusCollector.
-func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.MetricSet) *PrometheusMachineCollector {
+func NewPrometheusMachineCollector(i machineInfoProvider, includedMetrics container.MetricSet) *PrometheusMachineCollector {
 	c := &PrometheusMachineCollector{
 
 		infoProvider: i,
@@ -77,6 +110,80 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 			Help:      "1 if there was an error while getting machine metrics, 0 otherwise.",
 		}),
 		machineMetrics: []machineMetric{
+			{
+				name:        "machine_info",
+				help:        "Information about machine labeled by kernel version and operating system, value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusKernelLabelName, prometheusOSLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return metricValues{{
+						value:     1,
+						labels:    []string{machineInfo.KernelVersion, machineInfo.OSImage},
+						timestamp: machineInfo.Timestamp,
+					}}
+				},
+			},
+			{
+				name:      "machine_boot_time_seconds",
+				help:      "Time the machine booted at in seconds since the Unix epoch.",
+				valueType: prometheus.GaugeValue,
+				condition: func(machineInfo *info.MachineInfo) bool { return !machineInfo.BootTime.IsZero() },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return metricValues{{value: float64(machineInfo.BootTime.Unix()), timestamp: machineInfo.Timestamp}}
+				},
+			},
+			{
+				name:        "machine_cpu_info",
+				help:        "Information about CPU labeled by vendor, model name and microcode revision, value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusVendorLabelName, prometheusModelLabelName, prometheusMicrocodeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return machineInfo.CPUInfo != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return metricValues{{
+						value:     1,
+						labels:    []string{machineInfo.CPUInfo.Vendor, machineInfo.CPUInfo.Model, machineInfo.CPUInfo.Microcode},
+						timestamp: machineInfo.Timestamp,
+					}}
+				},
+			},
+			{
+				name:        "machine_cpu_flag_info",
+				help:        "CPU flag related to vector instructions, cryptography or virtualization supported by CPU, value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusFlagLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return machineInfo.CPUInfo != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.CPUInfo.Flags))
+					for _, flag := range machineInfo.CPUInfo.Flags {
+						mValues = append(mValues, metricValue{
+							value:     1,
+							labels:    []string{flag},
+							timestamp: machineInfo.Timestamp,
+						})
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_cpu_vulnerability_info",
+				help:        "CPU vulnerability labeled by its mitigation state reported by kernel, value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusVulnLabelName, prometheusMitigationLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.CPUVulnerabilities) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getCPUVulnerabilities(machineInfo)
+				},
+			},
+			{
+				name:        "machine_cpu_online",
+				help:        "1 if logical CPU is online, 0 if it is offline (e.g. after hotplug).",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusThreadLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.OnlineCPUs) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getCPUOnlineStates(machineInfo)
+				},
+			},
 			{
 				name:      "machine_cpu_physical_cores",
 				help:      "Number of physical CPU cores.",
@@ -101,6 +208,26 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return metricValues{{value: float64(machineInfo.NumSockets), timestamp: machineInfo.Timestamp}}
 				},
 			},
+			{
+				name:        "machine_cpu_frequency_hertz",
+				help:        "Current frequency of logical CPU labeled by frequency scaling governor.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusThreadLabelName, prometheusGovernorLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.CPUFrequencies) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getCPUFrequencies(machineInfo)
+				},
+			},
+			{
+				name:        "machine_cpu_time_in_state_seconds_total",
+				help:        "Time spent by logical CPU at frequency (in hertz) since boot.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusThreadLabelName, prometheusFrequencyLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.CPUFrequencies) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getCPUTimeInState(machineInfo)
+				},
+			},
 			{
 				name:      "machine_memory_bytes",
 				help:      "Amount of memory installed on the machine.",
@@ -129,6 +256,240 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return getMemoryByType(machineInfo, memoryByTypeDimmCapacityKey)
 				},
 			},
+			{
+				name:        "machine_energy_joules_total",
+				help:        "Energy consumed by RAPL power zone labeled by power domain (e.g. package-0, dram).",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusZoneLabelName, prometheusDomainLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.PowerZones) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getEnergy(machineInfo)
+				},
+			},
+			{
+				name:        "machine_disk_reads_completed_total",
+				help:        "Number of reads completed by block device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.ReadsCompleted) })
+				},
+			},
+			{
+				name:        "machine_disk_read_bytes_total",
+				help:        "Number of bytes read from block device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.ReadBytes) })
+				},
+			},
+			{
+				name:        "machine_disk_read_time_seconds_total",
+				help:        "Time spent by reads from block device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.ReadTime) / 1000 })
+				},
+			},
+			{
+				name:        "machine_disk_writes_completed_total",
+				help:        "Number of writes completed by block device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.WritesCompleted) })
+				},
+			},
+			{
+				name:        "machine_disk_written_bytes_total",
+				help:        "Number of bytes written to block device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.WrittenBytes) })
+				},
+			},
+			{
+				name:        "machine_disk_write_time_seconds_total",
+				help:        "Time spent by writes to block device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.WriteTime) / 1000 })
+				},
+			},
+			{
+				name:        "machine_disk_io_now",
+				help:        "Number of IOs currently in progress on block device.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.IoInProgress) })
+				},
+			},
+			{
+				name:        "machine_disk_io_time_seconds_total",
+				help:        "Time block device had IOs in progress.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.IoTime) / 1000 })
+				},
+			},
+			{
+				name:        "machine_disk_io_time_weighted_seconds_total",
+				help:        "Time spent by IOs in queue of block device weighted by number of IOs in progress.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.BlockDeviceStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getBlockDeviceStats(machineInfo, func(stats info.BlockDeviceStats) float64 { return float64(stats.WeightedIoTime) / 1000 })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_data_received_bytes_total",
+				help:        "Number of data bytes received by InfiniBand port.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.ReceivedBytes })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_data_transmitted_bytes_total",
+				help:        "Number of data bytes transmitted by InfiniBand port.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.TransmittedBytes })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_packets_received_total",
+				help:        "Number of packets received by InfiniBand port.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.ReceivedPackets })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_packets_transmitted_total",
+				help:        "Number of packets transmitted by InfiniBand port.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.TransmittedPackets })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_errors_received_total",
+				help:        "Number of packets containing an error received by InfiniBand port.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.ReceiveErrors })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_transmit_discards_total",
+				help:        "Number of outbound packets discarded by InfiniBand port.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.TransmitDiscards })
+				},
+			},
+			{
+				name:        "machine_infiniband_port_link_downed_total",
+				help:        "Number of times link of InfiniBand port went down.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusPortLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.InfinibandPorts) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getInfinibandPortCounters(machineInfo, func(port info.InfinibandPort) uint64 { return port.LinkDowned })
+				},
+			},
+			{
+				name:        "machine_thermal_zone_celsius",
+				help:        "Temperature reported by hwmon sensor labeled by device (e.g. coretemp, nvme) and sensor.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusSensorLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.ThermalSensors) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getThermalSensors(machineInfo)
+				},
+			},
+			{
+				name:        "machine_thin_pool_info",
+				help:        "Devicemapper thin pool labeled by its mode (rw, ro or out_of_data_space), value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusPoolLabelName, prometheusModeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.ThinPools) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.ThinPools))
+					for _, pool := range machineInfo.ThinPools {
+						mValues = append(mValues, metricValue{
+							value:     1,
+							labels:    []string{pool.Name, pool.Mode},
+							timestamp: machineInfo.Timestamp,
+						})
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_thin_pool_usage_bytes",
+				help:        "Used data or metadata space of devicemapper thin pool in bytes.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusPoolLabelName, prometheusTypeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.ThinPools) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getThinPoolValues(machineInfo, func(pool info.ThinPoolInfo) (uint64, uint64) {
+						return pool.DataUsage, pool.MetadataUsage
+					})
+				},
+			},
+			{
+				name:        "machine_thin_pool_capacity_bytes",
+				help:        "Total data or metadata space of devicemapper thin pool in bytes.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusPoolLabelName, prometheusTypeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.ThinPools) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getThinPoolValues(machineInfo, func(pool info.ThinPoolInfo) (uint64, uint64) {
+						return pool.DataCapacity, pool.MetadataCapacity
+					})
+				},
+			},
+			{
+				name:        "machine_thin_pool_low_water_mark_bytes",
+				help:        "Free data or metadata space of devicemapper thin pool in bytes below which device mapper notifies userspace.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusPoolLabelName, prometheusTypeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.ThinPools) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getThinPoolValues(machineInfo, func(pool info.ThinPoolInfo) (uint64, uint64) {
+						return pool.DataLowWaterMark, pool.MetadataLowWaterMark
+					})
+				},
+			},
 			{
 				name:        "machine_nvm_capacity",
 				help:        "NVM capacity value labeled by NVM mode (memory mode or app direct mode).",
@@ -141,6 +502,24 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					}
 				},
 			},
+			{
+				name:        "machine_hugepages_reserved_count",
+				help:        "Number of hugepages reserved for allocation but not yet allocated.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusPageSizeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.HugePages) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.HugePages))
+					for _, hugePage := range machineInfo.HugePages {
+						mValues = append(mValues, metricValue{
+							value:     float64(hugePage.ReservedPages),
+							labels:    []string{strconv.FormatUint(hugePage.PageSize, 10)},
+							timestamp: machineInfo.Timestamp,
+						})
+					}
+					return mValues
+				},
+			},
 			{
 				name:      "machine_nvm_avg_power_budget_watts",
 				help:      "NVM power budget.",
@@ -149,6 +528,131 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return metricValues{{value: float64(machineInfo.NVMInfo.AvgPowerBudget), timestamp: machineInfo.Timestamp}}
 				},
 			},
+			{
+				name:        "machine_network_device_info",
+				help:        "Network device labeled by its operational state, duplex and driver, value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusOperStateLabelName, prometheusDuplexLabelName, prometheusDriverLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NetworkDevices) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.NetworkDevices))
+					for _, device := range machineInfo.NetworkDevices {
+						mValues = append(mValues, metricValue{
+							value:     1,
+							labels:    []string{device.Name, device.OperState, device.Duplex, device.Driver},
+							timestamp: machineInfo.Timestamp,
+						})
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_network_ethtool_stats_total",
+				help:        "Driver statistics of network device related to drops and errors labeled by statistic name (e.g. rx_missed_errors).",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusStatLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NetworkDevices) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := metricValues{}
+					for _, device := range machineInfo.NetworkDevices {
+						for stat, value := range device.EthtoolStats {
+							mValues = append(mValues, metricValue{
+								value:  float64(value),
+								labels: []string{device.Name, stat},
+							})
+						}
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_network_queue_packets_total",
+				help:        "Number of packets received or transmitted by queue of network device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusQueueLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NetworkQueueStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNetworkQueueStats(machineInfo, func(stats info.NetworkQueueStats) uint64 { return stats.Packets })
+				},
+			},
+			{
+				name:        "machine_network_queue_bytes_total",
+				help:        "Number of bytes received or transmitted by queue of network device.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusQueueLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NetworkQueueStats) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNetworkQueueStats(machineInfo, func(stats info.NetworkQueueStats) uint64 { return stats.Bytes })
+				},
+			},
+			{
+				name:        "machine_nvme_info",
+				help:        "Information about NVMe controller labeled by model and firmware revision, value is always 1.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusModelLabelName, prometheusFirmwareLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NVMeDevices) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.NVMeDevices))
+					for _, device := range machineInfo.NVMeDevices {
+						mValues = append(mValues, metricValue{
+							value:     1,
+							labels:    []string{device.Name, device.Model, device.Firmware},
+							timestamp: machineInfo.Timestamp,
+						})
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_nvme_namespace_size_bytes",
+				help:        "Size of NVMe namespace in bytes.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusDeviceLabelName, prometheusNVMeNamespaceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NVMeDevices) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.NVMeDevices))
+					for _, device := range machineInfo.NVMeDevices {
+						for _, namespace := range device.Namespaces {
+							mValues = append(mValues, metricValue{
+								value:     float64(namespace.Size),
+								labels:    []string{device.Name, namespace.Name},
+								timestamp: machineInfo.Timestamp,
+							})
+						}
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_nvme_temperature_celsius",
+				help:        "Composite temperature of NVMe controller in degrees Celsius.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusDeviceLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.NVMeDevices) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					mValues := make(metricValues, 0, len(machineInfo.NVMeDevices))
+					for _, device := range machineInfo.NVMeDevices {
+						if device.Temperature == nil {
+							continue
+						}
+						mValues = append(mValues, metricValue{
+							value:  *device.Temperature,
+							labels: []string{device.Name},
+						})
+					}
+					return mValues
+				},
+			},
+			{
+				name:        "machine_pmem_capacity_bytes",
+				help:        "Capacity of persistent memory namespaces labeled by namespace mode (e.g. fsdax, devdax) and NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusModeLabelName, prometheusNodeLabelName},
+				condition:   func(machineInfo *info.MachineInfo) bool { return len(machineInfo.PmemRegions) != 0 },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getPmemCapacityByMode(machineInfo)
+				},
+			},
 		},
 	}
 
@@ -163,6 +667,15 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return getCaches(machineInfo)
 				},
 			},
+			{
+				name:        "machine_cpu_core_max_frequency_hertz",
+				help:        "Maximal frequency of CPU core labeled by core type on hybrid CPUs.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName, prometheusCoreLabelName, prometheusCoreTypeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getCoresMaxFrequency(machineInfo)
+				},
+			},
 			{
 				name:        "machine_thread_siblings_count",
 				help:        "Number of CPU thread siblings.",
@@ -181,6 +694,69 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return getNodeMemory(machineInfo)
 				},
 			},
+			{
+				name:        "machine_node_cpu_cores",
+				help:        "Number of CPU cores of NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeCPUs(machineInfo, func(core info.Core) int { return 1 })
+				},
+			},
+			{
+				name:        "machine_node_cpu_threads",
+				help:        "Number of CPU threads (logical CPUs) of NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeCPUs(machineInfo, func(core info.Core) int { return len(core.Threads) })
+				},
+			},
+			{
+				name:        "machine_node_cache_capacity_bytes",
+				help:        "Total size of CPU caches of NUMA node by cache level and type.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName, prometheusTypeLabelName, prometheusLevelLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeCaches(machineInfo)
+				},
+			},
+			{
+				name:        "machine_node_distance",
+				help:        "Relative distance of memory of target NUMA node from NUMA node as reported by firmware, 10 for local memory.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName, prometheusTargetNodeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeDistances(machineInfo)
+				},
+			},
+			{
+				name:        "machine_node_memory_free_bytes",
+				help:        "Amount of free memory of NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeMemoryUsage(machineInfo, func(node info.Node) uint64 { return node.MemoryFree })
+				},
+			},
+			{
+				name:        "machine_node_memory_file_bytes",
+				help:        "Amount of file-backed memory (page cache) of NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeMemoryUsage(machineInfo, func(node info.Node) uint64 { return node.MemoryFile })
+				},
+			},
+			{
+				name:        "machine_node_memory_anon_bytes",
+				help:        "Amount of anonymous memory of NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getNodeMemoryUsage(machineInfo, func(node info.Node) uint64 { return node.MemoryAnon })
+				},
+			},
 			{
 				name:        "machine_node_hugepages_count",
 				help:        "Numer of hugepages assigned to NUMA node.",
@@ -190,6 +766,56 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return getHugePagesCount(machineInfo)
 				},
 			},
+			{
+				name:        "machine_node_hugepages_capacity_bytes",
+				help:        "Amount of memory of hugepages assigned to NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName, prometheusPageSizeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					// Page size is in kB.
+					return getHugePagesCounter(machineInfo, func(hugePage info.HugePagesInfo) uint64 { return hugePage.NumPages * hugePage.PageSize * 1024 })
+				},
+			},
+			{
+				name:        "machine_node_hugepages_free_count",
+				help:        "Number of free hugepages assigned to NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName, prometheusPageSizeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getHugePagesCounter(machineInfo, func(hugePage info.HugePagesInfo) uint64 { return hugePage.FreePages })
+				},
+			},
+			{
+				name:        "machine_node_hugepages_surplus_count",
+				help:        "Number of surplus (overcommitted) hugepages assigned to NUMA node.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{prometheusNodeLabelName, prometheusPageSizeLabelName},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getHugePagesCounter(machineInfo, func(hugePage info.HugePagesInfo) uint64 { return hugePage.SurplusPages })
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.PerfMetrics) {
+		c.machineMetrics = append(c.machineMetrics, []machineMetric{
+			{
+				name:        "machine_perf_uncore_events_total",
+				help:        "Perf uncore event metric summed per socket and type of PMU.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"socket", "event", "pmu"},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getPerfUncoreStats(machineInfo, func(stat info.PerfUncoreStat) float64 { return float64(stat.Value) })
+				},
+			},
+			{
+				name:        "machine_perf_uncore_events_scaling_ratio",
+				help:        "Lowest scaling ratio of perf uncore event summed per socket and type of PMU.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"socket", "event", "pmu"},
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					return getPerfUncoreStats(machineInfo, func(stat info.PerfUncoreStat) float64 { return stat.ScalingRatio })
+				},
+			},
 		}...)
 	}
 	return c
@@ -213,7 +839,7 @@ func (collector *PrometheusMachineCollector) Collect(ch chan<- prometheus.Metric
 }
 
 func (collector *PrometheusMachineCollector) collectMachineInfo(ch chan<- prometheus.Metric) {
-	machineInfo, err := collector.infoProvider.GetMachineInfo()
+	machineInfo, err := collector.infoProvider.GetMachineCounters()
 	if err != nil {
 		collector.errors.Set(1)
 		klog.Warningf("Couldn't get machine info: %s", err)
@@ -247,6 +873,29 @@ func (collector *PrometheusMachineCollector) collectMachineInfo(ch chan<- promet
 	}
 }
 
+func getPmemCapacityByMode(machineInfo *info.MachineInfo) metricValues {
+	type modeOnNode struct {
+		mode string
+		node int
+	}
+	capacity := map[modeOnNode]uint64{}
+	for _, region := range machineInfo.PmemRegions {
+		for _, namespace := range region.Namespaces {
+			capacity[modeOnNode{mode: namespace.Mode, node: namespace.NumaNode}] += namespace.Size
+		}
+	}
+	mValues := make(metricValues, 0, len(capacity))
+	for key, size := range capacity {
+		mValues = append(mValues,
+			metricValue{
+				value:     float64(size),
+				labels:    []string{key.mode, strconv.Itoa(key.node)},
+				timestamp: machineInfo.Timestamp,
+			})
+	}
+	return mValues
+}
+
 func getMemoryByType(machineInfo *info.MachineInfo, property string) metricValues {
 	mValues := make(metricValues, 0, len(machineInfo.MemoryByType))
 	for memoryType, memoryInfo := range machineInfo.MemoryByType {
@@ -287,6 +936,175 @@ func getThreadsSiblingsCount(machineInfo *info.MachineInfo) metricValues {
 	return mValues
 }
 
+func getCoresMaxFrequency(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0)
+	for _, node := range machineInfo.Topology {
+		nodeID := strconv.Itoa(node.Id)
+
+		for _, core := range node.Cores {
+			if core.MaxFrequency == 0 {
+				continue
+			}
+			mValues = append(mValues,
+				metricValue{
+					// Maximal frequency is reported in kHz.
+					value:     float64(core.MaxFrequency) * 1000,
+					labels:    []string{nodeID, strconv.Itoa(core.Id), core.CoreType},
+					timestamp: machineInfo.Timestamp,
+				})
+		}
+	}
+	return mValues
+}
+
+func getCPUFrequencies(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.CPUFrequencies))
+	for _, frequency := range machineInfo.CPUFrequencies {
+		mValues = append(mValues,
+			metricValue{
+				// Frequency is reported in kHz.
+				value:  float64(frequency.CurrentFrequency) * 1000,
+				labels: []string{strconv.Itoa(frequency.CPU), frequency.Governor},
+			})
+	}
+	return mValues
+}
+
+func getCPUTimeInState(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0)
+	for _, frequency := range machineInfo.CPUFrequencies {
+		cpu := strconv.Itoa(frequency.CPU)
+		for freq, milliseconds := range frequency.TimeInState {
+			mValues = append(mValues,
+				metricValue{
+					value:  float64(milliseconds) / 1000,
+					labels: []string{cpu, strconv.FormatUint(freq*1000, 10)},
+				})
+		}
+	}
+	return mValues
+}
+
+func getEnergy(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.PowerZones))
+	for _, zone := range machineInfo.PowerZones {
+		mValues = append(mValues,
+			metricValue{
+				// Energy is reported in microjoules.
+				value:  float64(zone.EnergyUJ) / 1000000,
+				labels: []string{zone.Id, zone.Domain},
+			})
+	}
+	return mValues
+}
+
+func getBlockDeviceStats(machineInfo *info.MachineInfo, getValue func(info.BlockDeviceStats) float64) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.BlockDeviceStats))
+	for _, stats := range machineInfo.BlockDeviceStats {
+		mValues = append(mValues,
+			metricValue{
+				value:  getValue(stats),
+				labels: []string{stats.Name},
+			})
+	}
+	return mValues
+}
+
+func getInfinibandPortCounters(machineInfo *info.MachineInfo, getValue func(info.InfinibandPort) uint64) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.InfinibandPorts))
+	for _, port := range machineInfo.InfinibandPorts {
+		mValues = append(mValues,
+			metricValue{
+				value:  float64(getValue(port)),
+				labels: []string{port.Device, strconv.Itoa(port.Port)},
+			})
+	}
+	return mValues
+}
+
+func getNetworkQueueStats(machineInfo *info.MachineInfo, getValue func(info.NetworkQueueStats) uint64) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.NetworkQueueStats))
+	for _, stats := range machineInfo.NetworkQueueStats {
+		mValues = append(mValues,
+			metricValue{
+				value:  float64(getValue(stats)),
+				labels: []string{stats.Device, stats.Queue},
+			})
+	}
+	return mValues
+}
+
+// getThinPoolValues returns data and metadata values of thin pools labeled by type, metadata value
+// is skipped when it is zero, i.e. metadata low watermark is not reported by kernels older than 4.19.
+func getThinPoolValues(machineInfo *info.MachineInfo, getValues func(info.ThinPoolInfo) (uint64, uint64)) metricValues {
+	mValues := make(metricValues, 0, 2*len(machineInfo.ThinPools))
+	for _, pool := range machineInfo.ThinPools {
+		data, metadata := getValues(pool)
+		mValues = append(mValues,
+			metricValue{
+				value:  float64(data),
+				labels: []string{pool.Name, "data"},
+			})
+		if metadata == 0 {
+			continue
+		}
+		mValues = append(mValues,
+			metricValue{
+				value:  float64(metadata),
+				labels: []string{pool.Name, "metadata"},
+			})
+	}
+	return mValues
+}
+
+func getThermalSensors(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.ThermalSensors))
+	for _, sensor := range machineInfo.ThermalSensors {
+		mValues = append(mValues,
+			metricValue{
+				value:  sensor.Temperature,
+				labels: []string{sensor.Device, sensor.Label},
+			})
+	}
+	return mValues
+}
+
+func getPerfUncoreStats(machineInfo *info.MachineInfo, getValue func(info.PerfUncoreStat) float64) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.PerfUncoreStats))
+	for _, stat := range machineInfo.PerfUncoreStats {
+		mValues = append(mValues,
+			metricValue{
+				value:  getValue(stat),
+				labels: []string{strconv.Itoa(stat.Socket), stat.Name, stat.PMU},
+			})
+	}
+	return mValues
+}
+
+func getCPUOnlineStates(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.OnlineCPUs)+len(machineInfo.OfflineCPUs))
+	for _, cpu := range machineInfo.OnlineCPUs {
+		mValues = append(mValues, metricValue{value: 1, labels: []string{strconv.Itoa(cpu)}, timestamp: machineInfo.Timestamp})
+	}
+	for _, cpu := range machineInfo.OfflineCPUs {
+		mValues = append(mValues, metricValue{value: 0, labels: []string{strconv.Itoa(cpu)}, timestamp: machineInfo.Timestamp})
+	}
+	return mValues
+}
+
+func getCPUVulnerabilities(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.CPUVulnerabilities))
+	for vulnerability, mitigation := range machineInfo.CPUVulnerabilities {
+		mValues = append(mValues,
+			metricValue{
+				value:     1,
+				labels:    []string{vulnerability, mitigation},
+				timestamp: machineInfo.Timestamp,
+			})
+	}
+	return mValues
+}
+
 func getNodeMemory(machineInfo *info.MachineInfo) metricValues {
 	mValues := make(metricValues, 0, len(machineInfo.Topology))
 	for _, node := range machineInfo.Topology {
@@ -301,6 +1119,96 @@ func getNodeMemory(machineInfo *info.MachineInfo) metricValues {
 	return mValues
 }
 
+func getNodeMemoryUsage(machineInfo *info.MachineInfo, usage func(node info.Node) uint64) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.Topology))
+	for _, node := range machineInfo.Topology {
+		mValues = append(mValues,
+			metricValue{
+				value:  float64(usage(node)),
+				labels: []string{strconv.Itoa(node.Id)},
+			})
+	}
+	return mValues
+}
+
+func getNodeCPUs(machineInfo *info.MachineInfo, count func(core info.Core) int) metricValues {
+	mValues := make(metricValues, 0, len(machineInfo.Topology))
+	for _, node := range machineInfo.Topology {
+		cpus := 0
+		for _, core := range node.Cores {
+			cpus += count(core)
+		}
+		mValues = append(mValues,
+			metricValue{
+				value:     float64(cpus),
+				labels:    []string{strconv.Itoa(node.Id)},
+				timestamp: machineInfo.Timestamp,
+			})
+	}
+	return mValues
+}
+
+// getNodeCaches returns sizes of caches of NUMA nodes summed by type and level,
+// caches shared with other nodes are counted by each of them.
+func getNodeCaches(machineInfo *info.MachineInfo) metricValues {
+	type cacheKind struct {
+		cacheType string
+		level     int
+	}
+	mValues := make(metricValues, 0)
+	for _, node := range machineInfo.Topology {
+		sizes := map[cacheKind]uint64{}
+		for _, core := range node.Cores {
+			for _, cache := range core.Caches {
+				sizes[cacheKind{cache.Type, cache.Level}] += cache.Size
+			}
+		}
+		for _, cache := range node.UncoreCaches {
+			sizes[cacheKind{cache.Type, cache.Level}] += cache.Size
+		}
+		for _, cache := range node.Caches {
+			sizes[cacheKind{cache.Type, cache.Level}] += cache.Size
+		}
+
+		kinds := make([]cacheKind, 0, len(sizes))
+		for kind := range sizes {
+			kinds = append(kinds, kind)
+		}
+		sort.Slice(kinds, func(i, j int) bool {
+			if kinds[i].level != kinds[j].level {
+				return kinds[i].level < kinds[j].level
+			}
+			return kinds[i].cacheType < kinds[j].cacheType
+		})
+		nodeID := strconv.Itoa(node.Id)
+		for _, kind := range kinds {
+			mValues = append(mValues,
+				metricValue{
+					value:     float64(sizes[kind]),
+					labels:    []string{nodeID, kind.cacheType, strconv.Itoa(kind.level)},
+					timestamp: machineInfo.Timestamp,
+				})
+		}
+	}
+	return mValues
+}
+
+func getNodeDistances(machineInfo *info.MachineInfo) metricValues {
+	mValues := make(metricValues, 0)
+	for _, node := range machineInfo.Topology {
+		nodeID := strconv.Itoa(node.Id)
+		for targetID, distance := range node.Distances {
+			mValues = append(mValues,
+				metricValue{
+					value:     float64(distance),
+					labels:    []string{nodeID, strconv.Itoa(targetID)},
+					timestamp: machineInfo.Timestamp,
+				})
+		}
+	}
+	return mValues
+}
+
 func getHugePagesCount(machineInfo *info.MachineInfo) metricValues {
 	mValues := make(metricValues, 0)
 	for _, node := range machineInfo.Topology {
@@ -318,6 +1226,22 @@ func getHugePagesCount(machineInfo *info.MachineInfo) metricValues {
 	return mValues
 }
 
+func getHugePagesCounter(machineInfo *info.MachineInfo, counter func(hugePage info.HugePagesInfo) uint64) metricValues {
+	mValues := make(metricValues, 0)
+	for _, node := range machineInfo.Topology {
+		nodeID := strconv.Itoa(node.Id)
+
+		for _, hugePage := range node.HugePages {
+			mValues = append(mValues,
+				metricValue{
+					value:  float64(counter(hugePage)),
+					labels: []string{nodeID, strconv.FormatUint(hugePage.PageSize, 10)},
+				})
+		}
+	}
+	return mValues
+}
+
 func getCaches(machineInfo *info.MachineInfo) metricValues {
 	mValues := make(metricValues, 0)
 	for _, node := range machineInfo.Topology {
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
 	"github.com/stretchr/testify/assert"
@@ -187,6 +188,64 @@ func TestGetHugePagesCount(t *testing.T) {
 	assertMetricValues(t, expectedMetricVals, metricVals, "Unexpected information about Node memory")
 }
 
+func TestGetNodeCPUs(t *testing.T) {
+	machineInfo, err := testSubcontainersInfoProvider{}.GetMachineInfo()
+	assert.Nil(t, err)
+
+	metricVals := getNodeCPUs(machineInfo, func(core info.Core) int { return len(core.Threads) })
+
+	assert.Equal(t, 2, len(metricVals))
+	expectedMetricVals := []metricValue{
+		{value: 8, labels: []string{"0"}, timestamp: time.Unix(1395066363, 0)},
+		{value: 8, labels: []string{"1"}, timestamp: time.Unix(1395066363, 0)},
+	}
+	assertMetricValues(t, expectedMetricVals, metricVals, "Unexpected information about Node CPUs")
+}
+
+func TestGetNodeCaches(t *testing.T) {
+	l2 := info.Cache{Size: 1048576, Type: "Unified", Level: 2, SharedCPUList: "0-3"}
+	machineInfo := &info.MachineInfo{
+		Timestamp: time.Unix(1395066363, 0),
+		Topology: []info.Node{
+			{
+				Id: 0,
+				Cores: []info.Core{
+					{Id: 0, Caches: []info.Cache{{Size: 32768, Type: "Data", Level: 1}}},
+					{Id: 1, Caches: []info.Cache{{Size: 32768, Type: "Data", Level: 1}}},
+				},
+				Caches:       []info.Cache{{Size: 8388608, Type: "Unified", Level: 3}},
+				UncoreCaches: []info.UncoreCache{{Cache: l2, ClusterID: "0"}},
+			},
+		},
+	}
+
+	metricVals := getNodeCaches(machineInfo)
+
+	assert.Equal(t, 3, len(metricVals))
+	expectedMetricVals := []metricValue{
+		{value: 65536, labels: []string{"0", "Data", "1"}, timestamp: time.Unix(1395066363, 0)},
+		{value: 1048576, labels: []string{"0", "Unified", "2"}, timestamp: time.Unix(1395066363, 0)},
+		{value: 8388608, labels: []string{"0", "Unified", "3"}, timestamp: time.Unix(1395066363, 0)},
+	}
+	assertMetricValues(t, expectedMetricVals, metricVals, "Unexpected information about Node caches")
+}
+
+func TestGetNodeDistances(t *testing.T) {
+	machineInfo, err := testSubcontainersInfoProvider{}.GetMachineInfo()
+	assert.Nil(t, err)
+
+	metricVals := getNodeDistances(machineInfo)
+
+	assert.Equal(t, 4, len(metricVals))
+	expectedMetricVals := []metricValue{
+		{value: 10, labels: []string{"0", "0"}, timestamp: time.Unix(1395066363, 0)},
+		{value: 21, labels: []string{"0", "1"}, timestamp: time.Unix(1395066363, 0)},
+		{value: 21, labels: []string{"1", "0"}, timestamp: time.Unix(1395066363, 0)},
+		{value: 10, labels: []string{"1", "1"}, timestamp: time.Unix(1395066363, 0)},
+	}
+	assertMetricValues(t, expectedMetricVals, metricVals, "Unexpected information about Node distances")
+}
+
 func assertMetricValues(t *testing.T, expected metricValues, actual metricValues, message string) {
 	for i := range actual {
 		assert.Truef(t, reflect.DeepEqual(expected[i], actual[i]),
@@ -16,6 +16,7 @@ package metrics
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -60,6 +61,9 @@ func TestPrometheusCollectorWithPerfAggregated(t *testing.T) {
 }
 
 func testPrometheusCollector(t *testing.T, gatherer prometheus.Gatherer, metricsFile string) {
+	// Expected metrics assume 4kB pages.
+	pageSize = 4096
+
 	wantMetrics, err := os.Open(metricsFile)
 	if err != nil {
 		t.Fatalf("unable to read input test file %s", metricsFile)
@@ -319,3 +323,64 @@ func TestGetMinCoreScalingRatio(t *testing.T) {
 	assert.Contains(t, values, 0.5)
 	assert.Contains(t, values, 0.3)
 }
+
+// manyContainersInfoProvider returns copies of the test container.
+type manyContainersInfoProvider struct {
+	testSubcontainersInfoProvider
+	containers map[string]*info.ContainerInfo
+}
+
+func newManyContainersInfoProvider(n int) *manyContainersInfoProvider {
+	p := &manyContainersInfoProvider{containers: make(map[string]*info.ContainerInfo, n)}
+	containers, _ := p.testSubcontainersInfoProvider.GetRequestedContainersInfo("/", v2.RequestOptions{})
+	for i := 0; i < n; i++ {
+		cont := *containers["testcontainer"]
+		cont.Name = fmt.Sprintf("testcontainer%d", i)
+		cont.Aliases = []string{fmt.Sprintf("testcontaineralias%d", i)}
+		p.containers[cont.Name] = &cont
+	}
+	return p
+}
+
+func (p *manyContainersInfoProvider) GetRequestedContainersInfo(string, v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return p.containers, nil
+}
+
+func TestPrometheusCollectorManyContainers(t *testing.T) {
+	c := NewPrometheusCollector(newManyContainersInfoProvider(50), DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	assert.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() == "container_last_seen" {
+			assert.Len(t, family.GetMetric(), 50)
+		}
+	}
+}
+
+func benchmarkPrometheusCollector(b *testing.B, workers int) {
+	c := NewPrometheusCollector(newManyContainersInfoProvider(500), DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{})
+	c.workers = workers
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ch := make(chan prometheus.Metric, 1024)
+		done := make(chan struct{})
+		go func() {
+			for range ch {
+			}
+			close(done)
+		}()
+		c.Collect(ch)
+		close(ch)
+		<-done
+	}
+}
+
+func BenchmarkPrometheusCollectorSerial(b *testing.B) {
+	benchmarkPrometheusCollector(b, 1)
+}
+
+func BenchmarkPrometheusCollectorParallel(b *testing.B) {
+	benchmarkPrometheusCollector(b, 0)
+}
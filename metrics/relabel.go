@@ -0,0 +1,173 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// RelabelConfig configures metric families and labels exported by
+// PrometheusCollector. Names are matched by regular expressions, which
+// have to match the whole name.
+type RelabelConfig struct {
+	// DropMetrics are names of metric families which are not exported.
+	DropMetrics []string `json:"drop_metrics,omitempty"`
+	// KeepMetrics are names of metric families exported, all are exported if empty.
+	KeepMetrics []string `json:"keep_metrics,omitempty"`
+	// DropLabels are names of labels of containers which are removed from
+	// metrics, e.g. "id" or "container_label_.*". Metrics of a container with
+	// the same labels as a container collected before are dropped.
+	DropLabels []string `json:"drop_labels,omitempty"`
+	// RenameLabels maps names of labels to new names.
+	RenameLabels map[string]string `json:"rename_labels,omitempty"`
+	// ContainerLabels are names of container labels exported with the
+	// container_label_ prefix, all are exported if nil. Names are matched
+	// as exported, with characters invalid in label names replaced by "_".
+	ContainerLabels []string `json:"container_labels,omitempty"`
+	// ContainerEnvs are names of environment variables exported with the
+	// container_env_ prefix, all are exported if nil. Names are matched as
+	// ContainerLabels.
+	ContainerEnvs []string `json:"container_envs,omitempty"`
+}
+
+// ParseRelabelConfig reads the configuration in JSON.
+func ParseRelabelConfig(reader io.Reader) (*RelabelConfig, error) {
+	config := &RelabelConfig{}
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("unable to decode relabel config: %v", err)
+	}
+	return config, nil
+}
+
+// Relabeler applies RelabelConfig to metrics of PrometheusCollector. A nil
+// Relabeler doesn't change metrics.
+type Relabeler struct {
+	dropMetrics  *regexp.Regexp
+	keepMetrics  *regexp.Regexp
+	dropLabels   *regexp.Regexp
+	renameLabels map[string]string
+	keepLabels   *regexp.Regexp
+	keepEnvs     *regexp.Regexp
+}
+
+// NewRelabeler validates the configuration and returns its Relabeler.
+func NewRelabeler(config *RelabelConfig) (*Relabeler, error) {
+	r := &Relabeler{renameLabels: map[string]string{}}
+	var err error
+	if r.dropMetrics, err = compileNames(config.DropMetrics, false); err != nil {
+		return nil, fmt.Errorf("invalid drop_metrics: %v", err)
+	}
+	if r.keepMetrics, err = compileNames(config.KeepMetrics, false); err != nil {
+		return nil, fmt.Errorf("invalid keep_metrics: %v", err)
+	}
+	if r.dropLabels, err = compileNames(config.DropLabels, false); err != nil {
+		return nil, fmt.Errorf("invalid drop_labels: %v", err)
+	}
+	if r.keepLabels, err = compileNames(config.ContainerLabels, config.ContainerLabels != nil); err != nil {
+		return nil, fmt.Errorf("invalid container_labels: %v", err)
+	}
+	if r.keepEnvs, err = compileNames(config.ContainerEnvs, config.ContainerEnvs != nil); err != nil {
+		return nil, fmt.Errorf("invalid container_envs: %v", err)
+	}
+	for from, to := range config.RenameLabels {
+		if sanitizeLabelName(to) != to || to == "" || strings.HasPrefix(to, "__") {
+			return nil, fmt.Errorf("invalid name %q to rename label %q to", to, from)
+		}
+		r.renameLabels[from] = to
+	}
+	return r, nil
+}
+
+// compileNames returns a regular expression matching any of the names, it is
+// nil if there are no names, unless matchNone is set.
+func compileNames(names []string, matchNone bool) (*regexp.Regexp, error) {
+	if len(names) == 0 {
+		if matchNone {
+			// Never matches.
+			return regexp.MustCompile(`a\A`), nil
+		}
+		return nil, nil
+	}
+	for _, name := range names {
+		if _, err := regexp.Compile(name); err != nil {
+			return nil, err
+		}
+	}
+	return regexp.Compile("^(?:" + strings.Join(names, "|") + ")$")
+}
+
+// KeepMetric returns true if the metric family with the name is exported.
+func (r *Relabeler) KeepMetric(name string) bool {
+	if r == nil {
+		return true
+	}
+	if r.keepMetrics != nil && !r.keepMetrics.MatchString(name) {
+		return false
+	}
+	return r.dropMetrics == nil || !r.dropMetrics.MatchString(name)
+}
+
+// containerLabels removes labels of the container which are not exported.
+func (r *Relabeler) containerLabels(labels map[string]string) map[string]string {
+	if r == nil {
+		return labels
+	}
+	filtered := make(map[string]string, len(labels))
+	for name, value := range labels {
+		sanitized := sanitizeLabelName(name)
+		if r.dropLabels != nil && r.dropLabels.MatchString(sanitized) {
+			continue
+		}
+		if r.keepLabels != nil && strings.HasPrefix(sanitized, ContainerLabelPrefix) &&
+			!r.keepLabels.MatchString(strings.TrimPrefix(sanitized, ContainerLabelPrefix)) {
+			continue
+		}
+		if r.keepEnvs != nil && strings.HasPrefix(sanitized, ContainerEnvPrefix) &&
+			!r.keepEnvs.MatchString(strings.TrimPrefix(sanitized, ContainerEnvPrefix)) {
+			continue
+		}
+		filtered[name] = value
+	}
+	return filtered
+}
+
+// labelName returns the name the label is exported with.
+func (r *Relabeler) labelName(name string) string {
+	if r == nil {
+		return name
+	}
+	if renamed, ok := r.renameLabels[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// labelNames returns names the labels are exported with.
+func (r *Relabeler) labelNames(names []string) []string {
+	if r == nil || len(r.renameLabels) == 0 {
+		return names
+	}
+	renamed := make([]string, len(names))
+	for i, name := range names {
+		renamed[i] = r.labelName(name)
+	}
+	return renamed
+}
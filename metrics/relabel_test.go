@@ -0,0 +1,140 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clonedInfoProvider returns the test container under two names.
+type clonedInfoProvider struct {
+	testSubcontainersInfoProvider
+}
+
+func (p clonedInfoProvider) GetRequestedContainersInfo(name string, opts v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	containers, err := p.testSubcontainersInfoProvider.GetRequestedContainersInfo(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	clone := *containers["testcontainer"]
+	clone.Name = "testcontainer2"
+	containers[clone.Name] = &clone
+	return containers, nil
+}
+
+func gatherRelabeled(t *testing.T, provider infoProvider, config string) map[string]*dto.MetricFamily {
+	relabelConfig, err := ParseRelabelConfig(strings.NewReader(config))
+	require.Nil(t, err)
+	relabeler, err := NewRelabeler(relabelConfig)
+	require.Nil(t, err)
+	c := NewPrometheusCollector(provider, DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{})
+	require.Nil(t, c.SetRelabeler(relabeler))
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	require.Nil(t, err)
+	gathered := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		gathered[family.GetName()] = family
+	}
+	return gathered
+}
+
+func labelPairs(metric *dto.Metric) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+func TestRelabelDropMetrics(t *testing.T) {
+	families := gatherRelabeled(t, testSubcontainersInfoProvider{}, `{"drop_metrics": ["container_memory_.*", "container_spec_cpu_period", "cadvisor_version_info"]}`)
+	assert.Contains(t, families, "container_cpu_usage_seconds_total")
+	assert.Contains(t, families, "container_spec_cpu_shares")
+	for name := range families {
+		assert.False(t, strings.HasPrefix(name, "container_memory_"), name)
+	}
+	assert.NotContains(t, families, "container_spec_cpu_period")
+	assert.NotContains(t, families, "cadvisor_version_info")
+}
+
+func TestRelabelKeepMetrics(t *testing.T) {
+	families := gatherRelabeled(t, testSubcontainersInfoProvider{}, `{"keep_metrics": ["container_cpu_.*"], "drop_metrics": ["container_cpu_load_.*"]}`)
+	assert.Contains(t, families, "container_cpu_usage_seconds_total")
+	for name := range families {
+		if name == "container_scrape_error" {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(name, "container_cpu_"), name)
+		assert.False(t, strings.HasPrefix(name, "container_cpu_load_"), name)
+	}
+}
+
+func TestRelabelLabels(t *testing.T) {
+	families := gatherRelabeled(t, testSubcontainersInfoProvider{}, `{
+		"drop_labels": ["image"],
+		"rename_labels": {"name": "container", "cpu": "core"},
+		"container_labels": [],
+		"container_envs": ["foo_.*"]
+	}`)
+	metrics := families["container_cpu_usage_seconds_total"].GetMetric()
+	require.NotEmpty(t, metrics)
+	assert.Equal(t, map[string]string{
+		"id":                    "testcontainer",
+		"container":             "testcontaineralias",
+		"container_env_foo_env": "prod",
+		"core":                  "cpu00",
+	}, labelPairs(metrics[0]))
+}
+
+func TestRelabelDuplicateContainers(t *testing.T) {
+	families := gatherRelabeled(t, clonedInfoProvider{}, `{}`)
+	assert.Len(t, families["container_last_seen"].GetMetric(), 2)
+
+	// Containers differ only by id.
+	families = gatherRelabeled(t, clonedInfoProvider{}, `{"drop_labels": ["id"]}`)
+	assert.Len(t, families["container_last_seen"].GetMetric(), 1)
+}
+
+func TestInvalidRelabelConfig(t *testing.T) {
+	_, err := ParseRelabelConfig(strings.NewReader(`{"drop_metric": ["container_.*"]}`))
+	assert.NotNil(t, err)
+
+	for _, config := range []RelabelConfig{
+		{DropMetrics: []string{"container_("}},
+		{ContainerEnvs: []string{"["}},
+		{RenameLabels: map[string]string{"name": "container-name"}},
+		{RenameLabels: map[string]string{"name": "__name__"}},
+	} {
+		_, err := NewRelabeler(&config)
+		assert.NotNil(t, err, "%+v", config)
+	}
+
+	relabeler, err := NewRelabeler(&RelabelConfig{RenameLabels: map[string]string{"name": "cpu"}})
+	require.Nil(t, err)
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, DefaultContainerLabels, container.AllMetrics, now, v2.RequestOptions{})
+	assert.NotNil(t, c.SetRelabeler(relabeler))
+}
@@ -18,8 +18,9 @@ package perf
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strconv"
+	"time"
 
 	"k8s.io/klog/v2"
 )
@@ -40,6 +41,35 @@ type Events struct {
 	// specify some events using their names and in such case you have
 	// to provide lower level configuration.
 	CustomEvents []CustomEvent `json:"custom_events"`
+
+	// Interval at which events are rotated on counters of PMUs when there
+	// are more events than counters, e.g. "4ms". Kernel default is used if
+	// not set.
+	MultiplexingInterval Duration `json:"multiplexing_interval,omitempty"`
+
+	// Sockets which uncore events are measured on, e.g. [0, 1]. All sockets
+	// are used if not set. It is ignored for core events.
+	Sockets []int `json:"sockets,omitempty"`
+}
+
+// Duration is time.Duration unmarshalled from string such as "10ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value string
+	err := json.Unmarshal(b, &value)
+	if err != nil {
+		return fmt.Errorf("unmarshalling %s into string failed: %q", b, err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("parsing %q into duration failed: %q", value, err)
+	}
+	if duration < time.Millisecond {
+		return fmt.Errorf("duration %q is shorter than 1ms", value)
+	}
+	*d = Duration(duration)
+	return nil
 }
 
 type Event string
@@ -80,11 +110,11 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-func parseConfig(file *os.File) (events PerfEvents, err error) {
-	decoder := json.NewDecoder(file)
+func parseConfig(reader io.Reader) (events PerfEvents, err error) {
+	decoder := json.NewDecoder(reader)
 	err = decoder.Decode(&events)
 	if err != nil {
-		err = fmt.Errorf("unable to load perf events configuration from %q: %q", file.Name(), err)
+		err = fmt.Errorf("unable to load perf events configuration: %q", err)
 		return
 	}
 	return
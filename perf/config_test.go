@@ -15,8 +15,10 @@
 package perf
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -37,6 +39,7 @@ func TestConfigParsing(t *testing.T) {
 	assert.Len(t, events.Core.Events[1].events, 1)
 	assert.Equal(t, false, events.Core.Events[1].array)
 	assert.Equal(t, Event("cycles"), events.Core.Events[1].events[0])
+	assert.Equal(t, Duration(4*time.Millisecond), events.Core.MultiplexingInterval)
 
 	assert.Len(t, events.Uncore.Events, 3)
 	assert.Equal(t, Event("cas_count_write"), events.Uncore.Events[0].events[0])
@@ -47,5 +50,14 @@ func TestConfigParsing(t *testing.T) {
 	assert.Equal(t, Config{0x5300}, events.Uncore.CustomEvents[0].Config)
 	assert.Equal(t, uint32(0x12), events.Uncore.CustomEvents[0].Type)
 	assert.Equal(t, Event("cas_count_write"), events.Uncore.CustomEvents[0].Name)
+	assert.Zero(t, events.Uncore.MultiplexingInterval)
 
 }
+
+func TestDurationUnmarshalInvalid(t *testing.T) {
+	for _, value := range []string{`4`, `"4"`, `"100us"`} {
+		var d Duration
+		err := json.Unmarshal([]byte(value), &d)
+		assert.NotNil(t, err, "value: %s", value)
+	}
+}
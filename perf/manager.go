@@ -0,0 +1,48 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Manager of perf events for containers.
+package perf
+
+import (
+	"fmt"
+
+	"github.com/google/cadvisor/stats"
+)
+
+// Manager is stats.Manager which configuration of perf events can be changed at runtime.
+type Manager interface {
+	stats.Manager
+
+	// Config returns JSON configuration of currently measured perf events.
+	Config() []byte
+
+	// Reload checks that perf events from JSON configuration can be measured on this
+	// machine and, unless dryRun is set, starts measuring them instead of current events.
+	Reload(config []byte, dryRun bool) error
+}
+
+// NoopManager is used when perf events are not measured.
+type NoopManager struct {
+	stats.NoopManager
+	reason string
+}
+
+func (m *NoopManager) Config() []byte {
+	return nil
+}
+
+func (m *NoopManager) Reload(config []byte, dryRun bool) error {
+	return fmt.Errorf("perf events configuration cannot be reloaded: %s", m.reason)
+}
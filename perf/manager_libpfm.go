@@ -18,36 +18,49 @@
 package perf
 
 import (
+	"bytes"
 	"fmt"
-	"os"
+	"io/ioutil"
+	"sync"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/stats"
 	"github.com/google/cadvisor/utils/sysinfo"
+
+	"k8s.io/klog/v2"
 )
 
 type manager struct {
+	// Lock protects events, config and generation which are replaced on reload.
+	lock        sync.RWMutex
 	events      PerfEvents
+	config      []byte
+	generation  uint64
 	onlineCPUs  []int
 	cpuToSocket map[int]int
 	stats.NoopDestroy
 }
 
-func NewManager(configFile string, topology []info.Node) (stats.Manager, error) {
+func NewManager(configFile string, topology []info.Node) (Manager, error) {
 	if configFile == "" {
-		return &stats.NoopManager{}, nil
+		return &NoopManager{reason: "perf events configuration file is not set"}, nil
 	}
 
-	file, err := os.Open(configFile)
+	config, err := ioutil.ReadFile(configFile)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read configuration file %q: %w", configFile, err)
 	}
 
-	config, err := parseConfig(file)
+	events, err := parseConfig(bytes.NewReader(config))
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse configuration file %q: %w", configFile, err)
 	}
 
+	err = setMultiplexingIntervals(eventSourceDevicesPath, events)
+	if err != nil {
+		klog.Warningf("Unable to set multiplexing interval of perf events, kernel default is used: %v", err)
+	}
+
 	onlineCPUs := sysinfo.GetOnlineCPUs(topology)
 
 	cpuToSocket := make(map[int]int)
@@ -56,15 +69,99 @@ func NewManager(configFile string, topology []info.Node) (stats.Manager, error)
 		cpuToSocket[cpu] = sysinfo.GetSocketFromCPU(topology, cpu)
 	}
 
-	return &manager{events: config, onlineCPUs: onlineCPUs, cpuToSocket: cpuToSocket}, nil
+	return &manager{events: events, config: config, onlineCPUs: onlineCPUs, cpuToSocket: cpuToSocket}, nil
 }
 
+// GetCollector returns collector which is set up again with new events after
+// configuration is reloaded. Collector is returned even if setup failed, so that
+// it can be retried with another configuration.
 func (m *manager) GetCollector(cgroupPath string) (stats.Collector, error) {
+	collector := &reloadableCollector{manager: m, cgroupPath: cgroupPath}
+	var err error
+	collector.collector, collector.generation, err = m.newCollector(cgroupPath)
+	return collector, err
+}
+
+func (m *manager) newCollector(cgroupPath string) (stats.Collector, uint64, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
 	collector := newCollector(cgroupPath, m.events, m.onlineCPUs, m.cpuToSocket)
 	err := collector.setup()
 	if err != nil {
 		collector.Destroy()
-		return &stats.NoopCollector{}, err
+		return &stats.NoopCollector{}, m.generation, err
+	}
+	return collector, m.generation, nil
+}
+
+func (m *manager) currentGeneration() uint64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.generation
+}
+
+func (m *manager) Config() []byte {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.config
+}
+
+func (m *manager) Reload(config []byte, dryRun bool) error {
+	events, err := parseConfig(bytes.NewReader(config))
+	if err != nil {
+		return err
 	}
-	return collector, nil
+	err = validateEvents(events, m.onlineCPUs, m.cpuToSocket)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	err = setMultiplexingIntervals(eventSourceDevicesPath, events)
+	if err != nil {
+		klog.Warningf("Unable to set multiplexing interval of perf events, kernel default is used: %v", err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.events = events
+	m.config = config
+	m.generation++
+	klog.Infof("Perf events configuration reloaded")
+	return nil
+}
+
+// reloadableCollector sets up perf events of the cgroup again when configuration
+// of the manager was reloaded since the previous update.
+type reloadableCollector struct {
+	manager    *manager
+	cgroupPath string
+
+	lock       sync.Mutex
+	collector  stats.Collector
+	generation uint64
+}
+
+func (c *reloadableCollector) UpdateStats(stats *info.ContainerStats) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.generation != c.manager.currentGeneration() {
+		c.collector.Destroy()
+		var err error
+		c.collector, c.generation, err = c.manager.newCollector(c.cgroupPath)
+		if err != nil {
+			return fmt.Errorf("unable to set up reloaded perf events for %q: %v", c.cgroupPath, err)
+		}
+	}
+	return c.collector.UpdateStats(stats)
+}
+
+func (c *reloadableCollector) Destroy() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.collector.Destroy()
 }
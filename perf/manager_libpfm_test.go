@@ -23,15 +23,15 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	info "github.com/google/cadvisor/info/v1"
-	"github.com/google/cadvisor/stats"
 )
 
 func TestNoConfigFilePassed(t *testing.T) {
 	manager, err := NewManager("", []info.Node{})
 
 	assert.Nil(t, err)
-	_, ok := manager.(*stats.NoopManager)
+	_, ok := manager.(*NoopManager)
 	assert.True(t, ok)
+	assert.NotNil(t, manager.Reload([]byte("{}"), true))
 }
 
 func TestNonExistentFile(t *testing.T) {
@@ -55,3 +55,14 @@ func TestNewManager(t *testing.T) {
 	_, ok := managerInstance.(*manager)
 	assert.True(t, ok)
 }
+
+func TestReloadMalformedConfig(t *testing.T) {
+	managerInstance, err := NewManager("testing/perf.json", []info.Node{})
+	assert.Nil(t, err)
+	config := managerInstance.Config()
+
+	err = managerInstance.Reload([]byte(`{"core": {"events": [`), false)
+	assert.NotNil(t, err)
+	assert.Equal(t, config, managerInstance.Config())
+	assert.Equal(t, uint64(0), managerInstance.(*manager).currentGeneration())
+}
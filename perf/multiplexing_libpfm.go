@@ -0,0 +1,77 @@
+// +build libpfm,cgo
+
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	eventSourceDevicesPath = "/sys/bus/event_source/devices"
+	muxIntervalFile        = "perf_event_mux_interval_ms"
+)
+
+// Core PMUs, hybrid CPUs expose separate PMU for each type of cores.
+var corePMUs = []string{"cpu", "cpu_core", "cpu_atom"}
+
+// setMultiplexingIntervals sets multiplexing interval of core and uncore PMUs
+// present in devicesPath to values configured for core and uncore events.
+func setMultiplexingIntervals(devicesPath string, events PerfEvents) error {
+	if events.Core.MultiplexingInterval != 0 {
+		for _, name := range corePMUs {
+			err := setMultiplexingInterval(filepath.Join(devicesPath, name), time.Duration(events.Core.MultiplexingInterval))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	if events.Uncore.MultiplexingInterval != 0 {
+		pmus, err := ioutil.ReadDir(devicesPath)
+		if err != nil {
+			return err
+		}
+		for _, pmu := range pmus {
+			if !strings.HasPrefix(pmu.Name(), "uncore_") {
+				continue
+			}
+			err = setMultiplexingInterval(filepath.Join(devicesPath, pmu.Name()), time.Duration(events.Uncore.MultiplexingInterval))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setMultiplexingInterval(pmuPath string, interval time.Duration) error {
+	if _, err := os.Stat(pmuPath); err != nil {
+		return err
+	}
+	intervalFile := filepath.Join(pmuPath, muxIntervalFile)
+	value := strconv.FormatInt(int64(interval/time.Millisecond), 10)
+	err := ioutil.WriteFile(intervalFile, []byte(value), 0644)
+	if err != nil {
+		return fmt.Errorf("unable to set multiplexing interval in %s: %w", intervalFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// +build libpfm,cgo
+
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMultiplexingIntervals(t *testing.T) {
+	path, err := ioutil.TempDir("", "cadvisor_perf_test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(path)
+
+	for _, pmu := range []string{"cpu", "uncore_imc_0", "uncore_imc_1", "msr"} {
+		err = os.Mkdir(filepath.Join(path, pmu), 0755)
+		assert.Nil(t, err)
+		err = ioutil.WriteFile(filepath.Join(path, pmu, muxIntervalFile), []byte("4"), 0644)
+		assert.Nil(t, err)
+	}
+
+	events := PerfEvents{
+		Core:   Events{MultiplexingInterval: Duration(10 * time.Millisecond)},
+		Uncore: Events{MultiplexingInterval: Duration(time.Second)},
+	}
+	err = setMultiplexingIntervals(path, events)
+	assert.Nil(t, err)
+
+	for pmu, expected := range map[string]string{
+		"cpu":          "10",
+		"uncore_imc_0": "1000",
+		"uncore_imc_1": "1000",
+		"msr":          "4",
+	} {
+		value, err := ioutil.ReadFile(filepath.Join(path, pmu, muxIntervalFile))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(value), "pmu: %s", pmu)
+	}
+}
+
+func TestSetMultiplexingIntervalsNotConfigured(t *testing.T) {
+	err := setMultiplexingIntervals("testing/missing", PerfEvents{})
+	assert.Nil(t, err)
+}
@@ -53,6 +53,7 @@ const (
 	pmuCpumaskFilename = "cpumask"
 	systemDevicesPath  = "/sys/devices"
 	rootPerfEventPath  = "/sys/fs/cgroup/perf_event"
+	rootUnifiedPath    = "/sys/fs/cgroup"
 	uncorePID          = -1
 )
 
@@ -138,7 +139,7 @@ type uncoreCollector struct {
 
 func NewUncoreCollector(cgroupPath string, events PerfEvents, cpuToSocket map[int]int) stats.Collector {
 
-	if cgroupPath != rootPerfEventPath {
+	if cgroupPath != rootPerfEventPath && cgroupPath != rootUnifiedPath {
 		// Uncore metric doesn't exists for cgroups, only for entire platform.
 		return &stats.NoopCollector{}
 	}
@@ -164,6 +165,7 @@ func (c *uncoreCollector) setup(events PerfEvents, devicesPath string) error {
 	if err != nil {
 		return err
 	}
+	readUncorePMUs = filterPMUsBySockets(readUncorePMUs, events.Uncore.Sockets, c.cpuToSocket)
 
 	c.cpuFiles = make(map[int]map[string]group)
 	c.events = events.Uncore.Events
@@ -227,6 +229,34 @@ func (c *uncoreCollector) setup(events PerfEvents, devicesPath string) error {
 	return nil
 }
 
+// filterPMUsBySockets leaves only CPUs located on given sockets in PMUs' cpumasks
+// and drops PMUs without any CPU left. PMUs are returned unchanged if sockets are empty.
+func filterPMUsBySockets(pmus uncorePMUs, sockets []int, cpuToSocket map[int]int) uncorePMUs {
+	if len(sockets) == 0 {
+		return pmus
+	}
+	included := make(map[int]bool, len(sockets))
+	for _, socket := range sockets {
+		included[socket] = true
+	}
+
+	filtered := make(uncorePMUs, len(pmus))
+	for name, pmu := range pmus {
+		var cpus []uint32
+		for _, cpu := range pmu.cpus {
+			if socket, ok := cpuToSocket[int(cpu)]; ok && included[socket] {
+				cpus = append(cpus, cpu)
+			}
+		}
+		if len(cpus) == 0 {
+			continue
+		}
+		pmu.cpus = cpus
+		filtered[name] = pmu
+	}
+	return filtered
+}
+
 func checkGroup(group Group, eventPMUs map[Event]uncorePMUs) error {
 	if group.array {
 		var pmu uncorePMUs
@@ -176,6 +176,28 @@ func TestObtainPMUs(t *testing.T) {
 	assert.Equal(t, uncorePMUs{}, actual)
 }
 
+func TestFilterPMUsBySockets(t *testing.T) {
+	pmus := uncorePMUs{
+		"uncore_imc_0": {name: "uncore_imc_0", typeOf: 18, cpus: []uint32{0, 1}},
+		"uncore_upi_0": {name: "uncore_upi_0", typeOf: 20, cpus: []uint32{1}},
+	}
+	cpuToSocket := map[int]int{0: 0, 1: 1}
+
+	actual := filterPMUsBySockets(pmus, nil, cpuToSocket)
+	assert.Equal(t, pmus, actual)
+
+	actual = filterPMUsBySockets(pmus, []int{0}, cpuToSocket)
+	assert.Equal(t, uncorePMUs{
+		"uncore_imc_0": {name: "uncore_imc_0", typeOf: 18, cpus: []uint32{0}},
+	}, actual)
+
+	actual = filterPMUsBySockets(pmus, []int{1}, cpuToSocket)
+	assert.Equal(t, uncorePMUs{
+		"uncore_imc_0": {name: "uncore_imc_0", typeOf: 18, cpus: []uint32{1}},
+		"uncore_upi_0": {name: "uncore_upi_0", typeOf: 20, cpus: []uint32{1}},
+	}, actual)
+}
+
 func TestUncoreParseEventName(t *testing.T) {
 	eventName, pmuPrefix := parseEventName("some_event")
 	assert.Equal(t, "some_event", eventName)
@@ -0,0 +1,60 @@
+// +build libpfm,cgo
+
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Validation of perf events configuration.
+package perf
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/google/cadvisor/stats"
+)
+
+// validateEvents checks that events can be measured on this machine by opening them
+// for the root cgroup and closing them right away. Opening fails when event is not
+// supported by the CPU or when group has more events than PMU has counters.
+func validateEvents(events PerfEvents, onlineCPUs []int, cpuToSocket map[int]int) error {
+	rootPath := rootPerfEventPath
+	if _, err := os.Stat(rootPath); err != nil {
+		rootPath = rootUnifiedPath
+	}
+
+	core := &collector{cgroupPath: rootPath, events: events, onlineCPUs: onlineCPUs, cpuFiles: map[int]group{}, uncore: &stats.NoopCollector{}}
+	mapEventsToCustomEvents(core)
+	defer core.Destroy()
+	err := core.setup()
+	if err != nil {
+		return fmt.Errorf("core events cannot be measured, event may be unsupported by the CPU or group may not fit into counters: %v", err)
+	}
+
+	if len(events.Uncore.Events) == 0 {
+		return nil
+	}
+	uncore := &uncoreCollector{
+		cpuToSocket:   cpuToSocket,
+		perfEventOpen: unix.PerfEventOpen,
+		ioctlSetInt:   unix.IoctlSetInt,
+	}
+	defer uncore.Destroy()
+	err = uncore.setup(events, systemDevicesPath)
+	if err != nil {
+		return fmt.Errorf("uncore events cannot be measured, event may be unsupported by the PMU or group may not fit into counters: %v", err)
+	}
+	return nil
+}
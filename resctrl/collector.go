@@ -18,57 +18,72 @@
 package resctrl
 
 import (
+	"os"
+	"path/filepath"
+
 	info "github.com/google/cadvisor/info/v1"
-	"github.com/google/cadvisor/stats"
 
-	"github.com/opencontainers/runc/libcontainer/configs"
-	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"k8s.io/klog/v2"
 )
 
 type collector struct {
-	resctrl intelrdt.IntelRdtManager
-	stats.NoopDestroy
+	// Path of resctrl group which mon_data is read from.
+	groupPath string
+	// Monitoring group is created by collector, processes of the container are
+	// assigned to it on every update and it is removed on destroy.
+	monGroup         bool
+	getContainerPids func() ([]int, error)
+	domainToNode     map[int]int
 }
 
-func newCollector(id string, resctrlPath string) *collector {
-	collector := &collector{
-		resctrl: intelrdt.IntelRdtManager{
-			Config: &configs.Config{
-				IntelRdt: &configs.IntelRdt{},
-			},
-			Id:   id,
-			Path: resctrlPath,
-		},
+func newCollector(rootPath string, containerName string, getContainerPids func() ([]int, error), domainToNode map[int]int) (*collector, error) {
+	c := &collector{getContainerPids: getContainerPids, domainToNode: domainToNode}
+
+	// Root container is monitored with default group and container which
+	// already has control group (e.g. created by runc) is monitored with it.
+	controlGroupPath := filepath.Join(rootPath, containerName)
+	if _, err := os.Stat(filepath.Join(controlGroupPath, monDataDir)); err == nil {
+		c.groupPath = controlGroupPath
+		return c, nil
 	}
 
-	return collector
+	groupPath, err := createMonGroup(rootPath, monGroupName(containerName))
+	if err != nil {
+		return nil, err
+	}
+	c.groupPath = groupPath
+	c.monGroup = true
+	return c, nil
 }
 
 func (c *collector) UpdateStats(stats *info.ContainerStats) error {
 	stats.Resctrl = info.ResctrlStats{}
 
-	resctrlStats, err := c.resctrl.GetStats()
+	if c.monGroup {
+		pids, err := c.getContainerPids()
+		if err != nil {
+			return err
+		}
+		err = assignPids(c.groupPath, pids)
+		if err != nil {
+			return err
+		}
+	}
+
+	resctrlStats, err := readMonData(c.groupPath, c.domainToNode)
 	if err != nil {
 		return err
 	}
+	stats.Resctrl = resctrlStats
+	return nil
+}
 
-	numberOfNUMANodes := len(*resctrlStats.MBMStats)
-
-	stats.Resctrl.MemoryBandwidth = make([]info.MemoryBandwidthStats, 0, numberOfNUMANodes)
-	stats.Resctrl.Cache = make([]info.CacheStats, 0, numberOfNUMANodes)
-
-	for _, numaNodeStats := range *resctrlStats.MBMStats {
-		stats.Resctrl.MemoryBandwidth = append(stats.Resctrl.MemoryBandwidth,
-			info.MemoryBandwidthStats{
-				TotalBytes: numaNodeStats.MBMTotalBytes,
-				LocalBytes: numaNodeStats.MBMLocalBytes,
-			})
+func (c *collector) Destroy() {
+	if !c.monGroup {
+		return
 	}
-
-	for _, numaNodeStats := range *resctrlStats.CMTStats {
-		stats.Resctrl.Cache = append(stats.Resctrl.Cache,
-			info.CacheStats{LLCOccupancy: numaNodeStats.LLCOccupancy})
+	err := os.Remove(c.groupPath)
+	if err != nil {
+		klog.Warningf("Unable to remove resctrl monitoring group %q: %v", c.groupPath, err)
 	}
-
-	return nil
 }
@@ -18,31 +18,68 @@
 package resctrl
 
 import (
-	"os"
-
 	"github.com/google/cadvisor/stats"
 
-	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"k8s.io/klog/v2"
 )
 
+// Manager creates resctrl collectors for containers. Unlike stats.Manager it
+// needs processes of the container to assign them to monitoring group.
+type Manager interface {
+	Destroy()
+	GetCollector(containerName string, getContainerPids func() ([]int, error)) (stats.Collector, error)
+}
+
 type manager struct {
-	id string
+	rootPath     string
+	domainToNode map[int]int
 	stats.NoopDestroy
 }
 
-func (m manager) GetCollector(resctrlPath string) (stats.Collector, error) {
-	if _, err := os.Stat(resctrlPath); err != nil {
+func (m *manager) GetCollector(containerName string, getContainerPids func() ([]int, error)) (stats.Collector, error) {
+	collector, err := newCollector(m.rootPath, containerName, getContainerPids, m.domainToNode)
+	if err != nil {
 		return &stats.NoopCollector{}, err
 	}
-	collector := newCollector(m.id, resctrlPath)
 	return collector, nil
 }
 
-func NewManager(id string) (stats.Manager, error) {
+func NewManager() (Manager, error) {
+	rootPath, err := findRootPath()
+	if err != nil {
+		return &NoopManager{}, err
+	}
+	// Monitoring features are listed the same way for Intel RDT and AMD PQoS.
+	features, err := readMonFeatures(rootPath)
+	if err != nil {
+		return &NoopManager{}, err
+	}
+	if len(features) == 0 {
+		return &NoopManager{}, nil
+	}
+	klog.V(1).Infof("resctrl monitoring features: %v", features)
 
-	if intelrdt.IsMBMEnabled() || intelrdt.IsCMTEnabled() {
-		return &manager{id: id}, nil
+	domainToNode, err := getL3DomainToNode(cpusPath)
+	if err != nil {
+		klog.Warningf("Unable to map L3 cache domains to NUMA nodes, resctrl stats are reported per L3 cache domain: %v", err)
+		domainToNode = nil
 	}
 
-	return &stats.NoopManager{}, nil
+	// Monitoring groups left by previous instance of cAdvisor hold RMIDs which are
+	// a scarce resource, so they are removed before any new group is created.
+	err = removeStaleMonGroups(rootPath)
+	if err != nil {
+		klog.Warningf("Unable to remove resctrl monitoring groups created by previous cAdvisor instance: %v", err)
+	}
+
+	return &manager{rootPath: rootPath, domainToNode: domainToNode}, nil
+}
+
+// NoopManager is used when resctrl monitoring is not supported.
+type NoopManager struct {
+	stats.NoopDestroy
+}
+
+func (m *NoopManager) GetCollector(string, func() ([]int, error)) (stats.Collector, error) {
+	return &stats.NoopCollector{}, nil
 }
@@ -0,0 +1,330 @@
+// +build linux
+
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Utilities for resctrl monitoring groups.
+package resctrl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+	mount "k8s.io/mount-utils"
+)
+
+const (
+	monGroupsDir      = "mon_groups"
+	monDataDir        = "mon_data"
+	monL3Prefix       = "mon_L3_"
+	tasksFile         = "tasks"
+	llcOccupancyFile  = "llc_occupancy"
+	mbmTotalBytesFile = "mbm_total_bytes"
+	mbmLocalBytesFile = "mbm_local_bytes"
+	monFeaturesFile   = "info/L3_MON/mon_features"
+	resctrlFsType     = "resctrl"
+
+	// Value read from mon_data when hardware was not able to count it, e.g. right
+	// after the group was created on AMD or when RMID was reused too early on Intel.
+	unavailableValue = "Unavailable"
+
+	// Prefix of monitoring groups created by cAdvisor.
+	monGroupPrefix = "cadvisor-"
+)
+
+// Handles for mocking purposes.
+var (
+	procPath      = "/proc"
+	mountInfoPath = "/proc/self/mountinfo"
+	cpusPath      = "/sys/devices/system/cpu"
+)
+
+// findRootPath returns mountpoint of resctrl filesystem. Resctrl is used by
+// Intel RDT as well as by AMD Platform Quality of Service.
+func findRootPath() (string, error) {
+	mounts, err := mount.ParseMountInfo(mountInfoPath)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range mounts {
+		if m.FsType == resctrlFsType {
+			return m.MountPoint, nil
+		}
+	}
+	return "", fmt.Errorf("resctrl filesystem is not mounted")
+}
+
+// readMonFeatures returns names of monitoring events supported by the platform,
+// e.g. llc_occupancy, mbm_total_bytes and mbm_local_bytes.
+func readMonFeatures(rootPath string) ([]string, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(rootPath, monFeaturesFile))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(buf)), nil
+}
+
+// getL3DomainToNode maps ids of L3 cache domains to NUMA nodes. There is one
+// domain per socket on Intel while on AMD there is one domain per CCX (group of
+// cores sharing L3 cache), so several domains belong to single NUMA node. Nil
+// is returned when a domain spans several NUMA nodes, e.g. with Sub-NUMA Clustering.
+func getL3DomainToNode(cpusPath string) (map[int]int, error) {
+	cpus, err := filepath.Glob(filepath.Join(cpusPath, "cpu[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	domainToNode := map[int]int{}
+	for _, cpu := range cpus {
+		nodes, err := filepath.Glob(filepath.Join(cpu, "node[0-9]*"))
+		if err != nil {
+			return nil, err
+		}
+		// Offline CPUs have no cache information.
+		id, ok, err := readL3CacheID(cpu)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) != 1 || !ok {
+			continue
+		}
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(nodes[0]), "node"))
+		if err != nil {
+			return nil, err
+		}
+		if previous, ok := domainToNode[id]; ok && previous != node {
+			return nil, nil
+		}
+		domainToNode[id] = node
+	}
+	if len(domainToNode) == 0 {
+		return nil, nil
+	}
+	return domainToNode, nil
+}
+
+func readL3CacheID(cpuPath string) (int, bool, error) {
+	caches, err := filepath.Glob(filepath.Join(cpuPath, "cache", "index[0-9]*"))
+	if err != nil {
+		return 0, false, err
+	}
+	for _, cache := range caches {
+		level, err := ioutil.ReadFile(filepath.Join(cache, "level"))
+		if err != nil {
+			return 0, false, err
+		}
+		if strings.TrimSpace(string(level)) != "3" {
+			continue
+		}
+		id, err := ioutil.ReadFile(filepath.Join(cache, "id"))
+		if err != nil {
+			return 0, false, err
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(string(id)))
+		if err != nil {
+			return 0, false, err
+		}
+		return value, true, nil
+	}
+	return 0, false, nil
+}
+
+// monGroupName returns name of monitoring group of the container, e.g.
+// "cadvisor-docker-1a2b" for "/docker/1a2b".
+func monGroupName(containerName string) string {
+	return monGroupPrefix + strings.ReplaceAll(strings.Trim(containerName, "/"), "/", "-")
+}
+
+// createMonGroup creates monitoring group in default control group, existing group is reused.
+func createMonGroup(rootPath string, name string) (string, error) {
+	path := filepath.Join(rootPath, monGroupsDir, name)
+	err := os.Mkdir(path, 0755)
+	if err != nil && !os.IsExist(err) {
+		if errors.Is(err, syscall.ENOSPC) {
+			return "", fmt.Errorf("no free RMID to create resctrl monitoring group %q", path)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// removeStaleMonGroups removes monitoring groups created by cAdvisor.
+func removeStaleMonGroups(rootPath string) error {
+	paths, err := filepath.Glob(filepath.Join(rootPath, monGroupsDir, monGroupPrefix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		// Removing directory of monitoring group moves its tasks to the parent group.
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTasks(groupPath string) (map[string]struct{}, error) {
+	file, err := os.Open(filepath.Join(groupPath, tasksFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tasks := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		tasks[strings.TrimSpace(scanner.Text())] = struct{}{}
+	}
+	return tasks, scanner.Err()
+}
+
+// assignPids moves all threads of given processes to the group, threads which are
+// already assigned are skipped. Resctrl assigns threads, not processes, and newly
+// created threads inherit group of their parent.
+func assignPids(groupPath string, pids []int) error {
+	tasks, err := readTasks(groupPath)
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range pids {
+		threads, err := ioutil.ReadDir(filepath.Join(procPath, strconv.Itoa(pid), "task"))
+		if err != nil {
+			// Process exited.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for _, thread := range threads {
+			if _, ok := tasks[thread.Name()]; ok {
+				continue
+			}
+			err = ioutil.WriteFile(filepath.Join(groupPath, tasksFile), []byte(thread.Name()), 0644)
+			if err != nil && !errors.Is(err, syscall.ESRCH) {
+				return fmt.Errorf("unable to assign thread %s to resctrl group %q: %v", thread.Name(), groupPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+func readMonValue(path string) (uint64, bool, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	value := strings.TrimSpace(string(buf))
+	if value == unavailableValue {
+		klog.V(4).Infof("Value of %q is not available", path)
+		return 0, true, nil
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to parse %q: %v", path, err)
+	}
+	return parsed, true, nil
+}
+
+type monValues struct {
+	llcOccupancy uint64
+	totalBytes   uint64
+	localBytes   uint64
+	hasCache     bool
+	hasBandwidth bool
+}
+
+// readMonData reads cache occupancy and memory bandwidth of the group, one value for each L3 cache
+// domain. Values of domains are summed per NUMA node if domainToNode is set.
+func readMonData(groupPath string, domainToNode map[int]int) (info.ResctrlStats, error) {
+	stats := info.ResctrlStats{}
+	domains, err := ioutil.ReadDir(filepath.Join(groupPath, monDataDir))
+	if err != nil {
+		return stats, err
+	}
+
+	values := map[int]*monValues{}
+	for _, domain := range domains {
+		if !strings.HasPrefix(domain.Name(), monL3Prefix) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(domain.Name(), monL3Prefix))
+		if err != nil {
+			return stats, fmt.Errorf("unable to parse id of L3 domain %q: %v", domain.Name(), err)
+		}
+		if domainToNode != nil {
+			node, ok := domainToNode[id]
+			if !ok {
+				return stats, fmt.Errorf("NUMA node of L3 domain %d is unknown", id)
+			}
+			id = node
+		}
+		if _, ok := values[id]; !ok {
+			values[id] = &monValues{}
+		}
+		domainPath := filepath.Join(groupPath, monDataDir, domain.Name())
+
+		llcOccupancy, ok, err := readMonValue(filepath.Join(domainPath, llcOccupancyFile))
+		if err != nil {
+			return stats, err
+		}
+		values[id].llcOccupancy += llcOccupancy
+		values[id].hasCache = values[id].hasCache || ok
+
+		totalBytes, ok, err := readMonValue(filepath.Join(domainPath, mbmTotalBytesFile))
+		if err != nil {
+			return stats, err
+		}
+		localBytes, _, err := readMonValue(filepath.Join(domainPath, mbmLocalBytesFile))
+		if err != nil {
+			return stats, err
+		}
+		values[id].totalBytes += totalBytes
+		values[id].localBytes += localBytes
+		values[id].hasBandwidth = values[id].hasBandwidth || ok
+	}
+
+	ids := make([]int, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if values[id].hasCache {
+			stats.Cache = append(stats.Cache, info.CacheStats{LLCOccupancy: values[id].llcOccupancy})
+		}
+		if values[id].hasBandwidth {
+			stats.MemoryBandwidth = append(stats.MemoryBandwidth, info.MemoryBandwidthStats{
+				TotalBytes: values[id].totalBytes,
+				LocalBytes: values[id].localBytes,
+			})
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,174 @@
+// +build linux
+
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resctrl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonGroupName(t *testing.T) {
+	assert.Equal(t, "cadvisor-docker-1a2b", monGroupName("/docker/1a2b"))
+	assert.Equal(t, "cadvisor-kubepods.slice-pod1.slice", monGroupName("/kubepods.slice/pod1.slice"))
+}
+
+func TestReadMonData(t *testing.T) {
+	stats, err := readMonData("testdata/resctrl", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, info.ResctrlStats{
+		MemoryBandwidth: []info.MemoryBandwidthStats{
+			{TotalBytes: 4512312, LocalBytes: 2390393},
+			{TotalBytes: 2173713, LocalBytes: 1231233},
+		},
+		Cache: []info.CacheStats{
+			{LLCOccupancy: 162626},
+			{LLCOccupancy: 213777},
+		},
+	}, stats)
+
+	_, err = readMonData("testdata/missing", nil)
+	assert.NotNil(t, err)
+}
+
+func TestReadMonDataPerNUMANode(t *testing.T) {
+	// Two CCXs per NUMA node, counters of the last one are not available yet.
+	stats, err := readMonData("testdata/resctrl_amd", map[int]int{0: 0, 1: 0, 2: 1, 3: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, info.ResctrlStats{
+		MemoryBandwidth: []info.MemoryBandwidthStats{
+			{TotalBytes: 4512312, LocalBytes: 2390393},
+			{TotalBytes: 2173713, LocalBytes: 1231233},
+		},
+		Cache: []info.CacheStats{
+			{LLCOccupancy: 162626},
+			{LLCOccupancy: 213777},
+		},
+	}, stats)
+
+	_, err = readMonData("testdata/resctrl_amd", map[int]int{0: 0})
+	assert.NotNil(t, err)
+}
+
+func TestGetL3DomainToNode(t *testing.T) {
+	domainToNode, err := getL3DomainToNode("testdata/cpu")
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]int{0: 0, 1: 0, 2: 1, 3: 1}, domainToNode)
+
+	domainToNode, err = getL3DomainToNode("testdata/missing")
+	assert.Nil(t, err)
+	assert.Nil(t, domainToNode)
+}
+
+func TestGetL3DomainToNodeSpanningNodes(t *testing.T) {
+	cpusPath, err := ioutil.TempDir("", "cadvisor_resctrl_test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(cpusPath)
+
+	// Single L3 domain shared by two NUMA nodes, like with Sub-NUMA Clustering.
+	for cpu, node := range []string{"node0", "node1"} {
+		cachePath := filepath.Join(cpusPath, fmt.Sprintf("cpu%d", cpu), "cache", "index3")
+		err = os.MkdirAll(cachePath, 0755)
+		assert.Nil(t, err)
+		err = os.Mkdir(filepath.Join(cpusPath, fmt.Sprintf("cpu%d", cpu), node), 0755)
+		assert.Nil(t, err)
+		err = ioutil.WriteFile(filepath.Join(cachePath, "level"), []byte("3\n"), 0644)
+		assert.Nil(t, err)
+		err = ioutil.WriteFile(filepath.Join(cachePath, "id"), []byte("0\n"), 0644)
+		assert.Nil(t, err)
+	}
+
+	domainToNode, err := getL3DomainToNode(cpusPath)
+	assert.Nil(t, err)
+	assert.Nil(t, domainToNode)
+}
+
+func TestFindRootPath(t *testing.T) {
+	mountInfoPath = "testdata/mountinfo"
+	defer func() {
+		mountInfoPath = "/proc/self/mountinfo"
+	}()
+
+	rootPath, err := findRootPath()
+	assert.Nil(t, err)
+	assert.Equal(t, "/sys/fs/resctrl", rootPath)
+}
+
+func TestReadMonFeatures(t *testing.T) {
+	features, err := readMonFeatures("testdata/resctrl")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"llc_occupancy", "mbm_total_bytes", "mbm_local_bytes"}, features)
+}
+
+func TestMonGroupLifecycle(t *testing.T) {
+	rootPath, err := ioutil.TempDir("", "cadvisor_resctrl_test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootPath)
+	err = os.Mkdir(filepath.Join(rootPath, monGroupsDir), 0755)
+	assert.Nil(t, err)
+	err = os.Mkdir(filepath.Join(rootPath, monGroupsDir, "other"), 0755)
+	assert.Nil(t, err)
+
+	path, err := createMonGroup(rootPath, monGroupName("/docker/1a2b"))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(rootPath, monGroupsDir, "cadvisor-docker-1a2b"), path)
+
+	// Existing group is reused.
+	_, err = createMonGroup(rootPath, monGroupName("/docker/1a2b"))
+	assert.Nil(t, err)
+
+	err = removeStaleMonGroups(rootPath)
+	assert.Nil(t, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(rootPath, monGroupsDir, "other"))
+	assert.Nil(t, err)
+}
+
+func TestAssignPids(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "cadvisor_resctrl_test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tmp)
+
+	procPath = filepath.Join(tmp, "proc")
+	defer func() {
+		procPath = "/proc"
+	}()
+	for _, task := range []string{"100/task/100", "100/task/101"} {
+		err = os.MkdirAll(filepath.Join(procPath, task), 0755)
+		assert.Nil(t, err)
+	}
+
+	groupPath := filepath.Join(tmp, "group")
+	err = os.Mkdir(groupPath, 0755)
+	assert.Nil(t, err)
+	err = ioutil.WriteFile(filepath.Join(groupPath, tasksFile), []byte("100\n"), 0644)
+	assert.Nil(t, err)
+
+	// Only thread which is not assigned yet is written, process 200 does not exist.
+	err = assignPids(groupPath, []int{100, 200})
+	assert.Nil(t, err)
+	tasks, err := ioutil.ReadFile(filepath.Join(groupPath, tasksFile))
+	assert.Nil(t, err)
+	assert.Equal(t, "101", string(tasks))
+}
@@ -76,3 +76,11 @@ func (provider) GetInstanceType() info.InstanceType {
 func (provider) GetInstanceID() info.InstanceID {
 	return info.InstanceID(getAwsMetadata("instance-id"))
 }
+
+func (provider) GetRegion() string {
+	region := getAwsMetadata("placement/region")
+	if region == info.UnknownInstance {
+		return ""
+	}
+	return region
+}
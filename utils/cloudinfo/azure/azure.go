@@ -15,17 +15,23 @@
 package cloudinfo
 
 import (
+	"fmt"
 	"io/ioutil"
 	"strings"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils/cloudinfo"
+
+	"k8s.io/klog/v2"
 )
 
 const (
 	sysVendorFileName    = "/sys/class/dmi/id/sys_vendor"
 	biosUUIDFileName     = "/sys/class/dmi/id/product_uuid"
 	microsoftCorporation = "Microsoft Corporation"
+
+	// Azure Instance Metadata Service, see https://docs.microsoft.com/en-us/azure/virtual-machines/linux/instance-metadata-service
+	instanceMetadataURL = "http://169.254.169.254/metadata/instance/compute/%s?api-version=2020-09-01&format=text"
 )
 
 func init() {
@@ -46,7 +52,14 @@ func (provider) IsActiveProvider() bool {
 
 // TODO: Implement method.
 func (provider) GetInstanceType() info.InstanceType {
-	return info.UnknownInstance
+	if !cloudinfo.MetadataProbingEnabled() {
+		return info.UnknownInstance
+	}
+	vmSize, err := getInstanceMetadata("vmSize")
+	if err != nil {
+		return info.UnknownInstance
+	}
+	return info.InstanceType(vmSize)
 }
 
 func (provider) GetInstanceID() info.InstanceID {
@@ -56,3 +69,20 @@ func (provider) GetInstanceID() info.InstanceID {
 	}
 	return info.InstanceID(strings.TrimSuffix(string(data), "\n"))
 }
+
+func (provider) GetRegion() string {
+	location, err := getInstanceMetadata("location")
+	if err != nil {
+		return ""
+	}
+	return location
+}
+
+func getInstanceMetadata(name string) (string, error) {
+	data, err := cloudinfo.GetMetadata(fmt.Sprintf(instanceMetadataURL, name), map[string]string{"Metadata": "true"})
+	if err != nil {
+		klog.V(2).Infof("Error while reading %s from instance metadata service: %v", name, err)
+		return "", err
+	}
+	return strings.TrimSpace(data), nil
+}
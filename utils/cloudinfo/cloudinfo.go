@@ -17,14 +17,26 @@
 package cloudinfo
 
 import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
 	info "github.com/google/cadvisor/info/v1"
 	"k8s.io/klog/v2"
 )
 
+var probeMetadata = flag.Bool("cloud_metadata", false, "Query metadata service of the detected cloud provider for region of the instance, and for instance type on Azure and OpenStack.")
+
+// Timeout of requests to metadata service, it is available only from the instance so it should respond quickly.
+const metadataTimeout = 2 * time.Second
+
 type CloudInfo interface {
 	GetCloudProvider() info.CloudProvider
 	GetInstanceType() info.InstanceType
 	GetInstanceID() info.InstanceID
+	GetRegion() string
 }
 
 // CloudProvider is an abstraction for providing cloud-specific information.
@@ -38,6 +50,10 @@ type CloudProvider interface {
 	// GetInstanceType gets the ID of the instance this process is running on.
 	// The behavior is undefined if this is not the active provider.
 	GetInstanceID() info.InstanceID
+	// GetRegion gets the region of the instance this process is running on,
+	// called only when metadata probing is enabled.
+	// The behavior is undefined if this is not the active provider.
+	GetRegion() string
 }
 
 var providers = map[info.CloudProvider]CloudProvider{}
@@ -54,16 +70,21 @@ type realCloudInfo struct {
 	cloudProvider info.CloudProvider
 	instanceType  info.InstanceType
 	instanceID    info.InstanceID
+	region        string
 }
 
 func NewRealCloudInfo() CloudInfo {
 	for name, provider := range providers {
 		if provider.IsActiveProvider() {
-			return &realCloudInfo{
+			cloudInfo := &realCloudInfo{
 				cloudProvider: name,
 				instanceType:  provider.GetInstanceType(),
 				instanceID:    provider.GetInstanceID(),
 			}
+			if *probeMetadata {
+				cloudInfo.region = provider.GetRegion()
+			}
+			return cloudInfo
 		}
 	}
 
@@ -86,3 +107,38 @@ func (i *realCloudInfo) GetInstanceType() info.InstanceType {
 func (i *realCloudInfo) GetInstanceID() info.InstanceID {
 	return i.instanceID
 }
+
+func (i *realCloudInfo) GetRegion() string {
+	return i.region
+}
+
+// MetadataProbingEnabled returns true if cloud providers may query metadata service
+// for information which is not available locally.
+func MetadataProbingEnabled() bool {
+	return *probeMetadata
+}
+
+// GetMetadata returns response of metadata service available under given url.
+func GetMetadata(url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	client := http.Client{Timeout: metadataTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status of %s: %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
@@ -64,3 +64,20 @@ func (provider) GetInstanceID() info.InstanceID {
 	}
 	return info.InstanceID(info.InstanceType(instanceID))
 }
+
+func (provider) GetRegion() string {
+	zone, err := metadata.Zone()
+	if err != nil {
+		klog.V(2).Infof("Error while reading zone of the instance: %v", err)
+		return ""
+	}
+	return regionFromZone(zone)
+}
+
+// regionFromZone returns region of zone, e.g. us-central1 for us-central1-a.
+func regionFromZone(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
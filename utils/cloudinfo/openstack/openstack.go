@@ -0,0 +1,83 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"io/ioutil"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/cloudinfo"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	sysVendorFileName   = "/sys/class/dmi/id/sys_vendor"
+	productNameFileName = "/sys/class/dmi/id/product_name"
+	// Nova sets system UUID to the id of the instance.
+	biosUUIDFileName = "/sys/class/dmi/id/product_uuid"
+	openStack        = "OpenStack"
+
+	// EC2 compatible metadata service of Nova, flavor of the instance is reported as instance type.
+	instanceTypeURL = "http://169.254.169.254/latest/meta-data/instance-type"
+)
+
+func init() {
+	cloudinfo.RegisterCloudProvider(info.OpenStack, &provider{})
+}
+
+type provider struct{}
+
+var _ cloudinfo.CloudProvider = provider{}
+
+func (provider) IsActiveProvider() bool {
+	// e.g. "OpenStack Foundation" and "OpenStack Nova" or "OpenStack Compute".
+	return fileContainsOpenStackIdentifier(sysVendorFileName) ||
+		fileContainsOpenStackIdentifier(productNameFileName)
+}
+
+func fileContainsOpenStackIdentifier(filename string) bool {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), openStack)
+}
+
+func (provider) GetInstanceType() info.InstanceType {
+	if !cloudinfo.MetadataProbingEnabled() {
+		return info.UnknownInstance
+	}
+	flavor, err := cloudinfo.GetMetadata(instanceTypeURL, nil)
+	if err != nil {
+		klog.V(2).Infof("Error while reading instance type from metadata service: %v", err)
+		return info.UnknownInstance
+	}
+	return info.InstanceType(strings.TrimSpace(flavor))
+}
+
+func (provider) GetInstanceID() info.InstanceID {
+	data, err := ioutil.ReadFile(biosUUIDFileName)
+	if err != nil {
+		return info.UnNamedInstance
+	}
+	return info.InstanceID(strings.TrimSpace(string(data)))
+}
+
+// GetRegion returns empty region, metadata service of Nova reports only availability zone of the instance.
+func (provider) GetRegion() string {
+	return ""
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethtool reads driver statistics of network devices (ethtool -S) using SIOCETHTOOL ioctl.
+// Driver statistics are not exposed through sysfs nor through ethtool netlink interface, see:
+// https://www.kernel.org/doc/html/latest/networking/ethtool-netlink.html
+package ethtool
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// See include/uapi/linux/ethtool.h.
+	ethtoolGDrvInfo    = 0x00000003
+	ethtoolGStrings    = 0x0000001b
+	ethtoolGStats      = 0x0000001d
+	ethSSStats         = 1
+	ethGStringLen      = 32
+	ethtoolBusInfoLen  = 32
+	ethtoolFwVersLen   = 32
+	ethtoolDriverLen   = 32
+	ethtoolVersionLen  = 32
+	ethtoolEromVersLen = 32
+
+	siocEthtool = 0x8946
+	ifNameSize  = 16
+
+	// Upper limit of number of statistics, drivers report up to several thousands (e.g. per queue counters).
+	maxStats = 1 << 16
+)
+
+// ifreq is struct ifreq with ifr_data member of the union, padded to size of the union (struct ifmap)
+// as SIOCETHTOOL copies whole struct ifreq back to user space.
+type ifreq struct {
+	name [ifNameSize]byte
+	data uintptr
+	_    [8 + unsafe.Sizeof(uintptr(0))]byte
+}
+
+type ethtoolDrvInfo struct {
+	cmd         uint32
+	driver      [ethtoolDriverLen]byte
+	version     [ethtoolVersionLen]byte
+	fwVersion   [ethtoolFwVersLen]byte
+	busInfo     [ethtoolBusInfoLen]byte
+	eromVersion [ethtoolEromVersLen]byte
+	reserved2   [12]byte
+	nPrivFlags  uint32
+	nStats      uint32
+	testInfoLen uint32
+	eedumpLen   uint32
+	regdumpLen  uint32
+}
+
+type ethtoolGStringsHeader struct {
+	cmd       uint32
+	stringSet uint32
+	len       uint32
+}
+
+type ethtoolStatsHeader struct {
+	cmd    uint32
+	nStats uint32
+}
+
+// GetStats returns driver statistics of network device by statistic name, e.g. rx_queue_0_packets.
+// Statistics are read in network namespace of the calling process.
+func GetStats(device string) (map[string]uint64, error) {
+	if len(device) >= ifNameSize {
+		return nil, fmt.Errorf("invalid network device name %q", device)
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	drvInfo := ethtoolDrvInfo{cmd: ethtoolGDrvInfo}
+	if err := ioctl(fd, device, unsafe.Pointer(&drvInfo)); err != nil {
+		return nil, fmt.Errorf("failed to get driver information of %s: %v", device, err)
+	}
+	nStats := int(drvInfo.nStats)
+	if nStats == 0 {
+		return map[string]uint64{}, nil
+	}
+	if nStats > maxStats {
+		return nil, fmt.Errorf("unexpected number of statistics of %s: %d", device, nStats)
+	}
+
+	headerSize := int(unsafe.Sizeof(ethtoolGStringsHeader{}))
+	stringsBuf := make([]byte, headerSize+nStats*ethGStringLen)
+	header := (*ethtoolGStringsHeader)(unsafe.Pointer(&stringsBuf[0]))
+	header.cmd = ethtoolGStrings
+	header.stringSet = ethSSStats
+	header.len = uint32(nStats)
+	if err := ioctl(fd, device, unsafe.Pointer(&stringsBuf[0])); err != nil {
+		return nil, fmt.Errorf("failed to get names of statistics of %s: %v", device, err)
+	}
+
+	statsHeaderSize := int(unsafe.Sizeof(ethtoolStatsHeader{}))
+	statsBuf := make([]uint64, (statsHeaderSize+7)/8+nStats)
+	statsHeader := (*ethtoolStatsHeader)(unsafe.Pointer(&statsBuf[0]))
+	statsHeader.cmd = ethtoolGStats
+	statsHeader.nStats = uint32(nStats)
+	if err := ioctl(fd, device, unsafe.Pointer(&statsBuf[0])); err != nil {
+		return nil, fmt.Errorf("failed to get statistics of %s: %v", device, err)
+	}
+
+	// Number of statistics may change between calls, e.g. when queues are added.
+	if int(header.len) < nStats {
+		nStats = int(header.len)
+	}
+	if int(statsHeader.nStats) < nStats {
+		nStats = int(statsHeader.nStats)
+	}
+	values := statsBuf[(statsHeaderSize+7)/8:]
+	stats := make(map[string]uint64, nStats)
+	for i := 0; i < nStats; i++ {
+		name := stringsBuf[headerSize+i*ethGStringLen : headerSize+(i+1)*ethGStringLen]
+		stats[string(bytes.TrimRight(name, "\x00"))] = values[i]
+	}
+	return stats, nil
+}
+
+func ioctl(fd int, device string, data unsafe.Pointer) error {
+	ifr := ifreq{data: uintptr(data)}
+	copy(ifr.name[:], device)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethtool
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfreqSize(t *testing.T) {
+	// Size of struct ifreq is 40 bytes on 64-bit architectures and 32 bytes on 32-bit ones.
+	expectedSize := uintptr(40)
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		expectedSize = 32
+	}
+	assert.Equal(t, expectedSize, unsafe.Sizeof(ifreq{}))
+}
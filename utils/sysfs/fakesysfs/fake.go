@@ -20,6 +20,8 @@ import (
 	"time"
 
 	"github.com/google/cadvisor/utils/sysfs"
+
+	"golang.org/x/sys/unix"
 )
 
 // If we extend sysfs to support more interfaces, it might be worth making this a mock instead of a fake.
@@ -52,8 +54,9 @@ func (i *FileInfo) Sys() interface{} {
 }
 
 type FakeSysFs struct {
-	info  FileInfo
-	cache sysfs.CacheInfo
+	info      FileInfo
+	cache     sysfs.CacheInfo
+	cpuCaches map[int][]sysfs.CacheInfo
 
 	nodesPaths  []string
 	nodePathErr error
@@ -67,15 +70,80 @@ type FakeSysFs struct {
 	physicalPackageIDs   map[string]string
 	physicalPackageIDErr map[string]error
 
+	dieIDs     map[string]string
+	clusterIDs map[string]string
+	bookIDs    map[string]string
+	drawerIDs  map[string]string
+
+	capacities map[string]string
+	maxFreqs   map[string]string
+	pmuCPUs    map[string]string
+
+	curFreqs     map[string]string
+	governors    map[string]string
+	timesInState map[string]string
+
+	hwmonSensors []sysfs.HwmonSensor
+
+	nvmeControllers []sysfs.NVMeController
+
+	infinibandPorts []sysfs.InfinibandPort
+
+	pciDevices []sysfs.PCIDevice
+
+	vulnerabilities    map[string]string
+	vulnerabilitiesErr error
+
+	onlineCPUList  string
+	offlineCPUList string
+	cpuListErr     error
+
+	memoryBlocksPaths  []string
+	memoryBlocksStates map[string]string
+
+	dmiEntries map[int][][]byte
+
+	powercapZones    []string
+	powercapNames    map[string]string
+	powercapEnergies map[string]string
+
 	memTotal string
 	memErr   error
 
+	distances    map[string]string
+	distancesErr error
+
 	hugePages    []os.FileInfo
 	hugePagesErr error
 
 	hugePagesNr    map[string]string
 	hugePagesNrErr error
 
+	hugePagesCounters map[string]string
+
+	blockDeviceQueueAttributes map[string]string
+	blockDeviceStats           map[string]string
+	blockDeviceSlaves          map[string][]string
+	blockDeviceRaidLevels      map[string]string
+	blockDeviceDMNames         map[string]string
+
+	networkOperStates map[string]string
+	networkDuplexes   map[string]string
+	networkDrivers    map[string]string
+
+	networkDevices      []os.FileInfo
+	networkDeviceTypes  map[string]string
+	networkBondSlaves   map[string][]string
+	networkBridgePorts  map[string][]string
+	networkLowerDevices map[string][]string
+
+	networkPCIAddresses     map[string]string
+	networkPCIAttributes    map[string]string
+	networkVirtualFunctions map[string]map[int]string
+
+	networkQueues       map[string][]string
+	networkEthtoolStats map[string]map[string]uint64
+
 	onlineCPUs map[string]interface{}
 }
 
@@ -95,10 +163,62 @@ func (fs *FakeSysFs) GetCPUPhysicalPackageID(cpuPath string) (string, error) {
 	return fs.physicalPackageIDs[cpuPath], fs.physicalPackageIDErr[cpuPath]
 }
 
+func (fs *FakeSysFs) GetDieID(cpuPath string) (string, error) {
+	return fs.dieIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetClusterID(cpuPath string) (string, error) {
+	return fs.clusterIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetBookID(cpuPath string) (string, error) {
+	return fs.bookIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetDrawerID(cpuPath string) (string, error) {
+	return fs.drawerIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetCPUCapacity(cpuPath string) (string, error) {
+	return getOrNotExist(fs.capacities, cpuPath)
+}
+
+func (fs *FakeSysFs) GetCPUMaxFrequency(cpuPath string) (string, error) {
+	return getOrNotExist(fs.maxFreqs, cpuPath)
+}
+
+func (fs *FakeSysFs) GetCPUCurrentFrequency(cpuPath string) (string, error) {
+	return getOrNotExist(fs.curFreqs, cpuPath)
+}
+
+func (fs *FakeSysFs) GetCPUScalingGovernor(cpuPath string) (string, error) {
+	return getOrNotExist(fs.governors, cpuPath)
+}
+
+func (fs *FakeSysFs) GetCPUTimeInState(cpuPath string) (string, error) {
+	return getOrNotExist(fs.timesInState, cpuPath)
+}
+
+func (fs *FakeSysFs) GetPMUCPUs(pmu string) (string, error) {
+	return getOrNotExist(fs.pmuCPUs, pmu)
+}
+
+func getOrNotExist(values map[string]string, key string) (string, error) {
+	value, ok := values[key]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return value, nil
+}
+
 func (fs *FakeSysFs) GetMemInfo(nodePath string) (string, error) {
 	return fs.memTotal, fs.memErr
 }
 
+func (fs *FakeSysFs) GetDistances(nodePath string) (string, error) {
+	return fs.distances[nodePath], fs.distancesErr
+}
+
 func (fs *FakeSysFs) GetHugePagesInfo(hugepagesDirectory string) ([]os.FileInfo, error) {
 	return fs.hugePages, fs.hugePagesErr
 }
@@ -125,7 +245,30 @@ func (fs *FakeSysFs) GetBlockDeviceNumbers(name string) (string, error) {
 	return "8:0\n", nil
 }
 
+func (fs *FakeSysFs) GetBlockDeviceQueueAttribute(name string, attribute string) (string, error) {
+	return getOrNotExist(fs.blockDeviceQueueAttributes, fmt.Sprintf("%s/queue/%s", name, attribute))
+}
+
+func (fs *FakeSysFs) GetBlockDeviceStat(name string) (string, error) {
+	return getOrNotExist(fs.blockDeviceStats, name)
+}
+
+func (fs *FakeSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	return fs.blockDeviceSlaves[name], nil
+}
+
+func (fs *FakeSysFs) GetBlockDeviceRaidLevel(name string) (string, error) {
+	return getOrNotExist(fs.blockDeviceRaidLevels, name)
+}
+
+func (fs *FakeSysFs) GetBlockDeviceDMName(name string) (string, error) {
+	return getOrNotExist(fs.blockDeviceDMNames, name)
+}
+
 func (fs *FakeSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
+	if fs.networkDevices != nil {
+		return fs.networkDevices, nil
+	}
 	return []os.FileInfo{&fs.info}, nil
 }
 
@@ -145,15 +288,97 @@ func (fs *FakeSysFs) GetNetworkStatValue(name string, stat string) (uint64, erro
 	return 1024, nil
 }
 
+func (fs *FakeSysFs) GetNetworkOperState(name string) (string, error) {
+	return getOrNotExist(fs.networkOperStates, name)
+}
+
+func (fs *FakeSysFs) GetNetworkDuplex(name string) (string, error) {
+	return getOrNotExist(fs.networkDuplexes, name)
+}
+
+func (fs *FakeSysFs) GetNetworkDriver(name string) (string, error) {
+	return getOrNotExist(fs.networkDrivers, name)
+}
+
+func (fs *FakeSysFs) GetNetworkDeviceType(name string) (string, error) {
+	return fs.networkDeviceTypes[name], nil
+}
+
+func (fs *FakeSysFs) GetNetworkBondSlaves(name string) ([]string, error) {
+	slaves, ok := fs.networkBondSlaves[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return slaves, nil
+}
+
+func (fs *FakeSysFs) GetNetworkBridgePorts(name string) ([]string, error) {
+	ports, ok := fs.networkBridgePorts[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ports, nil
+}
+
+func (fs *FakeSysFs) GetNetworkLowerDevices(name string) ([]string, error) {
+	return fs.networkLowerDevices[name], nil
+}
+
+func (fs *FakeSysFs) GetNetworkQueues(name string) ([]string, error) {
+	return fs.networkQueues[name], nil
+}
+
+func (fs *FakeSysFs) GetNetworkEthtoolStats(name string) (map[string]uint64, error) {
+	stats, ok := fs.networkEthtoolStats[name]
+	if !ok {
+		return nil, unix.EOPNOTSUPP
+	}
+	return stats, nil
+}
+
+func (fs *FakeSysFs) GetNetworkPCIAddress(name string) (string, error) {
+	return getOrNotExist(fs.networkPCIAddresses, name)
+}
+
+func (fs *FakeSysFs) GetNetworkPCIAttribute(name string, attribute string) (string, error) {
+	return getOrNotExist(fs.networkPCIAttributes, fmt.Sprintf("%s/device/%s", name, attribute))
+}
+
+func (fs *FakeSysFs) GetNetworkVirtualFunctions(name string) (map[int]string, error) {
+	return fs.networkVirtualFunctions[name], nil
+}
+
 func (fs *FakeSysFs) GetCaches(id int) ([]os.FileInfo, error) {
-	fs.info.EntryName = "index0"
-	return []os.FileInfo{&fs.info}, nil
+	if fs.cpuCaches != nil {
+		caches := []os.FileInfo{}
+		for i := range fs.cpuCaches[id] {
+			caches = append(caches, &FileInfo{EntryName: fmt.Sprintf("index%d", i)})
+		}
+		return caches, nil
+	}
+	return []os.FileInfo{&FileInfo{EntryName: "index0"}}, nil
 }
 
 func (fs *FakeSysFs) GetCacheInfo(cpu int, cache string) (sysfs.CacheInfo, error) {
+	if fs.cpuCaches != nil {
+		var index int
+		if _, err := fmt.Sscanf(cache, "index%d", &index); err != nil || index >= len(fs.cpuCaches[cpu]) {
+			return sysfs.CacheInfo{}, os.ErrNotExist
+		}
+		// Zero value represents cache without attributes.
+		if fs.cpuCaches[cpu][index] == (sysfs.CacheInfo{}) {
+			return sysfs.CacheInfo{}, os.ErrNotExist
+		}
+		return fs.cpuCaches[cpu][index], nil
+	}
 	return fs.cache, nil
 }
 
+// SetCPUCaches sets caches of each CPU, it takes precedence over SetCacheInfo.
+func (fs *FakeSysFs) SetCPUCaches(cpuCaches map[int][]sysfs.CacheInfo) {
+	fs.cpuCaches = cpuCaches
+}
+
 func (fs *FakeSysFs) SetCacheInfo(cache sysfs.CacheInfo) {
 	fs.cache = cache
 }
@@ -178,11 +403,50 @@ func (fs *FakeSysFs) SetPhysicalPackageIDs(physicalPackageIDs map[string]string,
 	fs.physicalPackageIDErr = physicalPackageIDErrors
 }
 
+func (fs *FakeSysFs) SetDieIDs(dieIDs map[string]string) {
+	fs.dieIDs = dieIDs
+}
+
+func (fs *FakeSysFs) SetClusterIDs(clusterIDs map[string]string) {
+	fs.clusterIDs = clusterIDs
+}
+
+func (fs *FakeSysFs) SetBookIDs(bookIDs map[string]string) {
+	fs.bookIDs = bookIDs
+}
+
+func (fs *FakeSysFs) SetDrawerIDs(drawerIDs map[string]string) {
+	fs.drawerIDs = drawerIDs
+}
+
+func (fs *FakeSysFs) SetCPUCapacities(capacities map[string]string) {
+	fs.capacities = capacities
+}
+
+func (fs *FakeSysFs) SetCPUMaxFrequencies(maxFreqs map[string]string) {
+	fs.maxFreqs = maxFreqs
+}
+
+func (fs *FakeSysFs) SetCPUFrequencyScaling(curFreqs, governors, timesInState map[string]string) {
+	fs.curFreqs = curFreqs
+	fs.governors = governors
+	fs.timesInState = timesInState
+}
+
+func (fs *FakeSysFs) SetPMUCPUs(pmuCPUs map[string]string) {
+	fs.pmuCPUs = pmuCPUs
+}
+
 func (fs *FakeSysFs) SetMemory(memTotal string, err error) {
 	fs.memTotal = memTotal
 	fs.memErr = err
 }
 
+func (fs *FakeSysFs) SetDistances(distances map[string]string, err error) {
+	fs.distances = distances
+	fs.distancesErr = err
+}
+
 func (fs *FakeSysFs) SetHugePages(hugePages []os.FileInfo, err error) {
 	fs.hugePages = hugePages
 	fs.hugePagesErr = err
@@ -193,10 +457,157 @@ func (fs *FakeSysFs) SetHugePagesNr(hugePagesNr map[string]string, err error) {
 	fs.hugePagesNrErr = err
 }
 
+func (fs *FakeSysFs) GetHugePagesCounter(hugepagesDirectory string, hugePageName string, counterFile string) (string, error) {
+	return getOrNotExist(fs.hugePagesCounters, fmt.Sprintf("%s%s/%s", hugepagesDirectory, hugePageName, counterFile))
+}
+
+func (fs *FakeSysFs) SetHugePagesCounters(hugePagesCounters map[string]string) {
+	fs.hugePagesCounters = hugePagesCounters
+}
+
+func (fs *FakeSysFs) SetBlockDeviceQueueAttributes(attributes map[string]string) {
+	fs.blockDeviceQueueAttributes = attributes
+}
+
+func (fs *FakeSysFs) SetBlockDeviceStats(stats map[string]string) {
+	fs.blockDeviceStats = stats
+}
+
+func (fs *FakeSysFs) SetBlockDeviceTopology(slaves map[string][]string, raidLevels map[string]string, dmNames map[string]string) {
+	fs.blockDeviceSlaves = slaves
+	fs.blockDeviceRaidLevels = raidLevels
+	fs.blockDeviceDMNames = dmNames
+}
+
+func (fs *FakeSysFs) SetNetworkLinkInfo(operStates map[string]string, duplexes map[string]string, drivers map[string]string) {
+	fs.networkOperStates = operStates
+	fs.networkDuplexes = duplexes
+	fs.networkDrivers = drivers
+}
+
+func (fs *FakeSysFs) SetNetworkDevices(names []string) {
+	fs.networkDevices = make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		fs.networkDevices = append(fs.networkDevices, &FileInfo{EntryName: name})
+	}
+}
+
+func (fs *FakeSysFs) SetNetworkTopology(deviceTypes map[string]string, bondSlaves map[string][]string, bridgePorts map[string][]string, lowerDevices map[string][]string) {
+	fs.networkDeviceTypes = deviceTypes
+	fs.networkBondSlaves = bondSlaves
+	fs.networkBridgePorts = bridgePorts
+	fs.networkLowerDevices = lowerDevices
+}
+
+func (fs *FakeSysFs) SetNetworkPCIDevices(addresses map[string]string, attributes map[string]string, virtualFunctions map[string]map[int]string) {
+	fs.networkPCIAddresses = addresses
+	fs.networkPCIAttributes = attributes
+	fs.networkVirtualFunctions = virtualFunctions
+}
+
+func (fs *FakeSysFs) SetNetworkQueueStats(queues map[string][]string, ethtoolStats map[string]map[string]uint64) {
+	fs.networkQueues = queues
+	fs.networkEthtoolStats = ethtoolStats
+}
+
 func (fs *FakeSysFs) SetEntryName(name string) {
 	fs.info.EntryName = name
 }
 
+func (fs *FakeSysFs) GetHwmonSensors() ([]sysfs.HwmonSensor, error) {
+	return fs.hwmonSensors, nil
+}
+
+func (fs *FakeSysFs) SetHwmonSensors(sensors []sysfs.HwmonSensor) {
+	fs.hwmonSensors = sensors
+}
+
+func (fs *FakeSysFs) GetNVMeControllers() ([]sysfs.NVMeController, error) {
+	return fs.nvmeControllers, nil
+}
+
+func (fs *FakeSysFs) SetNVMeControllers(controllers []sysfs.NVMeController) {
+	fs.nvmeControllers = controllers
+}
+
+func (fs *FakeSysFs) GetPCIDevices() ([]sysfs.PCIDevice, error) {
+	return fs.pciDevices, nil
+}
+
+func (fs *FakeSysFs) SetPCIDevices(devices []sysfs.PCIDevice) {
+	fs.pciDevices = devices
+}
+
+func (fs *FakeSysFs) GetInfinibandPorts() ([]sysfs.InfinibandPort, error) {
+	return fs.infinibandPorts, nil
+}
+
+func (fs *FakeSysFs) SetInfinibandPorts(ports []sysfs.InfinibandPort) {
+	fs.infinibandPorts = ports
+}
+
+func (fs *FakeSysFs) GetCPUVulnerabilities() (map[string]string, error) {
+	return fs.vulnerabilities, fs.vulnerabilitiesErr
+}
+
+func (fs *FakeSysFs) SetCPUVulnerabilities(vulnerabilities map[string]string, err error) {
+	fs.vulnerabilities = vulnerabilities
+	fs.vulnerabilitiesErr = err
+}
+
+func (fs *FakeSysFs) GetOnlineCPUs() (string, error) {
+	return fs.onlineCPUList, fs.cpuListErr
+}
+
+func (fs *FakeSysFs) GetOfflineCPUs() (string, error) {
+	return fs.offlineCPUList, fs.cpuListErr
+}
+
+func (fs *FakeSysFs) SetCPULists(online, offline string, err error) {
+	fs.onlineCPUList = online
+	fs.offlineCPUList = offline
+	fs.cpuListErr = err
+}
+
+func (fs *FakeSysFs) GetMemoryBlocksPaths() ([]string, error) {
+	return fs.memoryBlocksPaths, nil
+}
+
+func (fs *FakeSysFs) GetMemoryBlockState(memoryBlockPath string) (string, error) {
+	return getOrNotExist(fs.memoryBlocksStates, memoryBlockPath)
+}
+
+func (fs *FakeSysFs) SetMemoryBlocks(paths []string, states map[string]string) {
+	fs.memoryBlocksPaths = paths
+	fs.memoryBlocksStates = states
+}
+
+func (fs *FakeSysFs) GetDMIEntries(entryType int) ([][]byte, error) {
+	return fs.dmiEntries[entryType], nil
+}
+
+func (fs *FakeSysFs) SetDMIEntries(entries map[int][][]byte) {
+	fs.dmiEntries = entries
+}
+
+func (fs *FakeSysFs) GetPowercapZones() ([]string, error) {
+	return fs.powercapZones, nil
+}
+
+func (fs *FakeSysFs) GetPowercapZoneName(zonePath string) (string, error) {
+	return getOrNotExist(fs.powercapNames, zonePath)
+}
+
+func (fs *FakeSysFs) GetEnergyUJ(zonePath string) (string, error) {
+	return getOrNotExist(fs.powercapEnergies, zonePath)
+}
+
+func (fs *FakeSysFs) SetPowercapZones(zones []string, names map[string]string, energies map[string]string) {
+	fs.powercapZones = zones
+	fs.powercapNames = names
+	fs.powercapEnergies = energies
+}
+
 func (fs *FakeSysFs) GetSystemUUID() (string, error) {
 	return "1F862619-BA9F-4526-8F85-ECEAF0C97430", nil
 }
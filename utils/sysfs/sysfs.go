@@ -26,32 +26,152 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/google/cadvisor/utils/ethtool"
+
 	"k8s.io/klog/v2"
 )
 
 const (
 	blockDir     = "/sys/block"
+	devicesDir   = "/sys/devices"
+	powercapDir  = "/sys/class/powercap"
+	hwmonDir     = "/sys/class/hwmon"
+	nvmeDir      = "/sys/class/nvme"
+	ibDir        = "/sys/class/infiniband"
+	pciDir       = "/sys/bus/pci/devices"
+	vulnsDir     = "/sys/devices/system/cpu/vulnerabilities"
+	onlineFile   = "/sys/devices/system/cpu/online"
+	offlineFile  = "/sys/devices/system/cpu/offline"
+	memoryDir    = "/sys/devices/system/memory"
 	cacheDir     = "/sys/devices/system/cpu/cpu"
 	netDir       = "/sys/class/net"
 	dmiDir       = "/sys/class/dmi"
+	dmiEntryDir  = "/sys/firmware/dmi/entries"
 	ppcDevTree   = "/proc/device-tree"
 	s390xDevTree = "/etc" // s390/s390x changes
 
-	coreIDFilePath    = "/topology/core_id"
-	packageIDFilePath = "/topology/physical_package_id"
-	meminfoFile       = "meminfo"
+	// Raw SMBIOS tables, located through EFI system table on EFI systems.
+	smbiosTablesDir = "/sys/firmware/dmi/tables"
+
+	coreIDFilePath      = "/topology/core_id"
+	packageIDFilePath   = "/topology/physical_package_id"
+	dieIDFilePath       = "/topology/die_id"
+	clusterIDFilePath   = "/topology/cluster_id"
+	bookIDFilePath      = "/topology/book_id"
+	drawerIDFilePath    = "/topology/drawer_id"
+	capacityFilePath    = "/cpu_capacity"
+	maxFreqFilePath     = "/cpufreq/cpuinfo_max_freq"
+	curFreqFilePath     = "/cpufreq/scaling_cur_freq"
+	governorFilePath    = "/cpufreq/scaling_governor"
+	timeInStateFilePath = "/cpufreq/stats/time_in_state"
+	meminfoFile         = "meminfo"
+	distanceFile        = "distance"
+
+	cpuDirPattern      = "cpu*[0-9]"
+	nodeDirPattern     = "node*[0-9]"
+	raplZoneDirPattern = "intel-rapl:*"
+	hwmonDirPattern    = "hwmon*[0-9]"
+	memoryBlockPattern = "memory*[0-9]"
+	nvmeDirPattern     = "nvme*[0-9]"
 
-	cpuDirPattern  = "cpu*[0-9]"
-	nodeDirPattern = "node*[0-9]"
+	// See DSP0134 (SMBIOS Reference Specification).
+	smbiosSystemInformationType = 1
+	smbiosEndOfTableType        = 127
+	smbiosUUIDOffset            = 8
 
 	//HugePagesNrFile name of nr_hugepages file in sysfs
 	HugePagesNrFile = "nr_hugepages"
+	//HugePagesFreeFile name of free_hugepages file in sysfs
+	HugePagesFreeFile = "free_hugepages"
+	//HugePagesSurplusFile name of surplus_hugepages file in sysfs
+	HugePagesSurplusFile = "surplus_hugepages"
+	//HugePagesReservedFile name of resv_hugepages file in sysfs, it is not available per NUMA node
+	HugePagesReservedFile = "resv_hugepages"
+
+	//PCIVendorFile name of file holding PCI vendor ID of device
+	PCIVendorFile = "vendor"
+	//PCIDeviceFile name of file holding PCI device ID of device
+	PCIDeviceFile = "device"
+	//SriovTotalVFsFile name of file holding maximal number of SR-IOV virtual functions of physical function
+	SriovTotalVFsFile = "sriov_totalvfs"
+	//SriovNumVFsFile name of file holding number of enabled SR-IOV virtual functions of physical function
+	SriovNumVFsFile = "sriov_numvfs"
+
+	//BlockDeviceRotationalFile name of queue file telling whether block device is rotational
+	BlockDeviceRotationalFile = "rotational"
+	//BlockDeviceNrRequestsFile name of queue file holding block device queue depth
+	BlockDeviceNrRequestsFile = "nr_requests"
+	//BlockDeviceDiscardGranularityFile name of queue file holding block device discard granularity
+	BlockDeviceDiscardGranularityFile = "discard_granularity"
+	//BlockDevicePhysicalBlockSizeFile name of queue file holding block device physical block size
+	BlockDevicePhysicalBlockSizeFile = "physical_block_size"
 )
 
 var (
 	nodeDir = "/sys/devices/system/node/"
 )
 
+// HwmonSensor holds reading of temperature sensor of hardware monitoring device.
+type HwmonSensor struct {
+	// name of hwmon device, e.g. coretemp or nvme
+	Device string
+	// label of sensor, e.g. "Package id 0", name of input file (e.g. temp1) if label is not available
+	Label string
+	// temperature in millidegrees Celsius
+	Temperature int64
+}
+
+// NVMeController holds attributes of NVMe controller, see:
+// https://www.kernel.org/doc/Documentation/ABI/testing/sysfs-class-nvme
+type NVMeController struct {
+	// name of controller, e.g. nvme0
+	Name string
+	// model of controller
+	Model string
+	// firmware revision of controller
+	Firmware string
+	// sizes of namespaces in 512 bytes sectors by namespace name, e.g. nvme0n1
+	Namespaces map[string]uint64
+	// readings of controller temperature sensors, empty when kernel does not expose hwmon for NVMe (before 5.5)
+	Sensors []HwmonSensor
+}
+
+// PCIDevice holds attributes of PCI device, attributes which are not available are empty, see:
+// https://www.kernel.org/doc/Documentation/ABI/testing/sysfs-bus-pci
+type PCIDevice struct {
+	// address of device, e.g. 0000:3b:00.0
+	Address string
+	// vendor ID, e.g. 0x8086
+	Vendor string
+	// device ID, e.g. 0x158b
+	Device string
+	// class code, e.g. 0x020000
+	Class string
+	// NUMA node, -1 when device is not attached to any node
+	NumaNode string
+	// IOMMU group, empty when IOMMU is disabled
+	IOMMUGroup string
+	// current and maximal link speed, e.g. "8.0 GT/s PCIe", available only for PCI Express devices
+	CurrentLinkSpeed string
+	MaxLinkSpeed     string
+	// current and maximal link width, e.g. 8, available only for PCI Express devices
+	CurrentLinkWidth string
+	MaxLinkWidth     string
+}
+
+// InfinibandPort holds state and counters of InfiniBand (or RoCE) port, see:
+// https://www.kernel.org/doc/Documentation/ABI/stable/sysfs-class-infiniband
+type InfinibandPort struct {
+	// name of HCA, e.g. mlx5_0
+	Device string
+	// port number, starting from 1
+	Port int
+	// state of port, e.g. ACTIVE or DOWN
+	State string
+	// values of port counters by counter name, e.g. port_rcv_data
+	Counters map[string]uint64
+}
+
 type CacheInfo struct {
 	// size in bytes
 	Size uint64
@@ -61,6 +181,8 @@ type CacheInfo struct {
 	Level int
 	// number of cpus that can access this cache.
 	Cpus int
+	// list of cpus that can access this cache, e.g. "0-7,64-71", empty if not available.
+	CPUList string
 }
 
 // Abstracts the lowest level calls to sysfs.
@@ -73,12 +195,36 @@ type SysFs interface {
 	GetCoreID(coreIDFilePath string) (string, error)
 	// Get physical package id for specified CPU
 	GetCPUPhysicalPackageID(cpuPath string) (string, error)
+	// Get die id for specified CPU, available since kernel 5.2
+	GetDieID(cpuPath string) (string, error)
+	// Get cluster id for specified CPU, available since kernel 5.16
+	GetClusterID(cpuPath string) (string, error)
+	// Get book id for specified CPU, available on s390x
+	GetBookID(cpuPath string) (string, error)
+	// Get drawer id for specified CPU, available on s390x
+	GetDrawerID(cpuPath string) (string, error)
+	// Get capacity of specified CPU relative to the most performant CPU (1024), available on ARM
+	GetCPUCapacity(cpuPath string) (string, error)
+	// Get maximal frequency of specified CPU in kHz
+	GetCPUMaxFrequency(cpuPath string) (string, error)
+	// Get current frequency of specified CPU in kHz
+	GetCPUCurrentFrequency(cpuPath string) (string, error)
+	// Get frequency scaling governor of specified CPU
+	GetCPUScalingGovernor(cpuPath string) (string, error)
+	// Get time spent by specified CPU at each frequency, content of cpufreq/stats/time_in_state
+	GetCPUTimeInState(cpuPath string) (string, error)
+	// Get list of CPUs covered by performance monitoring unit, e.g. cpu_core or cpu_atom on Intel hybrid CPUs
+	GetPMUCPUs(pmu string) (string, error)
 	// Get total memory for specified NUMA node
 	GetMemInfo(nodeDir string) (string, error)
+	// Get distances from specified NUMA node to all NUMA nodes
+	GetDistances(nodeDir string) (string, error)
 	// Get hugepages from specified directory
 	GetHugePagesInfo(hugePagesDirectory string) ([]os.FileInfo, error)
 	// Get hugepage_nr from specified directory
 	GetHugePagesNr(hugePagesDirectory string, hugePageName string) (string, error)
+	// Get hugepages counter (e.g. free_hugepages) from specified directory
+	GetHugePagesCounter(hugePagesDirectory string, hugePageName string, counterFile string) (string, error)
 	// Get directory information for available block devices.
 	GetBlockDevices() ([]os.FileInfo, error)
 	// Get Size of a given block device.
@@ -87,42 +233,127 @@ type SysFs interface {
 	GetBlockDeviceScheduler(string) (string, error)
 	// Get device major:minor number string.
 	GetBlockDeviceNumbers(string) (string, error)
+	// Get content of a given queue attribute file for the block device, e.g. rotational.
+	GetBlockDeviceQueueAttribute(name string, attribute string) (string, error)
+	// Get IO statistics of the block device, see: https://www.kernel.org/doc/Documentation/block/stat.txt
+	GetBlockDeviceStat(string) (string, error)
+	// Get names of devices underlying the block device, e.g. members of md RAID array or dm device.
+	GetBlockDeviceSlaves(string) ([]string, error)
+	// Get RAID level of md block device, e.g. raid1.
+	GetBlockDeviceRaidLevel(string) (string, error)
+	// Get name of device-mapper block device, e.g. vg0-root.
+	GetBlockDeviceDMName(string) (string, error)
 
 	GetNetworkDevices() ([]os.FileInfo, error)
 	GetNetworkAddress(string) (string, error)
 	GetNetworkMtu(string) (string, error)
 	GetNetworkSpeed(string) (string, error)
 	GetNetworkStatValue(dev string, stat string) (uint64, error)
+	// Get operational state of network device, e.g. up, down or lowerlayerdown.
+	GetNetworkOperState(string) (string, error)
+	// Get duplex of network device, e.g. full or half, reading fails when link is down.
+	GetNetworkDuplex(string) (string, error)
+	// Get name of driver bound to network device, e.g. ixgbe.
+	GetNetworkDriver(string) (string, error)
+	// Get type of network device from uevent, e.g. bond, bridge or vlan, empty for physical devices.
+	GetNetworkDeviceType(string) (string, error)
+	// Get names of devices enslaved to bond, e.g. eth0 eth1.
+	GetNetworkBondSlaves(string) ([]string, error)
+	// Get names of devices attached to bridge.
+	GetNetworkBridgePorts(string) ([]string, error)
+	// Get PCI address of network device, e.g. 0000:3b:00.0.
+	GetNetworkPCIAddress(string) (string, error)
+	// Get content of a given PCI attribute file of network device, e.g. sriov_numvfs.
+	GetNetworkPCIAttribute(name string, attribute string) (string, error)
+	// Get PCI addresses of SR-IOV virtual functions of network device by virtual function index.
+	GetNetworkVirtualFunctions(string) (map[int]string, error)
+	// Get names of receive and transmit queues of network device, e.g. rx-0 or tx-0.
+	GetNetworkQueues(string) ([]string, error)
+	// Get driver statistics of network device (ethtool -S), they are read through SIOCETHTOOL ioctl
+	// in network namespace of cAdvisor as they are not available in sysfs.
+	GetNetworkEthtoolStats(string) (map[string]uint64, error)
+	// Get names of devices linked as lower devices of network device, e.g. eth0 for VLAN device eth0.100.
+	GetNetworkLowerDevices(string) ([]string, error)
 
 	// Get directory information for available caches accessible to given cpu.
 	GetCaches(id int) ([]os.FileInfo, error)
 	// Get information for a cache accessible from the given cpu.
 	GetCacheInfo(cpu int, cache string) (CacheInfo, error)
 
+	// Get readings of temperature sensors of hwmon devices, see:
+	// https://www.kernel.org/doc/Documentation/hwmon/sysfs-interface
+	GetHwmonSensors() ([]HwmonSensor, error)
+	// Get attributes of NVMe controllers from /sys/class/nvme
+	GetNVMeControllers() ([]NVMeController, error)
+	// Get attributes of PCI devices from /sys/bus/pci/devices
+	GetPCIDevices() ([]PCIDevice, error)
+	// Get state and counters of InfiniBand ports from /sys/class/infiniband
+	GetInfinibandPorts() ([]InfinibandPort, error)
+	// Get state of CPU vulnerabilities by vulnerability name, e.g. "meltdown": "Mitigation: PTI"
+	GetCPUVulnerabilities() (map[string]string, error)
+	// Get list of online CPUs in cpulist format, e.g. 0-3,5
+	GetOnlineCPUs() (string, error)
+	// Get list of offline CPUs in cpulist format, empty when all CPUs are online
+	GetOfflineCPUs() (string, error)
+	// Get paths to hotpluggable memory blocks, e.g. /sys/devices/system/memory/memory32
+	GetMemoryBlocksPaths() ([]string, error)
+	// Get state of memory block, e.g. online or offline
+	GetMemoryBlockState(memoryBlockPath string) (string, error)
+	// Get raw SMBIOS structures of specified type, e.g. 17 for memory devices
+	GetDMIEntries(entryType int) ([][]byte, error)
+	// Get paths to RAPL powercap zones and subzones, e.g. /sys/class/powercap/intel-rapl:0
+	GetPowercapZones() ([]string, error)
+	// Get name of powercap zone, e.g. package-0 or dram
+	GetPowercapZoneName(zonePath string) (string, error)
+	// Get energy counter of powercap zone in microjoules
+	GetEnergyUJ(zonePath string) (string, error)
+
 	GetSystemUUID() (string, error)
 	// IsCPUOnline determines if CPU status from kernel hotplug machanism standpoint.
 	// See: https://www.kernel.org/doc/html/latest/core-api/cpu_hotplug.html
 	IsCPUOnline(dir string) bool
 }
 
-type realSysFs struct{}
+type realSysFs struct {
+	// root is prepended to all sysfs and procfs paths, empty for the root of filesystem.
+	root string
+}
 
 func NewRealSysFs() SysFs {
 	return &realSysFs{}
 }
 
+// NewRealSysFsWithRoot returns SysFs reading sysfs and procfs files under given root,
+// e.g. /host when cAdvisor runs in a container with the host filesystem mounted at /host.
+func NewRealSysFsWithRoot(root string) SysFs {
+	root = filepath.Clean(root)
+	if root == "/" || root == "." {
+		root = ""
+	}
+	return &realSysFs{root: root}
+}
+
+// hostPath returns given path located under root. Paths which already point under root,
+// e.g. returned by GetNodesPaths(), are returned without changes.
+func (fs *realSysFs) hostPath(p string) string {
+	if fs.root == "" || strings.HasPrefix(p, fs.root+"/") {
+		return p
+	}
+	return fs.root + p
+}
+
 func (fs *realSysFs) GetNodesPaths() ([]string, error) {
-	pathPattern := fmt.Sprintf("%s%s", nodeDir, nodeDirPattern)
+	pathPattern := fmt.Sprintf("%s%s", fs.hostPath(nodeDir), nodeDirPattern)
 	return filepath.Glob(pathPattern)
 }
 
 func (fs *realSysFs) GetCPUsPaths(cpusPath string) ([]string, error) {
-	pathPattern := fmt.Sprintf("%s/%s", cpusPath, cpuDirPattern)
+	pathPattern := fmt.Sprintf("%s/%s", fs.hostPath(cpusPath), cpuDirPattern)
 	return filepath.Glob(pathPattern)
 }
 
 func (fs *realSysFs) GetCoreID(cpuPath string) (string, error) {
-	coreIDFilePath := fmt.Sprintf("%s%s", cpuPath, coreIDFilePath)
+	coreIDFilePath := fmt.Sprintf("%s%s", fs.hostPath(cpuPath), coreIDFilePath)
 	coreID, err := ioutil.ReadFile(coreIDFilePath)
 	if err != nil {
 		return "", err
@@ -131,7 +362,7 @@ func (fs *realSysFs) GetCoreID(cpuPath string) (string, error) {
 }
 
 func (fs *realSysFs) GetCPUPhysicalPackageID(cpuPath string) (string, error) {
-	packageIDFilePath := fmt.Sprintf("%s%s", cpuPath, packageIDFilePath)
+	packageIDFilePath := fmt.Sprintf("%s%s", fs.hostPath(cpuPath), packageIDFilePath)
 	packageID, err := ioutil.ReadFile(packageIDFilePath)
 	if err != nil {
 		return "", err
@@ -139,8 +370,88 @@ func (fs *realSysFs) GetCPUPhysicalPackageID(cpuPath string) (string, error) {
 	return strings.TrimSpace(string(packageID)), err
 }
 
+func (fs *realSysFs) GetDieID(cpuPath string) (string, error) {
+	dieID, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), dieIDFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(dieID)), nil
+}
+
+func (fs *realSysFs) GetClusterID(cpuPath string) (string, error) {
+	clusterID, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), clusterIDFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(clusterID)), nil
+}
+
+func (fs *realSysFs) GetBookID(cpuPath string) (string, error) {
+	bookID, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), bookIDFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bookID)), nil
+}
+
+func (fs *realSysFs) GetDrawerID(cpuPath string) (string, error) {
+	drawerID, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), drawerIDFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(drawerID)), nil
+}
+
+func (fs *realSysFs) GetCPUCapacity(cpuPath string) (string, error) {
+	capacity, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), capacityFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(capacity)), nil
+}
+
+func (fs *realSysFs) GetCPUMaxFrequency(cpuPath string) (string, error) {
+	maxFreq, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), maxFreqFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(maxFreq)), nil
+}
+
+func (fs *realSysFs) GetCPUCurrentFrequency(cpuPath string) (string, error) {
+	curFreq, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), curFreqFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(curFreq)), nil
+}
+
+func (fs *realSysFs) GetCPUScalingGovernor(cpuPath string) (string, error) {
+	governor, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), governorFilePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(governor)), nil
+}
+
+func (fs *realSysFs) GetCPUTimeInState(cpuPath string) (string, error) {
+	timeInState, err := ioutil.ReadFile(fmt.Sprintf("%s%s", fs.hostPath(cpuPath), timeInStateFilePath))
+	if err != nil {
+		return "", err
+	}
+	return string(timeInState), nil
+}
+
+func (fs *realSysFs) GetPMUCPUs(pmu string) (string, error) {
+	cpus, err := ioutil.ReadFile(path.Join(fs.hostPath(devicesDir), pmu, "cpus"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(cpus)), nil
+}
+
 func (fs *realSysFs) GetMemInfo(nodePath string) (string, error) {
-	meminfoPath := fmt.Sprintf("%s/%s", nodePath, meminfoFile)
+	meminfoPath := fmt.Sprintf("%s/%s", fs.hostPath(nodePath), meminfoFile)
 	meminfo, err := ioutil.ReadFile(meminfoPath)
 	if err != nil {
 		return "", err
@@ -148,12 +459,21 @@ func (fs *realSysFs) GetMemInfo(nodePath string) (string, error) {
 	return strings.TrimSpace(string(meminfo)), err
 }
 
+func (fs *realSysFs) GetDistances(nodePath string) (string, error) {
+	distancePath := fmt.Sprintf("%s/%s", fs.hostPath(nodePath), distanceFile)
+	distance, err := ioutil.ReadFile(distancePath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(distance)), err
+}
+
 func (fs *realSysFs) GetHugePagesInfo(hugePagesDirectory string) ([]os.FileInfo, error) {
-	return ioutil.ReadDir(hugePagesDirectory)
+	return ioutil.ReadDir(fs.hostPath(hugePagesDirectory))
 }
 
 func (fs *realSysFs) GetHugePagesNr(hugepagesDirectory string, hugePageName string) (string, error) {
-	hugePageFilePath := fmt.Sprintf("%s%s/%s", hugepagesDirectory, hugePageName, HugePagesNrFile)
+	hugePageFilePath := fmt.Sprintf("%s%s/%s", fs.hostPath(hugepagesDirectory), hugePageName, HugePagesNrFile)
 	hugePageFile, err := ioutil.ReadFile(hugePageFilePath)
 	if err != nil {
 		return "", err
@@ -161,12 +481,21 @@ func (fs *realSysFs) GetHugePagesNr(hugepagesDirectory string, hugePageName stri
 	return strings.TrimSpace(string(hugePageFile)), err
 }
 
+func (fs *realSysFs) GetHugePagesCounter(hugepagesDirectory string, hugePageName string, counterFile string) (string, error) {
+	counterFilePath := fmt.Sprintf("%s%s/%s", fs.hostPath(hugepagesDirectory), hugePageName, counterFile)
+	counter, err := ioutil.ReadFile(counterFilePath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(counter)), nil
+}
+
 func (fs *realSysFs) GetBlockDevices() ([]os.FileInfo, error) {
-	return ioutil.ReadDir(blockDir)
+	return ioutil.ReadDir(fs.hostPath(blockDir))
 }
 
 func (fs *realSysFs) GetBlockDeviceNumbers(name string) (string, error) {
-	dev, err := ioutil.ReadFile(path.Join(blockDir, name, "/dev"))
+	dev, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "/dev"))
 	if err != nil {
 		return "", err
 	}
@@ -174,15 +503,59 @@ func (fs *realSysFs) GetBlockDeviceNumbers(name string) (string, error) {
 }
 
 func (fs *realSysFs) GetBlockDeviceScheduler(name string) (string, error) {
-	sched, err := ioutil.ReadFile(path.Join(blockDir, name, "/queue/scheduler"))
+	sched, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "/queue/scheduler"))
 	if err != nil {
 		return "", err
 	}
 	return string(sched), nil
 }
 
+func (fs *realSysFs) GetBlockDeviceQueueAttribute(name string, attribute string) (string, error) {
+	value, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "queue", attribute))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceStat(name string) (string, error) {
+	stat, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "stat"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(stat)), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	files, err := ioutil.ReadDir(path.Join(fs.hostPath(blockDir), name, "slaves"))
+	if err != nil {
+		return nil, err
+	}
+	slaves := make([]string, 0, len(files))
+	for _, file := range files {
+		slaves = append(slaves, file.Name())
+	}
+	return slaves, nil
+}
+
+func (fs *realSysFs) GetBlockDeviceRaidLevel(name string) (string, error) {
+	level, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "md", "level"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(level)), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceDMName(name string) (string, error) {
+	dmName, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "dm", "name"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(dmName)), nil
+}
+
 func (fs *realSysFs) GetBlockDeviceSize(name string) (string, error) {
-	size, err := ioutil.ReadFile(path.Join(blockDir, name, "/size"))
+	size, err := ioutil.ReadFile(path.Join(fs.hostPath(blockDir), name, "/size"))
 	if err != nil {
 		return "", err
 	}
@@ -190,7 +563,7 @@ func (fs *realSysFs) GetBlockDeviceSize(name string) (string, error) {
 }
 
 func (fs *realSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
-	files, err := ioutil.ReadDir(netDir)
+	files, err := ioutil.ReadDir(fs.hostPath(netDir))
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +572,7 @@ func (fs *realSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
 	var dirs []os.FileInfo
 	for _, f := range files {
 		if f.Mode()|os.ModeSymlink != 0 {
-			f, err = os.Stat(path.Join(netDir, f.Name()))
+			f, err = os.Stat(path.Join(fs.hostPath(netDir), f.Name()))
 			if err != nil {
 				continue
 			}
@@ -212,7 +585,7 @@ func (fs *realSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
 }
 
 func (fs *realSysFs) GetNetworkAddress(name string) (string, error) {
-	address, err := ioutil.ReadFile(path.Join(netDir, name, "/address"))
+	address, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/address"))
 	if err != nil {
 		return "", err
 	}
@@ -220,7 +593,7 @@ func (fs *realSysFs) GetNetworkAddress(name string) (string, error) {
 }
 
 func (fs *realSysFs) GetNetworkMtu(name string) (string, error) {
-	mtu, err := ioutil.ReadFile(path.Join(netDir, name, "/mtu"))
+	mtu, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/mtu"))
 	if err != nil {
 		return "", err
 	}
@@ -228,15 +601,137 @@ func (fs *realSysFs) GetNetworkMtu(name string) (string, error) {
 }
 
 func (fs *realSysFs) GetNetworkSpeed(name string) (string, error) {
-	speed, err := ioutil.ReadFile(path.Join(netDir, name, "/speed"))
+	speed, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/speed"))
 	if err != nil {
 		return "", err
 	}
 	return string(speed), nil
 }
 
+func (fs *realSysFs) GetNetworkOperState(name string) (string, error) {
+	operState, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/operstate"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(operState)), nil
+}
+
+func (fs *realSysFs) GetNetworkDuplex(name string) (string, error) {
+	duplex, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/duplex"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(duplex)), nil
+}
+
+func (fs *realSysFs) GetNetworkDriver(name string) (string, error) {
+	// Virtual devices (e.g. bridge, bond) do not have device/driver link.
+	driver, err := os.Readlink(path.Join(fs.hostPath(netDir), name, "/device/driver"))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(driver), nil
+}
+
+func (fs *realSysFs) GetNetworkDeviceType(name string) (string, error) {
+	uevent, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/uevent"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(uevent), "\n") {
+		if strings.HasPrefix(line, "DEVTYPE=") {
+			return strings.TrimPrefix(line, "DEVTYPE="), nil
+		}
+	}
+	return "", nil
+}
+
+func (fs *realSysFs) GetNetworkBondSlaves(name string) ([]string, error) {
+	slaves, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "/bonding/slaves"))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(slaves)), nil
+}
+
+func (fs *realSysFs) GetNetworkBridgePorts(name string) ([]string, error) {
+	files, err := ioutil.ReadDir(path.Join(fs.hostPath(netDir), name, "/brif"))
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]string, 0, len(files))
+	for _, file := range files {
+		ports = append(ports, file.Name())
+	}
+	return ports, nil
+}
+
+func (fs *realSysFs) GetNetworkLowerDevices(name string) ([]string, error) {
+	links, err := filepath.Glob(path.Join(fs.hostPath(netDir), name, "lower_*"))
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]string, 0, len(links))
+	for _, link := range links {
+		devices = append(devices, strings.TrimPrefix(filepath.Base(link), "lower_"))
+	}
+	return devices, nil
+}
+
+func (fs *realSysFs) GetNetworkPCIAddress(name string) (string, error) {
+	device, err := os.Readlink(path.Join(fs.hostPath(netDir), name, "/device"))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(device), nil
+}
+
+func (fs *realSysFs) GetNetworkPCIAttribute(name string, attribute string) (string, error) {
+	value, err := ioutil.ReadFile(path.Join(fs.hostPath(netDir), name, "device", attribute))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+func (fs *realSysFs) GetNetworkVirtualFunctions(name string) (map[int]string, error) {
+	links, err := filepath.Glob(path.Join(fs.hostPath(netDir), name, "device", "virtfn*"))
+	if err != nil {
+		return nil, err
+	}
+	functions := make(map[int]string, len(links))
+	for _, link := range links {
+		index, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "virtfn"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse index of virtual function %s: %v", link, err)
+		}
+		address, err := os.Readlink(link)
+		if err != nil {
+			return nil, err
+		}
+		functions[index] = filepath.Base(address)
+	}
+	return functions, nil
+}
+
+func (fs *realSysFs) GetNetworkQueues(name string) ([]string, error) {
+	files, err := ioutil.ReadDir(path.Join(fs.hostPath(netDir), name, "/queues"))
+	if err != nil {
+		return nil, err
+	}
+	queues := make([]string, 0, len(files))
+	for _, file := range files {
+		queues = append(queues, file.Name())
+	}
+	return queues, nil
+}
+
+func (fs *realSysFs) GetNetworkEthtoolStats(name string) (map[string]uint64, error) {
+	return ethtool.GetStats(name)
+}
+
 func (fs *realSysFs) GetNetworkStatValue(dev string, stat string) (uint64, error) {
-	statPath := path.Join(netDir, dev, "/statistics", stat)
+	statPath := path.Join(fs.hostPath(netDir), dev, "/statistics", stat)
 	out, err := ioutil.ReadFile(statPath)
 	if err != nil {
 		return 0, fmt.Errorf("failed to read stat from %q for device %q", statPath, dev)
@@ -250,7 +745,7 @@ func (fs *realSysFs) GetNetworkStatValue(dev string, stat string) (uint64, error
 }
 
 func (fs *realSysFs) GetCaches(id int) ([]os.FileInfo, error) {
-	cpuPath := fmt.Sprintf("%s%d/cache", cacheDir, id)
+	cpuPath := fmt.Sprintf("%s%d/cache", fs.hostPath(cacheDir), id)
 	return ioutil.ReadDir(cpuPath)
 }
 
@@ -264,25 +759,66 @@ func bitCount(i uint64) (count int) {
 	return
 }
 
-func getCPUCount(cache string) (count int, err error) {
-	out, err := ioutil.ReadFile(path.Join(cache, "/shared_cpu_map"))
+// getCPUCount returns number of CPUs sharing cache. shared_cpu_list is preferred as it does not depend
+// on the width of mask, shared_cpu_map is used when the list is not available.
+func getCPUCount(cache string) (int, error) {
+	out, err := ioutil.ReadFile(path.Join(cache, "/shared_cpu_list"))
+	if err == nil {
+		return parseCPUListCount(string(out))
+	}
+	if !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	out, err = ioutil.ReadFile(path.Join(cache, "/shared_cpu_map"))
 	if err != nil {
 		return 0, err
 	}
-	masks := strings.Split(string(out), ",")
-	for _, mask := range masks {
-		// convert hex string to uint64
-		m, err := strconv.ParseUint(strings.TrimSpace(mask), 16, 64)
+	return parseCPUMapCount(string(out))
+}
+
+// parseCPUListCount counts CPUs in list of ranges, e.g. "0-63,128-191".
+func parseCPUListCount(cpuList string) (count int, err error) {
+	cpuList = strings.TrimSpace(cpuList)
+	if cpuList == "" {
+		return 0, nil
+	}
+	for _, cpuRange := range strings.Split(cpuList, ",") {
+		bounds := strings.SplitN(cpuRange, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse cpu list %q: %v", cpuList, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse cpu list %q: %v", cpuList, err)
+			}
+		}
+		if last < first {
+			return 0, fmt.Errorf("invalid range %q in cpu list %q", cpuRange, cpuList)
+		}
+		count += last - first + 1
+	}
+	return count, nil
+}
+
+// parseCPUMapCount counts CPUs in mask consisting of comma-separated 32-bit hex words,
+// e.g. "00000000,ffffffff,ffffffff".
+func parseCPUMapCount(cpuMap string) (count int, err error) {
+	for _, mask := range strings.Split(strings.TrimSpace(cpuMap), ",") {
+		m, err := strconv.ParseUint(mask, 16, 32)
 		if err != nil {
-			return 0, fmt.Errorf("failed to parse cpu map %q: %v", string(out), err)
+			return 0, fmt.Errorf("failed to parse cpu map %q: %v", cpuMap, err)
 		}
 		count += bitCount(m)
 	}
-	return
+	return count, nil
 }
 
 func (fs *realSysFs) GetCacheInfo(id int, name string) (CacheInfo, error) {
-	cachePath := fmt.Sprintf("%s%d/cache/%s", cacheDir, id, name)
+	cachePath := fmt.Sprintf("%s%d/cache/%s", fs.hostPath(cacheDir), id, name)
 	out, err := ioutil.ReadFile(path.Join(cachePath, "/size"))
 	if err != nil {
 		return CacheInfo{}, err
@@ -313,30 +849,400 @@ func (fs *realSysFs) GetCacheInfo(id int, name string) (CacheInfo, error) {
 	if err != nil {
 		return CacheInfo{}, err
 	}
+	cpuList := ""
+	if out, err = ioutil.ReadFile(path.Join(cachePath, "/shared_cpu_list")); err == nil {
+		cpuList = strings.TrimSpace(string(out))
+	}
 	return CacheInfo{
-		Size:  size,
-		Level: level,
-		Type:  cacheType,
-		Cpus:  cpuCount,
+		Size:    size,
+		Level:   level,
+		Type:    cacheType,
+		Cpus:    cpuCount,
+		CPUList: cpuList,
 	}, nil
 }
 
+func (fs *realSysFs) GetHwmonSensors() ([]HwmonSensor, error) {
+	return readHwmonSensors(path.Join(fs.hostPath(hwmonDir), hwmonDirPattern))
+}
+
+func readHwmonSensors(pattern string) ([]HwmonSensor, error) {
+	devicePaths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	sensors := []HwmonSensor{}
+	for _, devicePath := range devicePaths {
+		name, err := ioutil.ReadFile(path.Join(devicePath, "name"))
+		if err != nil {
+			return nil, err
+		}
+		inputPaths, err := filepath.Glob(path.Join(devicePath, "temp*_input"))
+		if err != nil {
+			return nil, err
+		}
+		for _, inputPath := range inputPaths {
+			input, err := ioutil.ReadFile(inputPath)
+			if err != nil {
+				// Reading of sensor fails when it is not available, e.g. device is suspended.
+				klog.V(4).Infof("Cannot read temperature from %s: %s", inputPath, err)
+				continue
+			}
+			temperature, err := strconv.ParseInt(strings.TrimSpace(string(input)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse temperature from %s: %v", inputPath, err)
+			}
+			sensor := strings.TrimSuffix(filepath.Base(inputPath), "_input")
+			label, err := ioutil.ReadFile(path.Join(devicePath, sensor+"_label"))
+			if err == nil {
+				sensor = strings.TrimSpace(string(label))
+			}
+			sensors = append(sensors, HwmonSensor{
+				Device:      strings.TrimSpace(string(name)),
+				Label:       sensor,
+				Temperature: temperature,
+			})
+		}
+	}
+	return sensors, nil
+}
+
+func (fs *realSysFs) GetNVMeControllers() ([]NVMeController, error) {
+	controllerPaths, err := filepath.Glob(path.Join(fs.hostPath(nvmeDir), nvmeDirPattern))
+	if err != nil {
+		return nil, err
+	}
+
+	controllers := make([]NVMeController, 0, len(controllerPaths))
+	for _, controllerPath := range controllerPaths {
+		name := filepath.Base(controllerPath)
+		model, err := ioutil.ReadFile(path.Join(controllerPath, "model"))
+		if err != nil {
+			return nil, err
+		}
+		firmware, err := ioutil.ReadFile(path.Join(controllerPath, "firmware_rev"))
+		if err != nil {
+			return nil, err
+		}
+		// Namespaces are named nvme0n1 or nvme0c0n1 when native NVMe multipath is enabled.
+		namespacePaths, err := filepath.Glob(path.Join(controllerPath, "nvme*n*[0-9]"))
+		if err != nil {
+			return nil, err
+		}
+		namespaces := make(map[string]uint64, len(namespacePaths))
+		for _, namespacePath := range namespacePaths {
+			size, err := ioutil.ReadFile(path.Join(namespacePath, "size"))
+			if err != nil {
+				return nil, err
+			}
+			namespaces[filepath.Base(namespacePath)], err = strconv.ParseUint(strings.TrimSpace(string(size)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse size of NVMe namespace %s: %v", namespacePath, err)
+			}
+		}
+		sensors, err := readHwmonSensors(path.Join(controllerPath, hwmonDirPattern))
+		if err != nil {
+			return nil, err
+		}
+		controllers = append(controllers, NVMeController{
+			Name: name,
+			// Model is padded with spaces to the length of identify controller field.
+			Model:      strings.TrimSpace(string(model)),
+			Firmware:   strings.TrimSpace(string(firmware)),
+			Namespaces: namespaces,
+			Sensors:    sensors,
+		})
+	}
+	return controllers, nil
+}
+
+func (fs *realSysFs) GetPCIDevices() ([]PCIDevice, error) {
+	devicePaths, err := filepath.Glob(path.Join(fs.hostPath(pciDir), "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]PCIDevice, 0, len(devicePaths))
+	for _, devicePath := range devicePaths {
+		vendor, err := ioutil.ReadFile(path.Join(devicePath, "vendor"))
+		if err != nil {
+			return nil, err
+		}
+		device, err := ioutil.ReadFile(path.Join(devicePath, "device"))
+		if err != nil {
+			return nil, err
+		}
+		class, err := ioutil.ReadFile(path.Join(devicePath, "class"))
+		if err != nil {
+			return nil, err
+		}
+		pciDevice := PCIDevice{
+			Address:          filepath.Base(devicePath),
+			Vendor:           strings.TrimSpace(string(vendor)),
+			Device:           strings.TrimSpace(string(device)),
+			Class:            strings.TrimSpace(string(class)),
+			NumaNode:         readOptionalAttribute(devicePath, "numa_node"),
+			CurrentLinkSpeed: readOptionalAttribute(devicePath, "current_link_speed"),
+			MaxLinkSpeed:     readOptionalAttribute(devicePath, "max_link_speed"),
+			CurrentLinkWidth: readOptionalAttribute(devicePath, "current_link_width"),
+			MaxLinkWidth:     readOptionalAttribute(devicePath, "max_link_width"),
+		}
+		if group, err := os.Readlink(path.Join(devicePath, "iommu_group")); err == nil {
+			pciDevice.IOMMUGroup = filepath.Base(group)
+		}
+		devices = append(devices, pciDevice)
+	}
+	return devices, nil
+}
+
+// readOptionalAttribute returns content of attribute file, empty string is returned when file cannot be read.
+func readOptionalAttribute(devicePath string, attribute string) string {
+	value, err := ioutil.ReadFile(path.Join(devicePath, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(value))
+}
+
+func (fs *realSysFs) GetInfinibandPorts() ([]InfinibandPort, error) {
+	portPaths, err := filepath.Glob(path.Join(fs.hostPath(ibDir), "*", "ports", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]InfinibandPort, 0, len(portPaths))
+	for _, portPath := range portPaths {
+		port, err := strconv.Atoi(filepath.Base(portPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse InfiniBand port number from %s: %v", portPath, err)
+		}
+		// State is reported as "4: ACTIVE".
+		state, err := ioutil.ReadFile(path.Join(portPath, "state"))
+		if err != nil {
+			return nil, err
+		}
+		stateFields := strings.SplitN(strings.TrimSpace(string(state)), ":", 2)
+		counterPaths, err := filepath.Glob(path.Join(portPath, "counters", "*"))
+		if err != nil {
+			return nil, err
+		}
+		counters := make(map[string]uint64, len(counterPaths))
+		for _, counterPath := range counterPaths {
+			value, err := ioutil.ReadFile(counterPath)
+			if err != nil {
+				// Some counters are not supported by all HCAs and reading of them fails.
+				klog.V(4).Infof("Cannot read InfiniBand counter from %s: %s", counterPath, err)
+				continue
+			}
+			counters[filepath.Base(counterPath)], err = strconv.ParseUint(strings.TrimSpace(string(value)), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse InfiniBand counter from %s: %v", counterPath, err)
+			}
+		}
+		ports = append(ports, InfinibandPort{
+			Device:   filepath.Base(filepath.Dir(filepath.Dir(portPath))),
+			Port:     port,
+			State:    strings.TrimSpace(stateFields[len(stateFields)-1]),
+			Counters: counters,
+		})
+	}
+	return ports, nil
+}
+
+func (fs *realSysFs) GetCPUVulnerabilities() (map[string]string, error) {
+	files, err := ioutil.ReadDir(fs.hostPath(vulnsDir))
+	if err != nil {
+		return nil, err
+	}
+	vulnerabilities := make(map[string]string, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		state, err := ioutil.ReadFile(path.Join(fs.hostPath(vulnsDir), file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		vulnerabilities[file.Name()] = strings.TrimSpace(string(state))
+	}
+	return vulnerabilities, nil
+}
+
+func (fs *realSysFs) GetOnlineCPUs() (string, error) {
+	online, err := ioutil.ReadFile(fs.hostPath(onlineFile))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(online)), nil
+}
+
+func (fs *realSysFs) GetOfflineCPUs() (string, error) {
+	offline, err := ioutil.ReadFile(fs.hostPath(offlineFile))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(offline)), nil
+}
+
+func (fs *realSysFs) GetMemoryBlocksPaths() ([]string, error) {
+	return filepath.Glob(path.Join(fs.hostPath(memoryDir), memoryBlockPattern))
+}
+
+func (fs *realSysFs) GetMemoryBlockState(memoryBlockPath string) (string, error) {
+	state, err := ioutil.ReadFile(path.Join(fs.hostPath(memoryBlockPath), "state"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(state)), nil
+}
+
+func (fs *realSysFs) GetDMIEntries(entryType int) ([][]byte, error) {
+	entryPaths, err := filepath.Glob(path.Join(fs.hostPath(dmiEntryDir), fmt.Sprintf("%d-*", entryType)))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([][]byte, 0, len(entryPaths))
+	for _, entryPath := range entryPaths {
+		raw, err := ioutil.ReadFile(path.Join(entryPath, "raw"))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, raw)
+	}
+	return entries, nil
+}
+
+func (fs *realSysFs) GetPowercapZones() ([]string, error) {
+	return filepath.Glob(path.Join(fs.hostPath(powercapDir), raplZoneDirPattern))
+}
+
+func (fs *realSysFs) GetPowercapZoneName(zonePath string) (string, error) {
+	name, err := ioutil.ReadFile(path.Join(fs.hostPath(zonePath), "name"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(name)), nil
+}
+
+func (fs *realSysFs) GetEnergyUJ(zonePath string) (string, error) {
+	energy, err := ioutil.ReadFile(path.Join(fs.hostPath(zonePath), "energy_uj"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(energy)), nil
+}
+
+// GetSystemUUID returns the first valid identifier of the machine found in DMI, raw SMBIOS tables
+// (available also when DMI id driver is not, e.g. on EFI systems without CONFIG_DMIID), device tree
+// (system-id and vm,uuid on ppc64, serial-number on boards like Raspberry Pi) or s390x machine-id.
 func (fs *realSysFs) GetSystemUUID() (string, error) {
-	if id, err := ioutil.ReadFile(path.Join(dmiDir, "id", "product_uuid")); err == nil {
-		return strings.TrimSpace(string(id)), nil
-	} else if id, err = ioutil.ReadFile(path.Join(ppcDevTree, "system-id")); err == nil {
-		return strings.TrimSpace(string(id)), nil
-	} else if id, err = ioutil.ReadFile(path.Join(ppcDevTree, "vm,uuid")); err == nil {
-		return strings.TrimSpace(string(id)), nil
-	} else if id, err = ioutil.ReadFile(path.Join(s390xDevTree, "machine-id")); err == nil {
-		return strings.TrimSpace(string(id)), nil
-	} else {
+	readers := []func() (string, error){
+		fs.systemUUIDReader(path.Join(dmiDir, "id", "product_uuid")),
+		fs.getSMBIOSSystemUUID,
+		fs.systemUUIDReader(path.Join(ppcDevTree, "system-id")),
+		fs.systemUUIDReader(path.Join(ppcDevTree, "vm,uuid")),
+		fs.systemUUIDReader(path.Join(ppcDevTree, "serial-number")),
+		fs.systemUUIDReader(path.Join(s390xDevTree, "machine-id")),
+	}
+	var err error
+	for _, read := range readers {
+		var id string
+		if id, err = read(); err == nil {
+			return id, nil
+		}
+	}
+	return "", err
+}
+
+func (fs *realSysFs) systemUUIDReader(file string) func() (string, error) {
+	return func() (string, error) {
+		content, err := ioutil.ReadFile(fs.hostPath(file))
+		if err != nil {
+			return "", err
+		}
+		// Device tree properties are null-terminated.
+		id := strings.TrimSpace(strings.TrimRight(string(content), "\x00"))
+		if !isValidSystemUUID(id) {
+			return "", fmt.Errorf("invalid system UUID %q in %s", id, file)
+		}
+		return id, nil
+	}
+}
+
+func (fs *realSysFs) getSMBIOSSystemUUID() (string, error) {
+	entryPoint, err := ioutil.ReadFile(path.Join(fs.hostPath(smbiosTablesDir), "smbios_entry_point"))
+	if err != nil {
 		return "", err
 	}
+	tables, err := ioutil.ReadFile(path.Join(fs.hostPath(smbiosTablesDir), "DMI"))
+	if err != nil {
+		return "", err
+	}
+	id, err := parseSMBIOSSystemUUID(entryPoint, tables)
+	if err != nil {
+		return "", err
+	}
+	if !isValidSystemUUID(id) {
+		return "", fmt.Errorf("invalid system UUID %q in SMBIOS tables", id)
+	}
+	return id, nil
+}
+
+// parseSMBIOSSystemUUID returns UUID from System Information (type 1) structure of SMBIOS tables,
+// formatted the same way as product_uuid in DMI id directory, see DSP0134 section 7.2.1.
+func parseSMBIOSSystemUUID(entryPoint []byte, tables []byte) (string, error) {
+	var major, minor byte
+	switch {
+	case bytes.HasPrefix(entryPoint, []byte("_SM3_")) && len(entryPoint) > 8:
+		major, minor = entryPoint[7], entryPoint[8]
+	case bytes.HasPrefix(entryPoint, []byte("_SM_")) && len(entryPoint) > 7:
+		major, minor = entryPoint[6], entryPoint[7]
+	default:
+		return "", fmt.Errorf("unsupported SMBIOS entry point")
+	}
+
+	for offset := 0; offset+4 <= len(tables); {
+		structType, length := tables[offset], int(tables[offset+1])
+		if length < 4 || offset+length > len(tables) {
+			return "", fmt.Errorf("invalid SMBIOS structure at offset %d", offset)
+		}
+		if structType == smbiosSystemInformationType {
+			if length < smbiosUUIDOffset+16 {
+				return "", fmt.Errorf("SMBIOS system information structure does not contain UUID")
+			}
+			u := tables[offset+smbiosUUIDOffset : offset+smbiosUUIDOffset+16]
+			// Since SMBIOS 2.6 the first three fields of UUID are little-endian.
+			if major > 2 || (major == 2 && minor >= 6) {
+				return fmt.Sprintf("%02x%02x%02x%02x-%02x%02x-%02x%02x-%x-%x", u[3], u[2], u[1], u[0], u[5], u[4], u[7], u[6], u[8:10], u[10:]), nil
+			}
+			return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+		}
+		if structType == smbiosEndOfTableType {
+			break
+		}
+		// Formatted area is followed by strings terminated by double null.
+		end := bytes.Index(tables[offset+length:], []byte{0, 0})
+		if end < 0 {
+			break
+		}
+		offset += length + end + 2
+	}
+	return "", fmt.Errorf("SMBIOS system information structure not found")
+}
+
+// isValidSystemUUID returns false for empty identifiers and placeholders used by firmware
+// when UUID is not set, e.g. 00000000-0000-0000-0000-000000000000 or "Not Settable".
+func isValidSystemUUID(id string) bool {
+	if len(id) == 0 || strings.HasPrefix(id, "Not ") {
+		return false
+	}
+	digits := strings.Replace(id, "-", "", -1)
+	return strings.Trim(digits, "0") != "" && strings.Trim(strings.ToLower(digits), "f") != ""
 }
 
 func (fs *realSysFs) IsCPUOnline(dir string) bool {
-	cpuPath := fmt.Sprintf("%s/online", dir)
+	cpuPath := fmt.Sprintf("%s/online", fs.hostPath(dir))
 	content, err := ioutil.ReadFile(cpuPath)
 	if err != nil {
 		pathErr, ok := err.(*os.PathError)
@@ -77,6 +77,50 @@ func TestGetCoreIDWhenFileIsMissing(t *testing.T) {
 	assert.Equal(t, "", rawCoreID)
 }
 
+func TestGetDieIDAndClusterID(t *testing.T) {
+	sysFs := NewRealSysFs()
+	dieID, err := sysFs.GetDieID("./testdata/node0/cpu0")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", dieID)
+
+	clusterID, err := sysFs.GetClusterID("./testdata/node0/cpu0")
+	assert.Nil(t, err)
+	assert.Equal(t, "8", clusterID)
+}
+
+func TestGetDieIDAndClusterIDWhenFilesAreMissing(t *testing.T) {
+	sysFs := NewRealSysFs()
+	_, err := sysFs.GetDieID("./testdata/node0/cpu1")
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = sysFs.GetClusterID("./testdata/node0/cpu1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetCPUCapacityAndMaxFrequency(t *testing.T) {
+	sysFs := NewRealSysFs()
+	capacity, err := sysFs.GetCPUCapacity("./testdata/node0/cpu0")
+	assert.Nil(t, err)
+	assert.Equal(t, "512", capacity)
+
+	maxFreq, err := sysFs.GetCPUMaxFrequency("./testdata/node0/cpu0")
+	assert.Nil(t, err)
+	assert.Equal(t, "3600000", maxFreq)
+
+	_, err = sysFs.GetCPUCapacity("./testdata/node0/cpu1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetPMUCPUs(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	cpus, err := sysFs.GetPMUCPUs("cpu_atom")
+	assert.Nil(t, err)
+	assert.Equal(t, "16-23", cpus)
+
+	_, err = sysFs.GetPMUCPUs("cpu_core")
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestGetMemInfo(t *testing.T) {
 	sysFs := NewRealSysFs()
 	memInfo, err := sysFs.GetMemInfo("./testdata/node0")
@@ -91,6 +135,20 @@ func TestGetMemInfoWhenFileIsMissing(t *testing.T) {
 	assert.Equal(t, "", memInfo)
 }
 
+func TestGetDistances(t *testing.T) {
+	sysFs := NewRealSysFs()
+	distances, err := sysFs.GetDistances("./testdata/node0")
+	assert.Nil(t, err)
+	assert.Equal(t, "10 21", distances)
+}
+
+func TestGetDistancesWhenFileIsMissing(t *testing.T) {
+	sysFs := NewRealSysFs()
+	distances, err := sysFs.GetDistances("./testdata/node1")
+	assert.NotNil(t, err)
+	assert.Equal(t, "", distances)
+}
+
 func TestGetHugePagesInfo(t *testing.T) {
 	sysFs := NewRealSysFs()
 	hugePages, err := sysFs.GetHugePagesInfo("./testdata/node0/hugepages")
@@ -141,3 +199,351 @@ func TestIsCPUOnlineNoFileAndCPU0MustBeOnline(t *testing.T) {
 	online = sysFs.IsCPUOnline("./testdata/missing_online/node0/cpu33")
 	assert.False(t, online)
 }
+
+func TestGetNodesWithRoot(t *testing.T) {
+	//overwrite global variable
+	nodeDir = "/sys/devices/system/node/"
+
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	nodesDirs, err := sysFs.GetNodesPaths()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"testdata/host/sys/devices/system/node/node0"}, nodesDirs)
+
+	// paths returned by sysfs are not prefixed with root again
+	memInfo, err := sysFs.GetMemInfo(nodesDirs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "Node 0 MemTotal:       16408596 kB", memInfo)
+}
+
+func TestGetCPUsPathsWithRoot(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host/")
+	cpuDirs, err := sysFs.GetCPUsPaths("/sys/devices/system/cpu")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"testdata/host/sys/devices/system/cpu/cpu0"}, cpuDirs)
+	assert.True(t, sysFs.IsCPUOnline(cpuDirs[0]))
+}
+
+func TestGetCPUCountFromSharedCPUList(t *testing.T) {
+	count, err := getCPUCount("./testdata/cache/list")
+	assert.Nil(t, err)
+	assert.Equal(t, 128, count)
+}
+
+func TestGetCPUCountFromSharedCPUMapWiderThan64CPUs(t *testing.T) {
+	count, err := getCPUCount("./testdata/cache/map")
+	assert.Nil(t, err)
+	assert.Equal(t, 3*32+17, count)
+}
+
+func TestParseCPUListCount(t *testing.T) {
+	count, err := parseCPUListCount("0,2-3,8-15\n")
+	assert.Nil(t, err)
+	assert.Equal(t, 11, count)
+
+	count, err = parseCPUListCount("\n")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = parseCPUListCount("3-1")
+	assert.NotNil(t, err)
+
+	_, err = parseCPUListCount("0-a")
+	assert.NotNil(t, err)
+}
+
+func TestGetPowercapZones(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	zones, err := sysFs.GetPowercapZones()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"testdata/host/sys/class/powercap/intel-rapl:0",
+		"testdata/host/sys/class/powercap/intel-rapl:0:0",
+	}, zones)
+
+	name, err := sysFs.GetPowercapZoneName(zones[1])
+	assert.Nil(t, err)
+	assert.Equal(t, "dram", name)
+
+	energy, err := sysFs.GetEnergyUJ(zones[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "123456789", energy)
+}
+
+func TestGetHwmonSensors(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	sensors, err := sysFs.GetHwmonSensors()
+	assert.Nil(t, err)
+	expected := []HwmonSensor{
+		{Device: "coretemp", Label: "Package id 0", Temperature: 45000},
+		{Device: "coretemp", Label: "temp2", Temperature: 43000},
+		{Device: "nvme", Label: "Composite", Temperature: 38850},
+	}
+	assert.Equal(t, expected, sensors)
+}
+
+func TestGetNVMeControllers(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	controllers, err := sysFs.GetNVMeControllers()
+	assert.Nil(t, err)
+	expected := []NVMeController{
+		{
+			Name:       "nvme0",
+			Model:      "Samsung SSD 970 EVO Plus 1TB",
+			Firmware:   "2B2QEXM7",
+			Namespaces: map[string]uint64{"nvme0n1": 1953525168},
+			Sensors:    []HwmonSensor{{Device: "nvme", Label: "Composite", Temperature: 38850}},
+		},
+	}
+	assert.Equal(t, expected, controllers)
+}
+
+func TestGetCPUVulnerabilities(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	vulnerabilities, err := sysFs.GetCPUVulnerabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"meltdown": "Mitigation: PTI",
+		"mds":      "Not affected",
+	}, vulnerabilities)
+}
+
+func TestGetOnlineAndOfflineCPUs(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	online, err := sysFs.GetOnlineCPUs()
+	assert.Nil(t, err)
+	assert.Equal(t, "0-2,4", online)
+
+	offline, err := sysFs.GetOfflineCPUs()
+	assert.Nil(t, err)
+	assert.Equal(t, "3", offline)
+}
+
+func TestGetMemoryBlocks(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	blocks, err := sysFs.GetMemoryBlocksPaths()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"testdata/host/sys/devices/system/memory/memory0",
+		"testdata/host/sys/devices/system/memory/memory1",
+	}, blocks)
+
+	state, err := sysFs.GetMemoryBlockState(blocks[1])
+	assert.Nil(t, err)
+	assert.Equal(t, "offline", state)
+}
+
+func TestGetDMIEntries(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	entries, err := sysFs.GetDMIEntries(17)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	// Type and length of memory device structure.
+	assert.Equal(t, []byte{17, 0x28}, entries[0][:2])
+
+	entries, err = sysFs.GetDMIEntries(4)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGetBlockDeviceQueueAttribute(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	rotational, err := sysFs.GetBlockDeviceQueueAttribute("sda", BlockDeviceRotationalFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", rotational)
+
+	physicalBlockSize, err := sysFs.GetBlockDeviceQueueAttribute("sda", BlockDevicePhysicalBlockSizeFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "4096", physicalBlockSize)
+
+	_, err = sysFs.GetBlockDeviceQueueAttribute("sdb", BlockDeviceRotationalFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetBlockDeviceTopology(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	slaves, err := sysFs.GetBlockDeviceSlaves("md0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sda1", "sdb1"}, slaves)
+
+	level, err := sysFs.GetBlockDeviceRaidLevel("md0")
+	assert.Nil(t, err)
+	assert.Equal(t, "raid1", level)
+
+	slaves, err = sysFs.GetBlockDeviceSlaves("dm-0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"md0"}, slaves)
+
+	dmName, err := sysFs.GetBlockDeviceDMName("dm-0")
+	assert.Nil(t, err)
+	assert.Equal(t, "vg0-root", dmName)
+
+	_, err = sysFs.GetBlockDeviceRaidLevel("dm-0")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetBlockDeviceStat(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	stat, err := sysFs.GetBlockDeviceStat("sda")
+	assert.Nil(t, err)
+	assert.Equal(t, "8764      213   453920     3210    12345     6789  1048576    45678        0    30120    48888        0        0        0        0      512      120", stat)
+}
+
+func TestGetNetworkLinkInfo(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	operState, err := sysFs.GetNetworkOperState("eth0")
+	assert.Nil(t, err)
+	assert.Equal(t, "up", operState)
+
+	duplex, err := sysFs.GetNetworkDuplex("eth0")
+	assert.Nil(t, err)
+	assert.Equal(t, "full", duplex)
+
+	driver, err := sysFs.GetNetworkDriver("eth0")
+	assert.Nil(t, err)
+	assert.Equal(t, "ixgbe", driver)
+
+	// Bridge does not have a driver bound.
+	_, err = sysFs.GetNetworkDriver("br0")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetNetworkTopology(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	devType, err := sysFs.GetNetworkDeviceType("bond0")
+	assert.Nil(t, err)
+	assert.Equal(t, "bond", devType)
+
+	devType, err = sysFs.GetNetworkDeviceType("eth0")
+	assert.Nil(t, err)
+	assert.Equal(t, "", devType)
+
+	slaves, err := sysFs.GetNetworkBondSlaves("bond0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"eth0", "eth1"}, slaves)
+
+	ports, err := sysFs.GetNetworkBridgePorts("br0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"veth1234"}, ports)
+
+	lower, err := sysFs.GetNetworkLowerDevices("eth0.100")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"eth0"}, lower)
+
+	_, err = sysFs.GetNetworkBondSlaves("eth0")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetNetworkSriov(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	address, err := sysFs.GetNetworkPCIAddress("ens785f0")
+	assert.Nil(t, err)
+	assert.Equal(t, "0000:3b:00.0", address)
+
+	totalVFs, err := sysFs.GetNetworkPCIAttribute("ens785f0", SriovTotalVFsFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "64", totalVFs)
+
+	functions, err := sysFs.GetNetworkVirtualFunctions("ens785f0")
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]string{0: "0000:3b:02.0", 1: "0000:3b:02.1"}, functions)
+
+	_, err = sysFs.GetNetworkPCIAttribute("eth0", SriovTotalVFsFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetInfinibandPorts(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	ports, err := sysFs.GetInfinibandPorts()
+	assert.Nil(t, err)
+	expected := []InfinibandPort{
+		{
+			Device: "mlx5_0",
+			Port:   1,
+			State:  "ACTIVE",
+			Counters: map[string]uint64{
+				"link_downed":        1,
+				"port_rcv_data":      1048576,
+				"port_rcv_errors":    0,
+				"port_rcv_packets":   4096,
+				"port_xmit_data":     2097152,
+				"port_xmit_discards": 3,
+				"port_xmit_packets":  8192,
+			},
+		},
+	}
+	assert.Equal(t, expected, ports)
+}
+
+func TestGetNetworkQueues(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	queues, err := sysFs.GetNetworkQueues("eth0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rx-0", "rx-1", "tx-0", "tx-1"}, queues)
+}
+
+func TestGetPCIDevices(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/host")
+	devices, err := sysFs.GetPCIDevices()
+	assert.Nil(t, err)
+	expected := []PCIDevice{
+		{
+			Address:  "0000:00:1f.3",
+			Vendor:   "0x8086",
+			Device:   "0xa348",
+			Class:    "0x040300",
+			NumaNode: "-1",
+		},
+		{
+			Address:          "0000:3b:00.0",
+			Vendor:           "0x8086",
+			Device:           "0x158b",
+			Class:            "0x020000",
+			NumaNode:         "0",
+			IOMMUGroup:       "45",
+			CurrentLinkSpeed: "8.0 GT/s PCIe",
+			MaxLinkSpeed:     "8.0 GT/s PCIe",
+			CurrentLinkWidth: "8",
+			MaxLinkWidth:     "16",
+		},
+	}
+	assert.Equal(t, expected, devices)
+}
+
+func TestGetSystemUUIDFromSMBIOSTables(t *testing.T) {
+	// product_uuid contains placeholder which should be skipped.
+	sysFs := NewRealSysFsWithRoot("./testdata/uuid/smbios")
+	id, err := sysFs.GetSystemUUID()
+	assert.Nil(t, err)
+	assert.Equal(t, "4c4c4544-0042-3510-8052-b4c04f333732", id)
+}
+
+func TestGetSystemUUIDFromDeviceTreeSerialNumber(t *testing.T) {
+	sysFs := NewRealSysFsWithRoot("./testdata/uuid/devicetree")
+	id, err := sysFs.GetSystemUUID()
+	assert.Nil(t, err)
+	assert.Equal(t, "10000000a3b4c5d6", id)
+}
+
+func TestParseSMBIOSSystemUUIDBefore26(t *testing.T) {
+	entryPoint := []byte("_SM_\x00\x1f\x02\x05")
+	tables := append([]byte{1, 0x19, 0, 1, 1, 2, 0, 0,
+		0x44, 0x45, 0x4c, 0x4c, 0x42, 0x00, 0x10, 0x35, 0x80, 0x52, 0xb4, 0xc0, 0x4f, 0x33, 0x37, 0x32, 6},
+		[]byte("QEMU\x00\x00")...)
+	id, err := parseSMBIOSSystemUUID(entryPoint, tables)
+	assert.Nil(t, err)
+	assert.Equal(t, "44454c4c-4200-1035-8052-b4c04f333732", id)
+
+	_, err = parseSMBIOSSystemUUID(entryPoint, []byte{127, 4, 0, 1, 0, 0})
+	assert.NotNil(t, err)
+
+	_, err = parseSMBIOSSystemUUID([]byte("invalid"), tables)
+	assert.NotNil(t, err)
+}
+
+func TestIsValidSystemUUID(t *testing.T) {
+	assert.True(t, isValidSystemUUID("1F862619-BA9F-4526-8F85-ECEAF0C97430"))
+	assert.False(t, isValidSystemUUID(""))
+	assert.False(t, isValidSystemUUID("00000000-0000-0000-0000-000000000000"))
+	assert.False(t, isValidSystemUUID("FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF"))
+	assert.False(t, isValidSystemUUID("Not Settable"))
+}
@@ -15,11 +15,15 @@
 package sysinfo
 
 import (
+	"encoding/binary"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils/sysfs"
@@ -28,14 +32,66 @@ import (
 )
 
 var (
-	schedulerRegExp      = regexp.MustCompile(`.*\[(.*)\].*`)
-	nodeDirRegExp        = regexp.MustCompile(`node/node(\d*)`)
-	cpuDirRegExp         = regexp.MustCompile(`/cpu(\d+)`)
-	memoryCapacityRegexp = regexp.MustCompile(`MemTotal:\s*([0-9]+) kB`)
+	schedulerRegExp       = regexp.MustCompile(`.*\[(.*)\].*`)
+	nodeDirRegExp         = regexp.MustCompile(`node/node(\d*)`)
+	cpuDirRegExp          = regexp.MustCompile(`/cpu(\d+)`)
+	memoryCapacityRegexp  = regexp.MustCompile(`MemTotal:\s*([0-9]+) kB`)
+	nodeMemoryUsageRegexp = regexp.MustCompile(`(?m)(MemFree|FilePages|AnonPages):\s*([0-9]+) kB`)
+	// Names of driver statistics related to drops and errors, e.g. rx_missed_errors, rx_fifo_errors or rx_queue_0_drops.
+	ethtoolStatRegexp = regexp.MustCompile(`drop|miss|fifo|err|discard|overrun|no_buf|no_dma`)
+	// Names of per queue driver statistics, e.g. rx_queue_0_packets, rx-0.packets or rx0_packets.
+	networkQueueStatRegexp = regexp.MustCompile(`^(rx|tx)[_-]?(?:queue[_-])?(\d+)[._](packets|bytes)$`)
 
 	cpusPath = "/sys/devices/system/cpu"
+
+	// Maximal number of NUMA nodes or CPUs which topology is read concurrently.
+	topologyReadParallelism = 16
+	// Slots of goroutines reading topology, shared by all calls of parallelize.
+	topologyReadLimiter = make(chan struct{}, topologyReadParallelism)
+)
+
+const (
+	// SMBIOS memory device structure, see: https://www.dmtf.org/standards/smbios (DSP0134, section 7.18).
+	dmiMemoryDeviceType            = 17
+	dmiMemoryDeviceSizeOffset      = 0x0C
+	dmiMemoryDeviceLocatorOffset   = 0x10
+	dmiMemoryDeviceBankOffset      = 0x11
+	dmiMemoryDeviceTypeOffset      = 0x12
+	dmiMemoryDeviceSpeedOffset     = 0x15
+	dmiMemoryDeviceVendorOffset    = 0x17
+	dmiMemoryDeviceExtSizeOffset   = 0x1C
+	dmiMemoryDeviceMinLength       = 0x15
+	dmiMemoryDeviceSizeUnknown     = 0xFFFF
+	dmiMemoryDeviceSizeExtended    = 0x7FFF
+	dmiMemoryDeviceSizeInKilobytes = 0x8000
+
+	// Number of fields in /sys/block/<dev>/stat reported by all kernels.
+	blockDeviceStatFields = 11
+
+	// Label of hwmon sensor reporting composite temperature of NVMe controller.
+	nvmeCompositeSensor = "Composite"
 )
 
+// dmiMemoryTypes maps SMBIOS memory type to its name.
+var dmiMemoryTypes = map[byte]string{
+	0x0F: "SDRAM",
+	0x12: "DDR",
+	0x13: "DDR2",
+	0x14: "DDR2 FB-DIMM",
+	0x18: "DDR3",
+	0x1A: "DDR4",
+	0x1B: "LPDDR",
+	0x1C: "LPDDR2",
+	0x1D: "LPDDR3",
+	0x1E: "LPDDR4",
+	0x1F: "Logical non-volatile device",
+	0x20: "HBM",
+	0x21: "HBM2",
+	0x22: "DDR5",
+	0x23: "LPDDR5",
+	0x24: "HBM3",
+}
+
 const (
 	cacheLevel2  = 2
 	hugepagesDir = "hugepages/"
@@ -52,9 +108,7 @@ func GetBlockDeviceInfo(sysfs sysfs.SysFs) (map[string]info.DiskInfo, error) {
 	diskMap := make(map[string]info.DiskInfo)
 	for _, disk := range disks {
 		name := disk.Name()
-		// Ignore non-disk devices.
-		// TODO(rjnagal): Maybe just match hd, sd, and dm prefixes.
-		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "sr") {
+		if isNonDiskDevice(name) {
 			continue
 		}
 		diskInfo := info.DiskInfo{
@@ -88,12 +142,148 @@ func GetBlockDeviceInfo(sysfs sysfs.SysFs) (map[string]info.DiskInfo, error) {
 				diskInfo.Scheduler = string(matches[1])
 			}
 		}
+		setBlockDeviceQueueAttributes(sysfs, name, &diskInfo)
+		setBlockDeviceTopology(sysfs, name, &diskInfo)
 		device := fmt.Sprintf("%d:%d", diskInfo.Major, diskInfo.Minor)
 		diskMap[device] = diskInfo
 	}
 	return diskMap, nil
 }
 
+// isNonDiskDevice returns true for block devices which are not disks.
+// TODO(rjnagal): Maybe just match hd, sd, and dm prefixes.
+func isNonDiskDevice(name string) bool {
+	return strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "sr")
+}
+
+// GetBlockDeviceStats returns IO statistics of disks.
+func GetBlockDeviceStats(sysFs sysfs.SysFs) ([]info.BlockDeviceStats, error) {
+	disks, err := sysFs.GetBlockDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]info.BlockDeviceStats, 0, len(disks))
+	for _, disk := range disks {
+		name := disk.Name()
+		if isNonDiskDevice(name) {
+			continue
+		}
+		dev, err := sysFs.GetBlockDeviceNumbers(name)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := sysFs.GetBlockDeviceStat(name)
+		if err != nil {
+			return nil, err
+		}
+		deviceStats, err := parseBlockDeviceStat(stat)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse stat of block device %s: %v", name, err)
+		}
+		deviceStats.Name = name
+		n, err := fmt.Sscanf(dev, "%d:%d", &deviceStats.Major, &deviceStats.Minor)
+		if err != nil || n != 2 {
+			return nil, fmt.Errorf("could not parse device numbers from %s for device %s", dev, name)
+		}
+		stats = append(stats, deviceStats)
+	}
+	return stats, nil
+}
+
+// parseBlockDeviceStat parses content of /sys/block/<dev>/stat, discard and flush statistics
+// reported by newer kernels (4.18+, 5.5+) are ignored.
+func parseBlockDeviceStat(stat string) (info.BlockDeviceStats, error) {
+	fields := strings.Fields(stat)
+	if len(fields) < blockDeviceStatFields {
+		return info.BlockDeviceStats{}, fmt.Errorf("expected at least %d fields, got %d", blockDeviceStatFields, len(fields))
+	}
+	values := make([]uint64, blockDeviceStatFields)
+	for i := range values {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return info.BlockDeviceStats{}, err
+		}
+		values[i] = value
+	}
+	return info.BlockDeviceStats{
+		ReadsCompleted: values[0],
+		ReadsMerged:    values[1],
+		// Sectors are always 512 bytes regardless of physical block size of the device.
+		ReadBytes:       values[2] * 512,
+		ReadTime:        values[3],
+		WritesCompleted: values[4],
+		WritesMerged:    values[5],
+		WrittenBytes:    values[6] * 512,
+		WriteTime:       values[7],
+		IoInProgress:    values[8],
+		IoTime:          values[9],
+		WeightedIoTime:  values[10],
+	}, nil
+}
+
+// setBlockDeviceQueueAttributes fills queue attributes of the block device, these are optional
+// and not available for all devices, so failures are only logged.
+func setBlockDeviceQueueAttributes(sysFs sysfs.SysFs, name string, diskInfo *info.DiskInfo) {
+	rotational, err := getBlockDeviceQueueAttribute(sysFs, name, sysfs.BlockDeviceRotationalFile)
+	if err == nil {
+		diskInfo.Rotational = rotational == 1
+	}
+	nrRequests, err := getBlockDeviceQueueAttribute(sysFs, name, sysfs.BlockDeviceNrRequestsFile)
+	if err == nil {
+		diskInfo.NrRequests = nrRequests
+	}
+	discardGranularity, err := getBlockDeviceQueueAttribute(sysFs, name, sysfs.BlockDeviceDiscardGranularityFile)
+	if err == nil {
+		diskInfo.DiscardGranularity = discardGranularity
+	}
+	physicalBlockSize, err := getBlockDeviceQueueAttribute(sysFs, name, sysfs.BlockDevicePhysicalBlockSizeFile)
+	if err == nil {
+		diskInfo.PhysicalBlockSize = physicalBlockSize
+	}
+}
+
+// setBlockDeviceTopology fills RAID level, device-mapper name and underlying devices of md and dm
+// block devices, so IO of containers can be mapped to physical disks.
+func setBlockDeviceTopology(sysFs sysfs.SysFs, name string, diskInfo *info.DiskInfo) {
+	slaves, err := sysFs.GetBlockDeviceSlaves(name)
+	if err != nil {
+		klog.V(4).Infof("Cannot read slaves of block device %s: %s", name, err)
+	} else if len(slaves) != 0 {
+		sort.Strings(slaves)
+		diskInfo.Slaves = slaves
+	}
+	// md and dm attributes are not present for other block devices.
+	level, err := sysFs.GetBlockDeviceRaidLevel(name)
+	if err == nil {
+		diskInfo.RaidLevel = level
+	} else if !os.IsNotExist(err) {
+		klog.V(4).Infof("Cannot read RAID level of block device %s: %s", name, err)
+	}
+	dmName, err := sysFs.GetBlockDeviceDMName(name)
+	if err == nil {
+		diskInfo.DMName = dmName
+	} else if !os.IsNotExist(err) {
+		klog.V(4).Infof("Cannot read device-mapper name of block device %s: %s", name, err)
+	}
+}
+
+func getBlockDeviceQueueAttribute(sysFs sysfs.SysFs, name string, attribute string) (uint64, error) {
+	out, err := sysFs.GetBlockDeviceQueueAttribute(name, attribute)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.V(4).Infof("Cannot read %s queue attribute of block device %s: %s", attribute, name, err)
+		}
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		klog.V(4).Infof("Cannot parse %s queue attribute of block device %s: %s", attribute, name, err)
+		return 0, err
+	}
+	return value, nil
+}
+
 // Get information about network devices present on the system.
 func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 	devs, err := sysfs.GetNetworkDevices()
@@ -103,16 +293,7 @@ func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 	netDevices := []info.NetInfo{}
 	for _, dev := range devs {
 		name := dev.Name()
-		// Ignore docker, loopback, and veth devices.
-		ignoredDevices := []string{"lo", "veth", "docker"}
-		ignored := false
-		for _, prefix := range ignoredDevices {
-			if strings.HasPrefix(name, prefix) {
-				ignored = true
-				break
-			}
-		}
-		if ignored {
+		if isIgnoredNetworkDevice(name) {
 			continue
 		}
 		address, err := sysfs.GetNetworkAddress(name)
@@ -143,11 +324,232 @@ func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 			}
 			netInfo.Speed = s
 		}
+		// Link state is optional, e.g. duplex is not available when link is down.
+		if operState, err := sysfs.GetNetworkOperState(name); err == nil {
+			netInfo.OperState = operState
+		}
+		if duplex, err := sysfs.GetNetworkDuplex(name); err == nil {
+			netInfo.Duplex = duplex
+		}
+		if driver, err := sysfs.GetNetworkDriver(name); err == nil {
+			netInfo.Driver = driver
+		}
+		sriov, err := getSriovInfo(sysfs, name)
+		if err != nil {
+			klog.V(4).Infof("Cannot read SR-IOV information of network device %s: %s", name, err)
+		}
+		netInfo.Sriov = sriov
 		netDevices = append(netDevices, netInfo)
 	}
 	return netDevices, nil
 }
 
+// isIgnoredNetworkDevice returns true for docker, loopback, and veth devices.
+func isIgnoredNetworkDevice(name string) bool {
+	ignoredDevices := []string{"lo", "veth", "docker"}
+	for _, prefix := range ignoredDevices {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetNetworkEthtoolStats fills driver statistics related to drops and errors of network devices,
+// which are not visible in statistics available in sysfs (e.g. missed packets or per ring drops).
+func SetNetworkEthtoolStats(sysFs sysfs.SysFs, netDevices []info.NetInfo) {
+	for i := range netDevices {
+		ethtoolStats, err := sysFs.GetNetworkEthtoolStats(netDevices[i].Name)
+		if err != nil {
+			klog.V(4).Infof("Cannot read driver statistics of network device %s: %s", netDevices[i].Name, err)
+			continue
+		}
+		stats := map[string]uint64{}
+		for name, value := range ethtoolStats {
+			if ethtoolStatRegexp.MatchString(name) {
+				stats[name] = value
+			}
+		}
+		if len(stats) != 0 {
+			netDevices[i].EthtoolStats = stats
+		}
+	}
+}
+
+// GetNetworkQueueStats returns packet and byte counters of receive and transmit queues of multiqueue
+// network devices. Counters are taken from driver statistics, devices whose drivers do not report
+// per queue statistics are omitted.
+func GetNetworkQueueStats(sysFs sysfs.SysFs) ([]info.NetworkQueueStats, error) {
+	devs, err := sysFs.GetNetworkDevices()
+	if err != nil {
+		return nil, err
+	}
+	queueStats := []info.NetworkQueueStats{}
+	for _, dev := range devs {
+		name := dev.Name()
+		if isIgnoredNetworkDevice(name) {
+			continue
+		}
+		queues, err := sysFs.GetNetworkQueues(name)
+		if err != nil {
+			klog.V(4).Infof("Cannot read queues of network device %s: %s", name, err)
+			continue
+		}
+		if len(queues) == 0 {
+			continue
+		}
+		ethtoolStats, err := sysFs.GetNetworkEthtoolStats(name)
+		if err != nil {
+			klog.V(4).Infof("Cannot read driver statistics of network device %s: %s", name, err)
+			continue
+		}
+		perQueue := parseNetworkQueueStats(ethtoolStats)
+		sort.Strings(queues)
+		for _, queue := range queues {
+			if stats, ok := perQueue[queue]; ok {
+				stats.Device = name
+				stats.Queue = queue
+				queueStats = append(queueStats, *stats)
+			}
+		}
+	}
+	return queueStats, nil
+}
+
+// parseNetworkQueueStats extracts per queue counters from driver statistics by queue name (e.g. rx-0).
+// Naming differs between drivers, e.g. rx_queue_0_packets (ixgbe, virtio_net), rx-0.packets (i40e)
+// or rx0_packets (mlx5).
+func parseNetworkQueueStats(ethtoolStats map[string]uint64) map[string]*info.NetworkQueueStats {
+	perQueue := map[string]*info.NetworkQueueStats{}
+	for name, value := range ethtoolStats {
+		matches := networkQueueStatRegexp.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+		queue := fmt.Sprintf("%s-%s", matches[1], matches[2])
+		stats, ok := perQueue[queue]
+		if !ok {
+			stats = &info.NetworkQueueStats{}
+			perQueue[queue] = stats
+		}
+		switch matches[3] {
+		case "packets":
+			stats.Packets = value
+		case "bytes":
+			stats.Bytes = value
+		}
+	}
+	return perQueue
+}
+
+// getSriovInfo returns SR-IOV information of network device, nil is returned for devices which are not
+// SR-IOV physical functions.
+func getSriovInfo(sysFs sysfs.SysFs, name string) (*info.SriovInfo, error) {
+	totalVFs, err := sysFs.GetNetworkPCIAttribute(name, sysfs.SriovTotalVFsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sriov := &info.SriovInfo{}
+	if sriov.TotalVFs, err = strconv.Atoi(totalVFs); err != nil {
+		return nil, err
+	}
+	numVFs, err := sysFs.GetNetworkPCIAttribute(name, sysfs.SriovNumVFsFile)
+	if err != nil {
+		return nil, err
+	}
+	if sriov.NumVFs, err = strconv.Atoi(numVFs); err != nil {
+		return nil, err
+	}
+	if sriov.PCIAddress, err = sysFs.GetNetworkPCIAddress(name); err != nil {
+		return nil, err
+	}
+	if sriov.VendorID, err = sysFs.GetNetworkPCIAttribute(name, sysfs.PCIVendorFile); err != nil {
+		return nil, err
+	}
+	if sriov.DeviceID, err = sysFs.GetNetworkPCIAttribute(name, sysfs.PCIDeviceFile); err != nil {
+		return nil, err
+	}
+	virtualFunctions, err := sysFs.GetNetworkVirtualFunctions(name)
+	if err != nil {
+		return nil, err
+	}
+	for index, address := range virtualFunctions {
+		sriov.VirtualFunctions = append(sriov.VirtualFunctions, info.VirtualFunction{
+			Index:      index,
+			PCIAddress: address,
+		})
+	}
+	sort.Slice(sriov.VirtualFunctions, func(i, j int) bool {
+		return sriov.VirtualFunctions[i].Index < sriov.VirtualFunctions[j].Index
+	})
+	return sriov, nil
+}
+
+// GetNetworkTopology returns hierarchy of network interfaces, e.g. physical devices backing bond
+// or bridge. Interfaces not related to any other interface are not reported.
+func GetNetworkTopology(sysFs sysfs.SysFs) ([]info.NetworkInterface, error) {
+	devs, err := sysFs.GetNetworkDevices()
+	if err != nil {
+		return nil, err
+	}
+	interfaces := make(map[string]*info.NetworkInterface, len(devs))
+	for _, dev := range devs {
+		name := dev.Name()
+		if name == "lo" {
+			continue
+		}
+		devType, err := sysFs.GetNetworkDeviceType(name)
+		if err != nil {
+			klog.V(4).Infof("Cannot read type of network device %s: %s", name, err)
+		}
+		lower, err := getLowerNetworkDevices(sysFs, name)
+		if err != nil {
+			klog.V(4).Infof("Cannot read lower devices of network device %s: %s", name, err)
+		}
+		sort.Strings(lower)
+		interfaces[name] = &info.NetworkInterface{
+			Name:  name,
+			Type:  devType,
+			Lower: lower,
+		}
+	}
+	for _, iface := range interfaces {
+		for _, lower := range iface.Lower {
+			if lowerIface, ok := interfaces[lower]; ok {
+				lowerIface.Upper = append(lowerIface.Upper, iface.Name)
+			}
+		}
+	}
+
+	topology := []info.NetworkInterface{}
+	for _, iface := range interfaces {
+		if len(iface.Lower) == 0 && len(iface.Upper) == 0 {
+			continue
+		}
+		sort.Strings(iface.Upper)
+		topology = append(topology, *iface)
+	}
+	sort.Slice(topology, func(i, j int) bool {
+		return topology[i].Name < topology[j].Name
+	})
+	return topology, nil
+}
+
+// getLowerNetworkDevices returns devices backing the network device. Bond slaves and bridge ports
+// are read from bonding and brif directories, lower_* links are used for other devices (e.g. VLAN, macvlan).
+func getLowerNetworkDevices(sysFs sysfs.SysFs, name string) ([]string, error) {
+	if slaves, err := sysFs.GetNetworkBondSlaves(name); err == nil {
+		return slaves, nil
+	}
+	if ports, err := sysFs.GetNetworkBridgePorts(name); err == nil {
+		return ports, nil
+	}
+	return sysFs.GetNetworkLowerDevices(name)
+}
+
 // GetHugePagesInfo returns information about pre-allocated huge pages
 // hugepagesDirectory should be top directory of hugepages
 // Such as: /sys/kernel/mm/hugepages/
@@ -181,17 +583,43 @@ func GetHugePagesInfo(sysFs sysfs.SysFs, hugepagesDirectory string) ([]info.Huge
 			return hugePagesInfo, fmt.Errorf("could not parse file nr_hugepage for %s, contents %q", st.Name(), string(val))
 		}
 
-		hugePagesInfo = append(hugePagesInfo, info.HugePagesInfo{
+		hugePageInfo := info.HugePagesInfo{
 			NumPages: numPages,
 			PageSize: pageSize,
-		})
+		}
+		for counterFile, counter := range map[string]*uint64{
+			sysfs.HugePagesFreeFile:     &hugePageInfo.FreePages,
+			sysfs.HugePagesSurplusFile:  &hugePageInfo.SurplusPages,
+			sysfs.HugePagesReservedFile: &hugePageInfo.ReservedPages,
+		} {
+			*counter, err = getHugePagesCounter(sysFs, hugepagesDirectory, st.Name(), counterFile)
+			if err != nil {
+				return hugePagesInfo, err
+			}
+		}
+		hugePagesInfo = append(hugePagesInfo, hugePageInfo)
 	}
 	return hugePagesInfo, nil
 }
 
+// getHugePagesCounter returns value of hugepages counter, 0 is returned when counter is not
+// available, e.g. resv_hugepages of NUMA node.
+func getHugePagesCounter(sysFs sysfs.SysFs, hugepagesDirectory, hugePageName, counterFile string) (uint64, error) {
+	val, err := sysFs.GetHugePagesCounter(hugepagesDirectory, hugePageName, counterFile)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	counter, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse file %s for %s, contents %q", counterFile, hugePageName, val)
+	}
+	return counter, nil
+}
+
 // GetNodesInfo returns information about NUMA nodes and their topology
 func GetNodesInfo(sysFs sysfs.SysFs) ([]info.Node, int, error) {
-	nodes := []info.Node{}
 	allLogicalCoresCount := 0
 
 	nodesDirs, err := sysFs.GetNodesPaths()
@@ -204,48 +632,72 @@ func GetNodesInfo(sysFs sysfs.SysFs) ([]info.Node, int, error) {
 		return getCPUTopology(sysFs)
 	}
 
-	for _, nodeDir := range nodesDirs {
-		id, err := getMatchedInt(nodeDirRegExp, nodeDir)
-		if err != nil {
-			return nil, 0, err
+	nodes := make([]info.Node, len(nodesDirs))
+	logicalCoresCounts := make([]int, len(nodesDirs))
+	errs := make([]error, len(nodesDirs))
+	parallelize(len(nodesDirs), false, func(i int) {
+		nodes[i], logicalCoresCounts[i], errs[i] = getNodeInfo(sysFs, nodesDirs[i])
+	})
+	for i := range nodesDirs {
+		if errs[i] != nil {
+			return nil, 0, errs[i]
 		}
-		node := info.Node{Id: id}
+		allLogicalCoresCount += logicalCoresCounts[i]
+	}
+	addCoreTypes(sysFs, nodes)
+	return nodes, allLogicalCoresCount, nil
+}
 
-		cpuDirs, err := sysFs.GetCPUsPaths(nodeDir)
-		if len(cpuDirs) == 0 {
-			klog.Warningf("Found node without any CPU, nodeDir: %s, number of cpuDirs %d, err: %v", nodeDir, len(cpuDirs), err)
-		} else {
-			cores, err := getCoresInfo(sysFs, cpuDirs)
-			if err != nil {
-				return nil, 0, err
-			}
-			node.Cores = cores
-			for _, core := range cores {
-				allLogicalCoresCount += len(core.Threads)
-			}
-		}
+// getNodeInfo returns information about NUMA node and number of its logical cores.
+func getNodeInfo(sysFs sysfs.SysFs, nodeDir string) (info.Node, int, error) {
+	logicalCoresCount := 0
+	id, err := getMatchedInt(nodeDirRegExp, nodeDir)
+	if err != nil {
+		return info.Node{}, 0, err
+	}
+	node := info.Node{Id: id}
 
-		// On some Linux platforms(such as Arm64 guest kernel), cache info may not exist.
-		// So, we should ignore error here.
-		err = addCacheInfo(sysFs, &node)
+	cpuDirs, err := sysFs.GetCPUsPaths(nodeDir)
+	if len(cpuDirs) == 0 {
+		klog.Warningf("Found node without any CPU, nodeDir: %s, number of cpuDirs %d, err: %v", nodeDir, len(cpuDirs), err)
+	} else {
+		cores, err := getCoresInfo(sysFs, cpuDirs, true)
 		if err != nil {
-			klog.V(1).Infof("Found node without cache information, nodeDir: %s", nodeDir)
+			return info.Node{}, 0, err
 		}
-
-		node.Memory, err = getNodeMemInfo(sysFs, nodeDir)
-		if err != nil {
-			return nil, 0, err
+		node.Cores = cores
+		for _, core := range cores {
+			logicalCoresCount += len(core.Threads)
 		}
+	}
 
-		hugepagesDirectory := fmt.Sprintf("%s/%s", nodeDir, hugepagesDir)
-		node.HugePages, err = GetHugePagesInfo(sysFs, hugepagesDirectory)
-		if err != nil {
-			return nil, 0, err
-		}
+	// On some Linux platforms(such as Arm64 guest kernel), cache info may not exist.
+	// So, we should ignore error here.
+	err = addCacheInfo(sysFs, &node, true)
+	if err != nil {
+		klog.V(1).Infof("Found node without cache information, nodeDir: %s", nodeDir)
+	}
 
-		nodes = append(nodes, node)
+	node.Memory, err = getNodeMemInfo(sysFs, nodeDir)
+	if err != nil {
+		return info.Node{}, 0, err
+	}
+	err = setNodeMemoryUsage(sysFs, nodeDir, &node)
+	if err != nil {
+		return info.Node{}, 0, err
 	}
-	return nodes, allLogicalCoresCount, err
+
+	hugepagesDirectory := fmt.Sprintf("%s/%s", nodeDir, hugepagesDir)
+	node.HugePages, err = GetHugePagesInfo(sysFs, hugepagesDirectory)
+	if err != nil {
+		return info.Node{}, 0, err
+	}
+
+	node.Distances, err = getNodeDistances(sysFs, nodeDir)
+	if err != nil {
+		return info.Node{}, 0, err
+	}
+	return node, logicalCoresCount, nil
 }
 
 func getCPUTopology(sysFs sysfs.SysFs) ([]info.Node, int, error) {
@@ -275,7 +727,7 @@ func getCPUTopology(sysFs sysfs.SysFs) ([]info.Node, int, error) {
 	for physicalPackageID, cpus := range cpusByPhysicalPackageID {
 		node := info.Node{Id: physicalPackageID}
 
-		cores, err := getCoresInfo(sysFs, cpus)
+		cores, err := getCoresInfo(sysFs, cpus, false)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -283,12 +735,13 @@ func getCPUTopology(sysFs sysfs.SysFs) ([]info.Node, int, error) {
 
 		// On some Linux platforms(such as Arm64 guest kernel), cache info may not exist.
 		// So, we should ignore error here.
-		err = addCacheInfo(sysFs, &node)
+		err = addCacheInfo(sysFs, &node, false)
 		if err != nil {
 			klog.V(1).Infof("Found cpu without cache information, cpuPath: %s", cpus)
 		}
 		nodes = append(nodes, node)
 	}
+	addCoreTypes(sysFs, nodes)
 	return nodes, cpusCount, nil
 }
 
@@ -304,7 +757,7 @@ func getCpusByPhysicalPackageID(sysFs sysfs.SysFs, cpusPaths []string) (map[int]
 			return nil, err
 		}
 
-		physicalPackageID, err := strconv.Atoi(rawPhysicalPackageID)
+		physicalPackageID, err := parsePhysicalPackageID(rawPhysicalPackageID)
 		if err != nil {
 			return nil, err
 		}
@@ -318,23 +771,537 @@ func getCpusByPhysicalPackageID(sysFs sysfs.SysFs, cpusPaths []string) (map[int]
 	return cpuPathsByPhysicalPackageID, nil
 }
 
-// addCacheInfo adds information about cache for NUMA node
-func addCacheInfo(sysFs sysfs.SysFs, node *info.Node) error {
-	for coreID, core := range node.Cores {
-		threadID := core.Threads[0] //get any thread for core
-		caches, err := GetCacheInfo(sysFs, threadID)
+// parsePhysicalPackageID parses content of physical_package_id. Arm64 kernels report -1 when
+// firmware does not describe packages (ACPI without PPTT), whole system is treated as one package then.
+func parsePhysicalPackageID(rawPhysicalPackageID string) (int, error) {
+	physicalPackageID, err := strconv.Atoi(rawPhysicalPackageID)
+	if err != nil {
+		return 0, err
+	}
+	if physicalPackageID < 0 {
+		return 0, nil
+	}
+	return physicalPackageID, nil
+}
+
+// addCoreTypes sets type of cores on hybrid CPUs. Intel hybrid CPUs provide separate performance
+// monitoring units for performance (cpu_core) and efficiency (cpu_atom) cores. On ARM big.LITTLE
+// cores with capacity lower than the maximal one are efficiency cores.
+func addCoreTypes(sysFs sysfs.SysFs, nodes []info.Node) {
+	coreTypes := getIntelHybridCoreTypes(sysFs)
+	if coreTypes == nil {
+		coreTypes = getCapacityCoreTypes(sysFs, nodes)
+	}
+	if coreTypes == nil {
+		return
+	}
+	for i := range nodes {
+		for j := range nodes[i].Cores {
+			core := &nodes[i].Cores[j]
+			if len(core.Threads) == 0 {
+				continue
+			}
+			core.CoreType = coreTypes[core.Threads[0]]
+		}
+	}
+}
+
+// getIntelHybridCoreTypes returns core types by CPU id, nil is returned for non-hybrid CPUs.
+func getIntelHybridCoreTypes(sysFs sysfs.SysFs) map[int]string {
+	coreTypes := make(map[int]string)
+	for pmu, coreType := range map[string]string{
+		"cpu_core": info.CoreTypePerformance,
+		"cpu_atom": info.CoreTypeEfficiency,
+	} {
+		rawCPUs, err := sysFs.GetPMUCPUs(pmu)
 		if err != nil {
-			return err
+			return nil
+		}
+		cpus, err := parseCPUList(rawCPUs)
+		if err != nil {
+			klog.Warningf("Cannot parse CPUs of %s: %s", pmu, err)
+			return nil
 		}
+		for _, cpu := range cpus {
+			coreTypes[cpu] = coreType
+		}
+	}
+	return coreTypes
+}
+
+// getCapacityCoreTypes returns core types by CPU id based on CPU capacity, nil is returned when
+// capacity is not available or all CPUs have the same capacity.
+func getCapacityCoreTypes(sysFs sysfs.SysFs, nodes []info.Node) map[int]string {
+	capacities := make(map[int]uint64)
+	maxCapacity := uint64(0)
+	for _, node := range nodes {
+		for _, core := range node.Cores {
+			for _, thread := range core.Threads {
+				rawCapacity, err := sysFs.GetCPUCapacity(fmt.Sprintf("%s/cpu%d", cpusPath, thread))
+				if err != nil {
+					return nil
+				}
+				capacity, err := strconv.ParseUint(rawCapacity, 10, 64)
+				if err != nil {
+					klog.Warningf("Cannot parse capacity of CPU %d: %s", thread, err)
+					return nil
+				}
+				capacities[thread] = capacity
+				if capacity > maxCapacity {
+					maxCapacity = capacity
+				}
+			}
+		}
+	}
+
+	coreTypes := make(map[int]string, len(capacities))
+	hybrid := false
+	for cpu, capacity := range capacities {
+		if capacity == maxCapacity {
+			coreTypes[cpu] = info.CoreTypePerformance
+		} else {
+			coreTypes[cpu] = info.CoreTypeEfficiency
+			hybrid = true
+		}
+	}
+	if !hybrid {
+		return nil
+	}
+	return coreTypes
+}
+
+// parseCPUList parses list of CPU ranges, e.g. "0-15,24".
+func parseCPUList(cpuList string) ([]int, error) {
+	cpus := []int{}
+	if cpuList == "" {
+		return cpus, nil
+	}
+	for _, cpuRange := range strings.Split(cpuList, ",") {
+		bounds := strings.SplitN(cpuRange, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// GetCPUFrequencies returns frequency scaling state of online CPUs, CPUs without cpufreq support are skipped.
+func GetCPUFrequencies(sysFs sysfs.SysFs) ([]info.CPUFrequency, error) {
+	cpuDirs, err := sysFs.GetCPUsPaths(cpusPath)
+	if err != nil {
+		return nil, err
+	}
+
+	frequencies := []info.CPUFrequency{}
+	for _, cpuDir := range cpuDirs {
+		cpuID, err := getMatchedInt(cpuDirRegExp, cpuDir)
+		if err != nil {
+			return nil, fmt.Errorf("Unexpected format of CPU directory, cpuDirRegExp %s, cpuDir: %s", cpuDirRegExp, cpuDir)
+		}
+		if !sysFs.IsCPUOnline(cpuDir) {
+			continue
+		}
+
+		rawCurFreq, err := sysFs.GetCPUCurrentFrequency(cpuDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		curFreq, err := strconv.ParseUint(rawCurFreq, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse current frequency of %s: %v", cpuDir, err)
+		}
+		frequency := info.CPUFrequency{
+			CPU:              cpuID,
+			CurrentFrequency: curFreq,
+		}
+
+		if governor, err := sysFs.GetCPUScalingGovernor(cpuDir); err == nil {
+			frequency.Governor = governor
+		}
+		// cpufreq statistics are not available when kernel is built without CONFIG_CPU_FREQ_STAT.
+		if rawTimeInState, err := sysFs.GetCPUTimeInState(cpuDir); err == nil {
+			frequency.TimeInState, err = parseTimeInState(rawTimeInState)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse time in state of %s: %v", cpuDir, err)
+			}
+		}
+		frequencies = append(frequencies, frequency)
+	}
+	sort.Slice(frequencies, func(i, j int) bool {
+		return frequencies[i].CPU < frequencies[j].CPU
+	})
+	return frequencies, nil
+}
+
+// GetPowerZones returns energy counters of RAPL power zones, empty list is returned when RAPL is not available.
+func GetPowerZones(sysFs sysfs.SysFs) ([]info.PowerZone, error) {
+	zonePaths, err := sysFs.GetPowercapZones()
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]info.PowerZone, 0, len(zonePaths))
+	for _, zonePath := range zonePaths {
+		domain, err := sysFs.GetPowercapZoneName(zonePath)
+		if err != nil {
+			return nil, err
+		}
+		// energy_uj is readable only by root since kernel 5.10.
+		rawEnergy, err := sysFs.GetEnergyUJ(zonePath)
+		if err != nil {
+			klog.V(4).Infof("Cannot read energy of powercap zone %s: %s", zonePath, err)
+			continue
+		}
+		energy, err := strconv.ParseUint(rawEnergy, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse energy of powercap zone %s: %v", zonePath, err)
+		}
+		zones = append(zones, info.PowerZone{
+			Id:       filepath.Base(zonePath),
+			Domain:   domain,
+			EnergyUJ: energy,
+		})
+	}
+	return zones, nil
+}
+
+// GetThermalSensors returns readings of hwmon temperature sensors.
+func GetThermalSensors(sysFs sysfs.SysFs) ([]info.ThermalSensor, error) {
+	hwmonSensors, err := sysFs.GetHwmonSensors()
+	if err != nil {
+		return nil, err
+	}
+	sensors := make([]info.ThermalSensor, 0, len(hwmonSensors))
+	for _, sensor := range hwmonSensors {
+		sensors = append(sensors, info.ThermalSensor{
+			Device: sensor.Device,
+			Label:  sensor.Label,
+			// hwmon reports temperature in millidegrees Celsius.
+			Temperature: float64(sensor.Temperature) / 1000,
+		})
+	}
+	return sensors, nil
+}
+
+// GetNVMeDevices returns NVMe controllers with their namespaces and composite temperature.
+func GetNVMeDevices(sysFs sysfs.SysFs) ([]info.NVMeDevice, error) {
+	controllers, err := sysFs.GetNVMeControllers()
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]info.NVMeDevice, 0, len(controllers))
+	for _, controller := range controllers {
+		device := info.NVMeDevice{
+			Name:       controller.Name,
+			Model:      controller.Model,
+			Firmware:   controller.Firmware,
+			Namespaces: make([]info.NVMeNamespace, 0, len(controller.Namespaces)),
+		}
+		for name, size := range controller.Namespaces {
+			device.Namespaces = append(device.Namespaces, info.NVMeNamespace{
+				Name: name,
+				// size is in 512 bytes sectors.
+				Size: size * 512,
+			})
+		}
+		sort.Slice(device.Namespaces, func(i, j int) bool {
+			return device.Namespaces[i].Name < device.Namespaces[j].Name
+		})
+		for _, sensor := range controller.Sensors {
+			// Controllers may report additional sensors (Sensor 1-8) beside composite temperature.
+			if sensor.Label == nvmeCompositeSensor {
+				temperature := float64(sensor.Temperature) / 1000
+				device.Temperature = &temperature
+				break
+			}
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// GetPCIDevices returns PCI devices with NUMA node they are attached to and state of their link.
+func GetPCIDevices(sysFs sysfs.SysFs) ([]info.PCIDevice, error) {
+	pciDevices, err := sysFs.GetPCIDevices()
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]info.PCIDevice, 0, len(pciDevices))
+	for _, pciDevice := range pciDevices {
+		device := info.PCIDevice{
+			Address:          pciDevice.Address,
+			VendorID:         pciDevice.Vendor,
+			DeviceID:         pciDevice.Device,
+			Class:            pciDevice.Class,
+			NumaNode:         -1,
+			IOMMUGroup:       pciDevice.IOMMUGroup,
+			CurrentLinkSpeed: pciDevice.CurrentLinkSpeed,
+			MaxLinkSpeed:     pciDevice.MaxLinkSpeed,
+		}
+		// numa_node is not available when kernel is built without NUMA support.
+		if pciDevice.NumaNode != "" {
+			if device.NumaNode, err = strconv.Atoi(pciDevice.NumaNode); err != nil {
+				return nil, fmt.Errorf("could not parse NUMA node of PCI device %s: %v", pciDevice.Address, err)
+			}
+		}
+		// Link width of devices which are not PCI Express may not be readable.
+		if width, err := strconv.Atoi(pciDevice.CurrentLinkWidth); err == nil {
+			device.CurrentLinkWidth = width
+		}
+		if width, err := strconv.Atoi(pciDevice.MaxLinkWidth); err == nil {
+			device.MaxLinkWidth = width
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// GetInfinibandPorts returns state and counters of InfiniBand ports.
+func GetInfinibandPorts(sysFs sysfs.SysFs) ([]info.InfinibandPort, error) {
+	ibPorts, err := sysFs.GetInfinibandPorts()
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]info.InfinibandPort, 0, len(ibPorts))
+	for _, port := range ibPorts {
+		ports = append(ports, info.InfinibandPort{
+			Device: port.Device,
+			Port:   port.Port,
+			State:  port.State,
+			// Data counters are reported in units of 4 octets.
+			ReceivedBytes:      port.Counters["port_rcv_data"] * 4,
+			TransmittedBytes:   port.Counters["port_xmit_data"] * 4,
+			ReceivedPackets:    port.Counters["port_rcv_packets"],
+			TransmittedPackets: port.Counters["port_xmit_packets"],
+			ReceiveErrors:      port.Counters["port_rcv_errors"],
+			TransmitDiscards:   port.Counters["port_xmit_discards"],
+			LinkDowned:         port.Counters["link_downed"],
+		})
+	}
+	return ports, nil
+}
+
+// GetCPUVulnerabilities returns mitigation state of CPU vulnerabilities, nil is returned when kernel
+// does not report vulnerabilities (before 4.15).
+func GetCPUVulnerabilities(sysFs sysfs.SysFs) (map[string]string, error) {
+	vulnerabilities, err := sysFs.GetCPUVulnerabilities()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return vulnerabilities, err
+}
+
+// GetOnlineAndOfflineCPUs returns logical CPUs which are online and offline, CPUs which are not present
+// are not reported.
+func GetOnlineAndOfflineCPUs(sysFs sysfs.SysFs) ([]int, []int, error) {
+	onlineList, err := sysFs.GetOnlineCPUs()
+	if err != nil {
+		return nil, nil, err
+	}
+	online, err := parseCPUList(onlineList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse online CPUs %q: %v", onlineList, err)
+	}
+	offlineList, err := sysFs.GetOfflineCPUs()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return online, []int{}, nil
+		}
+		return nil, nil, err
+	}
+	offline, err := parseCPUList(offlineList)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse offline CPUs %q: %v", offlineList, err)
+	}
+	return online, offline, nil
+}
+
+// GetOnlineMemoryBlocks returns number of online memory blocks, it changes when memory is hot-added
+// or hot-removed. Memory blocks which are removed concurrently are skipped.
+func GetOnlineMemoryBlocks(sysFs sysfs.SysFs) (int, error) {
+	blocks, err := sysFs.GetMemoryBlocksPaths()
+	if err != nil {
+		return 0, err
+	}
+	online := 0
+	for _, block := range blocks {
+		state, err := sysFs.GetMemoryBlockState(block)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		if state == "online" {
+			online++
+		}
+	}
+	return online, nil
+}
+
+// GetMemoryDevices returns memory devices (DIMMs) described by SMBIOS, empty slots are skipped.
+func GetMemoryDevices(sysFs sysfs.SysFs) ([]info.MemoryDevice, error) {
+	entries, err := sysFs.GetDMIEntries(dmiMemoryDeviceType)
+	if err != nil {
+		return nil, err
+	}
+	devices := []info.MemoryDevice{}
+	for _, entry := range entries {
+		device, err := parseDMIMemoryDevice(entry)
+		if err != nil {
+			return nil, err
+		}
+		if device.Size == 0 {
+			continue
+		}
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Locator < devices[j].Locator
+	})
+	return devices, nil
+}
+
+// GetMemoryByTypeFromDevices returns capacity and number of memory devices by memory type.
+func GetMemoryByTypeFromDevices(devices []info.MemoryDevice) map[string]*info.MemoryInfo {
+	memory := map[string]*info.MemoryInfo{}
+	for _, device := range devices {
+		if _, exists := memory[device.Type]; !exists {
+			memory[device.Type] = &info.MemoryInfo{}
+		}
+		memory[device.Type].Capacity += device.Size
+		memory[device.Type].DimmCount++
+	}
+	return memory
+}
+
+// parseDMIMemoryDevice parses raw SMBIOS memory device structure, which consists of formatted
+// area followed by set of null-terminated strings referenced by their 1-based index.
+func parseDMIMemoryDevice(raw []byte) (info.MemoryDevice, error) {
+	if len(raw) < dmiMemoryDeviceMinLength || raw[0] != dmiMemoryDeviceType {
+		return info.MemoryDevice{}, fmt.Errorf("unexpected SMBIOS memory device structure of length %d", len(raw))
+	}
+	length := int(raw[1])
+	if length < dmiMemoryDeviceMinLength || length > len(raw) {
+		return info.MemoryDevice{}, fmt.Errorf("unexpected length of SMBIOS memory device structure: %d", length)
+	}
+	formatted := raw[:length]
+	strs := strings.Split(string(raw[length:]), "\x00")
+	dmiString := func(offset int) string {
+		if offset >= length {
+			return ""
+		}
+		index := int(formatted[offset])
+		if index == 0 || index > len(strs) {
+			return ""
+		}
+		return strings.TrimSpace(strs[index-1])
+	}
+
+	device := info.MemoryDevice{
+		Locator:      dmiString(dmiMemoryDeviceLocatorOffset),
+		BankLocator:  dmiString(dmiMemoryDeviceBankOffset),
+		Type:         "Unknown",
+		Manufacturer: dmiString(dmiMemoryDeviceVendorOffset),
+	}
+	if memType, ok := dmiMemoryTypes[formatted[dmiMemoryDeviceTypeOffset]]; ok {
+		device.Type = memType
+	}
+	if length >= dmiMemoryDeviceSpeedOffset+2 {
+		device.Speed = uint64(binary.LittleEndian.Uint16(formatted[dmiMemoryDeviceSpeedOffset:]))
+	}
+
+	size := binary.LittleEndian.Uint16(formatted[dmiMemoryDeviceSizeOffset:])
+	switch {
+	case size == dmiMemoryDeviceSizeUnknown:
+		// Size is unknown.
+	case size == dmiMemoryDeviceSizeExtended:
+		if length >= dmiMemoryDeviceExtSizeOffset+4 {
+			extSize := binary.LittleEndian.Uint32(formatted[dmiMemoryDeviceExtSizeOffset:]) & 0x7FFFFFFF
+			device.Size = uint64(extSize) * 1024 * 1024
+		}
+	case size&dmiMemoryDeviceSizeInKilobytes != 0:
+		device.Size = uint64(size&^dmiMemoryDeviceSizeInKilobytes) * 1024
+	default:
+		device.Size = uint64(size) * 1024 * 1024
+	}
+	return device, nil
+}
+
+// parseTimeInState parses content of cpufreq/stats/time_in_state, each line consists of frequency in kHz
+// and time spent at the frequency in 10ms units, e.g. "2400000 1234".
+func parseTimeInState(timeInState string) (map[uint64]uint64, error) {
+	times := make(map[uint64]uint64)
+	for _, line := range strings.Split(timeInState, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected line %q", line)
+		}
+		freq, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		units, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		times[freq] = units * 10
+	}
+	return times, nil
+}
+
+// addCacheInfo adds information about cache for NUMA node, caches of cores are read concurrently,
+// nested must be set when called from a function run by parallelize.
+func addCacheInfo(sysFs sysfs.SysFs, node *info.Node, nested bool) error {
+	coresCaches := make([][]sysfs.CacheInfo, len(node.Cores))
+	errs := make([]error, len(node.Cores))
+	parallelize(len(node.Cores), nested, func(i int) {
+		threadID := node.Cores[i].Threads[0] //get any thread for core
+		coresCaches[i], errs[i] = GetCacheInfo(sysFs, threadID)
+	})
+
+	// Number of threads of clusters of the node.
+	type clusterKey struct {
+		socketID  int
+		dieID     string
+		clusterID string
+	}
+	clusterThreads := map[clusterKey]int{}
+	for _, core := range node.Cores {
+		clusterThreads[clusterKey{core.SocketID, core.DieID, core.ClusterID}] += len(core.Threads)
+	}
+
+	for coreID, core := range node.Cores {
+		if errs[coreID] != nil {
+			return errs[coreID]
+		}
+		caches := coresCaches[coreID]
 
 		numThreadsPerCore := len(core.Threads)
 		numThreadsPerNode := len(node.Cores) * numThreadsPerCore
 
 		for _, cache := range caches {
 			c := info.Cache{
-				Size:  cache.Size,
-				Level: cache.Level,
-				Type:  cache.Type,
+				Size:          cache.Size,
+				Level:         cache.Level,
+				Type:          cache.Type,
+				SharedCPUList: cache.CPUList,
 			}
 			if cache.Cpus == numThreadsPerNode && cache.Level > cacheLevel2 {
 				// Add a node-level cache.
@@ -350,6 +1317,21 @@ func addCacheInfo(sysFs sysfs.SysFs, node *info.Node) error {
 			} else if cache.Cpus == numThreadsPerCore {
 				// Add core level cache
 				node.Cores[coreID].Caches = append(node.Cores[coreID].Caches, c)
+			} else if cache.Cpus > numThreadsPerCore {
+				// Add cache shared by group of cores, e.g. core cluster, die or socket spanning several nodes.
+				uncoreCache := info.UncoreCache{Cache: c, SocketID: core.SocketID, DieID: core.DieID}
+				if core.ClusterID != "" && cache.Cpus <= clusterThreads[clusterKey{core.SocketID, core.DieID, core.ClusterID}] {
+					uncoreCache.ClusterID = core.ClusterID
+				}
+				cacheFound := false
+				for _, nodeCache := range node.UncoreCaches {
+					if nodeCache == uncoreCache {
+						cacheFound = true
+					}
+				}
+				if !cacheFound {
+					node.UncoreCaches = append(node.UncoreCaches, uncoreCache)
+				}
 			}
 			// Ignore unknown caches.
 		}
@@ -377,33 +1359,108 @@ func getNodeMemInfo(sysFs sysfs.SysFs, nodeDir string) (uint64, error) {
 	return uint64(memory), nil
 }
 
-// getCoresInfo returns information about physical cores
-func getCoresInfo(sysFs sysfs.SysFs, cpuDirs []string) ([]info.Core, error) {
-	cores := make([]info.Core, 0, len(cpuDirs))
-	for _, cpuDir := range cpuDirs {
-		cpuID, err := getMatchedInt(cpuDirRegExp, cpuDir)
+// setNodeMemoryUsage sets free, file-backed and anonymous memory of NUMA node
+func setNodeMemoryUsage(sysFs sysfs.SysFs, nodeDir string, node *info.Node) error {
+	rawMem, err := sysFs.GetMemInfo(nodeDir)
+	if err != nil {
+		// Ignore if per-node info is not available, it is reported by getNodeMemInfo.
+		return nil
+	}
+	for _, matches := range nodeMemoryUsageRegexp.FindAllStringSubmatch(rawMem, -1) {
+		value, err := strconv.ParseUint(matches[2], 10, 64)
 		if err != nil {
-			return nil, fmt.Errorf("Unexpected format of CPU directory, cpuDirRegExp %s, cpuDir: %s", cpuDirRegExp, cpuDir)
+			return err
 		}
-		if !sysFs.IsCPUOnline(cpuDir) {
-			continue
+		value = value * 1024 // Convert to bytes
+		switch matches[1] {
+		case "MemFree":
+			node.MemoryFree = value
+		case "FilePages":
+			node.MemoryFile = value
+		case "AnonPages":
+			node.MemoryAnon = value
 		}
+	}
+	return nil
+}
 
-		rawPhysicalID, err := sysFs.GetCoreID(cpuDir)
-		if os.IsNotExist(err) {
-			klog.Warningf("Cannot read core id for %s, core_id file does not exist, err: %s", cpuDir, err)
-			continue
-		} else if err != nil {
-			return nil, err
+// SetNodesCounters reads current memory usage and huge pages counters of NUMA nodes, nodes which
+// are no longer present keep their previous values.
+func SetNodesCounters(sysFs sysfs.SysFs, nodes []info.Node) error {
+	nodesDirs, err := sysFs.GetNodesPaths()
+	if err != nil {
+		return err
+	}
+	for _, nodeDir := range nodesDirs {
+		id, err := getMatchedInt(nodeDirRegExp, nodeDir)
+		if err != nil {
+			return err
 		}
-		physicalID, err := strconv.Atoi(rawPhysicalID)
+		for i := range nodes {
+			if nodes[i].Id != id {
+				continue
+			}
+			node := nodes[i]
+			err = setNodeMemoryUsage(sysFs, nodeDir, &node)
+			if err != nil {
+				return err
+			}
+			hugepagesDirectory := fmt.Sprintf("%s/%s", nodeDir, hugepagesDir)
+			node.HugePages, err = GetHugePagesInfo(sysFs, hugepagesDirectory)
+			if err != nil {
+				return err
+			}
+			nodes[i] = node
+			break
+		}
+	}
+	return nil
+}
+
+// getNodeDistances returns distances from NUMA node to all NUMA nodes
+func getNodeDistances(sysFs sysfs.SysFs, nodeDir string) ([]uint64, error) {
+	rawDistances, err := sysFs.GetDistances(nodeDir)
+	if err != nil {
+		//Ignore if per-node distances are not available.
+		klog.Warningf("Found node without distance information, nodeDir: %s", nodeDir)
+		return nil, nil
+	}
+	fields := strings.Fields(rawDistances)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	distances := make([]uint64, 0, len(fields))
+	for _, field := range fields {
+		distance, err := strconv.ParseUint(field, 10, 64)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("could not parse distances %q for node %s: %v", rawDistances, nodeDir, err)
+		}
+		distances = append(distances, distance)
+	}
+	return distances, nil
+}
+
+// getCoresInfo returns information about physical cores, topology of CPUs is read concurrently,
+// nested must be set when called from a function run by parallelize.
+func getCoresInfo(sysFs sysfs.SysFs, cpuDirs []string, nested bool) ([]info.Core, error) {
+	cpus := make([]cpuTopology, len(cpuDirs))
+	errs := make([]error, len(cpuDirs))
+	parallelize(len(cpuDirs), nested, func(i int) {
+		cpus[i], errs[i] = readCPUTopology(sysFs, cpuDirs[i])
+	})
+
+	cores := make([]info.Core, 0, len(cpuDirs))
+	for i, cpu := range cpus {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if cpu.skip {
+			continue
 		}
 
 		coreIDx := -1
 		for id, core := range cores {
-			if core.Id == physicalID {
+			if core.Id == cpu.coreID {
 				coreIDx = id
 			}
 		}
@@ -413,28 +1470,161 @@ func getCoresInfo(sysFs sysfs.SysFs, cpuDirs []string) ([]info.Core, error) {
 		}
 		desiredCore := &cores[coreIDx]
 
-		desiredCore.Id = physicalID
+		desiredCore.Id = cpu.coreID
 		if len(desiredCore.Threads) == 0 {
-			desiredCore.Threads = []int{cpuID}
+			desiredCore.Threads = []int{cpu.cpuID}
 		} else {
-			desiredCore.Threads = append(desiredCore.Threads, cpuID)
+			desiredCore.Threads = append(desiredCore.Threads, cpu.cpuID)
 		}
-
-		rawPhysicalPackageID, err := sysFs.GetCPUPhysicalPackageID(cpuDir)
-		if os.IsNotExist(err) {
-			klog.Warningf("Cannot read physical package id for %s, physical_package_id file does not exist, err: %s", cpuDir, err)
-			continue
-		} else if err != nil {
-			return nil, err
+		if cpu.dieID != "" {
+			desiredCore.DieID = cpu.dieID
+		}
+		if cpu.clusterID != "" {
+			desiredCore.ClusterID = cpu.clusterID
+		}
+		if cpu.bookID != "" {
+			desiredCore.BookID = cpu.bookID
+		}
+		if cpu.drawerID != "" {
+			desiredCore.DrawerID = cpu.drawerID
+		}
+		if cpu.capacity != 0 {
+			desiredCore.Capacity = cpu.capacity
+		}
+		if cpu.maxFrequency > desiredCore.MaxFrequency {
+			desiredCore.MaxFrequency = cpu.maxFrequency
 		}
+		if cpu.hasSocketID {
+			desiredCore.SocketID = cpu.socketID
+		}
+	}
+	return cores, nil
+}
 
-		physicalPackageID, err := strconv.Atoi(rawPhysicalPackageID)
+// cpuTopology is topology of a single CPU read from sysfs.
+type cpuTopology struct {
+	cpuID  int
+	coreID int
+	// skip is set for offline CPUs and CPUs without core id.
+	skip         bool
+	dieID        string
+	clusterID    string
+	bookID       string
+	drawerID     string
+	capacity     uint64
+	maxFrequency uint64
+	socketID     int
+	hasSocketID  bool
+}
+
+// readCPUTopology reads topology of the CPU, it is safe to call it concurrently for different CPUs.
+func readCPUTopology(sysFs sysfs.SysFs, cpuDir string) (cpuTopology, error) {
+	cpu := cpuTopology{}
+	cpuID, err := getMatchedInt(cpuDirRegExp, cpuDir)
+	if err != nil {
+		return cpu, fmt.Errorf("Unexpected format of CPU directory, cpuDirRegExp %s, cpuDir: %s", cpuDirRegExp, cpuDir)
+	}
+	cpu.cpuID = cpuID
+	if !sysFs.IsCPUOnline(cpuDir) {
+		cpu.skip = true
+		return cpu, nil
+	}
+
+	rawPhysicalID, err := sysFs.GetCoreID(cpuDir)
+	if os.IsNotExist(err) {
+		klog.Warningf("Cannot read core id for %s, core_id file does not exist, err: %s", cpuDir, err)
+		cpu.skip = true
+		return cpu, nil
+	} else if err != nil {
+		return cpu, err
+	}
+	cpu.coreID, err = strconv.Atoi(rawPhysicalID)
+	if err != nil {
+		return cpu, err
+	}
+
+	// die_id and cluster_id are not available on older kernels.
+	if dieID, err := sysFs.GetDieID(cpuDir); err == nil {
+		cpu.dieID = dieID
+	} else if !os.IsNotExist(err) {
+		klog.V(4).Infof("Cannot read die id for %s, err: %s", cpuDir, err)
+	}
+	if clusterID, err := sysFs.GetClusterID(cpuDir); err == nil {
+		cpu.clusterID = clusterID
+	} else if !os.IsNotExist(err) {
+		klog.V(4).Infof("Cannot read cluster id for %s, err: %s", cpuDir, err)
+	}
+	// book_id and drawer_id are available only on s390x.
+	if bookID, err := sysFs.GetBookID(cpuDir); err == nil {
+		cpu.bookID = bookID
+	} else if !os.IsNotExist(err) {
+		klog.V(4).Infof("Cannot read book id for %s, err: %s", cpuDir, err)
+	}
+	if drawerID, err := sysFs.GetDrawerID(cpuDir); err == nil {
+		cpu.drawerID = drawerID
+	} else if !os.IsNotExist(err) {
+		klog.V(4).Infof("Cannot read drawer id for %s, err: %s", cpuDir, err)
+	}
+	// cpu_capacity is available on arm64 and riscv.
+	if rawCapacity, err := sysFs.GetCPUCapacity(cpuDir); err == nil {
+		capacity, err := strconv.ParseUint(rawCapacity, 10, 64)
 		if err != nil {
-			return nil, err
+			klog.Warningf("Cannot parse capacity of %s: %s", cpuDir, err)
+		} else {
+			cpu.capacity = capacity
 		}
-		desiredCore.SocketID = physicalPackageID
 	}
-	return cores, nil
+	if rawMaxFreq, err := sysFs.GetCPUMaxFrequency(cpuDir); err == nil {
+		maxFreq, err := strconv.ParseUint(rawMaxFreq, 10, 64)
+		if err != nil {
+			klog.Warningf("Cannot parse maximal frequency of %s: %s", cpuDir, err)
+		} else {
+			cpu.maxFrequency = maxFreq
+		}
+	}
+
+	rawPhysicalPackageID, err := sysFs.GetCPUPhysicalPackageID(cpuDir)
+	if os.IsNotExist(err) {
+		klog.Warningf("Cannot read physical package id for %s, physical_package_id file does not exist, err: %s", cpuDir, err)
+		return cpu, nil
+	} else if err != nil {
+		return cpu, err
+	}
+	cpu.socketID, err = parsePhysicalPackageID(rawPhysicalPackageID)
+	if err != nil {
+		return cpu, err
+	}
+	cpu.hasSocketID = true
+	return cpu, nil
+}
+
+// parallelize calls f for each index from 0 to n-1 in goroutines, at most topologyReadParallelism of them
+// run at once across all calls of parallelize. nested must be set when parallelize is called from f, the caller
+// holds a slot then, so it does not wait for free slots, which could be held by its callers, and calls f itself
+// when none is free. Reading topology of machines with hundreds of CPUs one file at a time is slow.
+func parallelize(n int, nested bool, f func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if nested {
+			select {
+			case topologyReadLimiter <- struct{}{}:
+			default:
+				f(i)
+				continue
+			}
+		} else {
+			topologyReadLimiter <- struct{}{}
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-topologyReadLimiter
+				wg.Done()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
 }
 
 // GetCacheInfo return information about a cache accessible from the given cpu thread
@@ -450,7 +1640,11 @@ func GetCacheInfo(sysFs sysfs.SysFs, id int) ([]sysfs.CacheInfo, error) {
 			continue
 		}
 		cacheInfo, err := sysFs.GetCacheInfo(id, cache.Name())
-		if err != nil {
+		if os.IsNotExist(err) {
+			// Firmware of arm64 machines often describes only some attributes of caches.
+			klog.V(4).Infof("Ignoring incomplete cache %s of CPU %d: %s", cache.Name(), id, err)
+			continue
+		} else if err != nil {
 			return nil, err
 		}
 		info = append(info, cacheInfo)
@@ -15,11 +15,15 @@
 package sysinfo
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils/sysfs"
@@ -103,6 +107,36 @@ func TestGetHugePagesInfoWithWrongNrHugePageValue(t *testing.T) {
 	assert.Equal(t, 0, len(hugePagesInfo))
 }
 
+func TestGetHugePagesInfoWithCounters(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	hugePages := []os.FileInfo{
+		&fakesysfs.FileInfo{EntryName: "hugepages-2048kB"},
+	}
+	fakeSys.SetHugePages(hugePages, nil)
+
+	hugePageNr := map[string]string{
+		"/fakeSysfs/devices/system/node/node0/hugepages/hugepages-2048kB/nr_hugepages": "512",
+	}
+	fakeSys.SetHugePagesNr(hugePageNr, nil)
+	// resv_hugepages is not available per NUMA node.
+	fakeSys.SetHugePagesCounters(map[string]string{
+		"/fakeSysfs/devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages":    "100",
+		"/fakeSysfs/devices/system/node/node0/hugepages/hugepages-2048kB/surplus_hugepages": "2",
+	})
+
+	hugePagesInfo, err := GetHugePagesInfo(&fakeSys, "/fakeSysfs/devices/system/node/node0/hugepages/")
+	assert.Nil(t, err)
+	assert.Equal(t, []info.HugePagesInfo{
+		{PageSize: 2048, NumPages: 512, FreePages: 100, SurplusPages: 2},
+	}, hugePagesInfo)
+
+	fakeSys.SetHugePagesCounters(map[string]string{
+		"/fakeSysfs/devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages": "-",
+	})
+	_, err = GetHugePagesInfo(&fakeSys, "/fakeSysfs/devices/system/node/node0/hugepages/")
+	assert.NotNil(t, err)
+}
+
 func TestGetNodesInfo(t *testing.T) {
 	fakeSys := &fakesysfs.FakeSysFs{}
 	c := sysfs.CacheInfo{
@@ -731,6 +765,71 @@ func TestGetNodesInfoWithoutNodes(t *testing.T) {
 	assert.JSONEq(t, expectedNodes, string(nodesJSON))
 }
 
+// TestGetNodesInfoOnArm64Server uses layout of Graviton2 or Ampere Altra scaled down to four cores:
+// no SMT, single package reported as -1 when firmware does not describe it, private L1 and L2 caches,
+// L3 shared by all cores and cpu_capacity provided instead of cpufreq.
+func TestGetNodesInfoOnArm64Server(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetNodesPaths([]string{}, nil)
+
+	cpus := []string{
+		cpusPath + "/cpu0",
+		cpusPath + "/cpu1",
+		cpusPath + "/cpu2",
+		cpusPath + "/cpu3",
+	}
+	fakeSys.SetCPUsPaths(map[string][]string{cpusPath: cpus}, nil)
+
+	coreThread := map[string]string{}
+	physicalPackageIDs := map[string]string{}
+	clusterIDs := map[string]string{}
+	capacities := map[string]string{}
+	cpuCaches := map[int][]sysfs.CacheInfo{}
+	for i, cpu := range cpus {
+		coreThread[cpu] = strconv.Itoa(i)
+		physicalPackageIDs[cpu] = "-1"
+		clusterIDs[cpu] = strconv.Itoa(i)
+		capacities[cpu] = "1024"
+		cpuCaches[i] = []sysfs.CacheInfo{
+			{Size: 64 * 1024, Type: "Data", Level: 1, Cpus: 1, CPUList: strconv.Itoa(i)},
+			{Size: 64 * 1024, Type: "Instruction", Level: 1, Cpus: 1, CPUList: strconv.Itoa(i)},
+			{Size: 1024 * 1024, Type: "Unified", Level: 2, Cpus: 1, CPUList: strconv.Itoa(i)},
+			{Size: 32 * 1024 * 1024, Type: "Unified", Level: 3, Cpus: 4, CPUList: "0-3"},
+		}
+	}
+	// System level cache described without attributes by firmware.
+	cpuCaches[3] = append(cpuCaches[3], sysfs.CacheInfo{})
+	fakeSys.SetCoreThreads(coreThread, nil)
+	fakeSys.SetPhysicalPackageIDs(physicalPackageIDs, nil)
+	fakeSys.SetClusterIDs(clusterIDs)
+	fakeSys.SetCPUCapacities(capacities)
+	fakeSys.SetCPUCaches(cpuCaches)
+
+	nodes, numCores, err := GetNodesInfo(fakeSys)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, numCores)
+	assert.Equal(t, 1, len(nodes))
+	assert.Equal(t, 0, nodes[0].Id)
+	assert.Equal(t, []info.Cache{
+		{Size: 32 * 1024 * 1024, Type: "Unified", Level: 3, SharedCPUList: "0-3"},
+	}, nodes[0].Caches)
+	assert.Equal(t, 4, len(nodes[0].Cores))
+	for i, core := range nodes[0].Cores {
+		assert.Equal(t, i, core.Id)
+		assert.Equal(t, []int{i}, core.Threads)
+		assert.Equal(t, 0, core.SocketID)
+		assert.Equal(t, strconv.Itoa(i), core.ClusterID)
+		assert.Equal(t, uint64(1024), core.Capacity)
+		// All cores have the same capacity, so CPU is not hybrid.
+		assert.Empty(t, core.CoreType)
+		assert.Equal(t, []info.Cache{
+			{Size: 64 * 1024, Type: "Data", Level: 1, SharedCPUList: strconv.Itoa(i)},
+			{Size: 64 * 1024, Type: "Instruction", Level: 1, SharedCPUList: strconv.Itoa(i)},
+			{Size: 1024 * 1024, Type: "Unified", Level: 2, SharedCPUList: strconv.Itoa(i)},
+		}, core.Caches)
+	}
+}
+
 func TestGetNodesInfoWithoutNodesWhenPhysicalPackageIDMissingForOneCPU(t *testing.T) {
 	fakeSys := &fakesysfs.FakeSysFs{}
 
@@ -1061,6 +1160,94 @@ func TestGetNodeMemInfoWhenMemInfoMissing(t *testing.T) {
 	assert.Equal(t, uint64(0), mem)
 }
 
+func TestSetNodeMemoryUsage(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	memInfo := `Node 0 MemTotal:       32817192 kB
+Node 0 MemFree:        20112372 kB
+Node 0 MemUsed:        12704820 kB
+Node 0 FilePages:       8145808 kB
+Node 0 AnonPages:       3209544 kB`
+	fakeSys.SetMemory(memInfo, nil)
+
+	node := info.Node{}
+	err := setNodeMemoryUsage(fakeSys, "/fakeSysfs/devices/system/node/node0", &node)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(20112372*1024), node.MemoryFree)
+	assert.Equal(t, uint64(8145808*1024), node.MemoryFile)
+	assert.Equal(t, uint64(3209544*1024), node.MemoryAnon)
+}
+
+func TestSetNodesCounters(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetNodesPaths([]string{"/fakeSysfs/devices/system/node/node1"}, nil)
+	fakeSys.SetMemory("Node 1 MemFree:        20112372 kB", nil)
+	fakeSys.SetHugePages([]os.FileInfo{&fakesysfs.FileInfo{EntryName: "hugepages-2048kB"}}, nil)
+	fakeSys.SetHugePagesNr(map[string]string{
+		"/fakeSysfs/devices/system/node/node1/hugepages/hugepages-2048kB/nr_hugepages": "512",
+	}, nil)
+	fakeSys.SetHugePagesCounters(map[string]string{
+		"/fakeSysfs/devices/system/node/node1/hugepages/hugepages-2048kB/free_hugepages": "100",
+	})
+
+	nodes := []info.Node{
+		{Id: 0, MemoryFree: 1024},
+		{Id: 1, Memory: 33604804608, MemoryFree: 1024, HugePages: []info.HugePagesInfo{{PageSize: 2048, NumPages: 512, FreePages: 512}}},
+	}
+	err := SetNodesCounters(fakeSys, nodes)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.Node{
+		{Id: 0, MemoryFree: 1024},
+		{Id: 1, Memory: 33604804608, MemoryFree: 20112372 * 1024, HugePages: []info.HugePagesInfo{{PageSize: 2048, NumPages: 512, FreePages: 100}}},
+	}, nodes)
+
+	// Counters of node are kept when they cannot be read.
+	fakeSys.SetHugePagesCounters(map[string]string{
+		"/fakeSysfs/devices/system/node/node1/hugepages/hugepages-2048kB/free_hugepages": "-",
+	})
+	fakeSys.SetMemory("Node 1 MemFree:        10 kB", nil)
+	err = SetNodesCounters(fakeSys, nodes)
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(20112372*1024), nodes[1].MemoryFree)
+}
+
+func TestGetNodeDistances(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	distances := map[string]string{
+		"/fakeSysfs/devices/system/node/node0": "10 21",
+		"/fakeSysfs/devices/system/node/node1": "21 10",
+	}
+	fakeSys.SetDistances(distances, nil)
+
+	nodeDistances, err := getNodeDistances(fakeSys, "/fakeSysfs/devices/system/node/node0")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{10, 21}, nodeDistances)
+
+	nodeDistances, err = getNodeDistances(fakeSys, "/fakeSysfs/devices/system/node/node1")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{21, 10}, nodeDistances)
+}
+
+func TestGetNodeDistancesWithWrongValue(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	distances := map[string]string{
+		"/fakeSysfs/devices/system/node/node0": "10 XX",
+	}
+	fakeSys.SetDistances(distances, nil)
+
+	nodeDistances, err := getNodeDistances(fakeSys, "/fakeSysfs/devices/system/node/node0")
+	assert.NotNil(t, err)
+	assert.Nil(t, nodeDistances)
+}
+
+func TestGetNodeDistancesWhenDistanceMissing(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetDistances(nil, fmt.Errorf("Cannot read distance file"))
+
+	nodeDistances, err := getNodeDistances(fakeSys, "/fakeSysfs/devices/system/node/node0")
+	assert.Nil(t, err)
+	assert.Nil(t, nodeDistances)
+}
+
 func TestGetCoresInfoWhenCoreIDIsNotDigit(t *testing.T) {
 	sysFs := &fakesysfs.FakeSysFs{}
 	nodesPaths := []string{
@@ -1080,7 +1267,7 @@ func TestGetCoresInfoWhenCoreIDIsNotDigit(t *testing.T) {
 	}
 	sysFs.SetCoreThreads(coreThread, nil)
 
-	cores, err := getCoresInfo(sysFs, []string{"/fakeSysfs/devices/system/node/node0/cpu0"})
+	cores, err := getCoresInfo(sysFs, []string{"/fakeSysfs/devices/system/node/node0/cpu0"}, false)
 	assert.NotNil(t, err)
 	assert.Equal(t, []info.Core(nil), cores)
 }
@@ -1114,6 +1301,7 @@ func TestGetCoresInfoWithOnlineOfflineFile(t *testing.T) {
 	cores, err := getCoresInfo(
 		sysFs,
 		[]string{"/fakeSysfs/devices/system/node/node0/cpu0", "/fakeSysfs/devices/system/node/node0/cpu1"},
+		false,
 	)
 	assert.NoError(t, err)
 	expected := []info.Core{
@@ -1127,6 +1315,508 @@ func TestGetCoresInfoWithOnlineOfflineFile(t *testing.T) {
 	assert.Equal(t, expected, cores)
 }
 
+func TestGetCoresInfoWithDieAndCluster(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	cpus := []string{
+		"/fakeSysfs/devices/system/cpu/cpu0",
+		"/fakeSysfs/devices/system/cpu/cpu1",
+	}
+	sysFs.SetCoreThreads(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "1",
+	}, nil)
+	sysFs.SetPhysicalPackageIDs(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "0",
+	}, nil)
+	sysFs.SetDieIDs(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "1",
+	})
+	sysFs.SetClusterIDs(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "8",
+	})
+
+	cores, err := getCoresInfo(sysFs, cpus, false)
+	assert.NoError(t, err)
+	expected := []info.Core{
+		{
+			Id:        0,
+			Threads:   []int{0},
+			SocketID:  0,
+			DieID:     "0",
+			ClusterID: "0",
+		},
+		{
+			Id:        1,
+			Threads:   []int{1},
+			SocketID:  0,
+			DieID:     "1",
+			ClusterID: "8",
+		},
+	}
+	assert.Equal(t, expected, cores)
+}
+
+func TestGetCoresInfoWithBookAndDrawer(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	cpus := []string{
+		"/fakeSysfs/devices/system/cpu/cpu0",
+		"/fakeSysfs/devices/system/cpu/cpu1",
+	}
+	sysFs.SetCoreThreads(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "1",
+	}, nil)
+	sysFs.SetPhysicalPackageIDs(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "1",
+	}, nil)
+	sysFs.SetBookIDs(map[string]string{
+		cpus[0]: "1",
+		cpus[1]: "2",
+	})
+	sysFs.SetDrawerIDs(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "0",
+	})
+
+	cores, err := getCoresInfo(sysFs, cpus, false)
+	assert.NoError(t, err)
+	expected := []info.Core{
+		{
+			Id:       0,
+			Threads:  []int{0},
+			SocketID: 0,
+			BookID:   "1",
+			DrawerID: "0",
+		},
+		{
+			Id:       1,
+			Threads:  []int{1},
+			SocketID: 1,
+			BookID:   "2",
+			DrawerID: "0",
+		},
+	}
+	assert.Equal(t, expected, cores)
+}
+
+func TestGetCoresInfoWithMaxFrequency(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	cpus := []string{
+		"/fakeSysfs/devices/system/cpu/cpu0",
+		"/fakeSysfs/devices/system/cpu/cpu1",
+	}
+	sysFs.SetCoreThreads(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "0",
+	}, nil)
+	sysFs.SetPhysicalPackageIDs(map[string]string{
+		cpus[0]: "0",
+		cpus[1]: "0",
+	}, nil)
+	sysFs.SetCPUMaxFrequencies(map[string]string{
+		cpus[0]: "4700000",
+		cpus[1]: "4800000",
+	})
+
+	cores, err := getCoresInfo(sysFs, cpus, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(cores))
+	assert.Equal(t, uint64(4800000), cores[0].MaxFrequency)
+}
+
+func TestAddCoreTypesOnIntelHybridCPU(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetPMUCPUs(map[string]string{
+		"cpu_core": "0-1",
+		"cpu_atom": "2",
+	})
+	nodes := []info.Node{
+		{
+			Cores: []info.Core{
+				{Id: 0, Threads: []int{0, 1}},
+				{Id: 8, Threads: []int{2}},
+			},
+		},
+	}
+
+	addCoreTypes(sysFs, nodes)
+	assert.Equal(t, info.CoreTypePerformance, nodes[0].Cores[0].CoreType)
+	assert.Equal(t, info.CoreTypeEfficiency, nodes[0].Cores[1].CoreType)
+}
+
+func TestAddCoreTypesOnBigLittleCPU(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetCPUCapacities(map[string]string{
+		"/sys/devices/system/cpu/cpu0": "446",
+		"/sys/devices/system/cpu/cpu1": "1024",
+	})
+	nodes := []info.Node{
+		{
+			Cores: []info.Core{
+				{Id: 0, Threads: []int{0}},
+				{Id: 1, Threads: []int{1}},
+			},
+		},
+	}
+
+	addCoreTypes(sysFs, nodes)
+	assert.Equal(t, info.CoreTypeEfficiency, nodes[0].Cores[0].CoreType)
+	assert.Equal(t, info.CoreTypePerformance, nodes[0].Cores[1].CoreType)
+}
+
+func TestAddCoreTypesOnNonHybridCPU(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetCPUCapacities(map[string]string{
+		"/sys/devices/system/cpu/cpu0": "1024",
+		"/sys/devices/system/cpu/cpu1": "1024",
+	})
+	nodes := []info.Node{
+		{
+			Cores: []info.Core{
+				{Id: 0, Threads: []int{0}},
+				{Id: 1, Threads: []int{1}},
+			},
+		},
+	}
+
+	addCoreTypes(sysFs, nodes)
+	assert.Equal(t, "", nodes[0].Cores[0].CoreType)
+	assert.Equal(t, "", nodes[0].Cores[1].CoreType)
+}
+
+func TestGetCPUFrequencies(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetCPUsPaths(map[string][]string{
+		"/sys/devices/system/cpu": {
+			"/sys/devices/system/cpu/cpu0",
+			"/sys/devices/system/cpu/cpu1",
+			"/sys/devices/system/cpu/cpu10",
+			"/sys/devices/system/cpu/cpu2",
+		},
+	}, nil)
+	sysFs.SetOnlineCPUs(map[string]interface{}{
+		"/sys/devices/system/cpu/cpu0":  nil,
+		"/sys/devices/system/cpu/cpu1":  nil,
+		"/sys/devices/system/cpu/cpu10": nil,
+	})
+	sysFs.SetCPUFrequencyScaling(
+		map[string]string{
+			"/sys/devices/system/cpu/cpu0":  "2400000",
+			"/sys/devices/system/cpu/cpu10": "800000",
+			"/sys/devices/system/cpu/cpu2":  "2400000",
+		},
+		map[string]string{
+			"/sys/devices/system/cpu/cpu0":  "powersave",
+			"/sys/devices/system/cpu/cpu10": "performance",
+		},
+		map[string]string{
+			"/sys/devices/system/cpu/cpu0": "2400000 150\n800000 25\n",
+		},
+	)
+
+	frequencies, err := GetCPUFrequencies(sysFs)
+	assert.Nil(t, err)
+	// cpu1 does not support cpufreq and cpu2 is offline.
+	expected := []info.CPUFrequency{
+		{
+			CPU:              0,
+			CurrentFrequency: 2400000,
+			Governor:         "powersave",
+			TimeInState:      map[uint64]uint64{2400000: 1500, 800000: 250},
+		},
+		{
+			CPU:              10,
+			CurrentFrequency: 800000,
+			Governor:         "performance",
+		},
+	}
+	assert.Equal(t, expected, frequencies)
+}
+
+func TestParseTimeInStateWithWrongFormat(t *testing.T) {
+	_, err := parseTimeInState("2400000\n")
+	assert.NotNil(t, err)
+
+	_, err = parseTimeInState("2400000 abc\n")
+	assert.NotNil(t, err)
+}
+
+func TestGetPowerZones(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetPowercapZones(
+		[]string{
+			"/sys/class/powercap/intel-rapl:0",
+			"/sys/class/powercap/intel-rapl:0:0",
+			"/sys/class/powercap/intel-rapl:1",
+		},
+		map[string]string{
+			"/sys/class/powercap/intel-rapl:0":   "package-0",
+			"/sys/class/powercap/intel-rapl:0:0": "dram",
+			"/sys/class/powercap/intel-rapl:1":   "package-1",
+		},
+		map[string]string{
+			"/sys/class/powercap/intel-rapl:0":   "123456789",
+			"/sys/class/powercap/intel-rapl:0:0": "4567",
+		},
+	)
+
+	zones, err := GetPowerZones(sysFs)
+	assert.Nil(t, err)
+	// Energy of intel-rapl:1 is not readable.
+	expected := []info.PowerZone{
+		{Id: "intel-rapl:0", Domain: "package-0", EnergyUJ: 123456789},
+		{Id: "intel-rapl:0:0", Domain: "dram", EnergyUJ: 4567},
+	}
+	assert.Equal(t, expected, zones)
+}
+
+func TestGetPowerZonesWithWrongEnergy(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetPowercapZones(
+		[]string{"/sys/class/powercap/intel-rapl:0"},
+		map[string]string{"/sys/class/powercap/intel-rapl:0": "package-0"},
+		map[string]string{"/sys/class/powercap/intel-rapl:0": "abc"},
+	)
+
+	_, err := GetPowerZones(sysFs)
+	assert.NotNil(t, err)
+}
+
+func TestGetThermalSensors(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetHwmonSensors([]sysfs.HwmonSensor{
+		{Device: "coretemp", Label: "Package id 0", Temperature: 45000},
+		{Device: "nvme", Label: "Composite", Temperature: 38850},
+	})
+
+	sensors, err := GetThermalSensors(sysFs)
+	assert.Nil(t, err)
+	expected := []info.ThermalSensor{
+		{Device: "coretemp", Label: "Package id 0", Temperature: 45},
+		{Device: "nvme", Label: "Composite", Temperature: 38.85},
+	}
+	assert.Equal(t, expected, sensors)
+}
+
+func TestGetNVMeDevices(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetNVMeControllers([]sysfs.NVMeController{
+		{
+			Name:       "nvme0",
+			Model:      "Samsung SSD 970 EVO Plus 1TB",
+			Firmware:   "2B2QEXM7",
+			Namespaces: map[string]uint64{"nvme0n2": 2048, "nvme0n1": 1953525168},
+			Sensors: []sysfs.HwmonSensor{
+				{Device: "nvme", Label: "Sensor 1", Temperature: 41850},
+				{Device: "nvme", Label: "Composite", Temperature: 38850},
+			},
+		},
+		{
+			Name:     "nvme1",
+			Model:    "QEMU NVMe Ctrl",
+			Firmware: "1.0",
+		},
+	})
+
+	devices, err := GetNVMeDevices(sysFs)
+	assert.Nil(t, err)
+	temperature := 38.85
+	expected := []info.NVMeDevice{
+		{
+			Name:     "nvme0",
+			Model:    "Samsung SSD 970 EVO Plus 1TB",
+			Firmware: "2B2QEXM7",
+			Namespaces: []info.NVMeNamespace{
+				{Name: "nvme0n1", Size: 1953525168 * 512},
+				{Name: "nvme0n2", Size: 2048 * 512},
+			},
+			Temperature: &temperature,
+		},
+		{
+			Name:       "nvme1",
+			Model:      "QEMU NVMe Ctrl",
+			Firmware:   "1.0",
+			Namespaces: []info.NVMeNamespace{},
+		},
+	}
+	assert.Equal(t, expected, devices)
+}
+
+func TestGetPCIDevices(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetPCIDevices([]sysfs.PCIDevice{
+		{Address: "0000:00:1f.3", Vendor: "0x8086", Device: "0xa348", Class: "0x040300", NumaNode: "-1"},
+		{
+			Address:          "0000:3b:00.0",
+			Vendor:           "0x8086",
+			Device:           "0x158b",
+			Class:            "0x020000",
+			NumaNode:         "1",
+			IOMMUGroup:       "45",
+			CurrentLinkSpeed: "8.0 GT/s PCIe",
+			MaxLinkSpeed:     "8.0 GT/s PCIe",
+			CurrentLinkWidth: "8",
+			MaxLinkWidth:     "16",
+		},
+		{Address: "0000:00:00.0", Vendor: "0x8086", Device: "0x2020", Class: "0x060000"},
+	})
+
+	devices, err := GetPCIDevices(sysFs)
+	assert.Nil(t, err)
+	expected := []info.PCIDevice{
+		{Address: "0000:00:1f.3", VendorID: "0x8086", DeviceID: "0xa348", Class: "0x040300", NumaNode: -1},
+		{
+			Address:          "0000:3b:00.0",
+			VendorID:         "0x8086",
+			DeviceID:         "0x158b",
+			Class:            "0x020000",
+			NumaNode:         1,
+			IOMMUGroup:       "45",
+			CurrentLinkSpeed: "8.0 GT/s PCIe",
+			MaxLinkSpeed:     "8.0 GT/s PCIe",
+			CurrentLinkWidth: 8,
+			MaxLinkWidth:     16,
+		},
+		{Address: "0000:00:00.0", VendorID: "0x8086", DeviceID: "0x2020", Class: "0x060000", NumaNode: -1},
+	}
+	assert.Equal(t, expected, devices)
+}
+
+func TestGetInfinibandPorts(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetInfinibandPorts([]sysfs.InfinibandPort{
+		{
+			Device: "mlx5_0",
+			Port:   1,
+			State:  "ACTIVE",
+			Counters: map[string]uint64{
+				"port_rcv_data":      1048576,
+				"port_xmit_data":     2097152,
+				"port_rcv_packets":   4096,
+				"port_xmit_packets":  8192,
+				"port_xmit_discards": 3,
+				"link_downed":        1,
+			},
+		},
+	})
+
+	ports, err := GetInfinibandPorts(sysFs)
+	assert.Nil(t, err)
+	expected := []info.InfinibandPort{
+		{
+			Device:             "mlx5_0",
+			Port:               1,
+			State:              "ACTIVE",
+			ReceivedBytes:      4194304,
+			TransmittedBytes:   8388608,
+			ReceivedPackets:    4096,
+			TransmittedPackets: 8192,
+			TransmitDiscards:   3,
+			LinkDowned:         1,
+		},
+	}
+	assert.Equal(t, expected, ports)
+}
+
+func TestGetCPUVulnerabilitiesWhenNotReported(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetCPUVulnerabilities(nil, os.ErrNotExist)
+
+	vulnerabilities, err := GetCPUVulnerabilities(sysFs)
+	assert.Nil(t, err)
+	assert.Nil(t, vulnerabilities)
+
+	sysFs.SetCPUVulnerabilities(nil, os.ErrPermission)
+	_, err = GetCPUVulnerabilities(sysFs)
+	assert.NotNil(t, err)
+}
+
+func TestGetOnlineAndOfflineCPUs(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetCPULists("0-2,4", "3", nil)
+
+	online, offline, err := GetOnlineAndOfflineCPUs(sysFs)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2, 4}, online)
+	assert.Equal(t, []int{3}, offline)
+
+	sysFs.SetCPULists("0-a", "", nil)
+	_, _, err = GetOnlineAndOfflineCPUs(sysFs)
+	assert.NotNil(t, err)
+}
+
+func TestGetOnlineMemoryBlocks(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetMemoryBlocks(
+		[]string{"/sys/devices/system/memory/memory0", "/sys/devices/system/memory/memory1", "/sys/devices/system/memory/memory2"},
+		map[string]string{
+			"/sys/devices/system/memory/memory0": "online",
+			"/sys/devices/system/memory/memory1": "offline",
+		})
+
+	online, err := GetOnlineMemoryBlocks(sysFs)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, online)
+}
+
+// dmiMemoryDevice builds raw SMBIOS 2.8 memory device structure.
+func dmiMemoryDevice(size uint16, extSize uint32, memType byte, speed uint16, strs ...string) []byte {
+	raw := make([]byte, 0x28)
+	raw[0] = dmiMemoryDeviceType
+	raw[1] = 0x28
+	binary.LittleEndian.PutUint16(raw[dmiMemoryDeviceSizeOffset:], size)
+	raw[dmiMemoryDeviceLocatorOffset] = 1
+	raw[dmiMemoryDeviceBankOffset] = 2
+	raw[dmiMemoryDeviceTypeOffset] = memType
+	binary.LittleEndian.PutUint16(raw[dmiMemoryDeviceSpeedOffset:], speed)
+	raw[dmiMemoryDeviceVendorOffset] = 3
+	binary.LittleEndian.PutUint32(raw[dmiMemoryDeviceExtSizeOffset:], extSize)
+	for _, str := range strs {
+		raw = append(raw, append([]byte(str), 0)...)
+	}
+	return append(raw, 0)
+}
+
+func TestGetMemoryDevices(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetDMIEntries(map[int][][]byte{
+		17: {
+			dmiMemoryDevice(0x7FFF, 65536, 0x22, 4800, "DIMM_B1", "BANK 1", "Micron"),
+			dmiMemoryDevice(16384, 0, 0x1A, 3200, "DIMM_A1", "BANK 0", "Samsung"),
+			dmiMemoryDevice(0x8000|512, 0, 0x0F, 0, "DIMM_A2", "BANK 0", ""),
+			// Empty slot.
+			dmiMemoryDevice(0, 0, 0x02, 0, "DIMM_A3", "BANK 0", "NO DIMM"),
+		},
+	})
+
+	devices, err := GetMemoryDevices(sysFs)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.MemoryDevice{
+		{Locator: "DIMM_A1", BankLocator: "BANK 0", Type: "DDR4", Size: 16 * 1024 * 1024 * 1024, Speed: 3200, Manufacturer: "Samsung"},
+		{Locator: "DIMM_A2", BankLocator: "BANK 0", Type: "SDRAM", Size: 512 * 1024},
+		{Locator: "DIMM_B1", BankLocator: "BANK 1", Type: "DDR5", Size: 64 * 1024 * 1024 * 1024, Speed: 4800, Manufacturer: "Micron"},
+	}, devices)
+
+	memoryByType := GetMemoryByTypeFromDevices(devices)
+	assert.Equal(t, map[string]*info.MemoryInfo{
+		"DDR4":  {Capacity: 16 * 1024 * 1024 * 1024, DimmCount: 1},
+		"SDRAM": {Capacity: 512 * 1024, DimmCount: 1},
+		"DDR5":  {Capacity: 64 * 1024 * 1024 * 1024, DimmCount: 1},
+	}, memoryByType)
+}
+
+func TestGetMemoryDevicesWithMalformedEntry(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetDMIEntries(map[int][][]byte{17: {{17, 0x04, 0x00, 0x11}}})
+
+	_, err := GetMemoryDevices(sysFs)
+	assert.NotNil(t, err)
+}
+
 func TestGetBlockDeviceInfo(t *testing.T) {
 	fakeSys := fakesysfs.FakeSysFs{}
 	disks, err := GetBlockDeviceInfo(&fakeSys)
@@ -1151,6 +1841,86 @@ func TestGetBlockDeviceInfo(t *testing.T) {
 	if disk.Scheduler != "cfq" {
 		t.Errorf("expected to get scheduler type of cfq. Got %q", disk.Scheduler)
 	}
+	// Queue attributes are optional.
+	assert.False(t, disk.Rotational)
+	assert.Equal(t, uint64(0), disk.NrRequests)
+}
+
+func TestGetBlockDeviceInfoQueueAttributes(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetBlockDeviceQueueAttributes(map[string]string{
+		"sda/queue/rotational":          "1",
+		"sda/queue/nr_requests":         "64",
+		"sda/queue/discard_granularity": "512",
+		"sda/queue/physical_block_size": "4096",
+	})
+	disks, err := GetBlockDeviceInfo(&fakeSys)
+	assert.Nil(t, err)
+	assert.Equal(t, info.DiskInfo{
+		Name:               "sda",
+		Major:              8,
+		Minor:              0,
+		Size:               1234567 * 512,
+		Scheduler:          "cfq",
+		Rotational:         true,
+		NrRequests:         64,
+		DiscardGranularity: 512,
+		PhysicalBlockSize:  4096,
+	}, disks["8:0"])
+}
+
+func TestGetBlockDeviceInfoTopology(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetBlockDeviceTopology(
+		map[string][]string{"sda": {"sdc1", "sdb1"}},
+		map[string]string{"sda": "raid1"},
+		map[string]string{"sda": "vg0-root"},
+	)
+	disks, err := GetBlockDeviceInfo(&fakeSys)
+	assert.Nil(t, err)
+	disk := disks["8:0"]
+	assert.Equal(t, "raid1", disk.RaidLevel)
+	assert.Equal(t, "vg0-root", disk.DMName)
+	assert.Equal(t, []string{"sdb1", "sdc1"}, disk.Slaves)
+}
+
+func TestGetBlockDeviceStats(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetBlockDeviceStats(map[string]string{
+		"sda": "8764 213 453920 3210 12345 6789 1048576 45678 2 30120 48888 0 0 0 0 512 120",
+	})
+	stats, err := GetBlockDeviceStats(&fakeSys)
+	assert.Nil(t, err)
+	expected := []info.BlockDeviceStats{
+		{
+			Name:            "sda",
+			Major:           8,
+			Minor:           0,
+			ReadsCompleted:  8764,
+			ReadsMerged:     213,
+			ReadBytes:       453920 * 512,
+			ReadTime:        3210,
+			WritesCompleted: 12345,
+			WritesMerged:    6789,
+			WrittenBytes:    1048576 * 512,
+			WriteTime:       45678,
+			IoInProgress:    2,
+			IoTime:          30120,
+			WeightedIoTime:  48888,
+		},
+	}
+	assert.Equal(t, expected, stats)
+}
+
+func TestGetBlockDeviceStatsWithMalformedStat(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetBlockDeviceStats(map[string]string{"sda": "8764 213 453920"})
+	_, err := GetBlockDeviceStats(&fakeSys)
+	assert.NotNil(t, err)
+
+	fakeSys.SetBlockDeviceStats(nil)
+	_, err = GetBlockDeviceStats(&fakeSys)
+	assert.True(t, os.IsNotExist(err))
 }
 
 func TestGetNetworkDevices(t *testing.T) {
@@ -1178,6 +1948,161 @@ func TestGetNetworkDevices(t *testing.T) {
 	}
 }
 
+func TestGetNetworkDevicesLinkInfo(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetEntryName("eth0")
+	fakeSys.SetNetworkLinkInfo(
+		map[string]string{"eth0": "up"},
+		map[string]string{"eth0": "full"},
+		map[string]string{"eth0": "ixgbe"},
+	)
+	devs, err := GetNetworkDevices(&fakeSys)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.NetInfo{
+		{
+			Name:       "eth0",
+			MacAddress: "42:01:02:03:04:f4",
+			Speed:      1000,
+			Mtu:        1024,
+			OperState:  "up",
+			Duplex:     "full",
+			Driver:     "ixgbe",
+		},
+	}, devs)
+}
+
+func TestGetNetworkDevicesSriov(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetEntryName("ens785f0")
+	fakeSys.SetNetworkPCIDevices(
+		map[string]string{"ens785f0": "0000:3b:00.0"},
+		map[string]string{
+			"ens785f0/device/vendor":         "0x8086",
+			"ens785f0/device/device":         "0x158b",
+			"ens785f0/device/sriov_totalvfs": "64",
+			"ens785f0/device/sriov_numvfs":   "2",
+		},
+		map[string]map[int]string{"ens785f0": {1: "0000:3b:02.1", 0: "0000:3b:02.0"}},
+	)
+	devs, err := GetNetworkDevices(&fakeSys)
+	assert.Nil(t, err)
+	assert.Len(t, devs, 1)
+	assert.Equal(t, &info.SriovInfo{
+		PCIAddress: "0000:3b:00.0",
+		VendorID:   "0x8086",
+		DeviceID:   "0x158b",
+		TotalVFs:   64,
+		NumVFs:     2,
+		VirtualFunctions: []info.VirtualFunction{
+			{Index: 0, PCIAddress: "0000:3b:02.0"},
+			{Index: 1, PCIAddress: "0000:3b:02.1"},
+		},
+	}, devs[0].Sriov)
+}
+
+func TestGetNetworkQueueStats(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetNetworkDevices([]string{"lo", "eth0", "eth1", "eth2"})
+	fakeSys.SetNetworkQueueStats(
+		map[string][]string{
+			"eth0": {"rx-0", "rx-1", "tx-0", "tx-1"},
+			"eth1": {"rx-0", "tx-0"},
+			"eth2": {"rx-0", "tx-0"},
+		},
+		map[string]map[string]uint64{
+			// ixgbe
+			"eth0": {
+				"rx_packets":         30,
+				"rx_queue_0_packets": 10,
+				"rx_queue_0_bytes":   1000,
+				"rx_queue_1_packets": 20,
+				"rx_queue_1_bytes":   2000,
+				"tx_queue_0_packets": 5,
+				"tx_queue_0_bytes":   500,
+				"tx_queue_1_packets": 0,
+				"tx_queue_1_bytes":   0,
+			},
+			// i40e
+			"eth1": {
+				"rx-0.packets": 7,
+				"rx-0.bytes":   700,
+				"tx-0.packets": 3,
+				"tx-0.bytes":   300,
+			},
+			// eth2 driver does not support ethtool statistics
+		},
+	)
+	stats, err := GetNetworkQueueStats(&fakeSys)
+	assert.Nil(t, err)
+	expected := []info.NetworkQueueStats{
+		{Device: "eth0", Queue: "rx-0", Packets: 10, Bytes: 1000},
+		{Device: "eth0", Queue: "rx-1", Packets: 20, Bytes: 2000},
+		{Device: "eth0", Queue: "tx-0", Packets: 5, Bytes: 500},
+		{Device: "eth0", Queue: "tx-1", Packets: 0, Bytes: 0},
+		{Device: "eth1", Queue: "rx-0", Packets: 7, Bytes: 700},
+		{Device: "eth1", Queue: "tx-0", Packets: 3, Bytes: 300},
+	}
+	assert.Equal(t, expected, stats)
+}
+
+func TestSetNetworkEthtoolStats(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetNetworkQueueStats(nil, map[string]map[string]uint64{
+		"eth0": {
+			"rx_packets":       100,
+			"rx_missed_errors": 3,
+			"rx_fifo_errors":   1,
+			"rx_queue_0_drops": 2,
+			"tx_queue_0_bytes": 1000,
+		},
+		"eth1": {
+			"rx_packets": 100,
+		},
+	})
+	netDevices := []info.NetInfo{{Name: "eth0"}, {Name: "eth1"}, {Name: "eth2"}}
+	SetNetworkEthtoolStats(&fakeSys, netDevices)
+	assert.Equal(t, []info.NetInfo{
+		{Name: "eth0", EthtoolStats: map[string]uint64{"rx_missed_errors": 3, "rx_fifo_errors": 1, "rx_queue_0_drops": 2}},
+		{Name: "eth1"},
+		{Name: "eth2"},
+	}, netDevices)
+}
+
+func TestParseNetworkQueueStatsOfMlx5(t *testing.T) {
+	perQueue := parseNetworkQueueStats(map[string]uint64{
+		"rx0_packets":      11,
+		"rx0_bytes":        1100,
+		"tx12_packets":     4,
+		"rx_vport_unicast": 100,
+	})
+	assert.Equal(t, map[string]*info.NetworkQueueStats{
+		"rx-0":  {Packets: 11, Bytes: 1100},
+		"tx-12": {Packets: 4},
+	}, perQueue)
+}
+
+func TestGetNetworkTopology(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	fakeSys.SetNetworkDevices([]string{"lo", "eth0", "eth1", "eth2", "bond0", "br0", "veth1234", "bond0.100"})
+	fakeSys.SetNetworkTopology(
+		map[string]string{"bond0": "bond", "br0": "bridge", "bond0.100": "vlan"},
+		map[string][]string{"bond0": {"eth1", "eth0"}},
+		map[string][]string{"br0": {"veth1234"}},
+		map[string][]string{"bond0.100": {"bond0"}},
+	)
+	topology, err := GetNetworkTopology(&fakeSys)
+	assert.Nil(t, err)
+	expected := []info.NetworkInterface{
+		{Name: "bond0", Type: "bond", Lower: []string{"eth0", "eth1"}, Upper: []string{"bond0.100"}},
+		{Name: "bond0.100", Type: "vlan", Lower: []string{"bond0"}},
+		{Name: "br0", Type: "bridge", Lower: []string{"veth1234"}},
+		{Name: "eth0", Upper: []string{"bond0"}},
+		{Name: "eth1", Upper: []string{"bond0"}},
+		{Name: "veth1234", Upper: []string{"br0"}},
+	}
+	assert.Equal(t, expected, topology)
+}
+
 func TestIgnoredNetworkDevices(t *testing.T) {
 	fakeSys := fakesysfs.FakeSysFs{}
 	ignoredDevices := []string{"veth1234", "lo", "docker0"}
@@ -1214,6 +2139,47 @@ func TestGetCacheInfo(t *testing.T) {
 	}
 }
 
+func TestAddCacheInfoWithCacheSharedByCluster(t *testing.T) {
+	l2Cache := func(cpuList string) sysfs.CacheInfo {
+		return sysfs.CacheInfo{Size: 2 * 1024 * 1024, Type: "Unified", Level: 2, Cpus: 2, CPUList: cpuList}
+	}
+	// L3 cache of die is shared with CPUs of another node.
+	l3Cache := sysfs.CacheInfo{Size: 32 * 1024 * 1024, Type: "Unified", Level: 3, Cpus: 8, CPUList: "0-7"}
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetCPUCaches(map[int][]sysfs.CacheInfo{
+		0: {l2Cache("0-1"), l3Cache},
+		1: {l2Cache("0-1"), l3Cache},
+		2: {l2Cache("2-3"), l3Cache},
+		3: {l2Cache("2-3"), l3Cache},
+	})
+
+	node := info.Node{
+		Id: 0,
+		Cores: []info.Core{
+			{Id: 0, Threads: []int{0}, SocketID: 1, DieID: "0", ClusterID: "0"},
+			{Id: 1, Threads: []int{1}, SocketID: 1, DieID: "0", ClusterID: "0"},
+			{Id: 2, Threads: []int{2}, SocketID: 1, DieID: "0", ClusterID: "8"},
+			{Id: 3, Threads: []int{3}, SocketID: 1, DieID: "0", ClusterID: "8"},
+		},
+	}
+	err := addCacheInfo(fakeSys, &node, false)
+	assert.Nil(t, err)
+
+	expectedL2Cache := func(cpuList string) info.Cache {
+		return info.Cache{Size: 2 * 1024 * 1024, Type: "Unified", Level: 2, SharedCPUList: cpuList}
+	}
+	expectedL3Cache := info.Cache{Size: 32 * 1024 * 1024, Type: "Unified", Level: 3, SharedCPUList: "0-7"}
+	assert.Nil(t, node.Caches)
+	for _, core := range node.Cores {
+		assert.Nil(t, core.Caches)
+	}
+	assert.Equal(t, []info.UncoreCache{
+		{Cache: expectedL2Cache("0-1"), SocketID: 1, DieID: "0", ClusterID: "0"},
+		{Cache: expectedL3Cache, SocketID: 1, DieID: "0"},
+		{Cache: expectedL2Cache("2-3"), SocketID: 1, DieID: "0", ClusterID: "8"},
+	}, node.UncoreCaches)
+}
+
 func TestGetNetworkStats(t *testing.T) {
 	expectedStats := info.InterfaceStats{
 		Name:      "eth0",
@@ -1333,3 +2299,159 @@ func TestGetOnlineCPUs(t *testing.T) {
 	onlineCPUs := GetOnlineCPUs(topology)
 	assert.Equal(t, onlineCPUs, []int{0, 1, 2, 3, 4, 5, 6, 7})
 }
+
+// slowSysFs adds latency of reading a sysfs file to the fake to simulate machines with many CPUs.
+type slowSysFs struct {
+	*fakesysfs.FakeSysFs
+}
+
+const sysfsReadLatency = 20 * time.Microsecond
+
+func (fs slowSysFs) GetCoreID(cpuPath string) (string, error) {
+	time.Sleep(sysfsReadLatency)
+	return fs.FakeSysFs.GetCoreID(cpuPath)
+}
+
+func (fs slowSysFs) GetCPUPhysicalPackageID(cpuPath string) (string, error) {
+	time.Sleep(sysfsReadLatency)
+	return fs.FakeSysFs.GetCPUPhysicalPackageID(cpuPath)
+}
+
+func (fs slowSysFs) GetCacheInfo(cpu int, cache string) (sysfs.CacheInfo, error) {
+	time.Sleep(sysfsReadLatency)
+	return fs.FakeSysFs.GetCacheInfo(cpu, cache)
+}
+
+// countingSysFs counts sysfs files describing topology of CPUs which are read at once.
+type countingSysFs struct {
+	*fakesysfs.FakeSysFs
+	reads    int32
+	maxReads int32
+}
+
+func (fs *countingSysFs) read() {
+	reads := atomic.AddInt32(&fs.reads, 1)
+	for {
+		maxReads := atomic.LoadInt32(&fs.maxReads)
+		if reads <= maxReads || atomic.CompareAndSwapInt32(&fs.maxReads, maxReads, reads) {
+			break
+		}
+	}
+	time.Sleep(sysfsReadLatency)
+	atomic.AddInt32(&fs.reads, -1)
+}
+
+func (fs *countingSysFs) GetCoreID(cpuPath string) (string, error) {
+	fs.read()
+	return fs.FakeSysFs.GetCoreID(cpuPath)
+}
+
+func (fs *countingSysFs) GetCacheInfo(cpu int, cache string) (sysfs.CacheInfo, error) {
+	fs.read()
+	return fs.FakeSysFs.GetCacheInfo(cpu, cache)
+}
+
+func TestGetNodesInfoReadsLimitedNumberOfCPUsAtOnce(t *testing.T) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetCacheInfo(sysfs.CacheInfo{
+		Size:  32 * 1024,
+		Type:  "Data",
+		Level: 1,
+		Cpus:  1,
+	})
+	fakeSys.SetMemory("MemTotal:       32817192 kB", nil)
+
+	nodesPaths := []string{}
+	cpusPaths := map[string][]string{}
+	coreThread := map[string]string{}
+	physicalPackageIDs := map[string]string{}
+	for node := 0; node < 4; node++ {
+		nodePath := fmt.Sprintf("/fakeSysfs/devices/system/node/node%d", node)
+		nodesPaths = append(nodesPaths, nodePath)
+		for i := 0; i < 64; i++ {
+			cpu := fmt.Sprintf("%s/cpu%d", nodePath, node*64+i)
+			cpusPaths[nodePath] = append(cpusPaths[nodePath], cpu)
+			coreThread[cpu] = strconv.Itoa(node*64 + i)
+			physicalPackageIDs[cpu] = strconv.Itoa(node)
+		}
+	}
+	fakeSys.SetNodesPaths(nodesPaths, nil)
+	fakeSys.SetCPUsPaths(cpusPaths, nil)
+	fakeSys.SetCoreThreads(coreThread, nil)
+	fakeSys.SetPhysicalPackageIDs(physicalPackageIDs, nil)
+	sysFs := &countingSysFs{FakeSysFs: fakeSys}
+
+	_, numCores, err := GetNodesInfo(sysFs)
+	assert.NoError(t, err)
+	assert.Equal(t, 256, numCores)
+	assert.LessOrEqual(t, int(sysFs.maxReads), topologyReadParallelism)
+
+	// CPUs of a single node are read concurrently too.
+	fakeSys.SetNodesPaths(nodesPaths[:1], nil)
+	sysFs.maxReads = 0
+
+	_, numCores, err = GetNodesInfo(sysFs)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, numCores)
+	assert.LessOrEqual(t, int(sysFs.maxReads), topologyReadParallelism)
+	assert.Greater(t, int(sysFs.maxReads), 1)
+
+	// Topology of CPUs is read concurrently when nodes are not available.
+	fakeSys.SetNodesPaths([]string{}, nil)
+	fakeSys.SetCPUsPaths(map[string][]string{cpusPath: cpusPaths[nodesPaths[0]]}, nil)
+	sysFs.maxReads = 0
+
+	_, numCores, err = GetNodesInfo(sysFs)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, numCores)
+	assert.LessOrEqual(t, int(sysFs.maxReads), topologyReadParallelism)
+	assert.Greater(t, int(sysFs.maxReads), 1)
+}
+
+// BenchmarkGetNodesInfo compares sequential and concurrent reading of topology of 256 threads in 2 NUMA nodes.
+func BenchmarkGetNodesInfo(b *testing.B) {
+	fakeSys := &fakesysfs.FakeSysFs{}
+	fakeSys.SetCacheInfo(sysfs.CacheInfo{
+		Size:  32 * 1024,
+		Type:  "Data",
+		Level: 1,
+		Cpus:  2,
+	})
+	fakeSys.SetMemory("MemTotal:       32817192 kB", nil)
+
+	nodesPaths := []string{}
+	cpusPaths := map[string][]string{}
+	coreThread := map[string]string{}
+	physicalPackageIDs := map[string]string{}
+	for i := 0; i < 256; i++ {
+		nodePath := fmt.Sprintf("/fakeSysfs/devices/system/node/node%d", i%128/64)
+		if len(cpusPaths[nodePath]) == 0 {
+			nodesPaths = append(nodesPaths, nodePath)
+		}
+		cpu := fmt.Sprintf("%s/cpu%d", nodePath, i)
+		cpusPaths[nodePath] = append(cpusPaths[nodePath], cpu)
+		coreThread[cpu] = strconv.Itoa(i % 128)
+		physicalPackageIDs[cpu] = strconv.Itoa(i % 128 / 64)
+	}
+	fakeSys.SetNodesPaths(nodesPaths, nil)
+	fakeSys.SetCPUsPaths(cpusPaths, nil)
+	fakeSys.SetCoreThreads(coreThread, nil)
+	fakeSys.SetPhysicalPackageIDs(physicalPackageIDs, nil)
+	sysFs := slowSysFs{fakeSys}
+
+	originalLimiter := topologyReadLimiter
+	defer func() {
+		topologyReadLimiter = originalLimiter
+	}()
+	for name, parallelism := range map[string]int{"sequential": 1, "parallel": topologyReadParallelism} {
+		b.Run(name, func(b *testing.B) {
+			topologyReadLimiter = make(chan struct{}, parallelism)
+			for i := 0; i < b.N; i++ {
+				_, numCores, err := GetNodesInfo(sysFs)
+				if err != nil || numCores != 256 {
+					b.Fatalf("unexpected result of GetNodesInfo: %d, %v", numCores, err)
+				}
+			}
+		})
+	}
+}